@@ -0,0 +1,157 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
+)
+
+// aliasReason returns the skip reason of an execution that is identical
+// to the execution with the ID, and so is not run.
+func aliasReason(id string) string {
+	return fmt.Sprintf("same execution as %s", id)
+}
+
+// executionKey returns what makes the Terraform run of the execution
+// unique: the configuration of its module, other than its name, the
+// directory of its code, its variables and targets, and the policies its
+// plans are checked against. Executions with the same key would run the
+// same code, in the same way, against the same state.
+func executionKey(b *boundExecution, policies []conf.Policy) string {
+	moduleConfig := b.ModuleConfig()
+
+	var applyingPolicies []int
+	for i, p := range policies {
+		if p.Applies(moduleConfig.Name) {
+			applyingPolicies = append(applyingPolicies, i)
+		}
+	}
+
+	moduleConfig.Name = ""
+
+	// maps are marshalled with sorted keys, so equal keys are equal
+	// strings
+	key, err := json.Marshal(struct {
+		Module              conf.Module
+		Dir                 string
+		Variables           map[string]string
+		TerraformParameters []string
+		Targets             []string
+		Policies            []int
+	}{
+		Module:              moduleConfig,
+		Dir:                 filepath.Join(moduleConfig.TerraformCodeRoot, moduleConfig.Path),
+		Variables:           b.Variables(),
+		TerraformParameters: b.TerraformParameters(),
+		Targets:             b.Targets(),
+		Policies:            applyingPolicies,
+	})
+	if err != nil {
+		// the config was unmarshalled, so it can be marshalled
+		panic(err)
+	}
+
+	return string(key)
+}
+
+// dedupeExecutions returns the executions without those that are
+// identical to an earlier one, which are recorded as its aliases instead.
+// Otherwise the same plan would run twice, with both runs racing on the
+// same state. Only executions with a remote backend share state: the
+// local state of the others is in the sandbox of each execution.
+func dedupeExecutions(boundExecutions []*boundExecution, policies []conf.Policy) []*boundExecution {
+	var results []*boundExecution
+	seen := map[string]*boundExecution{}
+
+	for _, b := range boundExecutions {
+		if b.ModuleConfig().Remote.Backend == "" {
+			results = append(results, b)
+			continue
+		}
+		key := executionKey(b, policies)
+		if original, ok := seen[key]; ok {
+			logger.Warn("execution is identical to another one; not running it", logger.Fields{"execution": b.ID(), "same_as": original.ID()})
+			original.aliases = append(original.aliases, b)
+			continue
+		}
+		seen[key] = b
+		results = append(results, b)
+	}
+
+	return results
+}
+
+// aliasModules returns the names of the modules of the aliases of the
+// execution, so that executions that depend on them depend on it.
+func aliasModules(e terraformExecution) []string {
+	b, ok := e.(*boundExecution)
+	if !ok {
+		return nil
+	}
+	var names []string
+	for _, alias := range b.aliases {
+		names = append(names, alias.ModuleConfig().Name)
+	}
+	return names
+}
+
+// executionDeps returns the dependencies of the module of the execution,
+// along with those of the modules of its aliases, which run when it does.
+func executionDeps(e terraformExecution) []conf.Dependency {
+	deps := append([]conf.Dependency{}, e.ModuleConfig().Deps...)
+	if b, ok := e.(*boundExecution); ok {
+		for _, alias := range b.aliases {
+			deps = append(deps, alias.ModuleConfig().Deps...)
+		}
+	}
+	return deps
+}
+
+// reportAliases passes on the results, following the result of each
+// execution with a skipped result for each of its aliases, so that it is
+// clear why they didn't run.
+func reportAliases(boundExecutions []*boundExecution, results <-chan *Result) <-chan *Result {
+	aliases := map[string][]*boundExecution{}
+	count := len(boundExecutions)
+	for _, b := range boundExecutions {
+		aliases[b.ID()] = b.aliases
+		count += len(b.aliases)
+	}
+
+	reported := newResults(count)
+
+	go func() {
+		defer close(reported)
+
+		for result := range results {
+			reported <- result
+			for _, alias := range aliases[result.id] {
+				reported <- &Result{
+					id:         alias.ID(),
+					skipReason: aliasReason(result.id),
+				}
+			}
+		}
+	}()
+
+	return reported
+}
@@ -0,0 +1,127 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"testing"
+
+	"github.com/uber/astro/astro/conf"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bindModules returns the bound executions of the modules.
+func bindModules(t *testing.T, modules ...conf.Module) []*boundExecution {
+	var bound []*boundExecution
+	for _, m := range modules {
+		for _, e := range newModule(m).executions(NoExecutionParameters()) {
+			b, err := e.(*unboundExecution).bind(nil)
+			require.NoError(t, err)
+			bound = append(bound, b)
+		}
+	}
+	return bound
+}
+
+func TestDedupeExecutions(t *testing.T) {
+	t.Parallel()
+
+	environments := []conf.Variable{{Name: "environment", Values: []string{"dev", "prod"}}}
+	remote := conf.Remote{
+		Backend:       "s3",
+		BackendConfig: map[string]string{"key": "app/{{.environment}}"},
+	}
+
+	bound := bindModules(t,
+		conf.Module{Name: "app", Path: "app", Variables: environments, Remote: remote},
+		// the same code, variables and state as app
+		conf.Module{Name: "app-copy", Path: "app", Variables: environments, Remote: remote},
+		// the same code and variables, with different state
+		conf.Module{Name: "app-other", Path: "app", Variables: environments, Remote: conf.Remote{
+			Backend:       "s3",
+			BackendConfig: map[string]string{"key": "other/{{.environment}}"},
+		}},
+		// the same code, variables and state, run differently
+		conf.Module{Name: "app-hooked", Path: "app", Variables: environments, Remote: remote, Hooks: conf.ModuleHooks{
+			PreModuleRun: []conf.Hook{{Command: "true"}},
+		}},
+		// the same code, variables and state, checked against a policy
+		conf.Module{Name: "app-checked", Path: "app", Variables: environments, Remote: remote},
+		// the same code without a remote backend, so with its own state
+		conf.Module{Name: "local", Path: "app"},
+		conf.Module{Name: "local-copy", Path: "app"},
+		conf.Module{Name: "users", Path: "users", Deps: []conf.Dependency{{Module: "app-copy"}}},
+	)
+
+	deduped := dedupeExecutions(bound, []conf.Policy{{Path: "policy.rego", Modules: []string{"app-checked"}}})
+
+	var ids []string
+	for _, b := range deduped {
+		ids = append(ids, b.ID())
+	}
+	assert.Equal(t, []string{
+		"app-dev", "app-prod",
+		"app-other-dev", "app-other-prod",
+		"app-hooked-dev", "app-hooked-prod",
+		"app-checked-dev", "app-checked-prod",
+		"local", "local-copy",
+		"users",
+	}, ids)
+	require.Len(t, deduped[0].aliases, 1)
+	assert.Equal(t, "app-copy-dev", deduped[0].aliases[0].ID())
+
+	// Dependencies on the aliases are dependencies on the executions
+	// they are the same as
+	executions := executionSet{}
+	for _, b := range deduped {
+		executions = append(executions, b)
+	}
+	graph, err := executions.graph()
+	require.NoError(t, err)
+
+	var deps []string
+	for _, edge := range graph.EdgesFrom(deduped[10]) {
+		deps = append(deps, edge.Target().(*boundExecution).ID())
+	}
+	assert.ElementsMatch(t, []string{"app-dev", "app-prod"}, deps)
+}
+
+func TestReportAliases(t *testing.T) {
+	t.Parallel()
+
+	remote := conf.Remote{Backend: "s3", BackendConfig: map[string]string{"key": "app"}}
+	bound := dedupeExecutions(bindModules(t,
+		conf.Module{Name: "app", Path: "app", Remote: remote},
+		conf.Module{Name: "app-copy", Path: "app", Remote: remote},
+	), nil)
+	require.Len(t, bound, 1)
+
+	results := make(chan *Result, 1)
+	results <- &Result{id: "app"}
+	close(results)
+
+	var reported []*Result
+	for result := range reportAliases(bound, results) {
+		reported = append(reported, result)
+	}
+
+	require.Len(t, reported, 2)
+	assert.Equal(t, "app", reported[0].ID())
+	assert.Equal(t, "app-copy", reported[1].ID())
+	assert.Equal(t, "same execution as app", reported[1].SkipReason())
+}
@@ -0,0 +1,215 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package annotate reports failed executions as annotations in the CI
+// system that astro is running in, e.g. GitHub Actions, so that failures
+// are shown in its UI instead of only in the log of the job.
+package annotate
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/uber/astro/astro/logger"
+)
+
+// maxMessageLines is how many lines of the error of an execution are
+// included in its annotation. The end of the output of Terraform is what
+// explains the failure.
+const maxMessageLines = 30
+
+// Failure is an execution that failed.
+type Failure struct {
+	ID    string
+	Error string
+	// Guidance is what to do about the error, from the docs and
+	// on_error_message of the module.
+	Guidance string
+	// ModuleDir is the absolute path of the directory of the module.
+	ModuleDir string
+	// Log is the path to the log of the Terraform command.
+	Log string
+}
+
+// CI is a CI system that failures can be annotated in.
+type CI interface {
+	// Name is the name of the CI system, for messages.
+	Name() string
+	// Annotate annotates the failures of an astro command, e.g. "plan".
+	Annotate(ctx context.Context, session string, command string, failures []Failure) error
+}
+
+// Detect returns the CI system that astro is running in, using getenv to
+// read its environment variables, or nil if it isn't running in a
+// supported one. GitHub Actions annotations are written to stdout.
+func Detect(getenv func(string) string, stdout io.Writer) CI {
+	switch {
+	case getenv("GITHUB_ACTIONS") == "true":
+		gh := &githubActions{
+			stdout: stdout,
+			root:   getenv("GITHUB_WORKSPACE"),
+		}
+		if server, repo, run := getenv("GITHUB_SERVER_URL"), getenv("GITHUB_REPOSITORY"), getenv("GITHUB_RUN_ID"); server != "" && repo != "" && run != "" {
+			gh.link = fmt.Sprintf("%s/%s/actions/runs/%s", server, repo, run)
+		}
+		return gh
+	case getenv("BUILDKITE") == "true":
+		bk := &buildkite{
+			root: getenv("BUILDKITE_BUILD_CHECKOUT_PATH"),
+		}
+		if build := getenv("BUILDKITE_BUILD_URL"); build != "" {
+			bk.link = build
+			if job := getenv("BUILDKITE_JOB_ID"); job != "" {
+				bk.link += "#" + job
+			}
+		}
+		return bk
+	}
+	return nil
+}
+
+// githubActions annotates failures with workflow commands, which the
+// runner reads from the output of the job.
+type githubActions struct {
+	stdout io.Writer
+	// root is the checkout that file paths are relative to
+	root string
+	// link is the URL of the workflow run
+	link string
+}
+
+func (gh *githubActions) Name() string {
+	return "GitHub Actions"
+}
+
+func (gh *githubActions) Annotate(ctx context.Context, session string, command string, failures []Failure) error {
+	for _, failure := range failures {
+		properties := []string{}
+		if file := relativePath(gh.root, failure.ModuleDir); file != "" {
+			properties = append(properties, "file="+escapeGitHubProperty(file))
+		}
+		properties = append(properties, "title="+escapeGitHubProperty(fmt.Sprintf("astro %s failed: %s", command, failure.ID)))
+
+		message := message(failure, session, gh.link)
+
+		_, err := fmt.Fprintf(gh.stdout, "::error %s::%s\n", strings.Join(properties, ","), escapeGitHubData(message))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildkite annotates failures with buildkite-agent, as a single
+// annotation of the build for each command.
+type buildkite struct {
+	// root is the checkout that file paths are relative to
+	root string
+	// link is the URL of the job
+	link string
+}
+
+func (bk *buildkite) Name() string {
+	return "Buildkite"
+}
+
+func (bk *buildkite) Annotate(ctx context.Context, session string, command string, failures []Failure) error {
+	if len(failures) == 0 {
+		return nil
+	}
+
+	body := &bytes.Buffer{}
+	fmt.Fprintf(body, "### astro %s failed\n", command)
+	for _, failure := range failures {
+		fmt.Fprintf(body, "\n#### %s", failure.ID)
+		if file := relativePath(bk.root, failure.ModuleDir); file != "" {
+			fmt.Fprintf(body, " (`%s`)", file)
+		}
+		fmt.Fprintf(body, "\n\n```\n%s\n```\n", message(failure, session, bk.link))
+	}
+
+	args := []string{"buildkite-agent", "annotate", "--style", "error", "--context", "astro-" + command}
+	logger.Trace.Printf("annotate: running %v", args)
+
+	stderr := &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = body
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("buildkite-agent annotate: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// message returns the text of the annotation of a failure: the end of
+// its error, followed by its guidance and where to find its logs.
+func message(failure Failure, session string, link string) string {
+	lines := strings.Split(strings.TrimSpace(failure.Error), "\n")
+	if len(lines) > maxMessageLines {
+		lines = append([]string{"..."}, lines[len(lines)-maxMessageLines:]...)
+	}
+
+	if failure.Guidance != "" {
+		lines = append(lines, "", failure.Guidance)
+	}
+
+	lines = append(lines, "")
+	if session != "" {
+		lines = append(lines, "Session: "+session)
+	}
+	if failure.Log != "" {
+		lines = append(lines, "Log: "+failure.Log)
+	}
+	if link != "" {
+		lines = append(lines, "Job: "+link)
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// relativePath returns the path of dir relative to root, or "" if it
+// isn't within it, as CI systems only know files of the checkout.
+func relativePath(root, dir string) string {
+	if root == "" || dir == "" {
+		return ""
+	}
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// escapeGitHubData escapes the message of a workflow command.
+func escapeGitHubData(s string) string {
+	s = strings.ReplaceAll(s, "%", "%25")
+	s = strings.ReplaceAll(s, "\r", "%0D")
+	return strings.ReplaceAll(s, "\n", "%0A")
+}
+
+// escapeGitHubProperty escapes the value of a property of a workflow
+// command, which also can't contain the separators of properties.
+func escapeGitHubProperty(s string) string {
+	s = escapeGitHubData(s)
+	s = strings.ReplaceAll(s, ":", "%3A")
+	return strings.ReplaceAll(s, ",", "%2C")
+}
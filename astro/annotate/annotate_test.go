@@ -0,0 +1,112 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package annotate_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/uber/astro/astro/annotate"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testFailures = []annotate.Failure{
+	{
+		ID:        "app-prod",
+		Error:     "exit status 1\nError: 100% broken",
+		Guidance:  "See: https://wiki.example.com/app",
+		ModuleDir: "/src/repo/terraform/app",
+		Log:       "/src/repo/.astro/01E2Q5HXW3TGNWAJ9T3V0MB4T4/app-prod/logs/plan.log",
+	},
+	{
+		ID:        "external",
+		Error:     "exit status 1",
+		ModuleDir: "/elsewhere/external",
+	},
+}
+
+// env returns a getenv function for the variables.
+func env(vars map[string]string) func(string) string {
+	return func(name string) string {
+		return vars[name]
+	}
+}
+
+func TestDetectNone(t *testing.T) {
+	assert.Nil(t, annotate.Detect(env(nil), &bytes.Buffer{}))
+}
+
+func TestAnnotateGitHubActions(t *testing.T) {
+	out := &bytes.Buffer{}
+	ci := annotate.Detect(env(map[string]string{
+		"GITHUB_ACTIONS":    "true",
+		"GITHUB_WORKSPACE":  "/src/repo",
+		"GITHUB_SERVER_URL": "https://github.com",
+		"GITHUB_REPOSITORY": "uber/astro",
+		"GITHUB_RUN_ID":     "42",
+	}), out)
+	require.NotNil(t, ci)
+	assert.Equal(t, "GitHub Actions", ci.Name())
+
+	require.NoError(t, ci.Annotate(context.Background(), "01E2Q5HXW3TGNWAJ9T3V0MB4T4", "plan", testFailures))
+
+	assert.Equal(t,
+		"::error file=terraform/app,title=astro plan failed%3A app-prod::exit status 1%0AError: 100%25 broken%0A%0A"+
+			"See: https://wiki.example.com/app%0A%0ASession: 01E2Q5HXW3TGNWAJ9T3V0MB4T4%0A"+
+			"Log: /src/repo/.astro/01E2Q5HXW3TGNWAJ9T3V0MB4T4/app-prod/logs/plan.log%0A"+
+			"Job: https://github.com/uber/astro/actions/runs/42\n"+
+			"::error title=astro plan failed%3A external::exit status 1%0A%0ASession: 01E2Q5HXW3TGNWAJ9T3V0MB4T4%0A"+
+			"Job: https://github.com/uber/astro/actions/runs/42\n",
+		out.String())
+}
+
+func TestAnnotateBuildkite(t *testing.T) {
+	binDir := t.TempDir()
+	callLog := filepath.Join(binDir, "calls")
+
+	// fake buildkite-agent that logs its arguments and input
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "buildkite-agent"), []byte(`#!/bin/sh
+echo "$@" >> `+callLog+`
+cat >> `+callLog+`
+`), 0755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	ci := annotate.Detect(env(map[string]string{
+		"BUILDKITE":                     "true",
+		"BUILDKITE_BUILD_CHECKOUT_PATH": "/src/repo",
+		"BUILDKITE_BUILD_URL":           "https://buildkite.com/uber/astro/builds/7",
+		"BUILDKITE_JOB_ID":              "abc",
+	}), &bytes.Buffer{})
+	require.NotNil(t, ci)
+
+	require.NoError(t, ci.Annotate(context.Background(), "", "apply", testFailures[:1]))
+
+	calls, err := os.ReadFile(callLog)
+	require.NoError(t, err)
+	assert.Equal(t, "annotate --style error --context astro-apply\n"+
+		"### astro apply failed\n\n"+
+		"#### app-prod (`terraform/app`)\n\n"+
+		"```\nexit status 1\nError: 100% broken\n\nSee: https://wiki.example.com/app\n\n"+
+		"Log: /src/repo/.astro/01E2Q5HXW3TGNWAJ9T3V0MB4T4/app-prod/logs/plan.log\n"+
+		"Job: https://buildkite.com/uber/astro/builds/7#abc\n```\n",
+		string(calls))
+}
@@ -17,13 +17,27 @@
 package astro
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/filter"
 	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/scheduler"
+	"github.com/uber/astro/astro/secrets"
+	"github.com/uber/astro/astro/state"
 	"github.com/uber/astro/astro/tvm"
 	"github.com/uber/astro/astro/utils"
+
+	"github.com/hashicorp/go-multierror"
 )
 
 // Project is a collection of Terraform modules, based on configuration.
@@ -41,11 +55,56 @@ type Project struct {
 	config            *conf.Project
 	sessions          *SessionRepo
 	terraformVersions *tvm.VersionRepo
+
+	// state, if set, is where the history of sessions, the run lock and
+	// the stats of each run are kept, beyond the session repo.
+	state state.Backend
+
+	// secrets resolve the references of secret variables, by scheme.
+	secrets secrets.Resolvers
+
+	// terraformOutput, if set, receives the output of Terraform as it runs.
+	terraformOutput io.Writer
+
+	// lockTimeoutOverride, if set, is how long every execution waits for
+	// the lock of its state, instead of its lock_timeout.
+	lockTimeoutOverride *time.Duration
+
+	// timeoutWarnings, if set, is called when an execution runs for longer
+	// than its warn_after.
+	timeoutWarnings func(TimeoutWarning)
+
+	// sessionLogFormat, if set, is the format of the log file written to
+	// each session directory.
+	sessionLogFormat string
+
+	// command and commandArgs, if set, are the command line recorded in
+	// the sessions of the project, instead of the one of the process.
+	command     string
+	commandArgs []string
+
+	// version is the version of astro recorded in the manifest of each
+	// session, if set.
+	version string
+
+	// clock is used for everything that is timed or timestamped, and
+	// generateID for the IDs of new sessions.
+	clock      utils.Clock
+	generateID func() string
+
+	// terraformHashes caches the hashes of Terraform binaries, by path.
+	terraformHashes sync.Map
+
+	// geteuid returns the user ID astro runs as, for the preflight checks.
+	geteuid func() int
 }
 
 // NewProject returns a new instance of Project.
 func NewProject(opts ...Option) (*Project, error) {
-	project := &Project{}
+	project := &Project{
+		clock:   utils.SystemClock,
+		geteuid: os.Geteuid,
+	}
 
 	logger.Trace.Println("astro: initializing")
 
@@ -59,18 +118,47 @@ func NewProject(opts ...Option) (*Project, error) {
 	}
 	project.terraformVersions = versionRepo
 
+	if project.secrets == nil {
+		project.secrets = secrets.Resolvers{}
+	}
+	for scheme, resolver := range secrets.Defaults(project.config.VaultPath, project.config.AWSCLIPath) {
+		if _, ok := project.secrets[scheme]; !ok {
+			project.secrets[scheme] = resolver
+		}
+	}
+
+	if project.generateID == nil {
+		project.generateID = func() string {
+			return utils.ULIDAt(project.clock.Now()).String()
+		}
+	}
+
 	sessionRepoPath := filepath.Join(project.config.SessionRepoDir, ".astro")
-	sessions, err := NewSessionRepo(project, sessionRepoPath, utils.ULIDString)
+	sessions, err := NewSessionRepo(project, sessionRepoPath, project.generateID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize session repository: %v", err)
 	}
 	project.sessions = sessions
 
+	if project.config.State != nil {
+		backend, err := state.New(*project.config.State, project.config.AWSCLIPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize state backend: %v", err)
+		}
+		project.state = backend
+	}
+
 	// check dependency graph is all good
 	if _, err := project.executions(NoExecutionParameters()).graph(); err != nil {
 		return nil, err
 	}
 
+	// check variable files exist, where they can be resolved without user
+	// variables
+	if err := project.executions(NoExecutionParameters()).checkVarFiles(); err != nil {
+		return nil, err
+	}
+
 	if project.config.Hooks.Startup == nil {
 		return project, nil
 	}
@@ -79,8 +167,10 @@ func NewProject(opts ...Option) (*Project, error) {
 	if err != nil {
 		return nil, err
 	}
+	stopWatching := session.watchSignals()
+	defer stopWatching()
 	for _, hook := range project.config.Hooks.Startup {
-		if err := runCommandkAndSetEnvironment(session.path, hook); err != nil {
+		if err := runCommandkAndSetEnvironment(session.ctx, session.path, hook); err != nil {
 			return nil, fmt.Errorf("error running Startup hook: %v", err)
 		}
 	}
@@ -98,6 +188,328 @@ func (c *Project) executions(parameters ExecutionParameters) executionSet {
 	return results
 }
 
+// boundExecutions returns the executions for the parameters, bound to the
+// user variables and filtered by execution ID. The values of the user
+// variables are checked first, so that nothing runs if one is invalid.
+func (c *Project) boundExecutions(parameters ExecutionParameters) ([]*boundExecution, error) {
+	if err := c.checkUserVariables(parameters); err != nil {
+		return nil, err
+	}
+	return c.bindExecutions(parameters)
+}
+
+// checkUserVariables checks that the user variables of the executions of
+// the parameters have valid values, and that the variables with values
+// that are required were given one. Required variables without values are
+// reported when the executions are bound.
+func (c *Project) checkUserVariables(parameters ExecutionParameters) (errs error) {
+	checked := map[string]bool{}
+	reported := map[string]bool{}
+	var missing []string
+	for _, e := range c.executions(parameters) {
+		moduleConfig := e.ModuleConfig()
+		for _, variable := range moduleConfig.Variables {
+			key := moduleConfig.Name + "/" + variable.Name
+			if checked[key] || variable.IsComputed() {
+				continue
+			}
+			checked[key] = true
+
+			value := parameters.UserVars.Values[variable.Name]
+			if value == "" {
+				if variable.IsRequired() && variable.IsFilter() && !utils.StringSliceContains(missing, variable.Name) {
+					missing = append(missing, variable.Name)
+				}
+				continue
+			}
+			// modules may share a variable, which is only reported once
+			if err := variable.CheckValue(value); err != nil && !reported[err.Error()] {
+				reported[err.Error()] = true
+				errs = multierror.Append(errs, err)
+			}
+		}
+	}
+
+	if errs != nil {
+		return errs
+	}
+	if missing != nil {
+		sort.Strings(missing)
+		return &MissingRequiredVarsError{missing: missing}
+	}
+	return nil
+}
+
+// bindExecutions is boundExecutions, without checking the values of the
+// user variables.
+func (c *Project) bindExecutions(parameters ExecutionParameters) ([]*boundExecution, error) {
+	executions := c.executions(parameters)
+	if len(executions) == 0 && parameters.UserVars.FilterCount() > 0 {
+		return nil, c.noExecutionsMatched(parameters)
+	}
+
+	boundExecutions, err := executions.bindAll(parameters.UserVars.Values)
+	if err != nil {
+		return nil, err
+	}
+
+	if parameters.ExecutionPatterns != nil {
+		if boundExecutions, err = filterByPatterns(boundExecutions, parameters.ExecutionPatterns); err != nil {
+			return nil, err
+		}
+	}
+
+	if parameters.Filter != "" {
+		if boundExecutions, err = filterByExpression(boundExecutions, parameters.Filter); err != nil {
+			return nil, err
+		}
+	}
+
+	if parameters.ExecutionIDs == nil {
+		return dedupeExecutions(boundExecutions, c.config.Policies), nil
+	}
+
+	var results []*boundExecution
+	for _, b := range boundExecutions {
+		if utils.StringSliceContains(parameters.ExecutionIDs, b.ID()) {
+			results = append(results, b)
+		}
+	}
+
+	return dedupeExecutions(results, c.config.Policies), nil
+}
+
+// filterByExpression returns the executions that match the filter
+// expression. It returns an error if none of them do.
+func filterByExpression(boundExecutions []*boundExecution, source string) ([]*boundExecution, error) {
+	expr, err := filter.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*boundExecution
+	for _, b := range boundExecutions {
+		if expr.Match(b.filterFields()) {
+			results = append(results, b)
+		}
+	}
+
+	if results == nil {
+		return nil, fmt.Errorf("no executions match filter %s", source)
+	}
+
+	return results, nil
+}
+
+// filterByPatterns returns the executions whose IDs match any of the glob
+// patterns. It returns an error if a pattern doesn't match any execution.
+func filterByPatterns(boundExecutions []*boundExecution, patterns []string) ([]*boundExecution, error) {
+	var ids []string
+	for _, b := range boundExecutions {
+		ids = append(ids, b.ID())
+	}
+
+	matched := map[string]bool{}
+	for _, pattern := range patterns {
+		found := false
+		for _, id := range ids {
+			ok, err := path.Match(pattern, id)
+			if err != nil {
+				return nil, fmt.Errorf("invalid execution pattern %q: %v", pattern, err)
+			}
+			if ok {
+				matched[id] = true
+				found = true
+			}
+		}
+
+		if !found {
+			if suggestions := utils.ClosestMatches(pattern, ids); suggestions != nil {
+				return nil, fmt.Errorf("no executions match %s; did you mean %s?", pattern, strings.Join(suggestions, " or "))
+			}
+			return nil, fmt.Errorf("no executions match %s", pattern)
+		}
+	}
+
+	var results []*boundExecution
+	for _, b := range boundExecutions {
+		if matched[b.ID()] {
+			results = append(results, b)
+		}
+	}
+
+	return results, nil
+}
+
+// NewScheduler returns a scheduler for the executions of the project. It
+// runs at most parallelism executions at the same time, or the parallelism
+// of the configuration if it's not set, and at most the parallelism of
+// each module and concurrency group. Its limits can be changed, and it can
+// be paused and resumed, while the executions it was passed to with
+// ExecutionParameters.Scheduler run. The groups of the parallelism of
+// modules are named "module:<name>", and concurrency groups
+// "group:<name>".
+func (c *Project) NewScheduler(parallelism int) *scheduler.Scheduler {
+	if parallelism < 1 {
+		parallelism = c.config.Parallelism
+	}
+	return newScheduler(parallelism, c.config.Modules, c.config.Groups)
+}
+
+// executionLimiter returns a limiter for the executions that run at the
+// same time, with the scheduler from the parameters if it's set, or else
+// a new one with the parallelism from the parameters, or else
+// commandParallelism, the parallelism that the configuration sets for the
+// command, e.g. plan_parallelism, or else the parallelism of the
+// configuration.
+func (c *Project) executionLimiter(parameters ExecutionParameters, commandParallelism int) *executionLimiter {
+	if parameters.Scheduler != nil {
+		return newExecutionLimiter(parameters.Scheduler)
+	}
+	parallelism := parameters.Parallelism
+	if parallelism < 1 {
+		parallelism = commandParallelism
+	}
+	return newExecutionLimiter(c.NewScheduler(parallelism))
+}
+
+// defaultMaxOutputInMemory is the number of bytes of the stdout and of the
+// stderr of each Terraform command that are kept in memory, if the
+// configuration doesn't set it.
+const defaultMaxOutputInMemory = 1 << 20
+
+// maxOutputInMemory returns the number of bytes of each stream of
+// Terraform output that are kept in memory.
+func (c *Project) maxOutputInMemory() int {
+	if c.config.MaxOutputInMemory > 0 {
+		return c.config.MaxOutputInMemory
+	}
+	return defaultMaxOutputInMemory
+}
+
+// terraformPath returns the path of the Terraform binary that a module
+// runs with: its path, if it's set, or else where tvm installs its
+// version and flavor, which may not have been downloaded yet.
+func (c *Project) terraformPath(terraform conf.Terraform) string {
+	if terraform.Path == "" && terraform.Version != nil {
+		return c.terraformVersions.ForFlavor(terraform.TVMFlavor()).Path(terraform.Version.String())
+	}
+	return terraform.Path
+}
+
+// lockTimeout returns how long the executions of the module wait for the
+// lock of their state.
+func (c *Project) lockTimeout(moduleConfig conf.Module) time.Duration {
+	if c.lockTimeoutOverride != nil {
+		return *c.lockTimeoutOverride
+	}
+	return moduleConfig.Terraform.LockTimeoutDuration()
+}
+
+// noExecutionsMatched returns an error for variable filters that didn't
+// match any executions, with suggestions for values that are close to the
+// ones provided.
+func (c *Project) noExecutionsMatched(parameters ExecutionParameters) error {
+	err := &NoExecutionsMatchedError{
+		filters:     map[string]string{},
+		suggestions: map[string][]string{},
+	}
+
+	for name := range parameters.UserVars.Filters {
+		value := parameters.UserVars.Values[name]
+		err.filters[name] = value
+
+		var values []string
+		for _, m := range c.modules(parameters.ModuleNames) {
+			for _, variable := range m.config.Variables {
+				if variable.Name == name {
+					values = append(values, variable.Values...)
+				}
+			}
+		}
+
+		if !utils.StringSliceContains(values, value) {
+			if suggestions := utils.ClosestMatches(value, values); suggestions != nil {
+				err.suggestions[name] = suggestions
+			}
+		}
+	}
+
+	return err
+}
+
+// ExecutionIDs returns the IDs of the executions that would run with the
+// parameters, grouped by module name.
+func (c *Project) ExecutionIDs(parameters ExecutionParameters) (map[string][]string, error) {
+	boundExecutions, err := c.boundExecutions(parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := map[string][]string{}
+	for _, b := range boundExecutions {
+		name := b.ModuleConfig().Name
+		ids[name] = append(ids[name], b.ID())
+	}
+
+	return ids, nil
+}
+
+// Dependencies returns the IDs of the executions that would run with the
+// parameters, mapped to the IDs of the executions each one depends on.
+// This is the graph that Apply walks. Dependencies are included even if
+// the parameters filter them out.
+func (c *Project) Dependencies(parameters ExecutionParameters) (map[string][]string, error) {
+	boundExecutions, err := c.boundExecutions(parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	// The graph is built from all executions, as dependencies that are
+	// filtered out cannot be resolved otherwise
+	values := map[string]string{}
+	for key, val := range parameters.UserVars.Values {
+		if !parameters.UserVars.HasFilter(key) {
+			values[key] = val
+		}
+	}
+	allExecutions, err := c.bindExecutions(ExecutionParameters{
+		UserVars: &UserVariables{Values: values},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	executions := make(executionSet, len(allExecutions))
+	for i, e := range allExecutions {
+		executions[i] = e
+	}
+
+	graph, err := executions.graph()
+	if err != nil {
+		return nil, err
+	}
+
+	deps := map[string][]string{}
+	for _, b := range boundExecutions {
+		deps[b.ID()] = []string{}
+	}
+	for _, edge := range graph.Edges() {
+		if _, ok := edge.Source().(graphNodeRoot); ok {
+			continue
+		}
+		id := edge.Source().(*boundExecution).ID()
+		if _, ok := deps[id]; ok {
+			deps[id] = append(deps[id], edge.Target().(*boundExecution).ID())
+		}
+	}
+	for _, ids := range deps {
+		sort.Strings(ids)
+	}
+
+	return deps, nil
+}
+
 // modules creates a list of modules based on the config.
 func (c *Project) modules(moduleNames []string) []*module {
 	var results []*module
@@ -113,12 +525,26 @@ func (c *Project) modules(moduleNames []string) []*module {
 }
 
 // Plan does a Terraform plan for every possible execution, in
-// parallel, ignoring dependencies.
+// parallel, ignoring dependencies. If PlanExecutionParameters.UseGraph or
+// the plan_use_graph project setting is set, executions are planned
+// after the executions they depend on instead, like for Apply.
+//
+// Status updates and results are sent on the returned channels, which are
+// both closed once all executions have finished. Executions never block
+// on either channel: status updates are dropped if the consumer falls
+// behind. Use Collect to wait for the results without handling status
+// updates.
 func (c *Project) Plan(parameters PlanExecutionParameters) (<-chan string, <-chan *Result, error) {
-	logger.Trace.Println("astro: running Plan")
+	logger.Debug("starting plan", logger.Fields{"modules": parameters.ModuleNames})
+
+	if parameters.Frozen {
+		if err := c.VerifyLock(parameters.ModuleNames); err != nil {
+			return nil, nil, err
+		}
+	}
 
 	// Binds user vars
-	boundExecutions, err := c.executions(parameters.ExecutionParameters).bindAll(parameters.UserVars.Values)
+	boundExecutions, err := c.boundExecutions(parameters.ExecutionParameters)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -129,18 +555,197 @@ func (c *Project) Plan(parameters PlanExecutionParameters) (<-chan string, <-cha
 		return nil, nil, err
 	}
 
-	return session.plan(boundExecutions, parameters.Detach)
+	if parameters.DetectDrift {
+		if parameters.Detach {
+			return nil, nil, errors.New("drift cannot be detected with remote state detached")
+		}
+		if parameters.SavePlans {
+			return nil, nil, errors.New("plans made to detect drift cannot be saved")
+		}
+		session.detectDrift = true
+	}
+
+	if parameters.RawOutput {
+		session.rawOutput = true
+	}
+
+	if parameters.EstimateCost {
+		if c.config.Cost == nil {
+			return nil, nil, errors.New("cost estimation is not configured; add a cost block to the config")
+		}
+		session.estimateCosts = true
+	}
+
+	if parameters.SavePlans {
+		if parameters.Detach {
+			return nil, nil, errors.New("plans made with remote state detached cannot be saved")
+		}
+		// Only the plan of one workspace could be saved for each
+		// execution, and applies use the default workspace
+		for _, b := range boundExecutions {
+			if b.ModuleConfig().Workspaces != "" {
+				return nil, nil, fmt.Errorf("plans of modules with workspaces cannot be saved: %s", b.ModuleConfig().Name)
+			}
+		}
+
+		sourceHashes, err := c.sourceHashes(parameters.ModuleNames)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		plans := &savedPlans{
+			UserVars:          parameters.UserVars.Values,
+			Filters:           parameters.UserVars.Filters,
+			ModuleNames:       parameters.ModuleNames,
+			ExecutionIDs:      parameters.ExecutionIDs,
+			ExecutionPatterns: parameters.ExecutionPatterns,
+			Filter:            parameters.Filter,
+			Targets:           parameters.Targets,
+			PlannedAt:         c.clock.Now(),
+			SourceHashes:      sourceHashes,
+		}
+		for _, b := range boundExecutions {
+			plans.Executions = append(plans.Executions, b.ID())
+		}
+
+		if err := session.writeSavedPlans(plans); err != nil {
+			return nil, nil, err
+		}
+		session.savePlans = true
+	}
+
+	// Dependencies on executions that are not planned are ignored
+	withGraph := parameters.UseGraph || c.config.PlanUseGraph
+
+	var planFn func([]*boundExecution, *executionLimiter, bool) (<-chan string, <-chan *Result, error)
+	if withGraph {
+		planFn = session.planWithGraph
+	} else {
+		planFn = session.plan
+	}
+
+	status, results, err := planFn(boundExecutions, c.executionLimiter(parameters.ExecutionParameters, c.config.PlanParallelism), parameters.Detach)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return status, c.recordRun(session, "plan", parameters.ExecutionParameters, boundExecutions, func() {}, reportAliases(boundExecutions, addGuidance(boundExecutions, results))), nil
+}
+
+// SessionID returns the ID of the current session. Plans saved with
+// PlanExecutionParameters.SavePlans can be applied by passing it as
+// ApplyExecutionParameters.FromSession.
+func (c *Project) SessionID() (string, error) {
+	session, err := c.sessions.Current()
+	if err != nil {
+		return "", err
+	}
+	return session.id, nil
 }
 
 // Apply does a Terraform apply for every possible execution,
 // in parallel, taking into consideration dependencies. It returns an
 // error if it is unable to start, e.g. due to a missing required
-// variable.
+// variable. The returned channels behave in the same way as for Plan.
 func (c *Project) Apply(parameters ApplyExecutionParameters) (<-chan string, <-chan *Result, error) {
-	logger.Trace.Println("astro: running Apply")
+	logger.Debug("starting apply", logger.Fields{"modules": parameters.ModuleNames, "from_session": parameters.FromSession})
+
+	// Apply the saved plans of the session with the same executions they
+	// were planned for
+	if parameters.FromSession != "" {
+		if parameters.ExecutionPatterns != nil || parameters.Filter != "" {
+			return nil, nil, errors.New("executions cannot be selected when applying saved plans; they are applied for the executions they were planned for")
+		}
+
+		if parameters.Targets != nil {
+			return nil, nil, errors.New("resource targets cannot be given when applying saved plans; the plans were made with the targets given to plan")
+		}
+
+		session, err := c.sessions.Open(parameters.FromSession)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		plans, err := session.readSavedPlans()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if session.stalePlans, err = c.stalePlans(plans); err != nil {
+			return nil, nil, err
+		}
+
+		// Only some of the saved plans may be applied
+		if parameters.ExecutionIDs != nil {
+			for _, id := range parameters.ExecutionIDs {
+				if !utils.StringSliceContains(plans.Executions, id) {
+					return nil, nil, fmt.Errorf("no plan for %v was saved in session %v", id, parameters.FromSession)
+				}
+			}
+		} else {
+			parameters.ExecutionIDs = plans.Executions
+		}
+
+		parameters.UserVars = plans.userVariables()
+		parameters.ModuleNames = plans.ModuleNames
+		parameters.Targets = plans.Targets
+	}
+
+	// Resume the last apply of the session with the executions it ran
+	var resumed *applyRecord
+	if parameters.Resume != "" {
+		if parameters.FromSession != "" {
+			return nil, nil, errors.New("an apply cannot be resumed and apply saved plans at the same time")
+		}
+
+		if parameters.targeted() || parameters.Targets != nil {
+			return nil, nil, errors.New("executions and resource targets cannot be selected when resuming an apply; it runs the executions it ran before")
+		}
+
+		session, err := c.sessions.open(parameters.Resume)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if resumed, err = session.readApplyRecord(); err != nil {
+			return nil, nil, err
+		}
+
+		session.resumed = true
+		session.resumedApplied = resumed.applied()
+
+		parameters.UserVars = resumed.userVariables()
+		parameters.ExecutionIDs = resumed.Executions
+		parameters.TerraformParameters = resumed.TerraformParameters
+		parameters.Targets = resumed.Targets
+	}
+
+	if parameters.Frozen {
+		if err := c.VerifyLock(parameters.ModuleNames); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var gitSHA string
+	if c.config.RequireCleanTree {
+		sha, err := c.verifyCleanTree(parameters.ModuleNames)
+		if err != nil {
+			return nil, nil, err
+		}
+		gitSHA = sha
+	}
+
+	var pinnedBuilds map[string]terraformBuild
+	if parameters.SameVersionsAs != "" {
+		builds, err := c.sessions.readTerraformBuilds(parameters.SameVersionsAs)
+		if err != nil {
+			return nil, nil, err
+		}
+		pinnedBuilds = builds
+	}
 
 	// Bind user vars
-	boundExecutions, err := c.executions(parameters.ExecutionParameters).bindAll(parameters.UserVars.Values)
+	boundExecutions, err := c.boundExecutions(parameters.ExecutionParameters)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -151,12 +756,80 @@ func (c *Project) Apply(parameters ApplyExecutionParameters) (<-chan string, <-c
 		return nil, nil, err
 	}
 
-	var applyFn func([]*boundExecution) (<-chan string, <-chan *Result, error)
-	if parameters.ModuleNames != nil {
-		applyFn = session.apply
-	} else {
-		applyFn = session.applyWithGraph
+	session.pinnedBuilds = pinnedBuilds
+	session.pinnedSession = parameters.SameVersionsAs
+	session.approveRisk = parameters.ApproveRisk
+
+	if session.fromSavedPlans && len(boundExecutions) != len(parameters.ExecutionIDs) {
+		return nil, nil, fmt.Errorf("the executions planned in session %v no longer match the configuration; plan again", session.id)
+	}
+
+	if gitSHA != "" {
+		if err := session.recordGitSHA(gitSHA); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if session.resumed && len(boundExecutions) != len(parameters.ExecutionIDs) {
+		return nil, nil, fmt.Errorf("the executions applied in session %v no longer match the configuration; apply again", session.id)
+	}
+
+	record := resumed
+	if record == nil {
+		record = newApplyRecord(parameters, boundExecutions)
+	}
+
+	unlock, err := c.lockRun(session, "apply")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	status, results, err := session.applyWithGraph(boundExecutions, c.executionLimiter(parameters.ExecutionParameters, c.config.ApplyParallelism))
+	if err != nil {
+		unlock()
+		return nil, nil, err
+	}
+
+	return status, c.recordRun(session, "apply", parameters.ExecutionParameters, boundExecutions, unlock, reportAliases(boundExecutions, addGuidance(boundExecutions, c.recordApplied(boundExecutions, session.recordApplyResults(record, results))))), nil
+}
+
+// Destroy does a Terraform destroy for every possible execution, in
+// parallel, taking into consideration dependencies: executions are
+// destroyed before the executions they depend on. The returned channels
+// behave in the same way as for Plan.
+func (c *Project) Destroy(parameters DestroyExecutionParameters) (<-chan string, <-chan *Result, error) {
+	logger.Debug("starting destroy", logger.Fields{"modules": parameters.ModuleNames})
+
+	if parameters.Frozen {
+		if err := c.VerifyLock(parameters.ModuleNames); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Bind user vars
+	boundExecutions, err := c.boundExecutions(parameters.ExecutionParameters)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Get session
+	session, err := c.sessions.Current()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	unlock, err := c.lockRun(session, "destroy")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Selected executions are still destroyed before the selected
+	// executions they depend on
+	status, results, err := session.destroyWithGraph(boundExecutions, c.executionLimiter(parameters.ExecutionParameters, c.config.ApplyParallelism))
+	if err != nil {
+		unlock()
+		return nil, nil, err
 	}
 
-	return applyFn(boundExecutions)
+	return status, c.recordRun(session, "destroy", parameters.ExecutionParameters, boundExecutions, unlock, reportAliases(boundExecutions, addGuidance(boundExecutions, c.recordDestroyed(results)))), nil
 }
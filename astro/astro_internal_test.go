@@ -17,9 +17,19 @@
 package astro
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/cost"
+	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/terraform"
 	"github.com/uber/astro/astro/utils"
 
 	"github.com/stretchr/testify/assert"
@@ -116,6 +126,199 @@ func TestPlanModulesFiltered(t *testing.T) {
 	}, testResultErrs(testReadResults(resultChan)))
 }
 
+func TestPlanExecutionPatterns(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-plan-success/astro.yaml")
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Plan(PlanExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			ExecutionPatterns: []string{"app-*-dev", "users"},
+			UserVars: &UserVariables{
+				Values: map[string]string{
+					"aws_region": "east1",
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]error{
+		"app-east1-dev": nil,
+		"users":         nil,
+	}, testResultErrs(testReadResults(resultChan)))
+
+	_, _, err = c.Plan(PlanExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			ExecutionPatterns: []string{"app-east1-deb"},
+			UserVars: &UserVariables{
+				Values: map[string]string{
+					"aws_region": "east1",
+				},
+			},
+		},
+	})
+	assert.EqualError(t, err, "no executions match app-east1-deb; did you mean app-east1-dev?")
+}
+
+func TestPlanFilterExpression(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-plan-success/astro.yaml")
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Plan(PlanExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			Filter: `label.team == "identity" || module =~ "app|database" && environment == "dev"`,
+			UserVars: &UserVariables{
+				Values: map[string]string{
+					"aws_region": "east1",
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]error{
+		"app-east1-dev":      nil,
+		"database-east1-dev": nil,
+		"users":              nil,
+	}, testResultErrs(testReadResults(resultChan)))
+}
+
+func TestPlanTargets(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-plan-success/astro.yaml")
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Plan(PlanExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			ModuleNames: []string{"users"},
+			UserVars:    NoUserVariables(),
+			Targets:     []string{"aws_iam_user.alice", `module.team["ops"]`},
+		},
+	})
+	require.NoError(t, err)
+
+	results := testReadResults(resultChan)
+	require.Contains(t, results, "users")
+	require.NoError(t, results["users"].Err())
+
+	var planArgs []string
+	for _, invocation := range results["users"].Invocations() {
+		if invocation.Args[1] == "plan" {
+			planArgs = invocation.Args
+		}
+	}
+	assert.Contains(t, planArgs, "-target=aws_iam_user.alice")
+	assert.Contains(t, planArgs, `-target=module.team["ops"]`)
+}
+
+func TestPlanDetectDrift(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-drift/astro.yaml")
+	require.NoError(t, err)
+	for i := range c.config.Modules {
+		if c.config.Modules[i].Name == "drifted" {
+			c.config.Modules[i].Terraform.Path = absolutePath("fixtures/mock-terraform/drift")
+		}
+	}
+
+	_, resultChan, err := c.Plan(PlanExecutionParameters{
+		ExecutionParameters: NoExecutionParameters(),
+		DetectDrift:         true,
+	})
+	require.NoError(t, err)
+
+	results := testReadResults(resultChan)
+	assert.Equal(t, map[string]error{
+		"clean":   nil,
+		"drifted": nil,
+	}, testResultErrs(results))
+
+	planResult := results["drifted"].TerraformResult().(*terraform.PlanResult)
+	assert.True(t, planResult.HasChanges())
+	assert.Contains(t, planResult.Changes(), "null_resource.a has been deleted")
+	assert.False(t, results["clean"].TerraformResult().(*terraform.PlanResult).HasChanges())
+
+	for id, result := range results {
+		for _, invocation := range result.Invocations() {
+			if invocation.Args[1] == "plan" {
+				// -refresh-only requires Terraform 0.15.4
+				assert.Equal(t, id == "drifted", utils.StringSliceContains(invocation.Args, "-refresh-only"), id)
+			}
+		}
+	}
+}
+
+func TestPlanMaxOutputInMemory(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-drift/astro.yaml")
+	require.NoError(t, err)
+	for i := range c.config.Modules {
+		if c.config.Modules[i].Name == "drifted" {
+			c.config.Modules[i].Terraform.Path = absolutePath("fixtures/mock-terraform/drift")
+		}
+	}
+	// all output is moved to files
+	c.config.MaxOutputInMemory = 1
+
+	_, resultChan, err := c.Plan(PlanExecutionParameters{
+		ExecutionParameters: NoExecutionParameters(),
+		DetectDrift:         true,
+	})
+	require.NoError(t, err)
+
+	results := testReadResults(resultChan)
+	require.NoError(t, results["drifted"].Err())
+
+	planResult := results["drifted"].TerraformResult().(*terraform.PlanResult)
+	assert.True(t, planResult.HasChanges())
+	assert.Contains(t, planResult.Changes(), "null_resource.a has been deleted")
+
+	output, err := os.ReadFile(results["drifted"].OutputPath())
+	require.NoError(t, err)
+	assert.Contains(t, string(output), "null_resource.a has been deleted")
+}
+
+func TestPlanLocale(t *testing.T) {
+	t.Parallel()
+
+	// The mock translates its output unless astro sets the locale
+	plan := func(rawOutput bool) *terraform.PlanResult {
+		c, err := NewProjectFromConfigFile("fixtures/test-locale/astro.yaml")
+		require.NoError(t, err)
+		c.config.Modules[0].Terraform.Path = absolutePath("fixtures/mock-terraform/localized")
+
+		_, resultChan, err := c.Plan(PlanExecutionParameters{
+			ExecutionParameters: NoExecutionParameters(),
+			RawOutput:           rawOutput,
+		})
+		require.NoError(t, err)
+
+		results := testReadResults(resultChan)
+		require.NoError(t, results["app"].Err())
+		return results["app"].TerraformResult().(*terraform.PlanResult)
+	}
+
+	planResult := plan(false)
+	assert.True(t, planResult.HasChanges())
+	assert.Equal(t, terraform.ChangeCounts{Add: 1}, planResult.ChangeCounts())
+	assert.Contains(t, planResult.Changes(), "null_resource.a will be created")
+	assert.NotContains(t, planResult.Changes(), "Terraform will perform the following actions:")
+
+	// The output is not parsed, so there are no counts
+	planResult = plan(true)
+	assert.True(t, planResult.HasChanges())
+	assert.Equal(t, terraform.ChangeCounts{}, planResult.ChangeCounts())
+	assert.Contains(t, planResult.Changes(), "Terraform will perform the following actions:")
+	assert.Contains(t, planResult.Changes(), "Plan: 1 to add, 0 to change, 0 to destroy.")
+}
+
 func TestPlanVariablesFiltered(t *testing.T) {
 	c, err := NewProjectFromConfigFile("fixtures/foosite.yaml")
 	require.NoError(t, err)
@@ -145,6 +348,111 @@ func TestPlanVariablesFiltered(t *testing.T) {
 	}, testResultErrs(testReadResults(resultChan)))
 }
 
+func TestDependencies(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-plan-success/astro.yaml")
+	require.NoError(t, err)
+
+	deps, err := c.Dependencies(ExecutionParameters{
+		UserVars: &UserVariables{
+			Values: map[string]string{
+				"aws_region":  "east1",
+				"environment": "dev",
+			},
+			Filters: map[string]bool{
+				"environment": true,
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	// users is filtered out, but still listed as a dependency
+	assert.Equal(t, map[string][]string{
+		"app-east1-dev":      {"database-east1-dev", "network-east1-dev"},
+		"database-east1-dev": {"users"},
+		"network-east1-dev":  {},
+	}, deps)
+}
+
+func TestListExecutions(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-plan-success/astro.yaml")
+	require.NoError(t, err)
+
+	// aws_region is required, but not given, so it keeps its placeholder
+	executions, err := c.ListExecutions(ExecutionParameters{
+		ModuleNames: []string{"app", "mgmt", "users"},
+		UserVars: &UserVariables{
+			Values:  map[string]string{"environment": "dev"},
+			Filters: map[string]bool{"environment": true},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []ExecutionSummary{
+		{
+			ID:           "app-{aws_region}-dev",
+			Module:       "app",
+			Variables:    map[string]string{"aws_region": "{aws_region}", "environment": "dev"},
+			Dependencies: []string{"database-{aws_region}-dev", "network-{aws_region}-dev"},
+		},
+	}, executions)
+
+	executions, err = c.ListExecutions(ExecutionParameters{
+		ModuleNames: []string{"mgmt", "users"},
+		UserVars: &UserVariables{
+			Values: map[string]string{"aws_region": "east1"},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []ExecutionSummary{
+		{
+			ID:           "mgmt-east1",
+			Module:       "mgmt",
+			Variables:    map[string]string{"aws_region": "east1"},
+			Dependencies: []string{"network-east1-mgmt"},
+		},
+		{
+			ID:           "users",
+			Module:       "users",
+			Variables:    map[string]string{},
+			Dependencies: []string{},
+		},
+	}, executions)
+}
+
+func TestListModules(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-plan-success/astro.yaml")
+	require.NoError(t, err)
+
+	modules := c.ListModules()
+	require.Len(t, modules, 5)
+
+	var names []string
+	for _, m := range modules {
+		names = append(names, m.Name)
+	}
+	assert.Equal(t, []string{"app", "database", "network", "mgmt", "users"}, names)
+
+	assert.Equal(t, 3, modules[0].Executions)
+	assert.Equal(t, []string{"database", "network"}, modules[0].Dependencies)
+	assert.Equal(t, []string{}, modules[0].Dependents)
+	assert.Equal(t, []string{"app", "mgmt"}, modules[2].Dependents)
+	assert.Equal(t, ModuleSummary{
+		Name:         "users",
+		Path:         ".",
+		Labels:       map[string]string{"team": "identity"},
+		Executions:   1,
+		Dependencies: []string{},
+		Dependents:   []string{"database"},
+	}, modules[4])
+}
+
 func TestApplySuccess(t *testing.T) {
 	t.Parallel()
 
@@ -232,21 +540,115 @@ func TestApplyFailModule(t *testing.T) {
 	}
 }
 
-// Tests that variables are passed to the modules that declare them and not
-// passed to the modules that didn't
-func TestPassVariables(t *testing.T) {
+func TestApplyResume(t *testing.T) {
 	t.Parallel()
 
-	c, err := NewProjectFromConfigFile("fixtures/test-pass-variables/astro.yaml")
+	// a mock Terraform that fails to apply the "fail" module until the
+	// marker file is removed
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "failing")
+	terraformPath := filepath.Join(dir, "terraform")
+	require.NoError(t, os.WriteFile(marker, nil, 0644))
+	require.NoError(t, os.WriteFile(terraformPath, []byte(`#!/bin/bash
+case "$1" in
+    version) echo "Terraform v0.8.8" ;;
+    apply) [ "$(basename "$(pwd)")" == "fail" ] && [ -f "`+marker+`" ] && exit 1 ;;
+esac
+exit 0
+`), 0755))
+
+	newProject := func() *Project {
+		c, err := NewProjectFromConfigFile("fixtures/test-apply-fail-module/astro.yaml")
+		require.NoError(t, err)
+		for i := range c.config.Modules {
+			c.config.Modules[i].Terraform.Path = terraformPath
+		}
+		return c
+	}
+
+	c := newProject()
+	_, resultChan, err := c.Apply(ApplyExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			UserVars: &UserVariables{
+				Values: map[string]string{
+					"aws_region": "east1",
+				},
+			},
+		},
+	})
 	require.NoError(t, err)
+	results := testResultErrs(testReadResults(resultChan))
+	require.Error(t, results["users"])
+	require.NotContains(t, results, "app-east1-dev")
 
-	c.config.TerraformDefaults.Path = absolutePath("fixtures/mock-terraform/success")
+	sessionID, err := c.SessionID()
+	require.NoError(t, err)
 
-	_, resultChan, err := c.Plan(PlanExecutionParameters{
+	// executions can't be selected when resuming
+	_, _, err = newProject().Apply(ApplyExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			ModuleNames: []string{"users"},
+			UserVars:    NoUserVariables(),
+		},
+		Resume: sessionID,
+	})
+	assert.Error(t, err)
+
+	require.NoError(t, os.Remove(marker))
+
+	c = newProject()
+	_, resultChan, err = c.Apply(ApplyExecutionParameters{
+		ExecutionParameters: NoExecutionParameters(),
+		Resume:              sessionID,
+	})
+	require.NoError(t, err)
+	resumed := testReadResults(resultChan)
+
+	for id, result := range resumed {
+		require.NoError(t, result.Err(), id)
+	}
+	// the executions that failed or didn't run are applied
+	for _, id := range []string{"users", "app-east1-dev", "database-east1-prod"} {
+		require.Contains(t, resumed, id)
+		assert.Equal(t, "", resumed[id].SkipReason(), id)
+		assert.NotNil(t, resumed[id].TerraformResult(), id)
+	}
+	// and the executions that were applied are not
+	for _, id := range []string{"network-east1-dev", "mgmt-east1"} {
+		require.Contains(t, resumed, id)
+		assert.Equal(t, alreadyAppliedReason, resumed[id].SkipReason(), id)
+		assert.Nil(t, resumed[id].TerraformResult(), id)
+	}
+
+	resumedSessionID, err := c.SessionID()
+	require.NoError(t, err)
+	assert.Equal(t, sessionID, resumedSessionID)
+
+	session, err := c.sessions.Current()
+	require.NoError(t, err)
+	record, err := session.readApplyRecord()
+	require.NoError(t, err)
+	assert.Len(t, record.Executions, len(resumed))
+	assert.Len(t, record.applied(), len(resumed))
+}
+
+func TestErrorGuidance(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-apply-fail-module/astro.yaml")
+	require.NoError(t, err)
+
+	for i := range c.config.Modules {
+		c.config.Modules[i].OnErrorMessage = "Ask #team-" + c.config.Modules[i].Name + " for help.\n"
+		c.config.Modules[i].Docs = []string{"https://example.com/runbooks/" + c.config.Modules[i].Name}
+	}
+
+	_, resultChan, err := c.Apply(ApplyExecutionParameters{
 		ExecutionParameters: ExecutionParameters{
+			ModuleNames: []string{"users", "mgmt"},
 			UserVars: &UserVariables{
 				Values: map[string]string{
-					"region": "east1",
+					"aws_region": "east1",
 				},
 			},
 		},
@@ -254,10 +656,1100 @@ func TestPassVariables(t *testing.T) {
 	require.NoError(t, err)
 
 	results := testReadResults(resultChan)
+	require.Error(t, results["users"].Err())
+	assert.Equal(t, "Ask #team-users for help.\nSee: https://example.com/runbooks/users", results["users"].Guidance())
+	// only failures have guidance
+	require.NoError(t, results["mgmt-east1"].Err())
+	assert.Equal(t, "", results["mgmt-east1"].Guidance())
+}
 
-	// Mocked terraform prints parameters it was called with to stderr.
-	// Check that variables were passed to the module that declared it and not
-	// passed to the one that didn't.
-	assert.Contains(t, results["bar-east1"].TerraformResult().Stderr(), "-var region=east1")
-	assert.NotContains(t, results["foo"].TerraformResult().Stderr(), "-var")
+func TestPlanPolicies(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-policies/astro.yaml")
+	require.NoError(t, err)
+	for i := range c.config.Modules {
+		c.config.Modules[i].Terraform.Path = absolutePath("fixtures/mock-terraform/json-plan")
+	}
+
+	_, resultChan, err := c.Plan(PlanExecutionParameters{
+		ExecutionParameters: NoExecutionParameters(),
+	})
+	require.NoError(t, err)
+
+	results := testReadResults(resultChan)
+
+	require.NoError(t, results["db"].Err())
+	assert.Equal(t, []string{"policy violation: null_resource.a has no owner tag"}, results["db"].Warnings())
+
+	var violation *PolicyViolationError
+	require.True(t, errors.As(results["app"].Err(), &violation))
+	assert.Equal(t, []string{"null_resource.a must not be created"}, violation.Violations)
+	// the plan is still shown
+	assert.NotNil(t, results["app"].TerraformResult())
+}
+
+func TestPlanPoliciesWorkspaces(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-policies/astro.yaml")
+	require.NoError(t, err)
+	for i := range c.config.Modules {
+		c.config.Modules[i].Terraform.Path = absolutePath("fixtures/mock-terraform/json-plan")
+		c.config.Modules[i].Workspaces = "*"
+	}
+
+	_, resultChan, err := c.Plan(PlanExecutionParameters{
+		ExecutionParameters: NoExecutionParameters(),
+	})
+	require.NoError(t, err)
+
+	results := testReadResults(resultChan)
+
+	require.Contains(t, results, "db")
+	require.NoError(t, results["db"].Err())
+	require.Len(t, results["db"].SubResults(), 2)
+	for _, sub := range results["db"].SubResults() {
+		assert.Equal(t, []string{"policy violation: null_resource.a has no owner tag"}, sub.Warnings())
+	}
+
+	// every workspace of app violates the denying policy
+	require.Contains(t, results, "app")
+	var violation *PolicyViolationError
+	require.Len(t, results["app"].SubResults(), 2)
+	for _, sub := range results["app"].SubResults() {
+		require.True(t, errors.As(sub.Err(), &violation))
+		assert.Equal(t, []string{"null_resource.a must not be created"}, violation.Violations)
+	}
+	assert.Error(t, results["app"].Err())
+}
+
+func TestPlanCost(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-cost/astro.yaml")
+	require.NoError(t, err)
+	for i := range c.config.Modules {
+		c.config.Modules[i].Terraform.Path = absolutePath("fixtures/mock-terraform/json-plan")
+	}
+
+	_, resultChan, err := c.Plan(PlanExecutionParameters{
+		ExecutionParameters: NoExecutionParameters(),
+		EstimateCost:        true,
+	})
+	require.NoError(t, err)
+
+	results := testReadResults(resultChan)
+	for _, id := range []string{"app", "db"} {
+		require.NoError(t, results[id].Err())
+		assert.Equal(t, &cost.Estimate{
+			Currency:    "USD",
+			PastMonthly: 10,
+			Monthly:     30.5,
+		}, results[id].Cost())
+	}
+}
+
+func TestPlanCostWorkspaces(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-cost/astro.yaml")
+	require.NoError(t, err)
+	for i := range c.config.Modules {
+		c.config.Modules[i].Terraform.Path = absolutePath("fixtures/mock-terraform/json-plan")
+		c.config.Modules[i].Workspaces = "customer-*"
+	}
+
+	_, resultChan, err := c.Plan(PlanExecutionParameters{
+		ExecutionParameters: NoExecutionParameters(),
+		EstimateCost:        true,
+	})
+	require.NoError(t, err)
+
+	results := testReadResults(resultChan)
+	for _, id := range []string{"app", "db"} {
+		require.Contains(t, results, id)
+		require.NoError(t, results[id].Err())
+		require.Len(t, results[id].SubResults(), 1)
+		assert.Equal(t, &cost.Estimate{
+			Currency:    "USD",
+			PastMonthly: 10,
+			Monthly:     30.5,
+		}, results[id].SubResults()[0].Cost())
+	}
+}
+
+func TestPlanCostNotConfigured(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-drift/astro.yaml")
+	require.NoError(t, err)
+
+	_, _, err = c.Plan(PlanExecutionParameters{
+		ExecutionParameters: NoExecutionParameters(),
+		EstimateCost:        true,
+	})
+	assert.EqualError(t, err, "cost estimation is not configured; add a cost block to the config")
+}
+
+func TestSessionLog(t *testing.T) {
+	config, err := NewConfigFromFile("fixtures/test-plan-success/astro.yaml")
+	require.NoError(t, err)
+
+	c, err := NewProject(WithConfig(*config), WithSessionLog(logger.FormatJSON))
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Plan(PlanExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			ModuleNames: []string{"users"},
+			UserVars:    NoUserVariables(),
+		},
+	})
+	require.NoError(t, err)
+	testReadResults(resultChan)
+
+	session, err := c.sessions.Current()
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(session.path, sessionLogFile))
+	require.NoError(t, err)
+
+	var messages []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		messages = append(messages, record["msg"].(string))
+	}
+	assert.Contains(t, messages, "running plan")
+	assert.Contains(t, messages, "running terraform")
+}
+
+func TestSessionLogClosed(t *testing.T) {
+	config, err := NewConfigFromFile("fixtures/test-plan-success/astro.yaml")
+	require.NoError(t, err)
+	config.SessionRepoDir = t.TempDir()
+
+	c, err := NewProject(WithConfig(*config), WithSessionLog(logger.FormatJSON))
+	require.NoError(t, err)
+
+	session, err := c.sessions.Current()
+	require.NoError(t, err)
+
+	// another project doesn't take over the log of the session
+	other, err := NewProject(WithConfig(*config), WithSessionLog(logger.FormatJSON))
+	require.NoError(t, err)
+	_, err = other.sessions.Current()
+	require.NoError(t, err)
+	require.NoError(t, other.Close())
+
+	logger.Debug("before close")
+	require.NoError(t, c.Close())
+	logger.Debug("after close")
+
+	data, err := os.ReadFile(filepath.Join(session.path, sessionLogFile))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "before close")
+	assert.NotContains(t, string(data), "after close")
+}
+
+func TestPlanCancelled(t *testing.T) {
+	t.Parallel()
+
+	config, err := NewConfigFromFile("fixtures/test-plan-success/astro.yaml")
+	require.NoError(t, err)
+	config.SessionRepoDir = t.TempDir()
+
+	c, err := NewProject(WithConfig(*config))
+	require.NoError(t, err)
+
+	session, err := c.sessions.Current()
+	require.NoError(t, err)
+	session.cancel()
+
+	_, resultChan, err := c.Plan(PlanExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			ModuleNames: []string{"app", "users"},
+			UserVars: &UserVariables{
+				Values: map[string]string{"aws_region": "east1"},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	// executions that never started are still reported
+	results := testReadResults(resultChan)
+	assert.Len(t, results, 4)
+	for id, result := range results {
+		assert.EqualError(t, result.Err(), "not planned: context canceled", id)
+	}
+}
+
+// testClock is a clock that is stopped at a fixed time.
+type testClock struct {
+	now time.Time
+}
+
+func (c testClock) Now() time.Time {
+	return c.now
+}
+
+func TestClockAndIDGenerator(t *testing.T) {
+	t.Parallel()
+
+	config, err := NewConfigFromFile("fixtures/test-plan-success/astro.yaml")
+	require.NoError(t, err)
+	config.SessionRepoDir = t.TempDir()
+
+	now := time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+	var lastID int
+	c, err := NewProject(
+		WithConfig(*config),
+		WithClock(testClock{now: now}),
+		WithIDGenerator(func() string {
+			lastID++
+			return fmt.Sprintf("session-%d", lastID)
+		}),
+	)
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Plan(PlanExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			ModuleNames: []string{"users"},
+			UserVars:    NoUserVariables(),
+		},
+		SavePlans: true,
+	})
+	require.NoError(t, err)
+
+	result := testReadResults(resultChan)["users"]
+	require.NotNil(t, result)
+	require.NoError(t, result.Err())
+	assert.Equal(t, "0s", result.TerraformResult().Runtime())
+
+	sessionID, err := c.SessionID()
+	require.NoError(t, err)
+	assert.Equal(t, "session-1", sessionID)
+
+	session, err := c.sessions.Current()
+	require.NoError(t, err)
+	plans, err := session.readSavedPlans()
+	require.NoError(t, err)
+	assert.True(t, now.Equal(plans.PlannedAt))
+}
+
+func TestPlanSaveWorkspaces(t *testing.T) {
+	t.Parallel()
+
+	config, err := NewConfigFromFile("fixtures/test-plan-success/astro.yaml")
+	require.NoError(t, err)
+	config.SessionRepoDir = t.TempDir()
+	for i := range config.Modules {
+		config.Modules[i].Workspaces = "*"
+	}
+
+	c, err := NewProject(WithConfig(*config))
+	require.NoError(t, err)
+
+	_, _, err = c.Plan(PlanExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			ModuleNames: []string{"users"},
+			UserVars:    NoUserVariables(),
+		},
+		SavePlans: true,
+	})
+	assert.EqualError(t, err, "plans of modules with workspaces cannot be saved: users")
+}
+
+func TestApplySameVersionsAs(t *testing.T) {
+	t.Parallel()
+
+	// a copy of the mock Terraform, so that it can be changed
+	terraformPath := filepath.Join(t.TempDir(), "terraform")
+	data, err := os.ReadFile("fixtures/mock-terraform/success")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(terraformPath, data, 0755))
+
+	newProject := func() *Project {
+		c, err := NewProjectFromConfigFile("fixtures/test-plan-success/astro.yaml")
+		require.NoError(t, err)
+		for i := range c.config.Modules {
+			c.config.Modules[i].Terraform.Path = terraformPath
+		}
+		return c
+	}
+	apply := func(c *Project, sameVersionsAs string) map[string]error {
+		_, resultChan, err := c.Apply(ApplyExecutionParameters{
+			ExecutionParameters: ExecutionParameters{
+				ModuleNames: []string{"users"},
+				UserVars:    NoUserVariables(),
+			},
+			SameVersionsAs: sameVersionsAs,
+		})
+		require.NoError(t, err)
+		return testResultErrs(testReadResults(resultChan))
+	}
+
+	c := newProject()
+	assert.Equal(t, map[string]error{"users": nil}, apply(c, ""))
+	reviewed, err := c.SessionID()
+	require.NoError(t, err)
+
+	builds, err := c.sessions.readTerraformBuilds(reviewed)
+	require.NoError(t, err)
+	require.Contains(t, builds, "users")
+	assert.Equal(t, terraformPath, builds["users"].Path)
+	assert.Equal(t, "0.8.8", builds["users"].Version)
+
+	assert.Equal(t, map[string]error{"users": nil}, apply(newProject(), reviewed))
+
+	// the binary changes after the session
+	require.NoError(t, os.WriteFile(terraformPath, append(data, "\n# changed\n"...), 0755))
+
+	errs := apply(newProject(), reviewed)
+	require.Error(t, errs["users"])
+	assert.Contains(t, errs["users"].Error(), "has been removed or changed")
+}
+
+func TestApplySameVersionsAsWithoutVersion(t *testing.T) {
+	t.Parallel()
+
+	terraformPath, err := filepath.Abs("fixtures/mock-terraform/success")
+	require.NoError(t, err)
+
+	newProject := func() *Project {
+		c, err := NewProjectFromConfigFile("fixtures/test-plan-success/astro.yaml")
+		require.NoError(t, err)
+		for i := range c.config.Modules {
+			c.config.Modules[i].Terraform.Path = terraformPath
+		}
+		return c
+	}
+	apply := func(c *Project, sameVersionsAs string) map[string]error {
+		_, resultChan, err := c.Apply(ApplyExecutionParameters{
+			ExecutionParameters: ExecutionParameters{
+				ModuleNames: []string{"users"},
+				UserVars:    NoUserVariables(),
+			},
+			SameVersionsAs: sameVersionsAs,
+		})
+		require.NoError(t, err)
+		return testResultErrs(testReadResults(resultChan))
+	}
+
+	c := newProject()
+	assert.Equal(t, map[string]error{"users": nil}, apply(c, ""))
+	reviewed, err := c.SessionID()
+	require.NoError(t, err)
+
+	// a binary whose version wasn't known when it ran
+	session, err := c.sessions.Current()
+	require.NoError(t, err)
+	buildFile := filepath.Join(session.path, "users", terraformBuildFile)
+	data, err := os.ReadFile(buildFile)
+	require.NoError(t, err)
+	var build terraformBuild
+	require.NoError(t, json.Unmarshal(data, &build))
+	build.Version = ""
+	data, err = json.Marshal(build)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(buildFile, data, 0644))
+
+	// is identified by its hash alone
+	assert.Equal(t, map[string]error{"users": nil}, apply(newProject(), reviewed))
+
+	executions, err := newProject().DryRunApply(ApplyExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			ModuleNames: []string{"users"},
+			UserVars:    NoUserVariables(),
+		},
+		SameVersionsAs: reviewed,
+	})
+	require.NoError(t, err)
+	require.Len(t, executions, 1)
+	assert.Equal(t, terraformPath, executions[0].Commands[0].Args[0])
+}
+
+func TestApplyRetry(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-retry/astro.yaml")
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Apply(ApplyExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			UserVars: NoUserVariables(),
+		},
+	})
+	require.NoError(t, err)
+
+	results := testReadResults(resultChan)
+	errs := testResultErrs(results)
+	assert.NoError(t, errs["flaky"])
+	assert.NoError(t, errs["app"], "dependents of retried executions run")
+	assert.Error(t, errs["broken"])
+
+	applies := func(id string) (n int) {
+		for _, invocation := range results[id].Invocations() {
+			if invocation.Args[1] == "apply" {
+				n++
+			}
+		}
+		return n
+	}
+	assert.Equal(t, 2, applies("flaky"), "transient errors are retried")
+	assert.Equal(t, 1, applies("broken"), "other errors are not retried")
+}
+
+func TestApplyRetryStateLock(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-state-lock/astro.yaml")
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Apply(ApplyExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			UserVars: NoUserVariables(),
+		},
+	})
+	require.NoError(t, err)
+
+	results := testReadResults(resultChan)
+	errs := testResultErrs(results)
+	assert.NoError(t, errs["locked"], "locked states are retried")
+	assert.NoError(t, errs["app"])
+
+	applies := func(id string) (args [][]string) {
+		for _, invocation := range results[id].Invocations() {
+			if invocation.Args[1] == "apply" {
+				args = append(args, invocation.Args)
+			}
+		}
+		return args
+	}
+	require.Len(t, applies("locked"), 2)
+	assert.Contains(t, applies("locked")[0], "-lock-timeout=1m0s")
+	require.Len(t, applies("app"), 1)
+	assert.Contains(t, applies("app")[0], "-lock-timeout=30s")
+}
+
+func TestLockTimeoutOverride(t *testing.T) {
+	t.Parallel()
+
+	config, err := NewConfigFromFile("fixtures/test-state-lock/astro.yaml")
+	require.NoError(t, err)
+	config.Retry = nil
+
+	c, err := NewProject(WithConfig(*config), WithLockTimeout(0))
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Apply(ApplyExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			UserVars: NoUserVariables(),
+		},
+	})
+	require.NoError(t, err)
+
+	results := testReadResults(resultChan)
+	var lockErr *terraform.StateLockedError
+	require.True(t, errors.As(results["locked"].Err(), &lockErr), "locked states are not retried without state_locks")
+	assert.Equal(t, "jane@laptop", lockErr.Who)
+	for _, invocation := range results["app"].Invocations() {
+		for _, arg := range invocation.Args {
+			assert.False(t, strings.HasPrefix(arg, "-lock-timeout"), "the lock timeout is overridden")
+		}
+	}
+
+	_, err = NewProject(WithConfig(*config), WithLockTimeout(-time.Second))
+	assert.EqualError(t, err, "1 error occurred:\n\n* lock timeout cannot be negative")
+}
+
+func TestPlanWithGraphFailModule(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-apply-fail-module/astro.yaml")
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Plan(PlanExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			UserVars: &UserVariables{
+				Values: map[string]string{
+					"aws_region": "east1",
+				},
+			},
+		},
+		UseGraph: true,
+	})
+	require.NoError(t, err)
+
+	results := testResultErrs(testReadResults(resultChan))
+
+	// users module should have failed, and everything depending on it
+	// should have been skipped
+	assert.Error(t, results["users"])
+	for _, id := range []string{
+		"app-east1-dev",
+		"database-east1-dev",
+	} {
+		assert.NotContains(t, results, id)
+	}
+	assert.NoError(t, results["network-east1-dev"])
+}
+
+func TestApplyFilterWithGraph(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-apply-fail-module/astro.yaml")
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Apply(ApplyExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			UserVars: &UserVariables{
+				Values: map[string]string{
+					"aws_region": "east1",
+				},
+			},
+			Filter: `module =~ "database|users"`,
+		},
+	})
+	require.NoError(t, err)
+
+	results := testResultErrs(testReadResults(resultChan))
+
+	// the selected executions are still applied in dependency order, so
+	// database isn't applied once users fails
+	assert.Error(t, results["users"])
+	for _, id := range []string{"database-east1-dev", "database-east1-staging", "database-east1-prod"} {
+		assert.NotContains(t, results, id)
+	}
+}
+
+func TestDestroyFailModule(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-apply-fail-module/astro.yaml")
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Destroy(DestroyExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			UserVars: &UserVariables{
+				Values: map[string]string{
+					"aws_region": "east1",
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	results := testResultErrs(testReadResults(resultChan))
+
+	// users is destroyed last, so everything depending on it should have
+	// been destroyed before it failed
+	assert.Error(t, results["users"])
+	assert.Len(t, results, 12)
+	for id, err := range results {
+		if id != "users" {
+			assert.NoError(t, err, id)
+		}
+	}
+}
+
+func TestDestroySelectedModules(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-apply-fail-module/astro.yaml")
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Destroy(DestroyExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			ModuleNames: []string{"database", "users"},
+			UserVars: &UserVariables{
+				Values: map[string]string{
+					"aws_region": "east1",
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	results := testResultErrs(testReadResults(resultChan))
+
+	// users is still destroyed after database, which depends on it
+	assert.Error(t, results["users"])
+	assert.Len(t, results, 4)
+	for id, err := range results {
+		if id != "users" {
+			assert.NoError(t, err, id)
+		}
+	}
+}
+
+// Tests that variables are passed to the modules that declare them and not
+// passed to the modules that didn't
+func TestPassVariables(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-pass-variables/astro.yaml")
+	require.NoError(t, err)
+
+	c.config.TerraformDefaults.Path = absolutePath("fixtures/mock-terraform/success")
+
+	_, resultChan, err := c.Plan(PlanExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			UserVars: &UserVariables{
+				Values: map[string]string{
+					"region": "east1",
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	results := testReadResults(resultChan)
+
+	// Mocked terraform prints parameters it was called with to stderr.
+	// Check that variables were passed to the module that declared it and not
+	// passed to the one that didn't.
+	assert.Contains(t, results["bar-east1"].TerraformResult().Stderr(), "-var region=east1")
+	assert.NotContains(t, results["foo"].TerraformResult().Stderr(), "-var")
+}
+
+func TestVarFiles(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-var-files/astro.yaml")
+	require.NoError(t, err)
+
+	c.config.TerraformDefaults.Path = absolutePath("fixtures/mock-terraform/success")
+
+	_, resultChan, err := c.Plan(PlanExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			UserVars: &UserVariables{
+				Values: map[string]string{
+					"region": "east1",
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	results := testReadResults(resultChan)
+	require.NoError(t, results["app-dev-east1"].Err())
+
+	stderr := results["app-dev-east1"].TerraformResult().Stderr()
+	assert.Contains(t, stderr, "-var-file=vars/dev.tfvars")
+	assert.Contains(t, stderr, "-var-file=vars/east1.tfvars")
+
+	// var files that depend on user variables are checked when binding
+	_, _, err = c.Plan(PlanExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			UserVars: &UserVariables{
+				Values: map[string]string{
+					"region": "west1",
+				},
+			},
+		},
+	})
+	assert.Contains(t, err.Error(), "var file does not exist")
+}
+
+func TestVarFilesMissing(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewProjectFromConfigFile("fixtures/test-var-files/missing.yaml")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "app-prod: var file does not exist")
+}
+
+func TestApplyFromSession(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-plan-success/astro.yaml")
+	require.NoError(t, err)
+
+	c.config.TerraformDefaults.Path = absolutePath("fixtures/mock-terraform/success")
+
+	_, resultChan, err := c.Plan(PlanExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			ModuleNames: []string{"app"},
+			UserVars: &UserVariables{
+				Values: map[string]string{
+					"aws_region": "east1",
+				},
+			},
+		},
+		SavePlans: true,
+	})
+	require.NoError(t, err)
+	testReadResults(resultChan)
+
+	sessionID, err := c.SessionID()
+	require.NoError(t, err)
+
+	// Apply from a new project, as a later run of astro would
+	c, err = NewProjectFromConfigFile("fixtures/test-plan-success/astro.yaml")
+	require.NoError(t, err)
+
+	c.config.TerraformDefaults.Path = absolutePath("fixtures/mock-terraform/success")
+
+	_, resultChan, err = c.Apply(ApplyExecutionParameters{
+		ExecutionParameters: NoExecutionParameters(),
+		FromSession:         sessionID,
+	})
+	require.NoError(t, err)
+
+	results := testReadResults(resultChan)
+	assert.Equal(t, map[string]error{
+		"app-east1-dev":     nil,
+		"app-east1-prod":    nil,
+		"app-east1-staging": nil,
+	}, testResultErrs(results))
+	assert.Contains(t, results["app-east1-dev"].TerraformResult().Stderr(), "apply app-east1-dev.plan")
+}
+
+func TestApplyFromSessionSelected(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-plan-success/astro.yaml")
+	require.NoError(t, err)
+
+	c.config.TerraformDefaults.Path = absolutePath("fixtures/mock-terraform/success")
+
+	_, resultChan, err := c.Plan(PlanExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			ModuleNames: []string{"app"},
+			UserVars: &UserVariables{
+				Values: map[string]string{
+					"aws_region": "east1",
+				},
+			},
+		},
+		SavePlans: true,
+	})
+	require.NoError(t, err)
+	testReadResults(resultChan)
+
+	sessionID, err := c.SessionID()
+	require.NoError(t, err)
+
+	// Only the selected plans are applied, in the same project
+	_, resultChan, err = c.Apply(ApplyExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			UserVars:     NoUserVariables(),
+			ExecutionIDs: []string{"app-east1-prod"},
+		},
+		FromSession: sessionID,
+	})
+	require.NoError(t, err)
+
+	results := testReadResults(resultChan)
+	assert.Equal(t, map[string]error{
+		"app-east1-prod": nil,
+	}, testResultErrs(results))
+	assert.Contains(t, results["app-east1-prod"].TerraformResult().Stderr(), "apply app-east1-prod.plan")
+
+	_, _, err = c.Apply(ApplyExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			UserVars:     NoUserVariables(),
+			ExecutionIDs: []string{"users"},
+		},
+		FromSession: sessionID,
+	})
+	assert.EqualError(t, err, fmt.Sprintf("no plan for users was saved in session %v", sessionID))
+}
+
+func TestApplyStalePlans(t *testing.T) {
+	t.Parallel()
+
+	for _, policy := range []string{conf.StalePlanRefuse, conf.StalePlanWarn, conf.StalePlanReplan} {
+		policy := policy
+		t.Run(policy, func(t *testing.T) {
+			t.Parallel()
+
+			c, err := NewProjectFromConfigFile("fixtures/test-plan-success/astro.yaml")
+			require.NoError(t, err)
+
+			c.config.TerraformDefaults.Path = absolutePath("fixtures/mock-terraform/success")
+
+			_, resultChan, err := c.Plan(PlanExecutionParameters{
+				ExecutionParameters: ExecutionParameters{
+					ModuleNames: []string{"users"},
+					UserVars:    NoUserVariables(),
+				},
+				SavePlans: true,
+			})
+			require.NoError(t, err)
+			testReadResults(resultChan)
+
+			// Make the plans older than the maximum age
+			session, err := c.sessions.Current()
+			require.NoError(t, err)
+			plans, err := session.readSavedPlans()
+			require.NoError(t, err)
+			plans.PlannedAt = plans.PlannedAt.Add(-2 * time.Hour)
+			require.NoError(t, session.writeSavedPlans(plans))
+
+			c, err = NewProjectFromConfigFile("fixtures/test-plan-success/astro.yaml")
+			require.NoError(t, err)
+
+			c.config.TerraformDefaults.Path = absolutePath("fixtures/mock-terraform/success")
+			c.config.StalePlanPolicy = policy
+			c.config.StalePlanMaxAge = "1h"
+
+			_, resultChan, err = c.Apply(ApplyExecutionParameters{
+				ExecutionParameters: NoExecutionParameters(),
+				FromSession:         session.id,
+			})
+			require.NoError(t, err)
+
+			result := testReadResults(resultChan)["users"]
+			require.NotNil(t, result)
+
+			switch policy {
+			case conf.StalePlanRefuse:
+				assert.EqualError(t, result.Err(), "saved plan is stale: planned 2h0m0s ago; plan again")
+			case conf.StalePlanWarn:
+				assert.NoError(t, result.Err())
+				assert.Equal(t, []string{"saved plan is stale: planned 2h0m0s ago"}, result.Warnings())
+				assert.Contains(t, result.TerraformResult().Stderr(), "apply users.plan")
+			case conf.StalePlanReplan:
+				assert.NoError(t, result.Err())
+				assert.NotContains(t, result.TerraformResult().Stderr(), "users.plan")
+			}
+		})
+	}
+}
+
+func TestApplyFromMissingSession(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-plan-success/astro.yaml")
+	require.NoError(t, err)
+
+	_, _, err = c.Apply(ApplyExecutionParameters{
+		ExecutionParameters: NoExecutionParameters(),
+		FromSession:         "does-not-exist",
+	})
+	assert.EqualError(t, err, "session does not exist: does-not-exist")
+}
+
+func TestPlanFilterNoMatch(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/foosite.yaml")
+	require.NoError(t, err)
+
+	_, _, err = c.Plan(PlanExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			UserVars: &UserVariables{
+				Values: map[string]string{
+					"aws_region":  "east1",
+					"environment": "pord",
+				},
+				Filters: map[string]bool{
+					"environment": true,
+				},
+			},
+		},
+	})
+	require.Error(t, err)
+
+	noMatchErr, ok := err.(*NoExecutionsMatchedError)
+	require.True(t, ok)
+	assert.Equal(t, map[string][]string{"environment": {"prod"}}, noMatchErr.Suggestions())
+	assert.EqualError(t, err, "no executions matched: environment=pord; did you mean environment=prod?")
+}
+
+func TestSessionInfo(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-plan-success/astro.yaml")
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Plan(PlanExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			ModuleNames: []string{"users"},
+			UserVars:    NoUserVariables(),
+		},
+	})
+	require.NoError(t, err)
+	testReadResults(resultChan)
+
+	sessionID, err := c.SessionID()
+	require.NoError(t, err)
+
+	sessions, err := c.Sessions()
+	require.NoError(t, err)
+	var ids []string
+	for _, session := range sessions {
+		ids = append(ids, session.ID)
+	}
+	assert.Contains(t, ids, sessionID)
+
+	info, err := c.SessionInfo(sessionID)
+	require.NoError(t, err)
+	require.Len(t, info.Executions, 1)
+	assert.Equal(t, "users", info.Executions[0].ID)
+	assert.False(t, info.Started.IsZero())
+
+	var logs []string
+	for _, log := range info.Executions[0].Logs {
+		logs = append(logs, log.Name)
+	}
+	assert.Contains(t, logs, "plan.log")
+	assert.Contains(t, logs, "plan.stdout.log")
+	assert.Contains(t, logs, "plan.stderr.log")
+
+	var commands []string
+	for _, command := range info.Executions[0].Commands {
+		commands = append(commands, command.Name)
+		assert.Equal(t, 0, command.ExitCode)
+		assert.True(t, command.Success)
+	}
+	assert.Contains(t, commands, "plan")
+
+	data, err := c.SessionLog(sessionID, "users", "plan.log")
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "plan")
+
+	_, err = c.SessionLog(sessionID, "..", "astro.yaml")
+	assert.Error(t, err)
+	_, err = c.SessionLog(sessionID, "", "git-sha")
+	assert.Error(t, err)
+}
+
+func TestPreflight(t *testing.T) {
+	// The identity of the mock AWS CLI comes from the environment of astro
+	t.Setenv("MOCK_AWS_LOG", filepath.Join(t.TempDir(), "aws.log"))
+	t.Setenv("MOCK_AWS_ARN", "arn:aws:iam::123456789012:user/admin")
+
+	c, err := NewProjectFromConfigFile("fixtures/test-aws-role/astro.yaml")
+	require.NoError(t, err)
+
+	// Not root, and the identity check is off by default
+	c.geteuid = func() int { return 1000 }
+	warnings, err := c.Preflight()
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	// Root is a warning by default
+	c.geteuid = func() int { return 0 }
+	warnings, err = c.Preflight()
+	require.NoError(t, err)
+	require.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "running as root")
+
+	c.config.Preflight = &conf.Preflight{
+		Root:             conf.PreflightRefuse,
+		AdminCredentials: conf.PreflightWarn,
+	}
+	_, err = c.Preflight()
+	assert.IsType(t, &PreflightError{}, err)
+
+	c.geteuid = func() int { return 1000 }
+	warnings, err = c.Preflight()
+	require.NoError(t, err)
+	require.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "arn:aws:iam::123456789012:user/admin is an administrator")
+
+	c.config.Preflight.AdminCredentials = conf.PreflightRefuse
+	_, err = c.Preflight()
+	assert.IsType(t, &PreflightError{}, err)
+
+	// An identity that can't be checked is refused too
+	awsCLIPath := c.config.AWSCLIPath
+	c.config.AWSCLIPath = filepath.Join(t.TempDir(), "aws")
+	_, err = c.Preflight()
+	assert.IsType(t, &PreflightError{}, err)
+	assert.Contains(t, err.Error(), "unable to check the AWS identity")
+
+	c.config.Preflight.AdminCredentials = conf.PreflightWarn
+	warnings, err = c.Preflight()
+	require.NoError(t, err)
+	require.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "unable to check the AWS identity")
+
+	c.config.Preflight.AdminCredentials = conf.PreflightRefuse
+	c.config.AWSCLIPath = awsCLIPath
+
+	// Identities that don't match the patterns are fine
+	c.config.Preflight.AdminIdentities = []string{`:role/break-glass$`}
+	warnings, err = c.Preflight()
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-aws-role/astro.yaml")
+	require.NoError(t, err)
+
+	// The mock AWS CLI would log its calls to the file in the module env
+	awsLog := filepath.Join(t.TempDir(), "aws.log")
+	for i := range c.config.Modules {
+		c.config.Modules[i].Env = map[string]string{"MOCK_AWS_LOG": awsLog}
+	}
+
+	_, resultChan, err := c.Validate(NoExecutionParameters())
+	require.NoError(t, err)
+
+	results := testReadResults(resultChan)
+	assert.Equal(t, map[string]error{
+		"app-dev":  nil,
+		"app-prod": nil,
+		"db-dev":   nil,
+		"db-prod":  nil,
+	}, testResultErrs(results))
+
+	invocations := results["app-dev"].Invocations()
+	require.NotEmpty(t, invocations)
+	assert.Equal(t, "validate", invocations[len(invocations)-1].Args[1])
+
+	// No roles are assumed
+	assert.False(t, utils.FileExists(awsLog))
+}
+
+func TestSignalsWatchedWhileRunning(t *testing.T) {
+	t.Parallel()
+
+	config, err := NewConfigFromFile("fixtures/test-plan-success/astro.yaml")
+	require.NoError(t, err)
+	config.SessionRepoDir = t.TempDir()
+
+	c, err := NewProject(WithConfig(*config))
+	require.NoError(t, err)
+
+	session, err := c.sessions.Current()
+	require.NoError(t, err)
+	assert.Equal(t, 0, session.signalRuns)
+
+	_, resultChan, err := c.Plan(PlanExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			ModuleNames: []string{"app"},
+			UserVars: &UserVariables{
+				Values: map[string]string{"aws_region": "east1"},
+			},
+		},
+	})
+	require.NoError(t, err)
+	testReadResults(resultChan)
+
+	// the plan has finished, so its signal handler is gone even though the
+	// project isn't closed
+	session.signalsMu.Lock()
+	assert.Equal(t, 0, session.signalRuns)
+	session.signalsMu.Unlock()
+
+	stopFirst := session.watchSignals()
+	stopSecond := session.watchSignals()
+	stopFirst()
+	stopFirst()
+	assert.Equal(t, 1, session.signalRuns)
+	stopSecond()
+	assert.Equal(t, 0, session.signalRuns)
+
+	// closing the project stops runs that are still in progress
+	session.watchSignals()
+	require.NoError(t, c.Close())
+	assert.Equal(t, 0, session.signalRuns)
 }
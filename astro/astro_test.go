@@ -22,6 +22,7 @@ import (
 	"github.com/uber/astro/astro"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestMissingDependencyExecution tests that we fail when a particular
@@ -50,3 +51,24 @@ func TestMissingDependencyModule(t *testing.T) {
 }
 
 // TODO: Test multiple modules with the same name
+
+func TestOutputs(t *testing.T) {
+	t.Parallel()
+
+	c, err := astro.NewProjectFromConfigFile("fixtures/test-plan-success/astro.yaml")
+	require.NoError(t, err)
+
+	outputs, err := c.Outputs(astro.ExecutionParameters{
+		ModuleNames: []string{"network", "users"},
+		UserVars: &astro.UserVariables{
+			Values: map[string]string{"aws_region": "us-east-1"},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Len(t, outputs, 5)
+	assert.Contains(t, outputs, "network-us-east-1-mgmt")
+	require.Contains(t, outputs, "users")
+	assert.JSONEq(t, `"hello"`, string(outputs["users"]["greeting"].Value))
+	assert.False(t, outputs["users"]["greeting"].Sensitive)
+}
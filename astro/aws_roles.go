@@ -0,0 +1,64 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/sts"
+)
+
+// awsRoleKey identifies the credentials of an assumed role in the cache of
+// the session.
+func awsRoleKey(role conf.AWSCredentials) string {
+	return fmt.Sprintf("%s|%s|%s", role.RoleARN, role.ExternalID, role.SessionDuration)
+}
+
+// assumeAWSRole returns credentials for the role, assuming it unless the
+// session already has credentials for it that are still valid. env is the
+// module environment, that the AWS CLI runs with on top of astro's own.
+func (session *Session) assumeAWSRole(role conf.AWSCredentials, env map[string]string) (*sts.Credentials, error) {
+	key := awsRoleKey(role)
+
+	lock, _ := session.awsRoleLocks.LoadOrStore(key, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	clock := session.repo.project.clock
+
+	if cached, ok := session.awsRoles.Load(key); ok && cached.(*sts.Credentials).Valid(clock.Now()) {
+		return cached.(*sts.Credentials), nil
+	}
+
+	cliEnv := os.Environ()
+	for key, val := range env {
+		cliEnv = append(cliEnv, fmt.Sprintf("%s=%s", key, val))
+	}
+
+	logger.Trace.Printf("astro: assuming AWS role %v", role.RoleARN)
+	credentials, err := sts.AssumeRole(session.ctx, session.repo.project.config.AWSCLIPath, cliEnv, role, "astro-"+session.id)
+	if err != nil {
+		return nil, err
+	}
+
+	session.awsRoles.Store(key, credentials)
+	return credentials, nil
+}
@@ -0,0 +1,71 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/uber/astro/astro"
+	"github.com/uber/astro/astro/annotate"
+)
+
+// annotate reports the executions that failed as annotations in the CI
+// system astro is running in, if any. Failures are printed as warnings,
+// as the command itself has already completed.
+func (cli *AstroCLI) annotate(command string, results []*astro.Result) {
+	ci := annotate.Detect(os.Getenv, cli.stdout)
+	if ci == nil {
+		return
+	}
+
+	failures := annotationFailures(results)
+	if len(failures) == 0 {
+		return
+	}
+
+	var session string
+	if cli.project != nil {
+		session, _ = cli.project.SessionID()
+	}
+
+	if err := ci.Annotate(context.Background(), session, command, failures); err != nil {
+		cli.printWarning("unable to annotate %s: %v", ci.Name(), err)
+	}
+}
+
+// annotationFailures returns the executions that failed. The error of an
+// execution is followed by the stderr of Terraform, which explains it.
+func annotationFailures(results []*astro.Result) []annotate.Failure {
+	var failures []annotate.Failure
+	for _, result := range results {
+		if result.Err() == nil {
+			continue
+		}
+		message := result.Err().Error()
+		if terraformResult := result.TerraformResult(); terraformResult != nil && terraformResult.Stderr() != "" {
+			message += "\n" + terraformResult.Stderr()
+		}
+		failures = append(failures, annotate.Failure{
+			ID:        result.ID(),
+			Error:     message,
+			Guidance:  result.Guidance(),
+			ModuleDir: result.ModuleDir(),
+			Log:       result.OutputPath(),
+		})
+	}
+	return failures
+}
@@ -0,0 +1,152 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/uber/astro/astro"
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/terraform"
+	"github.com/uber/astro/astro/utils"
+)
+
+// errApplyCancelled is returned when the user doesn't approve an
+// interactive apply.
+var errApplyCancelled = errors.New("apply cancelled")
+
+// approveApply plans the executions of an interactive apply, saving the
+// plans, shows the changes and asks the user which of them to apply. It
+// returns the parameters to apply the approved plans with, or nil if
+// there are no changes to apply.
+func (cli *AstroCLI) approveApply(parameters astro.ApplyExecutionParameters) (*astro.ApplyExecutionParameters, error) {
+	if parameters.FromSession != "" || parameters.Resume != "" || parameters.SameVersionsAs != "" {
+		return nil, errors.New("--interactive cannot be used with --from-session, --resume or --same-versions-as")
+	}
+	if !isInteractive(cli.stdin) {
+		return nil, errors.New("--interactive requires an interactive terminal")
+	}
+
+	status, results, err := cli.project.Plan(astro.PlanExecutionParameters{
+		ExecutionParameters: parameters.ExecutionParameters,
+		SavePlans:           true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ERROR: %v", cli.processError(err))
+	}
+
+	collected, err := cli.printExecStatus(status, results)
+	if err != nil {
+		return nil, cli.exitWith(conf.ResultsFailed, errors.New("there were errors planning; nothing was applied"))
+	}
+
+	changed := changedExecutions(collected)
+	if len(changed) == 0 {
+		_, err := fmt.Fprintln(cli.stdout, "\nNo changes to apply")
+		return nil, err
+	}
+
+	ids, err := cli.project.ExecutionIDs(parameters.ExecutionParameters)
+	if err != nil {
+		return nil, err
+	}
+	var modules []string
+	for _, module := range cli.config.Modules {
+		modules = append(modules, module.Name)
+	}
+
+	selected, err := promptApproval(modules, ids, changed, cli.stdin, cli.stderr)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionID, err := cli.project.SessionID()
+	if err != nil {
+		return nil, err
+	}
+
+	return &astro.ApplyExecutionParameters{
+		ExecutionParameters: astro.ExecutionParameters{
+			UserVars:     astro.NoUserVariables(),
+			ExecutionIDs: selected,
+			Parallelism:  parameters.Parallelism,
+		},
+		FromSession: sessionID,
+		// the user has seen the risk of the plans they approved
+		ApproveRisk: true,
+	}, nil
+}
+
+// changedExecutions returns the IDs of the executions whose plans have
+// changes.
+func changedExecutions(results []*astro.Result) []string {
+	var changed []string
+	for _, result := range results {
+		if planResult, ok := result.TerraformResult().(*terraform.PlanResult); ok && planResult != nil && planResult.HasChanges() {
+			changed = append(changed, result.ID())
+		}
+	}
+	return changed
+}
+
+// promptApproval asks the user whether to apply the plans of the changed
+// executions, all of which are planned executions of the modules. It
+// returns nil to apply all the plans, or the executions the user selected.
+func promptApproval(modules []string, ids map[string][]string, changed []string, r io.Reader, w io.Writer) ([]string, error) {
+	// The selector reads from the same buffer, so that no input is lost
+	reader := bufio.NewReader(r)
+
+	for {
+		fmt.Fprintf(w, "\n%d executions have changes. Apply them? \"y\" to apply, \"s\" to select executions, \"n\" to cancel: ", len(changed))
+
+		line, err := reader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return nil, errApplyCancelled
+		}
+
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "y", "yes":
+			return nil, nil
+		case "n", "no":
+			return nil, errApplyCancelled
+		case "s":
+			// Only executions with changes can be selected, and they all
+			// are to start with
+			changedIDs := map[string][]string{}
+			for module, moduleIDs := range ids {
+				for _, id := range moduleIDs {
+					if utils.StringSliceContains(changed, id) {
+						changedIDs[module] = append(changedIDs[module], id)
+					}
+				}
+			}
+			selector := newExecutionSelector(modules, changedIDs)
+			for _, item := range selector.items {
+				item.selected = true
+			}
+			selected, err := runExecutionSelector(selector, reader, w)
+			if err != nil {
+				return nil, errApplyCancelled
+			}
+			return selected, nil
+		}
+	}
+}
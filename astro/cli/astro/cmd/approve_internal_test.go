@@ -0,0 +1,63 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromptApproval(t *testing.T) {
+	modules := []string{"app", "database"}
+	ids := map[string][]string{
+		"app":      {"app-dev", "app-prod"},
+		"database": {"database-dev", "database-prod"},
+	}
+	changed := []string{"app-prod", "database-dev", "database-prod"}
+
+	prompt := func(input string) ([]string, string, error) {
+		out := &bytes.Buffer{}
+		selected, err := promptApproval(modules, ids, changed, strings.NewReader(input), out)
+		return selected, out.String(), err
+	}
+
+	// All the plans are applied
+	selected, out, err := prompt("maybe\ny\n")
+	require.NoError(t, err)
+	assert.Nil(t, selected)
+	assert.Equal(t, 2, strings.Count(out, "3 executions have changes. Apply them?"))
+
+	_, _, err = prompt("n\n")
+	assert.Equal(t, errApplyCancelled, err)
+
+	_, _, err = prompt("")
+	assert.Equal(t, errApplyCancelled, err)
+
+	// Executions with changes are selected to start with
+	selected, out, err = prompt("s\n3\n\n")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app-prod", "database-dev"}, selected)
+	assert.Contains(t, out, "app\n  [x]   1  app-prod\ndatabase\n  [x]   2  database-dev\n  [x]   3  database-prod\n")
+	assert.NotContains(t, out, "app-dev")
+
+	_, _, err = prompt("s\nq\n")
+	assert.Equal(t, errApplyCancelled, err)
+}
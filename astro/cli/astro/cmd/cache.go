@@ -0,0 +1,76 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/uber/astro/astro/conf"
+
+	"github.com/spf13/cobra"
+)
+
+func (cli *AstroCLI) createCacheCmd() {
+	cacheCmd := &cobra.Command{
+		Use:                   "cache",
+		DisableFlagsInUseLine: true,
+		Short:                 "Manage the cache of Terraform providers",
+	}
+
+	pruneCmd := &cobra.Command{
+		Use:               "prune [flags]",
+		Short:             "Remove the providers cached the longest ago until the plugin cache fits its max size",
+		Args:              cobra.NoArgs,
+		PersistentPreRunE: cli.preRun,
+		RunE:              cli.runCachePrune,
+	}
+	pruneCmd.Flags().StringVar(&cli.flags.cacheMaxSize, "max-size", "", "size to prune the cache to, e.g. 5GB (default plugin_cache.max_size)")
+	pruneCmd.Flags().BoolVar(&cli.flags.cachePruneAll, "all", false, "remove every provider from the cache")
+
+	cacheCmd.AddCommand(pruneCmd)
+
+	cli.commands.cache = cacheCmd
+}
+
+func (cli *AstroCLI) runCachePrune(cmd *cobra.Command, args []string) error {
+	maxBytes := cli.config.PluginCache.MaxBytes()
+	switch {
+	case cli.flags.cachePruneAll:
+		maxBytes = 0
+	case cli.flags.cacheMaxSize != "":
+		size, err := conf.ParseSize(cli.flags.cacheMaxSize)
+		if err != nil {
+			return fmt.Errorf("ERROR: invalid --max-size: %v", err)
+		}
+		maxBytes = size
+	case maxBytes == 0:
+		return fmt.Errorf("ERROR: pass --max-size or --all, or set plugin_cache.max_size in the config")
+	}
+
+	prune, err := cli.project.PrunePluginCache(maxBytes)
+	if err != nil {
+		return fmt.Errorf("ERROR: unable to prune the plugin cache: %v", err)
+	}
+	if prune.Dir == "" {
+		_, err := fmt.Fprintln(cli.stdout, "Each session has its own plugin cache, which is removed when it ends")
+		return err
+	}
+
+	_, err = fmt.Fprintf(cli.stdout, "Removed %d providers (%.1f MB) from %s, which now holds %.1f MB\n",
+		prune.Removed, float64(prune.Freed)/1e6, prune.Dir, float64(prune.Size)/1e6)
+	return err
+}
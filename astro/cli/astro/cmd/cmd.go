@@ -25,10 +25,12 @@ import (
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/uber/astro/astro"
 	"github.com/uber/astro/astro/conf"
 	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/state"
 
 	"github.com/spf13/cobra"
 )
@@ -48,13 +50,72 @@ type AstroCLI struct {
 	project *astro.Project
 	config  *conf.Project
 
+	// configFilePath is the path to the config file that was loaded, if
+	// any.
+	configFilePath string
+
+	// terraformNotFound is why the config didn't load, for the commands
+	// that manage Terraform and so run without it.
+	terraformNotFound *conf.TerraformNotFoundError
+
+	// tvmRepoPath is the path of the versions that astro tvm manages. The
+	// default is the repository of tvm in the home directory.
+	tvmRepoPath string
+
+	// args are the arguments astro runs with, which are recorded in the
+	// session.
+	args []string
+
 	// these values are filled in based on runtime flags
 	flags struct {
+		approveRisk       bool
+		autoInstall       bool
+		cacheMaxSize      string
+		cachePruneAll     bool
+		color             string
+		compareRefs       []string
+		compatVersions    string
 		detach            bool
+		detectDrift       bool
+		debounce          time.Duration
+		dryRun            bool
+		estimateCost      bool
+		flavor            string
+		follow            bool
+		frozen            bool
+		filter            string
+		fromSession       string
+		graphFormat       string
+		interactive       bool
+		jsonReportFile    string
+		listFormat        string
+		lockTimeout       time.Duration
+		logFile           string
+		logFormat         string
+		logLevel          string
 		moduleNamesString string
+		noColor           bool
+		parallelism       int
+		profile           string
+		rawOutput         bool
+		releaseTrainFile  string
+		resume            string
+		sameVersionsAs    string
+		savePlans         bool
+		selectInteractive bool
+		sessionKeep       int
+		sessionLogStream  string
+		sessionOlderThan  string
+		since             string
+		targets           []string
 		trace             bool
+		tvmAvailable      bool
+		tvmRemoveAll      bool
+		uiAddress         string
+		useGraph          bool
 		userCfgFile       string
-		verbose           bool
+		verbosity         int
+		yes               bool
 
 		// projectFlags are special in that the actual flags are dynamic, based
 		// on the astro project configuration loaded.
@@ -62,10 +123,30 @@ type AstroCLI struct {
 	}
 
 	commands struct {
-		root    *cobra.Command
-		plan    *cobra.Command
-		apply   *cobra.Command
-		version *cobra.Command
+		root        *cobra.Command
+		plan        *cobra.Command
+		apply       *cobra.Command
+		cache       *cobra.Command
+		compare     *cobra.Command
+		compat      *cobra.Command
+		config      *cobra.Command
+		destroy     *cobra.Command
+		explain     *cobra.Command
+		forceUnlock *cobra.Command
+		graph       *cobra.Command
+		list        *cobra.Command
+		lock        *cobra.Command
+		modules     *cobra.Command
+		orphans     *cobra.Command
+		output      *cobra.Command
+		release     *cobra.Command
+		session     *cobra.Command
+		stats       *cobra.Command
+		tvm         *cobra.Command
+		ui          *cobra.Command
+		validate    *cobra.Command
+		version     *cobra.Command
+		watch       *cobra.Command
 	}
 }
 
@@ -85,20 +166,65 @@ func NewAstroCLI(opts ...Option) (*AstroCLI, error) {
 	cli.createRootCommand()
 	cli.createPlanCmd()
 	cli.createApplyCmd()
+	cli.createDestroyCmd()
+	cli.createCacheCmd()
+	cli.createCompareCmd()
+	cli.createCompatCmd()
+	cli.createConfigCmd()
+	cli.createExplainCmd()
+	cli.createForceUnlockCmd()
+	cli.createGraphCmd()
+	cli.createListCmd()
+	cli.createLockCmd()
+	cli.createModulesCmd()
+	cli.createOrphansCmd()
+	cli.createOutputCmd()
+	cli.createReleaseCmd()
+	cli.createSessionCmd()
+	cli.createStatsCmd()
+	cli.createTVMCmd()
+	cli.createUICmd()
+	cli.createValidateCmd()
 	cli.createVersionCmd()
+	cli.createWatchCmd()
 
 	cli.commands.root.AddCommand(
 		cli.commands.plan,
 		cli.commands.apply,
+		cli.commands.destroy,
+		cli.commands.cache,
+		cli.commands.compare,
+		cli.commands.compat,
+		cli.commands.config,
+		cli.commands.explain,
+		cli.commands.forceUnlock,
+		cli.commands.graph,
+		cli.commands.list,
+		cli.commands.lock,
+		cli.commands.modules,
+		cli.commands.orphans,
+		cli.commands.output,
+		cli.commands.release,
+		cli.commands.session,
+		cli.commands.stats,
+		cli.commands.tvm,
+		cli.commands.ui,
+		cli.commands.validate,
 		cli.commands.version,
+		cli.commands.watch,
 	)
 
-	// Set trace. Note, this will turn tracing on for all instances of astro
-	// running in the same process, as the logger is a singleton. This should
-	// only be of concern during testing.
+	// Set verbosity. Note, this will turn tracing on for all instances of
+	// astro running in the same process, as the logger is a singleton. This
+	// should only be of concern during testing.
 	cobra.OnInitialize(func() {
-		if cli.flags.trace {
-			logger.Trace.SetOutput(cli.stderr)
+		if cli.flags.trace && cli.flags.verbosity < logger.LevelTrace {
+			cli.flags.verbosity = logger.LevelTrace
+		}
+		if cli.flags.verbosity >= logger.LevelTrace && cli.flags.logLevel == "" {
+			logger.SetOutput(cli.stderr, logger.TraceLevel, cli.flags.logFormat)
+		}
+		if cli.flags.verbosity >= logger.LevelTrace {
 			log.SetOutput(cli.stderr)
 		}
 	})
@@ -108,10 +234,11 @@ func NewAstroCLI(opts ...Option) (*AstroCLI, error) {
 
 // Run is the main entry point into the CLI program.
 func (cli *AstroCLI) Run(args []string) (exitCode int) {
+	cli.args = args
 	cli.commands.root.SetArgs(args)
 	cli.commands.root.SetOutput(cli.stderr)
 
-	userProvidedConfigPath, err := configPathFromArgs(args)
+	early, err := earlyFlagsFromArgs(args)
 	if err != nil {
 		_, err := fmt.Fprintln(cli.stderr, err.Error())
 		if err != nil {
@@ -120,12 +247,36 @@ func (cli *AstroCLI) Run(args []string) (exitCode int) {
 		return 1
 	}
 
+	closeLog, err := cli.configureLogging(early)
+	if err != nil {
+		_, err := fmt.Fprintln(cli.stderr, err.Error())
+		if err != nil {
+			return 0
+		}
+		return 1
+	}
+	defer closeLog()
+
 	configFilePath := firstExistingFilePath(
-		append([]string{userProvidedConfigPath}, configFileSearchPaths...)...,
+		append([]string{early.configFilePath}, configFileSearchPaths...)...,
 	)
 
 	if configFilePath != "" {
-		config, err := astro.NewConfigFromFile(configFilePath)
+		presets := astro.ConfigPresets{Profile: early.profile, Flavor: early.flavor}
+		config, err := astro.NewConfigFromFileWithPresets(configFilePath, presets)
+
+		// Offer to install Terraform if it couldn't be found
+		var notFoundErr *conf.TerraformNotFoundError
+		if errors.As(err, &notFoundErr) && early.command == tvmCommand {
+			// astro tvm installs Terraform itself
+			cli.terraformNotFound = notFoundErr
+			err = nil
+		} else if errors.As(err, &notFoundErr) {
+			if err = cli.installTerraform(notFoundErr.Flavor, notFoundErr.Constraint, early.autoInstall); err == nil {
+				config, err = astro.NewConfigFromFileWithPresets(configFilePath, presets)
+			}
+		}
+
 		if err != nil {
 			_, err := fmt.Fprintln(cli.stderr, err.Error())
 			if err != nil {
@@ -135,16 +286,30 @@ func (cli *AstroCLI) Run(args []string) (exitCode int) {
 		}
 
 		cli.config = config
+		cli.configFilePath = configFilePath
 	}
 
-	cli.configureDynamicUserFlags()
+	cli.configureDynamicUserFlags(early.profile)
+	cli.addPluginCommands()
+
+	defer cli.closeProject()
 
 	if err := cli.commands.root.Execute(); err != nil {
-		_, err := fmt.Fprintln(cli.stderr, err.Error())
-		if err != nil {
-			return 0
+		// Plugins print their own errors
+		var pluginErr *pluginExitError
+		if errors.As(err, &pluginErr) {
+			return pluginErr.exitCode
 		}
+
 		exitCode = 1 // exit with error
+		var exitErr *exitCodeError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.code
+		}
+
+		if _, printErr := fmt.Fprintln(cli.stderr, err.Error()); printErr != nil {
+			return 0
+		}
 
 		// If we get an unknown flag, it could be because the user expected
 		// config to be loaded, but it wasn't. Display a message to the user to
@@ -162,11 +327,26 @@ func (cli *AstroCLI) Run(args []string) (exitCode int) {
 
 // configureDynamicUserFlags dynamically adds Cobra flags based on the loaded
 // configuration.
-func (cli *AstroCLI) configureDynamicUserFlags() {
+func (cli *AstroCLI) configureDynamicUserFlags(profile string) {
 	projectFlags := flagsFromConfig(cli.config)
+	if profile != "" {
+		// the values of the profile are the defaults of the flags
+		for _, flag := range projectFlags {
+			flag.Value = cli.config.Profiles[profile].Variables[flag.Variable]
+		}
+	}
 	addProjectFlagsToCommands(projectFlags,
 		cli.commands.plan,
 		cli.commands.apply,
+		cli.commands.destroy,
+		cli.commands.compare,
+		cli.commands.compat,
+		cli.commands.explain,
+		cli.commands.graph,
+		cli.commands.list,
+		cli.commands.output,
+		cli.commands.validate,
+		cli.commands.watch,
 	)
 	cli.flags.projectFlags = projectFlags
 }
@@ -179,50 +359,108 @@ func (cli *AstroCLI) createRootCommand() {
 		SilenceErrors: true,
 	}
 
-	rootCmd.PersistentFlags().BoolVarP(&cli.flags.verbose, "verbose", "v", false, "verbose output")
+	rootCmd.PersistentFlags().CountVarP(&cli.flags.verbosity, "verbose", "v", "verbose output; -v for status updates, -vv to stream Terraform output, -vvv for trace output")
 	rootCmd.PersistentFlags().BoolVarP(&cli.flags.trace, "trace", "", false, "trace output")
+	rootCmd.PersistentFlags().BoolVar(&cli.flags.follow, "follow", false, "stream the output of Terraform as it runs, each line prefixed with the execution ID, without the rest of -vv")
+	rootCmd.PersistentFlags().MarkDeprecated("trace", "use -vvv instead")
 	rootCmd.PersistentFlags().StringVar(&cli.flags.userCfgFile, "config", "", "config file")
+	rootCmd.PersistentFlags().StringVar(&cli.flags.profile, "profile", "", "apply the variable values, Terraform configuration and parallelism of this profile in the config")
+	rootCmd.PersistentFlags().BoolVar(&cli.flags.autoInstall, "auto-install", false, "install Terraform with tvm if it is not found")
+	rootCmd.PersistentFlags().StringVar(&cli.flags.flavor, "flavor", "", "flavor of Terraform to look up and install: terraform or opentofu; overrides terraform.flavor in the config")
+	rootCmd.PersistentFlags().StringVar(&cli.flags.logLevel, "log-level", "", "log to stderr at this level: trace, debug, info, warn or error")
+	rootCmd.PersistentFlags().StringVar(&cli.flags.logFormat, "log-format", logger.FormatText, "log format: text or json")
+	rootCmd.PersistentFlags().StringVar(&cli.flags.logFile, "log-file", "", "also log to this file")
+	rootCmd.PersistentFlags().StringVar(&cli.flags.color, "color", colorAuto, "color output: auto, always or never; auto colors output to a terminal, unless NO_COLOR or CI is set")
+	rootCmd.PersistentFlags().BoolVar(&cli.flags.noColor, "no-color", false, "do not color output; same as --color=never")
 
 	cli.commands.root = rootCmd
 }
 
 func (cli *AstroCLI) createApplyCmd() {
 	applyCmd := &cobra.Command{
-		Use:                   "apply [flags] [-- [Terraform argument]...]",
+		Use:                   "apply [flags] [execution...] [-- [Terraform argument]...]",
 		DisableFlagsInUseLine: true,
 		Short:                 "Run Terraform apply on all modules",
 		PersistentPreRunE:     cli.preRun,
 		RunE:                  cli.runApply,
 	}
 
+	applyCmd.PersistentFlags().BoolVar(&cli.flags.approveRisk, "approve-risk", false, "apply plans whose risk score is above approve_above in the config")
+	applyCmd.PersistentFlags().BoolVar(&cli.flags.dryRun, "dry-run", false, "print the executions in the order they would run, with their Terraform commands and variables, without running anything")
+	applyCmd.PersistentFlags().StringVar(&cli.flags.filter, "filter", "", "only apply the executions matching this expression")
+	applyCmd.PersistentFlags().StringArrayVar(&cli.flags.targets, "target", nil, "resource address to limit the apply to, passed to Terraform as -target (can be repeated)")
+	applyCmd.PersistentFlags().BoolVar(&cli.flags.frozen, "frozen", false, "fail if module sources do not match the lock file")
+	applyCmd.PersistentFlags().StringVar(&cli.flags.fromSession, "from-session", "", "apply the plans saved in this session by plan --out")
+	applyCmd.PersistentFlags().BoolVar(&cli.flags.interactive, "interactive", false, "plan first, show the changes and ask which of them to apply")
+	applyCmd.PersistentFlags().DurationVar(&cli.flags.lockTimeout, "lock-timeout", 0, "how long to wait for the lock of the state of each execution, if another run holds it; overrides lock_timeout in the config")
+	applyCmd.PersistentFlags().StringVar(&cli.flags.jsonReportFile, "json-report", "", "write a JSON report of the results to this file")
 	applyCmd.PersistentFlags().StringVar(&cli.flags.moduleNamesString, "modules", "", "list of modules to apply")
+	applyCmd.PersistentFlags().IntVar(&cli.flags.parallelism, "parallel", 0, "maximum number of executions to run at the same time")
+	applyCmd.PersistentFlags().StringVar(&cli.flags.resume, "resume", "", "resume the last apply of this session, applying only the executions that failed, were skipped or didn't run")
+	applyCmd.PersistentFlags().StringVar(&cli.flags.sameVersionsAs, "same-versions-as", "", "run every execution with the Terraform binary it ran with in this session")
 
 	cli.commands.apply = applyCmd
 }
 
+func (cli *AstroCLI) createDestroyCmd() {
+	destroyCmd := &cobra.Command{
+		Use:                   "destroy [flags] [execution...] [-- [Terraform argument]...]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Run Terraform destroy on all modules",
+		PersistentPreRunE:     cli.preRun,
+		RunE:                  cli.runDestroy,
+	}
+
+	destroyCmd.PersistentFlags().StringVar(&cli.flags.filter, "filter", "", "only destroy the executions matching this expression")
+	destroyCmd.PersistentFlags().BoolVar(&cli.flags.frozen, "frozen", false, "fail if module sources do not match the lock file")
+	destroyCmd.PersistentFlags().DurationVar(&cli.flags.lockTimeout, "lock-timeout", 0, "how long to wait for the lock of the state of each execution, if another run holds it; overrides lock_timeout in the config")
+	destroyCmd.PersistentFlags().StringVar(&cli.flags.jsonReportFile, "json-report", "", "write a JSON report of the results to this file")
+	destroyCmd.PersistentFlags().StringVar(&cli.flags.moduleNamesString, "modules", "", "list of modules to destroy")
+	destroyCmd.PersistentFlags().IntVar(&cli.flags.parallelism, "parallel", 0, "maximum number of executions to run at the same time")
+
+	cli.commands.destroy = destroyCmd
+}
+
 func (cli *AstroCLI) createPlanCmd() {
 	planCmd := &cobra.Command{
-		Use:                   "plan [flags] [-- [Terraform argument]...]",
+		Use:                   "plan [flags] [execution...] [-- [Terraform argument]...]",
 		DisableFlagsInUseLine: true,
 		Short:                 "Generate execution plans for modules",
 		PersistentPreRunE:     cli.preRun,
 		RunE:                  cli.runPlan,
 	}
 
+	planCmd.PersistentFlags().BoolVar(&cli.flags.estimateCost, "cost", false, "estimate the monthly cost of the changes with Infracost")
 	planCmd.PersistentFlags().BoolVar(&cli.flags.detach, "detach", false, "disconnect remote state before planning")
+	planCmd.PersistentFlags().BoolVar(&cli.flags.detectDrift, "detect-drift", false, "plan with -refresh-only and exit with code 2 if any execution has drifted from its state")
+	planCmd.PersistentFlags().BoolVar(&cli.flags.dryRun, "dry-run", false, "print the executions in the order they would run, with their Terraform commands and variables, without running anything")
+	planCmd.PersistentFlags().StringVar(&cli.flags.filter, "filter", "", "only plan the executions matching this expression")
+	planCmd.PersistentFlags().StringArrayVar(&cli.flags.targets, "target", nil, "resource address to limit the plan to, passed to Terraform as -target (can be repeated)")
+	planCmd.PersistentFlags().BoolVar(&cli.flags.frozen, "frozen", false, "fail if module sources do not match the lock file")
+	planCmd.PersistentFlags().DurationVar(&cli.flags.lockTimeout, "lock-timeout", 0, "how long to wait for the lock of the state of each execution, if another run holds it; overrides lock_timeout in the config")
+	planCmd.PersistentFlags().StringVar(&cli.flags.jsonReportFile, "json-report", "", "write a JSON report of the results to this file")
 	planCmd.PersistentFlags().StringVar(&cli.flags.moduleNamesString, "modules", "", "list of modules to plan")
+	planCmd.PersistentFlags().IntVar(&cli.flags.parallelism, "parallel", 0, "maximum number of executions to run at the same time")
+	planCmd.PersistentFlags().BoolVar(&cli.flags.savePlans, "out", false, "save the plans in the session directory, to apply with apply --from-session")
+	planCmd.PersistentFlags().BoolVar(&cli.flags.rawOutput, "raw-output", false, "show the output of Terraform as it is, without parsing the changes out of it")
+	planCmd.PersistentFlags().BoolVar(&cli.flags.selectInteractive, "select-interactive", false, "choose the executions to plan from a list")
+	planCmd.PersistentFlags().BoolVar(&cli.flags.useGraph, "use-graph", false, "plan executions after the executions they depend on")
 
 	cli.commands.plan = planCmd
 }
 
-func (cli *AstroCLI) preRun(*cobra.Command, []string) error {
+func (cli *AstroCLI) preRun(cmd *cobra.Command, args []string) error {
 	logger.Trace.Println("cli: in preRun")
 
+	if err := validateColorMode(cli.flags.color); err != nil {
+		return err
+	}
 	if cli.config == nil {
 		return fmt.Errorf("unable to find config file")
 	}
+
 	// Load astro from config
-	project, err := astro.NewProject(astro.WithConfig(*cli.config))
+	project, err := cli.newProject(cmd)
 	if err != nil {
 		return err
 	}
@@ -231,32 +469,178 @@ func (cli *AstroCLI) preRun(*cobra.Command, []string) error {
 	return nil
 }
 
+// followTerraform returns whether the output of Terraform is streamed as
+// it runs, with --follow or -vv.
+func (cli *AstroCLI) followTerraform() bool {
+	return cli.flags.follow || cli.flags.verbosity >= logger.LevelTerraform
+}
+
+// configPresets returns the config presets set by the global flags.
+func (cli *AstroCLI) configPresets() astro.ConfigPresets {
+	return astro.ConfigPresets{Profile: cli.flags.profile, Flavor: cli.flags.flavor}
+}
+
+// newProject returns a new project from the config, for the command.
+func (cli *AstroCLI) newProject(cmd *cobra.Command) (*astro.Project, error) {
+	command := cmd.Name()
+	opts := []astro.Option{
+		astro.WithConfig(*cli.config),
+		astro.WithSessionLog(cli.flags.logFormat),
+		astro.WithCommand(cli.commands.root.Name(), cli.args),
+		astro.WithVersion(version),
+		astro.WithTimeoutWarnings(func(warning astro.TimeoutWarning) {
+			cli.notifyTimeout(command, warning)
+		}),
+	}
+	if cli.followTerraform() {
+		opts = append(opts, astro.WithTerraformOutput(cli.stdout))
+	}
+	if flag := cmd.Flags().Lookup("lock-timeout"); flag != nil && flag.Changed {
+		opts = append(opts, astro.WithLockTimeout(cli.flags.lockTimeout))
+	}
+
+	return astro.NewProject(opts...)
+}
+
+// closeProject ends the session of the project the command ran, which
+// archives it if the config says so.
+func (cli *AstroCLI) closeProject() {
+	if cli.project == nil {
+		return
+	}
+	if err := cli.project.Close(); err != nil {
+		cli.printWarning("unable to archive session: %v", err)
+	}
+}
+
 // processError interprets certain astro errors and embellishes them for
 // display on the CLI.
 func (cli *AstroCLI) processError(err error) error {
 	var e *astro.MissingRequiredVarsError // change this line
+	var lockErr *astro.LockMismatchError
+	var noMatchErr *astro.NoExecutionsMatchedError
+	var lockedErr *state.LockedError
+	var invalidErr *conf.InvalidValueError
 	switch {
 	case errors.As(err, &e):
 		return fmt.Errorf("missing required flags: %s", strings.Join(cli.varsToFlagNames(e.MissingVars()), ", "))
+	case errors.As(err, &lockErr):
+		return fmt.Errorf("%v; run `astro lock` to update the lock file", lockErr)
+	case errors.As(err, &lockedErr):
+		return fmt.Errorf("%v; if it is no longer running, run `astro force-unlock`", lockedErr)
+	case errors.As(err, &noMatchErr):
+		return cli.noExecutionsMatchedError(noMatchErr)
+	case errors.As(err, &invalidErr):
+		return cli.invalidValueErrors(err)
 	default:
 		return err
 	}
 }
 
-func (cli *AstroCLI) runApply(_ *cobra.Command, args []string) error {
+func (cli *AstroCLI) runApply(cmd *cobra.Command, args []string) error {
+	// Dry runs don't run anything, including pre-flight checks
+	if !cli.flags.dryRun {
+		if err := cli.preflight(); err != nil {
+			return err
+		}
+	}
+
+	vars := flagsToUserVariables(cli.flags.projectFlags)
+	patterns, terraformArgs := splitArgs(cmd, args)
+
+	var moduleNames []string
+	if cli.flags.moduleNamesString != "" {
+		moduleNames = strings.Split(cli.flags.moduleNamesString, ",")
+	}
+
+	parameters := astro.ApplyExecutionParameters{
+		ExecutionParameters: astro.ExecutionParameters{
+			ModuleNames:         moduleNames,
+			UserVars:            vars,
+			TerraformParameters: terraformArgs,
+			ExecutionPatterns:   patterns,
+			Filter:              cli.flags.filter,
+			Targets:             cli.flags.targets,
+			Frozen:              cli.flags.frozen,
+			Parallelism:         cli.flags.parallelism,
+		},
+		FromSession:    cli.flags.fromSession,
+		SameVersionsAs: cli.flags.sameVersionsAs,
+		Resume:         cli.flags.resume,
+		ApproveRisk:    cli.flags.approveRisk,
+	}
+
+	if cli.flags.dryRun {
+		executions, err := cli.project.DryRunApply(parameters)
+		if err != nil {
+			return fmt.Errorf("ERROR: %v", cli.processError(err))
+		}
+		return printDryRun(cli.stdout, executions)
+	}
+
+	if cli.flags.interactive {
+		approved, err := cli.approveApply(parameters)
+		if err != nil {
+			return err
+		}
+		if approved == nil {
+			return nil
+		}
+		parameters = *approved
+	}
+
+	status, results, err := cli.project.Apply(parameters)
+	if err != nil {
+		return fmt.Errorf("ERROR: %v", cli.processError(err))
+	}
+
+	collected, err := cli.printExecStatus(status, results)
+	cli.notify("apply", collected)
+	cli.annotate("apply", collected)
+	if reportErr := cli.writeJSONReport(collected); reportErr != nil {
+		return fmt.Errorf("unable to write JSON report: %v", reportErr)
+	}
+	if err != nil {
+		if sessionID, sessionErr := cli.project.SessionID(); sessionErr == nil {
+			fmt.Fprintf(cli.stdout, "\nTo apply the executions that failed or didn't run, run: astro apply --resume %s\n", sessionID)
+		}
+		return cli.exitWith(conf.ResultsFailed, errors.New("done; there were errors; some modules may not have been applied"))
+	}
+	if err := cli.resultsExitError(collected); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(cli.stdout, "Done")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (cli *AstroCLI) runDestroy(cmd *cobra.Command, args []string) error {
+	if err := cli.preflight(); err != nil {
+		return err
+	}
+
 	vars := flagsToUserVariables(cli.flags.projectFlags)
+	patterns, terraformArgs := splitArgs(cmd, args)
 
 	var moduleNames []string
 	if cli.flags.moduleNamesString != "" {
 		moduleNames = strings.Split(cli.flags.moduleNamesString, ",")
 	}
 
-	status, results, err := cli.project.Apply(
-		astro.ApplyExecutionParameters{
+	status, results, err := cli.project.Destroy(
+		astro.DestroyExecutionParameters{
 			ExecutionParameters: astro.ExecutionParameters{
 				ModuleNames:         moduleNames,
 				UserVars:            vars,
-				TerraformParameters: args,
+				TerraformParameters: terraformArgs,
+				ExecutionPatterns:   patterns,
+				Filter:              cli.flags.filter,
+				Frozen:              cli.flags.frozen,
+				Parallelism:         cli.flags.parallelism,
 			},
 		},
 	)
@@ -264,9 +648,17 @@ func (cli *AstroCLI) runApply(_ *cobra.Command, args []string) error {
 		return fmt.Errorf("ERROR: %v", cli.processError(err))
 	}
 
-	err = cli.printExecStatus(status, results)
+	collected, err := cli.printExecStatus(status, results)
+	cli.notify("destroy", collected)
+	cli.annotate("destroy", collected)
+	if reportErr := cli.writeJSONReport(collected); reportErr != nil {
+		return fmt.Errorf("unable to write JSON report: %v", reportErr)
+	}
 	if err != nil {
-		return fmt.Errorf("done; there were errors; some modules may not have been applied")
+		return cli.exitWith(conf.ResultsFailed, errors.New("done; there were errors; some modules may not have been destroyed"))
+	}
+	if err := cli.resultsExitError(collected); err != nil {
+		return err
 	}
 
 	_, err = fmt.Fprintln(cli.stdout, "Done")
@@ -277,33 +669,94 @@ func (cli *AstroCLI) runApply(_ *cobra.Command, args []string) error {
 	return nil
 }
 
-func (cli *AstroCLI) runPlan(_ *cobra.Command, args []string) error {
+func (cli *AstroCLI) runPlan(cmd *cobra.Command, args []string) error {
 	logger.Trace.Printf("cli: plan args: %s\n", args)
 
+	if !cli.flags.dryRun {
+		if err := cli.preflight(); err != nil {
+			return err
+		}
+	}
+
 	vars := flagsToUserVariables(cli.flags.projectFlags)
+	patterns, terraformArgs := splitArgs(cmd, args)
 
 	var moduleNames []string
 	if cli.flags.moduleNamesString != "" {
 		moduleNames = strings.Split(cli.flags.moduleNamesString, ",")
 	}
 
-	status, results, err := cli.project.Plan(
-		astro.PlanExecutionParameters{
-			ExecutionParameters: astro.ExecutionParameters{
-				ModuleNames:         moduleNames,
-				UserVars:            vars,
-				TerraformParameters: args,
-			},
-			Detach: cli.flags.detach,
+	parameters := astro.PlanExecutionParameters{
+		ExecutionParameters: astro.ExecutionParameters{
+			ModuleNames:         moduleNames,
+			UserVars:            vars,
+			TerraformParameters: terraformArgs,
+			ExecutionPatterns:   patterns,
+			Filter:              cli.flags.filter,
+			Targets:             cli.flags.targets,
+			Frozen:              cli.flags.frozen,
+			Parallelism:         cli.flags.parallelism,
 		},
-	)
+		Detach:       cli.flags.detach,
+		SavePlans:    cli.flags.savePlans,
+		UseGraph:     cli.flags.useGraph,
+		DetectDrift:  cli.flags.detectDrift,
+		EstimateCost: cli.flags.estimateCost,
+		RawOutput:    cli.flags.rawOutput,
+	}
+
+	if cli.flags.selectInteractive {
+		executionIDs, err := cli.selectExecutions(parameters.ExecutionParameters)
+		if err != nil {
+			return fmt.Errorf("ERROR: %v", cli.processError(err))
+		}
+		parameters.ExecutionIDs = executionIDs
+	}
+
+	if cli.flags.dryRun {
+		executions, err := cli.project.DryRunPlan(parameters)
+		if err != nil {
+			return fmt.Errorf("ERROR: %v", cli.processError(err))
+		}
+		return printDryRun(cli.stdout, executions)
+	}
+
+	status, results, err := cli.project.Plan(parameters)
 	if err != nil {
 		return fmt.Errorf("ERROR: %v", cli.processError(err))
 	}
 
-	err = cli.printExecStatus(status, results)
+	collected, err := cli.printExecStatus(status, results)
+	cli.notify("plan", collected)
+	cli.annotate("plan", collected)
+	if reportErr := cli.writeJSONReport(collected); reportErr != nil {
+		return fmt.Errorf("unable to write JSON report: %v", reportErr)
+	}
+	if link, uploadErr := cli.uploadPlanReport(collected); uploadErr != nil {
+		cli.printWarning("unable to upload plan report: %v", uploadErr)
+	} else if link != "" {
+		fmt.Fprintf(cli.stdout, "\nPlan report: %s\n", link)
+	}
+	if cli.flags.savePlans {
+		sessionID, sessionErr := cli.project.SessionID()
+		if sessionErr != nil {
+			return sessionErr
+		}
+		fmt.Fprintf(cli.stdout, "\nPlans saved. To apply them, run: astro apply --from-session %s\n", sessionID)
+	}
+	if cli.flags.detectDrift {
+		if summaryErr := cli.printDriftSummary(collected); summaryErr != nil {
+			return summaryErr
+		}
+	}
 	if err != nil {
-		return errors.New("done; there were errors")
+		return cli.exitWith(conf.ResultsFailed, errors.New("done; there were errors"))
+	}
+	if drifted := driftedExecutions(collected); cli.flags.detectDrift && len(drifted) > 0 {
+		return cli.exitWith(conf.ResultsDrifted, &driftError{drifted: len(drifted)})
+	}
+	if err := cli.resultsExitError(collected); err != nil {
+		return err
 	}
 
 	_, err = fmt.Fprintln(cli.stdout, "Done")
@@ -313,3 +766,23 @@ func (cli *AstroCLI) runPlan(_ *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// splitArgs splits the arguments of a command into the patterns of the
+// executions to run, which come before any "--", and the arguments to pass
+// to Terraform, which come after it.
+func splitArgs(cmd *cobra.Command, args []string) (patterns []string, terraformArgs []string) {
+	dash := cmd.ArgsLenAtDash()
+	if dash < 0 {
+		return nilIfEmpty(args), nil
+	}
+	return nilIfEmpty(args[:dash]), args[dash:]
+}
+
+// nilIfEmpty returns nil for empty slices, as a nil list of patterns
+// means that executions aren't filtered.
+func nilIfEmpty(s []string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,44 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFollowTerraform(t *testing.T) {
+	for args, expected := range map[string]bool{
+		"":         false,
+		"-v":       false,
+		"-vv":      true,
+		"--follow": true,
+	} {
+		cli, err := NewAstroCLI(WithStdout(&bytes.Buffer{}), WithStderr(&bytes.Buffer{}))
+		require.NoError(t, err)
+
+		var flags []string
+		if args != "" {
+			flags = []string{args}
+		}
+		require.NoError(t, cli.commands.root.PersistentFlags().Parse(flags))
+		assert.Equal(t, expected, cli.followTerraform(), args)
+	}
+}
@@ -0,0 +1,121 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"github.com/logrusorgru/aurora"
+)
+
+// When the output of the CLI is colored, with --color.
+const (
+	// colorAuto colors output that goes to a terminal that can show
+	// colors, unless NO_COLOR or CI is set.
+	colorAuto = "auto"
+	// colorAlways colors all output, e.g. for CI systems that show
+	// colors in their logs.
+	colorAlways = "always"
+	// colorNever never colors output.
+	colorNever = "never"
+)
+
+// matches the escape sequences that color output, e.g. Terraform's.
+var reColor = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// validateColorMode checks the value of --color.
+func validateColorMode(mode string) error {
+	switch mode {
+	case colorAuto, colorAlways, colorNever:
+		return nil
+	}
+	return fmt.Errorf("--color must be one of %s, %s or %s", colorAuto, colorAlways, colorNever)
+}
+
+// colors returns the colors to write to w with: the ANSI colors if w can
+// show them, or plain text. This is the only place that decides whether
+// output is colored, so everything the CLI prints must be colored with
+// it rather than with aurora directly.
+func (cli *AstroCLI) colors(w io.Writer) aurora.Aurora {
+	return aurora.NewAurora(cli.colorEnabled(w))
+}
+
+// uncolor removes the colors from s, e.g. from the output of Terraform,
+// unless w shows them.
+func (cli *AstroCLI) uncolor(w io.Writer, s string) string {
+	if cli.colorEnabled(w) {
+		return s
+	}
+	return reColor.ReplaceAllString(s, "")
+}
+
+// colorEnabled returns whether output written to w is colored, with
+// --color, or --no-color.
+func (cli *AstroCLI) colorEnabled(w io.Writer) bool {
+	mode := cli.flags.color
+	if cli.flags.noColor {
+		mode = colorNever
+	}
+	return colorEnabled(mode, w, os.Getenv)
+}
+
+// colorEnabled returns whether output written to w is colored in mode.
+// In auto mode, output is only colored if w is a terminal that is not
+// dumb, and astro isn't running in CI, where terminals are often
+// emulated badly. The NO_COLOR and FORCE_COLOR conventions are followed,
+// with NO_COLOR taking precedence, so that colors can be turned off or
+// on without passing --color to every command.
+func colorEnabled(mode string, w io.Writer, getenv func(string) string) bool {
+	switch mode {
+	case colorAlways:
+		return true
+	case colorNever:
+		return false
+	}
+
+	if getenv("NO_COLOR") != "" {
+		return false
+	}
+	if force := getenv("FORCE_COLOR"); force != "" && force != "0" && force != "false" {
+		return true
+	}
+	if ci := getenv("CI"); ci != "" && ci != "0" && ci != "false" {
+		return false
+	}
+	if term := getenv("TERM"); term == "dumb" {
+		return false
+	}
+
+	f, ok := w.(*os.File)
+	if !ok || !isTerminal(f) {
+		return false
+	}
+	return enableTerminalColors(f)
+}
+
+// isTerminal returns whether f is a terminal, rather than e.g. a pipe or
+// a file.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
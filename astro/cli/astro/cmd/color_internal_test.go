@@ -0,0 +1,77 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestColorEnabled(t *testing.T) {
+	// a character device, which passes for a terminal
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	require.NoError(t, err)
+	defer devNull.Close()
+
+	for _, test := range []struct {
+		name     string
+		mode     string
+		w        io.Writer
+		env      map[string]string
+		expected bool
+	}{
+		{name: "terminal", mode: colorAuto, w: devNull, expected: true},
+		{name: "not a terminal", mode: colorAuto, w: &bytes.Buffer{}, expected: false},
+		{name: "dumb terminal", mode: colorAuto, w: devNull, env: map[string]string{"TERM": "dumb"}, expected: false},
+		{name: "CI", mode: colorAuto, w: devNull, env: map[string]string{"CI": "true"}, expected: false},
+		{name: "CI=false", mode: colorAuto, w: devNull, env: map[string]string{"CI": "false"}, expected: true},
+		{name: "NO_COLOR", mode: colorAuto, w: devNull, env: map[string]string{"NO_COLOR": "1"}, expected: false},
+		{name: "FORCE_COLOR", mode: colorAuto, w: &bytes.Buffer{}, env: map[string]string{"FORCE_COLOR": "1", "CI": "true"}, expected: true},
+		{name: "NO_COLOR over FORCE_COLOR", mode: colorAuto, w: devNull, env: map[string]string{"NO_COLOR": "1", "FORCE_COLOR": "1"}, expected: false},
+		{name: "always", mode: colorAlways, w: &bytes.Buffer{}, env: map[string]string{"NO_COLOR": "1"}, expected: true},
+		{name: "never", mode: colorNever, w: devNull, env: map[string]string{"FORCE_COLOR": "1"}, expected: false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			getenv := func(key string) string { return test.env[key] }
+			assert.Equal(t, test.expected, colorEnabled(test.mode, test.w, getenv))
+		})
+	}
+}
+
+func TestUncolor(t *testing.T) {
+	cli := &AstroCLI{}
+	cli.flags.color = colorNever
+
+	out := &bytes.Buffer{}
+	assert.Equal(t, "WARNING: changes", cli.uncolor(out, "\x1b[33mWARNING:\x1b[0m changes"))
+	assert.Equal(t, "OK", cli.colors(out).Green("OK").String())
+
+	cli.flags.color = colorAlways
+	assert.Equal(t, "\x1b[33mWARNING:\x1b[0m changes", cli.uncolor(out, "\x1b[33mWARNING:\x1b[0m changes"))
+	assert.Equal(t, "\x1b[32mOK\x1b[0m", cli.colors(out).Green("OK").String())
+
+	cli.flags.noColor = true
+	assert.Equal(t, "OK", cli.colors(out).Green("OK").String())
+
+	assert.NoError(t, validateColorMode(colorAuto))
+	assert.EqualError(t, validateColorMode("yes"), "--color must be one of auto, always or never")
+}
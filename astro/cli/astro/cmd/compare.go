@@ -0,0 +1,153 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/uber/astro/astro"
+
+	"github.com/spf13/cobra"
+)
+
+func (cli *AstroCLI) createCompareCmd() {
+	compareCmd := &cobra.Command{
+		Use:                   "compare --ref BASE --ref HEAD [flags]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Compare the plans of the project at two git refs",
+		RunE:                  cli.runCompare,
+	}
+
+	compareCmd.PersistentFlags().StringArrayVar(&cli.flags.compareRefs, "ref", nil, "git ref to plan the project at (must be given twice)")
+	compareCmd.PersistentFlags().StringVar(&cli.flags.moduleNamesString, "modules", "", "list of modules to compare")
+	compareCmd.PersistentFlags().IntVar(&cli.flags.parallelism, "parallel", 0, "maximum number of executions to run at the same time")
+
+	cli.commands.compare = compareCmd
+}
+
+func (cli *AstroCLI) runCompare(cmd *cobra.Command, args []string) error {
+	if len(cli.flags.compareRefs) != 2 {
+		return errors.New("ERROR: --ref must be given exactly twice, e.g. --ref main --ref my-branch")
+	}
+	if cli.configFilePath == "" {
+		return errors.New("ERROR: unable to find config file")
+	}
+
+	var moduleNames []string
+	if cli.flags.moduleNamesString != "" {
+		moduleNames = strings.Split(cli.flags.moduleNamesString, ",")
+	}
+
+	parameters := astro.PlanExecutionParameters{
+		ExecutionParameters: astro.ExecutionParameters{
+			ModuleNames: moduleNames,
+			UserVars:    flagsToUserVariables(cli.flags.projectFlags),
+			Parallelism: cli.flags.parallelism,
+		},
+		Detach: true,
+	}
+
+	base, head := cli.flags.compareRefs[0], cli.flags.compareRefs[1]
+
+	baseResults, err := cli.planAtRef(base, parameters)
+	if err != nil {
+		return fmt.Errorf("ERROR: %v", err)
+	}
+	headResults, err := cli.planAtRef(head, parameters)
+	if err != nil {
+		return fmt.Errorf("ERROR: %v", err)
+	}
+
+	failed, differ := 0, 0
+	for _, comparison := range astro.ComparePlans(baseResults, headResults) {
+		status := comparison.Status()
+		switch status {
+		case astro.ComparisonUnchanged:
+			continue
+		case astro.ComparisonFailed:
+			failed++
+			fmt.Fprintf(cli.stderr, "\n%s: %s\n", comparison.ID, cli.colors(cli.stderr).Red(status))
+		default:
+			differ++
+			fmt.Fprintf(cli.stdout, "\n%s: %s\n", comparison.ID, cli.colors(cli.stdout).Brown(status))
+		}
+
+		if diff := comparison.Diff(base, head); diff != "" {
+			fmt.Fprint(cli.stdout, diff)
+		}
+		for _, side := range []struct {
+			ref    string
+			result *astro.Result
+		}{{base, comparison.Base}, {head, comparison.Head}} {
+			if side.result != nil && side.result.Err() != nil {
+				fmt.Fprintf(cli.stderr, "%s: %v\n", side.ref, side.result.Err())
+			}
+		}
+	}
+
+	if differ == 0 {
+		fmt.Fprintf(cli.stdout, "\nNo differences between the plans at %s and %s\n", base, head)
+	} else {
+		fmt.Fprintf(cli.stdout, "\n%d executions differ between %s and %s\n", differ, base, head)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("done; %d executions could not be compared", failed)
+	}
+
+	return nil
+}
+
+// planAtRef plans the project at the git ref, in a working tree of its
+// own that is removed afterwards, and returns the results.
+func (cli *AstroCLI) planAtRef(ref string, parameters astro.PlanExecutionParameters) ([]*astro.Result, error) {
+	fmt.Fprintf(cli.stdout, "Planning at %s...\n", ref)
+
+	checkout, err := astro.CheckoutRef(cli.configFilePath, ref)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := checkout.Remove(); err != nil {
+			cli.printWarning("unable to remove working tree %s: %v", checkout.Dir, err)
+		}
+	}()
+
+	config, err := astro.NewConfigFromFileWithPresets(checkout.ConfigFile, cli.configPresets())
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []astro.Option{astro.WithConfig(*config), astro.WithSessionLog(cli.flags.logFormat)}
+	if cli.followTerraform() {
+		opts = append(opts, astro.WithTerraformOutput(cli.stdout))
+	}
+
+	project, err := astro.NewProject(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	status, results, err := project.Plan(parameters)
+	if err != nil {
+		return nil, fmt.Errorf("unable to plan at %s: %v", ref, cli.processError(err))
+	}
+
+	return astro.Collect(status, results), nil
+}
@@ -0,0 +1,169 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/uber/astro/astro"
+
+	"github.com/spf13/cobra"
+)
+
+func (cli *AstroCLI) createCompatCmd() {
+	compatCmd := &cobra.Command{
+		Use:                   "compat --versions VERSION,... [flags]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Check the project plans the same with other versions of Terraform",
+		RunE:                  cli.runCompat,
+	}
+
+	compatCmd.PersistentFlags().StringVar(&cli.flags.compatVersions, "versions", "", "comma-separated Terraform versions, or paths to Terraform or OpenTofu binaries, to plan with; the first is compared with the others")
+	compatCmd.PersistentFlags().StringVar(&cli.flags.moduleNamesString, "modules", "", "list of modules to plan")
+	compatCmd.PersistentFlags().IntVar(&cli.flags.parallelism, "parallel", 0, "maximum number of executions to run at the same time")
+
+	cli.commands.compat = compatCmd
+}
+
+func (cli *AstroCLI) runCompat(cmd *cobra.Command, args []string) error {
+	if cli.flags.compatVersions == "" {
+		return errors.New("ERROR: --versions is required, e.g. --versions 1.5.7,1.7.5")
+	}
+	if cli.configFilePath == "" {
+		return errors.New("ERROR: unable to find config file")
+	}
+
+	var engines []*astro.EngineVersion
+	for _, s := range strings.Split(cli.flags.compatVersions, ",") {
+		engine, err := astro.ParseEngineVersion(strings.TrimSpace(s))
+		if err != nil {
+			return fmt.Errorf("ERROR: %v", err)
+		}
+		engines = append(engines, engine)
+	}
+
+	var moduleNames []string
+	if cli.flags.moduleNamesString != "" {
+		moduleNames = strings.Split(cli.flags.moduleNamesString, ",")
+	}
+
+	parameters := astro.PlanExecutionParameters{
+		ExecutionParameters: astro.ExecutionParameters{
+			ModuleNames: moduleNames,
+			UserVars:    flagsToUserVariables(cli.flags.projectFlags),
+			Parallelism: cli.flags.parallelism,
+		},
+		Detach: true,
+	}
+
+	results := make([][]*astro.Result, len(engines))
+	failed := 0
+	for i, engine := range engines {
+		var err error
+		results[i], err = cli.planWithEngine(engine, parameters)
+		if err != nil {
+			return fmt.Errorf("ERROR: %v", err)
+		}
+
+		for _, result := range results[i] {
+			for _, result := range append([]*astro.Result{result}, result.SubResults()...) {
+				if result.Err() != nil {
+					failed++
+					fmt.Fprintf(cli.stderr, "\n%s: %s with %s\n%v\n", result.ID(), cli.colors(cli.stderr).Red("failed"), engine.Name, result.Err())
+				}
+			}
+		}
+	}
+
+	base := engines[0]
+	for i, head := range engines[1:] {
+		compared, differ := 0, 0
+		for _, comparison := range astro.ComparePlans(results[0], results[i+1]) {
+			status := comparison.Status()
+			// failures have been reported already
+			if status == astro.ComparisonFailed {
+				continue
+			}
+			compared++
+			if status == astro.ComparisonUnchanged {
+				continue
+			}
+			differ++
+			fmt.Fprintf(cli.stdout, "\n%s: %s\n", comparison.ID, cli.colors(cli.stdout).Brown(status))
+			if diff := comparison.Diff(base.Name, head.Name); diff != "" {
+				fmt.Fprint(cli.stdout, diff)
+			}
+		}
+
+		if compared == 0 {
+			continue
+		} else if differ == 0 {
+			fmt.Fprintf(cli.stdout, "\nNo differences between the plans with %s and %s\n", base.Name, head.Name)
+		} else {
+			fmt.Fprintf(cli.stdout, "\n%d executions differ between %s and %s\n", differ, base.Name, head.Name)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("done; %d plans failed", failed)
+	}
+
+	return nil
+}
+
+// planWithEngine plans the project with every module running with the
+// engine, in a session repo of its own that is removed afterwards, and
+// returns the results.
+func (cli *AstroCLI) planWithEngine(engine *astro.EngineVersion, parameters astro.PlanExecutionParameters) ([]*astro.Result, error) {
+	fmt.Fprintf(cli.stdout, "Planning with %s...\n", engine.Name)
+
+	dir, err := os.MkdirTemp("", "astro-compat-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			cli.printWarning("unable to remove sandbox %s: %v", dir, err)
+		}
+	}()
+
+	config, err := astro.NewConfigFromFileWithPresets(cli.configFilePath, cli.configPresets())
+	if err != nil {
+		return nil, err
+	}
+	engine.Sandbox(config, dir)
+
+	opts := []astro.Option{astro.WithConfig(*config), astro.WithSessionLog(cli.flags.logFormat)}
+	if cli.followTerraform() {
+		opts = append(opts, astro.WithTerraformOutput(cli.stdout))
+	}
+
+	project, err := astro.NewProject(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	status, results, err := project.Plan(parameters)
+	if err != nil {
+		return nil, fmt.Errorf("unable to plan with %s: %v", engine.Name, cli.processError(err))
+	}
+
+	return astro.Collect(status, results), nil
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 
 	"github.com/spf13/cobra"
+	"github.com/uber/astro/astro/logger"
 	"github.com/uber/astro/astro/utils"
 )
 
@@ -28,14 +29,29 @@ import (
 var configFileSearchPaths = []string{
 	"astro.yaml",
 	"astro.yml",
+	"astro.hcl",
 	"terraform/astro.yaml",
 	"terraform/astro.yml",
+	"terraform/astro.hcl",
 }
 
-// configPathFromArgs reads the command line arguments and returns the value of
-// the config option. It returns an empty string if there is no path in the
-// args.
-func configPathFromArgs(args []string) (configFilePath string, err error) {
+// earlyFlags are the flags that are needed before the config is loaded.
+type earlyFlags struct {
+	configFilePath string
+	autoInstall    bool
+	logLevel       string
+	logFormat      string
+	logFile        string
+	profile        string
+	flavor         string
+	// command is the name of the command in the args, e.g. "plan".
+	command string
+}
+
+// earlyFlagsFromArgs reads the command line arguments and returns the
+// values of the flags that are needed to load the config. The config file
+// path is empty if there is no path in the args.
+func earlyFlagsFromArgs(args []string) (flags earlyFlags, err error) {
 	// this is a special cobra command so that we can parse just the config
 	// flag early in the program lifecycle.
 	findConfig := &cobra.Command{
@@ -57,16 +73,23 @@ func configPathFromArgs(args []string) (configFilePath string, err error) {
 	}
 
 	// Do an early first parse of the config flag before the main command,
-	findConfig.PersistentFlags().StringVar(&configFilePath, "config", "", "config file")
+	findConfig.PersistentFlags().StringVar(&flags.configFilePath, "config", "", "config file")
+	findConfig.PersistentFlags().BoolVar(&flags.autoInstall, "auto-install", false, "install Terraform if it is not found")
+	findConfig.PersistentFlags().StringVar(&flags.logLevel, "log-level", "", "log level")
+	findConfig.PersistentFlags().StringVar(&flags.logFormat, "log-format", logger.FormatText, "log format")
+	findConfig.PersistentFlags().StringVar(&flags.logFile, "log-file", "", "log file")
+	findConfig.PersistentFlags().StringVar(&flags.profile, "profile", "", "profile")
+	findConfig.PersistentFlags().StringVar(&flags.flavor, "flavor", "", "flavor")
 	if err := findConfig.ParseFlags(finalArgs); err != nil {
-		return "", err
+		return earlyFlags{}, err
 	}
+	flags.command = findConfig.Flags().Arg(0)
 
-	if configFilePath != "" && !utils.FileExists(configFilePath) {
-		return "", fmt.Errorf("%v: file does not exist", configFilePath)
+	if flags.configFilePath != "" && !utils.FileExists(flags.configFilePath) {
+		return earlyFlags{}, fmt.Errorf("%v: file does not exist", flags.configFilePath)
 	}
 
-	return configFilePath, nil
+	return flags, nil
 }
 
 // firstExistingFilePath takes a list of paths and returns the first one
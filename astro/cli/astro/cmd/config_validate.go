@@ -0,0 +1,51 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func (cli *AstroCLI) createConfigCmd() {
+	configCmd := &cobra.Command{
+		Use:                   "config",
+		DisableFlagsInUseLine: true,
+		Short:                 "Work with the astro config",
+	}
+
+	validateCmd := &cobra.Command{
+		Use:               "validate",
+		Short:             "Check the config for mistakes without running Terraform",
+		Args:              cobra.NoArgs,
+		PersistentPreRunE: cli.preRun,
+		RunE:              cli.runConfigValidate,
+	}
+
+	configCmd.AddCommand(validateCmd)
+
+	cli.commands.config = configCmd
+}
+
+// runConfigValidate reports that the config is valid. The config is
+// checked when it is loaded, and again when the project is created in
+// preRun, so a config with mistakes doesn't get this far.
+func (cli *AstroCLI) runConfigValidate(*cobra.Command, []string) error {
+	_, err := fmt.Fprintf(cli.stdout, "%v: config is valid\n", cli.configFilePath)
+	return err
+}
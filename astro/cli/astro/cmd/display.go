@@ -17,24 +17,34 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"github.com/uber/astro/astro"
+	"github.com/uber/astro/astro/cost"
+	"github.com/uber/astro/astro/logger"
 	"github.com/uber/astro/astro/terraform"
 	"io"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
-	"github.com/logrusorgru/aurora"
 )
 
 // printExecStatus takes channels for status updates and exec results
-// and prints them on screen as they arrive.
-func (cli *AstroCLI) printExecStatus(status <-chan string, results <-chan *astro.Result) (errors error) {
+// and prints them on screen as they arrive. Once all results have arrived,
+// a summary of the run and of the changes is printed. It returns all of
+// the results.
+func (cli *AstroCLI) printExecStatus(status <-chan string, results <-chan *astro.Result) (collected []*astro.Result, errors error) {
+	started := time.Now()
+
 	// Print status updates to stdout as they arrive
+	statusDone := make(chan struct{})
 	if status != nil {
 		go func() {
+			defer close(statusDone)
+
 			var out io.Writer
 
-			if cli.flags.verbose {
+			if cli.flags.verbosity >= logger.LevelStatus {
 				out = cli.stdout
 			} else {
 				out = io.Discard
@@ -47,11 +57,12 @@ func (cli *AstroCLI) printExecStatus(status <-chan string, results <-chan *astro
 				}
 			}
 		}()
+	} else {
+		close(statusDone)
 	}
 
 	for result := range results {
-		var resultType, changesInfo, runtimeInfo string
-		var out = cli.stdout
+		collected = append(collected, result)
 
 		// If this was an error, append it to the list of errors to
 		// return.
@@ -59,75 +70,273 @@ func (cli *AstroCLI) printExecStatus(status <-chan string, results <-chan *astro
 			errors = multierror.Append(errors, result.Err())
 		}
 
-		terraformResult := result.TerraformResult()
-
-		// Check to see if this result is from a plan
-		planResult, _ := terraformResult.(*terraform.PlanResult)
-
-		if result.Err() == nil {
-			resultType = aurora.Green("OK").String()
-		} else {
-			resultType = aurora.Red("ERROR").String()
-			out = cli.stderr
+		if err := cli.printResult(result); err != nil {
+			return collected, err
 		}
 
-		// If this is a plan, show whether it has changes or not
-		if planResult != nil {
-			if planResult.HasChanges() {
-				changesInfo = aurora.Brown(" Changes").String()
-			} else {
-				changesInfo = aurora.Gray(" No changes").String()
+		for _, subResult := range result.SubResults() {
+			if err := cli.printResult(subResult); err != nil {
+				return collected, err
 			}
 		}
+	}
+
+	// The status channel is closed before the results channel, so wait
+	// for any remaining updates to be printed before the summary.
+	<-statusDone
+
+	if err := cli.printRunSummary(astro.Summarize(collected, time.Since(started))); err != nil {
+		return collected, err
+	}
+	if err := cli.printChangeSummary(collected); err != nil {
+		return collected, err
+	}
+	if err := cli.printCostSummary(collected); err != nil {
+		return collected, err
+	}
 
-		if terraformResult != nil {
-			runtimeInfo = terraformResult.Runtime()
-			runtimeInfo = aurora.Sprintf(aurora.Gray(" (%s)"), result.TerraformResult().Runtime())
+	return collected, errors
+}
+
+// printResult prints the status line of a single result, followed by the
+// plan if it has changes, and any errors.
+func (cli *AstroCLI) printResult(result *astro.Result) error {
+	var resultType, changesInfo, runtimeInfo string
+	var out = cli.stdout
+	var colors = cli.colors(out)
+
+	terraformResult := result.TerraformResult()
+
+	// Check to see if this result is from a plan
+	planResult, _ := terraformResult.(*terraform.PlanResult)
+
+	var blocked *astro.PreconditionError
+	if result.SkipReason() != "" {
+		resultType = colors.Gray("SKIPPED").String()
+		changesInfo = fmt.Sprintf(" (%s)", result.SkipReason())
+	} else if errors.As(result.Err(), &blocked) {
+		out = cli.stderr
+		colors = cli.colors(out)
+		resultType = colors.Brown("BLOCKED").String()
+		changesInfo = fmt.Sprintf(" (precondition %s failed: %s)", blocked.Precondition, blocked.Reason)
+	} else if result.Err() == nil {
+		resultType = colors.Green("OK").String()
+	} else {
+		out = cli.stderr
+		colors = cli.colors(out)
+		resultType = colors.Red("ERROR").String()
+	}
+
+	// If this is a plan, show whether it has changes or not
+	if planResult != nil {
+		if planResult.HasChanges() {
+			changesInfo = colors.Brown(" Changes").String()
+			if counts := result.ChangeCounts(); counts.Total() > 0 {
+				changesInfo = colors.Sprintf(colors.Brown(" Changes: %s"), counts)
+			}
+		} else {
+			changesInfo = colors.Gray(" No changes").String()
 		}
+		if suppressed := planResult.SuppressedCounts(); suppressed.Total() > 0 {
+			changesInfo += colors.Sprintf(colors.Gray(" (%s suppressed)"), suppressed)
+		}
+	}
 
-		// Print status line
-		_, err := fmt.Fprintf(out, "%s: %s%s%s\n",
-			result.ID(),
-			resultType,
-			changesInfo,
-			runtimeInfo,
-		)
-		if err != nil {
+	// If this has sub-results, e.g. one per workspace, show how many
+	if subResults := result.SubResults(); subResults != nil {
+		changesInfo = colors.Sprintf(colors.Gray(" (%d workspaces)"), len(subResults))
+	}
+
+	// If the cost of the plan was estimated, show how much it changes
+	if estimate := result.Cost(); estimate != nil {
+		changesInfo += colors.Sprintf(colors.Brown(" (%s)"), cost.FormatDelta(estimate.Delta(), estimate.Currency))
+	}
+
+	// If the risk of the plan was assessed, show its score
+	if assessment := result.Risk(); assessment != nil {
+		changesInfo += colors.Sprintf(colors.Brown(" (risk %d)"), assessment.Score)
+	}
+
+	if terraformResult != nil {
+		runtimeInfo = colors.Sprintf(colors.Gray(" (%s)"), terraformResult.Runtime())
+	}
+
+	// Print status line
+	_, err := fmt.Fprintf(out, "%s: %s%s%s\n",
+		result.ID(),
+		resultType,
+		changesInfo,
+		runtimeInfo,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, warning := range result.Warnings() {
+		if err := cli.printWarning("%s", warning); err != nil {
 			return err
 		}
+	}
 
-		// If this was a plan, print the plan
-		if planResult != nil && planResult.HasChanges() {
-			planOutput := planResult.Changes()
-			if terraform.CanDisplayReadableTerraformPolicyChanges() {
-				var err error
-				planOutput, err = terraform.ReadableTerraformPolicyChanges(planOutput)
+	// If this was a plan, print the plan
+	if planResult != nil && planResult.HasChanges() {
+		planOutput := planResult.Changes()
+		if terraform.CanDisplayReadableTerraformPolicyChanges() && !cli.flags.rawOutput {
+			var err error
+			planOutput, err = terraform.ReadableTerraformPolicyChanges(planOutput)
+			if err != nil {
+				_, err := fmt.Fprintf(out, "\n%s", err)
 				if err != nil {
-					_, err := fmt.Fprintf(out, "\n%s", err)
-					if err != nil {
-						return err
-					}
+					return err
 				}
 			}
-			_, err := fmt.Fprintf(out, "\n%s", planOutput)
-			if err != nil {
-				return err
-			}
 		}
+		_, err := fmt.Fprintf(out, "\n%s", cli.uncolor(out, planOutput))
+		if err != nil {
+			return err
+		}
+	}
 
-		// If there is a stderr, print it
-		if terraformResult != nil {
-			_, err := fmt.Fprintf(out, terraformResult.Stderr())
+	// If there is a stderr, print it. Errors of sub-results are printed
+	// with the sub-results themselves.
+	if terraformResult != nil {
+		_, err := fmt.Fprint(out, cli.uncolor(out, terraformResult.Stderr()))
+		if err != nil {
+			return err
+		}
+		// The plan itself succeeded, so its stderr does not explain why
+		var violation *astro.PolicyViolationError
+		var riskErr *astro.RiskError
+		if errors.As(result.Err(), &violation) {
+			_, err := fmt.Fprintln(out, violation)
 			if err != nil {
 				return err
 			}
-		} else if result.Err() != nil {
-			_, err := fmt.Fprintln(out, result.Err())
+		} else if errors.As(result.Err(), &riskErr) {
+			_, err := fmt.Fprintln(out, riskErr)
 			if err != nil {
 				return err
 			}
 		}
+	} else if result.Err() != nil && result.SubResults() == nil && blocked == nil {
+		_, err := fmt.Fprintln(out, result.Err())
+		if err != nil {
+			return err
+		}
+	}
+
+	// Point on-call engineers at what to do about the failure
+	if guidance := result.Guidance(); guidance != "" {
+		_, err := fmt.Fprintf(out, "\n%s\n", colors.Brown(guidance))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// printWarning prints a warning to stderr. Warnings don't stop the
+// command.
+func (cli *AstroCLI) printWarning(format string, args ...interface{}) error {
+	_, err := fmt.Fprintf(cli.stderr, "%s %s\n", cli.colors(cli.stderr).Brown("WARNING:"), fmt.Sprintf(format, args...))
+	return err
+}
+
+// printRunSummary prints how many executions succeeded, changed, failed
+// and were skipped, and the slowest ones, so that there is a recap of runs
+// whose results scrolled away. A single execution needs none.
+func (cli *AstroCLI) printRunSummary(summary astro.RunSummary) error {
+	if summary.Executions < 2 {
+		return nil
+	}
+
+	_, err := fmt.Fprintf(cli.stdout, "\nRan %d executions in %s: %d ok, %d changed, %d failed, %d skipped\n",
+		summary.Executions,
+		summary.WallTime.Round(time.Second),
+		summary.OK,
+		summary.Changed,
+		summary.Failed,
+		summary.Skipped,
+	)
+	if err != nil {
+		return err
+	}
+
+	if len(summary.Slowest) < 2 {
+		return nil
+	}
+
+	if _, err := fmt.Fprintln(cli.stdout, "Slowest executions:"); err != nil {
+		return err
+	}
+	for _, execution := range summary.Slowest {
+		_, err := fmt.Fprintf(cli.stdout, "  %s: %s\n", execution.ID, execution.Duration)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// printChangeSummary prints the total number of resource changes across
+// all plans, along with the executions with the most changes.
+func (cli *AstroCLI) printChangeSummary(results []*astro.Result) error {
+	totals, changed, top := changeStats(results)
+	if totals.Total() == 0 {
+		return nil
+	}
+
+	_, err := fmt.Fprintf(cli.stdout, "\nTotal changes: %s across %d executions\n", formatChangeCounts(totals), changed)
+	if err != nil {
+		return err
+	}
+
+	if len(top) < 2 {
+		return nil
 	}
 
-	return errors
+	if _, err := fmt.Fprintln(cli.stdout, "Most changes:"); err != nil {
+		return err
+	}
+	for _, result := range top {
+		_, err := fmt.Fprintf(cli.stdout, "  %s: %s\n", result.ID(), formatChangeCounts(result.ChangeCounts()))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// printCostSummary prints the total estimated change in monthly cost
+// across all plans whose cost was estimated.
+func (cli *AstroCLI) printCostSummary(results []*astro.Result) error {
+	var total cost.Estimate
+	estimated := 0
+	for _, result := range flattenResults(results) {
+		if estimate := result.Cost(); estimate != nil {
+			total = total.Plus(*estimate)
+			estimated++
+		}
+	}
+	if estimated == 0 {
+		return nil
+	}
+
+	_, err := fmt.Fprintf(cli.stdout, "\nMonthly cost: %s across %d executions (%.2f to %.2f %s)\n",
+		cost.FormatDelta(total.Delta(), total.Currency),
+		estimated,
+		total.PastMonthly,
+		total.Monthly,
+		total.Currency,
+	)
+	return err
+}
+
+// formatChangeCounts returns a short summary of change counts, e.g.
+// "+3 ~1 -0".
+func formatChangeCounts(counts terraform.ChangeCounts) string {
+	return counts.String()
 }
@@ -0,0 +1,61 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/uber/astro/astro"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintRunSummary(t *testing.T) {
+	out := &bytes.Buffer{}
+	cli := &AstroCLI{stdout: out}
+
+	require.NoError(t, cli.printRunSummary(astro.RunSummary{
+		Executions: 4,
+		OK:         1,
+		Changed:    1,
+		Failed:     1,
+		Skipped:    1,
+		Slowest: []astro.ExecutionDuration{
+			{ID: "app-prod", Duration: 72 * time.Second},
+			{ID: "app-dev", Duration: 41 * time.Second},
+		},
+		WallTime: 90*time.Second + 400*time.Millisecond,
+	}))
+
+	assert.Equal(t, `
+Ran 4 executions in 1m30s: 1 ok, 1 changed, 1 failed, 1 skipped
+Slowest executions:
+  app-prod: 1m12s
+  app-dev: 41s
+`, out.String())
+}
+
+func TestPrintRunSummarySingleExecution(t *testing.T) {
+	out := &bytes.Buffer{}
+	cli := &AstroCLI{stdout: out}
+
+	require.NoError(t, cli.printRunSummary(astro.RunSummary{Executions: 1, OK: 1}))
+	assert.Empty(t, out.String())
+}
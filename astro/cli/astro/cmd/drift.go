@@ -0,0 +1,69 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/uber/astro/astro"
+	"github.com/uber/astro/astro/terraform"
+)
+
+// driftError is returned by plan --detect-drift when the infrastructure
+// of some executions was changed outside of Terraform.
+type driftError struct {
+	drifted int
+}
+
+// Error is the error message, so this satisfies the error interface.
+func (e *driftError) Error() string {
+	return fmt.Sprintf("done; drift detected in %d executions", e.drifted)
+}
+
+// driftedExecutions returns the IDs of the executions whose plans have
+// changes, which are the executions that drifted when detecting drift.
+func driftedExecutions(results []*astro.Result) []string {
+	var drifted []string
+	for _, result := range flattenResults(results) {
+		if planResult, ok := result.TerraformResult().(*terraform.PlanResult); ok && planResult != nil && planResult.HasChanges() {
+			drifted = append(drifted, result.ID())
+		}
+	}
+	sort.Strings(drifted)
+	return drifted
+}
+
+// printDriftSummary prints which executions have drifted from their
+// state.
+func (cli *AstroCLI) printDriftSummary(results []*astro.Result) error {
+	drifted := driftedExecutions(results)
+	if len(drifted) == 0 {
+		_, err := fmt.Fprintln(cli.stdout, "\nNo drift detected")
+		return err
+	}
+
+	if _, err := fmt.Fprintf(cli.stdout, "\nDrift detected in %d executions:\n", len(drifted)); err != nil {
+		return err
+	}
+	for _, id := range drifted {
+		if _, err := fmt.Fprintf(cli.stdout, "  %s\n", id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/uber/astro/astro"
+)
+
+// printDryRun prints the executions of a dry run by step, with the
+// Terraform commands they would run.
+func printDryRun(w io.Writer, executions []*astro.DryRunExecution) error {
+	if len(executions) == 0 {
+		_, err := fmt.Fprintln(w, "No executions would run")
+		return err
+	}
+
+	step := 0
+	for _, e := range executions {
+		if e.Step != step {
+			step = e.Step
+			fmt.Fprintf(w, "Step %d:\n", step)
+		}
+
+		fmt.Fprintf(w, "  %s\n", e.ID)
+		fmt.Fprintf(w, "    Module:     %s\n", e.Module)
+		fmt.Fprintf(w, "    Directory:  %s\n", e.Dir)
+		fmt.Fprintf(w, "    Terraform:  %s\n", orDash(e.TerraformVersion))
+		fmt.Fprintf(w, "    Depends on: %s\n", orDash(strings.Join(e.Dependencies, ", ")))
+
+		fmt.Fprintf(w, "    Variables:  %s\n", orDash(formatVariables(e.Variables)))
+
+		fmt.Fprintln(w, "    Commands:")
+		for _, invocation := range e.Commands {
+			fmt.Fprintf(w, "      %s\n", invocation)
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "\n%d executions would run; nothing was run\n", len(executions))
+	return err
+}
@@ -0,0 +1,70 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/uber/astro/astro"
+	"github.com/uber/astro/astro/conf"
+)
+
+// exitCodeError is returned by commands whose results exit with the code
+// configured for their category, rather than 1.
+type exitCodeError struct {
+	err  error
+	code int
+}
+
+// Error is the error message, so this satisfies the error interface.
+func (e *exitCodeError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the error the command failed with.
+func (e *exitCodeError) Unwrap() error {
+	return e.err
+}
+
+// exitWith returns err with the exit code of a category of results.
+func (cli *AstroCLI) exitWith(category string, err error) error {
+	return &exitCodeError{err: err, code: cli.config.ExitCodes.Code(category)}
+}
+
+// resultsExitError returns the error that a command whose executions all
+// succeeded exits with, if the results have plans with changes or skipped
+// executions, whichever comes first, and its exit code is not 0.
+func (cli *AstroCLI) resultsExitError(results []*astro.Result) error {
+	if changed := driftedExecutions(results); len(changed) > 0 {
+		if cli.config.ExitCodes.Code(conf.ResultsChanged) == 0 {
+			return nil
+		}
+		return cli.exitWith(conf.ResultsChanged, fmt.Errorf("done; %d executions have changes", len(changed)))
+	}
+
+	skipped := 0
+	for _, result := range flattenResults(results) {
+		if result.SkipReason() != "" {
+			skipped++
+		}
+	}
+	if skipped > 0 && cli.config.ExitCodes.Code(conf.ResultsSkipped) != 0 {
+		return cli.exitWith(conf.ResultsSkipped, fmt.Errorf("done; %d executions were skipped", skipped))
+	}
+
+	return nil
+}
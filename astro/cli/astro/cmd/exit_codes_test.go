@@ -0,0 +1,36 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/uber/astro/astro/tests"
+)
+
+func TestExitCodes(t *testing.T) {
+	// the mock Terraform always has changes
+	result := tests.RunTest(t, []string{"plan"}, "fixtures/exit-codes", tests.VersionLatest)
+	assert.Contains(t, result.Stderr.String(), "done; 1 executions have changes")
+	assert.Equal(t, 3, result.ExitCode)
+
+	result = tests.RunTest(t, []string{"plan", "--detect-drift"}, "fixtures/exit-codes", tests.VersionLatest)
+	assert.Contains(t, result.Stderr.String(), "done; drift detected in 1 executions")
+	assert.Equal(t, 0, result.ExitCode)
+}
@@ -0,0 +1,143 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/uber/astro/astro"
+
+	"github.com/spf13/cobra"
+)
+
+func (cli *AstroCLI) createExplainCmd() {
+	explainCmd := &cobra.Command{
+		Use:                   "explain [flags] <execution>",
+		DisableFlagsInUseLine: true,
+		Short:                 "Explain how an execution is derived from the config",
+		Args:                  cobra.ExactArgs(1),
+		PersistentPreRunE:     cli.preRun,
+		RunE:                  cli.runExplain,
+	}
+
+	cli.commands.explain = explainCmd
+}
+
+func (cli *AstroCLI) runExplain(cmd *cobra.Command, args []string) error {
+	explanation, err := cli.project.Explain(astro.ExecutionParameters{
+		UserVars: flagsToUserVariables(cli.flags.projectFlags),
+	}, args[0])
+	if err != nil {
+		return fmt.Errorf("ERROR: %v", cli.processError(err))
+	}
+
+	cli.writeExplanation(cli.stdout, cmd, explanation)
+
+	return nil
+}
+
+// writeExplanation writes the explanation of the execution, with the
+// flags that set and select it.
+func (cli *AstroCLI) writeExplanation(w io.Writer, cmd *cobra.Command, e *astro.ExecutionExplanation) {
+	fmt.Fprintf(w, "Execution %s\n", e.ID)
+	fmt.Fprintf(w, "  Module:     %s (path: %s)\n", e.Module, e.Path)
+	fmt.Fprintf(w, "  Terraform:  %s (%s)\n", orDash(e.TerraformVersion), orDash(e.TerraformPath))
+
+	fmt.Fprintln(w, "\nVariables:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, variable := range e.Variables {
+		fmt.Fprintf(tw, "  %s\t= %s\t(%s)\n", variable.Name, variable.Value, cli.variableSource(cmd, variable))
+	}
+	if len(e.Variables) == 0 {
+		fmt.Fprintln(tw, "  -")
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\nBackend: %s\n", orDash(e.Backend))
+	if len(e.Missing) > 0 {
+		fmt.Fprintf(w, "  (not templated, as %s missing)\n", strings.Join(cli.varsToFlagNames(e.Missing), ", "))
+	}
+	var keys []string
+	for key := range e.BackendConfig {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, key := range keys {
+		fmt.Fprintf(tw, "  %s\t= %s\n", key, e.BackendConfig[key])
+	}
+	tw.Flush()
+
+	var overrides []string
+	for _, i := range e.Overrides {
+		overrides = append(overrides, fmt.Sprintf("overrides[%d] (when %s)", i, formatVariables(cli.config.Overrides[i].When)))
+	}
+	fmt.Fprintf(w, "\nOverrides:      %s\n", orDash(strings.Join(overrides, ", ")))
+	fmt.Fprintf(w, "Depends on:     %s\n", orDash(strings.Join(e.Dependencies, ", ")))
+	fmt.Fprintf(w, "Depended on by: %s\n", orDash(strings.Join(e.Dependents, ", ")))
+
+	// Filter variables select the execution, and the others are needed
+	// to run it
+	var selectors, runFlags []string
+	for _, variable := range e.Variables {
+		flag := fmt.Sprintf("--%s %s", cli.flagName(variable.Name), variable.Value)
+		switch {
+		case variable.Filter:
+			selectors = append(selectors, flag)
+			runFlags = append(runFlags, flag)
+		case variable.Source == astro.VariableSourceUser || variable.Source == astro.VariableSourceExecutionID:
+			runFlags = append(runFlags, flag)
+		case variable.Source == "":
+			runFlags = append(runFlags, fmt.Sprintf("--%s <value>", cli.flagName(variable.Name)))
+		}
+	}
+	selectors = append(selectors, "--modules "+e.Module, e.ID+" as an argument")
+	fmt.Fprintf(w, "\nSelected by: %s\n", strings.Join(selectors, ", "))
+	// the ID is only known once the missing variables are passed
+	if len(e.Missing) == 0 {
+		runFlags = append(runFlags, e.ID)
+	}
+	fmt.Fprintf(w, "Plan it with: astro plan %s\n", strings.Join(runFlags, " "))
+}
+
+// variableSource describes where the value of the variable came from.
+func (cli *AstroCLI) variableSource(cmd *cobra.Command, variable astro.ExplainedVariable) string {
+	flag := "--" + cli.flagName(variable.Name)
+
+	switch variable.Source {
+	case astro.VariableSourceValues:
+		return "one of the values of the module"
+	case astro.VariableSourceForEach:
+		return "foreach item"
+	case astro.VariableSourceUser:
+		if f := cmd.Flags().Lookup(cli.flagName(variable.Name)); cli.flags.profile != "" && (f == nil || !f.Changed) {
+			return "profile " + cli.flags.profile
+		}
+		return flag
+	case astro.VariableSourceExecutionID:
+		return "read from the execution ID; pass " + flag
+	case astro.VariableSourceDefault:
+		return "default"
+	case astro.VariableSourceComputed:
+		return "computed"
+	}
+	return "missing; pass " + flag
+}
@@ -17,13 +17,16 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
 
 	"github.com/uber/astro/astro"
 	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/utils"
 
+	"github.com/hashicorp/go-multierror"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
@@ -44,12 +47,13 @@ type projectFlag struct {
 	AllowedValues []string
 }
 
-// AddToFlagSet adds the flag to the specified flag set.
+// AddToFlagSet adds the flag to the specified flag set. Its current value,
+// e.g. from a profile, is the default.
 func (flag *projectFlag) AddToFlagSet(flags *pflag.FlagSet) {
 	if len(flag.AllowedValues) > 0 {
 		flags.Var(&stringEnum{flag: flag}, flag.Name, flag.Description)
 	} else {
-		flags.StringVar(&flag.Value, flag.Name, "", flag.Description)
+		flags.StringVar(&flag.Value, flag.Name, flag.Value, flag.Description)
 	}
 }
 
@@ -73,6 +77,9 @@ func (s *stringEnum) Set(value string) error {
 			return nil
 		}
 	}
+	if suggestions := utils.ClosestMatches(value, s.flag.AllowedValues); suggestions != nil {
+		return fmt.Errorf("did you mean %s? allowed values: %s", strings.Join(suggestions, " or "), strings.Join(s.flag.AllowedValues, ", "))
+	}
 	return fmt.Errorf("allowed values: %s", strings.Join(s.flag.AllowedValues, ", "))
 }
 
@@ -125,6 +132,11 @@ func flagsFromConfig(config *conf.Project) (flags []*projectFlag) {
 
 	for _, moduleConf := range config.Modules {
 		for _, variableConf := range moduleConf.Variables {
+			// computed variables can't be passed
+			if variableConf.IsComputed() {
+				continue
+			}
+
 			var flagName string
 			var flagConf conf.Flag
 
@@ -208,6 +220,57 @@ func (cli *AstroCLI) varsToFlagNames(variableNames []string) (flagNames []string
 	return flagNames
 }
 
+// noExecutionsMatchedError rewrites the error using flag names instead of
+// variable names.
+func (cli *AstroCLI) noExecutionsMatchedError(err *astro.NoExecutionsMatchedError) error {
+	var names []string
+	for name := range err.Filters() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var filters, suggestions []string
+	for _, name := range names {
+		flag := fmt.Sprintf("--%s", cli.flagName(name))
+		filters = append(filters, fmt.Sprintf("%s %s", flag, err.Filters()[name]))
+		for _, suggestion := range err.Suggestions()[name] {
+			suggestions = append(suggestions, fmt.Sprintf("%s %s", flag, suggestion))
+		}
+	}
+
+	msg := fmt.Sprintf("no executions matched %s", strings.Join(filters, " "))
+	if len(suggestions) > 0 {
+		msg += fmt.Sprintf("; did you mean %s?", strings.Join(suggestions, " or "))
+	}
+
+	return errors.New(msg)
+}
+
+// invalidValueErrors rewrites the errors for invalid variable values
+// using flag names instead of variable names. err is an
+// *conf.InvalidValueError, or several of them.
+func (cli *AstroCLI) invalidValueErrors(err error) error {
+	var invalidErrs []error
+	var multi *multierror.Error
+	if errors.As(err, &multi) {
+		invalidErrs = multi.Errors
+	} else {
+		invalidErrs = []error{err}
+	}
+
+	var messages []string
+	for _, err := range invalidErrs {
+		var invalidErr *conf.InvalidValueError
+		if !errors.As(err, &invalidErr) {
+			messages = append(messages, err.Error())
+			continue
+		}
+		messages = append(messages, fmt.Sprintf("invalid value %q for --%s; %s", invalidErr.Value, cli.flagName(invalidErr.Variable), invalidErr.Constraint()))
+	}
+
+	return errors.New(strings.Join(messages, "\n"))
+}
+
 func uniqueStrings(strings []string) []string {
 	sort.Strings(strings)
 	pos := 0
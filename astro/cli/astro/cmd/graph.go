@@ -0,0 +1,125 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/uber/astro/astro"
+
+	"github.com/spf13/cobra"
+)
+
+// graphFormats are the formats the execution graph can be printed in.
+var graphFormats = map[string]func(io.Writer, map[string][]string){
+	"dot":     writeDOTGraph,
+	"mermaid": writeMermaidGraph,
+}
+
+func (cli *AstroCLI) createGraphCmd() {
+	graphCmd := &cobra.Command{
+		Use:                   "graph [flags]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Print the dependency graph of executions",
+		PersistentPreRunE:     cli.preRun,
+		RunE:                  cli.runGraph,
+	}
+
+	graphCmd.PersistentFlags().StringVar(&cli.flags.graphFormat, "format", "dot", "output format: dot or mermaid")
+
+	cli.commands.graph = graphCmd
+}
+
+func (cli *AstroCLI) runGraph(*cobra.Command, []string) error {
+	write, ok := graphFormats[cli.flags.graphFormat]
+	if !ok {
+		return fmt.Errorf("ERROR: unknown graph format %q; allowed values: dot, mermaid", cli.flags.graphFormat)
+	}
+
+	deps, err := cli.project.Dependencies(astro.ExecutionParameters{
+		UserVars: flagsToUserVariables(cli.flags.projectFlags),
+	})
+	if err != nil {
+		return fmt.Errorf("ERROR: %v", cli.processError(err))
+	}
+
+	write(cli.stdout, deps)
+
+	return nil
+}
+
+// sortedIDs returns the IDs of all executions in the graph in order,
+// including dependencies that were filtered out.
+func sortedIDs(deps map[string][]string) []string {
+	seen := map[string]bool{}
+	var ids []string
+	for id, depIDs := range deps {
+		for _, id := range append([]string{id}, depIDs...) {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// writeDOTGraph writes the graph in Graphviz DOT format. Edges point from
+// an execution to the executions that depend on it, in the order they
+// run. Dependencies that were filtered out are dashed.
+func writeDOTGraph(w io.Writer, deps map[string][]string) {
+	fmt.Fprintln(w, "digraph astro {")
+	fmt.Fprintln(w, "  rankdir = \"LR\";")
+	for _, id := range sortedIDs(deps) {
+		if _, ok := deps[id]; !ok {
+			fmt.Fprintf(w, "  %q [style = \"dashed\"];\n", id)
+			continue
+		}
+		fmt.Fprintf(w, "  %q;\n", id)
+		for _, dep := range deps[id] {
+			fmt.Fprintf(w, "  %q -> %q;\n", dep, id)
+		}
+	}
+	fmt.Fprintln(w, "}")
+}
+
+// writeMermaidGraph writes the graph as a Mermaid flowchart, with the
+// same edges as writeDOTGraph.
+func writeMermaidGraph(w io.Writer, deps map[string][]string) {
+	ids := sortedIDs(deps)
+
+	// Mermaid node IDs cannot contain all the characters execution IDs
+	// can, so nodes are numbered and labelled with the execution ID.
+	nodes := map[string]string{}
+	for i, id := range ids {
+		nodes[id] = fmt.Sprintf("n%d", i)
+	}
+
+	fmt.Fprintln(w, "graph LR")
+	for _, id := range ids {
+		fmt.Fprintf(w, "  %s[\"%s\"]\n", nodes[id], strings.ReplaceAll(id, "\"", "#quot;"))
+	}
+	for _, id := range ids {
+		for _, dep := range deps[id] {
+			fmt.Fprintf(w, "  %s --> %s\n", nodes[dep], nodes[id])
+		}
+	}
+}
@@ -0,0 +1,62 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testDeps = map[string][]string{
+	"app-dev":      {"database-dev", "network-dev"},
+	"database-dev": {"users"},
+	"network-dev":  {},
+}
+
+func TestWriteDOTGraph(t *testing.T) {
+	out := &bytes.Buffer{}
+	writeDOTGraph(out, testDeps)
+
+	assert.Equal(t, `digraph astro {
+  rankdir = "LR";
+  "app-dev";
+  "database-dev" -> "app-dev";
+  "network-dev" -> "app-dev";
+  "database-dev";
+  "users" -> "database-dev";
+  "network-dev";
+  "users" [style = "dashed"];
+}
+`, out.String())
+}
+
+func TestWriteMermaidGraph(t *testing.T) {
+	out := &bytes.Buffer{}
+	writeMermaidGraph(out, testDeps)
+
+	assert.Equal(t, `graph LR
+  n0["app-dev"]
+  n1["database-dev"]
+  n2["network-dev"]
+  n3["users"]
+  n1 --> n0
+  n2 --> n0
+  n3 --> n1
+`, out.String())
+}
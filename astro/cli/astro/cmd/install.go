@@ -0,0 +1,97 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/uber/astro/astro/tvm"
+)
+
+// installTerraform installs the latest version of the flavor of Terraform
+// that matches the constraint using tvm, after printing what will be
+// downloaded and where. Unless autoInstall is set, the user is asked to
+// confirm first.
+func (cli *AstroCLI) installTerraform(flavor tvm.Flavor, constraint string, autoInstall bool) error {
+	repo, err := tvm.NewVersionRepoForCurrentSystem("")
+	if err != nil {
+		return err
+	}
+	repo = repo.ForFlavor(flavor)
+	name := flavor.Name()
+
+	version, err := repo.LatestAvailable(constraint)
+	if err != nil {
+		return fmt.Errorf("%s was not found, and unable to find a version to install: %v", name, err)
+	}
+
+	fmt.Fprintf(cli.stderr, "%s was not found. astro can install %s %s:\n", name, name, version)
+	fmt.Fprintf(cli.stderr, "  from: %s\n", repo.DownloadURL(version))
+	fmt.Fprintf(cli.stderr, "  to:   %s\n", repo.Path(version))
+
+	if !autoInstall {
+		if !isInteractive(cli.stdin) {
+			return fmt.Errorf("%s was not found; run again with --auto-install to install it", name)
+		}
+
+		install, err := cli.confirm("Install it now?")
+		if err != nil {
+			return err
+		}
+		if !install {
+			return fmt.Errorf("%s was not found; not installing it", name)
+		}
+	}
+
+	if _, err := repo.Get(version); err != nil {
+		return fmt.Errorf("unable to install %s %s: %v", name, version, err)
+	}
+
+	fmt.Fprintf(cli.stderr, "Installed %s %s\n", name, version)
+
+	return nil
+}
+
+// confirm asks the user a yes or no question on stdin. It returns
+// whether they answered yes.
+func (cli *AstroCLI) confirm(question string) (bool, error) {
+	fmt.Fprintf(cli.stderr, "%s [y/N] ", question)
+	answer, err := bufio.NewReader(cli.stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// isInteractive returns whether r is a terminal that a user can answer
+// prompts on.
+func isInteractive(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
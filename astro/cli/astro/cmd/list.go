@@ -0,0 +1,138 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/uber/astro/astro"
+
+	"github.com/spf13/cobra"
+)
+
+// listFormats are the formats executions can be listed in.
+var listFormats = map[string]func(io.Writer, []astro.ExecutionSummary) error{
+	"table": writeExecutionTable,
+	"json":  writeExecutionJSON,
+}
+
+// jsonExecutionList is the output of astro list --format json.
+type jsonExecutionList struct {
+	Executions []jsonExecution `json:"executions"`
+}
+
+type jsonExecution struct {
+	ID           string            `json:"id"`
+	Module       string            `json:"module"`
+	Variables    map[string]string `json:"variables"`
+	Dependencies []string          `json:"dependencies"`
+}
+
+func (cli *AstroCLI) createListCmd() {
+	listCmd := &cobra.Command{
+		Use:                   "list [flags]",
+		DisableFlagsInUseLine: true,
+		Short:                 "List the modules, their executions and what they depend on",
+		Args:                  cobra.NoArgs,
+		PersistentPreRunE:     cli.preRun,
+		RunE:                  cli.runList,
+	}
+
+	listCmd.PersistentFlags().StringVar(&cli.flags.listFormat, "format", "table", "output format: table or json")
+	listCmd.PersistentFlags().StringVar(&cli.flags.moduleNamesString, "modules", "", "list of modules to list")
+
+	cli.commands.list = listCmd
+}
+
+func (cli *AstroCLI) runList(*cobra.Command, []string) error {
+	write, ok := listFormats[cli.flags.listFormat]
+	if !ok {
+		return fmt.Errorf("ERROR: unknown list format %q; allowed values: table, json", cli.flags.listFormat)
+	}
+
+	var moduleNames []string
+	if cli.flags.moduleNamesString != "" {
+		moduleNames = strings.Split(cli.flags.moduleNamesString, ",")
+	}
+
+	executions, err := cli.project.ListExecutions(astro.ExecutionParameters{
+		ModuleNames: moduleNames,
+		UserVars:    flagsToUserVariables(cli.flags.projectFlags),
+	})
+	if err != nil {
+		return fmt.Errorf("ERROR: %v", cli.processError(err))
+	}
+
+	return write(cli.stdout, executions)
+}
+
+// writeExecutionTable writes the executions as a table, one row per
+// execution.
+func writeExecutionTable(w io.Writer, executions []astro.ExecutionSummary) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MODULE\tEXECUTION\tVARIABLES\tDEPENDS ON")
+	for _, e := range executions {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n",
+			e.Module,
+			e.ID,
+			orDash(formatVariables(e.Variables)),
+			orDash(strings.Join(e.Dependencies, ", ")),
+		)
+	}
+	return tw.Flush()
+}
+
+// writeExecutionJSON writes the executions as a JSON document.
+func writeExecutionJSON(w io.Writer, executions []astro.ExecutionSummary) error {
+	list := jsonExecutionList{Executions: []jsonExecution{}}
+	for _, e := range executions {
+		list.Executions = append(list.Executions, jsonExecution{
+			ID:           e.ID,
+			Module:       e.Module,
+			Variables:    e.Variables,
+			Dependencies: e.Dependencies,
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(list)
+}
+
+// formatVariables returns the variables as key=value pairs, sorted by key.
+func formatVariables(variables map[string]string) string {
+	var pairs []string
+	for key, val := range variables {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, val))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
+
+// orDash returns s, or "-" if it is empty, so that table cells are never
+// blank.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
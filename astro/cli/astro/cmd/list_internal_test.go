@@ -0,0 +1,69 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/uber/astro/astro"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testExecutions = []astro.ExecutionSummary{
+	{
+		ID:           "app-{aws_region}-dev",
+		Module:       "app",
+		Variables:    map[string]string{"environment": "dev", "aws_region": "{aws_region}"},
+		Dependencies: []string{"users"},
+	},
+	{
+		ID:           "users",
+		Module:       "users",
+		Variables:    map[string]string{},
+		Dependencies: []string{},
+	},
+}
+
+func TestWriteExecutionTable(t *testing.T) {
+	out := &bytes.Buffer{}
+	require.NoError(t, writeExecutionTable(out, testExecutions))
+
+	assert.Equal(t, `MODULE  EXECUTION             VARIABLES                                DEPENDS ON
+app     app-{aws_region}-dev  aws_region={aws_region} environment=dev  users
+users   users                 -                                        -
+`, out.String())
+}
+
+func TestWriteExecutionJSON(t *testing.T) {
+	out := &bytes.Buffer{}
+	require.NoError(t, writeExecutionJSON(out, testExecutions[1:]))
+
+	assert.Equal(t, `{
+  "executions": [
+    {
+      "id": "users",
+      "module": "users",
+      "variables": {},
+      "dependencies": []
+    }
+  ]
+}
+`, out.String())
+}
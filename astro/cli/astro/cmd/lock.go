@@ -0,0 +1,59 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func (cli *AstroCLI) createLockCmd() {
+	lockCmd := &cobra.Command{
+		Use:                   "lock",
+		DisableFlagsInUseLine: true,
+		Short:                 "Update the lock file with hashes of module sources",
+		PersistentPreRunE:     cli.preRun,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := cli.project.UpdateLock(); err != nil {
+				return fmt.Errorf("ERROR: %v", err)
+			}
+
+			_, err := fmt.Fprintln(cli.stdout, "Done")
+			return err
+		},
+	}
+	cli.commands.lock = lockCmd
+}
+
+func (cli *AstroCLI) createForceUnlockCmd() {
+	forceUnlockCmd := &cobra.Command{
+		Use:                   "force-unlock",
+		DisableFlagsInUseLine: true,
+		Short:                 "Release the run lock of an apply or destroy that is no longer running",
+		PersistentPreRunE:     cli.preRun,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := cli.project.ForceUnlock(); err != nil {
+				return fmt.Errorf("ERROR: %v", err)
+			}
+
+			_, err := fmt.Fprintln(cli.stdout, "Done")
+			return err
+		},
+	}
+	cli.commands.forceUnlock = forceUnlockCmd
+}
@@ -0,0 +1,60 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/uber/astro/astro/logger"
+)
+
+// configureLogging sets up logging from the log flags. They are read
+// before the config is loaded, so that loading it is logged too. It
+// returns a function that closes the log file, if there is one.
+func (cli *AstroCLI) configureLogging(flags earlyFlags) (closeLog func(), err error) {
+	if err := logger.ValidateFormat(flags.logFormat); err != nil {
+		return nil, err
+	}
+
+	// Without --log-level, nothing is logged to stderr unless -vvv is
+	// given, and everything is logged to the log file.
+	fileLevel := logger.TraceLevel
+	if flags.logLevel != "" {
+		level, err := logger.ParseLevel(flags.logLevel)
+		if err != nil {
+			return nil, err
+		}
+		logger.SetOutput(cli.stderr, level, flags.logFormat)
+		fileLevel = level
+	}
+
+	if flags.logFile == "" {
+		return func() {}, nil
+	}
+
+	f, err := os.OpenFile(flags.logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open log file: %v", err)
+	}
+	remove := logger.AddSink(f, fileLevel, flags.logFormat)
+
+	return func() {
+		remove()
+		f.Close()
+	}, nil
+}
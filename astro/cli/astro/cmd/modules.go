@@ -0,0 +1,164 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/uber/astro/astro"
+
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+)
+
+// moduleListFormats are the formats modules can be listed in.
+var moduleListFormats = map[string]func(io.Writer, []astro.ModuleSummary) error{
+	"table": writeModuleTable,
+	"json":  writeModuleJSON,
+	"yaml":  writeModuleYAML,
+}
+
+// jsonModuleList is the output of astro modules list --format json or
+// yaml.
+type jsonModuleList struct {
+	Modules []jsonModule `json:"modules"`
+}
+
+type jsonModule struct {
+	Name         string            `json:"name"`
+	Path         string            `json:"path"`
+	Variables    []jsonVariable    `json:"variables"`
+	Labels       map[string]string `json:"labels"`
+	Owners       []string          `json:"owners"`
+	Executions   int               `json:"executions"`
+	Dependencies []string          `json:"dependencies"`
+	Dependents   []string          `json:"dependents"`
+}
+
+type jsonVariable struct {
+	Name     string   `json:"name"`
+	Values   []string `json:"values,omitempty"`
+	Computed string   `json:"computed,omitempty"`
+	Secret   bool     `json:"secret,omitempty"`
+}
+
+func (cli *AstroCLI) createModulesCmd() {
+	modulesCmd := &cobra.Command{
+		Use:                   "modules",
+		DisableFlagsInUseLine: true,
+		Short:                 "Work with the modules of the project",
+	}
+
+	listCmd := &cobra.Command{
+		Use:               "list",
+		Short:             "List the modules, with their variables, labels, owners and dependencies",
+		Args:              cobra.NoArgs,
+		PersistentPreRunE: cli.preRun,
+		RunE:              cli.runModulesList,
+	}
+	listCmd.Flags().StringVar(&cli.flags.listFormat, "format", "table", "output format: table, json or yaml")
+
+	modulesCmd.AddCommand(listCmd)
+
+	cli.commands.modules = modulesCmd
+}
+
+func (cli *AstroCLI) runModulesList(*cobra.Command, []string) error {
+	write, ok := moduleListFormats[cli.flags.listFormat]
+	if !ok {
+		return fmt.Errorf("ERROR: unknown list format %q; allowed values: table, json, yaml", cli.flags.listFormat)
+	}
+
+	return write(cli.stdout, cli.project.ListModules())
+}
+
+// writeModuleTable writes the modules as a table, one row per module.
+func writeModuleTable(w io.Writer, modules []astro.ModuleSummary) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MODULE\tPATH\tVARIABLES\tLABELS\tOWNERS\tEXECUTIONS\tDEPS\tDEPENDENTS")
+	for _, m := range modules {
+		var variables []string
+		for _, variable := range m.Variables {
+			if len(variable.Values) > 0 {
+				variables = append(variables, fmt.Sprintf("%s=%s", variable.Name, strings.Join(variable.Values, "|")))
+			} else {
+				variables = append(variables, variable.Name)
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%d\t%d\n",
+			m.Name,
+			m.Path,
+			orDash(strings.Join(variables, " ")),
+			orDash(formatVariables(m.Labels)),
+			orDash(strings.Join(m.Owners, ", ")),
+			m.Executions,
+			len(m.Dependencies),
+			len(m.Dependents),
+		)
+	}
+	return tw.Flush()
+}
+
+// moduleList converts the modules to the document that is written as
+// JSON or YAML.
+func moduleList(modules []astro.ModuleSummary) jsonModuleList {
+	list := jsonModuleList{Modules: []jsonModule{}}
+	for _, m := range modules {
+		module := jsonModule{
+			Name:         m.Name,
+			Path:         m.Path,
+			Variables:    []jsonVariable{},
+			Labels:       m.Labels,
+			Owners:       m.Owners,
+			Executions:   m.Executions,
+			Dependencies: m.Dependencies,
+			Dependents:   m.Dependents,
+		}
+		for _, variable := range m.Variables {
+			module.Variables = append(module.Variables, jsonVariable{Name: variable.Name, Values: variable.Values, Computed: variable.Computed, Secret: variable.Secret})
+		}
+		if module.Labels == nil {
+			module.Labels = map[string]string{}
+		}
+		if module.Owners == nil {
+			module.Owners = []string{}
+		}
+		list.Modules = append(list.Modules, module)
+	}
+	return list
+}
+
+// writeModuleJSON writes the modules as a JSON document.
+func writeModuleJSON(w io.Writer, modules []astro.ModuleSummary) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(moduleList(modules))
+}
+
+// writeModuleYAML writes the modules as a YAML document.
+func writeModuleYAML(w io.Writer, modules []astro.ModuleSummary) error {
+	b, err := yaml.Marshal(moduleList(modules))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
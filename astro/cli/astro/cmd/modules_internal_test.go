@@ -0,0 +1,108 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/uber/astro/astro"
+	"github.com/uber/astro/astro/conf"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testModules = []astro.ModuleSummary{
+	{
+		Name: "app",
+		Path: "app",
+		Variables: []conf.Variable{
+			{Name: "environment", Values: []string{"dev", "prod"}},
+			{Name: "aws_region"},
+		},
+		Labels:       map[string]string{"team": "payments"},
+		Owners:       []string{"payments"},
+		Executions:   2,
+		Dependencies: []string{"users"},
+		Dependents:   []string{},
+	},
+	{
+		Name:         "users",
+		Path:         "users",
+		Executions:   1,
+		Dependencies: []string{},
+		Dependents:   []string{"app"},
+	},
+}
+
+func TestWriteModuleTable(t *testing.T) {
+	out := &bytes.Buffer{}
+	require.NoError(t, writeModuleTable(out, testModules))
+
+	assert.Equal(t, `MODULE  PATH   VARIABLES                        LABELS         OWNERS    EXECUTIONS  DEPS  DEPENDENTS
+app     app    environment=dev|prod aws_region  team=payments  payments  2           1     0
+users   users  -                                -              -         1           0     1
+`, out.String())
+}
+
+func TestWriteModuleJSON(t *testing.T) {
+	out := &bytes.Buffer{}
+	require.NoError(t, writeModuleJSON(out, testModules[1:]))
+
+	assert.Equal(t, `{
+  "modules": [
+    {
+      "name": "users",
+      "path": "users",
+      "variables": [],
+      "labels": {},
+      "owners": [],
+      "executions": 1,
+      "dependencies": [],
+      "dependents": [
+        "app"
+      ]
+    }
+  ]
+}
+`, out.String())
+}
+
+func TestWriteModuleYAML(t *testing.T) {
+	out := &bytes.Buffer{}
+	require.NoError(t, writeModuleYAML(out, testModules[:1]))
+
+	assert.Equal(t, `modules:
+- dependencies:
+  - users
+  dependents: []
+  executions: 2
+  labels:
+    team: payments
+  name: app
+  owners:
+  - payments
+  path: app
+  variables:
+  - name: environment
+    values:
+    - dev
+    - prod
+  - name: aws_region
+`, out.String())
+}
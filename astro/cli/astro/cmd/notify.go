@@ -0,0 +1,109 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cmd
+
+import (
+	"context"
+
+	"github.com/uber/astro/astro"
+	"github.com/uber/astro/astro/notify"
+	"github.com/uber/astro/astro/terraform"
+)
+
+// notify sends a summary of the results of the command to the configured
+// notifications. Failures are printed as warnings, as the command itself
+// has already completed.
+func (cli *AstroCLI) notify(command string, results []*astro.Result) {
+	if cli.config == nil || cli.config.Notifications == nil {
+		return
+	}
+
+	summary := newNotifySummary(command, results)
+	if sessionID, err := cli.project.SessionID(); err == nil {
+		summary.Session = sessionID
+	}
+
+	for _, notification := range cli.config.Notifications {
+		if !notification.Notifies(command) {
+			continue
+		}
+		if err := notify.Send(context.Background(), notification, summary); err != nil {
+			cli.printWarning("unable to send notification: %v", err)
+		}
+	}
+}
+
+// notifyTimeout sends a warning that an execution of the command is
+// running for longer than its warn_after to the notifications of the
+// command. Failures are printed as warnings, and don't stop the command.
+func (cli *AstroCLI) notifyTimeout(command string, warning astro.TimeoutWarning) {
+	if cli.config == nil || cli.config.Notifications == nil {
+		return
+	}
+
+	for _, notification := range cli.config.Notifications {
+		if !notification.Notifies(command) {
+			continue
+		}
+		err := notify.SendWarning(context.Background(), notification, notify.Warning{
+			Event:     "timeout",
+			Command:   command,
+			Session:   warning.Session,
+			Execution: warning.ID,
+			Message:   warning.Message(),
+		})
+		if err != nil {
+			cli.printWarning("unable to send notification: %v", err)
+		}
+	}
+}
+
+// newNotifySummary summarizes the results of the command for
+// notifications.
+func newNotifySummary(command string, results []*astro.Result) notify.Summary {
+	totals, _, _ := changeStats(results)
+
+	summary := notify.Summary{
+		Command:    command,
+		Success:    true,
+		Executions: []notify.Execution{},
+		Totals:     totals,
+	}
+
+	for _, result := range flattenResults(results) {
+		execution := notify.Execution{
+			ID:     result.ID(),
+			Status: notify.StatusOK,
+		}
+		switch {
+		case result.Err() != nil:
+			execution.Status = notify.StatusError
+			execution.Error = result.Err().Error()
+			execution.Guidance = result.Guidance()
+			summary.Success = false
+		case result.SkipReason() != "":
+			execution.Status = notify.StatusSkipped
+			execution.Reason = result.SkipReason()
+		}
+		if planResult, ok := result.TerraformResult().(*terraform.PlanResult); ok && planResult != nil {
+			counts := planResult.ChangeCounts()
+			execution.Changes = &counts
+		}
+		summary.Executions = append(summary.Executions, execution)
+	}
+
+	return summary
+}
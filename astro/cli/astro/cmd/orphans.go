@@ -0,0 +1,153 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func (cli *AstroCLI) createOrphansCmd() {
+	orphansCmd := &cobra.Command{
+		Use:                   "orphans",
+		DisableFlagsInUseLine: true,
+		Short:                 "Find applied executions that the config no longer generates",
+		PersistentPreRunE:     cli.preRun,
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List applied executions that the config no longer generates",
+		Args:  cobra.NoArgs,
+		RunE:  cli.runOrphansList,
+	}
+
+	destroyCmd := &cobra.Command{
+		Use:                   "destroy [flags] [execution...]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Destroy orphaned executions, or all of them if none are given",
+		RunE:                  cli.runOrphansDestroy,
+	}
+	destroyCmd.Flags().IntVar(&cli.flags.parallelism, "parallel", 0, "maximum number of executions to run at the same time")
+	destroyCmd.Flags().BoolVar(&cli.flags.yes, "yes", false, "destroy without asking for confirmation")
+
+	archiveCmd := &cobra.Command{
+		Use:                   "archive [flags] [execution...]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Stop reporting orphaned executions, leaving their infrastructure as it is",
+		RunE:                  cli.runOrphansArchive,
+	}
+	archiveCmd.Flags().BoolVar(&cli.flags.yes, "yes", false, "archive without asking for confirmation")
+
+	orphansCmd.AddCommand(listCmd, destroyCmd, archiveCmd)
+
+	cli.commands.orphans = orphansCmd
+}
+
+func (cli *AstroCLI) runOrphansList(*cobra.Command, []string) error {
+	orphans, err := cli.project.Orphans()
+	if err != nil {
+		return fmt.Errorf("ERROR: %v", err)
+	}
+
+	if len(orphans) == 0 {
+		_, err := fmt.Fprintln(cli.stdout, "No orphaned executions")
+		return err
+	}
+
+	for _, orphan := range orphans {
+		_, err := fmt.Fprintf(cli.stdout, "%s: %s (last applied %s)\n",
+			orphan.ID,
+			orphan.Reason,
+			orphan.AppliedAt.Local().Format("2006-01-02 15:04"),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (cli *AstroCLI) runOrphansDestroy(cmd *cobra.Command, args []string) error {
+	if err := cli.confirmOrphans("Destroy", args); err != nil {
+		return fmt.Errorf("ERROR: %v", err)
+	}
+
+	status, results, err := cli.project.DestroyOrphans(nilIfEmpty(args), cli.flags.parallelism)
+	if err != nil {
+		return fmt.Errorf("ERROR: %v", err)
+	}
+
+	if _, err := cli.printExecStatus(status, results); err != nil {
+		return errors.New("done; there were errors; some executions may not have been destroyed")
+	}
+
+	_, err = fmt.Fprintln(cli.stdout, "Done")
+	return err
+}
+
+func (cli *AstroCLI) runOrphansArchive(cmd *cobra.Command, args []string) error {
+	if err := cli.confirmOrphans("Archive", args); err != nil {
+		return fmt.Errorf("ERROR: %v", err)
+	}
+
+	if err := cli.project.ArchiveOrphans(nilIfEmpty(args)); err != nil {
+		return fmt.Errorf("ERROR: %v", err)
+	}
+
+	_, err := fmt.Fprintln(cli.stdout, "Done")
+	return err
+}
+
+// confirmOrphans asks for confirmation before acting on the orphans with
+// the given IDs, or on all orphans if there are none, unless --yes was
+// given.
+func (cli *AstroCLI) confirmOrphans(action string, ids []string) error {
+	if cli.flags.yes {
+		return nil
+	}
+	if !isInteractive(cli.stdin) {
+		return errors.New("run again with --yes to confirm")
+	}
+
+	if len(ids) == 0 {
+		orphans, err := cli.project.Orphans()
+		if err != nil {
+			return err
+		}
+		if len(orphans) == 0 {
+			return errors.New("no orphaned executions")
+		}
+		for _, orphan := range orphans {
+			ids = append(ids, orphan.ID)
+		}
+	}
+
+	ok, err := cli.confirm(fmt.Sprintf("%s %s?", action, strings.Join(ids, ", ")))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("cancelled")
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/uber/astro/astro"
+	"github.com/uber/astro/astro/logger"
+
+	"github.com/spf13/cobra"
+)
+
+func (cli *AstroCLI) createOutputCmd() {
+	outputCmd := &cobra.Command{
+		Use:                   "output [flags] [execution...]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Print the outputs of modules as JSON",
+		PersistentPreRunE:     cli.preRun,
+		RunE:                  cli.runOutput,
+	}
+
+	outputCmd.PersistentFlags().StringVar(&cli.flags.filter, "filter", "", "only print the outputs of the executions matching this expression")
+	outputCmd.PersistentFlags().StringVar(&cli.flags.moduleNamesString, "modules", "", "list of modules to print the outputs of")
+	outputCmd.PersistentFlags().IntVar(&cli.flags.parallelism, "parallel", 0, "maximum number of executions to run at the same time")
+
+	cli.commands.output = outputCmd
+}
+
+func (cli *AstroCLI) runOutput(cmd *cobra.Command, args []string) error {
+	logger.Trace.Printf("cli: output args: %s\n", args)
+
+	var moduleNames []string
+	if cli.flags.moduleNamesString != "" {
+		moduleNames = strings.Split(cli.flags.moduleNamesString, ",")
+	}
+
+	outputs, err := cli.project.Outputs(astro.ExecutionParameters{
+		ModuleNames:       moduleNames,
+		UserVars:          flagsToUserVariables(cli.flags.projectFlags),
+		ExecutionPatterns: nilIfEmpty(args),
+		Filter:            cli.flags.filter,
+		Parallelism:       cli.flags.parallelism,
+	})
+	// print the outputs of the executions that succeeded, even if others
+	// failed
+	if outputs != nil {
+		encoder := json.NewEncoder(cli.stdout)
+		encoder.SetIndent("", "  ")
+		if encodeErr := encoder.Encode(outputs); encodeErr != nil {
+			return encodeErr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("ERROR: %v", cli.processError(err))
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/uber/astro/astro/logger"
+
+	"github.com/spf13/cobra"
+)
+
+// pluginPrefix is the prefix of executables on the PATH that are exposed
+// as astro subcommands, e.g. astro-foo becomes `astro foo`.
+const pluginPrefix = "astro-"
+
+// pluginExitError is returned when a plugin exits with a non-zero exit
+// code. The plugin is expected to have printed its own error message.
+type pluginExitError struct {
+	exitCode int
+}
+
+// Error is the error message, so this satisfies the error interface.
+func (e *pluginExitError) Error() string {
+	return fmt.Sprintf("plugin exited with code %d", e.exitCode)
+}
+
+// findPlugins returns a map of plugin names to the paths of their
+// executables. If the same plugin exists in multiple directories in the
+// PATH, the first one wins.
+func findPlugins() map[string]string {
+	plugins := map[string]string{}
+
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		matches, err := filepath.Glob(filepath.Join(dir, pluginPrefix+"*"))
+		if err != nil {
+			continue
+		}
+
+		for _, path := range matches {
+			name := strings.TrimPrefix(filepath.Base(path), pluginPrefix)
+			if _, exists := plugins[name]; exists || name == "" {
+				continue
+			}
+
+			info, err := os.Stat(path)
+			if err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+				continue
+			}
+
+			plugins[name] = path
+		}
+	}
+
+	return plugins
+}
+
+// addPluginCommands adds a subcommand for every plugin found on the PATH.
+// Plugins cannot replace built-in commands.
+func (cli *AstroCLI) addPluginCommands() {
+	plugins := findPlugins()
+
+	var names []string
+	for name := range plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if cmd, _, err := cli.commands.root.Find([]string{name}); err == nil && cmd != cli.commands.root {
+			logger.Trace.Printf("cli: ignoring plugin %v as it conflicts with a built-in command", name)
+			continue
+		}
+
+		path := plugins[name]
+		cli.commands.root.AddCommand(&cobra.Command{
+			Use:                name,
+			Short:              fmt.Sprintf("Run the %s plugin (%s)", name, path),
+			DisableFlagParsing: true,
+			RunE: func(_ *cobra.Command, args []string) error {
+				return cli.runPlugin(path, args)
+			},
+		})
+	}
+}
+
+// runPlugin runs the plugin executable with the specified args. The
+// resolved project config is passed to it as JSON on stdin, and the paths
+// to the config file and session repository are set in the environment.
+func (cli *AstroCLI) runPlugin(path string, args []string) error {
+	logger.Trace.Printf("cli: running plugin %v with args: %v", path, args)
+
+	env := append(os.Environ(), fmt.Sprintf("ASTRO_VERSION=%s", version))
+
+	var stdin []byte
+	if cli.config != nil {
+		configJSON, err := json.Marshal(cli.config)
+		if err != nil {
+			return err
+		}
+		stdin = configJSON
+
+		configFilePath, err := filepath.Abs(cli.configFilePath)
+		if err != nil {
+			return err
+		}
+
+		env = append(env,
+			fmt.Sprintf("ASTRO_CONFIG_FILE=%s", configFilePath),
+			fmt.Sprintf("ASTRO_SESSION_REPO_DIR=%s", filepath.Join(cli.config.SessionRepoDir, ".astro")),
+			fmt.Sprintf("ASTRO_TERRAFORM_CODE_ROOT=%s", cli.config.TerraformCodeRoot),
+		)
+	}
+
+	cmd := exec.Command(path, args...)
+	cmd.Env = env
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = cli.stdout
+	cmd.Stderr = cli.stderr
+
+	err := cmd.Run()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return &pluginExitError{exitCode: exitErr.ExitCode()}
+	}
+
+	return err
+}
@@ -0,0 +1,145 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/uber/astro/astro/conf"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPlugin is a plugin that prints its args, records its stdin and
+// environment in the directory it is in, and exits with the code in its
+// first arg, if any.
+const testPlugin = `#!/bin/bash
+dir=$(dirname "$0")
+cat > "$dir/stdin.json"
+env > "$dir/env"
+echo "args: $*"
+exit "${1:-0}"
+`
+
+// writePlugin writes the plugin to dir with the specified name.
+func writePlugin(t *testing.T, dir, name string) string {
+	path := filepath.Join(dir, pluginPrefix+name)
+	require.NoError(t, os.WriteFile(path, []byte(testPlugin), 0755))
+	return path
+}
+
+func TestFindPlugins(t *testing.T) {
+	first := t.TempDir()
+	second := t.TempDir()
+
+	foo := writePlugin(t, first, "foo")
+	writePlugin(t, second, "foo")
+	bar := writePlugin(t, second, "bar")
+	// files that are not executable and directories are not plugins
+	require.NoError(t, os.WriteFile(filepath.Join(first, "astro-readme"), []byte("docs"), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(first, "astro-dir"), 0755))
+
+	t.Setenv("PATH", first+string(os.PathListSeparator)+second)
+
+	// the first plugin on the PATH wins
+	assert.Equal(t, map[string]string{"foo": foo, "bar": bar}, findPlugins())
+}
+
+func TestPluginBuiltInCommandsWin(t *testing.T) {
+	dir := t.TempDir()
+	writePlugin(t, dir, "foo")
+	writePlugin(t, dir, "plan")
+	t.Setenv("PATH", dir)
+
+	cli, err := NewAstroCLI(WithStdout(&bytes.Buffer{}), WithStderr(&bytes.Buffer{}))
+	require.NoError(t, err)
+	cli.addPluginCommands()
+
+	foo, _, err := cli.commands.root.Find([]string{"foo"})
+	require.NoError(t, err)
+	assert.Equal(t, "foo", foo.Name())
+
+	plan, _, err := cli.commands.root.Find([]string{"plan"})
+	require.NoError(t, err)
+	assert.True(t, plan == cli.commands.plan, "plugin replaced the plan command")
+}
+
+func TestRunPlugin(t *testing.T) {
+	pluginDir := t.TempDir()
+	writePlugin(t, pluginDir, "foo")
+	t.Setenv("PATH", pluginDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	projectDir := t.TempDir()
+	configFile := filepath.Join(projectDir, "astro.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(`---
+terraform:
+  path: /bin/true
+  version: 0.11.7
+modules:
+  - name: app
+    path: .
+`), 0644))
+
+	// the config is found in the working directory
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(projectDir))
+	t.Cleanup(func() {
+		require.NoError(t, os.Chdir(wd))
+	})
+
+	stdout := &bytes.Buffer{}
+	cli, err := NewAstroCLI(WithStdout(stdout), WithStderr(&bytes.Buffer{}))
+	require.NoError(t, err)
+
+	// flags after the plugin name are passed to it, and its exit code is
+	// the exit code of astro
+	assert.Equal(t, 3, cli.Run([]string{"foo", "3", "--bar"}))
+	assert.Equal(t, "args: 3 --bar\n", stdout.String())
+
+	// the resolved config is passed on stdin
+	data, err := os.ReadFile(filepath.Join(pluginDir, "stdin.json"))
+	require.NoError(t, err)
+	var config conf.Project
+	require.NoError(t, json.Unmarshal(data, &config))
+	require.Len(t, config.Modules, 1)
+	assert.Equal(t, "app", config.Modules[0].Name)
+
+	data, err = os.ReadFile(filepath.Join(pluginDir, "env"))
+	require.NoError(t, err)
+	env := strings.Split(string(data), "\n")
+	assert.Contains(t, env, "ASTRO_VERSION="+version)
+	assert.Contains(t, env, "ASTRO_CONFIG_FILE="+configFile)
+	assert.Contains(t, env, "ASTRO_SESSION_REPO_DIR="+filepath.Join(projectDir, ".astro"))
+	assert.Contains(t, env, "ASTRO_TERRAFORM_CODE_ROOT="+projectDir)
+}
+
+func TestRunPluginSuccess(t *testing.T) {
+	dir := t.TempDir()
+	writePlugin(t, dir, "foo")
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cli, err := NewAstroCLI(WithStdout(&bytes.Buffer{}), WithStderr(&bytes.Buffer{}))
+	require.NoError(t, err)
+
+	assert.Equal(t, 0, cli.Run([]string{"foo"}))
+}
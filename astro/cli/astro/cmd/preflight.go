@@ -0,0 +1,34 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+)
+
+// preflight runs the preflight checks of the project before it plans,
+// applies or destroys, and prints their warnings.
+func (cli *AstroCLI) preflight() error {
+	warnings, err := cli.project.Preflight()
+	for _, warning := range warnings {
+		cli.printWarning("%s", warning)
+	}
+	if err != nil {
+		return fmt.Errorf("ERROR: %v", err)
+	}
+	return nil
+}
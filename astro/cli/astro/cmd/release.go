@@ -0,0 +1,131 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/uber/astro/astro"
+
+	"github.com/spf13/cobra"
+)
+
+func (cli *AstroCLI) createReleaseCmd() {
+	releaseCmd := &cobra.Command{
+		Use:                   "release --train FILE",
+		DisableFlagsInUseLine: true,
+		Short:                 "Plan and apply several projects in order",
+		RunE:                  cli.runRelease,
+	}
+
+	releaseCmd.PersistentFlags().StringVar(&cli.flags.releaseTrainFile, "train", "", "release file listing the projects to plan and apply")
+	releaseCmd.PersistentFlags().IntVar(&cli.flags.parallelism, "parallel", 0, "maximum number of executions to run at the same time")
+	releaseCmd.PersistentFlags().BoolVar(&cli.flags.yes, "yes", false, "apply steps with a checkpoint without asking for confirmation")
+	releaseCmd.MarkPersistentFlagRequired("train")
+
+	cli.commands.release = releaseCmd
+}
+
+func (cli *AstroCLI) runRelease(*cobra.Command, []string) error {
+	train, err := astro.LoadReleaseTrain(cli.flags.releaseTrainFile)
+	if err != nil {
+		return fmt.Errorf("ERROR: %v", err)
+	}
+
+	for i, step := range train.Steps {
+		fmt.Fprintf(cli.stdout, "\n==> Step %d/%d: %s\n", i+1, len(train.Steps), step.Name)
+		if err := cli.runReleaseStep(step); err != nil {
+			return fmt.Errorf("ERROR: release stopped at step %s: %v", step.Name, err)
+		}
+	}
+
+	_, err = fmt.Fprintln(cli.stdout, "\nDone")
+	return err
+}
+
+// runReleaseStep plans the step's project and saves the plans, then
+// applies exactly those plans, asking for confirmation first if the step
+// is a checkpoint.
+func (cli *AstroCLI) runReleaseStep(step astro.ReleaseStep) error {
+	if err := step.VerifySHA(); err != nil {
+		return err
+	}
+
+	config, err := astro.NewConfigFromFile(step.Config)
+	if err != nil {
+		return err
+	}
+
+	opts := []astro.Option{astro.WithConfig(*config), astro.WithSessionLog(cli.flags.logFormat)}
+	if cli.followTerraform() {
+		opts = append(opts, astro.WithTerraformOutput(cli.stdout))
+	}
+
+	project, err := astro.NewProject(opts...)
+	if err != nil {
+		return err
+	}
+
+	status, results, err := project.Plan(astro.PlanExecutionParameters{
+		ExecutionParameters: astro.ExecutionParameters{
+			ModuleNames: step.Modules,
+			UserVars:    step.UserVariables(config),
+			Parallelism: cli.flags.parallelism,
+		},
+		SavePlans: true,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := cli.printExecStatus(status, results); err != nil {
+		return errors.New("there were errors planning")
+	}
+
+	if step.Checkpoint && !cli.flags.yes {
+		if !isInteractive(cli.stdin) {
+			return errors.New("step is a checkpoint; run again with --yes to apply it without confirmation")
+		}
+		apply, err := cli.confirm(fmt.Sprintf("Apply step %s?", step.Name))
+		if err != nil {
+			return err
+		}
+		if !apply {
+			return errors.New("not applied")
+		}
+	}
+
+	sessionID, err := project.SessionID()
+	if err != nil {
+		return err
+	}
+
+	status, results, err = project.Apply(astro.ApplyExecutionParameters{
+		ExecutionParameters: astro.ExecutionParameters{
+			Parallelism: cli.flags.parallelism,
+		},
+		FromSession: sessionID,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := cli.printExecStatus(status, results); err != nil {
+		return errors.New("there were errors applying; some modules may not have been applied")
+	}
+
+	return nil
+}
@@ -0,0 +1,322 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/uber/astro/astro"
+	"github.com/uber/astro/astro/cost"
+	"github.com/uber/astro/astro/risk"
+	"github.com/uber/astro/astro/terraform"
+	"github.com/uber/astro/astro/upload"
+	"github.com/uber/astro/astro/utils"
+)
+
+// topExecutionsCount is the number of executions with the most changes
+// that are listed in the summary and report.
+const topExecutionsCount = 5
+
+// jsonReport is the structure of the report written by --json-report.
+type jsonReport struct {
+	Executions []jsonReportExecution `json:"executions"`
+	// Totals is the sum of the changes in all plans.
+	Totals terraform.ChangeCounts `json:"totals"`
+	// Top is the IDs of the executions with the most changes, in
+	// descending order.
+	Top []string `json:"top"`
+	// Drifted is the IDs of the executions that drifted from their
+	// state, with plan --detect-drift.
+	Drifted []string `json:"drifted,omitempty"`
+}
+
+// jsonReportExecution is the result of a single execution in the report.
+type jsonReportExecution struct {
+	ID      string                  `json:"id"`
+	Success bool                    `json:"success"`
+	Error   string                  `json:"error,omitempty"`
+	Runtime string                  `json:"runtime,omitempty"`
+	Changes *terraform.ChangeCounts `json:"changes,omitempty"`
+	// Suppressed is the changes to the resources whose changes were all
+	// suppressed, if there are any.
+	Suppressed *terraform.ChangeCounts `json:"suppressed,omitempty"`
+	// Guidance is what to do about the error, from the docs and
+	// on_error_message of the module.
+	Guidance string `json:"guidance,omitempty"`
+	// Cost is the estimated monthly cost before and after the plan, with
+	// plan --cost.
+	Cost *cost.Estimate `json:"cost,omitempty"`
+	// Risk is the risk of the changes in the plan, if the config has a
+	// risk config.
+	Risk *risk.Assessment `json:"risk,omitempty"`
+	// SkipReason is why a PreModuleRun hook vetoed the execution, if it
+	// was skipped.
+	SkipReason string `json:"skip_reason,omitempty"`
+	// Commands is the Terraform commands that were run, so that they can
+	// be reproduced by hand.
+	Commands []terraform.Invocation `json:"commands,omitempty"`
+}
+
+// flattenResults returns the results along with all of their
+// sub-results.
+func flattenResults(results []*astro.Result) []*astro.Result {
+	var flattened []*astro.Result
+	for _, result := range results {
+		flattened = append(flattened, result)
+		flattened = append(flattened, result.SubResults()...)
+	}
+	return flattened
+}
+
+// changeStats returns the total changes across all plan results, the
+// number of plans with changes, and the plan results with the most
+// changes.
+func changeStats(results []*astro.Result) (totals terraform.ChangeCounts, changed int, top []*astro.Result) {
+	for _, result := range flattenResults(results) {
+		planResult, ok := result.TerraformResult().(*terraform.PlanResult)
+		if !ok || planResult == nil {
+			continue
+		}
+
+		counts := planResult.ChangeCounts()
+		totals = totals.Plus(counts)
+
+		if counts.Total() > 0 {
+			top = append(top, result)
+		}
+	}
+
+	changed = len(top)
+
+	sort.SliceStable(top, func(i, j int) bool {
+		return top[i].ChangeCounts().Total() > top[j].ChangeCounts().Total()
+	})
+
+	if len(top) > topExecutionsCount {
+		top = top[:topExecutionsCount]
+	}
+
+	return totals, changed, top
+}
+
+// writeJSONReport writes a JSON report of the results to the file set
+// with --json-report, if any.
+func (cli *AstroCLI) writeJSONReport(results []*astro.Result) error {
+	if cli.flags.jsonReportFile == "" {
+		return nil
+	}
+
+	totals, _, top := changeStats(results)
+
+	report := jsonReport{
+		Executions: []jsonReportExecution{},
+		Totals:     totals,
+		Top:        []string{},
+	}
+
+	for _, result := range flattenResults(results) {
+		execution := jsonReportExecution{
+			ID:         result.ID(),
+			Success:    result.Err() == nil,
+			SkipReason: result.SkipReason(),
+			Commands:   result.Invocations(),
+			Cost:       result.Cost(),
+			Risk:       result.Risk(),
+		}
+		if result.Err() != nil {
+			execution.Error = result.Err().Error()
+			execution.Guidance = result.Guidance()
+		}
+		if terraformResult := result.TerraformResult(); terraformResult != nil {
+			execution.Runtime = terraformResult.Runtime()
+		}
+		if planResult, ok := result.TerraformResult().(*terraform.PlanResult); ok && planResult != nil {
+			counts := planResult.ChangeCounts()
+			execution.Changes = &counts
+			if suppressed := planResult.SuppressedCounts(); suppressed.Total() > 0 {
+				execution.Suppressed = &suppressed
+			}
+		}
+		report.Executions = append(report.Executions, execution)
+	}
+
+	for _, result := range top {
+		report.Top = append(report.Top, result.ID())
+	}
+
+	if cli.flags.detectDrift {
+		report.Drifted = driftedExecutions(results)
+	}
+
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(cli.flags.jsonReportFile, append(b, '\n'), 0644)
+}
+
+// planReport is the data used to render Markdown and HTML plan reports.
+type planReport struct {
+	Totals     string
+	Changed    int
+	Executions []planReportExecution
+}
+
+// planReportExecution is the result of a single execution in a plan
+// report.
+type planReportExecution struct {
+	ID      string
+	Status  string
+	Changes string
+	Plan    string
+}
+
+const markdownReportTemplate = `# Astro plan report
+
+Total changes: {{.Totals}} across {{.Changed}} executions
+
+| Execution | Status | Changes |
+| --- | --- | --- |
+{{range .Executions}}| {{.ID}} | {{.Status}} | {{.Changes}} |
+{{end}}
+{{- range .Executions}}{{if .Plan}}
+## {{.ID}}
+
+` + "```" + `
+{{.Plan}}
+` + "```" + `
+{{end}}{{end}}`
+
+const htmlReportTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Astro plan report</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+pre { background: #f6f8fa; padding: 8px; }
+</style>
+</head>
+<body>
+<h1>Astro plan report</h1>
+<p>Total changes: {{.Totals}} across {{.Changed}} executions</p>
+<table>
+<tr><th>Execution</th><th>Status</th><th>Changes</th></tr>
+{{range .Executions}}<tr><td><a href="#{{.ID}}">{{.ID}}</a></td><td>{{.Status}}</td><td>{{.Changes}}</td></tr>
+{{end}}</table>
+{{range .Executions}}{{if .Plan}}<h2 id="{{.ID}}">{{.ID}}</h2>
+<pre>{{.Plan}}</pre>
+{{end}}{{end}}</body>
+</html>
+`
+
+// newPlanReport collects the data for a plan report from the results.
+func newPlanReport(results []*astro.Result) planReport {
+	totals, changed, _ := changeStats(results)
+
+	report := planReport{
+		Totals:  formatChangeCounts(totals),
+		Changed: changed,
+	}
+
+	for _, result := range flattenResults(results) {
+		execution := planReportExecution{
+			ID:     result.ID(),
+			Status: "OK",
+		}
+
+		if result.SkipReason() != "" {
+			execution.Status = "SKIPPED: " + result.SkipReason()
+		}
+		if result.Err() != nil {
+			execution.Status = "ERROR"
+			execution.Plan = strings.TrimSpace(result.Err().Error())
+		}
+
+		if planResult, ok := result.TerraformResult().(*terraform.PlanResult); ok && planResult != nil {
+			execution.Changes = formatChangeCounts(planResult.ChangeCounts())
+			if planResult.HasChanges() {
+				execution.Plan = planResult.Changes()
+			}
+		}
+
+		report.Executions = append(report.Executions, execution)
+	}
+
+	return report
+}
+
+// writePlanReport renders a plan report of the results in the specified
+// format ("markdown" or "html") to w.
+func writePlanReport(w io.Writer, format string, results []*astro.Result) error {
+	report := newPlanReport(results)
+
+	if format == "markdown" {
+		return template.Must(template.New("report").Parse(markdownReportTemplate)).Execute(w, report)
+	}
+
+	return htmltemplate.Must(htmltemplate.New("report").Parse(htmlReportTemplate)).Execute(w, report)
+}
+
+// uploadPlanReport uploads a plan report of the results, if configured to
+// do so, and returns a link to it.
+func (cli *AstroCLI) uploadPlanReport(results []*astro.Result) (string, error) {
+	uploadConfig := cli.config.Reports.Upload
+	if uploadConfig == nil {
+		return "", nil
+	}
+
+	format, extension := "html", "html"
+	if uploadConfig.Format == "markdown" {
+		format, extension = "markdown", "md"
+	}
+
+	var buf bytes.Buffer
+	if err := writePlanReport(&buf, format, results); err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp("", "astro-report-*."+extension)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	destURL := fmt.Sprintf("%s/%s.%s", strings.TrimSuffix(uploadConfig.URL, "/"), utils.ULIDString(), extension)
+
+	return upload.Upload(context.Background(), f.Name(), destURL, uploadConfig.Expiry())
+}
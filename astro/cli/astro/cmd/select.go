@@ -0,0 +1,212 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/uber/astro/astro"
+)
+
+// selectorItem is an execution that can be selected in the interactive
+// selector.
+type selectorItem struct {
+	module   string
+	id       string
+	selected bool
+}
+
+// executionSelector is a checklist of executions, grouped by module, that
+// can be filtered with a fuzzy search and toggled by number.
+type executionSelector struct {
+	items  []*selectorItem
+	filter string
+}
+
+// newExecutionSelector creates a selector with the execution IDs of each
+// module, in the order of the modules in the config.
+func newExecutionSelector(modules []string, ids map[string][]string) *executionSelector {
+	selector := &executionSelector{}
+	for _, module := range modules {
+		for _, id := range ids[module] {
+			selector.items = append(selector.items, &selectorItem{module: module, id: id})
+		}
+	}
+	return selector
+}
+
+// fuzzyMatch returns whether all the characters in pattern appear in s,
+// in order, ignoring case.
+func fuzzyMatch(pattern, s string) bool {
+	s = strings.ToLower(s)
+	for _, c := range strings.ToLower(pattern) {
+		i := strings.IndexRune(s, c)
+		if i < 0 {
+			return false
+		}
+		s = s[i+1:]
+	}
+	return true
+}
+
+// visible returns the indexes of the items that match the filter.
+func (s *executionSelector) visible() (indexes []int) {
+	for i, item := range s.items {
+		if fuzzyMatch(s.filter, item.id) {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// selected returns the IDs of the selected executions.
+func (s *executionSelector) selected() (ids []string) {
+	for _, item := range s.items {
+		if item.selected {
+			ids = append(ids, item.id)
+		}
+	}
+	return ids
+}
+
+// render writes the visible items to w, grouped by module.
+func (s *executionSelector) render(w io.Writer) {
+	module := ""
+	for _, i := range s.visible() {
+		item := s.items[i]
+		if item.module != module {
+			module = item.module
+			fmt.Fprintf(w, "%s\n", module)
+		}
+
+		check := " "
+		if item.selected {
+			check = "x"
+		}
+		fmt.Fprintf(w, "  [%s] %3d  %s\n", check, i+1, item.id)
+	}
+
+	if s.filter != "" {
+		fmt.Fprintf(w, "(filtered by %q)\n", s.filter)
+	}
+}
+
+// toggle toggles the items with the numbers in input, which is a list of
+// numbers and ranges separated by spaces or commas, e.g. "1 3-5". If input
+// is "a", all visible items are toggled.
+func (s *executionSelector) toggle(input string) error {
+	if input == "a" {
+		for _, i := range s.visible() {
+			s.items[i].selected = !s.items[i].selected
+		}
+		return nil
+	}
+
+	var indexes []int
+	for _, field := range strings.FieldsFunc(input, func(r rune) bool { return r == ' ' || r == ',' }) {
+		from, to := field, field
+		if parts := strings.SplitN(field, "-", 2); len(parts) == 2 {
+			from, to = parts[0], parts[1]
+		}
+
+		start, err := strconv.Atoi(from)
+		if err != nil {
+			return fmt.Errorf("invalid number: %v", from)
+		}
+		end, err := strconv.Atoi(to)
+		if err != nil {
+			return fmt.Errorf("invalid number: %v", to)
+		}
+		if start < 1 || end > len(s.items) || start > end {
+			return fmt.Errorf("invalid selection: %v", field)
+		}
+
+		for n := start; n <= end; n++ {
+			indexes = append(indexes, n-1)
+		}
+	}
+
+	for _, i := range indexes {
+		s.items[i].selected = !s.items[i].selected
+	}
+
+	return nil
+}
+
+// selectExecutions shows the executions that would run with the parameters
+// and asks the user which of them to run. It returns the IDs of the
+// selected executions.
+func (cli *AstroCLI) selectExecutions(parameters astro.ExecutionParameters) ([]string, error) {
+	if !isInteractive(cli.stdin) {
+		return nil, errors.New("--select-interactive requires an interactive terminal")
+	}
+
+	ids, err := cli.project.ExecutionIDs(parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	var modules []string
+	for _, module := range cli.config.Modules {
+		modules = append(modules, module.Name)
+	}
+
+	return runExecutionSelector(newExecutionSelector(modules, ids), cli.stdin, cli.stderr)
+}
+
+// runExecutionSelector reads commands from r until the user confirms or
+// cancels the selection.
+func runExecutionSelector(selector *executionSelector, r io.Reader, w io.Writer) ([]string, error) {
+	if len(selector.items) == 0 {
+		return nil, errors.New("no executions to select from")
+	}
+
+	reader := bufio.NewReader(r)
+
+	for {
+		selector.render(w)
+		fmt.Fprint(w, "Toggle by number (e.g. \"1 3-5\"), \"a\" to toggle all shown, \"/text\" to filter, \"/\" to clear the filter, Enter to run, \"q\" to quit: ")
+
+		line, err := reader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return nil, errors.New("selection cancelled")
+		}
+
+		switch input := strings.TrimSpace(line); {
+		case input == "":
+			selected := selector.selected()
+			if len(selected) == 0 {
+				fmt.Fprintln(w, "Nothing is selected.")
+				continue
+			}
+			return selected, nil
+		case input == "q":
+			return nil, errors.New("selection cancelled")
+		case strings.HasPrefix(input, "/"):
+			selector.filter = strings.TrimPrefix(input, "/")
+		default:
+			if err := selector.toggle(input); err != nil {
+				fmt.Fprintln(w, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,68 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	assert.True(t, fuzzyMatch("", "app-dev"))
+	assert.True(t, fuzzyMatch("apd", "app-dev"))
+	assert.True(t, fuzzyMatch("ADV", "app-dev"))
+	assert.False(t, fuzzyMatch("dva", "app-dev"))
+}
+
+func TestExecutionSelector(t *testing.T) {
+	selector := newExecutionSelector([]string{"app", "database"}, map[string][]string{
+		"database": {"database-dev", "database-prod"},
+		"app":      {"app-dev", "app-prod"},
+	})
+
+	input := strings.Join([]string{
+		"",      // nothing selected yet
+		"1 3-4", // app-dev, database-dev, database-prod
+		"/prod", // filter to the prod executions
+		"a",     // toggles app-prod and database-prod
+		"9",     // out of range
+		"/",     // clear the filter
+		"",      // run
+	}, "\n") + "\n"
+
+	out := &bytes.Buffer{}
+	selected, err := runExecutionSelector(selector, strings.NewReader(input), out)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"app-dev", "app-prod", "database-dev"}, selected)
+	assert.Contains(t, out.String(), "Nothing is selected.")
+	assert.Contains(t, out.String(), "invalid selection: 9")
+	assert.Contains(t, out.String(), "app\n  [x]   1  app-dev\n")
+}
+
+func TestExecutionSelectorQuit(t *testing.T) {
+	selector := newExecutionSelector([]string{"app"}, map[string][]string{
+		"app": {"app-dev"},
+	})
+
+	_, err := runExecutionSelector(selector, strings.NewReader("1\nq\n"), &bytes.Buffer{})
+	assert.EqualError(t, err, "selection cancelled")
+}
@@ -0,0 +1,271 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/uber/astro/astro"
+	"github.com/uber/astro/astro/terraform"
+
+	"github.com/spf13/cobra"
+)
+
+func (cli *AstroCLI) createSessionCmd() {
+	sessionCmd := &cobra.Command{
+		Use:                   "session",
+		DisableFlagsInUseLine: true,
+		Short:                 "Look into the sessions of the project, and prune them",
+	}
+
+	listCmd := &cobra.Command{
+		Use:               "list",
+		Short:             "List the sessions of the project, most recent first",
+		Args:              cobra.NoArgs,
+		PersistentPreRunE: cli.preRun,
+		RunE:              cli.runSessionList,
+	}
+
+	showCmd := &cobra.Command{
+		Use:               "show <session>",
+		Short:             "Show what ran in a session, and how each execution did",
+		Args:              cobra.ExactArgs(1),
+		PersistentPreRunE: cli.preRun,
+		RunE:              cli.runSessionShow,
+	}
+
+	pruneCmd := &cobra.Command{
+		Use:               "prune [flags]",
+		Short:             "Remove old sessions from the project",
+		Args:              cobra.NoArgs,
+		PersistentPreRunE: cli.preRun,
+		RunE:              cli.runSessionPrune,
+	}
+	pruneCmd.Flags().StringVar(&cli.flags.sessionOlderThan, "older-than", "", "remove the sessions that started longer ago than this, e.g. 30d, 2w or 12h")
+	pruneCmd.Flags().IntVar(&cli.flags.sessionKeep, "keep", 0, "remove all but this many of the most recent sessions")
+
+	logsCmd := &cobra.Command{
+		Use:               "logs <session> <execution> [command] [flags]",
+		Short:             "Show the Terraform commands of an execution, or the output of one of them",
+		Args:              cobra.RangeArgs(2, 3),
+		PersistentPreRunE: cli.preRun,
+		RunE:              cli.runSessionLogs,
+	}
+	logsCmd.Flags().StringVar(&cli.flags.sessionLogStream, "stream", "", "show only the stdout or stderr of the command")
+
+	sessionCmd.AddCommand(listCmd, showCmd, logsCmd, pruneCmd)
+
+	cli.commands.session = sessionCmd
+}
+
+func (cli *AstroCLI) runSessionLogs(cmd *cobra.Command, args []string) error {
+	sessionID, executionID := args[0], args[1]
+
+	if len(args) == 3 {
+		name := args[2] + ".log"
+		switch cli.flags.sessionLogStream {
+		case "":
+		case "stdout":
+			name = args[2] + terraform.StdoutLogSuffix
+		case "stderr":
+			name = args[2] + terraform.StderrLogSuffix
+		default:
+			return fmt.Errorf("ERROR: invalid --stream: %v; must be stdout or stderr", cli.flags.sessionLogStream)
+		}
+
+		log, err := cli.project.SessionLog(sessionID, executionID, name)
+		if err != nil {
+			return fmt.Errorf("ERROR: unable to read log: %v", err)
+		}
+		_, err = cli.stdout.Write(log)
+		return err
+	}
+
+	info, err := cli.project.SessionInfo(sessionID)
+	if err != nil {
+		return fmt.Errorf("ERROR: %v", err)
+	}
+	for _, execution := range info.Executions {
+		if execution.ID == executionID {
+			return printExecutionCommands(cli.stdout, execution)
+		}
+	}
+	return fmt.Errorf("ERROR: execution does not exist: %v", executionID)
+}
+
+// printExecutionCommands prints how the Terraform commands of the
+// execution exited. Executions recorded by older versions of astro only
+// have logs, which are listed instead.
+func printExecutionCommands(w io.Writer, execution astro.ExecutionInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if len(execution.Commands) == 0 {
+		fmt.Fprintln(tw, "LOG\tSIZE")
+		for _, log := range execution.Logs {
+			fmt.Fprintf(tw, "%s\t%d\n", log.Name, log.Size)
+		}
+		return tw.Flush()
+	}
+
+	fmt.Fprintln(tw, "COMMAND\tEXIT CODE\tDURATION\tSTARTED")
+	for _, command := range execution.Commands {
+		fmt.Fprintf(tw, "%s\t%d\t%v\t%s\n", command.Name, command.ExitCode, command.Duration.Round(time.Millisecond), command.Started.Format(time.RFC3339))
+	}
+	return tw.Flush()
+}
+
+func (cli *AstroCLI) runSessionList(cmd *cobra.Command, args []string) error {
+	sessions, err := cli.project.Sessions()
+	if err != nil {
+		return fmt.Errorf("ERROR: %v", err)
+	}
+	return printSessions(cli.stdout, sessions)
+}
+
+// printSessions prints a line for each session, with how its last run
+// did.
+func printSessions(w io.Writer, sessions []astro.SessionInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SESSION\tSTARTED\tSTATUS\tCOMMAND\tLAST RUN")
+	for _, session := range sessions {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			session.ID,
+			session.Started.UTC().Format(time.RFC3339),
+			session.Status,
+			sessionCommandLine(session),
+			lastRunSummary(session),
+		)
+	}
+	return tw.Flush()
+}
+
+// sessionCommandLine returns the command line that ran in the session, or
+// "-" if it wasn't recorded.
+func sessionCommandLine(session astro.SessionInfo) string {
+	if session.Command == "" {
+		return "-"
+	}
+	return strings.Join(append([]string{session.Command}, session.Args...), " ")
+}
+
+// lastRunSummary returns how the last run of the session did, e.g.
+// "plan: 3 executions, 1 failed", or "-" if none was recorded.
+func lastRunSummary(session astro.SessionInfo) string {
+	if len(session.Runs) == 0 {
+		return "-"
+	}
+	return runSummary(session.Runs[len(session.Runs)-1])
+}
+
+// runSummary returns how a run did, e.g. "plan: 3 executions, 1 failed".
+func runSummary(run astro.RunStats) string {
+	summary := fmt.Sprintf("%s: %d executions", run.Command, run.Executions)
+	if run.Failed > 0 {
+		summary += fmt.Sprintf(", %d failed", run.Failed)
+	}
+	if run.Skipped > 0 {
+		summary += fmt.Sprintf(", %d skipped", run.Skipped)
+	}
+	return summary
+}
+
+func (cli *AstroCLI) runSessionShow(cmd *cobra.Command, args []string) error {
+	info, err := cli.project.SessionInfo(args[0])
+	if err != nil {
+		return fmt.Errorf("ERROR: %v", err)
+	}
+	return printSession(cli.stdout, info)
+}
+
+// printSession prints the details of a session, and the outcome of each
+// execution of each of its runs.
+func printSession(w io.Writer, info *astro.SessionInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Session:\t%s\n", info.ID)
+	fmt.Fprintf(tw, "Started:\t%s\n", info.Started.UTC().Format(time.RFC3339))
+	if info.Finished.IsZero() {
+		fmt.Fprintf(tw, "Status:\t%s\n", info.Status)
+	} else {
+		fmt.Fprintf(tw, "Status:\t%s (finished %s)\n", info.Status, info.Finished.UTC().Format(time.RFC3339))
+	}
+	fmt.Fprintf(tw, "Command:\t%s\n", sessionCommandLine(*info))
+	if info.GitSHA != "" {
+		fmt.Fprintf(tw, "Git SHA:\t%s\n", info.GitSHA)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	terraformVersions := map[string]string{}
+	for _, execution := range info.Executions {
+		terraformVersions[execution.ID] = execution.TerraformVersion
+	}
+
+	for _, run := range info.Runs {
+		fmt.Fprintf(w, "\n%s (%s at %s)\n", runSummary(run), run.Finished.Sub(run.Started).Round(time.Second), run.Started.UTC().Format(time.RFC3339))
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, result := range run.Results {
+			outcome := "ok"
+			switch {
+			case result.Error != "":
+				outcome = "failed"
+			case result.Skipped:
+				outcome = "skipped"
+			}
+			terraformVersion := terraformVersions[result.ID]
+			if terraformVersion == "" {
+				terraformVersion = "-"
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", result.ID, outcome, result.Duration, terraformVersion)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (cli *AstroCLI) runSessionPrune(cmd *cobra.Command, args []string) error {
+	if cli.flags.sessionOlderThan == "" && cli.flags.sessionKeep == 0 {
+		return fmt.Errorf("ERROR: pass --older-than or --keep")
+	}
+	if cli.flags.sessionKeep < 0 {
+		return fmt.Errorf("ERROR: invalid --keep: %d is negative", cli.flags.sessionKeep)
+	}
+
+	var olderThan time.Duration
+	if cli.flags.sessionOlderThan != "" {
+		var err error
+		olderThan, err = parseSince(cli.flags.sessionOlderThan)
+		if err != nil {
+			return fmt.Errorf("ERROR: invalid --older-than: %v", err)
+		}
+	}
+
+	prune, err := cli.project.PruneSessions(olderThan, cli.flags.sessionKeep)
+	if err != nil {
+		return fmt.Errorf("ERROR: unable to prune sessions: %v", err)
+	}
+
+	_, err = fmt.Fprintf(cli.stdout, "Removed %d sessions, kept %d\n", len(prune.Removed), prune.Kept)
+	return err
+}
@@ -0,0 +1,112 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/uber/astro/astro"
+	"github.com/uber/astro/astro/exec2"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testShownSession = astro.SessionInfo{
+	ID:       "01D03HZ6S0000000000000000",
+	Started:  testSessionStart,
+	Status:   astro.SessionCompleted,
+	Finished: testSessionStart.Add(2 * time.Minute),
+	Command:  "astro",
+	Args:     []string{"plan", "--modules", "app"},
+	GitSHA:   "abc123",
+	Runs: []astro.RunStats{{
+		Command:    "plan",
+		Started:    testSessionStart.Add(time.Second),
+		Finished:   testSessionStart.Add(91 * time.Second),
+		Executions: 2,
+		Failed:     1,
+		Results: []astro.ExecutionRunStats{
+			{ID: "app-dev", Module: "app", Duration: 30 * time.Second},
+			{ID: "app-prod", Module: "app", Duration: 12 * time.Second, Error: "failed"},
+		},
+	}},
+	Executions: []astro.ExecutionInfo{
+		{ID: "app-dev", TerraformVersion: "1.5.7"},
+	},
+}
+
+func TestPrintSessions(t *testing.T) {
+	out := &bytes.Buffer{}
+	require.NoError(t, printSessions(out, []astro.SessionInfo{
+		testShownSession,
+		{ID: "01D03HZ6S0000000000000001", Started: testSessionStart, Status: astro.SessionStatusUnknown},
+	}))
+
+	assert.Equal(t, `SESSION                    STARTED               STATUS     COMMAND                   LAST RUN
+01D03HZ6S0000000000000000  2019-01-01T00:00:00Z  completed  astro plan --modules app  plan: 2 executions, 1 failed
+01D03HZ6S0000000000000001  2019-01-01T00:00:00Z  unknown    -                         -
+`, out.String())
+}
+
+func TestPrintSession(t *testing.T) {
+	out := &bytes.Buffer{}
+	require.NoError(t, printSession(out, &testShownSession))
+
+	assert.Equal(t, `Session:  01D03HZ6S0000000000000000
+Started:  2019-01-01T00:00:00Z
+Status:   completed (finished 2019-01-01T00:02:00Z)
+Command:  astro plan --modules app
+Git SHA:  abc123
+
+plan: 2 executions, 1 failed (1m30s at 2019-01-01T00:00:01Z)
+  app-dev   ok      30s  1.5.7
+  app-prod  failed  12s  -
+`, out.String())
+}
+
+func TestPrintExecutionCommands(t *testing.T) {
+	started := time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	out := &bytes.Buffer{}
+	require.NoError(t, printExecutionCommands(out, astro.ExecutionInfo{
+		ID: "app-dev",
+		Commands: []astro.CommandInfo{
+			{Name: "init", Status: exec2.Status{ExitCode: 0, Success: true, Started: started, Duration: 2500 * time.Millisecond}},
+			{Name: "plan", Status: exec2.Status{ExitCode: 1, Started: started.Add(3 * time.Second), Duration: 12 * time.Second}},
+		},
+	}))
+
+	assert.Equal(t, `COMMAND  EXIT CODE  DURATION  STARTED
+init     0          2.5s      2019-01-02T03:04:05Z
+plan     1          12s       2019-01-02T03:04:08Z
+`, out.String())
+}
+
+func TestPrintExecutionCommandsWithoutStatus(t *testing.T) {
+	out := &bytes.Buffer{}
+	require.NoError(t, printExecutionCommands(out, astro.ExecutionInfo{
+		ID:   "app-dev",
+		Logs: []astro.LogInfo{{Name: "plan.log", Size: 42}},
+	}))
+
+	assert.Equal(t, `LOG       SIZE
+plan.log  42
+`, out.String())
+}
@@ -0,0 +1,225 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/uber/astro/astro"
+
+	"github.com/spf13/cobra"
+)
+
+// statsTop is the number of flaky modules and errors that are listed.
+const statsTop = 10
+
+// statsFormats are the formats the stats can be written in.
+var statsFormats = map[string]func(io.Writer, *astro.Stats) error{
+	"table": writeStatsTable,
+	"json":  writeStatsJSON,
+}
+
+// jsonStats is the output of astro stats --format json.
+type jsonStats struct {
+	Since    *time.Time        `json:"since,omitempty"`
+	Sessions int               `json:"sessions"`
+	Runs     int               `json:"runs"`
+	Modules  []jsonModuleStats `json:"modules"`
+	Errors   []jsonErrorStats  `json:"errors"`
+}
+
+type jsonModuleStats struct {
+	Name            string  `json:"name"`
+	Executions      int     `json:"executions"`
+	Failed          int     `json:"failed"`
+	Skipped         int     `json:"skipped"`
+	FailureRate     float64 `json:"failure_rate"`
+	Flips           int     `json:"flips"`
+	Flakiness       float64 `json:"flakiness"`
+	AverageDuration float64 `json:"average_duration_seconds"`
+}
+
+type jsonErrorStats struct {
+	Signature string   `json:"signature"`
+	Count     int      `json:"count"`
+	Modules   []string `json:"modules"`
+}
+
+func (cli *AstroCLI) createStatsCmd() {
+	statsCmd := &cobra.Command{
+		Use:                   "stats [flags]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Summarize failure rates, durations, flaky modules and frequent errors from the run history",
+		Args:                  cobra.NoArgs,
+		PersistentPreRunE:     cli.preRun,
+		RunE:                  cli.runStats,
+	}
+	statsCmd.Flags().StringVar(&cli.flags.since, "since", "30d", "only count runs that started within this long, e.g. 30d, 2w or 12h; 0 counts all of them")
+	statsCmd.Flags().StringVar(&cli.flags.listFormat, "format", "table", "output format: table or json")
+
+	cli.commands.stats = statsCmd
+}
+
+func (cli *AstroCLI) runStats(*cobra.Command, []string) error {
+	write, ok := statsFormats[cli.flags.listFormat]
+	if !ok {
+		return fmt.Errorf("ERROR: unknown stats format %q; allowed values: table, json", cli.flags.listFormat)
+	}
+
+	since, err := parseSince(cli.flags.since)
+	if err != nil {
+		return fmt.Errorf("ERROR: invalid --since: %v", err)
+	}
+
+	stats, err := cli.project.Stats(since)
+	if err != nil {
+		return fmt.Errorf("ERROR: %v", err)
+	}
+
+	return write(cli.stdout, stats)
+}
+
+// parseSince parses a duration like time.ParseDuration, that can also be
+// a number of days or weeks, e.g. 30d or 2w.
+func parseSince(s string) (time.Duration, error) {
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if n, err := strconv.Atoi(strings.TrimSuffix(s, suffix)); err == nil && strings.HasSuffix(s, suffix) {
+			if n < 0 {
+				return 0, fmt.Errorf("%v is negative", s)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%v is negative", s)
+	}
+	return d, nil
+}
+
+// formatPercent formats a fraction as a percentage.
+func formatPercent(f float64) string {
+	return fmt.Sprintf("%.0f%%", f*100)
+}
+
+// writeStatsTable writes the stats of each module as a table, least
+// reliable first, followed by the flakiest modules and the most frequent
+// errors.
+func writeStatsTable(w io.Writer, stats *astro.Stats) error {
+	fmt.Fprintf(w, "%d runs in %d sessions", stats.Runs, stats.Sessions)
+	if !stats.Since.IsZero() {
+		fmt.Fprintf(w, " since %s", stats.Since.Local().Format("2006-01-02 15:04"))
+	}
+	fmt.Fprintln(w)
+	if len(stats.Modules) == 0 {
+		_, err := fmt.Fprintln(w, "No executions ran")
+		return err
+	}
+
+	modules := append([]astro.ModuleStats{}, stats.Modules...)
+	sort.SliceStable(modules, func(i, j int) bool {
+		return modules[i].FailureRate() > modules[j].FailureRate()
+	})
+
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MODULE\tEXECUTIONS\tFAILED\tSKIPPED\tFAILURE RATE\tFLAKINESS\tAVG DURATION")
+	for _, m := range modules {
+		duration := "-"
+		if m.Timed > 0 {
+			duration = m.AverageDuration().Round(time.Second).String()
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%s\t%s\t%s\n",
+			m.Name,
+			m.Executions,
+			m.Failed,
+			m.Skipped,
+			formatPercent(m.FailureRate()),
+			formatPercent(m.Flakiness()),
+			duration,
+		)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if flakiest := stats.Flakiest(); len(flakiest) > 0 {
+		fmt.Fprintln(w, "\nFlakiest modules:")
+		for i, m := range flakiest {
+			if i == statsTop {
+				break
+			}
+			fmt.Fprintf(w, "  %s: flipped between failing and succeeding in %d of %d reruns\n", m.Name, m.Flips, m.Reruns)
+		}
+	}
+
+	if len(stats.Errors) > 0 {
+		fmt.Fprintln(w, "\nMost frequent errors:")
+		for i, e := range stats.Errors {
+			if i == statsTop {
+				break
+			}
+			fmt.Fprintf(w, "  %dx %s (%s)\n", e.Count, e.Signature, strings.Join(e.Modules, ", "))
+		}
+	}
+
+	return nil
+}
+
+// writeStatsJSON writes the stats as a JSON document.
+func writeStatsJSON(w io.Writer, stats *astro.Stats) error {
+	doc := jsonStats{
+		Sessions: stats.Sessions,
+		Runs:     stats.Runs,
+		Modules:  []jsonModuleStats{},
+		Errors:   []jsonErrorStats{},
+	}
+	if !stats.Since.IsZero() {
+		doc.Since = &stats.Since
+	}
+	for _, m := range stats.Modules {
+		doc.Modules = append(doc.Modules, jsonModuleStats{
+			Name:            m.Name,
+			Executions:      m.Executions,
+			Failed:          m.Failed,
+			Skipped:         m.Skipped,
+			FailureRate:     m.FailureRate(),
+			Flips:           m.Flips,
+			Flakiness:       m.Flakiness(),
+			AverageDuration: m.AverageDuration().Seconds(),
+		})
+	}
+	for _, e := range stats.Errors {
+		doc.Errors = append(doc.Errors, jsonErrorStats{Signature: e.Signature, Count: e.Count, Modules: e.Modules})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
@@ -0,0 +1,102 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/uber/astro/astro"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testStats = &astro.Stats{
+	Sessions: 2,
+	Runs:     3,
+	Modules: []astro.ModuleStats{
+		{Name: "app", Executions: 3, Failed: 1, Flips: 2, Reruns: 2, TotalDuration: 90 * time.Second, Timed: 3},
+		{Name: "network", Executions: 3, Failed: 2, Skipped: 1, Reruns: 1},
+	},
+	Errors: []astro.ErrorStats{
+		{Signature: "creating VPC (*): LimitExceeded", Count: 2, Modules: []string{"network"}},
+		{Signature: "timeout while waiting for state", Count: 1, Modules: []string{"app"}},
+	},
+}
+
+func TestWriteStatsTable(t *testing.T) {
+	out := &bytes.Buffer{}
+	require.NoError(t, writeStatsTable(out, testStats))
+
+	assert.Equal(t, `3 runs in 2 sessions
+
+MODULE   EXECUTIONS  FAILED  SKIPPED  FAILURE RATE  FLAKINESS  AVG DURATION
+network  3           2       1        100%          0%         -
+app      3           1       0        33%           100%       30s
+
+Flakiest modules:
+  app: flipped between failing and succeeding in 2 of 2 reruns
+
+Most frequent errors:
+  2x creating VPC (*): LimitExceeded (network)
+  1x timeout while waiting for state (app)
+`, out.String())
+}
+
+func TestWriteStatsJSON(t *testing.T) {
+	out := &bytes.Buffer{}
+	require.NoError(t, writeStatsJSON(out, &astro.Stats{Runs: 1, Sessions: 1, Modules: testStats.Modules[:1]}))
+
+	assert.Equal(t, `{
+  "sessions": 1,
+  "runs": 1,
+  "modules": [
+    {
+      "name": "app",
+      "executions": 3,
+      "failed": 1,
+      "skipped": 0,
+      "failure_rate": 0.3333333333333333,
+      "flips": 2,
+      "flakiness": 1,
+      "average_duration_seconds": 30
+    }
+  ],
+  "errors": []
+}
+`, out.String())
+}
+
+func TestParseSince(t *testing.T) {
+	for s, expected := range map[string]time.Duration{
+		"30d": 30 * 24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+		"12h": 12 * time.Hour,
+		"0":   0,
+	} {
+		d, err := parseSince(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, expected, d, s)
+	}
+
+	for _, s := range []string{"", "d", "-1d", "-5m", "month"} {
+		_, err := parseSince(s)
+		assert.Error(t, err, s)
+	}
+}
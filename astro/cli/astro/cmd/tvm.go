@@ -0,0 +1,237 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/uber/astro/astro/tvm"
+
+	"github.com/spf13/cobra"
+)
+
+// tvmCommand is the name of the command that manages the versions of
+// Terraform installed by tvm.
+const tvmCommand = "tvm"
+
+func (cli *AstroCLI) createTVMCmd() {
+	tvmCmd := &cobra.Command{
+		Use:                   tvmCommand,
+		DisableFlagsInUseLine: true,
+		Short:                 "Manage the versions of Terraform that astro installs",
+		Long: `Manage the versions of Terraform that astro installs and looks up when
+Terraform is not in the PATH. Without a version constraint, the commands use
+the terraform.version_constraint of the config, if there is one. The flavor is
+the one of --flavor or the config.`,
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list [flags] [constraint]",
+		Short: "List the installed versions, or with --available the released versions",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  cli.runTVMList,
+	}
+	listCmd.Flags().BoolVar(&cli.flags.tvmAvailable, "available", false, "list the released versions that can be installed")
+
+	installCmd := &cobra.Command{
+		Use:   "install [flags] [constraint]",
+		Short: "Install the latest released version that matches the constraint",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  cli.runTVMInstall,
+	}
+
+	removeCmd := &cobra.Command{
+		Use:   "remove [flags] [version...]",
+		Short: "Remove installed versions",
+		RunE:  cli.runTVMRemove,
+	}
+	removeCmd.Flags().BoolVar(&cli.flags.tvmRemoveAll, "all", false, "remove all the installed versions")
+
+	whichCmd := &cobra.Command{
+		Use:   "which [constraint]",
+		Short: "Print the path of the latest installed version that matches the constraint",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  cli.runTVMWhich,
+	}
+
+	tvmCmd.AddCommand(listCmd, installCmd, removeCmd, whichCmd)
+
+	cli.commands.tvm = tvmCmd
+}
+
+func (cli *AstroCLI) runTVMList(cmd *cobra.Command, args []string) error {
+	repo, err := cli.tvmRepo()
+	if err != nil {
+		return fmt.Errorf("ERROR: %v", err)
+	}
+	constraint := cli.tvmConstraint(args)
+
+	installed, err := repo.List()
+	if err != nil {
+		// the repository doesn't have any versions for this platform yet
+		installed = map[string]string{}
+	}
+
+	if cli.flags.tvmAvailable {
+		available, err := repo.Available(constraint)
+		if err != nil {
+			return fmt.Errorf("ERROR: %v", err)
+		}
+		for _, version := range available {
+			if _, ok := installed[version]; ok {
+				fmt.Fprintf(cli.stdout, "%s (installed)\n", version)
+			} else {
+				fmt.Fprintln(cli.stdout, version)
+			}
+		}
+		return nil
+	}
+
+	// The version that astro runs is the latest that matches
+	current, err := repo.LatestInstalled(constraint)
+	if err != nil {
+		return fmt.Errorf("ERROR: invalid version constraint: %v", err)
+	}
+
+	versions := make([]string, 0, len(installed))
+	for version := range installed {
+		versions = append(versions, version)
+	}
+	for _, version := range tvm.SortVersions(versions) {
+		if version == current {
+			fmt.Fprintf(cli.stdout, "%s (current, %s)\n", version, installed[version])
+		} else {
+			fmt.Fprintf(cli.stdout, "%s (%s)\n", version, installed[version])
+		}
+	}
+
+	return nil
+}
+
+func (cli *AstroCLI) runTVMInstall(cmd *cobra.Command, args []string) error {
+	repo, err := cli.tvmRepo()
+	if err != nil {
+		return fmt.Errorf("ERROR: %v", err)
+	}
+	name := repo.Flavor().Name()
+
+	version, err := repo.LatestAvailable(cli.tvmConstraint(args))
+	if err != nil {
+		return fmt.Errorf("ERROR: %v", err)
+	}
+
+	path, err := repo.Get(version)
+	if err != nil {
+		return fmt.Errorf("ERROR: unable to install %s %s: %v", name, version, err)
+	}
+
+	_, err = fmt.Fprintf(cli.stdout, "Installed %s %s at %s\n", name, version, path)
+	return err
+}
+
+func (cli *AstroCLI) runTVMRemove(cmd *cobra.Command, args []string) error {
+	repo, err := cli.tvmRepo()
+	if err != nil {
+		return fmt.Errorf("ERROR: %v", err)
+	}
+	name := repo.Flavor().Name()
+
+	versions := args
+	if cli.flags.tvmRemoveAll {
+		if len(args) > 0 {
+			return fmt.Errorf("ERROR: --all removes every version; don't list versions with it")
+		}
+		installed, err := repo.List()
+		if err != nil {
+			// nothing was installed for this platform
+			installed = map[string]string{}
+		}
+		for version := range installed {
+			versions = append(versions, version)
+		}
+		versions = tvm.SortVersions(versions)
+	} else if len(args) == 0 {
+		return fmt.Errorf("ERROR: list the versions to remove, or use --all")
+	}
+
+	for _, version := range versions {
+		if err := repo.Remove(version); err != nil {
+			return fmt.Errorf("ERROR: %v", err)
+		}
+		fmt.Fprintf(cli.stdout, "Removed %s %s\n", name, version)
+	}
+
+	return nil
+}
+
+func (cli *AstroCLI) runTVMWhich(cmd *cobra.Command, args []string) error {
+	repo, err := cli.tvmRepo()
+	if err != nil {
+		return fmt.Errorf("ERROR: %v", err)
+	}
+	constraint := cli.tvmConstraint(args)
+
+	version, err := repo.LatestInstalled(constraint)
+	if err != nil {
+		return fmt.Errorf("ERROR: invalid version constraint: %v", err)
+	}
+	if version == "" && constraint == "" {
+		return fmt.Errorf("ERROR: no %s version is installed; install one with: astro tvm install", repo.Flavor().Name())
+	}
+	if version == "" {
+		return fmt.Errorf("ERROR: no installed %s version matches %q; install one with: astro tvm install", repo.Flavor().Name(), constraint)
+	}
+
+	_, err = fmt.Fprintln(cli.stdout, repo.Path(version))
+	return err
+}
+
+// tvmRepo returns the version repository of the flavor of --flavor, or of
+// the config.
+func (cli *AstroCLI) tvmRepo() (*tvm.VersionRepo, error) {
+	flavor, err := tvm.ParseFlavor(cli.flags.flavor)
+	if err != nil {
+		return nil, err
+	}
+	if cli.flags.flavor == "" {
+		if cli.config != nil {
+			flavor = cli.config.TerraformDefaults.TVMFlavor()
+		} else if cli.terraformNotFound != nil {
+			flavor = cli.terraformNotFound.Flavor
+		}
+	}
+
+	repo, err := tvm.NewVersionRepoForCurrentSystem(cli.tvmRepoPath)
+	if err != nil {
+		return nil, err
+	}
+	return repo.ForFlavor(flavor), nil
+}
+
+// tvmConstraint returns the version constraint in the args, or else the
+// one of the config.
+func (cli *AstroCLI) tvmConstraint(args []string) string {
+	switch {
+	case len(args) > 0:
+		return args[0]
+	case cli.config != nil:
+		return cli.config.TerraformDefaults.VersionConstraint
+	case cli.terraformNotFound != nil:
+		return cli.terraformNotFound.Constraint
+	}
+	return ""
+}
@@ -0,0 +1,114 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/tvm"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// installFakeTerraform creates empty Terraform binaries for the versions in
+// a new tvm repository, and returns its path.
+func installFakeTerraform(t *testing.T, versions ...string) string {
+	repoPath := t.TempDir()
+
+	for _, version := range versions {
+		dir := filepath.Join(repoPath, runtime.GOOS, runtime.GOARCH, version)
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "terraform"), nil, 0755))
+	}
+
+	return repoPath
+}
+
+func runTVM(t *testing.T, repoPath string, args ...string) (stdout string, exitCode int) {
+	out := &bytes.Buffer{}
+	cli, err := NewAstroCLI(WithStdout(out), WithStderr(&bytes.Buffer{}))
+	require.NoError(t, err)
+	cli.tvmRepoPath = repoPath
+
+	exitCode = cli.Run(append([]string{"tvm"}, args...))
+	return out.String(), exitCode
+}
+
+func TestTVMCommands(t *testing.T) {
+	repoPath := installFakeTerraform(t, "1.5.7", "1.6.0", "1.10.0")
+	repo, err := tvm.NewVersionRepoForCurrentSystem(repoPath)
+	require.NoError(t, err)
+
+	out, exitCode := runTVM(t, repoPath, "list", "< 1.10")
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "1.10.0 ("+repo.Path("1.10.0")+")\n"+
+		"1.6.0 (current, "+repo.Path("1.6.0")+")\n"+
+		"1.5.7 ("+repo.Path("1.5.7")+")\n", out)
+
+	out, exitCode = runTVM(t, repoPath, "which", "~> 1.5.0")
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, repo.Path("1.5.7")+"\n", out)
+
+	out, exitCode = runTVM(t, repoPath, "remove", "1.5.7")
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "Removed Terraform 1.5.7\n", out)
+
+	_, exitCode = runTVM(t, repoPath, "which", "~> 1.5.0")
+	assert.Equal(t, 1, exitCode)
+
+	_, exitCode = runTVM(t, repoPath, "remove", "1.5.7")
+	assert.Equal(t, 1, exitCode)
+
+	out, exitCode = runTVM(t, repoPath, "remove", "--all")
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "Removed Terraform 1.10.0\nRemoved Terraform 1.6.0\n", out)
+
+	installed, err := repo.List()
+	require.NoError(t, err)
+	assert.Empty(t, installed)
+}
+
+func TestTVMDefaultsFromConfig(t *testing.T) {
+	cli := &AstroCLI{tvmRepoPath: t.TempDir()}
+	assert.Equal(t, "", cli.tvmConstraint(nil))
+
+	cli.config = &conf.Project{TerraformDefaults: conf.Terraform{VersionConstraint: "~> 1.5", Flavor: "opentofu"}}
+	assert.Equal(t, "~> 1.5", cli.tvmConstraint(nil))
+	assert.Equal(t, "1.6.0", cli.tvmConstraint([]string{"1.6.0"}))
+
+	repo, err := cli.tvmRepo()
+	require.NoError(t, err)
+	assert.Equal(t, tvm.OpenTofu, repo.Flavor())
+
+	cli.flags.flavor = "terraform"
+	repo, err = cli.tvmRepo()
+	require.NoError(t, err)
+	assert.Equal(t, tvm.Terraform, repo.Flavor())
+
+	// without Terraform, the config doesn't load but tells what to install
+	cli = &AstroCLI{tvmRepoPath: cli.tvmRepoPath, terraformNotFound: &conf.TerraformNotFoundError{Constraint: "~> 1.6", Flavor: tvm.OpenTofu}}
+	assert.Equal(t, "~> 1.6", cli.tvmConstraint(nil))
+	repo, err = cli.tvmRepo()
+	require.NoError(t, err)
+	assert.Equal(t, tvm.OpenTofu, repo.Flavor())
+}
@@ -0,0 +1,182 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cmd
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/uber/astro/astro"
+
+	"github.com/spf13/cobra"
+)
+
+// sessionSource is the part of a project the session browser reads from.
+type sessionSource interface {
+	Sessions() ([]astro.SessionInfo, error)
+	SessionInfo(id string) (*astro.SessionInfo, error)
+	SessionLog(sessionID, executionID, name string) ([]byte, error)
+}
+
+// uiTemplates are the pages of the session browser.
+var uiTemplates = htmltemplate.Must(htmltemplate.New("ui").Funcs(htmltemplate.FuncMap{
+	"since":     uiSince,
+	"timestamp": func(t time.Time) string { return t.Format("2006-01-02 15:04:05") },
+}).Parse(`{{define "header"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.}}</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+pre { background: #f6f8fa; padding: 8px; }
+.crashed { color: #c00; }
+</style>
+</head>
+<body>
+<p><a href="/">Sessions</a></p>
+{{end}}
+{{define "footer"}}</body>
+</html>
+{{end}}
+{{define "sessions"}}{{template "header" "Astro sessions"}}<h1>Sessions</h1>
+{{if .}}<table>
+<tr><th>Session</th><th>Started</th><th>Status</th><th>Executions</th><th>Saved plans</th><th>Git SHA</th></tr>
+{{range .}}<tr><td><a href="/sessions/{{.ID}}">{{.ID}}</a></td><td>{{timestamp .Started}}</td><td>{{.Status}}</td><td>{{len .Executions}}</td><td>{{len .SavedPlans}}</td><td>{{.GitSHA}}</td></tr>
+{{end}}</table>
+{{else}}<p>No sessions yet.</p>
+{{end}}{{template "footer"}}{{end}}
+{{define "session"}}{{template "header" .ID}}<h1>Session {{.ID}}</h1>
+<p>Started {{timestamp .Started}}{{if .GitSHA}} from commit {{.GitSHA}}{{end}}, {{.Status}}.{{if .Command}} Ran <code>{{.Command}}{{range .Args}} {{.}}{{end}}</code>{{if .PID}} as PID {{.PID}} on {{.Host}}{{end}}.{{end}}{{if .Log}} <a href="/sessions/{{.ID}}/logs/{{.Log}}">Session log</a>{{end}}</p>
+{{if .SavedPlans}}<p>Plans saved at {{timestamp .PlannedAt}} for: {{range $i, $id := .SavedPlans}}{{if $i}}, {{end}}{{$id}}{{end}}</p>
+{{end}}{{$session := .}}<table>
+<tr><th>Execution</th><th>Terraform</th><th>Finished</th><th>Logs</th></tr>
+{{range .Executions}}<tr><td>{{.ID}}{{if .Crashed}} <span class="crashed">(crashed)</span>{{end}}</td><td>{{.TerraformVersion}}</td><td>{{since $session.Started .Finished}}</td><td>{{$execution := .}}{{range .Logs}}<a href="/sessions/{{$session.ID}}/{{$execution.ID}}/logs/{{.Name}}">{{.Name}}</a> ({{since $session.Started .Modified}}) {{end}}</td></tr>
+{{end}}</table>
+{{template "footer"}}{{end}}
+{{define "log"}}{{template "header" .Name}}<p><a href="/sessions/{{.SessionID}}">Session {{.SessionID}}</a></p>
+<h1>{{if .ExecutionID}}{{.ExecutionID}}: {{end}}{{.Name}}</h1>
+<pre>{{.Contents}}</pre>
+{{template "footer"}}{{end}}`))
+
+// uiSince formats when t was, relative to the start of a session.
+func uiSince(start, t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("+%v", t.Sub(start).Round(100*time.Millisecond))
+}
+
+func (cli *AstroCLI) createUICmd() {
+	uiCmd := &cobra.Command{
+		Use:                   "ui [flags]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Browse sessions in a web page",
+		PersistentPreRunE:     cli.preRun,
+		RunE:                  cli.runUI,
+	}
+
+	uiCmd.PersistentFlags().StringVar(&cli.flags.uiAddress, "address", "127.0.0.1:8080", "address to serve the web page on")
+
+	cli.commands.ui = uiCmd
+}
+
+func (cli *AstroCLI) runUI(*cobra.Command, []string) error {
+	listener, err := net.Listen("tcp", cli.flags.uiAddress)
+	if err != nil {
+		return fmt.Errorf("ERROR: %v", err)
+	}
+
+	fmt.Fprintf(cli.stdout, "Browse sessions at http://%s/ (press Ctrl-C to stop)\n", listener.Addr())
+
+	return http.Serve(listener, newSessionBrowser(cli.project))
+}
+
+// newSessionBrowser returns a handler that serves the pages of the session
+// browser:
+//
+//	/                                          list of sessions
+//	/sessions/<session>                        executions of a session
+//	/sessions/<session>/logs/<name>            log of a session
+//	/sessions/<session>/<execution>/logs/<name> log of an execution
+func newSessionBrowser(sessions sessionSource) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		list, err := sessions.Sessions()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		renderUIPage(w, "sessions", list)
+	})
+
+	mux.HandleFunc("/sessions/", func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/sessions/"), "/")
+
+		switch {
+		case len(parts) == 1:
+			info, err := sessions.SessionInfo(parts[0])
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			renderUIPage(w, "session", info)
+		case len(parts) == 3 && parts[1] == "logs":
+			serveSessionLog(w, sessions, parts[0], "", parts[2])
+		case len(parts) == 4 && parts[2] == "logs":
+			serveSessionLog(w, sessions, parts[0], parts[1], parts[3])
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	return mux
+}
+
+// serveSessionLog renders a log file of a session, or of an execution in
+// it if executionID is set.
+func serveSessionLog(w http.ResponseWriter, sessions sessionSource, sessionID, executionID, name string) {
+	contents, err := sessions.SessionLog(sessionID, executionID, name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	renderUIPage(w, "log", map[string]string{
+		"SessionID":   sessionID,
+		"ExecutionID": executionID,
+		"Name":        name,
+		"Contents":    string(contents),
+	})
+}
+
+// renderUIPage renders a page of the session browser.
+func renderUIPage(w http.ResponseWriter, page string, data interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := uiTemplates.ExecuteTemplate(w, page, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
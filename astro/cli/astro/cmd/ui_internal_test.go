@@ -0,0 +1,102 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/uber/astro/astro"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSessions is a session repo with a single session.
+type fakeSessions struct{}
+
+var testSessionStart = time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var testSession = astro.SessionInfo{
+	ID:      "01D03HZ6S0000000000000000",
+	Started: testSessionStart,
+	Executions: []astro.ExecutionInfo{{
+		ID:               "app-dev",
+		TerraformVersion: "0.11.7",
+		Logs: []astro.LogInfo{
+			{Name: "plan.log", Modified: testSessionStart.Add(90 * time.Second)},
+		},
+		Crashed: true,
+	}},
+}
+
+func (fakeSessions) Sessions() ([]astro.SessionInfo, error) {
+	return []astro.SessionInfo{testSession}, nil
+}
+
+func (fakeSessions) SessionInfo(id string) (*astro.SessionInfo, error) {
+	if id != testSession.ID {
+		return nil, fmt.Errorf("session does not exist: %v", id)
+	}
+	return &testSession, nil
+}
+
+func (fakeSessions) SessionLog(sessionID, executionID, name string) ([]byte, error) {
+	if sessionID != testSession.ID || executionID != "app-dev" || name != "plan.log" {
+		return nil, fmt.Errorf("invalid log: %v", name)
+	}
+	return []byte("Plan: 1 to add, 0 to change, 0 to destroy. <html>"), nil
+}
+
+func TestSessionBrowser(t *testing.T) {
+	server := httptest.NewServer(newSessionBrowser(fakeSessions{}))
+	defer server.Close()
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(server.URL + path)
+		if !assert.NoError(t, err) {
+			return 0, ""
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	status, body := get("/")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `<a href="/sessions/01D03HZ6S0000000000000000">`)
+
+	status, body = get("/sessions/01D03HZ6S0000000000000000")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, "app-dev")
+	assert.Contains(t, body, "(crashed)")
+	assert.Contains(t, body, "0.11.7")
+	assert.Contains(t, body, `<a href="/sessions/01D03HZ6S0000000000000000/app-dev/logs/plan.log">plan.log</a> (&#43;1m30s)`)
+
+	status, body = get("/sessions/01D03HZ6S0000000000000000/app-dev/logs/plan.log")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, "Plan: 1 to add, 0 to change, 0 to destroy. &lt;html&gt;")
+
+	status, _ = get("/sessions/unknown")
+	assert.Equal(t, http.StatusNotFound, status)
+
+	status, _ = get("/sessions/01D03HZ6S0000000000000000/app-dev/logs/apply.log")
+	assert.Equal(t, http.StatusNotFound, status)
+}
@@ -0,0 +1,80 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/uber/astro/astro"
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
+
+	"github.com/spf13/cobra"
+)
+
+func (cli *AstroCLI) createValidateCmd() {
+	validateCmd := &cobra.Command{
+		Use:                   "validate [flags] [execution...]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Validate modules without accessing their state",
+		PersistentPreRunE:     cli.preRun,
+		RunE:                  cli.runValidate,
+	}
+
+	validateCmd.PersistentFlags().StringVar(&cli.flags.filter, "filter", "", "only validate the executions matching this expression")
+	validateCmd.PersistentFlags().BoolVar(&cli.flags.frozen, "frozen", false, "fail if module sources do not match the lock file")
+	validateCmd.PersistentFlags().StringVar(&cli.flags.jsonReportFile, "json-report", "", "write a JSON report of the results to this file")
+	validateCmd.PersistentFlags().StringVar(&cli.flags.moduleNamesString, "modules", "", "list of modules to validate")
+	validateCmd.PersistentFlags().IntVar(&cli.flags.parallelism, "parallel", 0, "maximum number of executions to run at the same time")
+
+	cli.commands.validate = validateCmd
+}
+
+func (cli *AstroCLI) runValidate(cmd *cobra.Command, args []string) error {
+	logger.Trace.Printf("cli: validate args: %s\n", args)
+
+	var moduleNames []string
+	if cli.flags.moduleNamesString != "" {
+		moduleNames = strings.Split(cli.flags.moduleNamesString, ",")
+	}
+
+	status, results, err := cli.project.Validate(astro.ExecutionParameters{
+		ModuleNames:       moduleNames,
+		UserVars:          flagsToUserVariables(cli.flags.projectFlags),
+		ExecutionPatterns: nilIfEmpty(args),
+		Filter:            cli.flags.filter,
+		Frozen:            cli.flags.frozen,
+		Parallelism:       cli.flags.parallelism,
+	})
+	if err != nil {
+		return fmt.Errorf("ERROR: %v", cli.processError(err))
+	}
+
+	collected, err := cli.printExecStatus(status, results)
+	cli.annotate("validate", collected)
+	if reportErr := cli.writeJSONReport(collected); reportErr != nil {
+		return fmt.Errorf("unable to write JSON report: %v", reportErr)
+	}
+	if err != nil {
+		return cli.exitWith(conf.ResultsFailed, errors.New("done; there were errors; some modules are not valid"))
+	}
+
+	_, err = fmt.Fprintln(cli.stdout, "Done")
+	return err
+}
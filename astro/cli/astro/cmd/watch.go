@@ -0,0 +1,151 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/uber/astro/astro"
+	"github.com/uber/astro/astro/utils"
+
+	"github.com/spf13/cobra"
+)
+
+func (cli *AstroCLI) createWatchCmd() {
+	watchCmd := &cobra.Command{
+		Use:                   "watch [flags] [execution...]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Plan the executions of modules again whenever their code changes",
+		PersistentPreRunE:     cli.preRun,
+		RunE:                  cli.runWatch,
+	}
+
+	watchCmd.PersistentFlags().DurationVar(&cli.flags.debounce, "debounce", 500*time.Millisecond, "how long to wait for changes to settle before planning")
+	watchCmd.PersistentFlags().StringVar(&cli.flags.filter, "filter", "", "only plan the executions matching this expression")
+	watchCmd.PersistentFlags().StringVar(&cli.flags.moduleNamesString, "modules", "", "list of modules to watch")
+	watchCmd.PersistentFlags().IntVar(&cli.flags.parallelism, "parallel", 0, "maximum number of executions to run at the same time")
+
+	cli.commands.watch = watchCmd
+}
+
+// runWatch plans the executions of the modules whose code changed, once
+// the changes settle, until astro is interrupted. If the config changes,
+// it is loaded again, and every execution is planned.
+func (cli *AstroCLI) runWatch(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var moduleNames []string
+	if cli.flags.moduleNamesString != "" {
+		moduleNames = strings.Split(cli.flags.moduleNamesString, ",")
+	}
+
+	for {
+		watchCtx, stopWatching := context.WithCancel(ctx)
+		changes, err := cli.project.Watch(watchCtx, moduleNames, []string{cli.configFilePath}, cli.flags.debounce)
+		if err != nil {
+			stopWatching()
+			return fmt.Errorf("ERROR: %v", cli.processError(err))
+		}
+		fmt.Fprintf(cli.stdout, "Watching for changes to %s and the modules; press Ctrl-C to stop\n", cli.configFilePath)
+
+		reload := false
+		for change := range changes {
+			if len(change.Files) > 0 {
+				reload = true
+				break
+			}
+			cli.watchPlan(cmd, args, change.Modules)
+		}
+		stopWatching()
+
+		if !reload {
+			return nil
+		}
+
+		// The modules to watch may have changed with the config
+		fmt.Fprintf(cli.stdout, "\n%s changed; loading it again\n", cli.configFilePath)
+		config, err := astro.NewConfigFromFileWithPresets(cli.configFilePath, cli.configPresets())
+		if err != nil {
+			cli.printWarning("unable to load config: %v", err)
+		} else {
+			cli.config = config
+		}
+		cli.watchPlan(cmd, args, nil)
+	}
+}
+
+// watchPlan plans the selected executions of the modules that changed, all
+// of them if changed is nil, in a new session.
+func (cli *AstroCLI) watchPlan(cmd *cobra.Command, args []string, changed []string) {
+	var moduleNames []string
+	if cli.flags.moduleNamesString != "" {
+		for _, name := range strings.Split(cli.flags.moduleNamesString, ",") {
+			if changed == nil || utils.StringSliceContains(changed, name) {
+				moduleNames = append(moduleNames, name)
+			}
+		}
+	} else {
+		moduleNames = changed
+	}
+	if changed != nil {
+		fmt.Fprintf(cli.stdout, "\nChanged: %s\n", strings.Join(changed, ", "))
+		if len(moduleNames) == 0 {
+			fmt.Fprintln(cli.stdout, "No watched executions changed")
+			return
+		}
+	}
+
+	// Executions can only be planned once in a session
+	cli.closeProject()
+	project, err := cli.newProject(cmd)
+	if err != nil {
+		cli.printWarning("%v", err)
+		return
+	}
+	cli.project = project
+
+	patterns, terraformArgs := splitArgs(cmd, args)
+	status, results, err := project.Plan(astro.PlanExecutionParameters{
+		ExecutionParameters: astro.ExecutionParameters{
+			ModuleNames:         moduleNames,
+			UserVars:            flagsToUserVariables(cli.flags.projectFlags),
+			TerraformParameters: terraformArgs,
+			ExecutionPatterns:   patterns,
+			Filter:              cli.flags.filter,
+			Parallelism:         cli.flags.parallelism,
+		},
+	})
+	var noMatchErr *astro.NoExecutionsMatchedError
+	if errors.As(err, &noMatchErr) {
+		fmt.Fprintln(cli.stdout, "No watched executions changed")
+		return
+	} else if err != nil {
+		fmt.Fprintf(cli.stderr, "ERROR: %v\n", cli.processError(err))
+		return
+	}
+
+	// Failed plans are shown with their results, and watching goes on
+	cli.printExecStatus(status, results)
+}
@@ -0,0 +1,223 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/uber/astro/astro/git"
+	"github.com/uber/astro/astro/terraform"
+	"github.com/uber/astro/astro/utils"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// Differences between the plans of an execution at two refs.
+const (
+	// ComparisonUnchanged means the execution has the same plan at both
+	// refs.
+	ComparisonUnchanged = "unchanged"
+	// ComparisonChanged means the plans of the execution differ.
+	ComparisonChanged = "changed"
+	// ComparisonAdded means the execution only exists at the second ref.
+	ComparisonAdded = "added"
+	// ComparisonRemoved means the execution only exists at the first ref.
+	ComparisonRemoved = "removed"
+	// ComparisonFailed means the execution failed to plan at either ref,
+	// so its plans cannot be compared.
+	ComparisonFailed = "failed"
+)
+
+// RefCheckout is a project checked out at a git ref in a working tree of
+// its own, so that it can be planned without touching the working tree
+// it was checked out from.
+type RefCheckout struct {
+	Ref string
+	// Dir is the root of the working tree.
+	Dir string
+	// ConfigFile is the path to the config file of the project in the
+	// working tree.
+	ConfigFile string
+
+	// repo is the working tree that the checkout was added to.
+	repo string
+}
+
+// CheckoutRef checks out the git repository that contains the config
+// file at ref, in a temporary working tree. The working tree, including
+// the sessions of projects loaded from it, is deleted by Remove.
+func CheckoutRef(configFile, ref string) (*RefCheckout, error) {
+	configFile, err := filepath.Abs(configFile)
+	if err != nil {
+		return nil, err
+	}
+	// git resolves symlinks in the paths it returns
+	configFile, err = filepath.EvalSymlinks(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := git.TopLevel(filepath.Dir(configFile))
+	if err != nil {
+		return nil, fmt.Errorf("unable to find the git repository of %v: %v", configFile, err)
+	}
+	configPath, err := filepath.Rel(repo, configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "astro-compare-")
+	if err != nil {
+		return nil, err
+	}
+	// the working tree has to be created by git
+	dir = filepath.Join(dir, "src")
+
+	if err := git.AddWorktree(repo, dir, ref); err != nil {
+		os.RemoveAll(filepath.Dir(dir))
+		return nil, fmt.Errorf("unable to check out %v: %v", ref, err)
+	}
+
+	checkout := &RefCheckout{
+		Ref:        ref,
+		Dir:        dir,
+		ConfigFile: filepath.Join(dir, configPath),
+		repo:       repo,
+	}
+
+	if !utils.FileExists(checkout.ConfigFile) {
+		checkout.Remove()
+		return nil, fmt.Errorf("%v does not exist at %v", configPath, ref)
+	}
+
+	return checkout, nil
+}
+
+// Remove deletes the working tree of the checkout.
+func (c *RefCheckout) Remove() error {
+	err := git.RemoveWorktree(c.repo, c.Dir)
+	if removeErr := os.RemoveAll(filepath.Dir(c.Dir)); err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+// PlanComparison compares the plans of an execution at two refs.
+type PlanComparison struct {
+	ID string
+	// Base and Head are the results of planning the execution at the
+	// first and second refs. Either is nil if the execution doesn't
+	// exist at that ref.
+	Base *Result
+	Head *Result
+}
+
+// ComparePlans compares the results of planning the same project at two
+// refs, execution by execution. Comparisons are sorted by execution ID.
+func ComparePlans(base, head []*Result) []PlanComparison {
+	byID := map[string]*PlanComparison{}
+	comparison := func(id string) *PlanComparison {
+		if byID[id] == nil {
+			byID[id] = &PlanComparison{ID: id}
+		}
+		return byID[id]
+	}
+
+	for _, result := range flattenResults(base) {
+		comparison(result.ID()).Base = result
+	}
+	for _, result := range flattenResults(head) {
+		comparison(result.ID()).Head = result
+	}
+
+	comparisons := make([]PlanComparison, 0, len(byID))
+	for _, c := range byID {
+		comparisons = append(comparisons, *c)
+	}
+	sort.Slice(comparisons, func(i, j int) bool {
+		return comparisons[i].ID < comparisons[j].ID
+	})
+
+	return comparisons
+}
+
+// Status returns how the plans of the execution differ: one of
+// ComparisonUnchanged, ComparisonChanged, ComparisonAdded,
+// ComparisonRemoved or ComparisonFailed.
+func (c PlanComparison) Status() string {
+	switch {
+	case c.Base == nil:
+		return ComparisonAdded
+	case c.Head == nil:
+		return ComparisonRemoved
+	case c.Base.Err() != nil || c.Head.Err() != nil:
+		return ComparisonFailed
+	case planChanges(c.Base) != planChanges(c.Head):
+		return ComparisonChanged
+	default:
+		return ComparisonUnchanged
+	}
+}
+
+// Diff returns a unified diff of the plan at the first ref against the
+// plan at the second, which are named baseRef and headRef in the header.
+func (c PlanComparison) Diff(baseRef, headRef string) string {
+	diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        planLines(c.Base),
+		B:        planLines(c.Head),
+		FromFile: baseRef,
+		ToFile:   headRef,
+		Context:  3,
+	})
+	return diff
+}
+
+// planChanges returns the changes in the plan of the result, which are
+// empty if it has none.
+func planChanges(result *Result) string {
+	if result == nil {
+		return ""
+	}
+	planResult, ok := result.TerraformResult().(*terraform.PlanResult)
+	if !ok || planResult == nil || !planResult.HasChanges() {
+		return ""
+	}
+	return planResult.Changes()
+}
+
+// planLines returns the lines of the plan of the result, for diffing.
+func planLines(result *Result) []string {
+	changes := planChanges(result)
+	if changes == "" {
+		return nil
+	}
+	return difflib.SplitLines(changes)
+}
+
+// flattenResults returns the results followed by their sub-results, so
+// that each workspace is compared separately.
+func flattenResults(results []*Result) []*Result {
+	var flattened []*Result
+	for _, result := range results {
+		flattened = append(flattened, result)
+		flattened = append(flattened, result.SubResults()...)
+	}
+	return flattened
+}
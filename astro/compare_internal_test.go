@@ -0,0 +1,99 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckoutRef(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	git := func(dir string, args ...string) {
+		cmd := exec.Command("git", append([]string{
+			"-c", "user.name=test", "-c", "user.email=test@example.com",
+		}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+
+	repo := t.TempDir()
+	configFile := filepath.Join(repo, "infra", "astro.yaml")
+	require.NoError(t, os.MkdirAll(filepath.Dir(configFile), 0755))
+	require.NoError(t, os.WriteFile(configFile, []byte("# base\n"), 0644))
+	git(repo, "init", "--quiet")
+	git(repo, "add", ".")
+	git(repo, "commit", "--quiet", "-m", "base")
+	git(repo, "tag", "base")
+	require.NoError(t, os.WriteFile(configFile, []byte("# head\n"), 0644))
+	git(repo, "commit", "--quiet", "-am", "head")
+
+	checkout, err := CheckoutRef(configFile, "base")
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(checkout.ConfigFile)
+	require.NoError(t, err)
+	assert.Equal(t, "# base\n", string(data))
+	assert.Equal(t, filepath.Join(checkout.Dir, "infra", "astro.yaml"), checkout.ConfigFile)
+
+	require.NoError(t, checkout.Remove())
+	_, err = os.Stat(checkout.Dir)
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = CheckoutRef(configFile, "missing")
+	assert.Error(t, err)
+}
+
+func TestComparePlans(t *testing.T) {
+	t.Parallel()
+
+	base := []*Result{
+		{id: "app"},
+		{id: "db", err: errors.New("plan failed")},
+		{id: "old"},
+	}
+	head := []*Result{
+		{id: "new"},
+		{id: "db"},
+		{id: "app"},
+	}
+
+	statuses := map[string]string{}
+	var ids []string
+	for _, comparison := range ComparePlans(base, head) {
+		ids = append(ids, comparison.ID)
+		statuses[comparison.ID] = comparison.Status()
+	}
+
+	assert.Equal(t, []string{"app", "db", "new", "old"}, ids)
+	assert.Equal(t, map[string]string{
+		"app": ComparisonUnchanged,
+		"db":  ComparisonFailed,
+		"new": ComparisonAdded,
+		"old": ComparisonRemoved,
+	}, statuses)
+}
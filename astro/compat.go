@@ -0,0 +1,85 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/tvm"
+
+	"github.com/burl/go-version"
+)
+
+// EngineVersion is a version of Terraform, or of a compatible engine such
+// as OpenTofu, that a project can be planned with to check that it works
+// before the version it is pinned to is changed.
+type EngineVersion struct {
+	// Name is the version or path it was parsed from.
+	Name    string
+	Version *version.Version
+	// Path is the binary of the engine. If it is empty, the version of
+	// Terraform is installed by tvm.
+	Path string
+}
+
+// ParseEngineVersion parses a version of Terraform, e.g. "1.5.7", which
+// is installed by tvm if needed, or the path to the binary of Terraform or
+// of a compatible engine, e.g. "/usr/local/bin/tofu", whose version is
+// detected.
+func ParseEngineVersion(s string) (*EngineVersion, error) {
+	if !strings.ContainsRune(s, filepath.Separator) {
+		v, err := version.NewVersion(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version %q: %v", s, err)
+		}
+		return &EngineVersion{Name: s, Version: v}, nil
+	}
+
+	path, err := filepath.Abs(s)
+	if err != nil {
+		return nil, err
+	}
+	v, err := tvm.InspectVersion(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to detect the version of %v: %v", s, err)
+	}
+
+	return &EngineVersion{Name: s, Version: v, Path: path}, nil
+}
+
+// Sandbox changes the config so that every module runs with the engine,
+// and the sessions are in sessionRepoDir instead of next to the config,
+// so that planning with several versions doesn't share any state. Version
+// constraints are removed, so that versions that aren't allowed yet can
+// be tried.
+func (v *EngineVersion) Sandbox(config *conf.Project, sessionRepoDir string) {
+	sandbox := func(terraform *conf.Terraform) {
+		terraform.Path = v.Path
+		terraform.Version = v.Version
+		terraform.VersionConstraint = ""
+	}
+
+	sandbox(&config.TerraformDefaults)
+	for i := range config.Modules {
+		sandbox(&config.Modules[i].Terraform)
+	}
+
+	config.SessionRepoDir = sessionRepoDir
+}
@@ -0,0 +1,80 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEngineVersion(t *testing.T) {
+	engine, err := ParseEngineVersion("1.5.7")
+	require.NoError(t, err)
+	assert.Equal(t, "1.5.7", engine.Version.String())
+	assert.Empty(t, engine.Path)
+
+	// the version of binaries is detected
+	engine, err = ParseEngineVersion("fixtures/mock-terraform/drift")
+	require.NoError(t, err)
+	assert.Equal(t, "1.5.0", engine.Version.String())
+	assert.Equal(t, absolutePath("fixtures/mock-terraform/drift"), engine.Path)
+
+	_, err = ParseEngineVersion("latest")
+	assert.Error(t, err)
+}
+
+func TestEngineVersionSandbox(t *testing.T) {
+	t.Parallel()
+
+	config, err := NewConfigFromFile("fixtures/test-plan-success/astro.yaml")
+	require.NoError(t, err)
+	config.Modules[0].Terraform.VersionConstraint = "< 0.8"
+
+	engine, err := ParseEngineVersion("fixtures/mock-terraform/success")
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	engine.Sandbox(config, dir)
+
+	for _, module := range config.Modules {
+		assert.Equal(t, engine.Path, module.Terraform.Path)
+		assert.Equal(t, "0.8.8", module.Terraform.Version.String())
+		assert.Empty(t, module.Terraform.VersionConstraint)
+	}
+
+	c, err := NewProject(WithConfig(*config))
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Plan(PlanExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			ModuleNames: []string{"users"},
+			UserVars:    NoUserVariables(),
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]error{
+		"users": nil,
+	}, testResultErrs(testReadResults(resultChan)))
+
+	// the session is in the sandbox
+	sessions, err := filepath.Glob(filepath.Join(dir, ".astro", "*", "users"))
+	require.NoError(t, err)
+	assert.Len(t, sessions, 1)
+}
@@ -17,30 +17,169 @@
 package conf
 
 import (
+	"errors"
 	"fmt"
+	"sort"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 )
 
+// Policies for applying saved plans that are stale.
+const (
+	// StalePlanRefuse fails to apply stale plans. This is the default.
+	StalePlanRefuse = "refuse"
+	// StalePlanWarn applies stale plans, with a warning.
+	StalePlanWarn = "warn"
+	// StalePlanReplan plans again and applies the new plan.
+	StalePlanReplan = "replan"
+)
+
 // Project represents the structure of the YAML configuration for astro.
 type Project struct {
+	// ApplyParallelism is the maximum number of executions that are
+	// applied or destroyed at the same time, if it is not Parallelism,
+	// e.g. to stay under the rate limits of cloud APIs.
+	ApplyParallelism int `json:"apply_parallelism"`
+
+	// ArchiveSessions compresses each session directory into a zstd
+	// archive when the command that ran it ends. Archived sessions are
+	// read like the others, and extracted to apply their saved plans.
+	ArchiveSessions bool `json:"archive_sessions"`
+
+	// AWSCLIPath is the path to the AWS CLI that assumes the roles of
+	// modules with AWS credentials. Defaults to aws in the PATH.
+	AWSCLIPath string `json:"aws_cli_path"`
+
+	// Cost configures estimating the cost of the changes in plans. Costs
+	// are only estimated for plans that ask for it, e.g. with plan --cost,
+	// and only if this is set.
+	Cost *Cost
+
+	// ExitCodes configures the exit code of the CLI for each category of
+	// results, e.g. so that drift doesn't fail a pipeline.
+	ExitCodes ExitCodes `json:"exit_codes"`
+
 	// Flags is a mapping of module variable names to user flags, e.g. for on
 	// the CLI.
 	Flags map[string]Flag
 
+	// Groups are the concurrency groups that modules can be in, by name.
+	Groups map[string]Group
+
 	// Hooks contains configuration of hooks that can be invoked at various
 	// stages of the CLI lifecycle.
 	Hooks Hooks
 
+	// Include is a list of other config files, or glob patterns, relative
+	// to the file that includes them, whose configuration is merged into
+	// this one.
+	Include []string
+
+	// InferDeps adds the modules whose state the Terraform code of a
+	// module reads with terraform_remote_state to its deps, so that they
+	// don't have to be kept in sync by hand. Explicit deps on the same
+	// module take precedence.
+	InferDeps bool `json:"infer_deps"`
+
+	// InventoryFile is the path to the file that records the executions
+	// that have been applied, to find the ones that the configuration no
+	// longer generates. Defaults to inventory.json in the session repo.
+	InventoryFile string `json:"inventory_file"`
+
+	// LockFile is the path to the file that records hashes of the module
+	// sources. Defaults to astro.lock in the same directory as the config
+	// file.
+	LockFile string `json:"lock_file"`
+
+	// MaxOutputInMemory is the number of bytes of the stdout and of the
+	// stderr of each Terraform command that are kept in memory. Larger
+	// output is moved to a file in the session repo. Defaults to 1 MiB.
+	MaxOutputInMemory int `json:"max_output_in_memory"`
+
 	// Modules is a list of Terraform modules.
 	Modules []Module
 
+	// Notifications is a list of webhooks that are sent a summary of the
+	// results of every plan, apply and destroy.
+	Notifications []Notification
+
+	// OPAPath is the path to the OPA binary that evaluates Policies.
+	// Defaults to opa in the PATH.
+	OPAPath string `json:"opa_path"`
+
+	// Overrides is a list of configuration that applies to all executions
+	// with matching variable values, regardless of module.
+	Overrides []Override
+
+	// Parallelism is the maximum number of executions that run at the
+	// same time. Defaults to 10.
+	Parallelism int
+
+	// PlanParallelism is the maximum number of executions that are
+	// planned at the same time, if it is not Parallelism, e.g. to plan
+	// faster than applies are allowed to run.
+	PlanParallelism int `json:"plan_parallelism"`
+
+	// PlanUseGraph plans executions after the executions they depend on,
+	// like apply does, instead of planning all of them at once.
+	PlanUseGraph bool `json:"plan_use_graph"`
+
+	// PluginCache configures the directory that Terraform caches
+	// providers in.
+	PluginCache PluginCache `json:"plugin_cache"`
+
+	// Policies are Rego policies that every plan is checked against.
+	Policies []Policy
+
+	// Preflight configures the checks that are made before plans, applies
+	// and destroys, e.g. whether astro may run as root.
+	Preflight *Preflight
+
+	// Profiles are named presets of variable values, Terraform
+	// configuration and parallelism, selected with --profile.
+	Profiles map[string]Profile
+
+	// Reports contains configuration for plan reports.
+	Reports Reports
+
+	// RequireCleanTree refuses to apply if the module directories have
+	// uncommitted changes, or if the checked out commit has not been pushed
+	// to a remote branch. The commit SHA is recorded in the session.
+	RequireCleanTree bool `json:"require_clean_tree"`
+
+	// Retry, if set, retries Terraform commands that fail with a
+	// transient error.
+	Retry *Retry
+
+	// Risk, if set, scores the risk of the changes in plans, and can
+	// require approval for, or block, applies above thresholds.
+	Risk *Risk
+
 	// SessionRepoDir is the path to the directory where astro
 	// will create the .astro session repo that stores log files and
 	// plans during a session. Defaults to the same directory as the config
 	// file.
 	SessionRepoDir string `json:"session_repo_dir"`
 
+	// State, if set, keeps the history of sessions, the run lock and the
+	// stats of each run in a backend that outlives the machine astro runs
+	// on, e.g. an ephemeral CI container.
+	State *State
+
+	// StalePlanPolicy is what apply does with saved plans that are stale,
+	// because the state or module source changed since they were made, or
+	// they are older than StalePlanMaxAge: "refuse", "warn" or "replan".
+	// Defaults to "refuse".
+	StalePlanPolicy string `json:"stale_plan_policy"`
+
+	// StalePlanMaxAge is the duration after which saved plans are stale,
+	// e.g. "4h". Plans do not go stale with age if it's not set.
+	StalePlanMaxAge string `json:"stale_plan_max_age"`
+
+	// Suppressions hide known-noisy changes from the output of plans.
+	Suppressions []Suppression
+
 	// TerraformCodeRoot is the path to the root of the Terraform code for this
 	// Project. Defaults to the same directory as the config file.
 	TerraformCodeRoot string `json:"terraform_code_root"`
@@ -49,10 +188,40 @@ type Project struct {
 	// configuration is used when executing Terraform. Modules can
 	// override this configuration with their own.
 	TerraformDefaults Terraform `json:"terraform"`
+
+	// Variables are computed variables that every module has, unless it
+	// has a variable with the same name, e.g. a naming convention.
+	Variables []Variable
+
+	// VaultPath is the path to the Vault CLI that resolves the vault:
+	// references of secret variables. Defaults to vault in the PATH.
+	VaultPath string `json:"vault_path"`
 }
 
 // Validate checks the project configuration is good.
 func (conf *Project) Validate() (errs error) {
+	if conf.Parallelism < 0 {
+		errs = multierror.Append(errs, errors.New("parallelism cannot be negative"))
+	}
+	if conf.PlanParallelism < 0 {
+		errs = multierror.Append(errs, errors.New("plan_parallelism cannot be negative"))
+	}
+	if conf.ApplyParallelism < 0 {
+		errs = multierror.Append(errs, errors.New("apply_parallelism cannot be negative"))
+	}
+	if conf.MaxOutputInMemory < 0 {
+		errs = multierror.Append(errs, errors.New("max_output_in_memory cannot be negative"))
+	}
+	switch conf.StalePlanPolicy {
+	case "", StalePlanRefuse, StalePlanWarn, StalePlanReplan:
+	default:
+		errs = multierror.Append(errs, fmt.Errorf("stale_plan_policy must be one of %s, %s or %s", StalePlanRefuse, StalePlanWarn, StalePlanReplan))
+	}
+	if conf.StalePlanMaxAge != "" {
+		if _, err := time.ParseDuration(conf.StalePlanMaxAge); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("stale_plan_max_age: %v", err))
+		}
+	}
 	if err := conf.TerraformDefaults.Validate(); err != nil {
 		errs = multierror.Append(errs, fmt.Errorf("TerraformDefaults: %v", err))
 	}
@@ -61,6 +230,91 @@ func (conf *Project) Validate() (errs error) {
 			errs = multierror.Append(errs, fmt.Errorf("module[%v]: %v", moduleConf.Name, err))
 		}
 	}
+	for _, variable := range conf.Variables {
+		if !variable.IsComputed() {
+			errs = multierror.Append(errs, fmt.Errorf("variables[%v]: only computed variables can be set for the project", variable.Name))
+		} else if err := variable.Validate(); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("variables[%v]: %v", variable.Name, err))
+		}
+	}
+	for i, override := range conf.Overrides {
+		if err := override.Validate(); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("override[%d]: %v", i, err))
+		}
+	}
+	for i, policy := range conf.Policies {
+		if err := policy.Validate(); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("policies[%d]: %v", i, err))
+		}
+	}
+	for i, suppression := range conf.Suppressions {
+		if err := suppression.Validate(); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("suppressions[%d]: %v", i, err))
+		}
+	}
+	if err := conf.validateGroups(); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+	if len(conf.Profiles) > 0 {
+		variables := map[string]Variable{}
+		for _, moduleConf := range conf.Modules {
+			for _, variable := range moduleConf.Variables {
+				if variable.IsComputed() {
+					continue
+				}
+				// like flags, the values of all modules are allowed
+				variable.Values = append(variables[variable.Name].Values, variable.Values...)
+				variables[variable.Name] = variable
+			}
+		}
+		names := make([]string, 0, len(conf.Profiles))
+		for name := range conf.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			profile := conf.Profiles[name]
+			if err := profile.Validate(variables); err != nil {
+				errs = multierror.Append(errs, fmt.Errorf("profiles[%s]: %v", name, err))
+			}
+		}
+	}
+	if err := conf.ExitCodes.Validate(); err != nil {
+		errs = multierror.Append(errs, fmt.Errorf("exit_codes: %v", err))
+	}
+	if err := conf.PluginCache.Validate(); err != nil {
+		errs = multierror.Append(errs, fmt.Errorf("plugin_cache: %v", err))
+	}
+	if conf.Preflight != nil {
+		if err := conf.Preflight.Validate(); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("preflight: %v", err))
+		}
+	}
+	if conf.Retry != nil {
+		if err := conf.Retry.Validate(); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("retry: %v", err))
+		}
+	}
+	if conf.Risk != nil {
+		if err := conf.Risk.Validate(); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("risk: %v", err))
+		}
+	}
+	if conf.State != nil {
+		if err := conf.State.Validate(); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("state: %v", err))
+		}
+	}
+	if conf.Reports.Upload != nil {
+		if err := conf.Reports.Upload.Validate(); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("reports.upload: %v", err))
+		}
+	}
+	for i, notification := range conf.Notifications {
+		if err := notification.Validate(); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("notifications[%d]: %v", i, err))
+		}
+	}
 	for _, hook := range conf.Hooks.Startup {
 		if err := hook.Validate(); err != nil {
 			errs = multierror.Append(errs, fmt.Errorf("startup Hook: %v", err))
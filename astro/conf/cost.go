@@ -0,0 +1,29 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+// Cost configures estimating how much the changes in plans cost, with
+// Infracost.
+type Cost struct {
+	// Path is the path to the infracost binary. Defaults to infracost in
+	// the PATH.
+	Path string
+
+	// UsageFile is the path to an Infracost usage file, with estimates
+	// of usage-based costs such as data transfer.
+	UsageFile string `json:"usage_file"`
+}
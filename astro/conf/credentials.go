@@ -0,0 +1,194 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Credentials holds configuration for the credentials that Terraform should
+// use when running an execution. Credentials are resolved by astro into
+// environment variables for the Terraform process.
+type Credentials struct {
+	// AWS configures an IAM role that astro assumes before running
+	// Terraform.
+	AWS *AWSCredentials `json:"aws"`
+	// Azure configures the Azure service principal or workload identity
+	// used by the azurerm provider and backend.
+	Azure *AzureCredentials
+	// Env is a map of environment variables to set, e.g. AWS_PROFILE.
+	// Values may contain variable placeholders, e.g. "{{.environment}}".
+	// They take precedence over the variables set by Azure and GCP.
+	Env map[string]string
+	// GCP configures the Google Cloud credentials used by the google
+	// provider and gcs backend.
+	GCP *GCPCredentials `json:"gcp"`
+}
+
+// AWSCredentials configures an IAM role that astro assumes with STS, using
+// the AWS CLI, before running Terraform. The temporary credentials are set
+// in the environment of Terraform, and cached for the rest of the session.
+// RoleARN and ExternalID may contain variable placeholders.
+type AWSCredentials struct {
+	RoleARN    string `json:"role_arn"`
+	ExternalID string `json:"external_id"`
+	// SessionDuration is how long the credentials are valid for, e.g.
+	// "2h", between 15 minutes and 12 hours. Defaults to the STS default
+	// of 1 hour.
+	SessionDuration string `json:"session_duration"`
+}
+
+// Duration returns the session duration, or 0 if it's not set.
+func (conf *AWSCredentials) Duration() time.Duration {
+	// Validate ensures this parses
+	duration, _ := time.ParseDuration(conf.SessionDuration)
+	return duration
+}
+
+// Validate checks the AWS credentials configuration is good.
+func (conf *AWSCredentials) Validate() error {
+	if conf.RoleARN == "" {
+		return errors.New("role_arn cannot be empty")
+	}
+	if conf.SessionDuration != "" {
+		duration, err := time.ParseDuration(conf.SessionDuration)
+		if err != nil {
+			return fmt.Errorf("session_duration: %v", err)
+		}
+		if duration < 15*time.Minute || duration > 12*time.Hour {
+			return errors.New("session_duration must be between 15m and 12h")
+		}
+	}
+	return nil
+}
+
+// AzureCredentials configures an Azure service principal, authenticated
+// either with a client secret or with workload identity (OIDC). Values may
+// contain variable placeholders.
+type AzureCredentials struct {
+	TenantID       string `json:"tenant_id"`
+	SubscriptionID string `json:"subscription_id"`
+	ClientID       string `json:"client_id"`
+	// ClientSecretEnv is the name of the environment variable that holds
+	// the client secret, so that it does not have to be in the config.
+	ClientSecretEnv string `json:"client_secret_env"`
+	// UseOIDC authenticates with workload identity federation instead of
+	// a client secret.
+	UseOIDC bool `json:"use_oidc"`
+	// OIDCTokenFile is the path to the federated token, e.g. as mounted
+	// by AKS workload identity.
+	OIDCTokenFile string `json:"oidc_token_file"`
+}
+
+// GCPCredentials configures the Google Cloud project and the service
+// account used by Terraform. Values may contain variable placeholders.
+type GCPCredentials struct {
+	Project string
+	Region  string
+	// CredentialsFile is the path to a service account key or external
+	// account file. Application default credentials are used if it's not
+	// set.
+	CredentialsFile string `json:"credentials_file"`
+	// ImpersonateServiceAccount is the email of a service account to
+	// impersonate, using the credentials above.
+	ImpersonateServiceAccount string `json:"impersonate_service_account"`
+}
+
+// Environment returns the environment variables for these credentials.
+func (conf Credentials) Environment() map[string]string {
+	env := make(map[string]string)
+	if conf.Azure != nil {
+		conf.Azure.addEnvironment(env)
+	}
+	if conf.GCP != nil {
+		conf.GCP.addEnvironment(env)
+	}
+	for key, val := range conf.Env {
+		env[key] = val
+	}
+	return env
+}
+
+// Merge returns a copy of these credentials with the values from other
+// applied on top. Azure and GCP configuration from other replaces this
+// configuration entirely.
+func (conf Credentials) Merge(other Credentials) Credentials {
+	merged := Credentials{
+		AWS:   conf.AWS,
+		Azure: conf.Azure,
+		Env:   make(map[string]string),
+		GCP:   conf.GCP,
+	}
+	for key, val := range conf.Env {
+		merged.Env[key] = val
+	}
+	for key, val := range other.Env {
+		merged.Env[key] = val
+	}
+	if other.AWS != nil {
+		merged.AWS = other.AWS
+	}
+	if other.Azure != nil {
+		merged.Azure = other.Azure
+	}
+	if other.GCP != nil {
+		merged.GCP = other.GCP
+	}
+	return merged
+}
+
+// Validate checks the credentials configuration is good.
+func (conf Credentials) Validate() error {
+	if conf.AWS != nil {
+		if err := conf.AWS.Validate(); err != nil {
+			return fmt.Errorf("aws: %v", err)
+		}
+	}
+	if conf.Azure != nil && conf.Azure.UseOIDC && conf.Azure.ClientSecretEnv != "" {
+		return errors.New("azure: use_oidc and client_secret_env cannot both be set")
+	}
+	return nil
+}
+
+func (conf *AzureCredentials) addEnvironment(env map[string]string) {
+	setIfNotEmpty(env, "ARM_TENANT_ID", conf.TenantID)
+	setIfNotEmpty(env, "ARM_SUBSCRIPTION_ID", conf.SubscriptionID)
+	setIfNotEmpty(env, "ARM_CLIENT_ID", conf.ClientID)
+	if conf.ClientSecretEnv != "" {
+		env["ARM_CLIENT_SECRET"] = os.Getenv(conf.ClientSecretEnv)
+	}
+	if conf.UseOIDC {
+		env["ARM_USE_OIDC"] = "true"
+		setIfNotEmpty(env, "ARM_OIDC_TOKEN_FILE_PATH", conf.OIDCTokenFile)
+	}
+}
+
+func (conf *GCPCredentials) addEnvironment(env map[string]string) {
+	setIfNotEmpty(env, "GOOGLE_PROJECT", conf.Project)
+	setIfNotEmpty(env, "GOOGLE_REGION", conf.Region)
+	setIfNotEmpty(env, "GOOGLE_APPLICATION_CREDENTIALS", conf.CredentialsFile)
+	setIfNotEmpty(env, "GOOGLE_IMPERSONATE_SERVICE_ACCOUNT", conf.ImpersonateServiceAccount)
+}
+
+func setIfNotEmpty(env map[string]string, key, val string) {
+	if val != "" {
+		env[key] = val
+	}
+}
@@ -24,4 +24,9 @@ type Dependency struct {
 	// dependency to a specific execution. If this is nil, and the module has
 	// many different possible executions, we'll depend on all of them.
 	Variables map[string]string
+	// Outputs is an optional map of variables of this module to outputs of
+	// the dependency, e.g. vpc_id: vpc_id. The outputs are read from the
+	// state of the dependency and passed to Terraform as the variables,
+	// so the dependency must narrow down to a single execution.
+	Outputs map[string]string
 }
@@ -0,0 +1,79 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import "fmt"
+
+// Categories of results that the exit code of the CLI can be configured
+// for. When the results fall in more than one, the first one in this
+// order decides the exit code.
+const (
+	// ResultsFailed is when executions failed. Defaults to 1.
+	ResultsFailed = "error"
+	// ResultsDrifted is when plans detecting drift found some. Defaults
+	// to 2, like terraform plan -detailed-exitcode when there are changes.
+	ResultsDrifted = "drift"
+	// ResultsChanged is when plans have changes. Defaults to 0.
+	ResultsChanged = "changes"
+	// ResultsSkipped is when hooks skipped executions. Defaults to 0.
+	ResultsSkipped = "skipped"
+)
+
+// ExitCodes configures the exit code of the CLI for each category of
+// results. Categories that are not set keep their default.
+type ExitCodes struct {
+	Error   *int
+	Drift   *int
+	Changes *int
+	Skipped *int
+}
+
+// Code returns the exit code for a category of results.
+func (conf ExitCodes) Code(category string) int {
+	code, defaultCode := conf.codes()[category], 0
+	switch category {
+	case ResultsFailed:
+		defaultCode = 1
+	case ResultsDrifted:
+		defaultCode = 2
+	}
+	if code == nil {
+		return defaultCode
+	}
+	return *code
+}
+
+// codes returns the configured exit codes by category.
+func (conf ExitCodes) codes() map[string]*int {
+	return map[string]*int{
+		ResultsFailed:  conf.Error,
+		ResultsDrifted: conf.Drift,
+		ResultsChanged: conf.Changes,
+		ResultsSkipped: conf.Skipped,
+	}
+}
+
+// Validate checks the exit codes can be exited with.
+func (conf ExitCodes) Validate() error {
+	codes := conf.codes()
+	for _, category := range []string{ResultsFailed, ResultsDrifted, ResultsChanged, ResultsSkipped} {
+		if code := codes[category]; code != nil && (*code < 0 || *code > 255) {
+			return fmt.Errorf("%s: exit code must be between 0 and 255", category)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ForEach replicates a module once per item, each with its own variables,
+// e.g. for one execution per tenant or per cluster. Key is the variable
+// that identifies the items: it becomes a variable of the module whose
+// values are the keys of the items, so it is part of the execution IDs and
+// can be filtered on like any other.
+type ForEach struct {
+	Key string `json:"key"`
+	// Items are the variables of each item, in order. In the config, they
+	// are either a map from the key of each item to its variables, or a
+	// list of variables that each include the key.
+	Items []map[string]string `json:"items"`
+}
+
+// UnmarshalJSON reads items from either a map or a list. Items from a map
+// are sorted by key, and the key is added to their variables.
+func (f *ForEach) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Key   string          `json:"key"`
+		Items json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	f.Key = raw.Key
+	f.Items = nil
+
+	if len(raw.Items) == 0 || string(raw.Items) == "null" {
+		return nil
+	}
+
+	if raw.Items[0] == '[' {
+		return json.Unmarshal(raw.Items, &f.Items)
+	}
+
+	var byKey map[string]map[string]string
+	if err := json.Unmarshal(raw.Items, &byKey); err != nil {
+		return fmt.Errorf("foreach items must be a map or a list: %v", err)
+	}
+
+	var keys []string
+	for key := range byKey {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		item := map[string]string{}
+		for name, val := range byKey[key] {
+			item[name] = val
+		}
+		item[f.Key] = key
+		f.Items = append(f.Items, item)
+	}
+
+	return nil
+}
+
+// Keys returns the key of every item, in order.
+func (f *ForEach) Keys() []string {
+	var keys []string
+	for _, item := range f.Items {
+		keys = append(keys, item[f.Key])
+	}
+	return keys
+}
+
+// Item returns the variables of the item with the key, or nil if there is
+// no such item.
+func (f *ForEach) Item(key string) map[string]string {
+	for _, item := range f.Items {
+		if item[f.Key] == key {
+			return item
+		}
+	}
+	return nil
+}
+
+// Validate checks the foreach configuration is good.
+func (f *ForEach) Validate() error {
+	if f.Key == "" {
+		return errors.New("key cannot be empty")
+	}
+	if len(f.Items) == 0 {
+		return errors.New("items cannot be empty")
+	}
+	seen := map[string]bool{}
+	for i, item := range f.Items {
+		key := item[f.Key]
+		if key == "" {
+			return fmt.Errorf("item %d has no value for %v", i, f.Key)
+		}
+		if seen[key] {
+			return fmt.Errorf("duplicate item: %v", key)
+		}
+		seen[key] = true
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// Group is a concurrency group: modules that set it as their group share
+// its parallelism, e.g. modules that use the same API rate limit.
+type Group struct {
+	// Parallelism is the maximum number of executions of the modules in
+	// the group that run at the same time. There is no limit other than
+	// the project's if it's not set.
+	Parallelism int
+}
+
+// Validate checks the group configuration is good.
+func (conf *Group) Validate() error {
+	if conf.Parallelism < 0 {
+		return errors.New("parallelism cannot be negative")
+	}
+	return nil
+}
+
+// validateGroups checks the groups, and that the group of every module is
+// one of them.
+func (conf *Project) validateGroups() (errs error) {
+	names := make([]string, 0, len(conf.Groups))
+	for name := range conf.Groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		group := conf.Groups[name]
+		if err := group.Validate(); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("groups[%s]: %v", name, err))
+		}
+	}
+
+	for _, moduleConf := range conf.Modules {
+		if moduleConf.Group == "" {
+			continue
+		}
+		if _, ok := conf.Groups[moduleConf.Group]; !ok {
+			errs = multierror.Append(errs, fmt.Errorf("module[%v]: group %q is not one of the groups", moduleConf.Name, moduleConf.Group))
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,266 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/hcl/hcl/parser"
+	"github.com/hashicorp/hcl/hcl/token"
+)
+
+// localsBlock is the name of the block of an HCL config that defines
+// values that the rest of it can refer to.
+const localsBlock = "locals"
+
+// matches a reference to a local value, e.g. "${local.region}"
+var reLocalReference = regexp.MustCompile(`\$\{local\.([A-Za-z_][A-Za-z0-9_-]*)\}`)
+
+// HCLDocument converts the HCL config in data to the YAML document of the
+// same config, which can be read into target, e.g. a *Project. It also
+// returns the lines of the values of the document in data, so that
+// problems with them can be reported against the HCL.
+//
+// Keys are the same as in YAML. Repeated blocks fill lists, e.g.
+// "module" blocks are the items of "modules", and the label of a block is
+// its name, e.g. module "app" {}. Strings can refer to the values of the
+// "locals" block with ${local.name}.
+func HCLDocument(data []byte, target interface{}) ([]byte, SourceLines, error) {
+	file, err := parser.Parse(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c := &hclConverter{
+		lines:  SourceLines{},
+		locals: map[string]interface{}{},
+	}
+
+	list, _ := file.Node.(*ast.ObjectList)
+	if list == nil {
+		list = &ast.ObjectList{}
+	}
+
+	// Locals can be defined anywhere, but are read first so that every
+	// value can refer to them
+	config := &ast.ObjectList{}
+	for _, item := range list.Items {
+		if hclKey(item.Keys[0]) == localsBlock {
+			c.readLocals(item)
+			continue
+		}
+		config.Add(item)
+	}
+
+	document := c.object(config, reflect.TypeOf(target), "")
+	if len(c.errs) > 0 {
+		return nil, nil, SortSchemaErrors(c.errs)
+	}
+
+	yamlBytes, err := yaml.Marshal(document)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return yamlBytes, c.lines, nil
+}
+
+// hclConverter converts the nodes of an HCL config to the values of a
+// YAML document.
+type hclConverter struct {
+	lines  SourceLines
+	locals map[string]interface{}
+	errs   []*SchemaError
+}
+
+// report records a problem with the value at path, on line.
+func (c *hclConverter) report(line int, path string, format string, args ...interface{}) {
+	c.errs = append(c.errs, &SchemaError{
+		Line:    line,
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// readLocals reads the values of a locals block. Locals can refer to the
+// locals defined before them.
+func (c *hclConverter) readLocals(item *ast.ObjectItem) {
+	block, ok := item.Val.(*ast.ObjectType)
+	if !ok || len(item.Keys) > 1 {
+		c.report(item.Pos().Line, localsBlock, "expected a block without a label")
+		return
+	}
+	for _, local := range block.List.Items {
+		name := hclKey(local.Keys[0])
+		path := joinPath("local", name)
+		c.locals[name] = c.value(local.Val, nil, path)
+	}
+}
+
+// object converts the items of a block or map to a map, for a value of
+// type t.
+func (c *hclConverter) object(list *ast.ObjectList, t reflect.Type, path string) map[string]interface{} {
+	t = derefType(t)
+
+	var fields map[string]reflect.Type
+	if t != nil && t.Kind() == reflect.Struct {
+		fields, _ = schemaFields(t)
+	}
+
+	result := map[string]interface{}{}
+	for _, item := range list.Items {
+		key := hclKey(item.Keys[0])
+		labels := item.Keys[1:]
+		line := item.Pos().Line
+
+		// Blocks of a list are its items
+		if block, ok := item.Val.(*ast.ObjectType); ok && !item.Assign.IsValid() && fields != nil {
+			if name, fieldType := listField(fields, key); fieldType != nil {
+				items, _ := result[name].([]interface{})
+				itemPath := fmt.Sprintf("%s[%d]", joinPath(path, name), len(items))
+				c.lines[strings.ToLower(itemPath)] = line
+
+				value := c.object(block.List, fieldType.Elem(), itemPath)
+				switch len(labels) {
+				case 0:
+				case 1:
+					value["name"] = hclKey(labels[0])
+					c.lines[strings.ToLower(joinPath(itemPath, "name"))] = line
+				default:
+					c.report(line, itemPath, "expected at most one label")
+				}
+
+				result[name] = append(items, value)
+				continue
+			}
+		}
+
+		keyPath := joinPath(path, key)
+		if len(labels) > 0 {
+			c.report(line, keyPath, "unexpected label; only blocks of lists, e.g. module, have one")
+			continue
+		}
+		if _, ok := result[key]; ok {
+			c.report(line, keyPath, "duplicate key")
+			continue
+		}
+
+		var valueType reflect.Type
+		if fields != nil {
+			valueType = fields[strings.ToLower(key)]
+		} else if t != nil && t.Kind() == reflect.Map {
+			valueType = t.Elem()
+		}
+
+		c.lines[strings.ToLower(keyPath)] = line
+		result[key] = c.value(item.Val, valueType, keyPath)
+	}
+
+	return result
+}
+
+// value converts a node to a value, for a value of type t.
+func (c *hclConverter) value(node ast.Node, t reflect.Type, path string) interface{} {
+	switch n := node.(type) {
+	case *ast.LiteralType:
+		value := n.Token.Value()
+		if s, ok := value.(string); ok && n.Token.Type != token.IDENT {
+			return c.interpolate(s, n.Pos().Line, path)
+		}
+		return value
+
+	case *ast.ListType:
+		var elemType reflect.Type
+		if t = derefType(t); t != nil && t.Kind() == reflect.Slice {
+			elemType = t.Elem()
+		}
+		values := []interface{}{}
+		for i, elem := range n.List {
+			elemPath := fmt.Sprintf("%s[%d]", path, i)
+			c.lines[strings.ToLower(elemPath)] = elem.Pos().Line
+			values = append(values, c.value(elem, elemType, elemPath))
+		}
+		return values
+
+	case *ast.ObjectType:
+		return c.object(n.List, t, path)
+	}
+
+	c.report(node.Pos().Line, path, "unsupported value")
+	return nil
+}
+
+// interpolate replaces the references to locals in s with their values.
+// A string that is only a reference is replaced with the value itself,
+// so that locals can be lists or maps.
+func (c *hclConverter) interpolate(s string, line int, path string) interface{} {
+	if match := reLocalReference.FindStringSubmatch(s); match != nil && match[0] == s {
+		value, ok := c.locals[match[1]]
+		if !ok {
+			c.report(line, path, "undefined local %v", match[1])
+		}
+		return value
+	}
+
+	return reLocalReference.ReplaceAllStringFunc(s, func(reference string) string {
+		name := reLocalReference.FindStringSubmatch(reference)[1]
+		value, ok := c.locals[name]
+		if !ok {
+			c.report(line, path, "undefined local %v", name)
+			return reference
+		}
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			c.report(line, path, "local %v is not a string, so it can only be the whole value", name)
+			return reference
+		}
+		return fmt.Sprintf("%v", value)
+	})
+}
+
+// listField returns the name and type of the list field that the blocks
+// named key are the items of: the field named key, or its plural.
+func listField(fields map[string]reflect.Type, key string) (string, reflect.Type) {
+	key = strings.ToLower(key)
+	for _, name := range []string{key, key + "s", strings.TrimSuffix(key, "y") + "ies"} {
+		if t := derefType(fields[name]); t != nil && t.Kind() == reflect.Slice {
+			return name, t
+		}
+	}
+	return "", nil
+}
+
+// hclKey returns the text of a key or label.
+func hclKey(key *ast.ObjectKey) string {
+	if s, ok := key.Token.Value().(string); ok {
+		return s
+	}
+	return key.Token.Text
+}
+
+// derefType returns the type that t points to, if it is a pointer.
+func derefType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
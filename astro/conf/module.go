@@ -19,7 +19,10 @@ package conf
 import (
 	"errors"
 	"fmt"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/uber/astro/astro/utils"
 
@@ -28,15 +31,60 @@ import (
 
 // Module is the static configuration of a Terraform module.
 type Module struct {
+	// Credentials configures the credentials Terraform uses for this module.
+	Credentials Credentials
 	// Deps is a list of Terraform modules that need to be run before this one
 	// can run.
 	Deps []Dependency
+	// Docs is a list of links to documentation about the module, e.g.
+	// its README or runbook, that are shown when an execution fails.
+	Docs []string
+	// DisableInput passes -input=false to Terraform plan and apply, so
+	// that Terraform fails instead of prompting for missing variables.
+	DisableInput bool `json:"disable_input"`
+	// Env is a map of environment variables to set for Terraform, e.g.
+	// AWS_PROFILE. Values can refer to the execution's variables, e.g.
+	// "{{.environment}}-admin". The environment of the credentials takes
+	// precedence.
+	Env map[string]string
+	// ForEach, if set, replicates the module once per item, with the
+	// variables of the item. When the config is loaded, ApplyForEach
+	// adds its key to Variables.
+	ForEach *ForEach `json:"foreach"`
+	// Group is the name of the concurrency group of the module, one of
+	// the groups of the project, whose parallelism its executions share
+	// with those of the other modules in the group.
+	Group string
 	// Hooks contains the module-specific hooks that can run.
 	Hooks ModuleHooks
+	// Labels are arbitrary metadata about the module, e.g. the team that
+	// owns it, that executions can be filtered on.
+	Labels map[string]string
 	// Name is a unique name for this Terraform module.
 	Name string
+	// OnErrorMessage is shown when an execution fails, e.g. with what to
+	// do about common failures or who to contact.
+	OnErrorMessage string `json:"on_error_message"`
+	// Owners are the teams or people responsible for the module, which
+	// astro modules list shows.
+	Owners []string
+	// Overrides is the list of project overrides that may apply to
+	// executions of this module. Users cannot set this; instead they should
+	// set it on the project configuration.
+	Overrides []Override `json:"-"`
+	// Parallelism is the maximum number of executions of this module that
+	// run at the same time. There is no limit other than the project's
+	// if it's not set.
+	Parallelism int
 	// Path is the path to the module, relative to the code root.
 	Path string
+	// Preconditions are checks that must pass before an execution of the
+	// module is planned or applied. If one fails, the execution is
+	// blocked with the reason, rather than run.
+	Preconditions []Precondition
+	// Priority orders the executions that are waiting to run: those of
+	// modules with a higher priority start first. Defaults to 0.
+	Priority int
 	// Remote is the Terraform remote for this module.
 	Remote Remote
 	// TerraformCodeRoot is the base path to the Terraform code. Users cannot
@@ -48,6 +96,83 @@ type Module struct {
 	// Variables is a list of Terraform variables and possible values that this
 	// module accepts.
 	Variables []Variable
+	// VarFiles is a list of Terraform variable files, relative to the module
+	// path, to pass to Terraform. They can refer to the execution's
+	// variables, e.g. "vars/{{.environment}}.tfvars".
+	VarFiles []string `json:"var_files"`
+	// Workspaces, if set, is a glob pattern (e.g. "*" or "customer-*"). When
+	// planning, every existing Terraform workspace that matches it is
+	// planned, instead of only the default workspace.
+	Workspaces string
+}
+
+// Environment returns the environment variables to set for Terraform: Env,
+// with the environment of the credentials on top.
+func (m *Module) Environment() map[string]string {
+	env := make(map[string]string)
+	for key, val := range m.Env {
+		env[key] = val
+	}
+	for key, val := range m.Credentials.Environment() {
+		env[key] = val
+	}
+	return env
+}
+
+// ApplyForEach adds the key of ForEach to the variables of the module,
+// with the keys of the items as its values. It fails if the key is
+// already a variable of the module.
+func (m *Module) ApplyForEach() error {
+	if m.ForEach == nil {
+		return nil
+	}
+	for _, variable := range m.Variables {
+		if variable.Name == m.ForEach.Key {
+			return fmt.Errorf("foreach key %v is also a variable of the module", variable.Name)
+		}
+	}
+	m.Variables = append(m.Variables, Variable{
+		Name:   m.ForEach.Key,
+		Values: m.ForEach.Keys(),
+	})
+	return nil
+}
+
+// ApplyVariablesFrom adds the project variables that the module doesn't
+// have a variable for. They come first, so that the computed variables
+// of the module can use them.
+func (m *Module) ApplyVariablesFrom(projectVariables []Variable) {
+	var variables []Variable
+	for _, variable := range projectVariables {
+		if !m.hasVariable(variable.Name) {
+			variables = append(variables, variable)
+		}
+	}
+	m.Variables = append(variables, m.Variables...)
+}
+
+// hasVariable returns true if the module has a variable with the name.
+func (m *Module) hasVariable(name string) bool {
+	for _, variable := range m.Variables {
+		if variable.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrorGuidance returns what is shown after the output of a failed
+// execution of the module: OnErrorMessage, followed by a line for each
+// link in Docs. It is empty if neither is set.
+func (m *Module) ErrorGuidance() string {
+	var lines []string
+	if message := strings.TrimSpace(m.OnErrorMessage); message != "" {
+		lines = append(lines, message)
+	}
+	for _, link := range m.Docs {
+		lines = append(lines, "See: "+link)
+	}
+	return strings.Join(lines, "\n")
 }
 
 // Validate validates whether the configuration is good. Returns any validation
@@ -66,9 +191,42 @@ func (m *Module) Validate() (errs error) {
 			errs = multierror.Append(errs, fmt.Errorf("module directory does not exist: %v", fullModulePath))
 		}
 	}
+	if m.Parallelism < 0 {
+		errs = multierror.Append(errs, errors.New("parallelism cannot be negative"))
+	}
+	if m.Workspaces != "" {
+		if _, err := path.Match(m.Workspaces, ""); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("invalid workspaces pattern: %v", err))
+		}
+	}
+	for _, variable := range m.Variables {
+		if err := variable.Validate(); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("variables[%v]: %v", variable.Name, err))
+		}
+	}
+	for i, precondition := range m.Preconditions {
+		if err := precondition.Validate(); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("preconditions[%d]: %v", i, err))
+			continue
+		}
+		if precondition.Dependency != "" && !m.dependsOn(precondition.Dependency) {
+			errs = multierror.Append(errs, fmt.Errorf("preconditions[%d]: %v is not a dependency of the module", i, precondition.Dependency))
+		}
+	}
+	if err := m.validateDepOutputs(); err != nil {
+		errs = multierror.Append(errs, err)
+	}
 	if err := m.Terraform.Validate(); err != nil {
 		errs = multierror.Append(errs, fmt.Errorf("terraform: %v", err))
 	}
+	if err := m.Credentials.Validate(); err != nil {
+		errs = multierror.Append(errs, fmt.Errorf("credentials: %v", err))
+	}
+	if m.ForEach != nil {
+		if err := m.validateForEach(); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("foreach: %v", err))
+		}
+	}
 	for _, hook := range m.Hooks.PreModuleRun {
 		if err := hook.Validate(); err != nil {
 			errs = multierror.Append(errs, fmt.Errorf("PreModuleRun Hook: %v", err))
@@ -77,3 +235,61 @@ func (m *Module) Validate() (errs error) {
 
 	return errs
 }
+
+// dependsOn returns whether the module is one of the deps of the module.
+func (m *Module) dependsOn(module string) bool {
+	for _, dep := range m.Deps {
+		if dep.Module == module {
+			return true
+		}
+	}
+	return false
+}
+
+// validateDepOutputs checks that the outputs of the dependencies are
+// passed to variables that only they set.
+func (m *Module) validateDepOutputs() (errs error) {
+	wired := map[string]string{}
+	for _, variable := range m.Variables {
+		wired[variable.Name] = "a variable of the module"
+	}
+	for i, dep := range m.Deps {
+		var names []string
+		for name := range dep.Outputs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			output := dep.Outputs[name]
+			if name == "" || output == "" {
+				errs = multierror.Append(errs, fmt.Errorf("deps[%d].outputs: variable and output names cannot be empty", i))
+				continue
+			}
+			if other, ok := wired[name]; ok {
+				errs = multierror.Append(errs, fmt.Errorf("deps[%d].outputs.%v: %v is also %v", i, name, name, other))
+				continue
+			}
+			wired[name] = fmt.Sprintf("an output of %v", dep.Module)
+		}
+	}
+	return errs
+}
+
+// validateForEach checks that ForEach is good, and that the variables of
+// its items don't clash with the other variables of the module.
+func (m *Module) validateForEach() error {
+	if err := m.ForEach.Validate(); err != nil {
+		return err
+	}
+	for _, variable := range m.Variables {
+		if variable.Name == m.ForEach.Key {
+			continue
+		}
+		for _, item := range m.ForEach.Items {
+			if _, ok := item[variable.Name]; ok {
+				return fmt.Errorf("item %v sets %v, which is also a variable of the module", item[m.ForEach.Key], variable.Name)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+)
+
+// Notification formats.
+const (
+	// NotificationSlack posts a message to a Slack incoming webhook.
+	NotificationSlack = "slack"
+	// NotificationWebhook posts a JSON summary of the results.
+	NotificationWebhook = "webhook"
+)
+
+// NotificationEvents are the commands that can send notifications.
+var NotificationEvents = []string{"plan", "apply", "destroy"}
+
+// Notification configures a webhook that is sent a summary of the results
+// of a command when it completes.
+type Notification struct {
+	// URL is the URL of the webhook. Environment variables in it, e.g.
+	// ${SLACK_WEBHOOK_URL}, are expanded, so that it doesn't have to be
+	// committed.
+	URL string
+
+	// Format is the format of the request, either "slack" or "webhook".
+	// Defaults to "webhook".
+	Format string
+
+	// Events is the commands that send a notification. Defaults to all of
+	// NotificationEvents.
+	Events []string
+
+	// Template is a Go text/template for the text of the message, which
+	// is executed with the summary of the results. Defaults to a line for
+	// the command and a line for each execution.
+	Template string
+}
+
+// Notifies returns whether the notification is sent for the command.
+func (conf *Notification) Notifies(event string) bool {
+	if conf.Events == nil {
+		return true
+	}
+	for _, e := range conf.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the notification configuration is good.
+func (conf *Notification) Validate() error {
+	if conf.URL == "" {
+		return errors.New("missing url")
+	}
+	// URLs with environment variables are checked when they are expanded
+	if !strings.Contains(conf.URL, "$") {
+		u, err := url.Parse(conf.URL)
+		if err != nil {
+			return fmt.Errorf("invalid url: %v", err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("unsupported url scheme %q; must be http or https", u.Scheme)
+		}
+	}
+	switch conf.Format {
+	case "", NotificationSlack, NotificationWebhook:
+	default:
+		return fmt.Errorf("unsupported format %q; must be %s or %s", conf.Format, NotificationSlack, NotificationWebhook)
+	}
+	for _, event := range conf.Events {
+		valid := false
+		for _, e := range NotificationEvents {
+			valid = valid || e == event
+		}
+		if !valid {
+			return fmt.Errorf("unsupported event %q; must be one of %s", event, strings.Join(NotificationEvents, ", "))
+		}
+	}
+	if conf.Template != "" {
+		if _, err := template.New("notification").Parse(conf.Template); err != nil {
+			return fmt.Errorf("invalid template: %v", err)
+		}
+	}
+	return nil
+}
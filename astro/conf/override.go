@@ -0,0 +1,58 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Override is configuration that is applied to every execution, across all
+// modules, whose variables match the values in When. This allows settings
+// that depend on a variable value (e.g. credentials and state buckets for
+// environment=prod) to be declared in one place.
+type Override struct {
+	// When is a map of variable names to values. All of them must match the
+	// variables of an execution for the override to apply.
+	When map[string]string
+	// Credentials are merged into the credentials of matching executions.
+	Credentials Credentials
+	// Remote is merged into the remote configuration of matching executions.
+	Remote Remote
+}
+
+// Matches returns whether the override applies to an execution with the
+// specified variables.
+func (o *Override) Matches(variables map[string]string) bool {
+	for key, val := range o.When {
+		if v, ok := variables[key]; !ok || v != val {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate checks the override configuration is good.
+func (o *Override) Validate() error {
+	if len(o.When) == 0 {
+		return errors.New("when cannot be empty")
+	}
+	if err := o.Credentials.Validate(); err != nil {
+		return fmt.Errorf("credentials: %v", err)
+	}
+	return nil
+}
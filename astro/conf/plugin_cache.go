@@ -0,0 +1,116 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Scopes of the plugin cache, which are the runs that share it.
+const (
+	PluginCacheProject = "project"
+	PluginCacheSession = "session"
+	PluginCacheGlobal  = "global"
+)
+
+// sizeUnits are the units of sizes, e.g. "5GB", from the largest.
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"TB", 1e12},
+	{"GB", 1e9},
+	{"MB", 1e6},
+	{"KB", 1e3},
+	{"B", 1},
+}
+
+// PluginCache configures the directory that Terraform caches providers in,
+// TF_PLUGIN_CACHE_DIR, so that executions don't download the same
+// providers again. It is not used if TF_PLUGIN_CACHE_DIR is set.
+type PluginCache struct {
+	// Scope is which runs share the cache: "project", the default, for
+	// all the sessions of the project, in the session repository;
+	// "session" for the executions of a session, and the cache is removed
+	// when it ends; "global" for all the projects of the user, in
+	// ~/.astro/plugin-cache.
+	Scope string
+
+	// Dir is the path of the cache, e.g. a directory that every CI job on
+	// a machine shares. It overrides Scope.
+	Dir string
+
+	// MaxSize is the size the cache is pruned to when a session ends,
+	// e.g. "5GB", by removing the providers that were added to it the
+	// longest ago. The cache can grow as large as it needs if it isn't
+	// set.
+	MaxSize string `json:"max_size"`
+}
+
+// MaxBytes returns the size the cache is pruned to, or 0 if it isn't
+// pruned.
+func (conf *PluginCache) MaxBytes() int64 {
+	// Validate ensures this parses
+	size, _ := ParseSize(conf.MaxSize)
+	return size
+}
+
+// Validate checks the plugin cache configuration is good.
+func (conf *PluginCache) Validate() error {
+	switch conf.Scope {
+	case "", PluginCacheProject, PluginCacheSession, PluginCacheGlobal:
+	default:
+		return fmt.Errorf("scope must be one of %s, %s or %s", PluginCacheProject, PluginCacheSession, PluginCacheGlobal)
+	}
+	if conf.Dir != "" && conf.Scope != "" {
+		return fmt.Errorf("only one of scope and dir can be set")
+	}
+	if conf.MaxSize != "" {
+		if _, err := ParseSize(conf.MaxSize); err != nil {
+			return fmt.Errorf("invalid max_size: %v", err)
+		}
+	}
+	return nil
+}
+
+// ParseSize parses a size in bytes, with an optional unit: B, KB, MB, GB
+// or TB, in powers of 1000, e.g. "500MB" or "1.5GB". An empty size is 0.
+func ParseSize(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	number, unit := strings.TrimSpace(strings.ToUpper(s)), int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(number, u.suffix) {
+			number, unit = strings.TrimSpace(strings.TrimSuffix(number, u.suffix)), u.bytes
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a size, e.g. 500MB or 5GB", s)
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("%q is negative", s)
+	}
+
+	return int64(n * float64(unit)), nil
+}
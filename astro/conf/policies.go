@@ -0,0 +1,75 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Policy enforcement levels.
+const (
+	// PolicyError fails the executions whose plans violate the policy.
+	// This is the default.
+	PolicyError = "error"
+	// PolicyWarn only warns about the violations.
+	PolicyWarn = "warn"
+)
+
+// Policy configures Rego policies that plans are checked against with
+// OPA.
+type Policy struct {
+	// Path is the path to a Rego file, or to a directory of them.
+	Path string
+
+	// Query is the Rego query that returns the violations of the policy,
+	// as a set of messages. Defaults to "data.terraform.deny".
+	Query string
+
+	// Enforcement is what happens to executions whose plans violate the
+	// policy: "error" or "warn". Defaults to "error".
+	Enforcement string
+
+	// Modules, if set, limits the policy to these modules.
+	Modules []string
+}
+
+// Applies returns whether the policy applies to executions of the module.
+func (conf *Policy) Applies(module string) bool {
+	if conf.Modules == nil {
+		return true
+	}
+	for _, m := range conf.Modules {
+		if m == module {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the policy configuration is good.
+func (conf *Policy) Validate() error {
+	if conf.Path == "" {
+		return errors.New("missing path")
+	}
+	switch conf.Enforcement {
+	case "", PolicyError, PolicyWarn:
+	default:
+		return fmt.Errorf("enforcement must be one of %s or %s", PolicyError, PolicyWarn)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/uber/astro/astro/filter"
+)
+
+// Precondition is a check that must pass before an execution of a module
+// runs, e.g. that a service it deploys into is healthy. Exactly one of
+// HTTP, Command and Output is set. They can refer to the execution's
+// variables, e.g. "https://{{.environment}}.example.com/health".
+type Precondition struct {
+	// Name describes the check in the reason the execution is blocked.
+	// Defaults to the check itself.
+	Name string
+
+	// HTTP is a URL that must respond to a GET request with a 2xx status.
+	HTTP string
+
+	// Command is a shell-like command that must exit with 0.
+	Command string
+
+	// Output is a filter expression that the outputs of Dependency must
+	// match, e.g. `status == "ready"`. See the filter package for the
+	// syntax.
+	Output string
+
+	// Dependency is the module, one of the deps of the module, whose
+	// outputs Output is matched against.
+	Dependency string
+}
+
+// String returns the name of the precondition, or the check itself.
+func (p Precondition) String() string {
+	switch {
+	case p.Name != "":
+		return p.Name
+	case p.HTTP != "":
+		return "GET " + p.HTTP
+	case p.Command != "":
+		return p.Command
+	}
+	return fmt.Sprintf("outputs of %s match %s", p.Dependency, p.Output)
+}
+
+// Validate checks the precondition is good.
+func (p *Precondition) Validate() error {
+	set := 0
+	for _, check := range []string{p.HTTP, p.Command, p.Output} {
+		if check != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return errors.New("exactly one of http, command and output must be set")
+	}
+
+	// Checks with placeholders are only checked once they are filled in
+	if p.HTTP != "" && !strings.Contains(p.HTTP, "{{") {
+		u, err := url.Parse(p.HTTP)
+		if err != nil {
+			return fmt.Errorf("invalid http URL: %v", err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("http URL must be http or https: %v", p.HTTP)
+		}
+	}
+
+	if p.Output != "" {
+		if p.Dependency == "" {
+			return errors.New("output needs the dependency whose outputs it matches")
+		}
+		if _, err := filter.Parse(p.Output); err != nil && !strings.Contains(p.Output, "{{") {
+			return fmt.Errorf("invalid output expression: %v", err)
+		}
+	} else if p.Dependency != "" {
+		return errors.New("dependency is only used with output")
+	}
+
+	return nil
+}
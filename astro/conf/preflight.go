@@ -0,0 +1,118 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// What the preflight checks do when they find a problem.
+const (
+	// PreflightAllow ignores the problem.
+	PreflightAllow = "allow"
+	// PreflightWarn shows a warning and carries on.
+	PreflightWarn = "warn"
+	// PreflightRefuse fails before anything runs.
+	PreflightRefuse = "refuse"
+)
+
+// DefaultAdminIdentities match the ARNs of AWS identities that are
+// usually administrators: the root user of an account, and users and roles
+// with "admin" in their name. They are used when a preflight config
+// doesn't set admin_identities.
+var DefaultAdminIdentities = []string{
+	`:root$`,
+	`(?i)admin`,
+}
+
+// Preflight configures the checks that are made before astro plans,
+// applies or destroys, to nudge teams towards running it with scoped
+// credentials.
+type Preflight struct {
+	// Root is what to do when astro runs as root. Defaults to warn.
+	Root string
+
+	// AdminCredentials is what to do when the AWS identity astro runs
+	// with, from `aws sts get-caller-identity`, is an administrator.
+	// Defaults to allow, which skips the check.
+	AdminCredentials string `json:"admin_credentials"`
+
+	// AdminIdentities are regular expressions matched against the ARN of
+	// the AWS identity. It is an administrator if one matches. Defaults to
+	// DefaultAdminIdentities.
+	AdminIdentities []string `json:"admin_identities"`
+}
+
+// RootAction returns what to do when astro runs as root.
+func (conf *Preflight) RootAction() string {
+	if conf == nil || conf.Root == "" {
+		return PreflightWarn
+	}
+	return conf.Root
+}
+
+// AdminCredentialsAction returns what to do when the AWS identity is an
+// administrator.
+func (conf *Preflight) AdminCredentialsAction() string {
+	if conf == nil || conf.AdminCredentials == "" {
+		return PreflightAllow
+	}
+	return conf.AdminCredentials
+}
+
+// IsAdmin returns whether the ARN of an AWS identity is an
+// administrator.
+func (conf *Preflight) IsAdmin(arn string) bool {
+	patterns := DefaultAdminIdentities
+	if conf != nil && len(conf.AdminIdentities) > 0 {
+		patterns = conf.AdminIdentities
+	}
+	for _, pattern := range patterns {
+		// Validate ensures the patterns compile
+		if re, err := regexp.Compile(pattern); err == nil && re.MatchString(arn) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the preflight configuration is good.
+func (conf *Preflight) Validate() error {
+	if err := validatePreflightAction("root", conf.Root); err != nil {
+		return err
+	}
+	if err := validatePreflightAction("admin_credentials", conf.AdminCredentials); err != nil {
+		return err
+	}
+	for _, pattern := range conf.AdminIdentities {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid admin identity pattern %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+// validatePreflightAction checks the action of a preflight check is one of
+// the known actions.
+func validatePreflightAction(name, action string) error {
+	switch action {
+	case "", PreflightAllow, PreflightWarn, PreflightRefuse:
+		return nil
+	}
+	return fmt.Errorf("%s must be one of %s, %s or %s", name, PreflightAllow, PreflightWarn, PreflightRefuse)
+}
@@ -0,0 +1,114 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/uber/astro/astro/utils"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// Profile is a named set of presets, e.g. for an environment, that is
+// selected with --profile instead of passing the same flags every time.
+type Profile struct {
+	// Variables are the values of user variables, as if they were passed
+	// as flags. Flags that are passed take precedence.
+	Variables map[string]string
+
+	// Terraform overrides the default Terraform configuration of the
+	// project. Modules that set their own keep it.
+	Terraform Terraform
+
+	// Parallelism overrides the parallelism of the project, including
+	// plan_parallelism and apply_parallelism.
+	Parallelism int
+}
+
+// Validate checks the profile configuration is good. variables are the
+// variables of the modules of the project, by name.
+func (conf *Profile) Validate(variables map[string]Variable) (errs error) {
+	if conf.Parallelism < 0 {
+		errs = multierror.Append(errs, errors.New("parallelism cannot be negative"))
+	}
+
+	names := make([]string, 0, len(conf.Variables))
+	for name := range conf.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		variable, ok := variables[name]
+		if !ok {
+			errs = multierror.Append(errs, fmt.Errorf("no module has variable %q", name))
+			continue
+		}
+		if variable.IsFilter() && !utils.StringSliceContains(variable.Values, conf.Variables[name]) {
+			errs = multierror.Append(errs, fmt.Errorf("variable %q: %q is not one of %s", name, conf.Variables[name], strings.Join(variable.Values, ", ")))
+		} else if err := variable.CheckValue(conf.Variables[name]); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// ApplyProfile applies the presets of the named profile to the project
+// configuration. It has to be called before the defaults of the modules
+// are filled in.
+func (conf *Project) ApplyProfile(name string) error {
+	profile, ok := conf.Profiles[name]
+	if !ok {
+		return conf.unknownProfileError(name)
+	}
+
+	terraform := profile.Terraform
+	terraform.ApplyDefaultsFrom(conf.TerraformDefaults)
+	conf.TerraformDefaults = terraform
+
+	// the profile's parallelism is for every command
+	if profile.Parallelism > 0 {
+		conf.Parallelism = profile.Parallelism
+		conf.PlanParallelism = 0
+		conf.ApplyParallelism = 0
+	}
+
+	return nil
+}
+
+// unknownProfileError returns the error for a profile that isn't in the
+// configuration, suggesting the profiles that are.
+func (conf *Project) unknownProfileError(name string) error {
+	var names []string
+	for profile := range conf.Profiles {
+		names = append(names, profile)
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		return fmt.Errorf("unknown profile %q; the config has no profiles", name)
+	}
+	if suggestions := utils.ClosestMatches(name, names); suggestions != nil {
+		return fmt.Errorf("unknown profile %q; did you mean %s?", name, strings.Join(suggestions, " or "))
+	}
+	return fmt.Errorf("unknown profile %q; profiles: %s", name, strings.Join(names, ", "))
+}
@@ -0,0 +1,81 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Reports contains configuration for reports that astro generates.
+type Reports struct {
+	// Upload, if set, uploads a report of every plan so that it can be
+	// shared with a link.
+	Upload *ReportUpload
+}
+
+// ReportUpload configures where plan reports are uploaded.
+type ReportUpload struct {
+	// URL is the location reports are uploaded to, e.g.
+	// s3://bucket/prefix or gs://bucket/prefix.
+	URL string
+
+	// Format is the format of the report, either "html" or "markdown".
+	// Defaults to "html".
+	Format string
+
+	// ExpiresIn is how long the link to the report is valid for, e.g.
+	// "24h". Defaults to 24 hours.
+	ExpiresIn string `json:"expires_in"`
+}
+
+// Expiry returns how long the link to the report is valid for.
+func (conf *ReportUpload) Expiry() time.Duration {
+	if conf.ExpiresIn == "" {
+		return 24 * time.Hour
+	}
+	// Validate ensures this parses
+	d, _ := time.ParseDuration(conf.ExpiresIn)
+	return d
+}
+
+// Validate checks the report upload configuration is good.
+func (conf *ReportUpload) Validate() error {
+	if conf.URL == "" {
+		return errors.New("missing url")
+	}
+	u, err := url.Parse(conf.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %v", err)
+	}
+	if u.Scheme != "s3" && u.Scheme != "gs" {
+		return fmt.Errorf("unsupported url scheme %q; must be s3 or gs", u.Scheme)
+	}
+	switch conf.Format {
+	case "", "html", "markdown":
+	default:
+		return fmt.Errorf("unsupported format %q; must be html or markdown", conf.Format)
+	}
+	if conf.ExpiresIn != "" {
+		if _, err := time.ParseDuration(conf.ExpiresIn); err != nil {
+			return fmt.Errorf("invalid expires_in: %v", err)
+		}
+	}
+	return nil
+}
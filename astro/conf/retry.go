@@ -0,0 +1,137 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// DefaultRetryableErrors match the errors of common transient failures:
+// throttling and network errors. They are used when a retry policy doesn't
+// set retryable_errors.
+//
+// Errors of AWS eventual consistency, e.g. `NoSuchEntity` or
+// `InvalidInstanceID\.NotFound`, are not retried by default, since they are
+// also what a resource that really doesn't exist fails with. Add them to
+// retryable_errors to opt in.
+var DefaultRetryableErrors = []string{
+	`RequestError: send request failed`,
+	`(?i)connection reset by peer`,
+	`(?i)TLS handshake timeout`,
+	`(?i)i/o timeout`,
+	`Throttling`,
+	`Rate exceeded`,
+	`RequestLimitExceeded`,
+}
+
+// Retry configures how Terraform commands that fail with a transient error
+// are retried.
+type Retry struct {
+	// MaxAttempts is the maximum number of times a command is run,
+	// including the first. Defaults to 3.
+	MaxAttempts int `json:"max_attempts"`
+
+	// Backoff is how long to wait before the first retry, e.g. "10s". It
+	// doubles after every retry, up to MaxBackoff. Defaults to 10 seconds.
+	Backoff string
+
+	// MaxBackoff is the longest to wait between retries. Defaults to 2
+	// minutes.
+	MaxBackoff string `json:"max_backoff"`
+
+	// RetryableErrors are regular expressions matched against the error
+	// output of a failed command. It is only retried if one matches.
+	// Defaults to DefaultRetryableErrors.
+	RetryableErrors []string `json:"retryable_errors"`
+
+	// StateLocks also retries commands that fail because another run
+	// holds the lock of the state, e.g. after waiting for lock_timeout,
+	// so that executions queue for the lock with backoff.
+	StateLocks bool `json:"state_locks"`
+}
+
+// Attempts returns the maximum number of times a command is run.
+func (conf *Retry) Attempts() int {
+	if conf.MaxAttempts == 0 {
+		return 3
+	}
+	return conf.MaxAttempts
+}
+
+// Delay returns how long to wait after the failed attempt number attempt,
+// counting from 1, before the next one.
+func (conf *Retry) Delay(attempt int) time.Duration {
+	// Validate ensures these parse
+	delay, _ := time.ParseDuration(conf.Backoff)
+	if conf.Backoff == "" {
+		delay = 10 * time.Second
+	}
+	maxDelay, _ := time.ParseDuration(conf.MaxBackoff)
+	if conf.MaxBackoff == "" {
+		maxDelay = 2 * time.Minute
+	}
+
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
+
+// Retryable returns whether output, the error output of a failed command,
+// matches one of the retryable errors.
+func (conf *Retry) Retryable(output string) bool {
+	patterns := conf.RetryableErrors
+	if patterns == nil {
+		patterns = DefaultRetryableErrors
+	}
+	for _, pattern := range patterns {
+		// Validate ensures this compiles
+		if re, err := regexp.Compile(pattern); err == nil && re.MatchString(output) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the retry configuration is good.
+func (conf *Retry) Validate() error {
+	if conf.MaxAttempts < 0 {
+		return errors.New("max_attempts cannot be negative")
+	}
+	if conf.Backoff != "" {
+		if _, err := time.ParseDuration(conf.Backoff); err != nil {
+			return fmt.Errorf("invalid backoff: %v", err)
+		}
+	}
+	if conf.MaxBackoff != "" {
+		if _, err := time.ParseDuration(conf.MaxBackoff); err != nil {
+			return fmt.Errorf("invalid max_backoff: %v", err)
+		}
+	}
+	for _, pattern := range conf.RetryableErrors {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid retryable error %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"path"
+)
+
+// DefaultSensitiveResources match the types of the resources that control
+// access and networking, whose changes add to the risk of a plan. They are
+// used when a risk config doesn't set sensitive_resources.
+var DefaultSensitiveResources = []string{
+	"aws_iam_*",
+	"aws_security_group*",
+	"aws_vpc*",
+	"aws_subnet",
+	"aws_route*",
+	"aws_network_acl*",
+	"google_*_iam_*",
+	"google_compute_firewall",
+	"google_compute_network",
+	"google_compute_subnetwork",
+	"azurerm_role_*",
+	"azurerm_network_security_*",
+	"azurerm_virtual_network*",
+	"azurerm_subnet*",
+}
+
+// Risk configures scoring the risk of the changes in plans, and gating
+// applies on it, so that low-risk changes are applied automatically while
+// risky ones wait for a person. Thresholds that are 0 are not enforced.
+type Risk struct {
+	// ApproveAbove is the risk score above which applies need approval,
+	// e.g. with apply --approve-risk or --interactive.
+	ApproveAbove int `json:"approve_above"`
+
+	// BlockAbove is the risk score above which executions are never
+	// applied by astro.
+	BlockAbove int `json:"block_above"`
+
+	// SensitiveResources are glob patterns of the types of resources whose
+	// changes add to the risk, e.g. "aws_iam_*". Defaults to
+	// DefaultSensitiveResources.
+	SensitiveResources []string `json:"sensitive_resources"`
+}
+
+// Gates returns whether applies are gated on the risk of their plans.
+func (conf *Risk) Gates() bool {
+	return conf != nil && (conf.ApproveAbove > 0 || conf.BlockAbove > 0)
+}
+
+// SensitiveResourcePatterns returns the patterns of the types of the
+// sensitive resources.
+func (conf *Risk) SensitiveResourcePatterns() []string {
+	if conf == nil || conf.SensitiveResources == nil {
+		return DefaultSensitiveResources
+	}
+	return conf.SensitiveResources
+}
+
+// Validate checks the risk configuration is good.
+func (conf *Risk) Validate() error {
+	if conf.ApproveAbove < 0 || conf.BlockAbove < 0 {
+		return errors.New("thresholds cannot be negative")
+	}
+	if conf.ApproveAbove > 0 && conf.BlockAbove > 0 && conf.ApproveAbove >= conf.BlockAbove {
+		return errors.New("approve_above must be lower than block_above")
+	}
+	for _, pattern := range conf.SensitiveResources {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid sensitive resource pattern %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
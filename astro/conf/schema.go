@@ -0,0 +1,400 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/uber/astro/astro/utils"
+
+	"github.com/ghodss/yaml"
+	"github.com/hashicorp/go-multierror"
+)
+
+// SchemaError is a problem with a value in a config file, e.g. a key that
+// doesn't exist or a value of the wrong type.
+type SchemaError struct {
+	// Line is the line of the file the value is on, or 0 if it isn't
+	// known.
+	Line int
+	// Path is where the value is in the config, e.g. "modules[0].path".
+	Path    string
+	Message string
+}
+
+// Error is the error message, so this satisfies the error interface.
+func (e *SchemaError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d: %s: %s", e.Line, e.Path, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// SourceLines maps the paths of the keys and list items of a YAML
+// document, e.g. "modules[0].remote", to the lines they are on. Paths are
+// lowercase, as keys of the config are matched regardless of case.
+type SourceLines map[string]int
+
+var (
+	// matches a key at the start of a line, quoted or not, e.g. "name:"
+	reYAMLKey = regexp.MustCompile(`^("[^"]*"|'[^']*'|[^\s#"'{\[][^:#]*?)\s*:(?:\s+(.*))?$`)
+	// matches the indicator of a block scalar, e.g. "|" or ">-"
+	reYAMLBlockScalar = regexp.MustCompile(`^[|>][-+0-9]*\s*(#.*)?$`)
+)
+
+// NewSourceLines indexes the lines of a YAML document. It understands
+// the block style that configs are written in: flow collections, e.g.
+// "[dev, prod]", and the contents of block scalars are not indexed.
+func NewSourceLines(data []byte) SourceLines {
+	type entry struct {
+		indent int
+		path   string
+		item   bool
+		items  int
+	}
+
+	lines := SourceLines{}
+	var stack []*entry
+	// blockIndent is the indentation of the key whose value is a block
+	// scalar, while its lines are skipped
+	blockIndent := -1
+
+	for i, line := range strings.Split(string(data), "\n") {
+		content := strings.TrimLeft(line, " ")
+		indent := len(line) - len(content)
+		content = strings.TrimRight(content, " \t\r")
+
+		if blockIndent >= 0 {
+			if content == "" || indent > blockIndent {
+				continue
+			}
+			blockIndent = -1
+		}
+		if content == "" || strings.HasPrefix(content, "#") || content == "---" {
+			continue
+		}
+
+		item := content == "-" || strings.HasPrefix(content, "- ")
+
+		// pop the entries this line isn't nested in; list items can be
+		// at the same indentation as the key of the list
+		for len(stack) > 0 {
+			top := stack[len(stack)-1]
+			if top.indent < indent || (top.indent == indent && item && !top.item) {
+				break
+			}
+			stack = stack[:len(stack)-1]
+		}
+
+		var parent *entry
+		if len(stack) > 0 {
+			parent = stack[len(stack)-1]
+		} else {
+			parent = &entry{indent: -1}
+		}
+
+		if item {
+			path := fmt.Sprintf("%s[%d]", parent.path, parent.items)
+			parent.items++
+			lines[path] = i + 1
+			stack = append(stack, &entry{indent: indent, path: path, item: true})
+
+			// the item may be a map that starts on the same line
+			rest := strings.TrimPrefix(content, "-")
+			content = strings.TrimLeft(rest, " ")
+			indent += 1 + len(rest) - len(content)
+			parent = stack[len(stack)-1]
+		}
+
+		match := reYAMLKey.FindStringSubmatch(content)
+		if match == nil {
+			continue
+		}
+
+		key := strings.ToLower(strings.Trim(match[1], `"'`))
+		path := key
+		if parent.path != "" {
+			path = parent.path + "." + key
+		}
+		lines[path] = i + 1
+		stack = append(stack, &entry{indent: indent, path: path})
+
+		if reYAMLBlockScalar.MatchString(match[2]) {
+			blockIndent = indent
+		}
+	}
+
+	return lines
+}
+
+// Line returns the line the value at path is on, or 0 if it isn't known.
+func (s SourceLines) Line(path string) int {
+	return s[strings.ToLower(path)]
+}
+
+// Errorf returns a SchemaError for the value at path.
+func (s SourceLines) Errorf(path string, format string, args ...interface{}) *SchemaError {
+	return &SchemaError{
+		Line:    s.Line(path),
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// CheckSchema checks that the YAML document in data has the structure of
+// target, e.g. a *Project: that every key is a field of it, and that
+// every value has the right type. It returns a *SchemaError for each
+// problem, with the line it is on, so that mistakes in configs are
+// reported before they are unmarshalled, or silently ignored.
+func CheckSchema(data []byte, target interface{}) error {
+	errs, err := SchemaErrors(data, target)
+	if err != nil {
+		return err
+	}
+	return SortSchemaErrors(errs)
+}
+
+// SchemaErrors is like CheckSchema, but returns the problems so that they
+// can be reported together with others. The error is set if data isn't
+// YAML at all.
+func SchemaErrors(data []byte, target interface{}) ([]*SchemaError, error) {
+	return NewSourceLines(data).SchemaErrors(data, target)
+}
+
+// SchemaErrors is like the SchemaErrors function, for a YAML document
+// that was converted from another format, e.g. by HCLDocument, whose
+// lines are s.
+func (s SourceLines) SchemaErrors(data []byte, target interface{}) ([]*SchemaError, error) {
+	var document interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+
+	checker := &schemaChecker{lines: s}
+	checker.check(document, reflect.TypeOf(target), "")
+
+	return checker.errs, nil
+}
+
+// SortSchemaErrors returns the errors sorted by the line they are on.
+// Errors whose line isn't known are last.
+func SortSchemaErrors(errs []*SchemaError) error {
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].Line == 0 || errs[j].Line == 0 {
+			return errs[j].Line == 0 && errs[i].Line != 0
+		}
+		return errs[i].Line < errs[j].Line
+	})
+
+	var result error
+	for _, err := range errs {
+		result = multierror.Append(result, err)
+	}
+	return result
+}
+
+// schemaChecker collects the problems with a YAML document.
+type schemaChecker struct {
+	lines SourceLines
+	errs  []*SchemaError
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// check checks that value, from the YAML document, can be unmarshalled
+// into a value of type t.
+func (c *schemaChecker) check(value interface{}, t reflect.Type, path string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	// nulls are zero values
+	if value == nil {
+		return
+	}
+
+	// Types that unmarshal themselves, e.g. versions, are checked by
+	// unmarshalling the value
+	if reflect.PtrTo(t).Implements(jsonUnmarshalerType) {
+		data, err := json.Marshal(value)
+		if err == nil {
+			err = reflect.New(t).Interface().(json.Unmarshaler).UnmarshalJSON(data)
+		}
+		if err != nil {
+			c.report(path, "invalid value: %v", describeUnmarshalError(err))
+		}
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			c.report(path, "expected a map, not %s", describeValue(value))
+			return
+		}
+		fields, names := schemaFields(t)
+		for _, key := range sortedKeys(values) {
+			field, ok := fields[strings.ToLower(key)]
+			if !ok {
+				message := "unknown key"
+				if suggestions := utils.ClosestMatches(strings.ToLower(key), names); suggestions != nil {
+					message += fmt.Sprintf("; did you mean %s?", strings.Join(suggestions, " or "))
+				}
+				c.report(joinPath(path, key), message)
+				continue
+			}
+			c.check(values[key], field, joinPath(path, key))
+		}
+
+	case reflect.Map:
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			c.report(path, "expected a map, not %s", describeValue(value))
+			return
+		}
+		for _, key := range sortedKeys(values) {
+			c.check(values[key], t.Elem(), joinPath(path, key))
+		}
+
+	case reflect.Slice:
+		values, ok := value.([]interface{})
+		if !ok {
+			c.report(path, "expected a list, not %s", describeValue(value))
+			return
+		}
+		for i, v := range values {
+			c.check(v, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+		}
+
+	case reflect.String:
+		// numbers and booleans are converted to strings
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			c.report(path, "expected a string, not %s", describeValue(value))
+		}
+
+	case reflect.Bool:
+		if _, ok := value.(bool); !ok {
+			c.report(path, "expected true or false, not %s", describeValue(value))
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if number, ok := value.(float64); !ok || number != float64(int64(number)) {
+			c.report(path, "expected a whole number, not %s", describeValue(value))
+		}
+
+	case reflect.Float32, reflect.Float64:
+		if _, ok := value.(float64); !ok {
+			c.report(path, "expected a number, not %s", describeValue(value))
+		}
+	}
+}
+
+// report records a problem with the value at path.
+func (c *schemaChecker) report(path string, format string, args ...interface{}) {
+	if path == "" {
+		path = "config"
+	}
+	c.errs = append(c.errs, c.lines.Errorf(path, format, args...))
+}
+
+// schemaFields returns the types of the fields of the struct type t that
+// can be set from a config, by lowercase key, and the keys sorted.
+// Fields are named as encoding/json names them.
+func schemaFields(t reflect.Type) (map[string]reflect.Type, []string) {
+	fields := map[string]reflect.Type{}
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Name
+		if tag := field.Tag.Get("json"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			if tagName := strings.Split(tag, ",")[0]; tagName != "" {
+				name = tagName
+			}
+		}
+		name = strings.ToLower(name)
+		fields[name] = field.Type
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return fields, names
+}
+
+// describeValue returns the kind of a value from a YAML document, for
+// error messages.
+func describeValue(value interface{}) string {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return "a map"
+	case []interface{}:
+		return "a list"
+	case bool:
+		return fmt.Sprintf("%v", v)
+	case float64:
+		return fmt.Sprintf("the number %v", v)
+	case string:
+		return fmt.Sprintf("the string %q", v)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// describeUnmarshalError returns a readable description of an error from
+// encoding/json.
+func describeUnmarshalError(err error) string {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		message := fmt.Sprintf("expected a %v, not a %v", typeErr.Type, typeErr.Value)
+		if typeErr.Type.Kind() == reflect.String && typeErr.Value == "number" {
+			message += "; quote it"
+		}
+		return message
+	}
+	return err.Error()
+}
+
+// joinPath returns the path of a key of the map at path.
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// sortedKeys returns the keys of a map, sorted, so that problems are
+// reported in a consistent order.
+func sortedKeys(values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,88 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// Backends that astro's own metadata can be stored in.
+const (
+	// StateBackendFilesystem stores metadata in a directory, e.g. on a
+	// volume that is shared by CI jobs.
+	StateBackendFilesystem = "filesystem"
+	// StateBackendS3 stores metadata in an S3 bucket.
+	StateBackendS3 = "s3"
+	// StateBackendDynamoDB stores metadata in a DynamoDB table.
+	StateBackendDynamoDB = "dynamodb"
+)
+
+// State configures where astro keeps its own metadata, i.e. the history of
+// sessions, the lock that stops applies and destroys from running at the
+// same time, and the stats of each run, so that they outlive the machine
+// astro runs on.
+type State struct {
+	// Backend is where the metadata is stored: "filesystem", "s3" or
+	// "dynamodb".
+	Backend string
+
+	// Path is the directory of the filesystem backend.
+	Path string
+
+	// URL is the location of the S3 backend, e.g. s3://bucket/prefix.
+	URL string
+
+	// Table is the name of the table of the DynamoDB backend. Its
+	// partition key must be a string named "key".
+	Table string
+
+	// Region is the AWS region of the S3 bucket or DynamoDB table.
+	// Defaults to the region the AWS CLI is configured with.
+	Region string
+}
+
+// Validate checks the state configuration is good.
+func (conf *State) Validate() error {
+	switch conf.Backend {
+	case StateBackendFilesystem:
+		if conf.Path == "" {
+			return errors.New("missing path")
+		}
+	case StateBackendS3:
+		if conf.URL == "" {
+			return errors.New("missing url")
+		}
+		u, err := url.Parse(conf.URL)
+		if err != nil {
+			return fmt.Errorf("invalid url: %v", err)
+		}
+		if u.Scheme != "s3" || u.Host == "" {
+			return fmt.Errorf("invalid url %q; must be s3://bucket or s3://bucket/prefix", conf.URL)
+		}
+	case StateBackendDynamoDB:
+		if conf.Table == "" {
+			return errors.New("missing table")
+		}
+	case "":
+		return errors.New("missing backend")
+	default:
+		return fmt.Errorf("unsupported backend %q; must be %s, %s or %s", conf.Backend, StateBackendFilesystem, StateBackendS3, StateBackendDynamoDB)
+	}
+	return nil
+}
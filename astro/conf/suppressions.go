@@ -0,0 +1,60 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+)
+
+// Suppression hides a known-noisy change, such as a perpetual diff of tags
+// or of a computed timestamp, from the output of plans. Resources whose
+// changes are all suppressed are counted separately, and a plan whose
+// changes are all suppressed has no changes.
+type Suppression struct {
+	// Pattern is a regular expression. Changed lines of the plan output
+	// that match it are suppressed; if the line opens a block, e.g.
+	// `~ tags = {`, so is everything in the block.
+	Pattern string
+
+	// Attribute is the path of an attribute whose changes are suppressed,
+	// e.g. "tags.LastModified". Elements of the path can be globs, e.g.
+	// "tags.*", and the attributes nested in it are suppressed too.
+	Attribute string
+
+	// Resource, if set, is a glob that limits the suppression to the
+	// resources whose address matches, e.g. "aws_autoscaling_group.*".
+	Resource string
+}
+
+// Validate checks the suppression configuration is good.
+func (conf *Suppression) Validate() error {
+	if (conf.Pattern == "") == (conf.Attribute == "") {
+		return errors.New("exactly one of pattern and attribute must be set")
+	}
+	if conf.Pattern != "" {
+		if _, err := regexp.Compile(conf.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %v", err)
+		}
+	}
+	if _, err := path.Match(conf.Resource, ""); err != nil {
+		return fmt.Errorf("invalid resource %q: %v", conf.Resource, err)
+	}
+	return nil
+}
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"os/exec"
+	"time"
 
 	"github.com/uber/astro/astro/logger"
 	"github.com/uber/astro/astro/tvm"
@@ -34,30 +35,127 @@ type Terraform struct {
 	// if set, otherwise it will automatically download the version in
 	// Version below.
 	Path string
+	// Flavor is the distribution of Terraform that is looked up in the
+	// PATH and downloaded by tvm: "terraform" (the default) or "opentofu",
+	// whose binary is tofu. It doesn't matter if Path is set.
+	Flavor string
 	// Terraform version to use. If Path is empty, Astro will
 	// download this version automatically.
 	Version *version.Version
+	// VersionConstraint restricts the versions of Terraform that may be
+	// used, e.g. ">= 0.11, < 0.12". If Terraform is not installed, it is
+	// also used to pick the version to install.
+	VersionConstraint string `json:"version_constraint"`
+	// Lockfile is how `terraform init` treats the dependency lock file,
+	// .terraform.lock.hcl. If it is "readonly", init fails instead of
+	// changing the lock file, so that only the provider versions that
+	// were committed are used. Requires Terraform 1.0 or later.
+	Lockfile string
+	// LockTimeout is how long plan, apply and destroy wait for the lock of
+	// the state when another run holds it, e.g. "5m", instead of failing
+	// right away.
+	LockTimeout string `json:"lock_timeout"`
+	// WarnAfter is how long an execution can run Terraform before astro
+	// warns that it may be stuck, e.g. "20m", in the status output and
+	// in notifications. The execution keeps running.
+	WarnAfter string `json:"warn_after"`
+	// KillAfter is how long an execution can run Terraform before it is
+	// interrupted and fails, e.g. "1h".
+	KillAfter string `json:"kill_after"`
+}
+
+// Timeouts returns the durations of WarnAfter and KillAfter, which are 0
+// if they are not set.
+func (conf *Terraform) Timeouts() (warnAfter, killAfter time.Duration) {
+	// Validate ensures these parse
+	if conf.WarnAfter != "" {
+		warnAfter, _ = time.ParseDuration(conf.WarnAfter)
+	}
+	if conf.KillAfter != "" {
+		killAfter, _ = time.ParseDuration(conf.KillAfter)
+	}
+	return warnAfter, killAfter
+}
+
+// LockTimeoutDuration returns the duration of LockTimeout, which is 0 if
+// it is not set.
+func (conf *Terraform) LockTimeoutDuration() time.Duration {
+	// Validate ensures this parses
+	lockTimeout, _ := time.ParseDuration(conf.LockTimeout)
+	return lockTimeout
+}
+
+// TerraformNotFoundError is returned when no Terraform binary is
+// configured, none can be found in the PATH, and no matching version has
+// been installed by tvm.
+type TerraformNotFoundError struct {
+	// Constraint is the version constraint from the configuration, if any.
+	Constraint string
+	// Flavor is the flavor from the configuration.
+	Flavor tvm.Flavor
+}
+
+// Error is the error message, so this satisfies the error interface.
+func (e *TerraformNotFoundError) Error() string {
+	if e.Flavor == tvm.OpenTofu {
+		return "unable to find OpenTofu: tofu is not in the PATH, and neither terraform.path nor terraform.version is set in the config"
+	}
+	return "unable to find Terraform: it is not in the PATH, and neither terraform.path nor terraform.version is set in the config"
+}
+
+// TVMFlavor returns the flavor of Terraform that tvm installs.
+func (conf *Terraform) TVMFlavor() tvm.Flavor {
+	// Validate ensures this parses
+	flavor, err := tvm.ParseFlavor(conf.Flavor)
+	if err != nil {
+		return tvm.Terraform
+	}
+	return flavor
 }
 
 // ApplyDefaultsFrom takes a Terraform struct representation the default
-// configuration and fills in any fields that were not set.
+// configuration and fills in any fields that were not set. A version
+// without a path is downloaded by tvm, rather than using the binary of the
+// default path.
 func (conf *Terraform) ApplyDefaultsFrom(defaultConf Terraform) {
-	if conf.Path == "" {
+	if conf.Path == "" && conf.Version == nil {
 		conf.Path = defaultConf.Path
 	}
 	if conf.Version == nil {
 		conf.Version = defaultConf.Version
 	}
+	if conf.Flavor == "" {
+		conf.Flavor = defaultConf.Flavor
+	}
+	if conf.VersionConstraint == "" {
+		conf.VersionConstraint = defaultConf.VersionConstraint
+	}
+	if conf.Lockfile == "" {
+		conf.Lockfile = defaultConf.Lockfile
+	}
+	if conf.LockTimeout == "" {
+		conf.LockTimeout = defaultConf.LockTimeout
+	}
+	if conf.WarnAfter == "" {
+		conf.WarnAfter = defaultConf.WarnAfter
+	}
+	if conf.KillAfter == "" {
+		conf.KillAfter = defaultConf.KillAfter
+	}
 }
 
 // SetDefaultPath sets the path the Terraform binary from the environment, if
-// it hasn't already been provided in configuration.
+// it hasn't already been provided in configuration, e.g. tofu for OpenTofu.
+// If there is no binary in the PATH, the latest version installed by tvm
+// that matches the version constraint is used instead. If there is none, a
+// *TerraformNotFoundError is returned.
 func (conf *Terraform) SetDefaultPath() error {
 	// If the existing project config doesn't specify a Terraform path,
 	// search for it in the current environment.
-	terraformPath, err := exec.LookPath("terraform")
+	terraformPath, err := exec.LookPath(conf.TVMFlavor().Binary())
 	if err != nil {
-		return err
+		logger.Trace.Printf("conf/terraform: Terraform not found in PATH: %v", err)
+		return conf.setPathFromTVM()
 	}
 
 	logger.Trace.Printf("conf/terraform: setting Terraform path to: %v", terraformPath)
@@ -66,6 +164,29 @@ func (conf *Terraform) SetDefaultPath() error {
 	return nil
 }
 
+// setPathFromTVM sets the path to the latest Terraform version installed
+// by tvm that matches the version constraint.
+func (conf *Terraform) setPathFromTVM() error {
+	repo, err := tvm.NewVersionRepoForCurrentSystem("")
+	if err != nil {
+		return err
+	}
+	repo = repo.ForFlavor(conf.TVMFlavor())
+
+	latest, err := repo.LatestInstalled(conf.VersionConstraint)
+	if err != nil {
+		return fmt.Errorf("invalid version_constraint: %v", err)
+	}
+	if latest == "" {
+		return &TerraformNotFoundError{Constraint: conf.VersionConstraint, Flavor: repo.Flavor()}
+	}
+
+	logger.Trace.Printf("conf/terraform: using %v %v installed by tvm", repo.Flavor().Name(), latest)
+	conf.Path = repo.Path(latest)
+
+	return nil
+}
+
 // SetVersionFromBinary sets the value of the Version field from the binary.
 func (conf *Terraform) SetVersionFromBinary() error {
 	inspectVersion, err := tvm.InspectVersion(conf.Path)
@@ -86,5 +207,45 @@ func (conf *Terraform) Validate() (errs error) {
 	if conf.Version == nil {
 		errs = multierror.Append(errs, errors.New("version is not set"))
 	}
+	if conf.VersionConstraint != "" {
+		constraints, err := version.NewConstraint(conf.VersionConstraint)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("invalid version_constraint: %v", err))
+		} else if conf.Version != nil && !constraints.Check(conf.Version) {
+			errs = multierror.Append(errs, fmt.Errorf("version %v does not satisfy version_constraint %q", conf.Version, conf.VersionConstraint))
+		}
+	}
+	switch conf.Lockfile {
+	case "":
+	case "readonly":
+		if conf.Version != nil && conf.Version.LessThan(version.Must(version.NewVersion("1.0"))) {
+			errs = multierror.Append(errs, fmt.Errorf("lockfile requires Terraform 1.0 or later, not %v", conf.Version))
+		}
+	default:
+		errs = multierror.Append(errs, fmt.Errorf("unsupported lockfile mode %q; must be readonly", conf.Lockfile))
+	}
+	if conf.LockTimeout != "" {
+		if d, err := time.ParseDuration(conf.LockTimeout); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("invalid lock_timeout: %v", err))
+		} else if d < 0 {
+			errs = multierror.Append(errs, errors.New("lock_timeout cannot be negative"))
+		}
+	}
+	for _, timeout := range []struct{ name, value string }{{"warn_after", conf.WarnAfter}, {"kill_after", conf.KillAfter}} {
+		if timeout.value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(timeout.value); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("invalid %s: %v", timeout.name, err))
+		} else if d <= 0 {
+			errs = multierror.Append(errs, fmt.Errorf("%s must be positive", timeout.name))
+		}
+	}
+	if warnAfter, killAfter := conf.Timeouts(); warnAfter > 0 && killAfter > 0 && warnAfter >= killAfter {
+		errs = multierror.Append(errs, fmt.Errorf("warn_after (%v) must be shorter than kill_after (%v)", warnAfter, killAfter))
+	}
+	if _, err := tvm.ParseFlavor(conf.Flavor); err != nil {
+		errs = multierror.Append(errs, err)
+	}
 	return errs
 }
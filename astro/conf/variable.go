@@ -16,6 +16,18 @@
 
 package conf
 
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/uber/astro/astro/utils"
+
+	"github.com/hashicorp/go-multierror"
+)
+
 // Variable represents a variable that can be passed into a
 // Terraform module.
 type Variable struct {
@@ -24,6 +36,123 @@ type Variable struct {
 	// Values is a list of possible values for the variable. A value of nil
 	// means the possible values are unbound.
 	Values []string
+	// AllowedValues restricts the value of a variable that has no Values,
+	// without making it a filter.
+	AllowedValues []string `json:"allowed_values"`
+	// Pattern is a regular expression that the value of the variable must
+	// match.
+	Pattern string
+	// Required is whether a value must be passed. Variables without Values
+	// are required unless this is false, in which case their value is
+	// Default. Variables with Values, which otherwise run for all of their
+	// values, are only required if this is true.
+	Required *bool
+	// Default is the value of the variable when it isn't passed. Setting
+	// it makes the variable optional.
+	Default string
+	// Computed is a template that the value of the variable is derived
+	// from, e.g. "{{.module}}-{{.environment}}", evaluated against the
+	// other variables of the execution and the module name. Computed
+	// variables can't be passed and are not part of the execution ID.
+	Computed string
+	// Secret is whether the value of the variable is a secret, or a
+	// reference to one, e.g. vault:secret/db#password, which is resolved
+	// when the execution runs. Secrets are passed to Terraform in its
+	// environment, are never written to the session, and are not part of
+	// the execution ID.
+	Secret bool
+}
+
+// IsRequired returns true if a value must be passed for the variable.
+func (v *Variable) IsRequired() bool {
+	if v.Required != nil {
+		return *v.Required
+	}
+	return !v.IsFilter() && !v.IsComputed() && v.Default == ""
+}
+
+// IsComputed returns true if the value of the variable is derived from
+// the others, rather than passed.
+func (v *Variable) IsComputed() bool {
+	return v.Computed != ""
+}
+
+// CheckValue returns an *InvalidValueError if value is not one of the
+// allowed values of the variable, or doesn't match its pattern. Values
+// that are not one of Values are not checked here, as they filter out
+// the module instead.
+func (v *Variable) CheckValue(value string) error {
+	if len(v.AllowedValues) > 0 && !utils.StringSliceContains(v.AllowedValues, value) {
+		return &InvalidValueError{Variable: v.Name, Value: value, AllowedValues: v.AllowedValues}
+	}
+	// Validate ensures the pattern compiles
+	if v.Pattern != "" && !regexp.MustCompile(v.Pattern).MatchString(value) {
+		return &InvalidValueError{Variable: v.Name, Value: value, Pattern: v.Pattern}
+	}
+	return nil
+}
+
+// Validate checks the variable configuration is good.
+func (v *Variable) Validate() (errs error) {
+	if len(v.Values) > 0 && len(v.AllowedValues) > 0 {
+		errs = multierror.Append(errs, errors.New("values and allowed_values cannot both be set"))
+	}
+	if v.Pattern != "" {
+		if _, err := regexp.Compile(v.Pattern); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("invalid pattern: %v", err))
+		} else {
+			for _, value := range append(append([]string{}, v.Values...), v.AllowedValues...) {
+				if err := v.CheckValue(value); err != nil {
+					errs = multierror.Append(errs, err)
+				}
+			}
+		}
+	}
+	if v.IsComputed() {
+		if v.IsFilter() || len(v.AllowedValues) > 0 || v.Pattern != "" || v.Required != nil || v.Default != "" || v.Secret {
+			errs = multierror.Append(errs, errors.New("computed variables cannot have values, allowed_values, pattern, required or default, or be secret"))
+		} else if _, err := template.New("").Parse(v.Computed); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("invalid computed template: %v", err))
+		}
+		return errs
+	}
+	if v.Secret && (v.IsFilter() || len(v.AllowedValues) > 0 || v.Pattern != "") {
+		errs = multierror.Append(errs, errors.New("secret variables cannot have values, allowed_values or pattern"))
+	}
+	if v.Default != "" {
+		if v.IsRequired() || v.IsFilter() {
+			errs = multierror.Append(errs, errors.New("default can only be set on variables without values that are not required"))
+		} else if err := v.CheckValue(v.Default); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("default: %v", err))
+		}
+	}
+	return errs
+}
+
+// InvalidValueError is returned when the value passed for a variable is
+// not one of its allowed values, or doesn't match its pattern.
+type InvalidValueError struct {
+	Variable      string
+	Value         string
+	AllowedValues []string
+	Pattern       string
+}
+
+// Error is the error message, so this satisfies the error interface.
+func (e *InvalidValueError) Error() string {
+	return fmt.Sprintf("invalid value %q for variable %s; %s", e.Value, e.Variable, e.Constraint())
+}
+
+// Constraint describes what the value should have been, e.g. to list the
+// allowed values.
+func (e *InvalidValueError) Constraint() string {
+	if len(e.AllowedValues) > 0 {
+		if suggestions := utils.ClosestMatches(e.Value, e.AllowedValues); suggestions != nil {
+			return fmt.Sprintf("did you mean %s? allowed values: %s", strings.Join(suggestions, " or "), strings.Join(e.AllowedValues, ", "))
+		}
+		return fmt.Sprintf("allowed values: %s", strings.Join(e.AllowedValues, ", "))
+	}
+	return fmt.Sprintf("must match %s", e.Pattern)
 }
 
 // IsFilter returns true if the command-line parameter acts as a filter
@@ -26,18 +26,49 @@ import (
 	"github.com/uber/astro/astro/logger"
 
 	"github.com/ghodss/yaml"
+	"github.com/mitchellh/go-homedir"
 )
 
-// NewConfigFromFile parses the configuration in the specified config file
+// NewConfigFromFile parses the configuration in the specified config file,
+// which is HCL if its name ends in .hcl, and YAML otherwise.
 func NewConfigFromFile(configFilePath string) (*conf.Project, error) {
-	yamlBytes, err := os.ReadFile(configFilePath)
+	return NewConfigFromFileWithProfile(configFilePath, "")
+}
+
+// ConfigPresets are applied to the configuration when it is loaded, e.g.
+// from flags.
+type ConfigPresets struct {
+	// Profile is the name of the profile whose presets are applied, if it
+	// is not empty.
+	Profile string
+	// Flavor, if set, overrides the Terraform flavor of the project, e.g.
+	// "opentofu". Modules that set their own keep it.
+	Flavor string
+}
+
+// NewConfigFromFileWithProfile is like NewConfigFromFile, with the presets
+// of the named profile applied, if profile is not empty.
+func NewConfigFromFileWithProfile(configFilePath, profile string) (*conf.Project, error) {
+	return NewConfigFromFileWithPresets(configFilePath, ConfigPresets{Profile: profile})
+}
+
+// NewConfigFromFileWithPresets is like NewConfigFromFile, with the presets
+// applied.
+func NewConfigFromFileWithPresets(configFilePath string, presets ConfigPresets) (*conf.Project, error) {
+	data, err := os.ReadFile(configFilePath)
 	if err != nil {
 		return nil, err
 	}
 
-	config, err := configFromYAML(yamlBytes, filepath.Dir(configFilePath))
+	loader := configLoaderFor(configFilePath)
+	yamlBytes, lines, err := loader.load(data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load YAML from file: %s; %v", configFilePath, err)
+		return nil, fmt.Errorf("failed to load %s from file: %s; %w", loader.name(), configFilePath, err)
+	}
+
+	config, err := configFromDocument(yamlBytes, lines, filepath.Dir(configFilePath), presets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s from file: %s; %w", loader.name(), configFilePath, err)
 	}
 	return config, nil
 }
@@ -68,19 +99,76 @@ func NewProjectFromYAML(yamlBytes []byte) (*Project, error) {
 // configFromYAML takes YAML bytes and returns a Project configuration
 // struct.
 func configFromYAML(yamlBytes []byte, rootPath string) (*conf.Project, error) {
+	return configFromDocument(yamlBytes, conf.NewSourceLines(yamlBytes), rootPath, ConfigPresets{})
+}
+
+// configFromDocument is like configFromYAML, for a YAML document that may
+// have been converted from another format. lines are the lines of its
+// values in the file it was loaded from. The presets are applied.
+func configFromDocument(yamlBytes []byte, lines conf.SourceLines, rootPath string, presets ConfigPresets) (*conf.Project, error) {
 	var config conf.Project
 
-	err := yaml.Unmarshal(yamlBytes, &config)
+	// Check the schema first, so that all of the problems in the file are
+	// reported at once, with their line numbers.
+	problems, err := lines.SchemaErrors(yamlBytes, &config)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := yaml.Unmarshal(yamlBytes, &config); err != nil {
+		if len(problems) > 0 {
+			return nil, conf.SortSchemaErrors(problems)
+		}
+		return nil, err
+	}
+
+	for i, module := range config.Modules {
+		problems = append(problems, checkModuleVariables(module, config.Variables, lines, fmt.Sprintf("modules[%d]", i))...)
+	}
+	// Only the modules of this file have lines for their problems
+	mainModules := len(config.Modules)
+
+	// Config fragments are only read for config loaded from a file
+	fromFile := rootPath != ""
+
 	// Convert rootPath to absolute
 	rootPath, err = filepath.Abs(rootPath)
 	if err != nil {
 		return nil, err
 	}
 
+	// Merge in the config files this one includes. Their modules come
+	// after the modules of this file.
+	if fromFile && len(config.Include) > 0 && len(problems) == 0 {
+		merged, err := mergeIncludes(yamlBytes, rootPath, config.Variables)
+		if err != nil {
+			return nil, err
+		}
+		config = conf.Project{}
+		if err := yaml.Unmarshal(merged, &config); err != nil {
+			return nil, err
+		}
+	}
+
+	// Merge in modules from fragment files
+	if fromFile {
+		if err := mergeConfigFragments(&config, rootPath); err != nil {
+			return nil, err
+		}
+	}
+
+	// Apply the profile before the paths are rewritten and the module
+	// defaults are filled in, so that its Terraform configuration is
+	// treated like the project's.
+	if presets.Profile != "" {
+		if err := config.ApplyProfile(presets.Profile); err != nil {
+			return nil, err
+		}
+	}
+	if presets.Flavor != "" {
+		config.TerraformDefaults.Flavor = presets.Flavor
+	}
+
 	// Rewrite paths to absolute
 	if err := rewriteConfigPaths(rootPath, &config); err != nil {
 		return nil, fmt.Errorf("failed to resolve relative paths in config file: %s; %v", rootPath, err)
@@ -91,6 +179,27 @@ func configFromYAML(yamlBytes []byte, rootPath string) (*conf.Project, error) {
 		return nil, err
 	}
 
+	// Module paths can only be checked once the Terraform code root is
+	// known.
+	if fromFile {
+		for i, module := range config.Modules {
+			var moduleLines conf.SourceLines
+			if i < mainModules {
+				moduleLines = lines
+			}
+			if err := checkModulePath(module, moduleLines, fmt.Sprintf("modules[%d]", i)); err != nil {
+				problems = append(problems, err)
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return nil, conf.SortSchemaErrors(problems)
+	}
+
+	if config.InferDeps {
+		inferDependencies(&config)
+	}
+
 	// Fill in Terraform versions. This has to be done after paths are
 	// rewritten.
 	if err := setTerraformVersionFields(&config); err != nil {
@@ -140,12 +249,27 @@ func setDefaults(config *conf.Project, rootPath string) error {
 		}
 	}
 
+	// The lock file is committed next to the config file, wherever the
+	// sessions are kept
+	if config.LockFile == "" {
+		if rootPath != "" {
+			config.LockFile = filepath.Join(rootPath, "astro.lock")
+		} else {
+			config.LockFile = filepath.Join(cwd, "astro.lock")
+		}
+	}
+
 	// Fill in module defaults
 	for i := range config.Modules {
 		logger.Trace.Printf("config: applying default TerraformCodeRoot: \"%v\"", config.TerraformCodeRoot)
 		config.Modules[i].Hooks.ApplyDefaultsFrom(config.Hooks)
 		config.Modules[i].TerraformCodeRoot = config.TerraformCodeRoot
 		config.Modules[i].Terraform.ApplyDefaultsFrom(config.TerraformDefaults)
+		config.Modules[i].Overrides = config.Overrides
+		if err := config.Modules[i].ApplyForEach(); err != nil {
+			return fmt.Errorf("module %v: %v", config.Modules[i].Name, err)
+		}
+		config.Modules[i].ApplyVariablesFrom(config.Variables)
 	}
 
 	return nil
@@ -172,25 +296,85 @@ func setTerraformVersionFields(config *conf.Project) error {
 // Rewrite relative paths in the config file to be absolute paths.
 func rewriteConfigPaths(rootPath string, config *conf.Project) error {
 	if err := rewriteRelPaths(rootPath, false,
+		&config.InventoryFile,
+		&config.LockFile,
 		&config.SessionRepoDir,
 		&config.TerraformCodeRoot,
 		&config.TerraformDefaults.Path); err != nil {
 		return err
 	}
 
+	// the plugin cache may be in the home directory, e.g. to share it
+	// between projects
+	pluginCacheDir, err := homedir.Expand(config.PluginCache.Dir)
+	if err != nil {
+		return err
+	}
+	config.PluginCache.Dir = pluginCacheDir
+	if err := rewriteRelPaths(rootPath, false, &config.PluginCache.Dir); err != nil {
+		return err
+	}
+
 	if err := rewriteRelPathsInSlices(rootPath, config.Hooks.Startup, config.Hooks.PreModuleRun); err != nil {
 		return err
 	}
 
+	if err := rewriteRelPaths(rootPath, true, &config.OPAPath, &config.AWSCLIPath); err != nil {
+		return err
+	}
+	if config.State != nil {
+		if err := rewriteRelPaths(rootPath, false, &config.State.Path); err != nil {
+			return err
+		}
+	}
+	if config.Cost != nil {
+		if err := rewriteRelPaths(rootPath, true, &config.Cost.Path); err != nil {
+			return err
+		}
+		if err := rewriteRelPaths(rootPath, false, &config.Cost.UsageFile); err != nil {
+			return err
+		}
+	}
+	for i := range config.Policies {
+		if err := rewriteRelPaths(rootPath, false, &config.Policies[i].Path); err != nil {
+			return err
+		}
+	}
+
 	for _, moduleConfig := range config.Modules {
 		if err := rewriteRelPathsInSlices(rootPath, moduleConfig.Hooks.PreModuleRun); err != nil {
 			return err
 		}
+		if err := rewriteCredentialsPaths(rootPath, moduleConfig.Credentials); err != nil {
+			return err
+		}
+	}
+
+	for _, override := range config.Overrides {
+		if err := rewriteCredentialsPaths(rootPath, override.Credentials); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// rewriteCredentialsPaths rewrites the relative paths of credential
+// files, as Terraform does not run in the directory of the config file.
+func rewriteCredentialsPaths(rootPath string, credentials conf.Credentials) error {
+	if credentials.Azure != nil {
+		if err := rewriteRelPaths(rootPath, false, &credentials.Azure.OIDCTokenFile); err != nil {
+			return err
+		}
+	}
+	if credentials.GCP != nil {
+		if err := rewriteRelPaths(rootPath, false, &credentials.GCP.CredentialsFile); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // rewriteRelPaths rewrites all relative paths to be absolute - relative to
 // the specified root dir. If the path is already absolute, it is left
 // untouched. If a path is empty, it is left empty.
@@ -0,0 +1,171 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/utils"
+)
+
+var (
+	// matches the actions in a template, e.g. "{{.environment}}"
+	reTemplateAction = regexp.MustCompile(`\{\{(.*?)\}\}`)
+	// matches the fields an action refers to, e.g. ".environment"
+	reTemplateField = regexp.MustCompile(`(?:^|[\s(|])\.([A-Za-z_][A-Za-z0-9_]*)`)
+)
+
+// templateVariables returns the names of the variables that the
+// placeholders in s refer to.
+func templateVariables(s string) (names []string) {
+	for _, action := range reTemplateAction.FindAllStringSubmatch(s, -1) {
+		for _, field := range reTemplateField.FindAllStringSubmatch(action[1], -1) {
+			names = append(names, field[1])
+		}
+	}
+	return names
+}
+
+// checkModuleVariables returns an error for each placeholder in the
+// configuration of the module that refers to a variable the module
+// doesn't have, which would otherwise only fail when it runs. path is
+// where the module is in the file whose lines are given. The modules also
+// have the project variables.
+func checkModuleVariables(module conf.Module, projectVariables []conf.Variable, lines conf.SourceLines, path string) (errs []*conf.SchemaError) {
+	variables := map[string]bool{}
+	for _, variable := range append(append([]conf.Variable{}, module.Variables...), projectVariables...) {
+		variables[variable.Name] = true
+	}
+	if module.ForEach != nil {
+		variables[module.ForEach.Key] = true
+		for _, item := range module.ForEach.Items {
+			for name := range item {
+				variables[name] = true
+			}
+		}
+	}
+
+	check := func(key, value string) {
+		for _, name := range templateVariables(value) {
+			if variables[name] {
+				continue
+			}
+			message := fmt.Sprintf("undefined variable %v", name)
+			if len(variables) == 0 {
+				message += "; the module has no variables"
+			} else if suggestions := utils.ClosestMatches(name, sortedNames(variables)); suggestions != nil {
+				message += fmt.Sprintf("; did you mean %s?", strings.Join(suggestions, " or "))
+			}
+			errs = append(errs, lines.Errorf(joinConfigPath(path, key), "%s", message))
+		}
+	}
+	checkMap := func(key string, values map[string]string) {
+		for _, name := range sortedKeys(values) {
+			check(key+"."+name, values[name])
+		}
+	}
+
+	checkMap("remote.backend_config", module.Remote.BackendConfig)
+	checkMap("env", module.Env)
+	for i, varFile := range module.VarFiles {
+		check(fmt.Sprintf("var_files[%d]", i), varFile)
+	}
+	for i, dep := range module.Deps {
+		checkMap(fmt.Sprintf("deps[%d].variables", i), dep.Variables)
+	}
+
+	for i, precondition := range module.Preconditions {
+		check(fmt.Sprintf("preconditions[%d].http", i), precondition.HTTP)
+		check(fmt.Sprintf("preconditions[%d].command", i), precondition.Command)
+		check(fmt.Sprintf("preconditions[%d].output", i), precondition.Output)
+	}
+
+	credentials := module.Credentials
+	checkMap("credentials.env", credentials.Env)
+	if credentials.AWS != nil {
+		check("credentials.aws.role_arn", credentials.AWS.RoleARN)
+		check("credentials.aws.external_id", credentials.AWS.ExternalID)
+	}
+	if credentials.Azure != nil {
+		check("credentials.azure.tenant_id", credentials.Azure.TenantID)
+		check("credentials.azure.subscription_id", credentials.Azure.SubscriptionID)
+		check("credentials.azure.client_id", credentials.Azure.ClientID)
+		check("credentials.azure.oidc_token_file", credentials.Azure.OIDCTokenFile)
+	}
+	if credentials.GCP != nil {
+		check("credentials.gcp.project", credentials.GCP.Project)
+		check("credentials.gcp.region", credentials.GCP.Region)
+		check("credentials.gcp.credentials_file", credentials.GCP.CredentialsFile)
+		check("credentials.gcp.impersonate_service_account", credentials.GCP.ImpersonateServiceAccount)
+	}
+
+	// Computed variables can also use the module name. They are checked
+	// last, as it is not a variable anywhere else.
+	variables["module"] = true
+	for i, variable := range module.Variables {
+		check(fmt.Sprintf("variables[%d].computed", i), variable.Computed)
+	}
+
+	return errs
+}
+
+// checkModulePath returns an error if the directory of the module, whose
+// Terraform code root has been set, does not exist. Paths that are empty
+// or outside of the code root are left to Module.Validate.
+func checkModulePath(module conf.Module, lines conf.SourceLines, path string) *conf.SchemaError {
+	if module.Path == "" {
+		return nil
+	}
+	dir := filepath.Join(module.TerraformCodeRoot, module.Path)
+	if !utils.IsWithinPath(module.TerraformCodeRoot, dir) || utils.IsDirectory(dir) {
+		return nil
+	}
+	return lines.Errorf(joinConfigPath(path, "path"), "module directory does not exist: %v", dir)
+}
+
+// joinConfigPath returns the path of a key of the value at path.
+func joinConfigPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// sortedNames returns the names in a set, sorted.
+func sortedNames(set map[string]bool) []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortedKeys returns the keys of a map, sorted.
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
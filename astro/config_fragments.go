@@ -0,0 +1,203 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/utils"
+
+	"github.com/ghodss/yaml"
+)
+
+const (
+	// fragmentsDirName is the name of the directory, next to the config
+	// file, that contains config fragments.
+	fragmentsDirName = "astro.d"
+	// moduleFragmentFileName is the name of the file, in a module
+	// directory, that contains the configuration for that module.
+	moduleFragmentFileName = "module.astro.yaml"
+)
+
+// configFragment is the structure of a YAML file in the astro.d
+// directory.
+type configFragment struct {
+	Modules []conf.Module
+}
+
+// mergeConfigFragments adds the modules defined in astro.d/*.yaml files
+// next to the config file, and in module.astro.yaml files anywhere in the
+// Terraform code root, to the config. Module names must be unique across
+// all files.
+func mergeConfigFragments(config *conf.Project, rootPath string) error {
+	// sources keeps track of where each module was defined, so that
+	// duplicates can be reported.
+	sources := map[string]string{}
+	for _, module := range config.Modules {
+		sources[module.Name] = "main config file"
+	}
+
+	addModule := func(module conf.Module, source string) error {
+		if existing, ok := sources[module.Name]; ok {
+			return fmt.Errorf("module %q is defined in both %v and %v", module.Name, existing, source)
+		}
+		sources[module.Name] = source
+		config.Modules = append(config.Modules, module)
+		return nil
+	}
+
+	fragmentFiles, err := filepath.Glob(filepath.Join(rootPath, fragmentsDirName, "*.y*ml"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(fragmentFiles)
+
+	for _, file := range fragmentFiles {
+		logger.Trace.Printf("config: reading config fragment: %v", file)
+
+		var fragment configFragment
+		lines, err := readYAMLFile(file, &fragment)
+		if err != nil {
+			return err
+		}
+
+		for i, module := range fragment.Modules {
+			if err := checkFragmentVariables(file, module, config.Variables, lines, fmt.Sprintf("modules[%d]", i)); err != nil {
+				return err
+			}
+			if err := rewriteRelPathsInSlices(rootPath, module.Hooks.PreModuleRun); err != nil {
+				return err
+			}
+			if err := addModule(module, file); err != nil {
+				return err
+			}
+		}
+	}
+
+	codeRoot := config.TerraformCodeRoot
+	if codeRoot == "" {
+		codeRoot = rootPath
+	} else if !filepath.IsAbs(codeRoot) {
+		codeRoot = filepath.Join(rootPath, codeRoot)
+	}
+
+	moduleFiles, err := findModuleFragments(codeRoot)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range moduleFiles {
+		logger.Trace.Printf("config: reading module config: %v", file)
+
+		var module conf.Module
+		lines, err := readYAMLFile(file, &module)
+		if err != nil {
+			return err
+		}
+		if err := checkFragmentVariables(file, module, config.Variables, lines, ""); err != nil {
+			return err
+		}
+
+		// The module path is relative to the directory containing the
+		// file, and defaults to that directory.
+		moduleDir := filepath.Dir(file)
+		modulePath, err := filepath.Rel(codeRoot, filepath.Join(moduleDir, module.Path))
+		if err != nil {
+			return err
+		}
+		module.Path = modulePath
+
+		if err := rewriteRelPathsInSlices(moduleDir, module.Hooks.PreModuleRun); err != nil {
+			return err
+		}
+		if err := addModule(module, file); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// findModuleFragments returns the paths to all module.astro.yaml files
+// within codeRoot. Directories containing their own astro config file are
+// skipped.
+func findModuleFragments(codeRoot string) ([]string, error) {
+	var files []string
+
+	err := filepath.Walk(codeRoot, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path == codeRoot {
+				return nil
+			}
+			name := info.Name()
+			if name == ".terraform" || name == ".astro" || name == ".git" {
+				return filepath.SkipDir
+			}
+			// Don't descend into other astro projects
+			for _, configFile := range []string{"astro.yaml", "astro.yml", "astro.hcl"} {
+				if utils.FileExists(filepath.Join(path, configFile)) {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+		if info.Name() == moduleFragmentFileName {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// readYAMLFile reads the YAML file at path into v, after checking that it
+// has the schema of v. It returns the lines of the file, for reporting
+// other problems with it.
+func readYAMLFile(path string, v interface{}) (conf.SourceLines, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := conf.CheckSchema(b, v); err != nil {
+		return nil, fmt.Errorf("failed to load YAML from file: %s; %w", path, err)
+	}
+	if err := yaml.Unmarshal(b, v); err != nil {
+		return nil, fmt.Errorf("failed to load YAML from file: %s; %v", path, err)
+	}
+	return conf.NewSourceLines(b), nil
+}
+
+// checkFragmentVariables checks the placeholders of a module defined in
+// the fragment file. variables are the project variables.
+func checkFragmentVariables(file string, module conf.Module, variables []conf.Variable, lines conf.SourceLines, path string) error {
+	problems := checkModuleVariables(module, variables, lines, path)
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("failed to load YAML from file: %s; %w", file, conf.SortSchemaErrors(problems))
+}
@@ -0,0 +1,288 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
+
+	"github.com/ghodss/yaml"
+)
+
+const (
+	// includeKey is the key of the list of files that a config file
+	// includes.
+	includeKey = "include"
+	// mainConfigSource is how the main config file is referred to in
+	// errors about values that are also set in other files.
+	mainConfigSource = "main config file"
+)
+
+// configIncludes merges the config files that are included by a config
+// file into its YAML document.
+//
+// Maps are merged key by key, and lists are concatenated, in the order
+// the files are included. Any other value can only be set in one file,
+// so that it doesn't matter which file is read first.
+type configIncludes struct {
+	// files is the files that have been included, so that no file is
+	// included twice
+	files map[string]bool
+	// sources is the file that set each value of the document, by path;
+	// values without one were set in the main file
+	sources map[string]string
+	// modules is the file that defined each module, by name
+	modules map[string]string
+	// variables are the project variables of the main file, which the
+	// modules of the included files can use
+	variables []conf.Variable
+}
+
+// mergeIncludes returns the YAML document of a config, with the files it
+// includes, and the files they include in turn, merged into it. rootPath
+// is the directory of the config file, and variables are its project
+// variables.
+func mergeIncludes(yamlBytes []byte, rootPath string, variables []conf.Variable) ([]byte, error) {
+	var document map[string]interface{}
+	if err := yaml.Unmarshal(yamlBytes, &document); err != nil {
+		return nil, err
+	}
+
+	includes := &configIncludes{
+		files:     map[string]bool{},
+		sources:   map[string]string{},
+		modules:   map[string]string{},
+		variables: variables,
+	}
+	for _, name := range documentModuleNames(document) {
+		includes.modules[name] = mainConfigSource
+	}
+
+	patterns := documentIncludes(document)
+	delete(document, includeKey)
+
+	if err := includes.include(document, patterns, rootPath); err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(document)
+}
+
+// include merges the files matching the patterns, relative to dir, into
+// the document, followed by the files they include.
+func (c *configIncludes) include(document map[string]interface{}, patterns []string, dir string) error {
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid include %q: %v", pattern, err)
+		}
+		if len(files) == 0 {
+			return fmt.Errorf("include matches no files: %v", pattern)
+		}
+		sort.Strings(files)
+
+		for _, file := range files {
+			if c.files[file] {
+				return fmt.Errorf("config file is included more than once: %v", file)
+			}
+			c.files[file] = true
+
+			logger.Trace.Printf("config: reading included config: %v", file)
+
+			included, err := readIncludedConfig(file, c.variables)
+			if err != nil {
+				return err
+			}
+
+			nested := documentIncludes(included)
+			delete(included, includeKey)
+
+			if err := c.merge(document, included, "", file); err != nil {
+				return err
+			}
+			if err := c.include(document, nested, filepath.Dir(file)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// merge merges the values of src, from file, into dst, whose path in the
+// document is path.
+func (c *configIncludes) merge(dst, src map[string]interface{}, path string, file string) error {
+	keys := make([]string, 0, len(src))
+	for key := range src {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		keyPath := joinConfigPath(path, key)
+		value := src[key]
+		if value == nil {
+			continue
+		}
+
+		existing, ok := dst[key]
+		if !ok || existing == nil {
+			if keyPath == "modules" {
+				if err := c.addModules(value, file); err != nil {
+					return err
+				}
+			}
+			dst[key] = value
+			c.sources[keyPath] = file
+			continue
+		}
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if existingMap, ok := existing.(map[string]interface{}); ok {
+				if err := c.merge(existingMap, v, keyPath, file); err != nil {
+					return err
+				}
+				continue
+			}
+		case []interface{}:
+			if existingList, ok := existing.([]interface{}); ok {
+				if keyPath == "modules" {
+					if err := c.addModules(value, file); err != nil {
+						return err
+					}
+				}
+				dst[key] = append(existingList, v...)
+				continue
+			}
+		}
+
+		return fmt.Errorf("%v is set in both %v and %v", keyPath, c.source(keyPath), file)
+	}
+
+	return nil
+}
+
+// addModules records the modules defined in file, and returns an error if
+// one of them has already been defined.
+func (c *configIncludes) addModules(modules interface{}, file string) error {
+	for _, name := range documentModuleNames(map[string]interface{}{"modules": modules}) {
+		if existing, ok := c.modules[name]; ok {
+			return fmt.Errorf("module %q is defined in both %v and %v", name, existing, file)
+		}
+		c.modules[name] = file
+	}
+	return nil
+}
+
+// source returns the file that set the value at path, or one of the maps
+// that contain it.
+func (c *configIncludes) source(path string) string {
+	for {
+		if file, ok := c.sources[path]; ok {
+			return file
+		}
+		i := strings.LastIndex(path, ".")
+		if i < 0 {
+			return mainConfigSource
+		}
+		path = path[:i]
+	}
+}
+
+// readIncludedConfig reads an included config file, which can be in any
+// format a config can be, and checks it like the main file, so that its
+// problems are reported with its own line numbers. variables are the
+// project variables of the main file.
+func readIncludedConfig(file string, variables []conf.Variable) (map[string]interface{}, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	loader := configLoaderFor(file)
+	failed := func(err error) error {
+		return fmt.Errorf("failed to load %s from file: %s; %w", loader.name(), file, err)
+	}
+
+	yamlBytes, lines, err := loader.load(data)
+	if err != nil {
+		return nil, failed(err)
+	}
+
+	var config conf.Project
+	problems, err := lines.SchemaErrors(yamlBytes, &config)
+	if err != nil {
+		return nil, failed(err)
+	}
+	if err := yaml.Unmarshal(yamlBytes, &config); err != nil {
+		if len(problems) > 0 {
+			return nil, failed(conf.SortSchemaErrors(problems))
+		}
+		return nil, failed(err)
+	}
+	for i, module := range config.Modules {
+		problems = append(problems, checkModuleVariables(module, append(append([]conf.Variable{}, variables...), config.Variables...), lines, fmt.Sprintf("modules[%d]", i))...)
+	}
+	if len(problems) > 0 {
+		return nil, failed(conf.SortSchemaErrors(problems))
+	}
+
+	var document map[string]interface{}
+	if err := yaml.Unmarshal(yamlBytes, &document); err != nil {
+		return nil, failed(err)
+	}
+	if document == nil {
+		document = map[string]interface{}{}
+	}
+
+	return document, nil
+}
+
+// documentIncludes returns the include patterns of a YAML document,
+// whose schema has been checked.
+func documentIncludes(document map[string]interface{}) (patterns []string) {
+	values, _ := document[includeKey].([]interface{})
+	for _, value := range values {
+		patterns = append(patterns, fmt.Sprintf("%v", value))
+	}
+	return patterns
+}
+
+// documentModuleNames returns the names of the modules of a YAML
+// document, whose schema has been checked.
+func documentModuleNames(document map[string]interface{}) (names []string) {
+	modules, _ := document["modules"].([]interface{})
+	for _, module := range modules {
+		if values, ok := module.(map[string]interface{}); ok {
+			if name, ok := values["name"].(string); ok {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
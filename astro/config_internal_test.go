@@ -22,6 +22,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/uber/astro/astro/conf"
 	"github.com/uber/astro/astro/utils"
 
 	"github.com/burl/go-version"
@@ -54,6 +55,323 @@ func TestTerraformCodeRootPaths(t *testing.T) {
 	}
 }
 
+func TestConfigFragments(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-config-fragments/astro.yaml")
+	require.NoError(t, err)
+
+	var names, paths []string
+	for _, module := range c.config.Modules {
+		names = append(names, module.Name)
+		paths = append(paths, module.Path)
+	}
+
+	// main config first, then astro.d, then module.astro.yaml files
+	assert.Equal(t, []string{"database", "network", "app"}, names)
+	assert.Equal(t, []string{".", "network", "app"}, paths)
+}
+
+func TestConfigFragmentsDuplicateModule(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "astro.d"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "astro.yaml"), []byte("modules:\n  - name: app\n    path: .\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "astro.d/app.yaml"), []byte("modules:\n  - name: app\n    path: .\n"), 0644))
+
+	_, err := NewConfigFromFile(filepath.Join(dir, "astro.yaml"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `module "app" is defined in both`)
+}
+
+func TestConfigSchemaErrors(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "app"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "astro.yaml"), []byte(`---
+terraform:
+  path: /bin/true
+  version: 0.11.7
+
+modules:
+  - name: app
+    path: app
+    parallelsim: 2
+    remote:
+      backend: s3
+      backend_config:
+        key: "app/{{.enviroment}}"
+    variables:
+      - name: environment
+        values: [dev, prod]
+  - name: missing
+    path: missing
+    timeout: 5
+`), 0644))
+
+	_, err := NewConfigFromFile(filepath.Join(dir, "astro.yaml"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "4 errors occurred")
+	assert.Contains(t, err.Error(), "line 9: modules[0].parallelsim: unknown key; did you mean parallelism?")
+	assert.Contains(t, err.Error(), "line 13: modules[0].remote.backend_config.key: undefined variable enviroment; did you mean environment?")
+	assert.Contains(t, err.Error(), "line 18: modules[1].path: module directory does not exist")
+	assert.Contains(t, err.Error(), "line 19: modules[1].timeout: unknown key")
+}
+
+func TestConfigSchemaTypeErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := configFromYAML([]byte("parallelism: many\nmodules:\n  - name: app\n    deps: app\n"), "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 1: parallelism: expected")
+	assert.Contains(t, err.Error(), `line 4: modules[0].deps: expected a list, not the string "app"`)
+}
+
+func TestConfigFragmentSchemaErrors(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "app"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "astro.yaml"), []byte("terraform:\n  path: /bin/true\n  version: 0.11.7\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app/module.astro.yaml"), []byte("name: app\nenv:\n  REGION: \"{{.region}}\"\n"), 0644))
+
+	_, err := NewConfigFromFile(filepath.Join(dir, "astro.yaml"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 3: env.REGION: undefined variable region; the module has no variables")
+}
+
+func TestConfigHCL(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "app"), 0755))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "network"), 0755))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "astro.hcl"), []byte(`
+locals {
+  bucket       = "terraform-states"
+  environments = ["dev", "prod"]
+}
+
+terraform {
+  path    = "/bin/true"
+  version = "0.11.7"
+}
+
+module "network" {
+  path = "network"
+
+  variable "environment" {
+    values = "${local.environments}"
+  }
+}
+
+module "app" {
+  path = "app"
+
+  remote {
+    backend = "s3"
+    backend_config {
+      bucket = "${local.bucket}"
+      key    = "app/{{.environment}}"
+    }
+  }
+
+  variable "environment" {
+    values = "${local.environments}"
+  }
+
+  deps {
+    module    = "network"
+    variables = { environment = "{{.environment}}" }
+  }
+}
+`), 0644))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "astro.yaml"), []byte(`---
+terraform:
+  path: /bin/true
+  version: 0.11.7
+
+modules:
+  - name: network
+    path: network
+    variables:
+      - name: environment
+        values: [dev, prod]
+  - name: app
+    path: app
+    remote:
+      backend: s3
+      backend_config:
+        bucket: terraform-states
+        key: "app/{{.environment}}"
+    variables:
+      - name: environment
+        values: [dev, prod]
+    deps:
+      - module: network
+        variables:
+          environment: "{{.environment}}"
+`), 0644))
+
+	fromHCL, err := NewConfigFromFile(filepath.Join(dir, "astro.hcl"))
+	require.NoError(t, err)
+	fromYAML, err := NewConfigFromFile(filepath.Join(dir, "astro.yaml"))
+	require.NoError(t, err)
+
+	assert.Equal(t, fromYAML, fromHCL)
+}
+
+func TestConfigHCLErrors(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "astro.hcl"), []byte(`terraform {
+  path = "/bin/true"
+}
+
+module "app" {
+  path        = "${local.app_path}"
+  parallelsim = 2
+}
+`), 0644))
+
+	_, err := NewConfigFromFile(filepath.Join(dir, "astro.hcl"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to load HCL from file")
+	assert.Contains(t, err.Error(), "line 6: modules[0].path: undefined local app_path")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "astro.hcl"), []byte(`terraform {
+  path = "/bin/true"
+}
+
+module "app" {
+  path        = "app"
+  parallelsim = 2
+}
+`), 0644))
+
+	_, err = NewConfigFromFile(filepath.Join(dir, "astro.hcl"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 7: modules[0].parallelsim: unknown key; did you mean parallelism?")
+	assert.Contains(t, err.Error(), "line 6: modules[0].path: module directory does not exist")
+}
+
+// writeFiles writes the files, by path relative to dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0644))
+	}
+}
+
+func TestConfigIncludes(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"astro.yaml": `---
+include:
+  - teams/*.yaml
+terraform:
+  path: /bin/true
+modules:
+  - name: network
+    path: network
+flags:
+  environment:
+    name: env
+`,
+		"teams/app.yaml": `---
+include:
+  - ../environments/prod.hcl
+terraform:
+  version: 0.11.7
+modules:
+  - name: app
+    path: app
+    variables:
+      - name: environment
+        values: [dev, prod]
+flags:
+  region:
+    name: region
+`,
+		"teams/data.yaml": `---
+modules:
+  - name: database
+    path: database
+`,
+		"environments/prod.hcl": `
+module "app-prod" {
+  path = "app"
+}
+`,
+		"network/.keep":  "",
+		"app/.keep":      "",
+		"database/.keep": "",
+	})
+
+	config, err := NewConfigFromFile(filepath.Join(dir, "astro.yaml"))
+	require.NoError(t, err)
+
+	var names []string
+	for _, module := range config.Modules {
+		names = append(names, module.Name)
+	}
+	assert.Equal(t, []string{"network", "app", "app-prod", "database"}, names)
+	assert.Equal(t, "/bin/true", config.TerraformDefaults.Path)
+	assert.Equal(t, "0.11.7", config.TerraformDefaults.Version.String())
+	assert.Equal(t, "env", config.Flags["environment"].Name)
+	assert.Equal(t, "region", config.Flags["region"].Name)
+}
+
+func TestConfigIncludeErrors(t *testing.T) {
+	t.Parallel()
+
+	load := func(files map[string]string) error {
+		dir := t.TempDir()
+		writeFiles(t, dir, files)
+		_, err := NewConfigFromFile(filepath.Join(dir, "astro.yaml"))
+		require.Error(t, err)
+		return err
+	}
+
+	main := "include: [team.yaml]\nterraform:\n  path: /bin/true\n"
+
+	err := load(map[string]string{"astro.yaml": main})
+	assert.Contains(t, err.Error(), "include matches no files")
+
+	err = load(map[string]string{
+		"astro.yaml": main,
+		"team.yaml":  "terraform:\n  path: /usr/bin/true\n",
+	})
+	assert.Contains(t, err.Error(), "terraform.path is set in both main config file and")
+
+	err = load(map[string]string{
+		"astro.yaml": main + "modules:\n  - name: app\n    path: app\n",
+		"team.yaml":  "modules:\n  - name: app\n    path: app\n",
+	})
+	assert.Contains(t, err.Error(), `module "app" is defined in both main config file and`)
+
+	err = load(map[string]string{
+		"astro.yaml": main,
+		"team.yaml":  "include: [team.yaml]\n",
+	})
+	assert.Contains(t, err.Error(), "config file is included more than once")
+
+	err = load(map[string]string{
+		"astro.yaml": main,
+		"team.yaml":  "modules:\n  - name: app\n    paht: app\n",
+	})
+	assert.Contains(t, err.Error(), "team.yaml; 1 error occurred")
+	assert.Contains(t, err.Error(), "line 3: modules[0].paht: unknown key; did you mean path?")
+}
+
 func TestModulePathCannotEscapeCodeRoot(t *testing.T) {
 	t.Parallel()
 
@@ -121,3 +439,208 @@ func TestUnmarshalTerraformVersion(t *testing.T) {
 
 	assert.Equal(t, expectedObj, c.config.TerraformDefaults.Version)
 }
+
+func TestConfigProfiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"astro.yaml": `---
+terraform:
+  path: /bin/true
+  version: 0.11.7
+parallelism: 4
+profiles:
+  dev:
+    variables:
+      environment: dev
+  prod:
+    variables:
+      environment: prod
+      region: us-east-1
+    terraform:
+      version: 1.5.7
+      warn_after: 20m
+    parallelism: 2
+modules:
+  - name: app
+    path: app
+    variables:
+      - name: environment
+        values: [dev, prod]
+      - name: region
+  - name: legacy
+    path: legacy
+    terraform:
+      path: /usr/bin/true
+      version: 0.11.14
+`,
+		"app/.keep":    "",
+		"legacy/.keep": "",
+	})
+
+	config, err := NewConfigFromFile(filepath.Join(dir, "astro.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "0.11.7", config.Modules[0].Terraform.Version.String())
+	assert.Equal(t, 4, config.Parallelism)
+
+	config, err = NewConfigFromFileWithProfile(filepath.Join(dir, "astro.yaml"), "prod")
+	require.NoError(t, err)
+	assert.Equal(t, 2, config.Parallelism)
+	// the version of the profile is downloaded, not run with the project's path
+	assert.Equal(t, "", config.Modules[0].Terraform.Path)
+	assert.Equal(t, "1.5.7", config.Modules[0].Terraform.Version.String())
+	assert.Equal(t, "20m", config.Modules[0].Terraform.WarnAfter)
+	// modules keep their own configuration
+	assert.Equal(t, "/usr/bin/true", config.Modules[1].Terraform.Path)
+	assert.Equal(t, "0.11.14", config.Modules[1].Terraform.Version.String())
+	assert.Equal(t, "20m", config.Modules[1].Terraform.WarnAfter)
+
+	_, err = NewConfigFromFileWithProfile(filepath.Join(dir, "astro.yaml"), "prd")
+	assert.EqualError(t, err, `failed to load YAML from file: `+filepath.Join(dir, "astro.yaml")+`; unknown profile "prd"; did you mean prod?`)
+}
+
+func TestConfigProfilesValidate(t *testing.T) {
+	t.Parallel()
+
+	config, err := configFromYAML([]byte(`---
+terraform:
+  path: /bin/true
+  version: 0.11.7
+profiles:
+  prod:
+    variables:
+      environment: production
+      region: us-east-1
+    parallelism: -1
+modules:
+  - name: app
+    path: .
+    variables:
+      - name: environment
+        values: [dev, prod]
+`), "")
+	require.NoError(t, err)
+
+	_, err = NewProject(WithConfig(*config))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "profiles[prod]: 3 errors occurred")
+	assert.Contains(t, err.Error(), "parallelism cannot be negative")
+	assert.Contains(t, err.Error(), `variable "environment": "production" is not one of dev, prod`)
+	assert.Contains(t, err.Error(), `no module has variable "region"`)
+}
+
+func TestConfigGroupsValidate(t *testing.T) {
+	t.Parallel()
+
+	config, err := configFromYAML([]byte(`---
+terraform:
+  path: /bin/true
+  version: 0.11.7
+groups:
+  storage:
+    parallelism: -1
+modules:
+  - name: app
+    path: .
+    group: compute
+  - name: database
+    path: .
+    group: storage
+    priority: 10
+`), "")
+	require.NoError(t, err)
+	assert.Equal(t, 10, config.Modules[1].Priority)
+
+	_, err = NewProject(WithConfig(*config))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "groups[storage]: parallelism cannot be negative")
+	assert.Contains(t, err.Error(), `module[app]: group "compute" is not one of the groups`)
+	assert.NotContains(t, err.Error(), "module[database]")
+}
+
+func TestConfigModuleTerraformVersion(t *testing.T) {
+	t.Parallel()
+
+	config, err := configFromYAML([]byte(`---
+terraform:
+  path: /bin/true
+  version: 0.11.7
+  lock_timeout: 5m
+modules:
+  - name: app
+    path: .
+  - name: legacy
+    path: .
+    terraform:
+      version: 0.12.31
+  - name: tofu
+    path: .
+    terraform:
+      path: /usr/bin/true
+      version: 1.6.0
+`), "")
+	require.NoError(t, err)
+
+	assert.Equal(t, "/bin/true", config.Modules[0].Terraform.Path)
+	assert.Equal(t, "0.11.7", config.Modules[0].Terraform.Version.String())
+	// a version without a path is downloaded, not run with the project's path
+	assert.Equal(t, "", config.Modules[1].Terraform.Path)
+	assert.Equal(t, "0.12.31", config.Modules[1].Terraform.Version.String())
+	assert.Equal(t, "5m", config.Modules[1].Terraform.LockTimeout)
+	assert.Equal(t, "/usr/bin/true", config.Modules[2].Terraform.Path)
+}
+
+func TestConfigTerraformFlavor(t *testing.T) {
+	t.Parallel()
+
+	yamlBytes := []byte(`---
+terraform:
+  path: /bin/true
+  version: 1.6.0
+modules:
+  - name: app
+    path: .
+  - name: legacy
+    path: .
+    terraform:
+      flavor: terraform
+      path: /bin/true
+      version: 0.12.31
+`)
+
+	config, err := configFromYAML(yamlBytes, "")
+	require.NoError(t, err)
+	assert.Equal(t, "", config.Modules[0].Terraform.Flavor)
+
+	config, err = configFromDocument(yamlBytes, conf.NewSourceLines(yamlBytes), "", ConfigPresets{Flavor: "opentofu"})
+	require.NoError(t, err)
+	assert.Equal(t, "opentofu", config.Modules[0].Terraform.Flavor)
+	assert.Equal(t, "terraform", config.Modules[1].Terraform.Flavor)
+
+	config, err = configFromDocument(yamlBytes, conf.NewSourceLines(yamlBytes), "", ConfigPresets{Flavor: "tofu"})
+	require.NoError(t, err)
+	_, err = NewProject(WithConfig(*config))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown flavor "tofu"; must be one of opentofu, terraform`)
+}
+
+func TestLockFileDefault(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	config, err := configFromYAML([]byte(`---
+terraform:
+  path: /bin/true
+  version: 0.11.7
+session_repo_dir: /var/lib/astro
+modules:
+  - name: app
+    path: .
+`), dir)
+	require.NoError(t, err)
+
+	// the lock file is next to the config file, not in the session repo
+	assert.Equal(t, "/var/lib/astro", config.SessionRepoDir)
+	assert.Equal(t, filepath.Join(dir, "astro.lock"), config.LockFile)
+}
@@ -0,0 +1,66 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/uber/astro/astro/conf"
+)
+
+// configLoader reads a format of config files. Every format is converted
+// to the YAML document that configs are read from, so that they all map
+// to the same conf.Project.
+type configLoader interface {
+	// name is the name of the format, for errors.
+	name() string
+	// load returns the YAML document of the config in data, and the
+	// lines of its values in data.
+	load(data []byte) ([]byte, conf.SourceLines, error)
+}
+
+// configLoaderFor returns the loader of the config file at path, by its
+// extension. Files are YAML unless they end in .hcl.
+func configLoaderFor(path string) configLoader {
+	if strings.EqualFold(filepath.Ext(path), ".hcl") {
+		return hclLoader{}
+	}
+	return yamlLoader{}
+}
+
+// yamlLoader loads astro.yaml files.
+type yamlLoader struct{}
+
+func (yamlLoader) name() string {
+	return "YAML"
+}
+
+func (yamlLoader) load(data []byte) ([]byte, conf.SourceLines, error) {
+	return data, conf.NewSourceLines(data), nil
+}
+
+// hclLoader loads astro.hcl files.
+type hclLoader struct{}
+
+func (hclLoader) name() string {
+	return "HCL"
+}
+
+func (hclLoader) load(data []byte) ([]byte, conf.SourceLines, error) {
+	return conf.HCLDocument(data, &conf.Project{})
+}
@@ -0,0 +1,36 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+
+	"github.com/uber/astro/astro/cost"
+)
+
+// estimateCost estimates the cost of the changes in the plan of the
+// execution.
+func (session *Session) estimateCost(b *boundExecution, planJSON func() (string, error), status *statusQueue) (*cost.Estimate, error) {
+	status.send(b.ID(), "Estimating cost...")
+
+	planPath, err := planJSON()
+	if err != nil {
+		return nil, fmt.Errorf("unable to estimate cost: %v", err)
+	}
+
+	return cost.EstimatePlan(session.ctx, *session.repo.project.config.Cost, planPath)
+}
@@ -0,0 +1,158 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package cost estimates how much the changes in Terraform plans cost, by
+// running Infracost on them.
+package cost
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/exec2"
+	"github.com/uber/astro/astro/logger"
+)
+
+// killTimeout is how long Infracost has to exit after it is interrupted
+// before it is killed.
+const killTimeout = 10 * time.Second
+
+// Estimate is the estimated monthly cost of the resources of a plan,
+// before and after it is applied.
+type Estimate struct {
+	Currency string `json:"currency"`
+	// PastMonthly is the monthly cost before the plan is applied, and
+	// Monthly is the monthly cost after.
+	PastMonthly float64 `json:"past_monthly_cost"`
+	Monthly     float64 `json:"monthly_cost"`
+}
+
+// Delta returns how much the plan changes the monthly cost by.
+func (e Estimate) Delta() float64 {
+	return e.Monthly - e.PastMonthly
+}
+
+// Plus returns the sum of two estimates, e.g. for a total across plans.
+// The currency of e is kept if it is set.
+func (e Estimate) Plus(other Estimate) Estimate {
+	currency := e.Currency
+	if currency == "" {
+		currency = other.Currency
+	}
+	return Estimate{
+		Currency:    currency,
+		PastMonthly: e.PastMonthly + other.PastMonthly,
+		Monthly:     e.Monthly + other.Monthly,
+	}
+}
+
+// FormatDelta formats an amount the monthly cost changes by, e.g.
+// "+12.30 USD/month".
+func FormatDelta(delta float64, currency string) string {
+	return strings.TrimSpace(fmt.Sprintf("%+.2f %s", delta, currency)) + "/month"
+}
+
+// breakdown is the part of the output of `infracost breakdown --format
+// json` that astro uses. Costs are decimal strings, or null if nothing
+// could be priced.
+type breakdown struct {
+	Currency             string  `json:"currency"`
+	TotalMonthlyCost     *string `json:"totalMonthlyCost"`
+	PastTotalMonthlyCost *string `json:"pastTotalMonthlyCost"`
+}
+
+// EstimatePlan estimates the cost of the plan at planPath, in the JSON
+// format of `terraform show -json`.
+func EstimatePlan(ctx context.Context, config conf.Cost, planPath string) (*Estimate, error) {
+	infracostPath := config.Path
+	if infracostPath == "" {
+		path, err := exec.LookPath("infracost")
+		if err != nil {
+			return nil, errors.New("unable to find infracost to estimate costs: install it, or set cost.path in the config")
+		}
+		infracostPath = path
+	}
+
+	args := []string{"breakdown", "--path", planPath, "--format", "json", "--log-level", "warn"}
+	if config.UsageFile != "" {
+		args = append(args, "--usage-file", config.UsageFile)
+	}
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+	cmd := exec.Command(infracostPath, args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	logger.Trace.Printf("cost: running %v", cmd.Args)
+	if err := exec2.RunContext(ctx, cmd, killTimeout); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("unable to estimate cost: %v", message)
+		}
+		return nil, fmt.Errorf("unable to estimate cost: %v", err)
+	}
+
+	estimate, err := parseBreakdown(stdout.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("unable to estimate cost: %v", err)
+	}
+
+	return estimate, nil
+}
+
+// parseBreakdown returns the estimate in the output of `infracost
+// breakdown`.
+func parseBreakdown(data []byte) (*Estimate, error) {
+	var output breakdown
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, err
+	}
+
+	monthly, err := parseCost(output.TotalMonthlyCost)
+	if err != nil {
+		return nil, err
+	}
+	pastMonthly, err := parseCost(output.PastTotalMonthlyCost)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Estimate{
+		Currency:    output.Currency,
+		PastMonthly: pastMonthly,
+		Monthly:     monthly,
+	}, nil
+}
+
+// parseCost parses a cost, which is zero if it is missing.
+func parseCost(cost *string) (float64, error) {
+	if cost == nil || *cost == "" {
+		return 0, nil
+	}
+	value, err := strconv.ParseFloat(*cost, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cost %q", *cost)
+	}
+	return value, nil
+}
@@ -0,0 +1,42 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cost
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBreakdown(t *testing.T) {
+	estimate, err := parseBreakdown([]byte(`{"currency":"EUR","totalMonthlyCost":"12.5","pastTotalMonthlyCost":null}`))
+	require.NoError(t, err)
+	assert.Equal(t, &Estimate{Currency: "EUR", Monthly: 12.5}, estimate)
+	assert.Equal(t, 12.5, estimate.Delta())
+
+	_, err = parseBreakdown([]byte(`{"totalMonthlyCost":"lots"}`))
+	assert.Error(t, err)
+}
+
+func TestEstimatePlus(t *testing.T) {
+	total := Estimate{}.Plus(Estimate{Currency: "USD", PastMonthly: 10, Monthly: 5})
+	total = total.Plus(Estimate{PastMonthly: 1, Monthly: 2.5})
+	assert.Equal(t, Estimate{Currency: "USD", PastMonthly: 11, Monthly: 7.5}, total)
+	assert.Equal(t, "-3.50 USD/month", FormatDelta(total.Delta(), total.Currency))
+	assert.Equal(t, "+0.00/month", FormatDelta(0, ""))
+}
@@ -0,0 +1,233 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/terraform"
+	"github.com/uber/astro/astro/utils"
+)
+
+// wireDependencyOutputs adds the outputs of the dependencies of the
+// execution that its module passes to Terraform, i.e. the outputs of its
+// deps, to the variables of config. Sensitive outputs are passed like
+// secret variables, so that they are never logged.
+func (session *Session) wireDependencyOutputs(execution *boundExecution, config *terraform.Config) error {
+	for _, dep := range executionDeps(execution) {
+		if len(dep.Outputs) == 0 {
+			continue
+		}
+
+		depExecution, err := session.repo.project.outputDependency(execution, dep)
+		if err != nil {
+			return fmt.Errorf("unable to read outputs of %v: %v", dep.Module, err)
+		}
+		outputs, err := session.readDependencyOutputs(depExecution)
+		if err != nil {
+			return fmt.Errorf("unable to read outputs of %v: %v", depExecution.ID(), err)
+		}
+
+		var names []string
+		for name := range dep.Outputs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			output, ok := outputs[dep.Outputs[name]]
+			if !ok {
+				return fmt.Errorf("%v has no output %v for variable %v; it may not have been applied yet", depExecution.ID(), dep.Outputs[name], name)
+			}
+			value, err := outputVariableValue(output)
+			if err != nil {
+				return fmt.Errorf("output %v of %v: %v", dep.Outputs[name], depExecution.ID(), err)
+			}
+
+			if output.Sensitive {
+				if config.SecretVariables == nil {
+					config.SecretVariables = map[string]string{}
+				}
+				config.SecretVariables[name] = value
+				continue
+			}
+			config.Variables = mergeMaps(config.Variables, map[string]string{name: value})
+		}
+	}
+
+	return nil
+}
+
+// outputDependency returns the execution of the dependency whose outputs
+// are passed to the execution. Variables of the dependency without values
+// take the values of the execution, as when they are given by the user,
+// and then the variables of dep narrow it down to a single execution.
+func (c *Project) outputDependency(execution *boundExecution, dep conf.Dependency) (*boundExecution, error) {
+	vars, err := replaceVarsInMapValues(dep.Variables, execution.Variables())
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := c.executions(ExecutionParameters{UserVars: NoUserVariables()}).filterByModule(dep.Module)
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("missing dependency: %v", dep.Module)
+	}
+
+	var matches []*boundExecution
+	seen := map[string]bool{}
+	for _, candidate := range candidates {
+		userVars := map[string]string{}
+		for key, val := range candidate.Variables() {
+			if assertAllVarsReplaced(val) == nil {
+				continue
+			}
+			if value, ok := vars[key]; ok {
+				userVars[key] = value
+			} else if value, ok := execution.Variables()[key]; ok {
+				userVars[key] = value
+			}
+		}
+
+		bound, err := candidate.(*unboundExecution).bind(userVars)
+		if err != nil {
+			return nil, err
+		}
+		if !filterMaps(vars, bound.Variables()) || seen[bound.ID()] {
+			continue
+		}
+		seen[bound.ID()] = true
+		matches = append(matches, bound)
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no execution matching dep: %v", dep)
+	case 1:
+		return matches[0], nil
+	}
+
+	var ids []string
+	for _, b := range matches {
+		ids = append(ids, b.ID())
+	}
+	return nil, fmt.Errorf("%d executions match: %v; narrow the dependency down with variables", len(ids), ids)
+}
+
+// readDependencyOutputs returns the outputs of an execution that other
+// executions depend on. Outputs recorded when the execution was applied in
+// the session are used, and otherwise they are read from its state with
+// `terraform output`, in a sandbox of their own.
+func (session *Session) readDependencyOutputs(b *boundExecution) (map[string]terraform.Output, error) {
+	lock, _ := session.dependencyOutputLocks.LoadOrStore(b.ID(), &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	if cached, ok := session.dependencyOutputs.Load(b.ID()); ok {
+		return cached.(map[string]terraform.Output), nil
+	}
+
+	logger.Trace.Printf("astro: reading outputs of %v", b.ID())
+
+	config, _, err := session.terraformConfig(b)
+	if err != nil {
+		return nil, err
+	}
+	outputsDir := filepath.Join(session.path, ".outputs")
+	if err := os.MkdirAll(outputsDir, 0755); err != nil {
+		return nil, err
+	}
+	terraformSession, err := terraform.NewTerraformSession(session.ctx, b.ID(), filepath.Join(outputsDir, b.ID()), config)
+	if err != nil {
+		return nil, err
+	}
+
+	// nobody reads the status updates, so they are all dropped
+	status := newStatusQueue()
+	defer status.close()
+
+	if _, err := session.initialize(b, terraformSession, status); err != nil {
+		return nil, err
+	}
+	outputs, _, err := terraformSession.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	session.dependencyOutputs.Store(b.ID(), outputs)
+	return outputs, nil
+}
+
+// recordDependencyOutputs reads the outputs of an execution that was just
+// applied, if other executions depend on them, so that they are passed to
+// those executions without reading them again. If they can't be read,
+// they are read again when they are needed.
+func (session *Session) recordDependencyOutputs(b *boundExecution, terraformSession *terraform.Session) {
+	if !session.repo.project.outputsDependedOn(b) {
+		return
+	}
+
+	outputs, _, err := terraformSession.Output()
+	if err != nil {
+		logger.Warn("unable to read outputs after apply", logger.Fields{"execution": b.ID(), "error": err})
+		return
+	}
+	session.dependencyOutputs.Store(b.ID(), outputs)
+}
+
+// outputsDependedOn returns whether a module depends on the outputs of the
+// module of the execution, or of one of its aliases, in its variables or in
+// the templates of its env and hook commands.
+func (c *Project) outputsDependedOn(b *boundExecution) bool {
+	modules := append([]string{b.ModuleConfig().Name}, aliasModules(b)...)
+	for _, moduleConfig := range c.config.Modules {
+		for _, dep := range moduleConfig.Deps {
+			if len(dep.Outputs) > 0 && utils.StringSliceContains(modules, dep.Module) {
+				return true
+			}
+		}
+		for _, module := range templateOutputModules(moduleConfig) {
+			if utils.StringSliceContains(modules, module) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// outputVariableValue returns the value of an output as the value of a
+// variable: strings as they are, and other types as JSON, which Terraform
+// reads as HCL.
+func outputVariableValue(output terraform.Output) (string, error) {
+	var s string
+	if err := json.Unmarshal(output.Value, &s); err == nil {
+		return s, nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(output.Value, &value); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(value)
+	return string(data), err
+}
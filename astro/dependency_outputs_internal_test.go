@@ -0,0 +1,265 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/terraform"
+	"github.com/uber/astro/astro/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testInvocations returns the arguments of the Terraform commands of the
+// result that ran the subcommand.
+func testInvocations(result *Result, subcommand string) (args [][]string) {
+	for _, invocation := range result.Invocations() {
+		if invocation.Args[1] == subcommand {
+			args = append(args, invocation.Args)
+		}
+	}
+	return args
+}
+
+func TestApplyPassesDependencyOutputs(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-dependency-outputs/astro.yaml")
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Apply(ApplyExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			UserVars: NoUserVariables(),
+		},
+	})
+	require.NoError(t, err)
+
+	results := testReadResults(resultChan)
+	for id, err := range testResultErrs(results) {
+		assert.NoError(t, err, id)
+	}
+
+	// the outputs are read once, right after the apply
+	assert.Len(t, testInvocations(results["network-dev"], "output"), 1)
+	require.Len(t, testInvocations(results["app-dev"], "apply"), 1)
+	assert.Contains(t, testInvocations(results["app-dev"], "apply")[0], "greeting=hello")
+
+	session, err := c.sessions.Current()
+	require.NoError(t, err)
+	assert.False(t, utils.FileExists(filepath.Join(session.path, ".outputs")))
+}
+
+func TestPlanReadsDependencyOutputs(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-dependency-outputs/astro.yaml")
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Plan(PlanExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			ModuleNames: []string{"app"},
+			UserVars:    NoUserVariables(),
+		},
+	})
+	require.NoError(t, err)
+
+	results := testReadResults(resultChan)
+	require.NoError(t, results["app-prod"].Err())
+	require.Len(t, testInvocations(results["app-prod"], "plan"), 1)
+	assert.Contains(t, testInvocations(results["app-prod"], "plan")[0], "greeting=hello")
+
+	// the dependency isn't in the run, so its outputs are read from its
+	// state
+	session, err := c.sessions.Current()
+	require.NoError(t, err)
+	assert.True(t, utils.IsDirectory(filepath.Join(session.path, ".outputs", "network-prod")))
+}
+
+func TestDependencyOutputsAmbiguous(t *testing.T) {
+	t.Parallel()
+
+	config, err := NewConfigFromFile("fixtures/test-dependency-outputs/astro.yaml")
+	require.NoError(t, err)
+	config.Modules[1].Deps[0].Variables = nil
+
+	c, err := NewProject(WithConfig(*config))
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Plan(PlanExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			ModuleNames: []string{"app"},
+			UserVars:    NoUserVariables(),
+		},
+	})
+	require.NoError(t, err)
+
+	results := testReadResults(resultChan)
+	require.Error(t, results["app-dev"].Err())
+	assert.Contains(t, results["app-dev"].Err().Error(), "unable to read outputs of network: 2 executions match: [network-dev network-prod]; narrow the dependency down with variables")
+}
+
+func TestDependencyOutputsValidation(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewProjectFromYAML([]byte(`
+terraform:
+  path: fixtures/mock-terraform/success
+modules:
+  - name: network
+    path: .
+  - name: app
+    path: .
+    variables:
+      - name: vpc_id
+    deps:
+      - module: network
+        outputs:
+          vpc_id: vpc_id
+`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "deps[0].outputs.vpc_id: vpc_id is also a variable of the module")
+}
+
+func TestOutputVariableValue(t *testing.T) {
+	for value, expected := range map[string]string{
+		`"hello"`:            "hello",
+		`3`:                  "3",
+		`true`:               "true",
+		`[ "a", "b" ]`:       `["a","b"]`,
+		`{"b": 1, "a": "x"}`: `{"a":"x","b":1}`,
+	} {
+		actual, err := outputVariableValue(terraform.Output{Value: json.RawMessage(value)})
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual, value)
+	}
+}
+
+func TestDependencyOutputsInEnvAndHooks(t *testing.T) {
+	t.Parallel()
+
+	config, err := NewConfigFromFile("fixtures/test-dependency-outputs/astro.yaml")
+	require.NoError(t, err)
+	config.Modules[1].Deps[0].Outputs = nil
+	config.Modules[1].Env = map[string]string{
+		"GREETING": `{{ output "network" "greeting" }}-{{.environment}}`,
+	}
+	config.Modules[1].Hooks.PreModuleRun = []conf.Hook{
+		{Command: `touch {{ output "network" "greeting" }}-{{.environment}}`},
+	}
+
+	c, err := NewProject(WithConfig(*config))
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Apply(ApplyExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			UserVars: NoUserVariables(),
+		},
+	})
+	require.NoError(t, err)
+
+	results := testReadResults(resultChan)
+	for id, err := range testResultErrs(results) {
+		assert.NoError(t, err, id)
+	}
+
+	// the outputs are read once, right after the apply, even though no
+	// variables are passed them
+	assert.Len(t, testInvocations(results["network-dev"], "output"), 1)
+	require.NotEmpty(t, results["app-dev"].Invocations())
+	for _, invocation := range results["app-dev"].Invocations() {
+		assert.Equal(t, "hello-dev", invocation.Env["GREETING"], invocation.Args)
+	}
+
+	session, err := c.sessions.Current()
+	require.NoError(t, err)
+	assert.True(t, utils.FileExists(filepath.Join(session.path, "hello-dev")))
+	assert.True(t, utils.FileExists(filepath.Join(session.path, "hello-prod")))
+}
+
+func TestDependencyOutputsInEnvNotADependency(t *testing.T) {
+	t.Parallel()
+
+	config, err := NewConfigFromFile("fixtures/test-dependency-outputs/astro.yaml")
+	require.NoError(t, err)
+	config.Modules[0].Env = map[string]string{
+		"GREETING": `{{ output "app" "greeting" }}`,
+	}
+
+	c, err := NewProject(WithConfig(*config))
+	require.NoError(t, err)
+
+	_, _, err = c.Plan(PlanExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			ModuleNames: []string{"network"},
+			UserVars:    NoUserVariables(),
+		},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "output greeting of app: app is not a dependency of the module")
+}
+
+func TestDryRunDependencyOutputsInEnv(t *testing.T) {
+	t.Parallel()
+
+	config, err := NewConfigFromFile("fixtures/test-dependency-outputs/astro.yaml")
+	require.NoError(t, err)
+	config.Modules[1].Env = map[string]string{
+		"GREETING": `{{ output "network" "greeting" }}`,
+	}
+
+	c, err := NewProject(WithConfig(*config))
+	require.NoError(t, err)
+
+	executions, err := c.DryRunPlan(PlanExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			ExecutionIDs: []string{"app-dev"},
+			UserVars:     NoUserVariables(),
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, executions, 1)
+	require.NotEmpty(t, executions[0].Commands)
+	for _, invocation := range executions[0].Commands {
+		assert.Equal(t, "(output greeting of network-dev)", invocation.Env["GREETING"])
+	}
+}
+
+func TestDependencyOutputsInEnvSensitive(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-dependency-outputs/astro.yaml")
+	require.NoError(t, err)
+
+	b, err := c.executions(NoExecutionParameters()).filterByModule("app")[0].(*unboundExecution).bind(map[string]string{
+		"environment": "dev",
+	})
+	require.NoError(t, err)
+
+	session, err := c.sessions.NewSession()
+	require.NoError(t, err)
+	session.dependencyOutputs.Store("network-dev", map[string]terraform.Output{
+		"greeting": {Sensitive: true, Value: json.RawMessage(`"hello"`)},
+	})
+
+	_, err = session.outputResolver(b)("network", "greeting")
+	assert.EqualError(t, err, "output greeting of network-dev is sensitive; pass it to a variable with deps outputs instead")
+}
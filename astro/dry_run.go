@@ -0,0 +1,284 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/uber/astro/astro/terraform"
+
+	"github.com/hashicorp/terraform/dag"
+)
+
+// dryRunSecret is the value shown for secret variables in dry runs, which
+// never resolve them.
+const dryRunSecret = "(secret)"
+
+// DryRunExecution is how an execution would run, as returned by a dry
+// run, which doesn't run anything.
+type DryRunExecution struct {
+	ID     string
+	Module string
+	// Step is when the execution would run: after the executions of the
+	// earlier steps, and at the same time as the others of its step, up
+	// to the parallelism. Executions that don't wait for each other are
+	// all in step 1.
+	Step int
+	// Dependencies are the executions it waits for.
+	Dependencies []string
+	// Dir is the directory of the module. Terraform runs in a copy of it
+	// in the session.
+	Dir string
+	// TerraformVersion is the configured Terraform version, if there is
+	// one.
+	TerraformVersion string
+	// Variables are the variables passed to Terraform. Secret variables,
+	// and variables passed outputs of dependencies, are only known when
+	// the execution runs, so they are placeholders.
+	Variables map[string]string
+	// Commands are the Terraform commands that would run, in order.
+	Commands []terraform.Invocation
+}
+
+// DryRunPlan returns how Plan would run the executions, in the order they
+// would run, without running anything: the config is resolved, the
+// executions are bound and the graph is built, but Terraform, hooks and
+// secret resolvers are not run.
+func (c *Project) DryRunPlan(parameters PlanExecutionParameters) ([]*DryRunExecution, error) {
+	withGraph := parameters.UseGraph || c.config.PlanUseGraph
+	return c.dryRun(parameters.ExecutionParameters, withGraph, parameters.DetectDrift, "", terraform.DryRunPlan)
+}
+
+// DryRunApply returns how Apply would run the executions, in the order
+// they would run, without running anything, like DryRunPlan. Saved plans
+// and resumed applies can't be dry run.
+func (c *Project) DryRunApply(parameters ApplyExecutionParameters) ([]*DryRunExecution, error) {
+	if parameters.FromSession != "" || parameters.Resume != "" {
+		return nil, errors.New("applies of saved plans and resumed applies cannot be dry run")
+	}
+
+	command := terraform.DryRunApply
+	if c.config.Risk.Gates() {
+		command = terraform.DryRunPlanAndApply
+	}
+	return c.dryRun(parameters.ExecutionParameters, true, false, parameters.SameVersionsAs, command)
+}
+
+// dryRun returns how the selected executions would run the command,
+// sorted by step and ID. If sameVersionsAs is set, they run with the
+// Terraform binaries they ran with in that session.
+func (c *Project) dryRun(parameters ExecutionParameters, withGraph, refreshOnly bool, sameVersionsAs string, command terraform.DryRunCommand) ([]*DryRunExecution, error) {
+	var pinnedBuilds map[string]terraformBuild
+	if sameVersionsAs != "" {
+		builds, err := c.sessions.readTerraformBuilds(sameVersionsAs)
+		if err != nil {
+			return nil, err
+		}
+		pinnedBuilds = builds
+	}
+
+	boundExecutions, err := c.boundExecutions(parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	steps := map[string]int{}
+	dependencies := map[string][]string{}
+	if withGraph {
+		executions := make(executionSet, len(boundExecutions))
+		for i, e := range boundExecutions {
+			executions[i] = e
+		}
+		graph, err := executions.selectionGraph()
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range boundExecutions {
+			dependencies[b.ID()] = dryRunDependencies(graph, b)
+			dryRunStep(graph, b, steps)
+		}
+	}
+
+	var results []*DryRunExecution
+	for _, b := range boundExecutions {
+		result, err := c.dryRunExecution(b, refreshOnly, pinnedBuilds, sameVersionsAs, command)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", b.ID(), err)
+		}
+		result.Step = steps[b.ID()]
+		if result.Step == 0 {
+			result.Step = 1
+		}
+		result.Dependencies = dependencies[b.ID()]
+		results = append(results, result)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Step != results[j].Step {
+			return results[i].Step < results[j].Step
+		}
+		return results[i].ID < results[j].ID
+	})
+
+	return results, nil
+}
+
+// dryRunExecution returns how the execution would run the command, with
+// its binary in pinnedBuilds if they are set.
+func (c *Project) dryRunExecution(b *boundExecution, refreshOnly bool, pinnedBuilds map[string]terraformBuild, pinnedSession string, command terraform.DryRunCommand) (*DryRunExecution, error) {
+	moduleConfig := b.ModuleConfig()
+
+	variables := map[string]string{}
+	for key, val := range b.Variables() {
+		variables[key] = val
+	}
+	secretVariables := map[string]string{}
+	for _, variable := range moduleConfig.Variables {
+		if value, ok := variables[variable.Name]; ok && variable.Secret {
+			delete(variables, variable.Name)
+			if value != "" {
+				secretVariables[variable.Name] = dryRunSecret
+			}
+		}
+	}
+
+	// The outputs of dependencies are only read when the execution runs
+	for _, dep := range executionDeps(b) {
+		if len(dep.Outputs) == 0 {
+			continue
+		}
+		depExecution, err := c.outputDependency(b, dep)
+		if err != nil {
+			return nil, fmt.Errorf("unable to resolve outputs of %v: %v", dep.Module, err)
+		}
+		for name, output := range dep.Outputs {
+			variables[name] = fmt.Sprintf("(output %s of %s)", output, depExecution.ID())
+		}
+	}
+
+	// and so are the outputs in env
+	env, err := renderOutputsInMapValues(moduleConfig.Environment(), func(module, name string) (string, error) {
+		for _, dep := range executionDeps(b) {
+			if dep.Module != module {
+				continue
+			}
+			depExecution, err := c.outputDependency(b, dep)
+			if err != nil {
+				return "", fmt.Errorf("unable to resolve outputs of %v: %v", module, err)
+			}
+			return fmt.Sprintf("(output %s of %s)", name, depExecution.ID()), nil
+		}
+		return "", fmt.Errorf("%v is not a dependency of the module", module)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to render env: %v", err)
+	}
+
+	config := c.newTerraformConfig(b, variables, secretVariables, env)
+	config.RefreshOnly = refreshOnly
+
+	result := &DryRunExecution{
+		ID:     b.ID(),
+		Module: moduleConfig.Name,
+		Dir:    filepath.Join(moduleConfig.TerraformCodeRoot, moduleConfig.Path),
+	}
+
+	// The Terraform binary isn't installed by dry runs
+	terraformVersion := moduleConfig.Terraform.Version
+	if pinnedBuilds != nil {
+		pinned, pinnedVersion, err := c.pinnedBuild(b, pinnedBuilds, pinnedSession)
+		if err != nil {
+			return nil, err
+		}
+		config.TerraformPath = pinned.Path
+		if pinnedVersion != nil {
+			terraformVersion = pinnedVersion
+		}
+	}
+	if terraformVersion != nil {
+		result.TerraformVersion = terraformVersion.String()
+	}
+
+	// nor is the shared plugin directory created
+	var pluginDir string
+	if usesSharedPluginDir(terraformVersion) {
+		pluginDir, err = c.dryRunPluginCacheDir()
+		if err != nil {
+			return nil, err
+		}
+	}
+	setTerraformVersion(&config, terraformVersion, pluginDir)
+
+	if config.TerraformPath == "" {
+		config.TerraformPath = "terraform"
+	}
+
+	commands, err := terraform.DryRun(b.ID(), result.Dir, config, terraformVersion, command)
+	if err != nil {
+		return nil, err
+	}
+	result.Commands = commands
+
+	result.Variables = variables
+	for name, value := range secretVariables {
+		result.Variables[name] = value
+	}
+
+	return result, nil
+}
+
+// dryRunPluginCacheDir returns the directory that Terraform would cache
+// providers in. Dry runs don't open a session, so with the session scope
+// it is in a placeholder session directory.
+func (c *Project) dryRunPluginCacheDir() (string, error) {
+	dir, err := c.PluginCacheDir()
+	if err != nil || dir != "" {
+		return dir, err
+	}
+	return filepath.Join(c.sessions.path, "(session)", pluginCacheDirName), nil
+}
+
+// dryRunDependencies returns the IDs of the executions the execution
+// depends on in the graph, sorted.
+func dryRunDependencies(graph *dag.AcyclicGraph, e terraformExecution) []string {
+	var ids []string
+	for _, v := range graph.DownEdges(e).List() {
+		ids = append(ids, v.(terraformExecution).ID())
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// dryRunStep returns the step the execution runs in, which is one after
+// the last of the steps of the executions it depends on, and records it
+// in steps by ID.
+func dryRunStep(graph *dag.AcyclicGraph, e terraformExecution, steps map[string]int) int {
+	if step, ok := steps[e.ID()]; ok {
+		return step
+	}
+	step := 1
+	for _, v := range graph.DownEdges(e).List() {
+		if depStep := dryRunStep(graph, v.(terraformExecution), steps) + 1; depStep > step {
+			step = depStep
+		}
+	}
+	steps[e.ID()] = step
+	return step
+}
@@ -0,0 +1,209 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/uber/astro/astro/utils"
+
+	"github.com/burl/go-version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRunApply(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-dependency-outputs/astro.yaml")
+	require.NoError(t, err)
+
+	executions, err := c.DryRunApply(ApplyExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			UserVars: &UserVariables{
+				Values:  map[string]string{"environment": "dev"},
+				Filters: map[string]bool{"environment": true},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, executions, 2)
+	network, app := executions[0], executions[1]
+
+	assert.Equal(t, "network-dev", network.ID)
+	assert.Equal(t, 1, network.Step)
+	assert.Empty(t, network.Dependencies)
+
+	assert.Equal(t, "app-dev", app.ID)
+	assert.Equal(t, 2, app.Step)
+	assert.Equal(t, []string{"network-dev"}, app.Dependencies)
+	assert.Equal(t, map[string]string{
+		"environment": "dev",
+		"greeting":    "(output greeting of network-dev)",
+	}, app.Variables)
+	assert.Equal(t, "mock/app", filepath.Base(filepath.Dir(app.Dir))+"/"+filepath.Base(app.Dir))
+
+	var commands []string
+	for _, invocation := range app.Commands {
+		assert.Equal(t, app.Dir, invocation.WorkingDir)
+		commands = append(commands, invocation.Args[1])
+	}
+	// the mock is Terraform 0.8.8, which has no init without a backend
+	assert.Equal(t, "0.8.8", app.TerraformVersion)
+	assert.Equal(t, []string{"get", "apply"}, commands)
+	assert.Contains(t, app.Commands[1].Args, "greeting=(output greeting of network-dev)")
+
+	// nothing ran in the session
+	session, err := c.sessions.Current()
+	require.NoError(t, err)
+	assert.False(t, utils.FileExists(filepath.Join(session.path, "network-dev")))
+	assert.False(t, utils.FileExists(filepath.Join(session.path, ".outputs")))
+}
+
+func TestDryRunPlan(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-dependency-outputs/astro.yaml")
+	require.NoError(t, err)
+
+	// without the graph, every execution runs at once
+	executions, err := c.DryRunPlan(PlanExecutionParameters{
+		ExecutionParameters: NoExecutionParameters(),
+	})
+	require.NoError(t, err)
+
+	var ids []string
+	for _, e := range executions {
+		ids = append(ids, e.ID)
+		assert.Equal(t, 1, e.Step)
+		assert.Nil(t, e.Dependencies)
+		assert.Equal(t, "plan", e.Commands[len(e.Commands)-1].Args[1])
+	}
+	assert.Equal(t, []string{"app-dev", "app-prod", "network-dev", "network-prod"}, ids)
+
+	executions, err = c.DryRunPlan(PlanExecutionParameters{
+		ExecutionParameters: NoExecutionParameters(),
+		UseGraph:            true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "network-dev", executions[0].ID)
+	assert.Equal(t, 2, executions[len(executions)-1].Step)
+}
+
+func TestDryRunSavedPlans(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-dependency-outputs/astro.yaml")
+	require.NoError(t, err)
+
+	_, err = c.DryRunApply(ApplyExecutionParameters{
+		ExecutionParameters: NoExecutionParameters(),
+		FromSession:         "01E2Q5HXW3TGNWAJ9T3V0MB4T4",
+	})
+	assert.EqualError(t, err, "applies of saved plans and resumed applies cannot be dry run")
+}
+
+func TestDryRunSameVersionsAs(t *testing.T) {
+	t.Parallel()
+
+	terraformPath, err := filepath.Abs("fixtures/mock-terraform/success")
+	require.NoError(t, err)
+
+	c, err := NewProjectFromConfigFile("fixtures/test-plan-success/astro.yaml")
+	require.NoError(t, err)
+	for i := range c.config.Modules {
+		c.config.Modules[i].Terraform.Path = terraformPath
+	}
+	_, resultChan, err := c.Apply(ApplyExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			ModuleNames: []string{"users"},
+			UserVars:    NoUserVariables(),
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]error{"users": nil}, testResultErrs(testReadResults(resultChan)))
+	reviewed, err := c.SessionID()
+	require.NoError(t, err)
+
+	// the configured binary has changed since, but not the pinned one
+	c, err = NewProjectFromConfigFile("fixtures/test-plan-success/astro.yaml")
+	require.NoError(t, err)
+	for i := range c.config.Modules {
+		c.config.Modules[i].Terraform.Path = "/nonexistent/terraform"
+	}
+	executions, err := c.DryRunApply(ApplyExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			ModuleNames: []string{"users"},
+			UserVars:    NoUserVariables(),
+		},
+		SameVersionsAs: reviewed,
+	})
+	require.NoError(t, err)
+	require.Len(t, executions, 1)
+	assert.Equal(t, "0.8.8", executions[0].TerraformVersion)
+	for _, invocation := range executions[0].Commands {
+		assert.Equal(t, terraformPath, invocation.Args[0])
+	}
+
+	_, err = c.DryRunApply(ApplyExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			ModuleNames: []string{"database"},
+			UserVars: &UserVariables{
+				Values: map[string]string{"aws_region": "east1"},
+			},
+		},
+		SameVersionsAs: reviewed,
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), fmt.Sprintf("did not run in session %v", reviewed))
+}
+
+func TestDryRunSharedPluginDir(t *testing.T) {
+	// restored after the test
+	t.Setenv("TF_PLUGIN_CACHE_DIR", "")
+	require.NoError(t, os.Unsetenv("TF_PLUGIN_CACHE_DIR"))
+
+	c, err := NewProjectFromConfigFile("fixtures/test-dependency-outputs/astro.yaml")
+	require.NoError(t, err)
+	v, err := version.NewVersion("0.12.31")
+	require.NoError(t, err)
+	for i := range c.config.Modules {
+		c.config.Modules[i].Terraform.Path = ""
+		c.config.Modules[i].Terraform.Version = v
+	}
+
+	executions, err := c.DryRunPlan(PlanExecutionParameters{
+		ExecutionParameters: NoExecutionParameters(),
+	})
+	require.NoError(t, err)
+
+	pluginDir, err := c.PluginCacheDir()
+	require.NoError(t, err)
+	for _, e := range executions {
+		assert.Equal(t, "0.12.31", e.TerraformVersion)
+		for _, invocation := range e.Commands {
+			assert.Equal(t, c.terraformVersions.Path("0.12.31"), invocation.Args[0])
+			assert.Equal(t, pluginDir, invocation.Env["TF_PLUGIN_CACHE_DIR"])
+		}
+	}
+	// but it isn't created
+	assert.False(t, utils.FileExists(pluginDir))
+}
@@ -16,20 +16,59 @@
 
 package exec2
 
+import (
+	"context"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/uber/astro/astro/utils"
+)
+
 // Cmd is the configuration struct for a process.
 type Cmd struct {
 	// Args is a list of arguments to provide to the process.
 	Args []string
+	// Context, if set, interrupts the process when it is done.
+	Context context.Context
 	// CombinedOutputLogFile is the path to a file where the process's
 	// stdout and stderr should be logged.
 	CombinedOutputLogFile string
+	// StdoutLogFile and StderrLogFile are the paths to files where the
+	// process's stdout and stderr should be logged separately.
+	StdoutLogFile string
+	StderrLogFile string
+	// StatusFile is the path to a file where the Status of the process is
+	// written as JSON when it exits.
+	StatusFile string
 	// Command is the path to the process that you want to run
 	Command string
 	// Environment variables to use. If empty, set to current process's env.
 	Env []string
+	// MaxOutputInMemory is the number of bytes of each of stdout and
+	// stderr that are kept in memory. Output that grows past it is moved
+	// to a file in SpillDir. If zero, all output is kept in memory.
+	MaxOutputInMemory int
+	// SpillDir is the directory that output larger than MaxOutputInMemory
+	// is moved to. Defaults to the temporary directory. The files are not
+	// removed.
+	SpillDir string
+	// KillTimeout is how long to wait for the process to exit after it has
+	// been interrupted before killing it. If zero, it is never killed.
+	KillTimeout time.Duration
 	// ExpectedSuccessCodes is a list of exit codes the process will return if
 	// it completes successfully.
 	ExpectedSuccessCodes []int
+	// PromptPattern, if set, is matched against the process's stdout as it
+	// is produced. A match means the process is waiting for input that it
+	// will never get, so it is killed, and Run returns a *PromptError.
+	PromptPattern *regexp.Regexp
+	// OutputWriter, if set, receives a copy of the process's stdout and
+	// stderr as it is produced. If it has a Flush() method, it is called
+	// when the process exits.
+	OutputWriter io.Writer
 	// WorkingDir is the working directory of the process.
 	WorkingDir string
+	// Clock times the process. Defaults to the system clock.
+	Clock utils.Clock
 }
@@ -0,0 +1,75 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec2
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/uber/astro/astro/logger"
+)
+
+// RunContext starts cmd and waits for it to complete. If ctx is done
+// before then, cmd is sent an interrupt signal, and if it still has not
+// exited after killTimeout, it is killed.
+//
+// This is an alternative to exec.CommandContext for commands that should
+// be given the chance to shut down cleanly.
+func RunContext(ctx context.Context, cmd *exec.Cmd, killTimeout time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	waitCh := make(chan error, 1)
+	go func() {
+		waitCh <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-waitCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	logger.Trace.Printf("exec2: context done, interrupting process: %d\n", cmd.Process.Pid)
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		logger.Trace.Printf("exec2: unable to interrupt process: %v\n", err)
+	}
+
+	select {
+	case err := <-waitCh:
+		if err == nil {
+			err = ctx.Err()
+		}
+		return err
+	case <-time.After(killTimeout):
+	}
+
+	logger.Trace.Printf("exec2: process did not exit after %v, killing: %d\n", killTimeout, cmd.Process.Pid)
+	if err := cmd.Process.Kill(); err != nil {
+		return err
+	}
+	<-waitCh
+
+	return ctx.Err()
+}
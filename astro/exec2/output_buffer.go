@@ -0,0 +1,122 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec2
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/uber/astro/astro/logger"
+)
+
+// OutputBuffer captures a stream of output of a process. The output is
+// kept in memory until it grows past a limit, when all of it is moved to
+// a file, so that processes with large outputs don't use up memory.
+type OutputBuffer struct {
+	// limit is the number of bytes kept in memory; zero means no limit.
+	limit int
+	// dir and pattern are where the output file is created, as in
+	// os.CreateTemp.
+	dir     string
+	pattern string
+
+	memory bytes.Buffer
+	file   *os.File
+	size   int
+}
+
+func newOutputBuffer(limit int, dir, pattern string) *OutputBuffer {
+	return &OutputBuffer{limit: limit, dir: dir, pattern: pattern}
+}
+
+// Write implements io.Writer.
+func (b *OutputBuffer) Write(p []byte) (int, error) {
+	if b.file == nil && (b.limit <= 0 || b.memory.Len()+len(p) <= b.limit) {
+		n, err := b.memory.Write(p)
+		b.size += n
+		return n, err
+	}
+
+	if b.file == nil {
+		if err := b.spill(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := b.file.Write(p)
+	b.size += n
+	return n, err
+}
+
+// spill moves the output captured so far to a new file, where the rest
+// of it is written.
+func (b *OutputBuffer) spill() error {
+	file, err := os.CreateTemp(b.dir, b.pattern)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(b.memory.Bytes()); err != nil {
+		file.Close()
+		return err
+	}
+	logger.Trace.Printf("exec2: output is larger than %d bytes, writing it to %v\n", b.limit, file.Name())
+
+	b.file = file
+	b.memory = bytes.Buffer{}
+	return nil
+}
+
+// close closes the output file, if there is one, once the process has
+// exited. The file is kept, so that the output can still be read.
+func (b *OutputBuffer) close() error {
+	if b.file == nil {
+		return nil
+	}
+	return b.file.Close()
+}
+
+// Len returns the number of bytes of output captured.
+func (b *OutputBuffer) Len() int {
+	return b.size
+}
+
+// Path returns the path to the file the output was moved to, or "" if it
+// is still in memory.
+func (b *OutputBuffer) Path() string {
+	if b.file == nil {
+		return ""
+	}
+	return b.file.Name()
+}
+
+// Bytes returns the output. If it was moved to a file, the file is read
+// each time, so callers that need it more than once should keep it.
+func (b *OutputBuffer) Bytes() []byte {
+	if b.file == nil {
+		return b.memory.Bytes()
+	}
+	data, err := os.ReadFile(b.file.Name())
+	if err != nil {
+		logger.Trace.Printf("exec2: unable to read output from %v: %v\n", b.file.Name(), err)
+	}
+	return data
+}
+
+// String returns the output as a string.
+func (b *OutputBuffer) String() string {
+	return string(b.Bytes())
+}
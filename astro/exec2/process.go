@@ -17,7 +17,6 @@
 package exec2
 
 import (
-	"bytes"
 	"fmt"
 	"io"
 	"os"
@@ -27,6 +26,7 @@ import (
 	"time"
 
 	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/utils"
 
 	"github.com/hashicorp/go-multierror"
 )
@@ -45,20 +45,32 @@ func NewProcess(config Cmd) *Process {
 type Process struct {
 	config       *Cmd
 	execCmd      *exec.Cmd
-	stdoutBuffer *bytes.Buffer
-	stderrBuffer *bytes.Buffer
-	time         time.Duration
+	stdoutBuffer *OutputBuffer
+	stderrBuffer *OutputBuffer
+	prompts      *promptWatcher
+	lines        *lineWatcher
+	// logFiles are the files that output is logged to, closed when the
+	// process exits.
+	logFiles []*os.File
+	started  time.Time
+	time     time.Duration
+}
+
+// WatchLines makes the process call fn with each line of its stdout, as it
+// is produced. It must be called before the process is started.
+func (p *Process) WatchLines(fn func(line string)) {
+	p.lines = &lineWatcher{fn: fn}
 }
 
 func (p *Process) configureOutputs() error {
-	p.stdoutBuffer = &bytes.Buffer{}
-	p.stderrBuffer = &bytes.Buffer{}
+	p.stdoutBuffer = newOutputBuffer(p.config.MaxOutputInMemory, p.config.SpillDir, "stdout-*")
+	p.stderrBuffer = newOutputBuffer(p.config.MaxOutputInMemory, p.config.SpillDir, "stderr-*")
 
 	stdoutWriters := []io.Writer{p.stdoutBuffer}
 	stderrWriters := []io.Writer{p.stderrBuffer}
 
 	if p.config.CombinedOutputLogFile != "" {
-		combinedOutputLog, err := os.Create(p.config.CombinedOutputLogFile)
+		combinedOutputLog, err := p.createLogFile(p.config.CombinedOutputLogFile)
 		if err != nil {
 			return err
 		}
@@ -72,12 +84,53 @@ func (p *Process) configureOutputs() error {
 		}
 	}
 
+	if p.config.StdoutLogFile != "" {
+		stdoutLog, err := p.createLogFile(p.config.StdoutLogFile)
+		if err != nil {
+			return err
+		}
+		stdoutWriters = append(stdoutWriters, stdoutLog)
+	}
+
+	if p.config.StderrLogFile != "" {
+		stderrLog, err := p.createLogFile(p.config.StderrLogFile)
+		if err != nil {
+			return err
+		}
+		stderrWriters = append(stderrWriters, stderrLog)
+	}
+
+	if p.config.PromptPattern != nil {
+		p.prompts = newPromptWatcher(p.config.PromptPattern)
+		stdoutWriters = append(stdoutWriters, p.prompts)
+	}
+
+	if p.lines != nil {
+		stdoutWriters = append(stdoutWriters, p.lines)
+	}
+
+	if p.config.OutputWriter != nil {
+		stdoutWriters = append(stdoutWriters, p.config.OutputWriter)
+		stderrWriters = append(stderrWriters, p.config.OutputWriter)
+	}
+
 	p.execCmd.Stdout = io.MultiWriter(stdoutWriters...)
 	p.execCmd.Stderr = io.MultiWriter(stderrWriters...)
 
 	return nil
 }
 
+// createLogFile creates a file that output is logged to, which is closed
+// when the process exits.
+func (p *Process) createLogFile(path string) (*os.File, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	p.logFiles = append(p.logFiles, f)
+	return f, nil
+}
+
 // Process returns the Process field of underlying exec command
 // This allows us to interact with it, i.e. for sending signals
 func (p *Process) Process() *os.Process {
@@ -120,10 +173,11 @@ func (p *Process) Run() error {
 	p.execCmd.Env = p.config.Env
 	err := p.configureOutputs()
 	if err != nil {
+		p.closeOutputs()
 		return err
 	}
 
-	if isInterrupted {
+	if isInterrupted || (p.config.Context != nil && p.config.Context.Err() != nil) {
 		return fmt.Errorf("astro was interrupted, command won't be run: %s, args: %v", command, args)
 	}
 
@@ -133,9 +187,15 @@ func (p *Process) Run() error {
 	}
 
 	// Run the process
-	started := time.Now()
+	clock := p.config.Clock
+	if clock == nil {
+		clock = utils.SystemClock
+	}
+	started := clock.Now()
+	p.started = started
 	if err := p.execCmd.Start(); err != nil {
-		p.time = time.Since(started)
+		p.time = clock.Now().Sub(started)
+		p.closeOutputs()
 		return err
 	} else {
 		// wait for the command to finish
@@ -146,6 +206,23 @@ func (p *Process) Run() error {
 		}()
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT)
+		defer signal.Stop(sigChan)
+
+		var ctxDone <-chan struct{}
+		if p.config.Context != nil {
+			ctxDone = p.config.Context.Done()
+		}
+
+		var promptCh <-chan []string
+		var promptErr *PromptError
+		if p.prompts != nil {
+			promptCh = p.prompts.found
+		}
+
+		// signalled is set once the process has been sent a signal, so that
+		// it is only interrupted once
+		signalled := false
+		var killTimer <-chan time.Time
 
 		var errors error
 		for {
@@ -155,13 +232,48 @@ func (p *Process) Run() error {
 				errors = multierror.Append(fmt.Errorf("signal received: %s", sig))
 				process := p.execCmd.Process
 				logger.Trace.Printf("Signal: %s, process: %d\n", sig, process.Pid)
-				if err := process.Signal(sig); err != nil {
+				// the context may have been cancelled by the same signal,
+				// in which case the process was already interrupted
+				if !signalled {
+					if err := process.Signal(sig); err != nil {
+						errors = multierror.Append(errors, err)
+					}
+					signalled = true
+				}
+				killTimer = p.startKillTimer(killTimer)
+			case <-ctxDone:
+				ctxDone = nil
+				errors = multierror.Append(errors, p.config.Context.Err())
+				if !signalled {
+					logger.Trace.Printf("exec2: context done, interrupting process: %d\n", p.execCmd.Process.Pid)
+					if err := p.execCmd.Process.Signal(os.Interrupt); err != nil {
+						errors = multierror.Append(errors, err)
+					}
+					signalled = true
+				}
+				killTimer = p.startKillTimer(killTimer)
+			case submatches := <-promptCh:
+				promptCh = nil
+				promptErr = &PromptError{Submatches: submatches}
+				logger.Trace.Printf("exec2: process is waiting for input, killing: %d\n", p.execCmd.Process.Pid)
+				if err := p.execCmd.Process.Kill(); err != nil {
+					errors = multierror.Append(errors, err)
+				}
+			case <-killTimer:
+				logger.Trace.Printf("exec2: process did not exit after %v, killing: %d\n", p.config.KillTimeout, p.execCmd.Process.Pid)
+				if err := p.execCmd.Process.Kill(); err != nil {
 					errors = multierror.Append(errors, err)
 				}
 			case err := <-waitCh:
 				// Record run time
-				p.time = time.Since(started)
+				p.time = clock.Now().Sub(started)
+				p.flushOutputWriter()
+				p.closeOutputs()
 				logger.Trace.Printf("exec2: command exit code: %v\n", p.ExitCode())
+				p.writeStatus()
+				if promptErr != nil {
+					return promptErr
+				}
 				// Return an error, if the command didn't exit with a success code
 				if !p.Success() {
 					errors = multierror.Append(errors, err)
@@ -173,21 +285,65 @@ func (p *Process) Run() error {
 	}
 }
 
+// startKillTimer returns a channel that fires when the process should be
+// killed, if a kill timeout is configured. If a timer is already running,
+// it is returned instead.
+func (p *Process) startKillTimer(existing <-chan time.Time) <-chan time.Time {
+	if existing != nil || p.config.KillTimeout == 0 {
+		return existing
+	}
+	return time.After(p.config.KillTimeout)
+}
+
+// flushOutputWriter flushes any output buffered by the configured
+// OutputWriter.
+func (p *Process) flushOutputWriter() {
+	if flusher, ok := p.config.OutputWriter.(interface{ Flush() error }); ok {
+		if err := flusher.Flush(); err != nil {
+			logger.Trace.Printf("exec2: unable to flush output: %v\n", err)
+		}
+	}
+}
+
+// closeOutputs closes the files that output was moved to, if any.
+func (p *Process) closeOutputs() {
+	if p.lines != nil {
+		p.lines.flush()
+	}
+	for _, buffer := range []*OutputBuffer{p.stdoutBuffer, p.stderrBuffer} {
+		if err := buffer.close(); err != nil {
+			logger.Trace.Printf("exec2: unable to close output file: %v\n", err)
+		}
+	}
+	for _, f := range p.logFiles {
+		if err := f.Close(); err != nil {
+			logger.Trace.Printf("exec2: unable to close log file: %v\n", err)
+		}
+	}
+	p.logFiles = nil
+}
+
 // Runtime returns the time.Duration the process took to run.
 func (p *Process) Runtime() time.Duration {
 	return p.time
 }
 
 // Stdout returns the contents of the process's stdout.
-func (p *Process) Stdout() *bytes.Buffer {
+func (p *Process) Stdout() *OutputBuffer {
 	return p.stdoutBuffer
 }
 
 // Stderr returns the contents of the process's stderr.
-func (p *Process) Stderr() *bytes.Buffer {
+func (p *Process) Stderr() *OutputBuffer {
 	return p.stderrBuffer
 }
 
+// LogFile returns the path to the file that the process's stdout and
+// stderr were logged to, or "" if they weren't.
+func (p *Process) LogFile() string {
+	return p.config.CombinedOutputLogFile
+}
+
 // Success returns whether or not the process has exited and if it
 // exited with a success code.
 func (p *Process) Success() bool {
@@ -17,8 +17,13 @@
 package exec2_test
 
 import (
+	"bytes"
+	"context"
 	"io"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
 	"syscall"
 	"testing"
 	"time"
@@ -63,6 +68,96 @@ func TestProcessError(t *testing.T) {
 	assert.Equal(t, "Houston, we have a problem\n", process.Stderr().String())
 }
 
+func TestOutputWriter(t *testing.T) {
+	var out bytes.Buffer
+
+	process := exec2.NewProcess(exec2.Cmd{
+		Command:      "/bin/sh",
+		Args:         []string{"-c", "echo Hello, world!; echo uhoh! >&2"},
+		OutputWriter: utils.NewPrefixWriter(&out, "[test] "),
+	})
+
+	err := process.Run()
+	require.NoError(t, err)
+
+	assert.Equal(t, "Hello, world!\n", process.Stdout().String())
+	// stdout and stderr are both streamed, but we can't be sure of the
+	// order.
+	assert.Contains(t, out.String(), "[test] Hello, world!\n")
+	assert.Contains(t, out.String(), "[test] uhoh!\n")
+}
+
+func TestWatchLines(t *testing.T) {
+	process := exec2.NewProcess(exec2.Cmd{
+		Command: "/bin/sh",
+		Args:    []string{"-c", "printf 'one\\ntw'; printf 'o\\r\\nthree'; echo ignored >&2"},
+	})
+
+	var lines []string
+	process.WatchLines(func(line string) {
+		lines = append(lines, line)
+	})
+
+	require.NoError(t, process.Run())
+	assert.Equal(t, []string{"one", "two", "three"}, lines)
+}
+
+func TestRunContextKillsHungProcess(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// ignores SIGINT, so must be killed
+	cmd := exec.Command("/bin/sh", "-c", "trap '' INT; exec sleep 30")
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	started := time.Now()
+	err := exec2.RunContext(ctx, cmd, 100*time.Millisecond)
+	assert.Equal(t, context.Canceled, err)
+	assert.True(t, time.Since(started) < 10*time.Second)
+}
+
+func TestProcessContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	process := exec2.NewProcess(exec2.Cmd{
+		Command:     "/bin/sh",
+		Args:        []string{"-c", "trap '' INT; exec sleep 30"},
+		Context:     ctx,
+		KillTimeout: 100 * time.Millisecond,
+	})
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	started := time.Now()
+	err := process.Run()
+	assert.Error(t, err)
+	assert.False(t, process.Success())
+	assert.True(t, time.Since(started) < 10*time.Second)
+}
+
+func TestProcessPrompt(t *testing.T) {
+	process := exec2.NewProcess(exec2.Cmd{
+		Command:       "/bin/sh",
+		Args:          []string{"-c", "echo 'var.region'; printf '  Enter a value: '; exec sleep 30"},
+		PromptPattern: regexp.MustCompile(`var\.(\S+)\n\s*Enter a value:`),
+	})
+
+	started := time.Now()
+	err := process.Run()
+	require.Error(t, err)
+	assert.True(t, time.Since(started) < 10*time.Second)
+
+	promptErr, ok := err.(*exec2.PromptError)
+	require.True(t, ok)
+	assert.Equal(t, "region", promptErr.Submatches[1])
+}
+
 func TestCombinedOutputLog(t *testing.T) {
 	tmpLogFile, err := os.CreateTemp("", "")
 	require.NoError(t, err)
@@ -99,6 +194,61 @@ func TestCombinedOutputLog(t *testing.T) {
 	assert.Equal(t, "uhoh!\n", process.Stderr().String())
 }
 
+func TestSeparateOutputLogs(t *testing.T) {
+	dir := t.TempDir()
+
+	process := exec2.NewProcess(exec2.Cmd{
+		Command:       "/bin/sh",
+		Args:          []string{"-c", "echo Hello, world!; echo uhoh! >&2; exit 3"},
+		StdoutLogFile: filepath.Join(dir, "stdout.log"),
+		StderrLogFile: filepath.Join(dir, "stderr.log"),
+		StatusFile:    filepath.Join(dir, "status.json"),
+	})
+	assert.Error(t, process.Run())
+
+	stdout, err := os.ReadFile(filepath.Join(dir, "stdout.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "Hello, world!\n", string(stdout))
+
+	stderr, err := os.ReadFile(filepath.Join(dir, "stderr.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "uhoh!\n", string(stderr))
+
+	data, err := os.ReadFile(filepath.Join(dir, "status.json"))
+	require.NoError(t, err)
+	status, err := exec2.ReadStatus(data)
+	require.NoError(t, err)
+	assert.Equal(t, "/bin/sh", status.Command)
+	assert.Equal(t, 3, status.ExitCode)
+	assert.False(t, status.Success)
+	assert.False(t, status.Started.IsZero())
+	assert.Equal(t, process.Runtime(), status.Duration)
+}
+
+func TestMaxOutputInMemory(t *testing.T) {
+	spillDir, err := os.MkdirTemp("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(spillDir)
+
+	// stdout is larger than the limit, stderr is not
+	process := exec2.NewProcess(exec2.Cmd{
+		Command:           "/bin/sh",
+		Args:              []string{"-c", "echo Hello; echo world!; echo uhoh! >&2"},
+		MaxOutputInMemory: 8,
+		SpillDir:          spillDir,
+	})
+
+	err = process.Run()
+	require.NoError(t, err)
+
+	assert.Equal(t, "Hello\nworld!\n", process.Stdout().String())
+	assert.Equal(t, 13, process.Stdout().Len())
+	assert.Equal(t, spillDir, filepath.Dir(process.Stdout().Path()))
+
+	assert.Equal(t, "uhoh!\n", process.Stderr().String())
+	assert.Equal(t, "", process.Stderr().Path())
+}
+
 func TestExited(t *testing.T) {
 	process := newHelloWorld()
 	assert.False(t, process.Exited())
@@ -0,0 +1,84 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec2
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// promptWatcherBufferSize is how much of the most recent output is kept
+// to match prompts against.
+const promptWatcherBufferSize = 8192
+
+// PromptError is returned when a process was killed because it prompted
+// for input.
+type PromptError struct {
+	// Submatches are the submatches of Cmd.PromptPattern in the output,
+	// starting with the whole match.
+	Submatches []string
+}
+
+// Error is the error message, so this satisfies the error interface.
+func (e *PromptError) Error() string {
+	return fmt.Sprintf("process is waiting for input: %q", e.Submatches[0])
+}
+
+// promptWatcher is an io.Writer that looks for a prompt in the output
+// written to it.
+type promptWatcher struct {
+	mu      sync.Mutex
+	pattern *regexp.Regexp
+	buf     []byte
+	// found receives the submatches of the first prompt found
+	found   chan []string
+	matched bool
+}
+
+func newPromptWatcher(pattern *regexp.Regexp) *promptWatcher {
+	return &promptWatcher{
+		pattern: pattern,
+		found:   make(chan []string, 1),
+	}
+}
+
+// Write adds p to the output and checks it for a prompt.
+func (w *promptWatcher) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.matched {
+		return len(p), nil
+	}
+
+	w.buf = append(w.buf, p...)
+	if len(w.buf) > promptWatcherBufferSize {
+		w.buf = append([]byte(nil), w.buf[len(w.buf)-promptWatcherBufferSize:]...)
+	}
+
+	if submatches := w.pattern.FindSubmatch(w.buf); submatches != nil {
+		w.matched = true
+		var strs []string
+		for _, s := range submatches {
+			strs = append(strs, string(s))
+		}
+		w.found <- strs
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,76 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec2
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/uber/astro/astro/logger"
+)
+
+// Status is how a process that ran exited, as written to the StatusFile.
+type Status struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+	// ExitCode is the exit code of the process, or -1 if it was killed
+	// by a signal.
+	ExitCode int           `json:"exit_code"`
+	Success  bool          `json:"success"`
+	Started  time.Time     `json:"started"`
+	Duration time.Duration `json:"duration"`
+}
+
+// ReadStatus reads the Status of a process from the JSON in data.
+func ReadStatus(data []byte) (Status, error) {
+	var status Status
+	err := json.Unmarshal(data, &status)
+	return status, err
+}
+
+// Status returns how the process exited. It is only meaningful once the
+// process has run.
+func (p *Process) Status() Status {
+	exitCode := 0
+	if p.execCmd != nil && p.execCmd.ProcessState != nil {
+		exitCode = p.execCmd.ProcessState.ExitCode()
+	}
+	return Status{
+		Command:  p.config.Command,
+		Args:     p.config.Args,
+		ExitCode: exitCode,
+		Success:  p.Success(),
+		Started:  p.started,
+		Duration: p.time,
+	}
+}
+
+// writeStatus writes the status of the process to the StatusFile, if one
+// is configured. Failing to write it doesn't fail the process.
+func (p *Process) writeStatus() {
+	if p.config.StatusFile == "" {
+		return
+	}
+	data, err := json.Marshal(p.Status())
+	if err == nil {
+		err = os.WriteFile(p.config.StatusFile, data, 0644)
+	}
+	if err != nil {
+		logger.Trace.Printf("exec2: unable to write status: %v\n", err)
+	}
+}
@@ -17,11 +17,17 @@
 package astro
 
 import (
+	"bytes"
 	"fmt"
+	"os"
+	"path/filepath"
 	"sort"
 	"strings"
+	"text/template"
 
 	"github.com/uber/astro/astro/conf"
+
+	"github.com/hashicorp/go-multierror"
 )
 
 // MissingRequiredVarsError is an error type that is returned from plan or
@@ -48,6 +54,54 @@ func (e *MissingRequiredVarsError) MissingVars() []string {
 	return e.missing
 }
 
+// NoExecutionsMatchedError is an error type that is returned from plan or
+// apply when the variable filters provided by the user don't match any
+// executions, e.g. because of a typo in a value.
+type NoExecutionsMatchedError struct {
+	filters     map[string]string
+	suggestions map[string][]string
+}
+
+// Error is the error message, so this satisfies the error interface.
+func (e *NoExecutionsMatchedError) Error() string {
+	var filters, suggestions []string
+	for _, name := range e.names() {
+		filters = append(filters, fmt.Sprintf("%s=%s", name, e.filters[name]))
+		for _, suggestion := range e.suggestions[name] {
+			suggestions = append(suggestions, fmt.Sprintf("%s=%s", name, suggestion))
+		}
+	}
+
+	msg := fmt.Sprintf("no executions matched: %s", strings.Join(filters, ", "))
+	if len(suggestions) > 0 {
+		msg += fmt.Sprintf("; did you mean %s?", strings.Join(suggestions, " or "))
+	}
+
+	return msg
+}
+
+// names returns the names of the filtered variables, sorted.
+func (e *NoExecutionsMatchedError) names() []string {
+	var names []string
+	for name := range e.filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Filters returns the variable filters that were provided, by variable
+// name.
+func (e *NoExecutionsMatchedError) Filters() map[string]string {
+	return e.filters
+}
+
+// Suggestions returns values close to the filter values that would have
+// matched executions, by variable name.
+func (e *NoExecutionsMatchedError) Suggestions() map[string][]string {
+	return e.suggestions
+}
+
 // terraformExecution is an interface that covers both bound and unbound
 // executions.
 type terraformExecution interface {
@@ -55,6 +109,7 @@ type terraformExecution interface {
 	ModuleConfig() conf.Module
 	Variables() map[string]string
 	TerraformParameters() []string
+	Targets() []string
 }
 
 // execution represents the execution of a module with some variable
@@ -69,6 +124,10 @@ type execution struct {
 
 	// terraformParameters is a list of additional Terraform parameters for this execution
 	terraformParameters []string
+
+	// targets is a list of Terraform resource addresses to limit the
+	// execution to
+	targets []string
 }
 
 // Name is an alias for ID; so that terraform/dag trace output makes
@@ -90,13 +149,21 @@ func (e *execution) ID() string {
 	// variable names that are relevant to this module.
 	var keys []string
 	for _, v := range e.ModuleConfig().Variables {
-		keys = append(keys, v.Name)
+		// computed variables are derived from the others, so they
+		// don't make the ID any more unique, and secrets must not be in
+		// it
+		if !v.IsComputed() && !v.Secret {
+			keys = append(keys, v.Name)
+		}
 	}
 
 	sort.Strings(keys)
 
 	for _, key := range keys {
-		values = append(values, e.variables[key])
+		// optional variables that are empty are left out
+		if e.variables[key] != "" {
+			values = append(values, e.variables[key])
+		}
 	}
 
 	// construct the ID
@@ -124,6 +191,12 @@ func (e *execution) TerraformParameters() []string {
 	return e.terraformParameters
 }
 
+// Targets returns the Terraform resource addresses this execution is
+// limited to
+func (e *execution) Targets() []string {
+	return e.targets
+}
+
 // unboundExecution represents a module execution before runtime
 // variables have been provided by the user and template strings
 // replaced in the variable values.
@@ -160,29 +233,250 @@ func (e *unboundExecution) bind(userVars map[string]string) (*boundExecution, er
 		return nil, &MissingRequiredVarsError{missing: missingVars}
 	}
 
+	if err := computeVariables(e.ModuleConfig(), boundVars); err != nil {
+		return nil, fmt.Errorf("unable to bind execution: %v; %v", e.ID(), err)
+	}
+
 	// Create a copy of the config and search attributes for placeholders
 	// to replace with values from the bound vars.
 	boundConfig := e.ModuleConfig()
 
+	// Apply project overrides that match the variables of this execution
+	for _, override := range boundConfig.Overrides {
+		if !override.Matches(boundVars) {
+			continue
+		}
+		if override.Remote.Backend != "" {
+			boundConfig.Remote.Backend = override.Remote.Backend
+		}
+		boundConfig.Remote.BackendConfig = mergeMaps(boundConfig.Remote.BackendConfig, override.Remote.BackendConfig)
+		boundConfig.Credentials = boundConfig.Credentials.Merge(override.Credentials)
+	}
+
 	// TODO: Loop over all module configuration using reflection
 
+	boundCredentials, err := bindCredentials(boundConfig.Credentials, boundVars)
+	if err != nil {
+		return nil, fmt.Errorf("unable to bind execution: %v; %v", e.ID(), err)
+	}
+	boundConfig.Credentials = boundCredentials
+
+	// The outputs of dependencies in env and hook commands are rendered
+	// when the execution runs
+	boundEnv := map[string]string{}
+	for key, val := range boundConfig.Env {
+		if boundEnv[key], err = replaceAllVarsDeferringOutputs(val, boundVars, boundConfig.Deps); err != nil {
+			return nil, fmt.Errorf("unable to bind execution: %v; %v", e.ID(), err)
+		}
+	}
+	boundConfig.Env = boundEnv
+
+	var boundHooks []conf.Hook
+	for _, hook := range boundConfig.Hooks.PreModuleRun {
+		// commands may have braces of their own, e.g. ${HOME}
+		if hook.Command, err = replaceVarsWithFuncs(hook.Command, boundVars, deferOutputFuncs(boundConfig.Deps)); err != nil {
+			return nil, fmt.Errorf("unable to bind execution: %v; %v", e.ID(), err)
+		}
+		boundHooks = append(boundHooks, hook)
+	}
+	boundConfig.Hooks.PreModuleRun = boundHooks
+
 	boundBackendConfig, err := replaceAllVarsInMapValues(boundConfig.Remote.BackendConfig, boundVars)
 	if err != nil {
 		return nil, fmt.Errorf("unable to bind execution: %v; %v", e.ID(), err)
 	}
 	boundConfig.Remote.BackendConfig = boundBackendConfig
 
+	var boundVarFiles []string
+	for _, varFile := range boundConfig.VarFiles {
+		boundVarFile, err := replaceAllVars(varFile, boundVars)
+		if err != nil {
+			return nil, fmt.Errorf("unable to bind execution: %v; %v", e.ID(), err)
+		}
+		if err := checkVarFile(boundConfig, boundVarFile); err != nil {
+			return nil, fmt.Errorf("unable to bind execution: %v; %v", e.ID(), err)
+		}
+		boundVarFiles = append(boundVarFiles, boundVarFile)
+	}
+	boundConfig.VarFiles = boundVarFiles
+
+	var boundPreconditions []conf.Precondition
+	for _, precondition := range boundConfig.Preconditions {
+		if err := replaceAllVarsInStrings(boundVars, &precondition.HTTP, &precondition.Output); err != nil {
+			return nil, fmt.Errorf("unable to bind execution: %v; %v", e.ID(), err)
+		}
+		// commands may have braces of their own, e.g. ${HOME}
+		if precondition.Command, err = replaceVars(precondition.Command, boundVars); err != nil {
+			return nil, fmt.Errorf("unable to bind execution: %v; %v", e.ID(), err)
+		}
+		boundPreconditions = append(boundPreconditions, precondition)
+	}
+	boundConfig.Preconditions = boundPreconditions
+
 	return &boundExecution{
-		&execution{
+		execution: &execution{
 			moduleConf:          &boundConfig,
 			variables:           boundVars,
 			terraformParameters: e.TerraformParameters(),
+			targets:             e.Targets(),
 		},
 	}, nil
 }
 
+// computeVariables sets the values of the computed variables of the
+// module in vars, in the order they are configured, so that they can use
+// the computed variables before them.
+func computeVariables(moduleConfig conf.Module, vars map[string]string) error {
+	for _, variable := range moduleConfig.Variables {
+		if !variable.IsComputed() {
+			continue
+		}
+
+		data := map[string]string{"module": moduleConfig.Name}
+		for key, val := range vars {
+			data[key] = val
+		}
+
+		t, err := template.New(variable.Name).Option("missingkey=error").Parse(variable.Computed)
+		if err != nil {
+			return fmt.Errorf("computed variable %v: %v", variable.Name, err)
+		}
+		buffer := &bytes.Buffer{}
+		if err := t.Execute(buffer, data); err != nil {
+			return fmt.Errorf("computed variable %v: %v", variable.Name, err)
+		}
+		vars[variable.Name] = buffer.String()
+	}
+	return nil
+}
+
+// partialBind returns a copy of the execution with the variables that
+// userVars has values for replaced. Unlike bind, it does not need all
+// required values, so the copy may still have placeholders.
+func (e *unboundExecution) partialBind(userVars map[string]string) *unboundExecution {
+	vars := make(map[string]string)
+	for key, val := range e.Variables() {
+		vars[key] = val
+		if userVal, ok := userVars[key]; ok {
+			vars[key] = userVal
+		}
+	}
+
+	return &unboundExecution{
+		&execution{
+			moduleConf:          e.moduleConf,
+			variables:           vars,
+			terraformParameters: e.TerraformParameters(),
+			targets:             e.Targets(),
+		},
+	}
+}
+
 // boundExecution represents a module execution that is ready to be
 // executed.
 type boundExecution struct {
 	*execution
+
+	// aliases are the executions that are identical to this one, which
+	// are not run
+	aliases []*boundExecution
+}
+
+// filterFields returns the fields that filter expressions are evaluated
+// against: the module name, the execution ID, the variables and the
+// module labels, prefixed with "label.".
+func (b *boundExecution) filterFields() map[string]string {
+	fields := map[string]string{}
+	for key, val := range b.Variables() {
+		fields[key] = val
+	}
+	for key, val := range b.ModuleConfig().Labels {
+		fields["label."+key] = val
+	}
+	fields["module"] = b.ModuleConfig().Name
+	fields["id"] = b.ID()
+	return fields
+}
+
+// checkVarFile returns an error if the variable file, relative to the
+// module path, does not exist.
+func checkVarFile(moduleConfig conf.Module, varFile string) error {
+	path := varFile
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(moduleConfig.TerraformCodeRoot, moduleConfig.Path, varFile)
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("var file does not exist: %v", path)
+	}
+
+	return nil
+}
+
+// checkVarFiles checks that the variable files of every execution exist.
+// Variable files that depend on variables provided at run time are
+// skipped, as they are checked when the execution is bound.
+func (s executionSet) checkVarFiles() (errs error) {
+	for _, e := range s {
+		for _, varFile := range e.ModuleConfig().VarFiles {
+			resolved, err := replaceVars(varFile, e.Variables())
+			if err != nil {
+				errs = multierror.Append(errs, fmt.Errorf("%v: invalid var file %q: %v", e.ID(), varFile, err))
+				continue
+			}
+			if assertAllVarsReplaced(resolved) != nil {
+				continue
+			}
+			if err := checkVarFile(e.ModuleConfig(), resolved); err != nil {
+				errs = multierror.Append(errs, fmt.Errorf("%v: %v", e.ID(), err))
+			}
+		}
+	}
+	return errs
+}
+
+// bindCredentials returns a copy of credentials with the placeholders in
+// their values replaced with the bound variables.
+func bindCredentials(credentials conf.Credentials, boundVars map[string]string) (conf.Credentials, error) {
+	env, err := replaceAllVarsInMapValues(credentials.Env, boundVars)
+	if err != nil {
+		return conf.Credentials{}, err
+	}
+	bound := conf.Credentials{Env: env}
+
+	if credentials.AWS != nil {
+		aws := *credentials.AWS
+		if err := replaceAllVarsInStrings(boundVars,
+			&aws.RoleARN,
+			&aws.ExternalID); err != nil {
+			return conf.Credentials{}, err
+		}
+		bound.AWS = &aws
+	}
+
+	if credentials.Azure != nil {
+		azure := *credentials.Azure
+		if err := replaceAllVarsInStrings(boundVars,
+			&azure.TenantID,
+			&azure.SubscriptionID,
+			&azure.ClientID,
+			&azure.OIDCTokenFile); err != nil {
+			return conf.Credentials{}, err
+		}
+		bound.Azure = &azure
+	}
+
+	if credentials.GCP != nil {
+		gcp := *credentials.GCP
+		if err := replaceAllVarsInStrings(boundVars,
+			&gcp.Project,
+			&gcp.Region,
+			&gcp.CredentialsFile,
+			&gcp.ImpersonateServiceAccount); err != nil {
+			return conf.Credentials{}, err
+		}
+		bound.GCP = &gcp
+	}
+
+	return bound, nil
 }
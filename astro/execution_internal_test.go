@@ -0,0 +1,216 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"testing"
+
+	"github.com/uber/astro/astro/conf"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBindAppliesMatchingOverrides(t *testing.T) {
+	t.Parallel()
+
+	c := conf.Module{
+		Name: "app",
+		Path: "app",
+		Remote: conf.Remote{
+			BackendConfig: map[string]string{
+				"bucket": "dev-states",
+				"key":    "app/{{.environment}}.tfstate",
+			},
+		},
+		Variables: []conf.Variable{
+			{
+				Name:   "environment",
+				Values: []string{"dev", "prod"},
+			},
+		},
+		Overrides: []conf.Override{
+			{
+				When: map[string]string{"environment": "prod"},
+				Credentials: conf.Credentials{
+					Env: map[string]string{"AWS_PROFILE": "{{.environment}}-admin"},
+				},
+				Remote: conf.Remote{
+					BackendConfig: map[string]string{"bucket": "prod-states"},
+				},
+			},
+		},
+	}
+
+	bound := map[string]*boundExecution{}
+	for _, e := range newModule(c).executions(NoExecutionParameters()) {
+		b, err := e.(*unboundExecution).bind(nil)
+		require.NoError(t, err)
+		bound[b.ID()] = b
+	}
+
+	dev := bound["app-dev"].ModuleConfig()
+	assert.Equal(t, "dev-states", dev.Remote.BackendConfig["bucket"])
+	assert.Empty(t, dev.Credentials.Environment())
+
+	prod := bound["app-prod"].ModuleConfig()
+	assert.Equal(t, map[string]string{
+		"bucket": "prod-states",
+		"key":    "app/prod.tfstate",
+	}, prod.Remote.BackendConfig)
+	assert.Equal(t, map[string]string{"AWS_PROFILE": "prod-admin"}, prod.Credentials.Environment())
+
+	// the unbound module configuration must not be modified
+	assert.Equal(t, "dev-states", c.Remote.BackendConfig["bucket"])
+}
+
+func TestBindEnv(t *testing.T) {
+	t.Parallel()
+
+	c := conf.Module{
+		Name: "app",
+		Path: "app",
+		Env: map[string]string{
+			"AWS_PROFILE":     "{{.environment}}",
+			"AWS_ROLE_ARN":    "arn:aws:iam::123456789012:role/{{.environment}}-deploy",
+			"TF_LOG_PROVIDER": "info",
+		},
+		Variables: []conf.Variable{
+			{
+				Name:   "environment",
+				Values: []string{"dev", "prod"},
+			},
+		},
+		Overrides: []conf.Override{
+			{
+				When: map[string]string{"environment": "prod"},
+				Credentials: conf.Credentials{
+					Env: map[string]string{"AWS_PROFILE": "prod-admin"},
+				},
+			},
+		},
+	}
+
+	bound := map[string]*boundExecution{}
+	for _, e := range newModule(c).executions(NoExecutionParameters()) {
+		b, err := e.(*unboundExecution).bind(nil)
+		require.NoError(t, err)
+		bound[b.ID()] = b
+	}
+
+	dev := bound["app-dev"].ModuleConfig()
+	assert.Equal(t, map[string]string{
+		"AWS_PROFILE":     "dev",
+		"AWS_ROLE_ARN":    "arn:aws:iam::123456789012:role/dev-deploy",
+		"TF_LOG_PROVIDER": "info",
+	}, dev.Environment())
+
+	// credentials take precedence
+	prod := bound["app-prod"].ModuleConfig()
+	assert.Equal(t, map[string]string{
+		"AWS_PROFILE":     "prod-admin",
+		"AWS_ROLE_ARN":    "arn:aws:iam::123456789012:role/prod-deploy",
+		"TF_LOG_PROVIDER": "info",
+	}, prod.Environment())
+
+	// the unbound module configuration must not be modified
+	assert.Equal(t, "{{.environment}}", c.Env["AWS_PROFILE"])
+}
+
+func TestBindCloudCredentials(t *testing.T) {
+	c := conf.Module{
+		Name: "app",
+		Path: "app",
+		Credentials: conf.Credentials{
+			GCP: &conf.GCPCredentials{
+				Project:                   "app-{{.environment}}",
+				ImpersonateServiceAccount: "terraform@app-{{.environment}}.iam.gserviceaccount.com",
+			},
+		},
+		Variables: []conf.Variable{
+			{
+				Name:   "environment",
+				Values: []string{"dev", "prod"},
+			},
+		},
+		Overrides: []conf.Override{
+			{
+				When: map[string]string{"environment": "prod"},
+				Credentials: conf.Credentials{
+					Azure: &conf.AzureCredentials{
+						TenantID:        "tenant",
+						ClientID:        "{{.environment}}-client",
+						ClientSecretEnv: "TEST_AZURE_CLIENT_SECRET",
+					},
+					Env: map[string]string{"GOOGLE_REGION": "us-east1"},
+				},
+			},
+		},
+	}
+
+	t.Setenv("TEST_AZURE_CLIENT_SECRET", "hunter2")
+
+	bound := map[string]*boundExecution{}
+	for _, e := range newModule(c).executions(NoExecutionParameters()) {
+		b, err := e.(*unboundExecution).bind(nil)
+		require.NoError(t, err)
+		bound[b.ID()] = b
+	}
+
+	assert.Equal(t, map[string]string{
+		"GOOGLE_PROJECT":                     "app-dev",
+		"GOOGLE_IMPERSONATE_SERVICE_ACCOUNT": "terraform@app-dev.iam.gserviceaccount.com",
+	}, bound["app-dev"].ModuleConfig().Credentials.Environment())
+
+	assert.Equal(t, map[string]string{
+		"ARM_TENANT_ID":                      "tenant",
+		"ARM_CLIENT_ID":                      "prod-client",
+		"ARM_CLIENT_SECRET":                  "hunter2",
+		"GOOGLE_PROJECT":                     "app-prod",
+		"GOOGLE_REGION":                      "us-east1",
+		"GOOGLE_IMPERSONATE_SERVICE_ACCOUNT": "terraform@app-prod.iam.gserviceaccount.com",
+	}, bound["app-prod"].ModuleConfig().Credentials.Environment())
+
+	// the unbound module configuration must not be modified
+	assert.Equal(t, "app-{{.environment}}", c.Credentials.GCP.Project)
+}
+
+func TestReverseGraphOfSelection(t *testing.T) {
+	t.Parallel()
+
+	// network is not selected
+	executions := executionSet{}
+	for _, b := range bindModules(t,
+		conf.Module{Name: "app", Path: "app", Deps: []conf.Dependency{{Module: "database"}, {Module: "network"}}},
+		conf.Module{Name: "database", Path: "database"},
+	) {
+		executions = append(executions, b)
+	}
+
+	_, err := executions.graph()
+	assert.EqualError(t, err, "invalid dependency for app: missing dependency: network")
+
+	graph, err := executions.reverseGraph()
+	require.NoError(t, err)
+
+	// database is destroyed after app, which depends on it
+	var waitsOn []string
+	for _, edge := range graph.EdgesFrom(executions[1]) {
+		waitsOn = append(waitsOn, edge.Target().(*boundExecution).ID())
+	}
+	assert.Equal(t, []string{"app"}, waitsOn)
+}
@@ -0,0 +1,157 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"sort"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/utils"
+)
+
+// ExecutionSummary describes an execution of a module, for listing the
+// executions of a project.
+type ExecutionSummary struct {
+	ID     string
+	Module string
+	// Variables is the values of the module variables for this
+	// execution. Required variables that were not given a value keep a
+	// placeholder, e.g. "{aws_region}".
+	Variables map[string]string
+	// Dependencies is the IDs of the executions this one depends on,
+	// sorted.
+	Dependencies []string
+}
+
+// ListExecutions returns the executions that the parameters select, sorted
+// by ID, with the executions they depend on. Unlike Dependencies, it does
+// not need values for required variables: the variables that were not
+// given keep their placeholder, and so do the execution IDs.
+func (c *Project) ListExecutions(parameters ExecutionParameters) ([]ExecutionSummary, error) {
+	selected := c.executions(parameters)
+	if len(selected) == 0 && parameters.UserVars.FilterCount() > 0 {
+		return nil, c.noExecutionsMatched(parameters)
+	}
+
+	// The graph is built from all executions, as dependencies that are
+	// filtered out cannot be resolved otherwise
+	values := map[string]string{}
+	for key, val := range parameters.UserVars.Values {
+		if !parameters.UserVars.HasFilter(key) {
+			values[key] = val
+		}
+	}
+	var all executionSet
+	for _, e := range c.executions(ExecutionParameters{UserVars: &UserVariables{Values: values}}) {
+		all = append(all, e.(*unboundExecution).partialBind(values))
+	}
+
+	graph, err := all.graph()
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := map[string]*ExecutionSummary{}
+	for _, e := range selected {
+		e = e.(*unboundExecution).partialBind(parameters.UserVars.Values)
+		summaries[e.ID()] = &ExecutionSummary{
+			ID:           e.ID(),
+			Module:       e.ModuleConfig().Name,
+			Variables:    e.Variables(),
+			Dependencies: []string{},
+		}
+	}
+	for _, edge := range graph.Edges() {
+		if _, ok := edge.Source().(graphNodeRoot); ok {
+			continue
+		}
+		id := edge.Source().(terraformExecution).ID()
+		if summary, ok := summaries[id]; ok {
+			summary.Dependencies = append(summary.Dependencies, edge.Target().(terraformExecution).ID())
+		}
+	}
+
+	var results []ExecutionSummary
+	for _, summary := range summaries {
+		sort.Strings(summary.Dependencies)
+		results = append(results, *summary)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].ID < results[j].ID
+	})
+
+	return results, nil
+}
+
+// ModuleSummary describes a module of a project, for listing the modules
+// of a project.
+type ModuleSummary struct {
+	Name string
+	Path string
+	// Variables are the variables of the module, with their values if
+	// they have any.
+	Variables []conf.Variable
+	Labels    map[string]string
+	Owners    []string
+	// Executions is the number of executions of the module when no
+	// variables are given.
+	Executions int
+	// Dependencies are the names of the modules this one depends on, and
+	// Dependents the names of the modules that depend on it, sorted.
+	Dependencies []string
+	Dependents   []string
+}
+
+// ListModules returns the modules of the project, in the order they are
+// configured.
+func (c *Project) ListModules() []ModuleSummary {
+	dependents := map[string][]string{}
+	for _, moduleConfig := range c.config.Modules {
+		for _, dep := range moduleConfig.Deps {
+			if !utils.StringSliceContains(dependents[dep.Module], moduleConfig.Name) {
+				dependents[dep.Module] = append(dependents[dep.Module], moduleConfig.Name)
+			}
+		}
+	}
+
+	results := []ModuleSummary{}
+	for _, m := range c.modules(nil) {
+		summary := ModuleSummary{
+			Name:         m.config.Name,
+			Path:         m.config.Path,
+			Variables:    m.config.Variables,
+			Labels:       m.config.Labels,
+			Owners:       m.config.Owners,
+			Executions:   len(m.executions(NoExecutionParameters())),
+			Dependencies: []string{},
+			Dependents:   dependents[m.config.Name],
+		}
+		for _, dep := range m.config.Deps {
+			if !utils.StringSliceContains(summary.Dependencies, dep.Module) {
+				summary.Dependencies = append(summary.Dependencies, dep.Module)
+			}
+		}
+		if summary.Dependents == nil {
+			summary.Dependents = []string{}
+		}
+		sort.Strings(summary.Dependencies)
+		sort.Strings(summary.Dependents)
+		results = append(results, summary)
+	}
+
+	return results
+}
@@ -16,19 +16,87 @@
 
 package astro
 
+import "github.com/uber/astro/astro/scheduler"
+
 type ExecutionParameters struct {
 	ModuleNames         []string
 	UserVars            *UserVariables
 	TerraformParameters []string
+	// Frozen fails the run if module sources do not match the lock file.
+	Frozen bool
+	// ExecutionIDs, if set, limits the run to the executions with these
+	// IDs.
+	ExecutionIDs []string
+	// ExecutionPatterns, if set, limits the run to the executions whose
+	// IDs match any of these glob patterns, e.g. "app-*-dev". Each
+	// pattern must match at least one execution.
+	ExecutionPatterns []string
+	// Filter, if set, is a filter expression that executions must match,
+	// e.g. `module =~ "app|db" && environment != "prod"`. See the filter
+	// package for the syntax.
+	Filter string
+	// Targets, if set, are Terraform resource addresses passed to plan,
+	// apply and destroy as -target, e.g. "aws_instance.web". They apply
+	// to every selected execution.
+	Targets []string
+	// Parallelism, if set, overrides the maximum number of executions that
+	// run at the same time from the project configuration.
+	Parallelism int
+	// Scheduler, if set, schedules the executions instead of a new
+	// scheduler with Parallelism, so that the caller can change its
+	// limits, or pause and resume it, while they run. See
+	// Project.NewScheduler.
+	Scheduler *scheduler.Scheduler
+}
+
+// targeted returns whether the parameters select executions by module
+// name, execution ID or filter expression, in which case the executions they depend on may
+// not be included.
+func (p ExecutionParameters) targeted() bool {
+	return p.ModuleNames != nil || p.ExecutionIDs != nil || p.ExecutionPatterns != nil || p.Filter != ""
 }
 
 type PlanExecutionParameters struct {
 	ExecutionParameters
 	Detach bool
+	// SavePlans records the plans in the session directory, so that they
+	// can be applied with ApplyExecutionParameters.FromSession.
+	SavePlans bool
+	// UseGraph plans executions after the executions they depend on,
+	// and skips them if planning a dependency fails.
+	UseGraph bool
+	// DetectDrift plans with -refresh-only, so that executions whose
+	// infrastructure was changed outside of Terraform have changes. See
+	// terraform.Config.RefreshOnly.
+	DetectDrift bool
+	// EstimateCost estimates the cost of the changes in each plan, with
+	// the cost estimation configured in the project.
+	EstimateCost bool
+	// RawOutput does not parse the output of Terraform, e.g. if it cannot
+	// be parsed. See terraform.Config.RawOutput.
+	RawOutput bool
 }
 
 type ApplyExecutionParameters struct {
 	ExecutionParameters
+	// FromSession is the ID of a session whose saved plans should be
+	// applied, instead of planning again. The user variables and filters
+	// the plans were made with are used. If ExecutionIDs is set, only the
+	// saved plans of those executions are applied.
+	FromSession string
+	// SameVersionsAs is the ID of a session, usually the one the changes
+	// were planned in, whose Terraform binaries should be used: every
+	// execution runs with the identical binary it ran with in that
+	// session, regardless of the configured version.
+	SameVersionsAs string
+	// Resume is the ID of a session whose last apply should be resumed:
+	// only its executions that failed, were skipped or didn't run are
+	// applied, with the parameters they were bound with.
+	Resume string
+	// ApproveRisk approves applying plans whose risk score is above the
+	// approve_above threshold of the risk config. Plans above block_above
+	// are never applied.
+	ApproveRisk bool
 }
 
 func NoExecutionParameters() ExecutionParameters {
@@ -44,3 +112,7 @@ func NoPlanExecutionParameters() PlanExecutionParameters {
 		},
 	}
 }
+
+type DestroyExecutionParameters struct {
+	ExecutionParameters
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 
 	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/utils"
 
 	"github.com/hashicorp/terraform/dag"
 )
@@ -50,10 +51,10 @@ func (s executionSet) bindAll(userVars map[string]string) ([]*boundExecution, er
 }
 
 // filterByModule returns all the executions in this set that match
-// moduleName.
+// moduleName, or have an alias that does.
 func (s executionSet) filterByModule(moduleName string) (results executionSet) {
 	for _, e := range s {
-		if e.ModuleConfig().Name == moduleName {
+		if e.ModuleConfig().Name == moduleName || utils.StringSliceContains(aliasModules(e), moduleName) {
 			results = append(results, e)
 		}
 	}
@@ -94,6 +95,21 @@ func (s executionSet) filterByDep(dep conf.Dependency) (executionSet, error) {
 
 // graph returns an acyclic graph of executions in this set.
 func (s executionSet) graph() (*dag.AcyclicGraph, error) {
+	return s.buildGraph(false)
+}
+
+// selectionGraph returns an acyclic graph of executions in this set, which
+// were selected from the executions of the project, e.g. with --modules or
+// --filter. Dependencies on executions that were not selected are ignored,
+// as those executions don't run.
+func (s executionSet) selectionGraph() (*dag.AcyclicGraph, error) {
+	return s.buildGraph(true)
+}
+
+// buildGraph returns an acyclic graph of executions in this set. If
+// selected is set, dependencies that match no execution in the set are
+// ignored instead of being an error.
+func (s executionSet) buildGraph(selected bool) (*dag.AcyclicGraph, error) {
 	graph := &dag.AcyclicGraph{}
 
 	// Add all executions to the graph to start off with
@@ -104,7 +120,7 @@ func (s executionSet) graph() (*dag.AcyclicGraph, error) {
 	// For each execution, we need to find the dependencies and connect
 	// them in the graph.
 	for _, e := range s {
-		for _, dep := range e.ModuleConfig().Deps {
+		for _, dep := range executionDeps(e) {
 			// Fill in any placeholders in the dependency with variable
 			// values from the current execution.
 			vars, err := replaceVarsInMapValues(dep.Variables, e.Variables())
@@ -114,10 +130,16 @@ func (s executionSet) graph() (*dag.AcyclicGraph, error) {
 			dep.Variables = vars
 
 			dependentExecutions, err := s.filterByDep(dep)
-			if err != nil {
+			if err != nil && selected {
+				continue
+			} else if err != nil {
 				return nil, fmt.Errorf("invalid dependency for %s: %v", e.ModuleConfig().Name, err)
 			}
 			for _, dependentExecution := range dependentExecutions {
+				// an alias can depend on the module of the execution
+				if dependentExecution == e {
+					continue
+				}
 				graph.Connect(dag.BasicEdge(e, dependentExecution))
 			}
 		}
@@ -130,3 +152,35 @@ func (s executionSet) graph() (*dag.AcyclicGraph, error) {
 
 	return graph, nil
 }
+
+// reverseGraph returns the selection graph of the executions in this set,
+// with all dependencies reversed, so that walking it visits executions
+// before the executions they depend on.
+func (s executionSet) reverseGraph() (*dag.AcyclicGraph, error) {
+	graph, err := s.selectionGraph()
+	if err != nil {
+		return nil, err
+	}
+
+	reversed := &dag.AcyclicGraph{}
+
+	for _, v := range graph.Vertices() {
+		if _, ok := v.(graphNodeRoot); ok {
+			continue
+		}
+		reversed.Add(v)
+	}
+
+	for _, edge := range graph.Edges() {
+		if _, ok := edge.Source().(graphNodeRoot); ok {
+			continue
+		}
+		reversed.Connect(dag.BasicEdge(edge.Target(), edge.Source()))
+	}
+
+	if err := addRoot(reversed); err != nil {
+		return nil, err
+	}
+
+	return reversed, nil
+}
@@ -0,0 +1,289 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/uber/astro/astro/utils"
+)
+
+// Sources of the values of the variables of an execution.
+const (
+	// VariableSourceValues is a value of the values of the variable in the
+	// module configuration. The module runs for each of them.
+	VariableSourceValues = "values"
+	// VariableSourceForEach is the key or a variable of the foreach item
+	// the execution was replicated for.
+	VariableSourceForEach = "foreach"
+	// VariableSourceUser is a value that was passed, e.g. as a flag.
+	VariableSourceUser = "user"
+	// VariableSourceExecutionID is a value that was read from the
+	// execution ID, as it wasn't passed.
+	VariableSourceExecutionID = "execution ID"
+	// VariableSourceDefault is the default of a variable that is not
+	// required.
+	VariableSourceDefault = "default"
+	// VariableSourceComputed is the value of a computed variable, derived
+	// from the other variables.
+	VariableSourceComputed = "computed"
+)
+
+// ExplainedVariable is a variable of an execution, with where its value
+// came from.
+type ExplainedVariable struct {
+	Name   string
+	Value  string
+	Source string
+	// Filter is whether the variable has values, so that passing it
+	// selects the execution.
+	Filter bool
+}
+
+// ExecutionExplanation describes how an execution is derived from the
+// configuration.
+type ExecutionExplanation struct {
+	ID     string
+	Module string
+	// Path is the path of the module, relative to the Terraform code
+	// root.
+	Path string
+
+	// Variables are the variables of the execution, sorted by name.
+	Variables []ExplainedVariable
+	// Missing are the required variables that have no value, so the
+	// execution can't run yet. Its backend configuration is not templated
+	// if there are any.
+	Missing []string
+
+	TerraformPath    string
+	TerraformVersion string
+
+	Backend       string
+	BackendConfig map[string]string
+	// Overrides are the indexes of the overrides that apply to the
+	// execution, in the order they are applied.
+	Overrides []int
+
+	// Dependencies are the executions this one depends on, and Dependents
+	// the executions that depend on it.
+	Dependencies []string
+	Dependents   []string
+}
+
+// Explain returns how the execution with the ID is derived from the
+// configuration. Variables that are not in parameters are read from the
+// ID where they can be, e.g. the region of app-dev-us-east-1.
+func (c *Project) Explain(parameters ExecutionParameters, id string) (*ExecutionExplanation, error) {
+	e, fromID, err := c.findExecution(parameters, id)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	for key, val := range parameters.UserVars.Values {
+		values[key] = val
+	}
+	for key, val := range fromID {
+		values[key] = val
+	}
+
+	moduleConfig := e.ModuleConfig()
+	explanation := &ExecutionExplanation{
+		ID:     id,
+		Module: moduleConfig.Name,
+		Path:   moduleConfig.Path,
+	}
+	if moduleConfig.Terraform.Version != nil {
+		explanation.TerraformVersion = moduleConfig.Terraform.Version.String()
+	}
+	explanation.TerraformPath = c.terraformPath(moduleConfig.Terraform)
+
+	explanation.Variables = explainVariables(e, parameters.UserVars.Values, fromID)
+
+	b, err := e.bind(values)
+	var missingErr *MissingRequiredVarsError
+	if errors.As(err, &missingErr) {
+		explanation.Missing = missingErr.MissingVars()
+		sort.Strings(explanation.Missing)
+		moduleConfig = e.partialBind(values).ModuleConfig()
+	} else if err != nil {
+		return nil, err
+	} else {
+		moduleConfig = b.ModuleConfig()
+		// computed variables only have values once all the others do
+		for _, variable := range moduleConfig.Variables {
+			if variable.IsComputed() {
+				explanation.Variables = append(explanation.Variables, ExplainedVariable{
+					Name:   variable.Name,
+					Value:  b.Variables()[variable.Name],
+					Source: VariableSourceComputed,
+				})
+			}
+		}
+		sort.Slice(explanation.Variables, func(i, j int) bool {
+			return explanation.Variables[i].Name < explanation.Variables[j].Name
+		})
+	}
+
+	explanation.Backend = moduleConfig.Remote.Backend
+	explanation.BackendConfig = moduleConfig.Remote.BackendConfig
+	variables := e.partialBind(values).Variables()
+	for i, override := range moduleConfig.Overrides {
+		if override.Matches(variables) {
+			explanation.Overrides = append(explanation.Overrides, i)
+		}
+	}
+
+	// Dependencies are resolved like list does, with the values that
+	// don't select executions
+	listValues := map[string]string{}
+	for _, variable := range explanation.Variables {
+		if !variable.Filter && (variable.Source == VariableSourceUser || variable.Source == VariableSourceExecutionID) {
+			listValues[variable.Name] = variable.Value
+		}
+	}
+	summaries, err := c.ListExecutions(ExecutionParameters{UserVars: &UserVariables{Values: listValues}})
+	if err != nil {
+		return nil, err
+	}
+	for _, summary := range summaries {
+		if summary.ID == id {
+			explanation.Dependencies = summary.Dependencies
+		} else if utils.StringSliceContains(summary.Dependencies, id) {
+			explanation.Dependents = append(explanation.Dependents, summary.ID)
+		}
+	}
+
+	return explanation, nil
+}
+
+// findExecution returns the execution with the ID, and the values of the
+// variables it has placeholders for that were read from the ID.
+func (c *Project) findExecution(parameters ExecutionParameters, id string) (*unboundExecution, map[string]string, error) {
+	var ids []string
+	var matches []*unboundExecution
+	var matchValues []map[string]string
+
+	for _, e := range c.executions(parameters) {
+		e := e.(*unboundExecution).partialBind(parameters.UserVars.Values)
+		ids = append(ids, e.ID())
+		if e.ID() == id {
+			return e, nil, nil
+		}
+		if values := matchPlaceholders(e, id); values != nil {
+			matches = append(matches, e)
+			matchValues = append(matchValues, values)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		if suggestions := utils.ClosestMatches(id, ids); suggestions != nil {
+			return nil, nil, fmt.Errorf("no execution %s; did you mean %s?", id, strings.Join(suggestions, " or "))
+		}
+		return nil, nil, fmt.Errorf("no execution %s", id)
+	case 1:
+		return matches[0], matchValues[0], nil
+	}
+
+	var candidates []string
+	for _, e := range matches {
+		candidates = append(candidates, e.ID())
+	}
+	return nil, nil, fmt.Errorf("%s could be any of %s; pass the values of their variables", id, strings.Join(candidates, ", "))
+}
+
+// placeholderPattern matches the placeholders of variables in an execution
+// ID that was escaped by regexp.QuoteMeta.
+var placeholderPattern = regexp.MustCompile(`\\\{([^}]+)\\\}`)
+
+// matchPlaceholders matches the ID of the execution, whose variables may
+// have placeholders, against id. If it matches, it returns the values of
+// the placeholders, and otherwise nil.
+func matchPlaceholders(e *unboundExecution, id string) map[string]string {
+	pattern := regexp.QuoteMeta(e.ID())
+
+	var names []string
+	for _, match := range placeholderPattern.FindAllStringSubmatch(pattern, -1) {
+		names = append(names, match[1])
+	}
+	if names == nil {
+		return nil
+	}
+
+	re, err := regexp.Compile("^" + placeholderPattern.ReplaceAllString(pattern, "(.+)") + "$")
+	if err != nil {
+		return nil
+	}
+	match := re.FindStringSubmatch(id)
+	if match == nil {
+		return nil
+	}
+
+	values := map[string]string{}
+	for i, name := range names {
+		values[name] = match[i+1]
+	}
+	return values
+}
+
+// explainVariables returns the variables of the execution, with where
+// their values come from.
+func explainVariables(e *unboundExecution, userValues, fromID map[string]string) []ExplainedVariable {
+	moduleConfig := e.ModuleConfig()
+
+	var variables []ExplainedVariable
+	for name, value := range e.Variables() {
+		variable := ExplainedVariable{Name: name, Value: value}
+
+		for _, variableConfig := range moduleConfig.Variables {
+			if variableConfig.Name != name {
+				continue
+			}
+			variable.Filter = variableConfig.IsFilter()
+			switch {
+			case moduleConfig.ForEach != nil && name == moduleConfig.ForEach.Key:
+				variable.Source = VariableSourceForEach
+			case userValues[name] != "":
+				variable.Source = VariableSourceUser
+			case fromID[name] != "":
+				variable.Source = VariableSourceExecutionID
+				variable.Value = fromID[name]
+			case variableConfig.IsFilter():
+				variable.Source = VariableSourceValues
+			case !variableConfig.IsRequired():
+				variable.Source = VariableSourceDefault
+			}
+		}
+		// the other variables are those of the foreach item
+		if variable.Source == "" && moduleConfig.ForEach != nil {
+			variable.Source = VariableSourceForEach
+		}
+
+		variables = append(variables, variable)
+	}
+
+	sort.Slice(variables, func(i, j int) bool {
+		return variables[i].Name < variables[j].Name
+	})
+	return variables
+}
@@ -0,0 +1,142 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newExplainTestProject(t *testing.T) *Project {
+	c, err := NewProjectFromYAML([]byte(`
+terraform:
+  path: fixtures/mock-terraform/success
+overrides:
+  - when: {environment: prod}
+    remote:
+      backend_config:
+        bucket: states-prod
+modules:
+  - name: network
+    path: .
+    variables:
+      - name: environment
+        values: [dev, prod]
+  - name: app
+    path: .
+    remote:
+      backend: s3
+      backend_config:
+        key: "app/{{.environment}}/{{.region}}"
+    deps:
+      - module: network
+    variables:
+      - name: environment
+        values: [dev, prod]
+      - name: region
+      - name: size
+        required: false
+        default: small
+`))
+	require.NoError(t, err)
+	return c
+}
+
+func TestExplain(t *testing.T) {
+	t.Parallel()
+
+	c := newExplainTestProject(t)
+
+	explanation, err := c.Explain(NoExecutionParameters(), "app-prod-us-east-1-small")
+	require.NoError(t, err)
+
+	assert.Equal(t, "app", explanation.Module)
+	assert.Equal(t, []ExplainedVariable{
+		{Name: "environment", Value: "prod", Source: VariableSourceValues, Filter: true},
+		{Name: "region", Value: "us-east-1", Source: VariableSourceExecutionID},
+		{Name: "size", Value: "small", Source: VariableSourceDefault},
+	}, explanation.Variables)
+	assert.Empty(t, explanation.Missing)
+	assert.Equal(t, "s3", explanation.Backend)
+	assert.Equal(t, map[string]string{"bucket": "states-prod", "key": "app/prod/us-east-1"}, explanation.BackendConfig)
+	assert.Equal(t, []int{0}, explanation.Overrides)
+	assert.Equal(t, []string{"network-dev", "network-prod"}, explanation.Dependencies)
+	assert.Empty(t, explanation.Dependents)
+
+	explanation, err = c.Explain(NoExecutionParameters(), "network-dev")
+	require.NoError(t, err)
+	assert.Empty(t, explanation.Overrides)
+	assert.Equal(t, []string{"app-dev-{region}-small", "app-prod-{region}-small"}, explanation.Dependents)
+}
+
+func TestExplainUserVariables(t *testing.T) {
+	t.Parallel()
+
+	c := newExplainTestProject(t)
+
+	explanation, err := c.Explain(ExecutionParameters{
+		UserVars: &UserVariables{
+			Values:  map[string]string{"environment": "dev"},
+			Filters: map[string]bool{"environment": true},
+		},
+	}, "app-dev-{region}-small")
+	require.NoError(t, err)
+
+	assert.Equal(t, VariableSourceUser, explanation.Variables[0].Source)
+	assert.Equal(t, []string{"region"}, explanation.Missing)
+	assert.Equal(t, "app/{{.environment}}/{{.region}}", explanation.BackendConfig["key"])
+}
+
+func TestExplainUnknownExecution(t *testing.T) {
+	t.Parallel()
+
+	c := newExplainTestProject(t)
+
+	_, err := c.Explain(NoExecutionParameters(), "network-dv")
+	assert.EqualError(t, err, "no execution network-dv; did you mean network-dev?")
+}
+
+func TestExplainModuleTerraformVersion(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromYAML([]byte(`
+terraform:
+  path: fixtures/mock-terraform/success
+modules:
+  - name: app
+    path: .
+  - name: legacy
+    path: .
+    terraform:
+      version: 0.12.31
+`))
+	require.NoError(t, err)
+
+	explanation, err := c.Explain(NoExecutionParameters(), "app")
+	require.NoError(t, err)
+	assert.Equal(t, "0.8.8", explanation.TerraformVersion)
+	assert.Equal(t, absolutePath("fixtures/mock-terraform/success"), explanation.TerraformPath)
+
+	// the version of the module is installed by tvm, rather than run with
+	// the project's path
+	explanation, err = c.Explain(NoExecutionParameters(), "legacy")
+	require.NoError(t, err)
+	assert.Equal(t, "0.12.31", explanation.TerraformVersion)
+	assert.Equal(t, c.terraformVersions.Path("0.12.31"), explanation.TerraformPath)
+}
@@ -0,0 +1,315 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package filter implements the expression language used to select
+// executions, e.g.
+//
+//	module =~ "app|db" && environment != "prod" && label.team == "payments"
+//
+// Expressions compare fields to quoted strings with ==, !=, =~ (regular
+// expression match) and !~, and combine comparisons with &&, || and !,
+// using parentheses for grouping. Regular expressions must match the whole
+// value. Fields that are not set are empty.
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expression is a parsed filter expression.
+type Expression struct {
+	source string
+	root   node
+}
+
+// Parse parses a filter expression.
+func Parse(source string) (*Expression, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %v", source, err)
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && !p.done() {
+		err = fmt.Errorf("unexpected %s", p.peek())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %v", source, err)
+	}
+
+	return &Expression{source: source, root: root}, nil
+}
+
+// Match returns whether the fields match the expression.
+func (e *Expression) Match(fields map[string]string) bool {
+	return e.root.match(fields)
+}
+
+// String returns the expression as it was written.
+func (e *Expression) String() string {
+	return e.source
+}
+
+type node interface {
+	match(fields map[string]string) bool
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) match(fields map[string]string) bool {
+	return n.left.match(fields) && n.right.match(fields)
+}
+
+type orNode struct{ left, right node }
+
+func (n orNode) match(fields map[string]string) bool {
+	return n.left.match(fields) || n.right.match(fields)
+}
+
+type notNode struct{ operand node }
+
+func (n notNode) match(fields map[string]string) bool {
+	return !n.operand.match(fields)
+}
+
+type comparisonNode struct {
+	field  string
+	op     string
+	value  string
+	regexp *regexp.Regexp
+}
+
+func (n comparisonNode) match(fields map[string]string) bool {
+	actual := fields[n.field]
+	switch n.op {
+	case "==":
+		return actual == n.value
+	case "!=":
+		return actual != n.value
+	case "=~":
+		return n.regexp.MatchString(actual)
+	default: // "!~"
+		return !n.regexp.MatchString(actual)
+	}
+}
+
+// token kinds
+const (
+	tokenField = iota
+	tokenString
+	tokenOperator
+)
+
+type token struct {
+	kind  int
+	value string
+}
+
+func (t token) String() string {
+	if t.kind == tokenString {
+		return strconv.Quote(t.value)
+	}
+	return t.value
+}
+
+// operators are the operators of the language. Longer operators that
+// start with the same character must come first.
+var operators = []string{"==", "!=", "=~", "!~", "&&", "||", "!", "(", ")"}
+
+// lex splits the source into tokens.
+func lex(source string) ([]token, error) {
+	var tokens []token
+
+	for i := 0; i < len(source); {
+		c := rune(source[i])
+
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c == '"':
+			// find the closing quote, skipping escaped characters
+			end := i + 1
+			for end < len(source) && source[end] != '"' {
+				if source[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(source) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			value, err := strconv.Unquote(source[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at %d: %v", i, err)
+			}
+			tokens = append(tokens, token{tokenString, value})
+			i = end + 1
+
+		case isFieldChar(c):
+			end := i
+			for end < len(source) && isFieldChar(rune(source[end])) {
+				end++
+			}
+			tokens = append(tokens, token{tokenField, source[i:end]})
+			i = end
+
+		default:
+			found := false
+			for _, op := range operators {
+				if strings.HasPrefix(source[i:], op) {
+					tokens = append(tokens, token{tokenOperator, op})
+					i += len(op)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("unexpected %q at %d", c, i)
+			}
+		}
+	}
+
+	return tokens, nil
+}
+
+func isFieldChar(c rune) bool {
+	return c == '_' || c == '.' || c == '-' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+// parser is a recursive descent parser for the grammar:
+//
+//	or         = and { "||" and }
+//	and        = unary { "&&" unary }
+//	unary      = "!" unary | "(" or ")" | comparison
+//	comparison = field ( "==" | "!=" | "=~" | "!~" ) string
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *parser) peek() string {
+	if p.done() {
+		return "end of filter"
+	}
+	return p.tokens[p.pos].String()
+}
+
+// accept consumes the next token if it is the operator op.
+func (p *parser) accept(op string) bool {
+	if !p.done() && p.tokens[p.pos].kind == tokenOperator && p.tokens[p.pos].value == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.accept("!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	}
+
+	if p.accept("(") {
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("expected ) but found %s", p.peek())
+		}
+		return inner, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	if p.done() || p.tokens[p.pos].kind != tokenField {
+		return nil, fmt.Errorf("expected a field but found %s", p.peek())
+	}
+	field := p.tokens[p.pos].value
+	p.pos++
+
+	var op string
+	for _, candidate := range []string{"==", "!=", "=~", "!~"} {
+		if p.accept(candidate) {
+			op = candidate
+			break
+		}
+	}
+	if op == "" {
+		return nil, fmt.Errorf("expected ==, !=, =~ or !~ after %s but found %s", field, p.peek())
+	}
+
+	if p.done() || p.tokens[p.pos].kind != tokenString {
+		return nil, fmt.Errorf("expected a quoted value after %s %s but found %s", field, op, p.peek())
+	}
+	comparison := comparisonNode{field: field, op: op, value: p.tokens[p.pos].value}
+	p.pos++
+
+	if op == "=~" || op == "!~" {
+		re, err := regexp.Compile("^(?:" + comparison.value + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %v", comparison.value, err)
+		}
+		comparison.regexp = re
+	}
+
+	return comparison, nil
+}
@@ -0,0 +1,83 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filter_test
+
+import (
+	"testing"
+
+	"github.com/uber/astro/astro/filter"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatch(t *testing.T) {
+	fields := map[string]string{
+		"module":      "database",
+		"environment": "dev",
+		"label.team":  "payments",
+	}
+
+	tt := []struct {
+		expr    string
+		matches bool
+	}{
+		{`module == "database"`, true},
+		{`module != "database"`, false},
+		{`module =~ "app|database"`, true},
+		{`module =~ "data"`, false}, // regular expressions match the whole value
+		{`module !~ "app.*"`, true},
+		{`module =~ "app|database" && environment != "prod" && label.team == "payments"`, true},
+		{`environment == "prod" || label.team == "payments"`, true},
+		{`!(environment == "dev")`, false},
+		{`environment == "prod" || environment == "staging" && module == "database"`, false},
+		{`(environment == "dev" || environment == "staging") && module == "database"`, true},
+		{`region == ""`, true}, // missing fields are empty
+		{`label.team == "say \"hi\""`, false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.expr, func(t *testing.T) {
+			expr, err := filter.Parse(tc.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tc.matches, expr.Match(fields))
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tt := []struct {
+		expr string
+		err  string
+	}{
+		{`module`, `invalid filter "module": expected ==, !=, =~ or !~ after module but found end of filter`},
+		{`module == app`, `invalid filter "module == app": expected a quoted value after module == but found app`},
+		{`module == "app" &&`, `invalid filter "module == \"app\" &&": expected a field but found end of filter`},
+		{`(module == "app"`, `invalid filter "(module == \"app\"": expected ) but found end of filter`},
+		{`module == "app`, `invalid filter "module == \"app": unterminated string at 10`},
+		{`module =~ "("`, "invalid filter \"module =~ \\\"(\\\"\": invalid regular expression \"(\": error parsing regexp: missing closing ): `^(?:()$`"},
+		{`module == "app" module == "db"`, `invalid filter "module == \"app\" module == \"db\"": unexpected module`},
+		{`module = "app"`, `invalid filter "module = \"app\"": unexpected '=' at 7`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.expr, func(t *testing.T) {
+			_, err := filter.Parse(tc.expr)
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}
@@ -0,0 +1,19 @@
+variable "environment" {}
+
+data "terraform_remote_state" "network" {
+  backend = "s3"
+
+  config = {
+    bucket = "states"
+    key    = "network/${var.environment}.tfstate"
+  }
+}
+
+data "terraform_remote_state" "dns" {
+  backend = "s3"
+
+  config {
+    bucket = "states"
+    key    = "dns.tfstate"
+  }
+}
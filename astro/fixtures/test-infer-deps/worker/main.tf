@@ -0,0 +1,7 @@
+data "terraform_remote_state" "network" {
+  backend = "s3"
+
+  config = {
+    key = "network/${terraform.workspace}.tfstate"
+  }
+}
@@ -0,0 +1,62 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/uber/astro/astro/git"
+	"github.com/uber/astro/astro/logger"
+)
+
+// verifyCleanTree checks that the directories of the specified modules
+// have no uncommitted changes and that the current commit has been pushed.
+// It returns the SHA of the current commit.
+func (c *Project) verifyCleanTree(moduleNames []string) (string, error) {
+	codeRoot := c.config.TerraformCodeRoot
+
+	var paths []string
+	for _, module := range c.modules(moduleNames) {
+		paths = append(paths, module.config.Path)
+	}
+
+	dirtyFiles, err := git.DirtyFiles(codeRoot, paths...)
+	if err != nil {
+		return "", fmt.Errorf("unable to check working tree is clean: %v", err)
+	}
+	if len(dirtyFiles) > 0 {
+		return "", fmt.Errorf("require_clean_tree is set, but module sources have uncommitted changes: %s", strings.Join(dirtyFiles, ", "))
+	}
+
+	pushed, err := git.IsPushed(codeRoot)
+	if err != nil {
+		return "", fmt.Errorf("unable to check commit has been pushed: %v", err)
+	}
+	if !pushed {
+		return "", fmt.Errorf("require_clean_tree is set, but the current commit has not been pushed to a remote branch")
+	}
+
+	sha, err := git.HeadSHA(codeRoot)
+	if err != nil {
+		return "", err
+	}
+
+	logger.Trace.Printf("astro: working tree is clean at %v", sha)
+
+	return sha, nil
+}
@@ -0,0 +1,104 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package git contains helpers for inspecting the git working tree that
+// contains the Terraform code.
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/uber/astro/astro/logger"
+)
+
+// run runs git with the specified args in dir and returns its stdout,
+// without the trailing newline.
+func run(dir string, args ...string) (string, error) {
+	logger.Trace.Printf("git: running git %v in %v", args, dir)
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimRight(stdout.String(), "\n"), nil
+}
+
+// HeadSHA returns the SHA of the commit checked out in dir.
+func HeadSHA(dir string) (string, error) {
+	return run(dir, "rev-parse", "HEAD")
+}
+
+// DirtyFiles returns the files under the specified paths in dir that have
+// uncommitted changes, including untracked files. If no paths are
+// specified, the whole working tree is checked.
+func DirtyFiles(dir string, paths ...string) ([]string, error) {
+	args := append([]string{"status", "--porcelain", "--"}, paths...)
+
+	out, err := run(dir, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, line := range strings.Split(out, "\n") {
+		if len(line) > 3 {
+			files = append(files, line[3:])
+		}
+	}
+
+	return files, nil
+}
+
+// IsPushed returns whether the commit checked out in dir is contained in
+// at least one remote branch.
+func IsPushed(dir string) (bool, error) {
+	out, err := run(dir, "branch", "--remotes", "--contains", "HEAD")
+	if err != nil {
+		return false, err
+	}
+
+	return out != "", nil
+}
+
+// TopLevel returns the root of the working tree that contains dir.
+func TopLevel(dir string) (string, error) {
+	return run(dir, "rev-parse", "--show-toplevel")
+}
+
+// AddWorktree checks out ref, detached, in a new working tree at path,
+// which shares its repository with the working tree in dir.
+func AddWorktree(dir, path, ref string) error {
+	_, err := run(dir, "worktree", "add", "--detach", "--quiet", path, ref)
+	return err
+}
+
+// RemoveWorktree removes the working tree at path that was added by
+// AddWorktree, even if it has changes.
+func RemoveWorktree(dir, path string) error {
+	_, err := run(dir, "worktree", "remove", "--force", path)
+	return err
+}
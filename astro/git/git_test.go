@@ -0,0 +1,118 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package git_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/uber/astro/astro/git"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gitCmd(t *testing.T, dir string, args ...string) {
+	cmd := exec.Command("git", append([]string{
+		"-c", "user.name=test", "-c", "user.email=test@example.com",
+	}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+}
+
+func TestWorkingTree(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	remote := t.TempDir()
+	gitCmd(t, remote, "init", "--bare", "--quiet")
+
+	dir := t.TempDir()
+	gitCmd(t, dir, "init", "--quiet")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "app"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app/main.tf"), []byte("# app\n"), 0644))
+	gitCmd(t, dir, "add", ".")
+	gitCmd(t, dir, "commit", "--quiet", "-m", "initial")
+
+	sha, err := git.HeadSHA(dir)
+	require.NoError(t, err)
+	assert.Len(t, sha, 40)
+
+	files, err := git.DirtyFiles(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	// changes outside of the checked paths are ignored
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README"), []byte("readme\n"), 0644))
+	files, err = git.DirtyFiles(dir, "app")
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app/main.tf"), []byte("# changed\n"), 0644))
+	files, err = git.DirtyFiles(dir, "app")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app/main.tf"}, files)
+
+	pushed, err := git.IsPushed(dir)
+	require.NoError(t, err)
+	assert.False(t, pushed)
+
+	gitCmd(t, dir, "remote", "add", "origin", remote)
+	gitCmd(t, dir, "push", "--quiet", "origin", "HEAD:refs/heads/main")
+	gitCmd(t, dir, "fetch", "--quiet", "origin")
+
+	pushed, err = git.IsPushed(dir)
+	require.NoError(t, err)
+	assert.True(t, pushed)
+}
+
+func TestWorktree(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	gitCmd(t, dir, "init", "--quiet")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte("# first\n"), 0644))
+	gitCmd(t, dir, "add", ".")
+	gitCmd(t, dir, "commit", "--quiet", "-m", "first")
+	gitCmd(t, dir, "tag", "first")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte("# second\n"), 0644))
+	gitCmd(t, dir, "commit", "--quiet", "-am", "second")
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "app"), 0755))
+	top, err := git.TopLevel(filepath.Join(dir, "app"))
+	require.NoError(t, err)
+	resolved, err := filepath.EvalSymlinks(dir)
+	require.NoError(t, err)
+	assert.Equal(t, resolved, top)
+
+	path := filepath.Join(t.TempDir(), "first")
+	require.NoError(t, git.AddWorktree(dir, path, "first"))
+
+	data, err := os.ReadFile(filepath.Join(path, "main.tf"))
+	require.NoError(t, err)
+	assert.Equal(t, "# first\n", string(data))
+
+	require.NoError(t, git.RemoveWorktree(dir, path))
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -17,8 +17,11 @@
 package astro
 
 import (
+	"sync"
 	"testing"
 
+	"github.com/hashicorp/terraform/dag"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -36,3 +39,49 @@ func TestGraph(t *testing.T) {
 	_, err = graph.Root()
 	require.NoError(t, err)
 }
+
+func TestReverseGraph(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-apply-fail-module/astro.yaml")
+	require.NoError(t, err)
+
+	boundExecutions, err := c.executions(NoExecutionParameters()).bindAll(map[string]string{
+		"aws_region": "east1",
+	})
+	require.NoError(t, err)
+
+	executions := make(executionSet, len(boundExecutions))
+	for i, e := range boundExecutions {
+		executions[i] = e
+	}
+
+	graph, err := executions.reverseGraph()
+	require.NoError(t, err)
+	require.NoError(t, graph.Validate())
+
+	var mu sync.Mutex
+	var order []string
+	require.NoError(t, graph.Walk(func(vertex dag.Vertex) error {
+		if e, ok := vertex.(*boundExecution); ok {
+			mu.Lock()
+			order = append(order, e.ID())
+			mu.Unlock()
+		}
+		return nil
+	}))
+
+	position := map[string]int{}
+	for i, id := range order {
+		position[id] = i
+	}
+	require.Len(t, position, len(executions))
+
+	// dependents must be visited before their dependencies
+	for _, env := range []string{"dev", "staging", "prod"} {
+		assert.True(t, position["app-east1-"+env] < position["database-east1-"+env])
+		assert.True(t, position["app-east1-"+env] < position["network-east1-"+env])
+		assert.True(t, position["database-east1-"+env] < position["users"])
+	}
+	assert.True(t, position["mgmt-east1"] < position["network-east1-mgmt"])
+}
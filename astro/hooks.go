@@ -19,24 +19,85 @@ package astro
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/exec2"
 	"github.com/uber/astro/astro/logger"
 
 	"github.com/kballard/go-shellquote"
 )
 
-// runCommandkAndSetEnvironment runs the specified hook/command.
+// hookKillTimeout is how long a hook has to exit after it is interrupted
+// before it is killed.
+const hookKillTimeout = 10 * time.Second
+
+// HookSkipExitCode is the exit code with which a PreModuleRun hook vetoes
+// an execution. The execution is skipped rather than failed, so the
+// executions that depend on it still run.
+const HookSkipExitCode = 75
+
+// skipReasonFile is the name of the file, in the session directory of an
+// execution, that a PreModuleRun hook can write the reason it vetoed the
+// execution to. Hooks find its path in ASTRO_SKIP_REASON_FILE.
+const skipReasonFile = "skip-reason"
+
+// defaultSkipReason is the reason an execution was skipped when the hook
+// that vetoed it didn't give one.
+const defaultSkipReason = "skipped by PreModuleRun hook"
+
+// runPreModuleRunHooks runs the PreModuleRun hooks of an execution. If a
+// hook exits with HookSkipExitCode, the remaining hooks are not run, and
+// the reason the execution is skipped is returned.
+func (session *Session) runPreModuleRunHooks(b *boundExecution, status *statusQueue) (skipReason string, err error) {
+	reasonFile := filepath.Join(session.path, b.ID(), skipReasonFile)
+	// the file may be left over from when the plans were made, when
+	// applying saved plans
+	os.Remove(reasonFile)
+
+	for _, hook := range b.ModuleConfig().Hooks.PreModuleRun {
+		command, err := renderOutputs(hook.Command, session.outputResolver(b))
+		if err != nil {
+			return "", fmt.Errorf("error running PreModuleRun hook: %v", err)
+		}
+		hook.Command = command
+
+		status.send(b.ID(), "Running PreModuleRun hook...")
+		err = runCommandkAndSetEnvironment(session.ctx, session.path, hook, "ASTRO_SKIP_REASON_FILE="+reasonFile)
+
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == HookSkipExitCode {
+			reason := defaultSkipReason
+			if data, err := os.ReadFile(reasonFile); err == nil && len(bytes.TrimSpace(data)) > 0 {
+				reason = string(bytes.TrimSpace(data))
+			}
+			logger.Info("execution skipped by hook", logger.Fields{"id": b.ID(), "command": hook.Command, "reason": reason})
+			return reason, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("error running PreModuleRun hook: %v", err)
+		}
+	}
+
+	return "", nil
+}
+
+// runCommandkAndSetEnvironment runs the specified hook/command, with env
+// added to its environment.
 //
 // If parseEnvironment is true, output in the format "KEY=VAL" for
 // hooks is insert into the current process's environment. An error is returned
-// if the hook fails to execute.
-func runCommandkAndSetEnvironment(workingDir string, hook conf.Hook) error {
-	logger.Trace.Printf("astro: running hook: %v", hook.Command)
+// if the hook fails to execute. If ctx is done before the hook exits, it is
+// interrupted, and killed if it has not exited after hookKillTimeout.
+func runCommandkAndSetEnvironment(ctx context.Context, workingDir string, hook conf.Hook, env ...string) error {
+	logger.Debug("running hook", logger.Fields{"command": hook.Command})
 
 	args, err := shellquote.Split(hook.Command)
 	if err != nil {
@@ -52,6 +113,9 @@ func runCommandkAndSetEnvironment(workingDir string, hook conf.Hook) error {
 
 	cmd := exec.Command(prog, args[1:]...)
 	cmd.Dir = workingDir
+	if env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
 
 	// Have to pipe through stderr and stdin so that scripts that prompt, e.g.
 	// for MFA will work.
@@ -59,7 +123,7 @@ func runCommandkAndSetEnvironment(workingDir string, hook conf.Hook) error {
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = output
 
-	if err := cmd.Run(); err != nil {
+	if err := exec2.RunContext(ctx, cmd, hookKillTimeout); err != nil {
 		return err
 	}
 
@@ -64,3 +64,30 @@ func TestHookInjectEnvVars(t *testing.T) {
 		"test": nil,
 	}, testResultErrs(testReadResults(resultChan)))
 }
+
+func TestHookSkip(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-hook-skip/astro.yaml")
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Plan(PlanExecutionParameters{
+		ExecutionParameters: NoExecutionParameters(),
+		UseGraph:            true,
+	})
+	require.NoError(t, err)
+
+	results := testReadResults(resultChan)
+
+	assert.Equal(t, map[string]error{
+		"app":    nil,
+		"frozen": nil,
+		"silent": nil,
+	}, testResultErrs(results))
+
+	assert.Equal(t, "account is frozen", results["frozen"].SkipReason())
+	assert.Equal(t, defaultSkipReason, results["silent"].SkipReason())
+	// executions that depend on a skipped execution still run
+	assert.Equal(t, "", results["app"].SkipReason())
+	assert.NotNil(t, results["app"].TerraformResult())
+}
@@ -0,0 +1,237 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
+)
+
+// stateKeyAttributes are the attributes of backend configuration that tell
+// the states of a backend apart, e.g. the key of an S3 object.
+var stateKeyAttributes = []string{"key", "path", "prefix"}
+
+var (
+	// matches the start of a terraform_remote_state data source, e.g.
+	// data "terraform_remote_state" "network" {
+	reRemoteStateBlock = regexp.MustCompile(`data\s+"terraform_remote_state"\s+"[^"]*"\s*\{`)
+	// matches the start of a backend block, e.g. backend "s3" {
+	reBackendBlock = regexp.MustCompile(`\bbackend\s+"[^"]*"\s*\{`)
+	// matches an attribute that is a string, e.g. key = "network.tfstate"
+	reStringAttribute = regexp.MustCompile(`(?m)^\s*"?([A-Za-z_][A-Za-z0-9_-]*)"?\s*[=:]\s*"([^"]*)"`)
+	// matches an interpolation in Terraform code, e.g. ${var.environment}
+	reInterpolation = regexp.MustCompile(`\$\{\s*(.*?)\s*\}`)
+	// matches the name of a variable
+	reIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// stateKey identifies the state of a module by an attribute of its
+// backend configuration. Placeholders, e.g. "{{.environment}}" in astro
+// config or "${var.environment}" in Terraform code, are "*" in pattern,
+// and what they refer to is in placeholders, in order.
+type stateKey struct {
+	attribute    string
+	pattern      string
+	placeholders []string
+}
+
+// newStateKey parses the value of an attribute, whose placeholders re
+// matches.
+func newStateKey(attribute, value string, re *regexp.Regexp) stateKey {
+	key := stateKey{attribute: attribute}
+	for _, match := range re.FindAllStringSubmatch(value, -1) {
+		key.placeholders = append(key.placeholders, strings.TrimSpace(match[1]))
+	}
+	key.pattern = attribute + "=" + re.ReplaceAllString(value, "*")
+	return key
+}
+
+// inferDependencies adds the modules whose state each module reads with
+// terraform_remote_state to its deps. The states are matched by their key,
+// path or prefix. When the key of both modules has a placeholder in the
+// same place, e.g. "network/${var.environment}.tfstate" and
+// "network/{{.environment}}.tfstate", the dependency is narrowed down to
+// the execution with the same value, if the module has that variable.
+func inferDependencies(config *conf.Project) {
+	owners := map[string][]stateKeyOwner{}
+	for _, module := range config.Modules {
+		for _, key := range moduleStateKeys(module) {
+			owners[key.pattern] = append(owners[key.pattern], stateKeyOwner{module: module.Name, key: key})
+		}
+	}
+
+	for i := range config.Modules {
+		module := &config.Modules[i]
+		for _, ref := range remoteStateKeys(moduleDir(*module)) {
+			for _, owner := range owners[ref.pattern] {
+				if owner.module == module.Name || hasDependency(*module, owner.module) {
+					continue
+				}
+				dep := conf.Dependency{
+					Module:    owner.module,
+					Variables: narrowInferredDependency(*module, ref, owner.key),
+				}
+				logger.Trace.Printf("config: inferred dependency of %v on %v from terraform_remote_state", module.Name, owner.module)
+				module.Deps = append(module.Deps, dep)
+			}
+		}
+	}
+}
+
+// stateKeyOwner is a module and the key of its state.
+type stateKeyOwner struct {
+	module string
+	key    stateKey
+}
+
+// moduleDir returns the directory of the Terraform code of the module.
+func moduleDir(module conf.Module) string {
+	return filepath.Join(module.TerraformCodeRoot, module.Path)
+}
+
+// hasDependency returns whether the module has a dep on the other module.
+func hasDependency(module conf.Module, other string) bool {
+	for _, dep := range module.Deps {
+		if dep.Module == other {
+			return true
+		}
+	}
+	return false
+}
+
+// narrowInferredDependency returns the variables of the dependency that
+// owns the state, from the variables of the module that reads it, or nil
+// to depend on all of its executions.
+func narrowInferredDependency(module conf.Module, ref, owner stateKey) map[string]string {
+	if len(ref.placeholders) != len(owner.placeholders) {
+		return nil
+	}
+
+	variables := map[string]bool{}
+	for _, variable := range module.Variables {
+		variables[variable.Name] = true
+	}
+
+	var narrowed map[string]string
+	for i, placeholder := range ref.placeholders {
+		// only placeholders that are a variable on both sides, e.g.
+		// ${var.environment} and {{.environment}}, narrow it down
+		name := strings.TrimPrefix(placeholder, "var.")
+		field := strings.TrimPrefix(owner.placeholders[i], ".")
+		if !strings.HasPrefix(placeholder, "var.") || !variables[name] || !reIdentifier.MatchString(field) || "."+field != owner.placeholders[i] {
+			continue
+		}
+		if narrowed == nil {
+			narrowed = map[string]string{}
+		}
+		narrowed[field] = "{{." + name + "}}"
+	}
+	return narrowed
+}
+
+// moduleStateKeys returns the keys of the state of the module, from its
+// backend_config, or from the backend block of its Terraform code for the
+// attributes that backend_config doesn't set.
+func moduleStateKeys(module conf.Module) (keys []stateKey) {
+	code := map[string]string{}
+	for _, body := range terraformBlocks(moduleDir(module), reBackendBlock) {
+		for attribute, value := range stringAttributes(body) {
+			code[attribute] = value
+		}
+	}
+
+	for _, attribute := range stateKeyAttributes {
+		if value, ok := module.Remote.BackendConfig[attribute]; ok {
+			keys = append(keys, newStateKey(attribute, value, reTemplateAction))
+		} else if value, ok := code[attribute]; ok {
+			keys = append(keys, newStateKey(attribute, value, reInterpolation))
+		}
+	}
+	return keys
+}
+
+// remoteStateKeys returns the keys of the states that the Terraform code
+// in dir reads with terraform_remote_state.
+func remoteStateKeys(dir string) (keys []stateKey) {
+	for _, body := range terraformBlocks(dir, reRemoteStateBlock) {
+		attributes := stringAttributes(body)
+		for _, attribute := range stateKeyAttributes {
+			if value, ok := attributes[attribute]; ok {
+				keys = append(keys, newStateKey(attribute, value, reInterpolation))
+			}
+		}
+	}
+	return keys
+}
+
+// terraformBlocks returns the bodies of the blocks that start with a match
+// of re in the Terraform files in dir. Files that can't be read are
+// skipped, as the module may not exist yet.
+func terraformBlocks(dir string, re *regexp.Regexp) (bodies []string) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			logger.Trace.Printf("config: unable to read %v: %v", file, err)
+			continue
+		}
+		code := string(data)
+		for _, loc := range re.FindAllStringIndex(code, -1) {
+			bodies = append(bodies, blockBody(code[loc[1]:]))
+		}
+	}
+	return bodies
+}
+
+// blockBody returns the code up to the brace that closes the block that
+// it is the body of.
+func blockBody(code string) string {
+	depth := 1
+	for i, c := range code {
+		switch c {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return code[:i]
+			}
+		}
+	}
+	return code
+}
+
+// stringAttributes returns the attributes of the body whose values are
+// strings, including those of nested blocks and objects.
+func stringAttributes(body string) map[string]string {
+	attributes := map[string]string{}
+	for _, match := range reStringAttribute.FindAllStringSubmatch(body, -1) {
+		attributes[match[1]] = match[2]
+	}
+	return attributes
+}
@@ -0,0 +1,103 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"testing"
+
+	"github.com/uber/astro/astro/conf"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testModuleDeps returns the deps of the module in the config.
+func testModuleDeps(t *testing.T, config *conf.Project, name string) []conf.Dependency {
+	for _, module := range config.Modules {
+		if module.Name == name {
+			return module.Deps
+		}
+	}
+	require.Fail(t, "no module "+name)
+	return nil
+}
+
+func TestInferDependencies(t *testing.T) {
+	t.Parallel()
+
+	config, err := NewConfigFromFile("fixtures/test-infer-deps/astro.yaml")
+	require.NoError(t, err)
+
+	assert.Equal(t, []conf.Dependency{
+		{Module: "network", Variables: map[string]string{"environment": "{{.environment}}"}},
+		{Module: "dns"},
+	}, testModuleDeps(t, config, "app"))
+
+	// explicit deps take precedence
+	assert.Equal(t, []conf.Dependency{
+		{Module: "network", Variables: map[string]string{"environment": "prod"}},
+	}, testModuleDeps(t, config, "worker"))
+
+	assert.Empty(t, testModuleDeps(t, config, "network"))
+	assert.Empty(t, testModuleDeps(t, config, "dns"))
+
+	// the inferred deps are in the graph
+	c, err := NewProject(WithConfig(*config))
+	require.NoError(t, err)
+	executions, err := c.bindExecutions(ExecutionParameters{UserVars: NoUserVariables()})
+	require.NoError(t, err)
+	set := executionSet{}
+	for _, b := range executions {
+		set = append(set, b)
+	}
+	graph, err := set.graph()
+	require.NoError(t, err)
+	for _, edge := range graph.Edges() {
+		if source, ok := edge.Source().(*boundExecution); ok && source.ID() == "app-dev" {
+			assert.Contains(t, []string{"network-dev", "dns"}, edge.Target().(*boundExecution).ID())
+		}
+	}
+}
+
+func TestInferDependenciesDisabled(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromYAML([]byte(`
+terraform:
+  path: fixtures/mock-terraform/success
+modules:
+  - name: dns
+    path: fixtures/test-infer-deps/dns
+  - name: app
+    path: fixtures/test-infer-deps/app
+    variables:
+      - name: environment
+        values: [dev, prod]
+`))
+	require.NoError(t, err)
+	assert.Empty(t, testModuleDeps(t, c.config, "app"))
+}
+
+func TestNewStateKey(t *testing.T) {
+	key := newStateKey("key", "network/${ var.environment }/${terraform.workspace}.tfstate", reInterpolation)
+	assert.Equal(t, "key=network/*/*.tfstate", key.pattern)
+	assert.Equal(t, []string{"var.environment", "terraform.workspace"}, key.placeholders)
+
+	key = newStateKey("key", "network/{{.environment}}/{{.region}}.tfstate", reTemplateAction)
+	assert.Equal(t, "key=network/*/*.tfstate", key.pattern)
+	assert.Equal(t, []string{".environment", ".region"}, key.placeholders)
+}
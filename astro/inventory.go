@@ -0,0 +1,377 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/utils"
+)
+
+// inventoryFile is the name of the inventory in the session repo, unless
+// the config sets inventory_file.
+const inventoryFile = "inventory.json"
+
+// inventory records the executions that have been applied, so that the
+// ones the configuration no longer generates can be found.
+type inventory struct {
+	// Executions are the executions that have been applied, by ID.
+	Executions map[string]inventoryEntry `json:"executions"`
+	// Archived are orphaned executions that were archived, so that they
+	// are no longer reported, by ID.
+	Archived map[string]inventoryEntry `json:"archived,omitempty"`
+}
+
+// inventoryEntry is an execution in the inventory, with what is needed to
+// destroy it after it has been removed from the config.
+type inventoryEntry struct {
+	Module    string            `json:"module"`
+	Path      string            `json:"path"`
+	Variables map[string]string `json:"variables,omitempty"`
+	// Remote is the bound remote state configuration of the execution.
+	Remote    conf.Remote `json:"remote"`
+	AppliedAt time.Time   `json:"applied_at"`
+}
+
+// Orphan is an execution that has been applied, but that the config no
+// longer generates, e.g. because its module or a value of one of its
+// variables was removed. Its infrastructure still exists until it is
+// destroyed.
+type Orphan struct {
+	ID        string
+	Module    string
+	Variables map[string]string
+	AppliedAt time.Time
+	// Reason is why the config no longer generates the execution.
+	Reason string
+}
+
+// inventoryPath returns the path to the inventory of the project.
+func (c *Project) inventoryPath() string {
+	if c.config.InventoryFile != "" {
+		return c.config.InventoryFile
+	}
+	return filepath.Join(c.sessions.path, inventoryFile)
+}
+
+// readInventory reads the inventory of the project. It is empty if no
+// executions have been applied yet.
+func (c *Project) readInventory() (*inventory, error) {
+	inv := &inventory{
+		Executions: map[string]inventoryEntry{},
+		Archived:   map[string]inventoryEntry{},
+	}
+
+	data, err := os.ReadFile(c.inventoryPath())
+	if os.IsNotExist(err) {
+		return inv, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, inv); err != nil {
+		return nil, fmt.Errorf("unable to read inventory %v: %v", c.inventoryPath(), err)
+	}
+	if inv.Executions == nil {
+		inv.Executions = map[string]inventoryEntry{}
+	}
+	if inv.Archived == nil {
+		inv.Archived = map[string]inventoryEntry{}
+	}
+
+	return inv, nil
+}
+
+// writeInventory writes the inventory of the project.
+func (c *Project) writeInventory(inv *inventory) error {
+	data, err := json.MarshalIndent(inv, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.inventoryPath(), data, 0644)
+}
+
+// updateInventory calls update with each result, and writes the
+// inventory if it changed it. Results are passed on, with a warning if
+// the inventory could not be updated.
+func (c *Project) updateInventory(results <-chan *Result, update func(*inventory, *Result) bool) <-chan *Result {
+	return passResults(results, func(result *Result) {
+		if err := c.updateInventoryWith(result, update); err != nil {
+			result.warnings = append(result.warnings, fmt.Sprintf("unable to update inventory: %v", err))
+		}
+	}, nil)
+}
+
+// updateInventoryWith reads the inventory, updates it with the result
+// and writes it back if it changed.
+func (c *Project) updateInventoryWith(result *Result, update func(*inventory, *Result) bool) error {
+	inv, err := c.readInventory()
+	if err != nil {
+		return err
+	}
+	if !update(inv, result) {
+		return nil
+	}
+	return c.writeInventory(inv)
+}
+
+// recordApplied adds the executions that were applied to the inventory.
+func (c *Project) recordApplied(boundExecutions []*boundExecution, results <-chan *Result) <-chan *Result {
+	byID := map[string]*boundExecution{}
+	for _, b := range boundExecutions {
+		byID[b.ID()] = b
+	}
+
+	return c.updateInventory(results, func(inv *inventory, result *Result) bool {
+		b := byID[result.id]
+		if b == nil || result.err != nil || result.skipReason != "" {
+			return false
+		}
+		moduleConfig := b.ModuleConfig()
+		inv.Executions[b.ID()] = inventoryEntry{
+			Module:    moduleConfig.Name,
+			Path:      moduleConfig.Path,
+			Variables: b.Variables(),
+			Remote:    moduleConfig.Remote,
+			AppliedAt: c.clock.Now().UTC(),
+		}
+		delete(inv.Archived, b.ID())
+		return true
+	})
+}
+
+// recordDestroyed removes the executions that were destroyed from the
+// inventory.
+func (c *Project) recordDestroyed(results <-chan *Result) <-chan *Result {
+	return c.updateInventory(results, func(inv *inventory, result *Result) bool {
+		if result.err != nil || result.skipReason != "" {
+			return false
+		}
+		_, applied := inv.Executions[result.id]
+		_, archived := inv.Archived[result.id]
+		delete(inv.Executions, result.id)
+		delete(inv.Archived, result.id)
+		return applied || archived
+	})
+}
+
+// Orphans returns the executions in the inventory that the config no
+// longer generates, sorted by ID. Archived executions are not included.
+func (c *Project) Orphans() ([]Orphan, error) {
+	inv, err := c.readInventory()
+	if err != nil {
+		return nil, err
+	}
+
+	orphans := []Orphan{}
+	for id, entry := range inv.Executions {
+		reason := c.orphanReason(entry)
+		if reason == "" {
+			continue
+		}
+		orphans = append(orphans, Orphan{
+			ID:        id,
+			Module:    entry.Module,
+			Variables: entry.Variables,
+			AppliedAt: entry.AppliedAt,
+			Reason:    reason,
+		})
+	}
+
+	sort.Slice(orphans, func(i, j int) bool {
+		return orphans[i].ID < orphans[j].ID
+	})
+
+	return orphans, nil
+}
+
+// orphanReason returns why the config no longer generates the execution,
+// or "" if it still does.
+func (c *Project) orphanReason(entry inventoryEntry) string {
+	var moduleConfig *conf.Module
+	for i := range c.config.Modules {
+		if c.config.Modules[i].Name == entry.Module {
+			moduleConfig = &c.config.Modules[i]
+		}
+	}
+	if moduleConfig == nil {
+		return fmt.Sprintf("module %s was removed", entry.Module)
+	}
+
+	variables := map[string]bool{}
+	for _, variable := range moduleConfig.Variables {
+		variables[variable.Name] = true
+		value, ok := entry.Variables[variable.Name]
+		if !ok {
+			return fmt.Sprintf("variable %s was added to module %s", variable.Name, entry.Module)
+		}
+		if variable.IsFilter() && !utils.StringSliceContains(variable.Values, value) {
+			return fmt.Sprintf("%s=%s was removed from module %s", variable.Name, value, entry.Module)
+		}
+	}
+
+	var names []string
+	for name := range entry.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !variables[name] {
+			return fmt.Sprintf("variable %s was removed from module %s", name, entry.Module)
+		}
+	}
+
+	return ""
+}
+
+// orphans returns the inventory entries of the orphans with the given
+// IDs, or of all orphans if there are none.
+func (c *Project) orphans(inv *inventory, ids []string) (map[string]inventoryEntry, error) {
+	orphans, err := c.Orphans()
+	if err != nil {
+		return nil, err
+	}
+
+	selected := map[string]inventoryEntry{}
+	for _, orphan := range orphans {
+		if ids == nil || utils.StringSliceContains(ids, orphan.ID) {
+			selected[orphan.ID] = inv.Executions[orphan.ID]
+		}
+	}
+
+	var missing []string
+	for _, id := range ids {
+		if _, ok := selected[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if missing != nil {
+		return nil, fmt.Errorf("not orphaned: %s", strings.Join(missing, ", "))
+	}
+
+	return selected, nil
+}
+
+// ArchiveOrphans archives the orphans with the given IDs, or all orphans
+// if there are none, so that they are no longer reported. Their
+// infrastructure is left as it is.
+func (c *Project) ArchiveOrphans(ids []string) error {
+	inv, err := c.readInventory()
+	if err != nil {
+		return err
+	}
+
+	orphans, err := c.orphans(inv, ids)
+	if err != nil {
+		return err
+	}
+
+	for id, entry := range orphans {
+		inv.Archived[id] = entry
+		delete(inv.Executions, id)
+	}
+
+	return c.writeInventory(inv)
+}
+
+// DestroyOrphans destroys the orphans with the given IDs, or all orphans
+// if there are none, and removes them from the inventory. They are
+// destroyed with the source of their module, which must still exist, and
+// the remote state they were applied with. The returned channels behave
+// in the same way as for Plan.
+func (c *Project) DestroyOrphans(ids []string, parallelism int) (<-chan string, <-chan *Result, error) {
+	inv, err := c.readInventory()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	orphans, err := c.orphans(inv, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var boundExecutions []*boundExecution
+	for id, entry := range orphans {
+		b, err := c.orphanExecution(id, entry)
+		if err != nil {
+			return nil, nil, err
+		}
+		boundExecutions = append(boundExecutions, b)
+	}
+
+	session, err := c.sessions.Current()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	status, results, err := session.destroy(boundExecutions, c.executionLimiter(ExecutionParameters{Parallelism: parallelism}, c.config.ApplyParallelism))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return status, c.recordDestroyed(results), nil
+}
+
+// orphanExecution returns the execution of an orphan, to destroy it. It
+// uses the current config of its module, if the module still exists, or
+// the project defaults otherwise.
+func (c *Project) orphanExecution(id string, entry inventoryEntry) (*boundExecution, error) {
+	moduleConfig := conf.Module{
+		Name:              entry.Module,
+		Path:              entry.Path,
+		TerraformCodeRoot: c.config.TerraformCodeRoot,
+		Overrides:         c.config.Overrides,
+	}
+	moduleConfig.Hooks.ApplyDefaultsFrom(c.config.Hooks)
+	moduleConfig.Terraform.ApplyDefaultsFrom(c.config.TerraformDefaults)
+	for _, m := range c.config.Modules {
+		if m.Name == entry.Module {
+			moduleConfig = m
+		}
+	}
+
+	path := filepath.Join(moduleConfig.TerraformCodeRoot, entry.Path)
+	if !utils.IsDirectory(path) {
+		return nil, fmt.Errorf("%v: the source of module %v no longer exists at %v; restore it to destroy the execution, or archive it", id, entry.Module, path)
+	}
+
+	// The execution is destroyed where it was applied, with the variables
+	// it had then
+	moduleConfig.Path = entry.Path
+	moduleConfig.Remote = entry.Remote
+	moduleConfig.Variables = nil
+	for name := range entry.Variables {
+		moduleConfig.Variables = append(moduleConfig.Variables, conf.Variable{Name: name})
+	}
+	moduleConfig.Deps = nil
+
+	unbound := &unboundExecution{
+		&execution{
+			moduleConf: &moduleConfig,
+			variables:  entry.Variables,
+		},
+	}
+
+	return unbound.bind(entry.Variables)
+}
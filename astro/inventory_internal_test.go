@@ -0,0 +1,135 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/uber/astro/astro/conf"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrphanReason(t *testing.T) {
+	c := &Project{config: &conf.Project{
+		Modules: []conf.Module{
+			{
+				Name: "app",
+				Variables: []conf.Variable{
+					{Name: "environment", Values: []string{"dev", "prod"}},
+					{Name: "region"},
+				},
+			},
+		},
+	}}
+
+	tt := []struct {
+		name      string
+		module    string
+		variables map[string]string
+		reason    string
+	}{
+		{
+			name:      "generated",
+			module:    "app",
+			variables: map[string]string{"environment": "dev", "region": "east1"},
+			reason:    "",
+		},
+		{
+			name:   "module removed",
+			module: "database",
+			reason: "module database was removed",
+		},
+		{
+			name:      "value removed",
+			module:    "app",
+			variables: map[string]string{"environment": "staging", "region": "east1"},
+			reason:    "environment=staging was removed from module app",
+		},
+		{
+			name:      "variable added",
+			module:    "app",
+			variables: map[string]string{"environment": "dev"},
+			reason:    "variable region was added to module app",
+		},
+		{
+			name:      "variable removed",
+			module:    "app",
+			variables: map[string]string{"environment": "dev", "region": "east1", "zone": "a"},
+			reason:    "variable zone was removed from module app",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.reason, c.orphanReason(inventoryEntry{
+				Module:    tc.module,
+				Variables: tc.variables,
+			}))
+		})
+	}
+}
+
+func TestDestroyOrphans(t *testing.T) {
+	t.Parallel()
+
+	inventoryFile := filepath.Join(t.TempDir(), "inventory.json")
+
+	c, err := NewProjectFromConfigFile("fixtures/test-drift/astro.yaml")
+	require.NoError(t, err)
+	c.config.InventoryFile = inventoryFile
+
+	_, resultChan, err := c.Apply(ApplyExecutionParameters{
+		ExecutionParameters: NoExecutionParameters(),
+	})
+	require.NoError(t, err)
+	testReadResults(resultChan)
+
+	orphans, err := c.Orphans()
+	require.NoError(t, err)
+	assert.Empty(t, orphans)
+
+	// remove the drifted module from the config
+	c, err = NewProjectFromConfigFile("fixtures/test-drift/astro.yaml")
+	require.NoError(t, err)
+	c.config.InventoryFile = inventoryFile
+	c.config.Modules = c.config.Modules[:1]
+
+	orphans, err = c.Orphans()
+	require.NoError(t, err)
+	require.Len(t, orphans, 1)
+	assert.Equal(t, "drifted", orphans[0].ID)
+	assert.Equal(t, "module drifted was removed", orphans[0].Reason)
+
+	_, _, err = c.DestroyOrphans([]string{"clean"}, 0)
+	assert.EqualError(t, err, "not orphaned: clean")
+
+	_, resultChan, err = c.DestroyOrphans(nil, 0)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]error{"drifted": nil}, testResultErrs(testReadResults(resultChan)))
+
+	orphans, err = c.Orphans()
+	require.NoError(t, err)
+	assert.Empty(t, orphans)
+
+	inv, err := c.readInventory()
+	require.NoError(t, err)
+	assert.Contains(t, inv.Executions, "clean")
+	assert.NotContains(t, inv.Executions, "drifted")
+}
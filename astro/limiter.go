@@ -0,0 +1,120 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"context"
+	"sync"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/scheduler"
+)
+
+// defaultParallelism is the maximum number of executions that run at the
+// same time, if it's not configured.
+const defaultParallelism = 10
+
+// moduleGroup and concurrencyGroup return the names of the scheduler
+// groups of the parallelism of a module, and of a concurrency group.
+func moduleGroup(name string) string      { return "module:" + name }
+func concurrencyGroup(name string) string { return "group:" + name }
+
+// newScheduler returns a scheduler that runs at most parallelism
+// executions at the same time, at most the configured parallelism of each
+// module, and of each concurrency group.
+func newScheduler(parallelism int, modules []conf.Module, groups map[string]conf.Group) *scheduler.Scheduler {
+	if parallelism < 1 {
+		parallelism = defaultParallelism
+	}
+
+	s := scheduler.New(parallelism)
+
+	for _, module := range modules {
+		if module.Parallelism > 0 {
+			s.SetGroupLimit(moduleGroup(module.Name), module.Parallelism)
+		}
+	}
+	for name, group := range groups {
+		if group.Parallelism > 0 {
+			s.SetGroupLimit(concurrencyGroup(name), group.Parallelism)
+		}
+	}
+
+	return s
+}
+
+// executionLimiter limits the number of executions that run at the same
+// time, and the order they start in, with a scheduler.
+type executionLimiter struct {
+	scheduler *scheduler.Scheduler
+}
+
+// newExecutionLimiter returns a limiter that schedules executions with s.
+func newExecutionLimiter(s *scheduler.Scheduler) *executionLimiter {
+	return &executionLimiter{scheduler: s}
+}
+
+// task returns the scheduler task of an execution, with the priority and
+// groups of its module.
+func (l *executionLimiter) task(b *boundExecution) scheduler.Task {
+	module := b.ModuleConfig()
+	task := scheduler.Task{
+		Priority: module.Priority,
+		Groups:   []string{moduleGroup(module.Name)},
+	}
+	if module.Group != "" {
+		task.Groups = append(task.Groups, concurrencyGroup(module.Group))
+	}
+	return task
+}
+
+// acquire blocks until the execution is allowed to run, or ctx is done,
+// in which case an error is returned. If it succeeds, release must be
+// called when the execution has finished.
+func (l *executionLimiter) acquire(ctx context.Context, b *boundExecution) error {
+	return l.scheduler.Acquire(ctx, l.task(b))
+}
+
+// release allows another execution to run.
+func (l *executionLimiter) release(b *boundExecution) {
+	l.scheduler.Release(l.task(b))
+}
+
+// run runs fn for each execution, as many at the same time as the limiter
+// allows, and waits for them to finish. Executions that have not started
+// when ctx is done are not run; cancelled, if set, is called for each of
+// them with the error of ctx instead, so that they can still be reported.
+func (l *executionLimiter) run(ctx context.Context, boundExecutions []*boundExecution, fn func(*boundExecution), cancelled func(*boundExecution, error)) {
+	wg := sync.WaitGroup{}
+
+	for _, b := range boundExecutions {
+		wg.Add(1)
+		go func(b *boundExecution) {
+			defer wg.Done()
+			if err := l.acquire(ctx, b); err != nil {
+				if cancelled != nil {
+					cancelled(b, err)
+				}
+				return
+			}
+			defer l.release(b)
+			fn(b)
+		}(b)
+	}
+
+	wg.Wait()
+}
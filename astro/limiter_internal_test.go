@@ -0,0 +1,203 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/uber/astro/astro/conf"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testConcurrency records the maximum number of functions running at the
+// same time, in total and by module.
+type testConcurrency struct {
+	mu         sync.Mutex
+	running    map[string]int
+	total      int
+	maxRunning map[string]int
+	maxTotal   int
+}
+
+func (c *testConcurrency) run(b *boundExecution) {
+	name := b.ModuleConfig().Name
+
+	c.mu.Lock()
+	c.running[name]++
+	c.total++
+	if c.running[name] > c.maxRunning[name] {
+		c.maxRunning[name] = c.running[name]
+	}
+	if c.total > c.maxTotal {
+		c.maxTotal = c.total
+	}
+	c.mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	c.mu.Lock()
+	c.running[name]--
+	c.total--
+	c.mu.Unlock()
+}
+
+func TestExecutionLimiter(t *testing.T) {
+	modules := []conf.Module{
+		{Name: "app"},
+		{Name: "database", Parallelism: 1},
+	}
+
+	var executions []*boundExecution
+	for i := 0; i < 4; i++ {
+		for j := range modules {
+			executions = append(executions, &boundExecution{execution: &execution{moduleConf: &modules[j]}})
+		}
+	}
+
+	concurrency := &testConcurrency{
+		running:    map[string]int{},
+		maxRunning: map[string]int{},
+	}
+
+	newExecutionLimiter(newScheduler(3, modules, nil)).run(context.Background(), executions, concurrency.run, nil)
+
+	assert.Equal(t, 3, concurrency.maxTotal)
+	assert.Equal(t, 1, concurrency.maxRunning["database"])
+}
+
+func TestExecutionLimiterCancelled(t *testing.T) {
+	modules := []conf.Module{{Name: "app"}}
+	executions := []*boundExecution{
+		{execution: &execution{moduleConf: &modules[0]}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ran := false
+	var cancelled []error
+	s := newScheduler(1, modules, nil)
+	s.Pause()
+	limiter := newExecutionLimiter(s)
+	limiter.run(ctx, executions, func(*boundExecution) { ran = true }, func(_ *boundExecution, err error) {
+		cancelled = append(cancelled, err)
+	})
+
+	assert.False(t, ran)
+	// executions that didn't run are still reported
+	assert.Equal(t, []error{context.Canceled}, cancelled)
+}
+
+func TestExecutionLimiterGroups(t *testing.T) {
+	modules := []conf.Module{
+		{Name: "app"},
+		{Name: "database", Group: "storage"},
+		{Name: "cache", Group: "storage"},
+	}
+
+	var executions []*boundExecution
+	for i := 0; i < 3; i++ {
+		for j := range modules {
+			executions = append(executions, &boundExecution{execution: &execution{moduleConf: &modules[j]}})
+		}
+	}
+
+	var mu sync.Mutex
+	storage, maxStorage := 0, 0
+	groups := map[string]conf.Group{"storage": {Parallelism: 2}}
+
+	newExecutionLimiter(newScheduler(5, modules, groups)).run(context.Background(), executions, func(b *boundExecution) {
+		if b.ModuleConfig().Group == "" {
+			return
+		}
+		mu.Lock()
+		storage++
+		if storage > maxStorage {
+			maxStorage = storage
+		}
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		storage--
+		mu.Unlock()
+	}, nil)
+
+	assert.Equal(t, 2, maxStorage)
+}
+
+func TestExecutionLimiterPriority(t *testing.T) {
+	modules := []conf.Module{
+		{Name: "app"},
+		{Name: "network", Priority: 10},
+	}
+	executions := []*boundExecution{
+		{execution: &execution{moduleConf: &modules[0]}},
+		{execution: &execution{moduleConf: &modules[1]}},
+	}
+
+	s := newScheduler(1, modules, nil)
+	s.Pause()
+
+	var mu sync.Mutex
+	var order []string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		newExecutionLimiter(s).run(context.Background(), executions, func(b *boundExecution) {
+			mu.Lock()
+			order = append(order, b.ModuleConfig().Name)
+			mu.Unlock()
+		}, nil)
+	}()
+
+	// start once both are waiting
+	for s.Waiting() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	s.Resume()
+	<-done
+
+	assert.Equal(t, []string{"network", "app"}, order)
+}
+
+func TestExecutionLimiterCommandParallelism(t *testing.T) {
+	config := &conf.Project{
+		Parallelism:      5,
+		PlanParallelism:  20,
+		ApplyParallelism: 2,
+		Profiles:         map[string]conf.Profile{"ci": {Parallelism: 8}},
+	}
+	c := &Project{config: config}
+
+	assert.Equal(t, 20, c.executionLimiter(ExecutionParameters{}, config.PlanParallelism).scheduler.Limit())
+	assert.Equal(t, 2, c.executionLimiter(ExecutionParameters{}, config.ApplyParallelism).scheduler.Limit())
+	assert.Equal(t, 5, c.executionLimiter(ExecutionParameters{}, 0).scheduler.Limit())
+
+	// --parallel overrides all of them
+	assert.Equal(t, 3, c.executionLimiter(ExecutionParameters{Parallelism: 3}, config.ApplyParallelism).scheduler.Limit())
+
+	// so does the parallelism of a profile
+	assert.NoError(t, config.ApplyProfile("ci"))
+	assert.Equal(t, 8, c.executionLimiter(ExecutionParameters{}, config.PlanParallelism).scheduler.Limit())
+	assert.Equal(t, 8, c.executionLimiter(ExecutionParameters{}, config.ApplyParallelism).scheduler.Limit())
+}
@@ -0,0 +1,227 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/utils"
+)
+
+var (
+	// matches the source attribute of a Terraform module block, e.g.
+	// source = "../modules/vpc"
+	reModuleSource = regexp.MustCompile(`(?m)^\s*source\s*=\s*"([^"]+)"`)
+)
+
+// lockFile is the structure of the astro.lock file. It records a content
+// hash of the source of every module in the project.
+type lockFile struct {
+	Modules map[string]moduleLock `json:"modules"`
+}
+
+// moduleLock is the lock information for a single module.
+type moduleLock struct {
+	// Hash is the hash of the module source, including any local
+	// Terraform modules that it references.
+	Hash string `json:"hash"`
+	// RemoteSources is a sorted list of the non-local module sources the
+	// module references, e.g. git URLs or registry addresses.
+	RemoteSources []string `json:"remote_sources,omitempty"`
+}
+
+// LockMismatchError is returned when module sources do not match the
+// astro.lock file.
+type LockMismatchError struct {
+	lockFilePath string
+	modules      []string
+}
+
+// Error is the error message, so this satisfies the error interface.
+func (e *LockMismatchError) Error() string {
+	return fmt.Sprintf("module sources do not match %s: %s", e.lockFilePath, strings.Join(e.modules, ", "))
+}
+
+// Modules returns the names of the modules that do not match the lock file.
+func (e *LockMismatchError) Modules() []string {
+	return e.modules
+}
+
+// UpdateLock computes the hashes of all module sources in the project and
+// writes them to the lock file.
+func (c *Project) UpdateLock() error {
+	lock := lockFile{Modules: map[string]moduleLock{}}
+
+	for _, moduleConfig := range c.config.Modules {
+		l, err := c.lockModule(moduleConfig)
+		if err != nil {
+			return err
+		}
+		lock.Modules[moduleConfig.Name] = l
+	}
+
+	b, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	logger.Trace.Printf("astro: writing lock file: %v", c.config.LockFile)
+
+	return os.WriteFile(c.config.LockFile, append(b, '\n'), 0644)
+}
+
+// VerifyLock checks that the sources of the specified modules match the
+// lock file. If moduleNames is nil, all modules are checked. A
+// *LockMismatchError is returned if any of them changed.
+func (c *Project) VerifyLock(moduleNames []string) error {
+	b, err := os.ReadFile(c.config.LockFile)
+	if err != nil {
+		return fmt.Errorf("unable to read lock file: %v", err)
+	}
+
+	var lock lockFile
+	if err := json.Unmarshal(b, &lock); err != nil {
+		return fmt.Errorf("unable to parse lock file: %s; %v", c.config.LockFile, err)
+	}
+
+	var mismatched []string
+	for _, module := range c.modules(moduleNames) {
+		expected, ok := lock.Modules[module.config.Name]
+		if !ok {
+			mismatched = append(mismatched, module.config.Name)
+			continue
+		}
+
+		actual, err := c.lockModule(*module.config)
+		if err != nil {
+			return err
+		}
+
+		if actual.Hash != expected.Hash || strings.Join(actual.RemoteSources, ",") != strings.Join(expected.RemoteSources, ",") {
+			mismatched = append(mismatched, module.config.Name)
+		}
+	}
+
+	if len(mismatched) > 0 {
+		return &LockMismatchError{lockFilePath: c.config.LockFile, modules: mismatched}
+	}
+
+	return nil
+}
+
+// lockModule returns the lock information for a module.
+func (c *Project) lockModule(moduleConfig conf.Module) (moduleLock, error) {
+	hash := sha256.New()
+	remoteSources := map[string]bool{}
+
+	modulePath := filepath.Join(moduleConfig.TerraformCodeRoot, moduleConfig.Path)
+	if err := c.hashModuleDir(hash, modulePath, map[string]bool{}, remoteSources); err != nil {
+		return moduleLock{}, fmt.Errorf("unable to hash module %v: %v", moduleConfig.Name, err)
+	}
+
+	var sources []string
+	for source := range remoteSources {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	return moduleLock{
+		Hash:          fmt.Sprintf("sha256:%x", hash.Sum(nil)),
+		RemoteSources: sources,
+	}, nil
+}
+
+// hashModuleDir writes the contents of the files in dir to hash, following
+// any local module sources that are referenced from Terraform files.
+func (c *Project) hashModuleDir(hash io.Writer, dir string, visited map[string]bool, remoteSources map[string]bool) error {
+	if visited[dir] {
+		return nil
+	}
+	visited[dir] = true
+
+	var localSources []string
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name := info.Name()
+		if info.IsDir() {
+			if path != dir && (name == ".terraform" || name == ".astro" || name == ".git") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(name, "terraform.tfstate") || path == c.config.LockFile {
+			return nil
+		}
+
+		rel, err := filepath.Rel(c.config.TerraformCodeRoot, path)
+		if err != nil {
+			return err
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprintf(hash, "%s\x00%x\n", rel, sha256.Sum256(b)); err != nil {
+			return err
+		}
+
+		if filepath.Ext(name) != ".tf" {
+			return nil
+		}
+
+		for _, match := range reModuleSource.FindAllStringSubmatch(string(b), -1) {
+			source := match[1]
+			if strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../") {
+				localSources = append(localSources, filepath.Join(filepath.Dir(path), source))
+			} else {
+				remoteSources[source] = true
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, source := range localSources {
+		if !utils.IsDirectory(source) {
+			return fmt.Errorf("local module source does not exist: %v", source)
+		}
+		if err := c.hashModuleDir(hash, source, visited, remoteSources); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
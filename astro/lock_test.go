@@ -0,0 +1,78 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/uber/astro/astro"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockDetectsChangedSources(t *testing.T) {
+	t.Parallel()
+
+	mockTerraform, err := filepath.Abs("fixtures/mock-terraform/success")
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "app"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "modules/vpc"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app/main.tf"), []byte(`
+module "vpc" {
+  source = "../modules/vpc"
+}
+
+module "remote" {
+  source = "git::https://example.com/remote.git?ref=v1.0.0"
+}
+`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "modules/vpc/main.tf"), []byte("# vpc\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "astro.yaml"), []byte(`
+terraform:
+  path: `+mockTerraform+`
+modules:
+  - name: app
+    path: app
+`), 0644))
+
+	project, err := astro.NewProjectFromConfigFile(filepath.Join(dir, "astro.yaml"))
+	require.NoError(t, err)
+
+	// no lock file yet
+	require.Error(t, project.VerifyLock(nil))
+
+	require.NoError(t, project.UpdateLock())
+	require.NoError(t, project.VerifyLock(nil))
+
+	// changing a local module referenced by app should be detected
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "modules/vpc/main.tf"), []byte("# vpc changed\n"), 0644))
+
+	err = project.VerifyLock(nil)
+	require.Error(t, err)
+
+	lockErr, ok := err.(*astro.LockMismatchError)
+	require.True(t, ok)
+	assert.Equal(t, []string{"app"}, lockErr.Modules())
+
+	require.NoError(t, project.UpdateLock())
+	require.NoError(t, project.VerifyLock(nil))
+}
@@ -17,17 +17,38 @@
 package logger
 
 import (
-	"io"
 	"log"
 	"os"
 	"strings"
 )
 
-// Trace is a logger containing debug information.
-var Trace = log.New(io.Discard, "[TRACE] ", log.LstdFlags)
+// Verbosity levels, as selected on the command line with -v, -vv and
+// -vvv.
+const (
+	// LevelQuiet only prints results.
+	LevelQuiet = iota
+	// LevelStatus also prints status updates as executions progress.
+	LevelStatus
+	// LevelTerraform also streams the output of Terraform as it runs.
+	LevelTerraform
+	// LevelTrace also prints internal trace information.
+	LevelTrace
+)
+
+// Trace is a logger for debug information. Every line printed to it is
+// logged at TraceLevel.
+var Trace = log.New(traceWriter{}, "", 0)
+
+// traceWriter logs what is written to it at TraceLevel.
+type traceWriter struct{}
+
+func (traceWriter) Write(p []byte) (int, error) {
+	Log(TraceLevel, strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
 
 func init() {
-	if strings.ToLower(os.Getenv("ASTRO_LOG")) == "trace" {
-		Trace.SetOutput(os.Stderr)
+	if level, err := ParseLevel(os.Getenv("ASTRO_LOG")); err == nil {
+		SetOutput(os.Stderr, level, FormatText)
 	}
 }
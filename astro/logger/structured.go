@@ -0,0 +1,286 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the severity of a log record.
+type Level int
+
+// Log levels, from the most verbose.
+const (
+	// TraceLevel is for internal details, e.g. every command that is run.
+	TraceLevel Level = iota
+	// DebugLevel is for what astro is doing, e.g. starting an execution.
+	DebugLevel
+	// InfoLevel is for the outcome of operations.
+	InfoLevel
+	// WarnLevel is for problems that astro recovered from.
+	WarnLevel
+	// ErrorLevel is for failures.
+	ErrorLevel
+)
+
+var levelNames = []string{"trace", "debug", "info", "warn", "error"}
+
+// String returns the name of the level, e.g. "info".
+func (l Level) String() string {
+	if l < TraceLevel || l > ErrorLevel {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+// ParseLevel returns the level with the name s, e.g. "debug".
+func ParseLevel(s string) (Level, error) {
+	for i, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return Level(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %q; must be one of %s", s, strings.Join(levelNames, ", "))
+}
+
+// Log formats.
+const (
+	// FormatText logs records as lines of text, with the fields as
+	// key=value pairs.
+	FormatText = "text"
+	// FormatJSON logs records as JSON objects, one per line.
+	FormatJSON = "json"
+)
+
+// ValidateFormat checks that format is a supported log format.
+func ValidateFormat(format string) error {
+	if format != FormatText && format != FormatJSON {
+		return fmt.Errorf("unknown log format %q; must be %s or %s", format, FormatText, FormatJSON)
+	}
+	return nil
+}
+
+// Fields are structured data attached to a log record, e.g. the ID of the
+// execution it is about.
+type Fields map[string]interface{}
+
+// sink is a destination for log records of at least a level.
+type sink struct {
+	w      io.Writer
+	level  Level
+	format string
+}
+
+var (
+	mu sync.Mutex
+	// console is where records are logged for the user, usually stderr.
+	// Nothing is logged there if it's nil.
+	console *sink
+	// files are the additional sinks, e.g. log files.
+	files = map[*sink]bool{}
+)
+
+// SetOutput logs records of at least level to w, in format, instead of
+// where they were logged before.
+func SetOutput(w io.Writer, level Level, format string) {
+	mu.Lock()
+	defer mu.Unlock()
+	console = &sink{w: w, level: level, format: format}
+}
+
+// AddSink also logs records of at least level to w, in format, until
+// remove is called.
+func AddSink(w io.Writer, level Level, format string) (remove func()) {
+	s := &sink{w: w, level: level, format: format}
+
+	mu.Lock()
+	defer mu.Unlock()
+	files[s] = true
+
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		delete(files, s)
+	}
+}
+
+// Log logs a record at level, with the fields merged.
+func Log(level Level, msg string, fields ...Fields) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	sinks := make([]*sink, 0, len(files)+1)
+	if console != nil && level >= console.level {
+		sinks = append(sinks, console)
+	}
+	for s := range files {
+		if level >= s.level {
+			sinks = append(sinks, s)
+		}
+	}
+	if len(sinks) == 0 {
+		return
+	}
+
+	r := record{time: time.Now(), level: level, msg: msg, fields: Fields{}}
+	for _, f := range fields {
+		for key, val := range f {
+			r.fields[key] = val
+		}
+	}
+
+	formatted := map[string][]byte{}
+	for _, s := range sinks {
+		line, ok := formatted[s.format]
+		if !ok {
+			line = r.format(s.format)
+			formatted[s.format] = line
+		}
+		s.w.Write(line)
+	}
+}
+
+// Debug logs a record at DebugLevel.
+func Debug(msg string, fields ...Fields) {
+	Log(DebugLevel, msg, fields...)
+}
+
+// Info logs a record at InfoLevel.
+func Info(msg string, fields ...Fields) {
+	Log(InfoLevel, msg, fields...)
+}
+
+// Warn logs a record at WarnLevel.
+func Warn(msg string, fields ...Fields) {
+	Log(WarnLevel, msg, fields...)
+}
+
+// Error logs a record at ErrorLevel.
+func Error(msg string, fields ...Fields) {
+	Log(ErrorLevel, msg, fields...)
+}
+
+// record is a log record.
+type record struct {
+	time   time.Time
+	level  Level
+	msg    string
+	fields Fields
+}
+
+// format returns the record as a line in format.
+func (r record) format(format string) []byte {
+	if format == FormatJSON {
+		return r.json()
+	}
+	return r.text()
+}
+
+// text returns the record as a line of text, e.g.
+//
+//	2019-06-01T10:00:00.000Z INFO applied id=app-dev runtime=31s
+func (r record) text() []byte {
+	var b strings.Builder
+	b.WriteString(r.time.Format("2006-01-02T15:04:05.000Z07:00"))
+	b.WriteString(" ")
+	b.WriteString(strings.ToUpper(r.level.String()))
+	b.WriteString(" ")
+	b.WriteString(r.msg)
+
+	for _, key := range r.keys() {
+		fmt.Fprintf(&b, " %s=%s", key, quoteValue(fmt.Sprint(fieldValue(r.fields[key]))))
+	}
+	b.WriteString("\n")
+
+	return []byte(b.String())
+}
+
+// json returns the record as a JSON object on a line, e.g.
+//
+//	{"time":"2019-06-01T10:00:00Z","level":"info","msg":"applied","id":"app-dev"}
+func (r record) json() []byte {
+	obj := map[string]interface{}{}
+	for key, val := range r.fields {
+		obj[key] = fieldValue(val)
+	}
+	obj["time"] = r.time.Format(time.RFC3339Nano)
+	obj["level"] = r.level.String()
+	obj["msg"] = r.msg
+
+	line, err := marshalLine(obj)
+	if err != nil {
+		// a field can't be marshalled, so log the fields as text
+		for key, val := range r.fields {
+			obj[key] = fmt.Sprint(val)
+		}
+		line, _ = marshalLine(obj)
+	}
+
+	return line
+}
+
+// marshalLine returns v as JSON on a line, without escaping HTML
+// characters, which are common in commands.
+func marshalLine(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// keys returns the keys of the fields in order.
+func (r record) keys() []string {
+	keys := make([]string, 0, len(r.fields))
+	for key := range r.fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// fieldValue returns how a field value is logged: errors and durations as
+// their messages, and everything else as is.
+func fieldValue(val interface{}) interface{} {
+	switch v := val.(type) {
+	case error:
+		return v.Error()
+	case time.Duration:
+		return v.String()
+	default:
+		return v
+	}
+}
+
+// quoteValue quotes a text field value if it is empty or contains spaces,
+// quotes or equals signs.
+func quoteValue(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,77 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logger_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/astro/astro/logger"
+)
+
+func TestParseLevel(t *testing.T) {
+	level, err := logger.ParseLevel("WARN")
+	require.NoError(t, err)
+	assert.Equal(t, logger.WarnLevel, level)
+
+	_, err = logger.ParseLevel("verbose")
+	assert.EqualError(t, err, `unknown log level "verbose"; must be one of trace, debug, info, warn, error`)
+}
+
+func TestSinkText(t *testing.T) {
+	var buf bytes.Buffer
+	remove := logger.AddSink(&buf, logger.InfoLevel, logger.FormatText)
+
+	logger.Debug("not logged")
+	logger.Info("applied", logger.Fields{"id": "app-dev", "error": errors.New("it failed"), "count": 2})
+	remove()
+	logger.Info("not logged after the sink is removed")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 1)
+	assert.Regexp(t, `^\S+ INFO applied count=2 error="it failed" id=app-dev$`, lines[0])
+}
+
+func TestSinkJSON(t *testing.T) {
+	var buf bytes.Buffer
+	remove := logger.AddSink(&buf, logger.TraceLevel, logger.FormatJSON)
+
+	logger.Trace.Printf("exec2: running command: %v", "terraform")
+	logger.Warn("retrying", logger.Fields{"id": "app-dev", "attempt": 2})
+	remove()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+
+	var trace, warn map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &trace))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &warn))
+
+	assert.Equal(t, "trace", trace["level"])
+	assert.Equal(t, "exec2: running command: terraform", trace["msg"])
+
+	assert.Equal(t, "warn", warn["level"])
+	assert.Equal(t, "retrying", warn["msg"])
+	assert.Equal(t, "app-dev", warn["id"])
+	assert.Equal(t, float64(2), warn["attempt"])
+	assert.NotEmpty(t, warn["time"])
+}
@@ -0,0 +1,440 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/state"
+	"github.com/uber/astro/astro/terraform"
+	"github.com/uber/astro/astro/utils"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+const (
+	// runsFile is the name of the file in the session directory that
+	// records the stats of the commands that ran in the session.
+	runsFile = "runs.json"
+	// runLockKey is the key of the lock, in the state backend, that stops
+	// applies and destroys from running at the same time.
+	runLockKey = "lock"
+	// sessionsPrefix is the prefix of the keys of the sessions in the
+	// state backend.
+	sessionsPrefix = "sessions/"
+	// manifestFile is the name of the file, in each session in the state
+	// backend, that lists its files. It is written last, so sessions
+	// without one are incomplete.
+	manifestFile = "manifest.json"
+)
+
+// RunStats describes a command that ran in a session.
+type RunStats struct {
+	// Command is the command that ran, e.g. apply.
+	Command  string    `json:"command"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	// Executions is the number of executions that the command ran or
+	// skipped, of which Failed failed and Skipped were skipped.
+	Executions int `json:"executions"`
+	Failed     int `json:"failed"`
+	Skipped    int `json:"skipped"`
+	// Results is the outcome of each execution, in the order they
+	// finished. Runs recorded by older versions of astro have none.
+	Results []ExecutionRunStats `json:"results,omitempty"`
+}
+
+// ExecutionRunStats describes how an execution did in a run.
+type ExecutionRunStats struct {
+	ID     string `json:"id"`
+	Module string `json:"module"`
+	// Duration is how long its Terraform command took, if it ran one.
+	Duration time.Duration `json:"duration,omitempty"`
+	// Error is the error of the execution, if it failed.
+	Error   string `json:"error,omitempty"`
+	Skipped bool   `json:"skipped,omitempty"`
+}
+
+// historyManifest lists the files of a session in the state backend, with
+// when they were last modified, so that they are restored as they were.
+type historyManifest struct {
+	Files map[string]time.Time `json:"files"`
+}
+
+// lockRun takes the run lock in the state backend, if there is one, for
+// the command running in the session. The returned function releases it.
+func (c *Project) lockRun(session *Session, command string) (unlock func(), err error) {
+	if c.state == nil {
+		return func() {}, nil
+	}
+
+	info := state.LockInfo{
+		Owner:   runOwner(),
+		Command: command,
+		Session: session.id,
+		Created: c.clock.Now().UTC(),
+	}
+	if err := state.Lock(session.ctx, c.state, runLockKey, info); err != nil {
+		return nil, err
+	}
+	logger.Debug("took run lock", logger.Fields{"backend": c.state.Name(), "session": session.id})
+
+	return func() {
+		// not the session context, so that the lock is released when the
+		// run was interrupted
+		if err := state.Unlock(context.Background(), c.state, runLockKey); err != nil {
+			logger.Warn("unable to release run lock", logger.Fields{"backend": c.state.Name(), "error": err})
+		}
+	}, nil
+}
+
+// ForceUnlock releases the run lock in the state backend, whoever holds
+// it, e.g. when the run that took it was killed.
+func (c *Project) ForceUnlock() error {
+	if c.state == nil {
+		return errors.New("there is no run lock; state is not configured")
+	}
+	return state.Unlock(context.Background(), c.state, runLockKey)
+}
+
+// runOwner returns who is running astro, for the run lock.
+func runOwner() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	host, _ := os.Hostname()
+	return name + "@" + host
+}
+
+// recordRun passes on the results of the command running in the session.
+// Once they have all been passed on, it records the stats of the run in
+// the session, saves the session to the state backend, if there is one,
+// and calls unlock, before closing the channel.
+func (c *Project) recordRun(session *Session, command string, parameters ExecutionParameters, boundExecutions []*boundExecution, unlock func(), results <-chan *Result) <-chan *Result {
+	stats := RunStats{
+		Command: command,
+		Started: c.clock.Now().UTC(),
+	}
+	manifestRun := newManifestRun(command, parameters, stats.Started)
+
+	modules := map[string]string{}
+	for _, b := range boundExecutions {
+		modules[b.ID()] = b.ModuleConfig().Name
+	}
+
+	return passResults(results, func(result *Result) {
+		stats.Executions++
+		executionStats := ExecutionRunStats{
+			ID:     result.id,
+			Module: modules[result.id],
+		}
+		if executionStats.Module == "" {
+			executionStats.Module = result.id
+		}
+		if result.terraformResult != nil {
+			// the runtime is only reported to the second
+			executionStats.Duration, _ = time.ParseDuration(result.terraformResult.Runtime())
+		}
+		switch {
+		case result.err != nil:
+			stats.Failed++
+			executionStats.Error = result.err.Error()
+		case result.skipReason != "":
+			stats.Skipped++
+			executionStats.Skipped = true
+		}
+		stats.Results = append(stats.Results, executionStats)
+		manifestRun.Executions = append(manifestRun.Executions, session.manifestExecution(result, executionStats.Module, stats.Started, c.clock.Now().UTC()))
+	}, func() {
+		defer unlock()
+
+		stats.Finished = c.clock.Now().UTC()
+		if err := session.recordRunStats(stats); err != nil {
+			logger.Warn("unable to record run stats", logger.Fields{"session": session.id, "error": err})
+		}
+		manifestRun.Finished = stats.Finished
+		if err := session.recordManifestRun(manifestRun); err != nil {
+			logger.Warn("unable to record session manifest", logger.Fields{"session": session.id, "error": err})
+		}
+
+		if c.state != nil {
+			if err := c.sessions.saveHistory(session); err != nil {
+				logger.Warn("unable to save session", logger.Fields{"backend": c.state.Name(), "session": session.id, "error": err})
+			}
+		}
+	})
+}
+
+// recordRunStats adds the stats of a run to the session.
+func (session *Session) recordRunStats(stats RunStats) error {
+	runs, err := readRunStats(os.DirFS(session.path))
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(append(runs, stats), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(session.path, runsFile), data, 0644)
+}
+
+// readRunStats returns the stats of the runs of the session whose files
+// are in sessionFS, if any were recorded.
+func readRunStats(sessionFS fs.FS) ([]RunStats, error) {
+	data, err := fs.ReadFile(sessionFS, runsFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []RunStats
+	if err := json.Unmarshal(data, &runs); err != nil {
+		return nil, fmt.Errorf("unable to read %v: %v", runsFile, err)
+	}
+
+	return runs, nil
+}
+
+// sessionHistoryFiles are the files of a session directory, and
+// executionHistoryFiles the files of the directory of an execution in it,
+// that are part of its history.
+var (
+	sessionHistoryFiles   = []string{"git-sha", sessionLogFile, savedPlansFile, applyRecordFile, runsFile, sessionMetadataFile, sessionCompletedFile, sessionManifestFile}
+	executionHistoryFiles = []string{terraformBuildFile, terraform.CrashBundleFile}
+)
+
+// historyFiles returns the files of the session directory that make up
+// its history, relative to it: what SessionInfo reads, and the logs.
+// Terraform working directories, plans and state are not included.
+func historyFiles(sessionPath string) ([]string, error) {
+	var files []string
+
+	for _, name := range sessionHistoryFiles {
+		if utils.FileExists(filepath.Join(sessionPath, name)) {
+			files = append(files, name)
+		}
+	}
+
+	entries, err := os.ReadDir(sessionPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		logsPath := filepath.Join(sessionPath, entry.Name(), "logs")
+		if !entry.IsDir() || !utils.IsDirectory(logsPath) {
+			continue
+		}
+
+		for _, name := range executionHistoryFiles {
+			if utils.FileExists(filepath.Join(sessionPath, entry.Name(), name)) {
+				files = append(files, path.Join(entry.Name(), name))
+			}
+		}
+
+		logs, err := os.ReadDir(logsPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, log := range logs {
+			if log.Type().IsRegular() {
+				files = append(files, path.Join(entry.Name(), "logs", log.Name()))
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// isHistoryFile returns whether the file of a session, by its slash
+// separated path relative to the session directory, is one of the files
+// historyFiles returns for it.
+func isHistoryFile(name string) bool {
+	parts := strings.Split(name, "/")
+	switch len(parts) {
+	case 1:
+		return utils.StringSliceContains(sessionHistoryFiles, parts[0])
+	case 2:
+		return utils.StringSliceContains(executionHistoryFiles, parts[1])
+	case 3:
+		return parts[1] == "logs"
+	}
+	return false
+}
+
+// historyKey returns the name a file of the history of a session is saved
+// with in the state backend, relative to the session. It is the path of
+// the file, except for the session manifest, whose name is taken by the
+// manifest of the history.
+func historyKey(file string) string {
+	if file == sessionManifestFile {
+		return sessionManifestKey
+	}
+	return file
+}
+
+// saveHistory copies the history of the session to the state backend,
+// followed by its manifest.
+func (r *SessionRepo) saveHistory(session *Session) (errs error) {
+	backend := r.project.state
+	ctx := context.Background()
+
+	files, err := historyFiles(session.path)
+	if err != nil {
+		return err
+	}
+
+	manifest := historyManifest{Files: map[string]time.Time{}}
+	for _, file := range files {
+		localPath := filepath.Join(session.path, filepath.FromSlash(file))
+
+		stat, err := os.Stat(localPath)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(localPath)
+		if err != nil {
+			return err
+		}
+
+		// a file that can't be saved, e.g. a log that is too large for
+		// the backend, is left out rather than losing the whole session
+		if err := backend.Put(ctx, sessionsPrefix+session.id+"/"+historyKey(file), data); err != nil {
+			errs = multierror.Append(errs, err)
+			continue
+		}
+		manifest.Files[file] = stat.ModTime().UTC()
+	}
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+
+	logger.Trace.Printf("astro: saved %d files of session %v to %v", len(manifest.Files), session.id, backend.Name())
+
+	if err := backend.Put(ctx, sessionsPrefix+session.id+"/"+manifestFile, data); err != nil {
+		return err
+	}
+
+	return errs
+}
+
+// restoreHistory copies the sessions in the state backend that are not
+// in the repo to it, so that they can be browsed like local ones. Only
+// the session with the ID is restored, if it is set.
+func (r *SessionRepo) restoreHistory(id string) error {
+	backend := r.project.state
+	if backend == nil {
+		return nil
+	}
+	if id != "" && r.exists(id) {
+		return nil
+	}
+
+	ctx := context.Background()
+
+	prefix := sessionsPrefix
+	if id != "" {
+		prefix += id + "/"
+	}
+	keys, err := backend.List(ctx, prefix)
+	if err != nil {
+		return fmt.Errorf("unable to list sessions in %v: %v", backend.Name(), err)
+	}
+
+	for _, key := range keys {
+		sessionID := strings.TrimSuffix(strings.TrimPrefix(key, sessionsPrefix), "/"+manifestFile)
+		if !strings.HasSuffix(key, "/"+manifestFile) || !validPathElement(sessionID) {
+			continue
+		}
+		if r.exists(sessionID) {
+			continue
+		}
+		if err := r.restoreSession(ctx, sessionID); err != nil {
+			return fmt.Errorf("unable to restore session %v from %v: %v", sessionID, backend.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// restoreSession copies the files of a session in the state backend to
+// its directory in the repo. They are written to a temporary directory
+// first, so that a session is either restored or not.
+func (r *SessionRepo) restoreSession(ctx context.Context, id string) error {
+	backend := r.project.state
+
+	data, err := backend.Get(ctx, sessionsPrefix+id+"/"+manifestFile)
+	if err != nil {
+		return err
+	}
+	var manifest historyManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return fmt.Errorf("unable to read %v: %v", manifestFile, err)
+	}
+
+	tmpPath, err := os.MkdirTemp(r.path, "."+id+"-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpPath)
+	if err := os.Chmod(tmpPath, 0755); err != nil {
+		return err
+	}
+
+	for file, modified := range manifest.Files {
+		if path.Clean(file) != file || path.IsAbs(file) || strings.HasPrefix(file, "../") {
+			return fmt.Errorf("invalid file: %q", file)
+		}
+
+		data, err := backend.Get(ctx, sessionsPrefix+id+"/"+historyKey(file))
+		if err != nil {
+			return fmt.Errorf("%v: %v", file, err)
+		}
+
+		localPath := filepath.Join(tmpPath, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(localPath, data, 0644); err != nil {
+			return err
+		}
+		if err := os.Chtimes(localPath, modified, modified); err != nil {
+			return err
+		}
+	}
+
+	logger.Trace.Printf("astro: restored session %v from %v", id, backend.Name())
+
+	return os.Rename(tmpPath, filepath.Join(r.path, id))
+}
@@ -0,0 +1,166 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/state"
+	"github.com/uber/astro/astro/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSessionID = "01E2Q5HXW3TGNWAJ9T3V0MB4T4"
+
+// newStateTestProject returns a project whose session repo is in a new
+// directory, with a filesystem state backend in stateDir.
+func newStateTestProject(t *testing.T, stateDir string) *Project {
+	c := &Project{
+		config: &conf.Project{},
+		clock:  utils.SystemClock,
+		state:  state.NewFilesystem(stateDir),
+	}
+	sessions, err := NewSessionRepo(c, filepath.Join(t.TempDir(), ".astro"), func() string { return testSessionID })
+	require.NoError(t, err)
+	c.sessions = sessions
+	return c
+}
+
+func TestSessionHistoryRoundTrip(t *testing.T) {
+	stateDir := t.TempDir()
+
+	// the first container runs a session
+	c := newStateTestProject(t, stateDir)
+	session, err := c.sessions.NewSession()
+	require.NoError(t, err)
+
+	modified := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	for file, content := range map[string]string{
+		"git-sha":                       "abc123\n",
+		"app-dev/logs/plan.log":         "Plan: 1 to add",
+		"app-dev/" + terraformBuildFile: `{"version":"0.12.6"}`,
+		"app-dev/terraform.tfstate":     "state",
+		"app-dev/main.tf":               "resource {}",
+	} {
+		path := filepath.Join(session.path, filepath.FromSlash(file))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		require.NoError(t, os.Chtimes(path, modified, modified))
+	}
+
+	results := make(chan *Result, 2)
+	results <- &Result{id: "app-dev"}
+	results <- &Result{id: "app-prod", err: errors.New("failed")}
+	close(results)
+	testReadResults(c.recordRun(session, "plan", NoExecutionParameters(), nil, func() {}, results))
+
+	// the next one, in a new container, sees it
+	restored := newStateTestProject(t, stateDir)
+
+	sessions, err := restored.Sessions()
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+
+	info := sessions[0]
+	assert.Equal(t, testSessionID, info.ID)
+	assert.Equal(t, "abc123", info.GitSHA)
+	require.Len(t, info.Runs, 1)
+	assert.Equal(t, "plan", info.Runs[0].Command)
+	assert.Equal(t, 2, info.Runs[0].Executions)
+	assert.Equal(t, 1, info.Runs[0].Failed)
+	assert.Equal(t, []ExecutionRunStats{
+		{ID: "app-dev", Module: "app-dev"},
+		{ID: "app-prod", Module: "app-prod", Error: "failed"},
+	}, info.Runs[0].Results)
+	require.Len(t, info.Executions, 1)
+	assert.Equal(t, "0.12.6", info.Executions[0].TerraformVersion)
+	assert.Equal(t, modified, info.Executions[0].Finished().UTC())
+
+	log, err := restored.SessionLog(testSessionID, "app-dev", "plan.log")
+	require.NoError(t, err)
+	assert.Equal(t, "Plan: 1 to add", string(log))
+
+	manifest, err := restored.SessionManifest(testSessionID)
+	require.NoError(t, err)
+	require.Len(t, manifest.Runs, 1)
+	assert.Equal(t, "plan", manifest.Runs[0].Command)
+	require.Len(t, manifest.Runs[0].Executions, 2)
+	assert.Equal(t, "0.12.6", manifest.Runs[0].Executions[0].TerraformVersion)
+	assert.Equal(t, "failed", manifest.Runs[0].Executions[1].Result)
+
+	// only the history is kept
+	sessionPath := filepath.Join(restored.sessions.path, testSessionID)
+	assert.False(t, utils.FileExists(filepath.Join(sessionPath, "app-dev", "terraform.tfstate")))
+	assert.False(t, utils.FileExists(filepath.Join(sessionPath, "app-dev", "main.tf")))
+}
+
+func TestRestoreIgnoresIncompleteSessions(t *testing.T) {
+	stateDir := t.TempDir()
+
+	// a session whose manifest was never written
+	backend := state.NewFilesystem(stateDir)
+	require.NoError(t, backend.Put(context.Background(), sessionsPrefix+testSessionID+"/git-sha", []byte("abc123\n")))
+
+	c := newStateTestProject(t, stateDir)
+	sessions, err := c.Sessions()
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+}
+
+func TestRunLock(t *testing.T) {
+	stateDir := t.TempDir()
+
+	c := newStateTestProject(t, stateDir)
+	session, err := c.sessions.NewSession()
+	require.NoError(t, err)
+
+	unlock, err := c.lockRun(session, "apply")
+	require.NoError(t, err)
+
+	// another container can't run until the lock is released
+	other := newStateTestProject(t, stateDir)
+	otherSession, err := other.sessions.NewSession()
+	require.NoError(t, err)
+
+	_, err = other.lockRun(otherSession, "destroy")
+	lockedErr, ok := err.(*state.LockedError)
+	require.True(t, ok, "expected a *state.LockedError, got %v", err)
+	assert.Equal(t, "apply", lockedErr.Info.Command)
+	assert.Equal(t, testSessionID, lockedErr.Info.Session)
+
+	// the lock is released once all the results have been read
+	results := make(chan *Result)
+	close(results)
+	testReadResults(c.recordRun(session, "apply", NoExecutionParameters(), nil, unlock, results))
+
+	unlockOther, err := other.lockRun(otherSession, "destroy")
+	require.NoError(t, err)
+
+	// and can be forced
+	require.NoError(t, c.ForceUnlock())
+	_, err = c.lockRun(session, "apply")
+	require.NoError(t, err)
+	unlockOther()
+}
@@ -46,22 +46,15 @@ func (m *module) executions(parameters ExecutionParameters) executionSet {
 		return executionSet{}
 	}
 
-	// If a module doesn't have any variables, then there's just a
-	// single execution.
-	if len(m.config.Variables) < 1 {
-		return executionSet{
-			&unboundExecution{
-				&execution{
-					moduleConf:          m.config,
-					terraformParameters: parameters.TerraformParameters,
-				},
-			},
-		}
-	}
-
 	var variableValues [][]interface{}
 
 	for _, variable := range m.config.Variables {
+		// Computed variables are derived from the others when the
+		// execution is bound
+		if variable.IsComputed() {
+			continue
+		}
+
 		var v []interface{}
 		filtered := variable.IsFilter() && parameters.UserVars.Values[variable.Name] != ""
 
@@ -71,6 +64,10 @@ func (m *module) executions(parameters ExecutionParameters) executionSet {
 					v = append(v, fmt.Sprintf("%s=%s", variable.Name, value))
 				}
 			}
+		} else if !variable.IsRequired() && parameters.UserVars.Values[variable.Name] == "" {
+			// Variables that are not required and weren't given are bound
+			// to their default
+			v = append(v, fmt.Sprintf("%s=%s", variable.Name, variable.Default))
 		} else {
 			// If there are no predefined variable values, we create a single
 			// value "{var_name}" as a placeholder
@@ -80,6 +77,20 @@ func (m *module) executions(parameters ExecutionParameters) executionSet {
 		variableValues = append(variableValues, v)
 	}
 
+	// If a module doesn't have any variables, then there's just a
+	// single execution.
+	if len(variableValues) < 1 {
+		return executionSet{
+			&unboundExecution{
+				&execution{
+					moduleConf:          m.config,
+					terraformParameters: parameters.TerraformParameters,
+					targets:             parameters.Targets,
+				},
+			},
+		}
+	}
+
 	executions := executionSet{}
 
 	products := cartesian(variableValues...)
@@ -89,15 +100,23 @@ func (m *module) executions(parameters ExecutionParameters) executionSet {
 			&execution{
 				moduleConf:          m.config,
 				terraformParameters: parameters.TerraformParameters,
+				targets:             parameters.Targets,
 			},
 		}
 
 		e.variables = make(map[string]string)
 		for _, value := range p {
-			s := strings.Split(value.(string), "=")
+			s := strings.SplitN(value.(string), "=", 2)
 			e.variables[s[0]] = s[1]
 		}
 
+		// Add the variables of the foreach item of the execution
+		if m.config.ForEach != nil {
+			for key, val := range m.config.ForEach.Item(e.variables[m.config.ForEach.Key]) {
+				e.variables[key] = val
+			}
+		}
+
 		executions = append(executions, e)
 	}
 
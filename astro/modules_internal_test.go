@@ -17,9 +17,11 @@
 package astro
 
 import (
+	"sort"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/uber/astro/astro/conf"
 )
@@ -104,3 +106,280 @@ func TestModuleExecutionTarget(t *testing.T) {
 		TerraformParameters: []string{"-target", "one.terraform.entity", "-target", "another.terraform.entity"},
 	}))
 }
+
+func TestModuleExecutionForEach(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromYAML([]byte(`
+terraform:
+  path: fixtures/mock-terraform/success
+modules:
+  - name: tenant
+    path: .
+    variables:
+      - name: environment
+        values: [dev, prod]
+    foreach:
+      key: tenant
+      items:
+        globex: {region: eu-west-1}
+        acme: {region: us-east-1, size: large}
+  - name: cluster
+    path: .
+    foreach:
+      key: cluster
+      items:
+        - {cluster: blue, zone: a}
+        - {cluster: green, zone: b}
+`))
+	require.NoError(t, err)
+
+	variables := func(parameters ExecutionParameters) map[string]map[string]string {
+		byID := map[string]map[string]string{}
+		for _, e := range c.executions(parameters) {
+			byID[e.ID()] = e.Variables()
+		}
+		return byID
+	}
+
+	assert.Equal(t, map[string]map[string]string{
+		"tenant-dev-acme":    {"environment": "dev", "tenant": "acme", "region": "us-east-1", "size": "large"},
+		"tenant-prod-acme":   {"environment": "prod", "tenant": "acme", "region": "us-east-1", "size": "large"},
+		"tenant-dev-globex":  {"environment": "dev", "tenant": "globex", "region": "eu-west-1"},
+		"tenant-prod-globex": {"environment": "prod", "tenant": "globex", "region": "eu-west-1"},
+		"cluster-blue":       {"cluster": "blue", "zone": "a"},
+		"cluster-green":      {"cluster": "green", "zone": "b"},
+	}, variables(NoExecutionParameters()))
+
+	// The key is a variable that can be filtered on
+	filtered := variables(ExecutionParameters{
+		UserVars: &UserVariables{
+			Values:  map[string]string{"tenant": "acme"},
+			Filters: map[string]bool{"tenant": true},
+		},
+	})
+	var ids []string
+	for id := range filtered {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	assert.Equal(t, []string{"tenant-dev-acme", "tenant-prod-acme"}, ids)
+}
+
+func TestModuleForEachValidation(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewProjectFromYAML([]byte(`
+terraform:
+  path: fixtures/mock-terraform/success
+modules:
+  - name: tenant
+    path: .
+    variables:
+      - name: region
+    foreach:
+      key: tenant
+      items:
+        - {tenant: acme, region: us-east-1}
+        - {tenant: acme}
+`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "foreach: duplicate item: acme")
+
+	_, err = NewProjectFromYAML([]byte(`
+terraform:
+  path: fixtures/mock-terraform/success
+modules:
+  - name: tenant
+    path: .
+    variables:
+      - name: region
+    foreach:
+      key: tenant
+      items:
+        acme: {region: us-east-1}
+`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "foreach: item acme sets region, which is also a variable of the module")
+
+	_, err = NewProjectFromYAML([]byte(`
+terraform:
+  path: fixtures/mock-terraform/success
+modules:
+  - name: tenant
+    path: .
+    variables:
+      - name: tenant
+    foreach:
+      key: tenant
+      items:
+        acme: {region: us-east-1}
+`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "module tenant: foreach key tenant is also a variable of the module")
+}
+
+func TestCheckUserVariables(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromYAML([]byte(`
+terraform:
+  path: fixtures/mock-terraform/success
+modules:
+  - name: app
+    path: .
+    variables:
+      - name: environment
+        values: [dev, prod]
+        required: true
+      - name: region
+        allowed_values: [us-east-1, eu-west-1]
+      - name: cluster
+        pattern: ^[a-z]+-[0-9]+$
+        required: false
+        default: main-1
+  - name: network
+    path: .
+    variables:
+      - name: region
+        allowed_values: [us-east-1, eu-west-1]
+`))
+	require.NoError(t, err)
+
+	parameters := func(values map[string]string) ExecutionParameters {
+		return ExecutionParameters{UserVars: &UserVariables{Values: values}}
+	}
+
+	_, err = c.boundExecutions(parameters(map[string]string{"region": "us-east-1"}))
+	assert.EqualError(t, err, "missing required variables: environment")
+
+	_, err = c.boundExecutions(parameters(map[string]string{"environment": "dev", "region": "us-east1", "cluster": "Main"}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "2 errors occurred")
+	assert.Contains(t, err.Error(), `invalid value "us-east1" for variable region; did you mean us-east-1? allowed values: us-east-1, eu-west-1`)
+	assert.Contains(t, err.Error(), `invalid value "Main" for variable cluster; must match ^[a-z]+-[0-9]+$`)
+
+	boundExecutions, err := c.boundExecutions(parameters(map[string]string{"environment": "dev", "region": "eu-west-1"}))
+	require.NoError(t, err)
+	var ids []string
+	for _, b := range boundExecutions {
+		ids = append(ids, b.ID())
+		if b.ModuleConfig().Name == "app" {
+			assert.Equal(t, "main-1", b.Variables()["cluster"])
+		}
+	}
+	sort.Strings(ids)
+	assert.Equal(t, []string{"app-main-1-dev-eu-west-1", "network-eu-west-1"}, ids)
+}
+
+func TestVariableValidation(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewProjectFromYAML([]byte(`
+terraform:
+  path: fixtures/mock-terraform/success
+modules:
+  - name: app
+    path: .
+    variables:
+      - name: environment
+        values: [dev, Prod]
+        pattern: ^[a-z]+$
+      - name: tier
+        values: [web]
+        allowed_values: [web]
+      - name: region
+        pattern: "["
+      - name: cluster
+        required: true
+        default: main
+`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "values and allowed_values cannot both be set")
+	assert.Contains(t, err.Error(), `invalid value "Prod" for variable environment; must match ^[a-z]+$`)
+	assert.Contains(t, err.Error(), "invalid pattern: error parsing regexp")
+	assert.Contains(t, err.Error(), "default can only be set on variables without values that are not required")
+}
+
+func TestComputedVariables(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromYAML([]byte(`
+terraform:
+  path: fixtures/mock-terraform/success
+variables:
+  - name: name
+    computed: "{{.module}}-{{.environment}}"
+modules:
+  - name: app
+    path: .
+    remote:
+      backend: s3
+      backend_config:
+        key: "{{.bucket}}/terraform.tfstate"
+    variables:
+      - name: environment
+        values: [dev, prod]
+      - name: region
+        default: us-east-1
+      - name: bucket
+        computed: "{{.name}}-{{.region}}"
+  - name: network
+    path: .
+    variables:
+      - name: name
+        values: [core]
+`))
+	require.NoError(t, err)
+
+	boundExecutions, err := c.boundExecutions(ExecutionParameters{UserVars: &UserVariables{Values: map[string]string{"environment": "prod"}}})
+	require.NoError(t, err)
+	require.Len(t, boundExecutions, 2)
+
+	// computed variables are not part of the ID, and modules keep their own
+	// variables
+	app, network := boundExecutions[0], boundExecutions[1]
+	assert.Equal(t, "app-prod-us-east-1", app.ID())
+	assert.Equal(t, "app-prod", app.Variables()["name"])
+	assert.Equal(t, "app-prod-us-east-1", app.Variables()["bucket"])
+	assert.Equal(t, "app-prod-us-east-1/terraform.tfstate", app.ModuleConfig().Remote.BackendConfig["key"])
+	assert.Equal(t, "network-core", network.ID())
+	assert.Equal(t, "core", network.Variables()["name"])
+}
+
+func TestComputedVariablesValidation(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewProjectFromYAML([]byte(`
+terraform:
+  path: fixtures/mock-terraform/success
+variables:
+  - name: environment
+    values: [dev]
+modules:
+  - name: app
+    path: .
+    variables:
+      - name: region
+      - name: name
+        computed: "{{.module}}"
+        default: app
+`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "variables[environment]: only computed variables can be set for the project")
+	assert.Contains(t, err.Error(), "computed variables cannot have values, allowed_values, pattern, required or default, or be secret")
+
+	_, err = NewProjectFromYAML([]byte(`
+terraform:
+  path: fixtures/mock-terraform/success
+modules:
+  - name: app
+    path: .
+    variables:
+      - name: region
+      - name: name
+        computed: "{{.module}}-{{.regoin}}"
+`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "modules[0].variables[1].computed: undefined variable regoin; did you mean region?")
+}
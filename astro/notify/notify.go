@@ -0,0 +1,210 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package notify posts summaries of the results of astro commands to
+// Slack and other webhooks.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/terraform"
+)
+
+// timeout is how long a webhook has to respond.
+const timeout = 10 * time.Second
+
+// Execution statuses.
+const (
+	StatusOK      = "ok"
+	StatusError   = "error"
+	StatusSkipped = "skipped"
+)
+
+// defaultTemplate is the text of the message when the notification has no
+// template.
+const defaultTemplate = `astro {{.Command}} {{if .Success}}succeeded{{else}}failed{{end}} (session {{.Session}})
+{{range .Executions}}{{.ID}}: {{.Status}}{{with .Changes}} {{.}}{{end}}{{with .Reason}} ({{.}}){{end}}{{with .Error}}: {{.}}{{end}}
+{{with .Guidance}}{{.}}
+{{end}}{{end}}`
+
+// Summary is a summary of the results of a command.
+type Summary struct {
+	// Command is the command that ran: "plan", "apply" or "destroy".
+	Command string `json:"command"`
+	// Session is the ID of the session the command ran in.
+	Session string `json:"session"`
+	// Success is whether all executions succeeded.
+	Success    bool        `json:"success"`
+	Executions []Execution `json:"executions"`
+	// Totals is the sum of the changes in all plans.
+	Totals terraform.ChangeCounts `json:"totals"`
+}
+
+// Execution is the result of a single execution.
+type Execution struct {
+	ID string `json:"id"`
+	// Status is StatusOK, StatusError or StatusSkipped.
+	Status string `json:"status"`
+	// Changes is the changes in the plan, for plans.
+	Changes *terraform.ChangeCounts `json:"changes,omitempty"`
+	Error   string                  `json:"error,omitempty"`
+	// Guidance is what to do about the error, from the docs and
+	// on_error_message of the module.
+	Guidance string `json:"guidance,omitempty"`
+	// Reason is why the execution was skipped.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Warning is sent while a command runs, e.g. when an execution runs for
+// longer than expected.
+type Warning struct {
+	// Event is what the warning is about: "timeout".
+	Event string `json:"event"`
+	// Command is the command that is running: "plan", "apply" or
+	// "destroy".
+	Command string `json:"command"`
+	// Session is the ID of the session the command runs in.
+	Session string `json:"session"`
+	// Execution is the ID of the execution the warning is about.
+	Execution string `json:"execution"`
+	Message   string `json:"message"`
+}
+
+// webhookPayload is the body of the requests of "webhook" notifications:
+// the summary, along with the text of the message.
+type webhookPayload struct {
+	Summary
+	Text string `json:"text"`
+}
+
+// warningPayload is the body of the requests of "webhook" notifications
+// of warnings.
+type warningPayload struct {
+	Warning
+	Text string `json:"text"`
+}
+
+// slackPayload is the body of the requests of "slack" notifications.
+type slackPayload struct {
+	Text string `json:"text"`
+}
+
+// Send posts the summary to the webhook of the notification.
+func Send(ctx context.Context, notification conf.Notification, summary Summary) error {
+	text, err := render(notification, summary)
+	if err != nil {
+		return err
+	}
+
+	var payload interface{}
+	if notification.Format == conf.NotificationSlack {
+		payload = slackPayload{Text: text}
+	} else {
+		payload = webhookPayload{Summary: summary, Text: text}
+	}
+
+	logger.Debug("sending notification", logger.Fields{"command": summary.Command, "format": notification.Format})
+
+	return post(ctx, notification, payload)
+}
+
+// SendWarning posts the warning to the webhook of the notification. The
+// template of the notification is only used for summaries, so warnings
+// always have the same text.
+func SendWarning(ctx context.Context, notification conf.Notification, warning Warning) error {
+	text := fmt.Sprintf("astro %s warning (session %s)\n%s: %s", warning.Command, warning.Session, warning.Execution, warning.Message)
+
+	var payload interface{}
+	if notification.Format == conf.NotificationSlack {
+		payload = slackPayload{Text: text}
+	} else {
+		payload = warningPayload{Warning: warning, Text: text}
+	}
+
+	logger.Debug("sending warning notification", logger.Fields{"command": warning.Command, "format": notification.Format})
+
+	return post(ctx, notification, payload)
+}
+
+// post posts the payload to the webhook of the notification, as JSON.
+func post(ctx context.Context, notification conf.Notification, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	// the URL is not logged or included in errors, as it is often a
+	// secret
+	webhookURL := os.ExpandEnv(notification.URL)
+	if u, err := url.Parse(webhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("invalid notification url; check the environment variables in it are set")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid notification url")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to send notification: %v", errors.Unwrap(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("notification failed: %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+
+	return nil
+}
+
+// render returns the text of the message for the summary.
+func render(notification conf.Notification, summary Summary) (string, error) {
+	text := notification.Template
+	if text == "" {
+		text = defaultTemplate
+	}
+
+	tmpl, err := template.New("notification").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, summary); err != nil {
+		return "", fmt.Errorf("unable to render notification: %v", err)
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
@@ -0,0 +1,137 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/notify"
+	"github.com/uber/astro/astro/terraform"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSummary = notify.Summary{
+	Command: "plan",
+	Session: "01E2Q5HXW3TGNWAJ9T3V0MB4T4",
+	Success: false,
+	Executions: []notify.Execution{
+		{ID: "app-dev", Status: notify.StatusOK, Changes: &terraform.ChangeCounts{Add: 1}},
+		{ID: "app-prod", Status: notify.StatusError, Error: "plan failed"},
+		{ID: "app-staging", Status: notify.StatusSkipped, Reason: "account is frozen"},
+	},
+	Totals: terraform.ChangeCounts{Add: 1},
+}
+
+// testServer returns a server that records the body of the last request.
+func testServer(t *testing.T, status int, body *map[string]interface{}) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(body))
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSendSlack(t *testing.T) {
+	var body map[string]interface{}
+	server := testServer(t, http.StatusOK, &body)
+
+	t.Setenv("TEST_WEBHOOK_URL", server.URL)
+
+	err := notify.Send(context.Background(), conf.Notification{
+		URL:    "${TEST_WEBHOOK_URL}/hook",
+		Format: conf.NotificationSlack,
+	}, testSummary)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"text": "astro plan failed (session 01E2Q5HXW3TGNWAJ9T3V0MB4T4)\n" +
+			"app-dev: ok +1 ~0 -0\n" +
+			"app-prod: error: plan failed\n" +
+			"app-staging: skipped (account is frozen)",
+	}, body)
+}
+
+func TestSendWebhook(t *testing.T) {
+	var body map[string]interface{}
+	server := testServer(t, http.StatusOK, &body)
+
+	err := notify.Send(context.Background(), conf.Notification{
+		URL:      server.URL,
+		Template: "{{.Command}}: {{.Totals}}",
+	}, testSummary)
+	require.NoError(t, err)
+
+	assert.Equal(t, "plan: +1 ~0 -0", body["text"])
+	assert.Equal(t, "plan", body["command"])
+	assert.Equal(t, false, body["success"])
+	assert.Len(t, body["executions"], 3)
+}
+
+func TestSendFailure(t *testing.T) {
+	var body map[string]interface{}
+	server := testServer(t, http.StatusForbidden, &body)
+
+	err := notify.Send(context.Background(), conf.Notification{URL: server.URL}, testSummary)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "403")
+
+	err = notify.Send(context.Background(), conf.Notification{URL: "${TEST_UNSET_WEBHOOK_URL}"}, testSummary)
+	assert.Error(t, err)
+}
+
+func TestSendWarning(t *testing.T) {
+	warning := notify.Warning{
+		Event:     "timeout",
+		Command:   "apply",
+		Session:   "01E2Q5HXW3TGNWAJ9T3V0MB4T4",
+		Execution: "app-prod",
+		Message:   "still running after 20m0s; it may be stuck",
+	}
+	text := "astro apply warning (session 01E2Q5HXW3TGNWAJ9T3V0MB4T4)\napp-prod: still running after 20m0s; it may be stuck"
+
+	var body map[string]interface{}
+	server := testServer(t, http.StatusOK, &body)
+
+	// the template is only for summaries
+	err := notify.SendWarning(context.Background(), conf.Notification{
+		URL:      server.URL,
+		Format:   conf.NotificationSlack,
+		Template: "{{.Totals}}",
+	}, warning)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"text": text}, body)
+
+	err = notify.SendWarning(context.Background(), conf.Notification{URL: server.URL}, warning)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"event":     "timeout",
+		"command":   "apply",
+		"session":   "01E2Q5HXW3TGNWAJ9T3V0MB4T4",
+		"execution": "app-prod",
+		"message":   "still running after 20m0s; it may be stuck",
+		"text":      text,
+	}, body)
+}
@@ -17,9 +17,16 @@
 package astro
 
 import (
+	"errors"
+	"io"
+	"time"
+
 	"github.com/hashicorp/go-multierror"
 
 	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/secrets"
+	"github.com/uber/astro/astro/utils"
 )
 
 // Option is an option for the c that allows for changing of options or
@@ -45,3 +52,102 @@ func WithConfig(config conf.Project) Option {
 		return nil
 	}
 }
+
+// WithSessionLog writes everything that is logged while a session is
+// current to a log file in the session directory, in format, for
+// debugging after the fact.
+func WithSessionLog(format string) Option {
+	return func(c *Project) error {
+		if err := logger.ValidateFormat(format); err != nil {
+			return err
+		}
+		c.sessionLogFormat = format
+		return nil
+	}
+}
+
+// WithClock makes the project use clock for everything it times or
+// timestamps, including the runtimes of Terraform commands and the IDs
+// of sessions, e.g. to make tests deterministic.
+func WithClock(clock utils.Clock) Option {
+	return func(c *Project) error {
+		c.clock = clock
+		return nil
+	}
+}
+
+// WithIDGenerator makes the project use generateID for the IDs of new
+// sessions, instead of ULIDs. It must be safe to call from multiple
+// goroutines, and return unique IDs.
+func WithIDGenerator(generateID func() string) Option {
+	return func(c *Project) error {
+		c.generateID = generateID
+		return nil
+	}
+}
+
+// WithCommand makes the project record command and args as the command
+// line that runs in its sessions, e.g. "astro plan" and the arguments
+// given to it, instead of the command line of the process.
+func WithCommand(command string, args []string) Option {
+	return func(c *Project) error {
+		c.command = command
+		c.commandArgs = args
+		return nil
+	}
+}
+
+// WithVersion makes the project record version as the version of astro
+// in the manifest of its sessions.
+func WithVersion(version string) Option {
+	return func(c *Project) error {
+		c.version = version
+		return nil
+	}
+}
+
+// WithTerraformOutput streams the output of Terraform commands to w as
+// they run. Each line is prefixed with the execution ID.
+func WithTerraformOutput(w io.Writer) Option {
+	return func(c *Project) error {
+		c.terraformOutput = utils.NewSyncWriter(w)
+		return nil
+	}
+}
+
+// WithLockTimeout makes every execution wait up to timeout for the lock of
+// its state, e.g. with --lock-timeout, instead of the lock_timeout of its
+// module. A timeout of 0 fails right away.
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(c *Project) error {
+		if timeout < 0 {
+			return errors.New("lock timeout cannot be negative")
+		}
+		c.lockTimeoutOverride = &timeout
+		return nil
+	}
+}
+
+// WithTimeoutWarnings calls warn when an execution has been running
+// Terraform for longer than its warn_after, e.g. to send a notification.
+// It is called from the goroutines that run executions, so it must be
+// safe to call from several at once.
+func WithTimeoutWarnings(warn func(TimeoutWarning)) Option {
+	return func(c *Project) error {
+		c.timeoutWarnings = warn
+		return nil
+	}
+}
+
+// WithSecretResolver makes the project resolve the references of secret
+// variables with the scheme, e.g. "vault" for vault:secret/db#password,
+// with resolver, instead of the built-in resolver for the scheme.
+func WithSecretResolver(scheme string, resolver secrets.Resolver) Option {
+	return func(c *Project) error {
+		if c.secrets == nil {
+			c.secrets = secrets.Resolvers{}
+		}
+		c.secrets[scheme] = resolver
+		return nil
+	}
+}
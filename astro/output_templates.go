@@ -0,0 +1,142 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"regexp"
+	"text/template"
+
+	"github.com/uber/astro/astro/conf"
+)
+
+// reDeferredOutput matches the output calls that are kept in the env and
+// hook commands of an execution when it is bound, e.g.
+// {{output "network" "vpc_id"}}.
+var reDeferredOutput = regexp.MustCompile(`\{\{output "(?:[^"\\]|\\.)*" "(?:[^"\\]|\\.)*"\}\}`)
+
+// reOutputCall matches the module of the output calls in a template.
+var reOutputCall = regexp.MustCompile(`\boutput\s+"((?:[^"\\]|\\.)*)"`)
+
+// outputResolver returns the value of an output of a dependency of an
+// execution, for the output template function.
+type outputResolver func(module, name string) (string, error)
+
+// deferOutputFuncs returns the template functions of the env and hook
+// commands of an execution when it is bound. The outputs of dependencies
+// can only be read when the execution runs, so output calls are checked
+// and kept, to be rendered by renderOutputs.
+func deferOutputFuncs(deps []conf.Dependency) template.FuncMap {
+	return template.FuncMap{
+		"output": func(module, name string) (string, error) {
+			for _, dep := range deps {
+				if dep.Module == module {
+					return fmt.Sprintf("{{output %q %q}}", module, name), nil
+				}
+			}
+			return "", fmt.Errorf("output %v of %v: %v is not a dependency of the module", name, module, module)
+		},
+	}
+}
+
+// replaceAllVarsDeferringOutputs is the same as replaceAllVars, except
+// that output calls are kept, to be rendered by renderOutputs.
+func replaceAllVarsDeferringOutputs(s string, data interface{}, deps []conf.Dependency) (string, error) {
+	result, err := replaceVarsWithFuncs(s, data, deferOutputFuncs(deps))
+	if err != nil {
+		return "", err
+	}
+	if err := assertAllVarsReplaced(reDeferredOutput.ReplaceAllString(result, "")); err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
+// renderOutputs renders the output calls that were kept in s when the
+// execution was bound with the values that resolve returns.
+func renderOutputs(s string, resolve outputResolver) (string, error) {
+	if !reDeferredOutput.MatchString(s) {
+		return s, nil
+	}
+	return replaceVarsWithFuncs(s, nil, template.FuncMap{"output": resolve})
+}
+
+// renderOutputsInMapValues is the same as renderOutputs for each of the
+// values of a map.
+func renderOutputsInMapValues(inputMap map[string]string, resolve outputResolver) (map[string]string, error) {
+	outputMap := make(map[string]string)
+	for key, val := range inputMap {
+		rendered, err := renderOutputs(val, resolve)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", key, err)
+		}
+		outputMap[key] = rendered
+	}
+	return outputMap, nil
+}
+
+// outputResolver returns a resolver for the outputs of the dependencies of
+// the execution, which are read like the outputs passed to its variables.
+// Sensitive outputs are refused, since env and hook commands are logged.
+func (session *Session) outputResolver(execution *boundExecution) outputResolver {
+	return func(module, name string) (string, error) {
+		for _, dep := range executionDeps(execution) {
+			if dep.Module != module {
+				continue
+			}
+
+			depExecution, err := session.repo.project.outputDependency(execution, dep)
+			if err != nil {
+				return "", fmt.Errorf("unable to read outputs of %v: %v", module, err)
+			}
+			outputs, err := session.readDependencyOutputs(depExecution)
+			if err != nil {
+				return "", fmt.Errorf("unable to read outputs of %v: %v", depExecution.ID(), err)
+			}
+
+			output, ok := outputs[name]
+			if !ok {
+				return "", fmt.Errorf("%v has no output %v; it may not have been applied yet", depExecution.ID(), name)
+			}
+			if output.Sensitive {
+				return "", fmt.Errorf("output %v of %v is sensitive; pass it to a variable with deps outputs instead", name, depExecution.ID())
+			}
+			return outputVariableValue(output)
+		}
+		return "", fmt.Errorf("%v is not a dependency of the module", module)
+	}
+}
+
+// templateOutputModules returns the modules whose outputs the env and
+// hook commands of the module read with the output template function.
+func templateOutputModules(moduleConfig conf.Module) []string {
+	templates := []string{}
+	for _, val := range moduleConfig.Env {
+		templates = append(templates, val)
+	}
+	for _, hook := range moduleConfig.Hooks.PreModuleRun {
+		templates = append(templates, hook.Command)
+	}
+
+	var modules []string
+	for _, s := range templates {
+		for _, match := range reOutputCall.FindAllStringSubmatch(s, -1) {
+			modules = append(modules, match[1])
+		}
+	}
+	return modules
+}
@@ -0,0 +1,114 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package astro
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/terraform"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// Outputs runs `terraform output` for every execution matching the
+// parameters, in parallel, and returns their outputs by execution ID, and
+// then by output name. If some executions fail, the outputs of the others
+// are returned along with an error for each execution that failed.
+func (c *Project) Outputs(parameters ExecutionParameters) (map[string]map[string]terraform.Output, error) {
+	logger.Debug("starting output", logger.Fields{"modules": parameters.ModuleNames})
+
+	boundExecutions, err := c.boundExecutions(parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := c.sessions.Current()
+	if err != nil {
+		return nil, err
+	}
+
+	return session.outputs(boundExecutions, c.executionLimiter(parameters, 0))
+}
+
+func (session *Session) outputs(boundExecutions []*boundExecution, limiter *executionLimiter) (map[string]map[string]terraform.Output, error) {
+	// nobody reads the status updates, so they are all dropped
+	status := newStatusQueue()
+	defer status.close()
+
+	stopWatching := session.watchSignals()
+	defer stopWatching()
+
+	var mu sync.Mutex
+	outputs := map[string]map[string]terraform.Output{}
+	errs := map[string]error{}
+
+	limiter.run(session.ctx, boundExecutions, func(b *boundExecution) {
+		executionOutputs, err := session.outputExecution(b, status)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			errs[b.ID()] = err
+			return
+		}
+		// skipped by a PreModuleRun hook
+		if executionOutputs == nil {
+			return
+		}
+		outputs[b.ID()] = executionOutputs
+	}, nil)
+
+	var ids []string
+	for id := range errs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var result error
+	for _, id := range ids {
+		result = multierror.Append(result, fmt.Errorf("%s: %v", id, errs[id]))
+	}
+	if err := session.ctx.Err(); err != nil {
+		result = multierror.Append(result, err)
+	}
+
+	return outputs, result
+}
+
+// outputExecution initializes a single execution and returns its outputs,
+// or nil if a PreModuleRun hook vetoed it.
+func (session *Session) outputExecution(b *boundExecution, status *statusQueue) (map[string]terraform.Output, error) {
+	terraform, err := session.newTerraformSession(b)
+	if err != nil {
+		return nil, err
+	}
+
+	skipReason, err := session.runPreModuleRunHooks(b, status)
+	if err != nil || skipReason != "" {
+		return nil, err
+	}
+
+	if _, err := session.initialize(b, terraform, status); err != nil {
+		return nil, err
+	}
+
+	outputs, _, err := terraform.Output()
+
+	return outputs, err
+}
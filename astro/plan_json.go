@@ -0,0 +1,63 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/uber/astro/astro/terraform"
+)
+
+// planJSONFile is the name of the file, in the session directory of an
+// execution, that its plan is written to in JSON, for policies and cost
+// estimation.
+const planJSONFile = "plan.json"
+
+// planJSON returns a function that writes the plan of the execution in
+// JSON to its session directory and returns the path to it. The plan is
+// only written the first time the function is called.
+func (session *Session) planJSON(b *boundExecution, terraform *terraform.Session) func() (string, error) {
+	var (
+		written bool
+		path    string
+		err     error
+	)
+	return func() (string, error) {
+		if !written {
+			written = true
+			path, err = session.writePlanJSON(b, terraform)
+		}
+		return path, err
+	}
+}
+
+// writePlanJSON writes the plan of the execution in JSON to its session
+// directory and returns the path to it.
+func (session *Session) writePlanJSON(b *boundExecution, terraform *terraform.Session) (string, error) {
+	data, _, err := terraform.PlanJSON()
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(session.path, b.ID(), planJSONFile)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
@@ -0,0 +1,216 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/utils"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+// pluginCacheDirName is the name of the plugin cache in the session
+// repository, or in the session with the session scope.
+const pluginCacheDirName = "plugins"
+
+// globalPluginCacheDir is the plugin cache of the global scope, which
+// every project of the user shares.
+var globalPluginCacheDir = filepath.Join("~", ".astro", "plugin-cache")
+
+// PluginCachePrune is what pruning the plugin cache removed.
+type PluginCachePrune struct {
+	// Dir is the directory of the plugin cache.
+	Dir string
+	// Removed is the number of provider packages that were removed, and
+	// Freed their size in bytes.
+	Removed int
+	Freed   int64
+	// Size is the size of the cache after pruning, in bytes.
+	Size int64
+}
+
+// pluginPackage is a provider in the plugin cache: the directory of a
+// version of a provider for a platform, or with Terraform 0.12 and
+// earlier, its binary.
+type pluginPackage struct {
+	path     string
+	size     int64
+	modified time.Time
+}
+
+// PluginCacheDir returns the directory that Terraform caches providers in
+// for the project, by the scope of the plugin cache. It is empty with the
+// session scope, where each session has its own cache.
+func (c *Project) PluginCacheDir() (string, error) {
+	switch {
+	case c.config.PluginCache.Dir != "":
+		return c.config.PluginCache.Dir, nil
+	case c.config.PluginCache.Scope == conf.PluginCacheGlobal:
+		return homedir.Expand(globalPluginCacheDir)
+	case c.config.PluginCache.Scope == conf.PluginCacheSession:
+		return "", nil
+	}
+	return filepath.Join(c.sessions.path, pluginCacheDirName), nil
+}
+
+// pluginCacheDir returns the directory that Terraform caches providers in
+// during the session.
+func (session *Session) pluginCacheDir() (string, error) {
+	if session.repo.project.config.PluginCache.Scope == conf.PluginCacheSession {
+		return filepath.Join(session.path, pluginCacheDirName), nil
+	}
+	return session.repo.project.PluginCacheDir()
+}
+
+// closePluginCache removes the plugin cache of the session if it has its
+// own, or else prunes the shared cache to its max_size, if it is set.
+func (session *Session) closePluginCache() error {
+	config := session.repo.project.config.PluginCache
+	if config.Scope == conf.PluginCacheSession {
+		return os.RemoveAll(filepath.Join(session.path, pluginCacheDirName))
+	}
+	if config.MaxBytes() == 0 {
+		return nil
+	}
+	_, err := session.repo.project.PrunePluginCache(config.MaxBytes())
+	return err
+}
+
+// PrunePluginCache removes providers from the shared plugin cache, the
+// ones that were cached the longest ago first, until it is no larger than
+// maxBytes. A maxBytes of 0 empties it. Executions that used a removed
+// provider download it again the next time they are initialized.
+func (c *Project) PrunePluginCache(maxBytes int64) (*PluginCachePrune, error) {
+	dir, err := c.PluginCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	prune := &PluginCachePrune{Dir: dir}
+	if dir == "" || !utils.IsDirectory(dir) {
+		return prune, nil
+	}
+
+	packages, err := pluginPackages(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range packages {
+		prune.Size += p.size
+	}
+
+	// the oldest first
+	sort.Slice(packages, func(i, j int) bool {
+		return packages[i].modified.Before(packages[j].modified)
+	})
+	for _, p := range packages {
+		if prune.Size <= maxBytes {
+			break
+		}
+		if err := os.RemoveAll(p.path); err != nil {
+			return nil, err
+		}
+		prune.Removed++
+		prune.Freed += p.size
+		prune.Size -= p.size
+	}
+
+	if prune.Removed > 0 {
+		logger.Trace.Printf("astro: pruned %d providers from plugin cache %v", prune.Removed, dir)
+	}
+
+	return prune, removeEmptyDirs(dir)
+}
+
+// pluginPackages returns the providers in the plugin cache. From Terraform
+// 0.13, the cache has a directory for each provider package, e.g.
+// registry.terraform.io/hashicorp/aws/5.31.0/linux_amd64/, which is
+// removed as a whole; before, it has the binaries of providers, e.g.
+// linux_amd64/terraform-provider-aws_v2.70.0_x4.
+func pluginPackages(dir string) ([]*pluginPackage, error) {
+	packages := map[string]*pluginPackage{}
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		packagePath := path
+		if parts := strings.Split(rel, string(filepath.Separator)); len(parts) > 5 {
+			packagePath = filepath.Join(append([]string{dir}, parts[:5]...)...)
+		}
+
+		p, ok := packages[packagePath]
+		if !ok {
+			p = &pluginPackage{path: packagePath}
+			packages[packagePath] = p
+		}
+		p.size += info.Size()
+		if info.ModTime().After(p.modified) {
+			p.modified = info.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*pluginPackage, 0, len(packages))
+	for _, p := range packages {
+		list = append(list, p)
+	}
+	return list, nil
+}
+
+// removeEmptyDirs removes the directories in dir that are empty once
+// their empty directories are removed, but not dir itself.
+func removeEmptyDirs(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if err := removeEmptyDirs(path); err != nil {
+			return err
+		}
+		if children, err := os.ReadDir(path); err == nil && len(children) == 0 {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPluginCacheTestProject returns a project with the plugin cache
+// config, and a session.
+func newPluginCacheTestProject(t *testing.T, config conf.PluginCache) (*Project, *Session) {
+	c := &Project{
+		config: &conf.Project{PluginCache: config},
+		clock:  utils.SystemClock,
+	}
+	sessions, err := NewSessionRepo(c, filepath.Join(t.TempDir(), ".astro"), func() string { return testSessionID })
+	require.NoError(t, err)
+	c.sessions = sessions
+
+	session, err := sessions.Current()
+	require.NoError(t, err)
+
+	return c, session
+}
+
+// cacheProviders writes providers of the size to the plugin cache at dir,
+// cached days ago, by path.
+func cacheProviders(t *testing.T, dir string, size int, providers map[string]int) {
+	for file, days := range providers {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		modified := time.Now().AddDate(0, 0, -days)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, make([]byte, size), 0755))
+		require.NoError(t, os.Chtimes(path, modified, modified))
+	}
+}
+
+func TestPluginCacheDir(t *testing.T) {
+	c, session := newPluginCacheTestProject(t, conf.PluginCache{})
+
+	dir, err := c.PluginCacheDir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(c.sessions.path, "plugins"), dir)
+
+	c.config.PluginCache = conf.PluginCache{Dir: "/var/cache/terraform"}
+	dir, err = session.pluginCacheDir()
+	require.NoError(t, err)
+	assert.Equal(t, "/var/cache/terraform", dir)
+
+	c.config.PluginCache = conf.PluginCache{Scope: conf.PluginCacheSession}
+	dir, err = c.PluginCacheDir()
+	require.NoError(t, err)
+	assert.Equal(t, "", dir)
+	dir, err = session.pluginCacheDir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(session.path, "plugins"), dir)
+}
+
+func TestPrunePluginCache(t *testing.T) {
+	c, _ := newPluginCacheTestProject(t, conf.PluginCache{})
+	dir, err := c.PluginCacheDir()
+	require.NoError(t, err)
+
+	cacheProviders(t, dir, 100, map[string]int{
+		"registry.terraform.io/hashicorp/aws/5.30.0/linux_amd64/terraform-provider-aws_v5.30.0_x5": 30,
+		"registry.terraform.io/hashicorp/aws/5.30.0/linux_amd64/LICENSE":                           30,
+		"registry.terraform.io/hashicorp/aws/5.31.0/linux_amd64/terraform-provider-aws_v5.31.0_x5": 1,
+		"registry.terraform.io/hashicorp/null/3.2.1/linux_amd64/terraform-provider-null_v3.2.1_x5": 10,
+		"linux_amd64/terraform-provider-aws_v2.70.0_x4":                                            20,
+	})
+
+	prune, err := c.PrunePluginCache(250)
+	require.NoError(t, err)
+	assert.Equal(t, &PluginCachePrune{Dir: dir, Removed: 2, Freed: 300, Size: 200}, prune)
+
+	// the packages are removed as a whole, and the directories they leave
+	// empty
+	assert.False(t, utils.FileExists(filepath.Join(dir, "registry.terraform.io/hashicorp/aws/5.30.0")))
+	assert.False(t, utils.FileExists(filepath.Join(dir, "linux_amd64")))
+	assert.True(t, utils.FileExists(filepath.Join(dir, "registry.terraform.io/hashicorp/aws/5.31.0/linux_amd64/terraform-provider-aws_v5.31.0_x5")))
+	assert.True(t, utils.FileExists(filepath.Join(dir, "registry.terraform.io/hashicorp/null/3.2.1/linux_amd64/terraform-provider-null_v3.2.1_x5")))
+
+	prune, err = c.PrunePluginCache(0)
+	require.NoError(t, err)
+	assert.Equal(t, &PluginCachePrune{Dir: dir, Removed: 2, Freed: 200}, prune)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestClosePrunesPluginCache(t *testing.T) {
+	c, _ := newPluginCacheTestProject(t, conf.PluginCache{MaxSize: "150B"})
+	dir, err := c.PluginCacheDir()
+	require.NoError(t, err)
+	cacheProviders(t, dir, 100, map[string]int{
+		"registry.terraform.io/hashicorp/aws/5.30.0/linux_amd64/terraform-provider-aws_v5.30.0_x5": 30,
+		"registry.terraform.io/hashicorp/aws/5.31.0/linux_amd64/terraform-provider-aws_v5.31.0_x5": 1,
+	})
+
+	require.NoError(t, c.Close())
+	assert.False(t, utils.FileExists(filepath.Join(dir, "registry.terraform.io/hashicorp/aws/5.30.0")))
+	assert.True(t, utils.FileExists(filepath.Join(dir, "registry.terraform.io/hashicorp/aws/5.31.0")))
+
+	// a session's own cache is removed when it ends
+	c, session := newPluginCacheTestProject(t, conf.PluginCache{Scope: conf.PluginCacheSession})
+	dir, err = session.pluginCacheDir()
+	require.NoError(t, err)
+	cacheProviders(t, dir, 100, map[string]int{"linux_amd64/terraform-provider-aws_v2.70.0_x4": 1})
+
+	require.NoError(t, c.Close())
+	assert.False(t, utils.FileExists(dir))
+	assert.True(t, utils.IsDirectory(session.path))
+}
+
+func TestParseSize(t *testing.T) {
+	for size, expected := range map[string]int64{
+		"":       0,
+		"1024":   1024,
+		"150B":   150,
+		"500MB":  500e6,
+		"1.5 GB": 1.5e9,
+		"2gb":    2e9,
+		"1TB":    1e12,
+	} {
+		actual, err := conf.ParseSize(size)
+		require.NoError(t, err, size)
+		assert.Equal(t, expected, actual, size)
+	}
+
+	for _, size := range []string{"GB", "5 XB", "-1GB"} {
+		_, err := conf.ParseSize(size)
+		assert.Error(t, err, size)
+	}
+}
+
+func TestConfigPluginCache(t *testing.T) {
+	t.Parallel()
+
+	config, err := configFromYAML([]byte(`---
+terraform:
+  path: /bin/true
+  version: 0.12.31
+plugin_cache:
+  dir: ~/cache/terraform
+  max_size: 5GB
+modules:
+  - name: app
+    path: .
+`), "")
+	require.NoError(t, err)
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, "cache", "terraform"), config.PluginCache.Dir)
+	assert.Equal(t, int64(5e9), config.PluginCache.MaxBytes())
+
+	config, err = configFromYAML([]byte(`---
+terraform:
+  path: /bin/true
+  version: 0.12.31
+plugin_cache:
+  scope: machine
+  max_size: lots
+modules:
+  - name: app
+    path: .
+`), "")
+	require.NoError(t, err)
+	_, err = NewProject(WithConfig(*config))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "plugin_cache: scope must be one of project, session or global")
+
+	config.PluginCache.Scope = ""
+	_, err = NewProject(WithConfig(*config))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "plugin_cache: invalid max_size")
+}
@@ -0,0 +1,87 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/policy"
+	"github.com/uber/astro/astro/terraform"
+)
+
+// PolicyViolationError is the error of an execution whose plan violates
+// policies with enforcement "error".
+type PolicyViolationError struct {
+	Violations []string
+}
+
+// Error is the error message, so this satisfies the error interface.
+func (e *PolicyViolationError) Error() string {
+	return fmt.Sprintf("plan violates policies:\n  %s", strings.Join(e.Violations, "\n  "))
+}
+
+// checkPolicies checks the plan of the execution against the policies
+// that apply to its module. Violations of policies with enforcement "warn"
+// are returned as warnings, and the others as a *PolicyViolationError, in
+// which case the plan is discarded so that it cannot be applied.
+func (session *Session) checkPolicies(b *boundExecution, terraform *terraform.Session, planJSON func() (string, error), status *statusQueue) ([]string, error) {
+	config := session.repo.project.config
+
+	var policies []conf.Policy
+	for _, p := range config.Policies {
+		if p.Applies(b.ModuleConfig().Name) {
+			policies = append(policies, p)
+		}
+	}
+	if len(policies) == 0 {
+		return nil, nil
+	}
+
+	status.send(b.ID(), "Checking policies...")
+
+	planPath, err := planJSON()
+	if err != nil {
+		return nil, fmt.Errorf("unable to check policies: %v", err)
+	}
+
+	var warnings []string
+	violationErr := &PolicyViolationError{}
+	for _, p := range policies {
+		violations, err := policy.Evaluate(session.ctx, config.OPAPath, p, planPath)
+		if err != nil {
+			return warnings, err
+		}
+		if p.Enforcement == conf.PolicyWarn {
+			for _, violation := range violations {
+				warnings = append(warnings, "policy violation: "+violation)
+			}
+		} else {
+			violationErr.Violations = append(violationErr.Violations, violations...)
+		}
+	}
+
+	if violationErr.Violations != nil {
+		if err := terraform.DiscardPlan(); err != nil {
+			return warnings, err
+		}
+		return warnings, violationErr
+	}
+
+	return warnings, nil
+}
@@ -0,0 +1,140 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package policy checks Terraform plans against Rego policies, by
+// evaluating them with OPA.
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/exec2"
+	"github.com/uber/astro/astro/logger"
+)
+
+// DefaultQuery is the query of policies that don't set one. It follows
+// the convention of conftest and most Terraform policy libraries.
+const DefaultQuery = "data.terraform.deny"
+
+// killTimeout is how long OPA has to exit after it is interrupted before
+// it is killed.
+const killTimeout = 10 * time.Second
+
+// evalOutput is the output of `opa eval --format json`. The result is
+// missing if the query is undefined, e.g. because no rule matched.
+type evalOutput struct {
+	Result []struct {
+		Expressions []struct {
+			Value interface{} `json:"value"`
+		} `json:"expressions"`
+	} `json:"result"`
+}
+
+// Evaluate evaluates the query of the policy against the plan at planPath,
+// in the JSON format of `terraform show -json`, and returns the
+// violations. opaPath is the path to the OPA binary; if it's empty, opa
+// is found in the PATH.
+func Evaluate(ctx context.Context, opaPath string, policy conf.Policy, planPath string) ([]string, error) {
+	if opaPath == "" {
+		path, err := exec.LookPath("opa")
+		if err != nil {
+			return nil, errors.New("unable to find opa to check policies: install it, or set opa_path in the config")
+		}
+		opaPath = path
+	}
+
+	query := policy.Query
+	if query == "" {
+		query = DefaultQuery
+	}
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+	cmd := exec.Command(opaPath, "eval", "--format", "json", "--data", policy.Path, "--input", planPath, query)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	logger.Trace.Printf("policy: running %v", cmd.Args)
+	if err := exec2.RunContext(ctx, cmd, killTimeout); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("unable to evaluate policy %v: %v", policy.Path, message)
+		}
+		return nil, fmt.Errorf("unable to evaluate policy %v: %v", policy.Path, err)
+	}
+
+	violations, err := parseViolations(stdout.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("unable to evaluate policy %v: %v", policy.Path, err)
+	}
+
+	return violations, nil
+}
+
+// parseViolations returns the violations in the output of `opa eval`. The
+// value of the query can be a set or array of messages, or of objects with
+// a "msg", like conftest's, a single message, or a boolean that is true if
+// the policy was violated.
+func parseViolations(data []byte) ([]string, error) {
+	var output evalOutput
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, err
+	}
+
+	var violations []string
+	for _, result := range output.Result {
+		for _, expression := range result.Expressions {
+			switch value := expression.Value.(type) {
+			case nil:
+			case bool:
+				if value {
+					violations = append(violations, "policy violated")
+				}
+			case string:
+				violations = append(violations, value)
+			case []interface{}:
+				for _, v := range value {
+					violations = append(violations, violationMessage(v))
+				}
+			default:
+				return nil, fmt.Errorf("the query must return a set of violations, not %v", value)
+			}
+		}
+	}
+
+	return violations, nil
+}
+
+// violationMessage returns the message of a violation.
+func violationMessage(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		if msg, ok := v["msg"].(string); ok {
+			return msg
+		}
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
@@ -0,0 +1,76 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseViolations(t *testing.T) {
+	tt := []struct {
+		name     string
+		output   string
+		expected []string
+	}{
+		{
+			name:     "undefined",
+			output:   `{}`,
+			expected: nil,
+		},
+		{
+			name:     "set of messages",
+			output:   `{"result":[{"expressions":[{"value":["a","b"]}]}]}`,
+			expected: []string{"a", "b"},
+		},
+		{
+			name:     "set of objects",
+			output:   `{"result":[{"expressions":[{"value":[{"msg":"a"},{"resource":"b"}]}]}]}`,
+			expected: []string{"a", `{"resource":"b"}`},
+		},
+		{
+			name:     "message",
+			output:   `{"result":[{"expressions":[{"value":"a"}]}]}`,
+			expected: []string{"a"},
+		},
+		{
+			name:     "violated",
+			output:   `{"result":[{"expressions":[{"value":true}]}]}`,
+			expected: []string{"policy violated"},
+		},
+		{
+			name:     "not violated",
+			output:   `{"result":[{"expressions":[{"value":false}]}]}`,
+			expected: nil,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			violations, err := parseViolations([]byte(tc.output))
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, violations)
+		})
+	}
+}
+
+func TestParseViolationsInvalid(t *testing.T) {
+	_, err := parseViolations([]byte(`{"result":[{"expressions":[{"value":1}]}]}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,172 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/exec2"
+	"github.com/uber/astro/astro/filter"
+	"github.com/uber/astro/astro/logger"
+
+	"github.com/kballard/go-shellquote"
+)
+
+// preconditionTimeout is how long an HTTP or command precondition has to
+// pass before it fails.
+const preconditionTimeout = 30 * time.Second
+
+// PreconditionError is the error of an execution that was blocked because
+// one of the preconditions of its module failed, so it was not run.
+type PreconditionError struct {
+	Precondition string
+	Reason       string
+}
+
+// Error is the error message, so this satisfies the error interface.
+func (e *PreconditionError) Error() string {
+	return fmt.Sprintf("blocked: precondition %s failed: %s", e.Precondition, e.Reason)
+}
+
+// checkPreconditions checks the preconditions of the module of the
+// execution, in order, and returns a *PreconditionError for the first one
+// that fails.
+func (session *Session) checkPreconditions(b *boundExecution, status *statusQueue) error {
+	for _, precondition := range b.ModuleConfig().Preconditions {
+		status.send(b.ID(), "Checking precondition %s...", precondition)
+
+		var err error
+		switch {
+		case precondition.HTTP != "":
+			err = checkHTTPPrecondition(session.ctx, precondition.HTTP)
+		case precondition.Command != "":
+			err = checkCommandPrecondition(session.ctx, session.path, precondition.Command)
+		default:
+			err = session.checkOutputPrecondition(b, precondition)
+		}
+		if err != nil {
+			// interrupted rather than failed
+			if ctxErr := session.ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			logger.Info("execution blocked by precondition", logger.Fields{"id": b.ID(), "precondition": precondition.String(), "reason": err.Error()})
+			return &PreconditionError{Precondition: precondition.String(), Reason: err.Error()}
+		}
+	}
+	return nil
+}
+
+// checkHTTPPrecondition checks that the URL responds with a 2xx status.
+func checkHTTPPrecondition(ctx context.Context, url string) error {
+	ctx, cancel := context.WithTimeout(ctx, preconditionTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s responded with %s", url, resp.Status)
+	}
+	return nil
+}
+
+// checkCommandPrecondition checks that the command exits with 0. Its
+// output is the reason it failed.
+func checkCommandPrecondition(ctx context.Context, workingDir, command string) error {
+	args, err := shellquote.Split(command)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return errors.New("empty command")
+	}
+	prog, err := exec.LookPath(args[0])
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, preconditionTimeout)
+	defer cancel()
+
+	output := &bytes.Buffer{}
+	cmd := exec.Command(prog, args[1:]...)
+	cmd.Dir = workingDir
+	cmd.Env = os.Environ()
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	if err := exec2.RunContext(ctx, cmd, hookKillTimeout); err != nil {
+		if out := strings.TrimSpace(output.String()); out != "" {
+			return fmt.Errorf("%v: %s", err, out)
+		}
+		return err
+	}
+	return nil
+}
+
+// checkOutputPrecondition checks that the outputs of the dependency match
+// the output expression of the precondition.
+func (session *Session) checkOutputPrecondition(b *boundExecution, precondition conf.Precondition) error {
+	expr, err := filter.Parse(precondition.Output)
+	if err != nil {
+		return err
+	}
+
+	for _, dep := range executionDeps(b) {
+		if dep.Module != precondition.Dependency {
+			continue
+		}
+
+		depExecution, err := session.repo.project.outputDependency(b, dep)
+		if err != nil {
+			return err
+		}
+		outputs, err := session.readDependencyOutputs(depExecution)
+		if err != nil {
+			return fmt.Errorf("unable to read outputs of %v: %v", depExecution.ID(), err)
+		}
+
+		fields := map[string]string{}
+		for name, output := range outputs {
+			if fields[name], err = outputVariableValue(output); err != nil {
+				return fmt.Errorf("output %v of %v: %v", name, depExecution.ID(), err)
+			}
+		}
+		if !expr.Match(fields) {
+			return fmt.Errorf("the outputs of %v don't match %s", depExecution.ID(), precondition.Output)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("%v is not a dependency of %v", precondition.Dependency, b.ModuleConfig().Name)
+}
@@ -0,0 +1,158 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/uber/astro/astro/conf"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckHTTPPrecondition(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	assert.NoError(t, checkHTTPPrecondition(context.Background(), server.URL+"/health"))
+	assert.EqualError(t, checkHTTPPrecondition(context.Background(), server.URL+"/down"), server.URL+"/down responded with 503 Service Unavailable")
+}
+
+func TestCheckCommandPrecondition(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	assert.NoError(t, checkCommandPrecondition(context.Background(), dir, "true"))
+	assert.EqualError(t, checkCommandPrecondition(context.Background(), dir, "sh -c 'echo not ready; exit 3'"), "exit status 3: not ready")
+}
+
+// testPlanWithPreconditions plans the app module of the
+// test-dependency-outputs fixture with the preconditions.
+func testPlanWithPreconditions(t *testing.T, preconditions ...conf.Precondition) *Result {
+	config, err := NewConfigFromFile("fixtures/test-dependency-outputs/astro.yaml")
+	require.NoError(t, err)
+	config.Modules[1].Preconditions = preconditions
+
+	c, err := NewProject(WithConfig(*config))
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Plan(PlanExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			ModuleNames: []string{"app"},
+			UserVars:    NoUserVariables(),
+		},
+	})
+	require.NoError(t, err)
+
+	return testReadResults(resultChan)["app-dev"]
+}
+
+func TestPlanPreconditionsPass(t *testing.T) {
+	t.Parallel()
+
+	result := testPlanWithPreconditions(t,
+		conf.Precondition{Command: "true"},
+		conf.Precondition{Dependency: "network", Output: `greeting == "hello"`},
+	)
+	assert.NoError(t, result.Err())
+	assert.NotNil(t, result.TerraformResult())
+}
+
+func TestPlanPreconditionBlocks(t *testing.T) {
+	t.Parallel()
+
+	result := testPlanWithPreconditions(t,
+		conf.Precondition{Name: "network is ready", Dependency: "network", Output: `greeting == "bye"`},
+		conf.Precondition{Command: "true"},
+	)
+
+	var blocked *PreconditionError
+	require.True(t, errors.As(result.Err(), &blocked))
+	assert.Equal(t, "network is ready", blocked.Precondition)
+	assert.Equal(t, `the outputs of network-dev don't match greeting == "bye"`, blocked.Reason)
+	assert.Nil(t, result.TerraformResult(), "blocked executions are not run")
+}
+
+func TestApplyPreconditionBlocksDependents(t *testing.T) {
+	t.Parallel()
+
+	config, err := NewConfigFromFile("fixtures/test-dependency-outputs/astro.yaml")
+	require.NoError(t, err)
+	config.Modules[0].Preconditions = []conf.Precondition{{Command: "sh -c 'test {{.environment}} = dev'"}}
+
+	c, err := NewProject(WithConfig(*config))
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Apply(ApplyExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			UserVars: NoUserVariables(),
+		},
+	})
+	require.NoError(t, err)
+
+	results := testReadResults(resultChan)
+	for _, id := range []string{"network-dev", "app-dev", "network-prod"} {
+		require.Contains(t, results, id)
+	}
+	assert.NoError(t, results["network-dev"].Err())
+	assert.NoError(t, results["app-dev"].Err())
+
+	var blocked *PreconditionError
+	require.True(t, errors.As(results["network-prod"].Err(), &blocked))
+	assert.Equal(t, "sh -c 'test prod = dev'", blocked.Precondition)
+	assert.Equal(t, "exit status 1", blocked.Reason)
+	assert.NotContains(t, results, "app-prod")
+}
+
+func TestPreconditionsValidation(t *testing.T) {
+	t.Parallel()
+
+	for precondition, message := range map[string]string{
+		"{http: 'http://example.com', command: 'true'}": "preconditions[0]: exactly one of http, command and output must be set",
+		"{http: 'ftp://example.com'}":                   "preconditions[0]: http URL must be http or https: ftp://example.com",
+		"{output: 'status == \"ready\"'}":               "preconditions[0]: output needs the dependency whose outputs it matches",
+		"{output: 'status ==', dependency: network}":    "preconditions[0]: invalid output expression",
+		"{output: 'status == \"ok\"', dependency: db}":  "preconditions[0]: db is not a dependency of the module",
+	} {
+		_, err := NewProjectFromYAML([]byte(`
+terraform:
+  path: fixtures/mock-terraform/success
+modules:
+  - name: network
+    path: .
+  - name: app
+    path: .
+    deps:
+      - module: network
+    preconditions:
+      - ` + precondition + `
+`))
+		require.Error(t, err, precondition)
+		assert.Contains(t, err.Error(), message, precondition)
+	}
+}
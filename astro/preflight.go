@@ -0,0 +1,87 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/sts"
+)
+
+// PreflightError is returned from Preflight when a check that is
+// configured to refuse fails.
+type PreflightError struct {
+	reason string
+}
+
+// Error is the error message, so this satisfies the error interface.
+func (e *PreflightError) Error() string {
+	return fmt.Sprintf("preflight check failed: %s", e.reason)
+}
+
+// Preflight checks how astro runs before it plans, applies or destroys:
+// whether it runs as root, and whether its AWS identity is an
+// administrator. Checks that are configured to warn return warnings, and
+// checks that are configured to refuse return a *PreflightError.
+func (c *Project) Preflight() (warnings []string, err error) {
+	preflight := c.config.Preflight
+
+	check := func(action, reason string) error {
+		switch action {
+		case conf.PreflightRefuse:
+			return &PreflightError{reason: reason}
+		case conf.PreflightWarn:
+			warnings = append(warnings, reason)
+		}
+		return nil
+	}
+
+	if c.geteuid() == 0 {
+		if err := check(preflight.RootAction(), "astro is running as root; run it as an unprivileged user, or set preflight.root to allow it"); err != nil {
+			return warnings, err
+		}
+	}
+
+	if preflight.AdminCredentialsAction() == conf.PreflightAllow {
+		return warnings, nil
+	}
+
+	identity, err := sts.CallerIdentity(context.Background(), c.config.AWSCLIPath, os.Environ())
+	if err != nil {
+		logger.Trace.Printf("astro: admin credentials check failed: %v", err)
+		// an identity that can't be checked isn't known not to be an
+		// administrator, so checks that refuse fail closed
+		reason := fmt.Sprintf("unable to check the AWS identity astro runs as: %v", err)
+		if err := check(preflight.AdminCredentialsAction(), reason); err != nil {
+			return warnings, err
+		}
+		return warnings, nil
+	}
+
+	if preflight.IsAdmin(identity.ARN) {
+		reason := fmt.Sprintf("the AWS identity %v is an administrator; use scoped credentials for routine plans, or set preflight.admin_credentials to allow it", identity.ARN)
+		if err := check(preflight.AdminCredentialsAction(), reason); err != nil {
+			return warnings, err
+		}
+	}
+
+	return warnings, nil
+}
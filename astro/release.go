@@ -0,0 +1,147 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/git"
+
+	"github.com/ghodss/yaml"
+	"github.com/hashicorp/go-multierror"
+)
+
+// ReleaseTrain is a list of steps, each of which plans and applies
+// modules of an astro project. Steps are run one after the other, in
+// order.
+type ReleaseTrain struct {
+	Steps []ReleaseStep
+}
+
+// ReleaseStep is a single step of a release train.
+type ReleaseStep struct {
+	// Name is shown when running the step. Defaults to the config path.
+	Name string
+	// Config is the path to the astro config file of the project. It is
+	// relative to the release file.
+	Config string
+	// Modules, if set, limits the step to these modules of the project.
+	Modules []string
+	// Variables are the values of the project's variables, in the same
+	// way as they are passed as flags on the command line.
+	Variables map[string]string
+	// SHA, if set, is the commit that the project's repository must be
+	// checked out at. It can be abbreviated.
+	SHA string `json:"sha"`
+	// Checkpoint asks for confirmation before the plans of this step are
+	// applied.
+	Checkpoint bool
+}
+
+// LoadReleaseTrain reads a release train from a YAML file.
+func LoadReleaseTrain(path string) (*ReleaseTrain, error) {
+	yamlBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	train := &ReleaseTrain{}
+	if err := yaml.Unmarshal(yamlBytes, train); err != nil {
+		return nil, fmt.Errorf("unable to parse release file %s: %v", path, err)
+	}
+
+	root := filepath.Dir(path)
+	for i := range train.Steps {
+		step := &train.Steps[i]
+		if step.Config != "" && !filepath.IsAbs(step.Config) {
+			step.Config = filepath.Join(root, step.Config)
+		}
+		if step.Name == "" {
+			step.Name = step.Config
+		}
+	}
+
+	if err := train.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid release file %s: %v", path, err)
+	}
+
+	return train, nil
+}
+
+// Validate checks the release train is good.
+func (t *ReleaseTrain) Validate() (errs error) {
+	if len(t.Steps) == 0 {
+		return errors.New("no steps")
+	}
+	for i, step := range t.Steps {
+		if step.Config == "" {
+			errs = multierror.Append(errs, fmt.Errorf("steps[%d]: config is required", i))
+			continue
+		}
+		if _, err := os.Stat(step.Config); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("steps[%d]: %v", i, err))
+		}
+	}
+	return errs
+}
+
+// VerifySHA checks that the repository of the step's project is checked
+// out at the step's SHA, if it has one.
+func (s *ReleaseStep) VerifySHA() error {
+	if s.SHA == "" {
+		return nil
+	}
+
+	head, err := git.HeadSHA(filepath.Dir(s.Config))
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(head, s.SHA) {
+		return fmt.Errorf("%s is checked out at %s, not %s", filepath.Dir(s.Config), head, s.SHA)
+	}
+
+	return nil
+}
+
+// UserVariables returns the step's variables as user variables for the
+// project with the given config. Variables that have a list of possible
+// values in any module act as filters, as they do on the command line.
+func (s *ReleaseStep) UserVariables(config *conf.Project) *UserVariables {
+	filters := map[string]bool{}
+	for _, module := range config.Modules {
+		for _, variable := range module.Variables {
+			if _, ok := s.Variables[variable.Name]; ok && variable.IsFilter() {
+				filters[variable.Name] = true
+			}
+		}
+	}
+
+	values := map[string]string{}
+	for name, value := range s.Variables {
+		values[name] = value
+	}
+
+	return &UserVariables{
+		Values:  values,
+		Filters: filters,
+	}
+}
@@ -0,0 +1,59 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro_test
+
+import (
+	"testing"
+
+	"github.com/uber/astro/astro"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadReleaseTrain(t *testing.T) {
+	t.Parallel()
+
+	train, err := astro.LoadReleaseTrain("fixtures/test-release/release.yaml")
+	require.NoError(t, err)
+	require.Len(t, train.Steps, 2)
+
+	network := train.Steps[0]
+	assert.Equal(t, "network", network.Name)
+	assert.Equal(t, "fixtures/test-plan-success/astro.yaml", network.Config)
+	assert.Equal(t, []string{"network"}, network.Modules)
+	assert.False(t, network.Checkpoint)
+
+	// steps are named after their config by default
+	assert.Equal(t, "fixtures/test-plan-success/astro.yaml", train.Steps[1].Name)
+	assert.True(t, train.Steps[1].Checkpoint)
+
+	// environment has a list of values, so it acts as a filter
+	config, err := astro.NewConfigFromFile(network.Config)
+	require.NoError(t, err)
+	assert.Equal(t, &astro.UserVariables{
+		Values:  map[string]string{"aws_region": "east1", "environment": "dev"},
+		Filters: map[string]bool{"environment": true},
+	}, network.UserVariables(config))
+}
+
+func TestLoadReleaseTrainMissingConfig(t *testing.T) {
+	t.Parallel()
+
+	_, err := astro.LoadReleaseTrain("fixtures/test-release/missing.yaml")
+	assert.Error(t, err)
+}
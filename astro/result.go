@@ -16,13 +16,27 @@
 
 package astro
 
-import "github.com/uber/astro/astro/terraform"
+import (
+	"path/filepath"
+
+	"github.com/uber/astro/astro/cost"
+	"github.com/uber/astro/astro/risk"
+	"github.com/uber/astro/astro/terraform"
+)
 
 // Result is what is returned from astro execution.
 type Result struct {
 	id              string
 	terraformResult terraform.Result
 	err             error
+	subResults      []*Result
+	invocations     []terraform.Invocation
+	warnings        []string
+	skipReason      string
+	guidance        string
+	moduleDir       string
+	cost            *cost.Estimate
+	risk            *risk.Assessment
 }
 
 // ID is a unique name that identifies the execution that run.
@@ -36,7 +50,153 @@ func (r *Result) TerraformResult() terraform.Result {
 	return r.terraformResult
 }
 
+// OutputPath returns the path to the log of the Terraform command, which
+// has all of its output, or "" if there wasn't one. Large output is not
+// kept in memory, so this is the way to read all of it.
+func (r *Result) OutputPath() string {
+	if r.terraformResult == nil {
+		return ""
+	}
+	return r.terraformResult.OutputPath()
+}
+
 // Err returns the error of the execution, if there was one.
 func (r *Result) Err() error {
 	return r.err
 }
+
+// Invocations returns all of the Terraform commands that were run for the
+// execution, so that they can be reproduced by hand.
+func (r *Result) Invocations() []terraform.Invocation {
+	return r.invocations
+}
+
+// SkipReason returns why a PreModuleRun hook vetoed the execution, or ""
+// if it ran. Skipped executions have no error.
+func (r *Result) SkipReason() string {
+	return r.skipReason
+}
+
+// Guidance returns what to do about the failure of the execution, from
+// the docs and on_error_message of its module, or "" if there is none or
+// it didn't fail.
+func (r *Result) Guidance() string {
+	return r.guidance
+}
+
+// ModuleDir returns the directory of the Terraform code of the module of
+// the execution, if it failed, so that the failure can be reported
+// against it.
+func (r *Result) ModuleDir() string {
+	return r.moduleDir
+}
+
+// ChangeCounts returns the number of resources the plan of the execution
+// will add, change and destroy, which are zero if it didn't plan. The
+// plans of workspaces are counted by the sub-results.
+func (r *Result) ChangeCounts() terraform.ChangeCounts {
+	if planResult, ok := r.terraformResult.(*terraform.PlanResult); ok && planResult != nil {
+		return planResult.ChangeCounts()
+	}
+	return terraform.ChangeCounts{}
+}
+
+// ResourceChanges returns the resources the plan of the execution will
+// add, change or destroy, if it planned.
+func (r *Result) ResourceChanges() []terraform.ResourceChange {
+	if planResult, ok := r.terraformResult.(*terraform.PlanResult); ok && planResult != nil {
+		return planResult.ResourceChanges()
+	}
+	return nil
+}
+
+// Cost returns the estimated cost of the plan of the execution, or nil if
+// it wasn't estimated.
+func (r *Result) Cost() *cost.Estimate {
+	return r.cost
+}
+
+// Risk returns the risk of the changes in the plan of the execution, or
+// nil if it wasn't assessed.
+func (r *Result) Risk() *risk.Assessment {
+	return r.risk
+}
+
+// Warnings returns problems that did not stop the execution, but that the
+// user should know about.
+func (r *Result) Warnings() []string {
+	return r.warnings
+}
+
+// SubResults returns the results of each workspace, for executions of
+// modules that plan multiple workspaces.
+func (r *Result) SubResults() []*Result {
+	return r.subResults
+}
+
+// newResults returns a channel for the results of n executions. Every
+// execution sends exactly one result, so sending to it never blocks, even
+// if the consumer doesn't read from it.
+func newResults(n int) chan *Result {
+	return make(chan *Result, n)
+}
+
+// passResults passes on the results, calling pass with each of them
+// first, and calls finish, if it is set, once they have all been passed
+// on, before closing the returned channel. Like results, sending to it
+// never blocks.
+func passResults(results <-chan *Result, pass func(*Result), finish func()) <-chan *Result {
+	passed := newResults(cap(results))
+
+	go func() {
+		defer close(passed)
+
+		for result := range results {
+			pass(result)
+			passed <- result
+		}
+		if finish != nil {
+			finish()
+		}
+	}()
+
+	return passed
+}
+
+// addGuidance adds the guidance and directory of their module to the
+// results of the executions that failed, and passes them on.
+func addGuidance(boundExecutions []*boundExecution, results <-chan *Result) <-chan *Result {
+	guidance := map[string]string{}
+	moduleDirs := map[string]string{}
+	for _, b := range boundExecutions {
+		moduleConfig := b.ModuleConfig()
+		guidance[b.ID()] = moduleConfig.ErrorGuidance()
+		moduleDirs[b.ID()] = filepath.Join(moduleConfig.TerraformCodeRoot, moduleConfig.Path)
+	}
+
+	return passResults(results, func(result *Result) {
+		if result.err != nil {
+			result.guidance = guidance[result.id]
+			result.moduleDir = moduleDirs[result.id]
+		}
+	}, nil)
+}
+
+// Collect blocks until all executions have finished and returns their
+// results. Status updates are discarded. It is a convenience for library
+// users that don't need to display progress.
+func Collect(status <-chan string, results <-chan *Result) []*Result {
+	if status != nil {
+		go func() {
+			for range status {
+			}
+		}()
+	}
+
+	var collected []*Result
+	for result := range results {
+		collected = append(collected, result)
+	}
+
+	return collected
+}
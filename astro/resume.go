@@ -0,0 +1,163 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/uber/astro/astro/logger"
+)
+
+// applyRecordFile is the name of the file in the session directory that
+// records the executions an apply ran and how they finished, so that it
+// can be resumed.
+const applyRecordFile = "apply.json"
+
+// Statuses of the executions in an apply record.
+const (
+	applyStatusOK      = "ok"
+	applyStatusFailed  = "failed"
+	applyStatusSkipped = "skipped"
+)
+
+// alreadyAppliedReason is the skip reason of the executions that are not
+// run again when an apply is resumed.
+const alreadyAppliedReason = "already applied in this session"
+
+// applyRecord records the executions of an apply in a session, and the
+// parameters they were bound with.
+type applyRecord struct {
+	// UserVars and Filters are the user variables the executions were
+	// bound with.
+	UserVars map[string]string `json:"user_vars"`
+	Filters  map[string]bool   `json:"filters"`
+	// TerraformParameters and Targets are passed to Terraform.
+	TerraformParameters []string `json:"terraform_parameters"`
+	Targets             []string `json:"targets"`
+	// Executions is the IDs of the executions of the apply.
+	Executions []string `json:"executions"`
+	// Results is how each execution that finished did, by ID: "ok",
+	// "failed" or "skipped". Executions that are missing didn't run, e.g.
+	// because an execution they depend on failed.
+	Results map[string]string `json:"results"`
+}
+
+// userVariables returns the user variables the executions were bound
+// with.
+func (r *applyRecord) userVariables() *UserVariables {
+	return &UserVariables{
+		Values:  r.UserVars,
+		Filters: r.Filters,
+	}
+}
+
+// newApplyRecord returns a record of an apply of the executions.
+func newApplyRecord(parameters ApplyExecutionParameters, boundExecutions []*boundExecution) *applyRecord {
+	record := &applyRecord{
+		TerraformParameters: parameters.TerraformParameters,
+		Targets:             parameters.Targets,
+		Executions:          []string{},
+		Results:             map[string]string{},
+	}
+	if parameters.UserVars != nil {
+		record.UserVars = parameters.UserVars.Values
+		record.Filters = parameters.UserVars.Filters
+	}
+	for _, b := range boundExecutions {
+		record.Executions = append(record.Executions, b.ID())
+	}
+	return record
+}
+
+// writeApplyRecord writes the record of the apply to the session
+// directory.
+func (session *Session) writeApplyRecord(record *applyRecord) error {
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(session.path, applyRecordFile), data, 0644)
+}
+
+// readApplyRecord reads the record of the last apply from the session
+// directory.
+func (session *Session) readApplyRecord() (*applyRecord, error) {
+	data, err := os.ReadFile(filepath.Join(session.path, applyRecordFile))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("session %v has no apply to resume", session.id)
+	} else if err != nil {
+		return nil, err
+	}
+
+	record := &applyRecord{}
+	if err := json.Unmarshal(data, record); err != nil {
+		return nil, fmt.Errorf("unable to read the apply record of session %v: %v", session.id, err)
+	}
+	if record.Results == nil {
+		record.Results = map[string]string{}
+	}
+
+	return record, nil
+}
+
+// applied returns the IDs of the executions that were applied
+// successfully.
+func (r *applyRecord) applied() map[string]bool {
+	applied := map[string]bool{}
+	for id, status := range r.Results {
+		if status == applyStatusOK {
+			applied[id] = true
+		}
+	}
+	return applied
+}
+
+// alreadyApplied returns whether the execution was applied successfully
+// before the apply was resumed, in which case it doesn't run again.
+func (session *Session) alreadyApplied(b *boundExecution) bool {
+	return session.resumedApplied[b.ID()]
+}
+
+// recordApplyResults records how every execution finished in the apply
+// record as its result arrives, and passes the results on. Executions
+// that were already applied stay recorded as such.
+func (session *Session) recordApplyResults(record *applyRecord, results <-chan *Result) <-chan *Result {
+	if err := session.writeApplyRecord(record); err != nil {
+		logger.Warn("unable to record apply", logger.Fields{"error": err})
+	}
+
+	return passResults(results, func(result *Result) {
+		if record.Results[result.ID()] == applyStatusOK {
+			return
+		}
+		switch {
+		case result.Err() != nil:
+			record.Results[result.ID()] = applyStatusFailed
+		case result.SkipReason() != "":
+			record.Results[result.ID()] = applyStatusSkipped
+		default:
+			record.Results[result.ID()] = applyStatusOK
+		}
+		if err := session.writeApplyRecord(record); err != nil {
+			logger.Warn("unable to record apply", logger.Fields{"error": err})
+		}
+	}, nil)
+}
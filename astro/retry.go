@@ -0,0 +1,73 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"errors"
+	"time"
+
+	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/terraform"
+)
+
+// retry runs a Terraform command for an execution, timed against its
+// warn_after and kill_after. If the project has a retry policy, the
+// command is run again while it fails with a retryable error, waiting
+// longer before each attempt. Commands that fail because the state is
+// locked are retried too if the policy sets state_locks.
+func (session *Session) retry(b *boundExecution, status *statusQueue, command func() (terraform.Result, error)) (terraform.Result, error) {
+	result, err := session.watch(b, status, command)
+
+	policy := session.repo.project.config.Retry
+	if policy == nil {
+		return result, err
+	}
+
+	// an execution that was killed is not retried
+	ctx := session.executionRun(b.ID()).ctx
+
+	for attempt := 1; err != nil && ctx.Err() == nil && attempt < policy.Attempts(); attempt++ {
+		output := err.Error()
+		if result != nil {
+			output = result.Stderr() + "\n" + output
+		}
+		var lockErr *terraform.StateLockedError
+		locked := policy.StateLocks && errors.As(err, &lockErr)
+		if !locked && !policy.Retryable(output) {
+			break
+		}
+
+		delay := policy.Delay(attempt)
+		if locked {
+			logger.Warn("retrying while the state is locked", logger.Fields{"id": b.ID(), "attempt": attempt + 1, "delay": delay, "lock": lockErr.Holder()})
+			status.send(b.ID(), "Retrying in %v, as %s (attempt %d of %d)...", delay, lockErr.Holder(), attempt+1, policy.Attempts())
+		} else {
+			logger.Warn("retrying after transient error", logger.Fields{"id": b.ID(), "attempt": attempt + 1, "delay": delay, "error": err})
+			status.send(b.ID(), "Retrying in %v (attempt %d of %d)...", delay, attempt+1, policy.Attempts())
+		}
+
+		select {
+		case <-ctx.Done():
+			return result, err
+		case <-time.After(delay):
+		}
+
+		result, err = session.watch(b, status, command)
+	}
+
+	return result, err
+}
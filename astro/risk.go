@@ -0,0 +1,136 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/uber/astro/astro/risk"
+	"github.com/uber/astro/astro/terraform"
+)
+
+// RiskError is the error of an execution that was not applied because the
+// risk score of its plan is above the approve_above threshold, and it was
+// not approved, or above the block_above threshold.
+type RiskError struct {
+	Assessment *risk.Assessment
+	// Threshold is the threshold the score is above, and Blocked is set
+	// if it is block_above, in which case approving doesn't help.
+	Threshold int
+	Blocked   bool
+}
+
+// Error is the error message, so this satisfies the error interface.
+func (e *RiskError) Error() string {
+	if e.Blocked {
+		return fmt.Sprintf("risk score %d is above block_above %d; not applied", e.Assessment.Score, e.Threshold)
+	}
+	return fmt.Sprintf("risk score %d is above approve_above %d and needs approval, e.g. with --approve-risk; not applied", e.Assessment.Score, e.Threshold)
+}
+
+// assessRisk scores the risk of the plan of the execution.
+func (session *Session) assessRisk(b *boundExecution, planJSON func() (string, error), status *statusQueue) (*risk.Assessment, error) {
+	status.send(b.ID(), "Assessing risk...")
+
+	path, err := planJSON()
+	if err != nil {
+		return nil, fmt.Errorf("unable to assess risk: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to assess risk: %v", err)
+	}
+	assessment, err := risk.Assess(data, session.repo.project.config.Risk.SensitiveResourcePatterns())
+	if err != nil {
+		return nil, fmt.Errorf("unable to assess risk: %v", err)
+	}
+	return assessment, nil
+}
+
+// checkRisk returns a *RiskError if the execution may not be applied
+// because of the risk of its plan.
+func (session *Session) checkRisk(assessment *risk.Assessment) error {
+	config := session.repo.project.config.Risk
+	if config.BlockAbove > 0 && assessment.Score > config.BlockAbove {
+		return &RiskError{Assessment: assessment, Threshold: config.BlockAbove, Blocked: true}
+	}
+	if config.ApproveAbove > 0 && assessment.Score > config.ApproveAbove && !session.approveRisk {
+		return &RiskError{Assessment: assessment, Threshold: config.ApproveAbove}
+	}
+	return nil
+}
+
+// gateRisk assesses the risk of the plan of the execution, which must have
+// been saved in its Terraform session, and returns a *RiskError if it may
+// not be applied.
+func (session *Session) gateRisk(b *boundExecution, terraform *terraform.Session, status *statusQueue) (*risk.Assessment, error) {
+	assessment, err := session.assessRisk(b, session.planJSON(b, terraform), status)
+	if err != nil {
+		return nil, err
+	}
+	return assessment, session.checkRisk(assessment)
+}
+
+// applyExecution applies the execution. If applies are gated on risk, it
+// is planned first, and the plan is only applied if its risk allows it,
+// so that exactly what was assessed is applied.
+func (session *Session) applyExecution(b *boundExecution, terraform *terraform.Session, status *statusQueue) *Result {
+	if !session.repo.project.config.Risk.Gates() {
+		status.send(b.ID(), "Applying...")
+		result, err := session.retry(b, status, terraform.Apply)
+		return &Result{
+			id:              b.ID(),
+			terraformResult: result,
+			invocations:     terraform.Invocations(),
+			err:             err,
+		}
+	}
+
+	status.send(b.ID(), "Planning...")
+	result, err := session.retry(b, status, terraform.Plan)
+	if err != nil {
+		return &Result{
+			id:              b.ID(),
+			terraformResult: result,
+			invocations:     terraform.Invocations(),
+			err:             err,
+		}
+	}
+
+	assessment, err := session.gateRisk(b, terraform, status)
+	if err != nil {
+		return &Result{
+			id:              b.ID(),
+			terraformResult: result,
+			invocations:     terraform.Invocations(),
+			risk:            assessment,
+			err:             err,
+		}
+	}
+
+	// A partial apply makes the plan stale, so it is not retried
+	status.send(b.ID(), "Applying...")
+	result, err = terraform.ApplyPlan()
+	return &Result{
+		id:              b.ID(),
+		terraformResult: result,
+		invocations:     terraform.Invocations(),
+		risk:            assessment,
+		err:             err,
+	}
+}
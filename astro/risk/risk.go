@@ -0,0 +1,149 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package risk scores how risky the changes in Terraform plans are, from
+// the plans in JSON: destroying and replacing resources, and changing
+// resources that control access and networking, are riskier than adding
+// and updating others.
+package risk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// How much each change adds to the score. A sensitive resource adds
+// SensitiveWeight on top of the weight of its change.
+const (
+	CreateWeight    = 1
+	UpdateWeight    = 2
+	ReplaceWeight   = 10
+	DestroyWeight   = 10
+	SensitiveWeight = 5
+)
+
+// Assessment is the risk of the changes of a plan.
+type Assessment struct {
+	Score     int `json:"score"`
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Replaced  int `json:"replaced"`
+	Destroyed int `json:"destroyed"`
+	// Sensitive are the addresses of the sensitive resources that change.
+	Sensitive []string `json:"sensitive,omitempty"`
+}
+
+// String summarizes the assessment, e.g.
+// "risk 27: 1 destroyed, 1 replaced, 2 sensitive".
+func (a *Assessment) String() string {
+	var parts []string
+	for _, count := range []struct {
+		n    int
+		name string
+	}{
+		{a.Destroyed, "destroyed"},
+		{a.Replaced, "replaced"},
+		{len(a.Sensitive), "sensitive"},
+	} {
+		if count.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", count.n, count.name))
+		}
+	}
+	if len(parts) == 0 {
+		return fmt.Sprintf("risk %d", a.Score)
+	}
+	return fmt.Sprintf("risk %d: %s", a.Score, strings.Join(parts, ", "))
+}
+
+// plan is the part of the machine-readable plan, printed by
+// `terraform show -json`, that describes the resource changes.
+type plan struct {
+	FormatVersion   string `json:"format_version"`
+	ResourceChanges []struct {
+		Address string `json:"address"`
+		Type    string `json:"type"`
+		Change  struct {
+			Actions []string `json:"actions"`
+		} `json:"change"`
+	} `json:"resource_changes"`
+}
+
+// Assess scores the changes of the plan in JSON. Resources whose type
+// matches one of the glob patterns of sensitiveTypes are sensitive.
+func Assess(planJSON []byte, sensitiveTypes []string) (*Assessment, error) {
+	var p plan
+	if err := json.Unmarshal(planJSON, &p); err != nil {
+		return nil, err
+	}
+	if p.FormatVersion == "" {
+		return nil, errors.New("missing format_version")
+	}
+
+	assessment := &Assessment{}
+	for _, resource := range p.ResourceChanges {
+		weight := actionsWeight(resource.Change.Actions, assessment)
+		if weight == 0 {
+			continue
+		}
+		if isSensitive(resource.Type, sensitiveTypes) {
+			assessment.Sensitive = append(assessment.Sensitive, resource.Address)
+			weight += SensitiveWeight
+		}
+		assessment.Score += weight
+	}
+
+	return assessment, nil
+}
+
+// actionsWeight returns the weight of the actions of a resource change,
+// and counts it in the assessment. No-ops and reads weigh nothing.
+func actionsWeight(actions []string, assessment *Assessment) int {
+	has := map[string]bool{}
+	for _, action := range actions {
+		has[action] = true
+	}
+
+	switch {
+	case has["create"] && has["delete"]:
+		assessment.Replaced++
+		return ReplaceWeight
+	case has["delete"]:
+		assessment.Destroyed++
+		return DestroyWeight
+	case has["update"]:
+		assessment.Updated++
+		return UpdateWeight
+	case has["create"]:
+		assessment.Created++
+		return CreateWeight
+	}
+	return 0
+}
+
+// isSensitive returns whether the resource type matches one of the
+// patterns.
+func isSensitive(resourceType string, patterns []string) bool {
+	for _, pattern := range patterns {
+		// bad patterns never match
+		if matched, _ := path.Match(pattern, resourceType); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package risk_test
+
+import (
+	"testing"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/risk"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPlan = `{
+  "format_version": "1.1",
+  "resource_changes": [
+    {"address": "null_resource.a", "type": "null_resource", "change": {"actions": ["create"]}},
+    {"address": "null_resource.b", "type": "null_resource", "change": {"actions": ["update"]}},
+    {"address": "null_resource.c", "type": "null_resource", "change": {"actions": ["no-op"]}},
+    {"address": "aws_instance.web", "type": "aws_instance", "change": {"actions": ["delete", "create"]}},
+    {"address": "aws_s3_bucket.logs", "type": "aws_s3_bucket", "change": {"actions": ["delete"]}},
+    {"address": "aws_iam_role.app", "type": "aws_iam_role", "change": {"actions": ["update"]}},
+    {"address": "data.aws_vpc.main", "type": "aws_vpc", "change": {"actions": ["read"]}}
+  ]
+}`
+
+func TestAssess(t *testing.T) {
+	assessment, err := risk.Assess([]byte(testPlan), conf.DefaultSensitiveResources)
+	require.NoError(t, err)
+
+	assert.Equal(t, &risk.Assessment{
+		Score:     1 + 2 + 10 + 10 + 2 + 5,
+		Created:   1,
+		Updated:   2,
+		Replaced:  1,
+		Destroyed: 1,
+		Sensitive: []string{"aws_iam_role.app"},
+	}, assessment)
+	assert.Equal(t, "risk 30: 1 destroyed, 1 replaced, 1 sensitive", assessment.String())
+}
+
+func TestAssessSensitiveResources(t *testing.T) {
+	assessment, err := risk.Assess([]byte(testPlan), []string{"aws_s3_*"})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1+2+10+10+5+2, assessment.Score)
+	assert.Equal(t, []string{"aws_s3_bucket.logs"}, assessment.Sensitive)
+}
+
+func TestAssessNoChanges(t *testing.T) {
+	assessment, err := risk.Assess([]byte(`{"format_version": "1.1"}`), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "risk 0", assessment.String())
+
+	_, err = risk.Assess([]byte(`{}`), nil)
+	assert.EqualError(t, err, "missing format_version")
+}
@@ -0,0 +1,98 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanAssessesRisk(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-risk/astro.yaml")
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Plan(PlanExecutionParameters{
+		ExecutionParameters: NoExecutionParameters(),
+	})
+	require.NoError(t, err)
+
+	results := testReadResults(resultChan)
+	for id, score := range map[string]int{"low": 1, "medium": 10, "high": 15} {
+		// plans are never blocked by their risk
+		require.NoError(t, results[id].Err())
+		require.NotNil(t, results[id].Risk())
+		assert.Equal(t, score, results[id].Risk().Score)
+	}
+	assert.Equal(t, []string{"aws_security_group.a"}, results["high"].Risk().Sensitive)
+}
+
+func TestApplyGatedOnRisk(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-risk/astro.yaml")
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Apply(ApplyExecutionParameters{
+		ExecutionParameters: NoExecutionParameters(),
+	})
+	require.NoError(t, err)
+
+	results := testReadResults(resultChan)
+
+	require.NoError(t, results["low"].Err())
+	assert.Equal(t, 1, results["low"].Risk().Score)
+	assert.Len(t, testInvocations(results["low"], "apply"), 1)
+
+	var riskErr *RiskError
+	require.True(t, errors.As(results["medium"].Err(), &riskErr))
+	assert.False(t, riskErr.Blocked)
+	assert.Equal(t, 5, riskErr.Threshold)
+	assert.EqualError(t, riskErr, "risk score 10 is above approve_above 5 and needs approval, e.g. with --approve-risk; not applied")
+	assert.Empty(t, testInvocations(results["medium"], "apply"))
+
+	require.True(t, errors.As(results["high"].Err(), &riskErr))
+	assert.True(t, riskErr.Blocked)
+	assert.EqualError(t, riskErr, "risk score 15 is above block_above 12; not applied")
+	assert.Empty(t, testInvocations(results["high"], "apply"))
+}
+
+func TestApplyApprovedRisk(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-risk/astro.yaml")
+	require.NoError(t, err)
+
+	_, resultChan, err := c.Apply(ApplyExecutionParameters{
+		ExecutionParameters: NoExecutionParameters(),
+		ApproveRisk:         true,
+	})
+	require.NoError(t, err)
+
+	results := testReadResults(resultChan)
+	require.NoError(t, results["low"].Err())
+	require.NoError(t, results["medium"].Err())
+
+	// approving doesn't apply plans above block_above
+	var riskErr *RiskError
+	require.True(t, errors.As(results["high"].Err(), &riskErr))
+	assert.True(t, riskErr.Blocked)
+}
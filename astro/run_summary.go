@@ -0,0 +1,127 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"sort"
+	"time"
+
+	"github.com/uber/astro/astro/terraform"
+)
+
+// slowestExecutionsCount is the number of executions listed in
+// RunSummary.Slowest.
+const slowestExecutionsCount = 5
+
+// RunSummary recaps the results of a run, e.g. a plan or an apply.
+type RunSummary struct {
+	// Executions is the number of executions that reported a result, of
+	// which OK succeeded without changes, Changed succeeded with a plan
+	// that has changes, Failed failed and Skipped were skipped.
+	Executions int
+	OK         int
+	Changed    int
+	Failed     int
+	Skipped    int
+	// Slowest is the executions whose Terraform command took the longest,
+	// slowest first, including the workspaces of executions that plan
+	// several.
+	Slowest []ExecutionDuration
+	// WallTime is how long the run took, from when it started to when
+	// its last result arrived.
+	WallTime time.Duration
+}
+
+// ExecutionDuration is how long the Terraform command of an execution
+// took.
+type ExecutionDuration struct {
+	ID       string
+	Duration time.Duration
+}
+
+// Summarize returns the summary of the results of a run that took
+// wallTime.
+func Summarize(results []*Result, wallTime time.Duration) RunSummary {
+	summary := RunSummary{WallTime: wallTime}
+
+	for _, result := range results {
+		summary.Executions++
+		switch {
+		case result.Err() != nil:
+			summary.Failed++
+		case result.SkipReason() != "":
+			summary.Skipped++
+		case result.hasChanges():
+			summary.Changed++
+		default:
+			summary.OK++
+		}
+
+		for _, r := range append([]*Result{result}, result.SubResults()...) {
+			if r.TerraformResult() == nil {
+				continue
+			}
+			// the runtime is only reported to the second
+			duration, _ := time.ParseDuration(r.TerraformResult().Runtime())
+			summary.Slowest = append(summary.Slowest, ExecutionDuration{ID: r.ID(), Duration: duration})
+		}
+	}
+
+	sort.SliceStable(summary.Slowest, func(i, j int) bool {
+		return summary.Slowest[i].Duration > summary.Slowest[j].Duration
+	})
+	if len(summary.Slowest) > slowestExecutionsCount {
+		summary.Slowest = summary.Slowest[:slowestExecutionsCount]
+	}
+
+	return summary
+}
+
+// SummarizeResults passes on the results of a run, e.g. from Plan or
+// Apply, and sends the summary of the run once they have all arrived.
+// The run is timed from when this is called, so it should be called right
+// after starting it.
+func (c *Project) SummarizeResults(results <-chan *Result) (<-chan *Result, <-chan RunSummary) {
+	started := c.clock.Now()
+
+	// There is one summary, so sending it never blocks
+	summary := make(chan RunSummary, 1)
+
+	var collected []*Result
+	passed := passResults(results, func(result *Result) {
+		collected = append(collected, result)
+	}, func() {
+		summary <- Summarize(collected, c.clock.Now().Sub(started))
+		close(summary)
+	})
+
+	return passed, summary
+}
+
+// hasChanges returns whether the result is of a plan with changes, or has
+// sub-results that are.
+func (r *Result) hasChanges() bool {
+	if planResult, ok := r.terraformResult.(*terraform.PlanResult); ok && planResult != nil && planResult.HasChanges() {
+		return true
+	}
+	for _, subResult := range r.subResults {
+		if subResult.hasChanges() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarize(t *testing.T) {
+	summary := Summarize([]*Result{
+		{id: "app-dev"},
+		{id: "app-prod", err: errors.New("failed")},
+		{id: "db-dev", skipReason: "vetoed"},
+		{id: "db-prod"},
+	}, 90*time.Second)
+
+	assert.Equal(t, RunSummary{
+		Executions: 4,
+		OK:         2,
+		Failed:     1,
+		Skipped:    1,
+		WallTime:   90 * time.Second,
+	}, summary)
+}
+
+func TestSummarizeResults(t *testing.T) {
+	now := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	c := &Project{clock: testClock{now: now}}
+
+	results := make(chan *Result, 2)
+	results <- &Result{id: "app-dev"}
+	results <- &Result{id: "app-prod", err: errors.New("failed")}
+	close(results)
+
+	passed, summaryChan := c.SummarizeResults(results)
+
+	var ids []string
+	for result := range passed {
+		ids = append(ids, result.ID())
+	}
+	assert.Equal(t, []string{"app-dev", "app-prod"}, ids)
+
+	summary, ok := <-summaryChan
+	require.True(t, ok)
+	assert.Equal(t, 2, summary.Executions)
+	assert.Equal(t, 1, summary.OK)
+	assert.Equal(t, 1, summary.Failed)
+}
+
+func TestSummarizePlan(t *testing.T) {
+	c, err := NewProjectFromConfigFile("fixtures/test-plan-success/astro.yaml")
+	require.NoError(t, err)
+
+	c.config.TerraformDefaults.Path = absolutePath("fixtures/mock-terraform/success")
+
+	_, results, err := c.Plan(PlanExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			UserVars: &UserVariables{
+				Values: map[string]string{
+					"aws_region": "east1",
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	passed, summaryChan := c.SummarizeResults(results)
+	collected := testReadResults(passed)
+	summary := <-summaryChan
+
+	assert.Equal(t, len(collected), summary.Executions)
+	assert.Equal(t, summary.Executions, summary.OK+summary.Changed+summary.Failed+summary.Skipped)
+	assert.NotEmpty(t, summary.Slowest)
+}
@@ -0,0 +1,262 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/risk"
+	"github.com/uber/astro/astro/terraform"
+)
+
+// savedPlansFile is the name of the file in the session directory that
+// records which plans were saved, so that they can be applied later.
+const savedPlansFile = "plans.json"
+
+// stateSerialFile is the name of the file in the directory of an
+// execution that records the serial of the state its plan was made
+// against.
+const stateSerialFile = "state-serial"
+
+// savedPlans records the plans saved in a session, and the parameters
+// they were planned with.
+type savedPlans struct {
+	// UserVars and Filters are the user variables the plans were made
+	// with.
+	UserVars map[string]string `json:"user_vars"`
+	Filters  map[string]bool   `json:"filters"`
+	// ModuleNames, ExecutionIDs, ExecutionPatterns and Filter are the
+	// filters the plans were made with, if any.
+	ModuleNames       []string `json:"module_names"`
+	ExecutionIDs      []string `json:"execution_ids"`
+	ExecutionPatterns []string `json:"execution_patterns"`
+	Filter            string   `json:"filter"`
+	// Targets are the resource targets the plans were made with. They
+	// are only used if an execution is planned again.
+	Targets []string `json:"targets"`
+	// Executions is the IDs of the executions that were planned. Plans
+	// that failed have no plan file, so they cannot be applied.
+	Executions []string `json:"executions"`
+	// PlannedAt is when the plans were made.
+	PlannedAt time.Time `json:"planned_at"`
+	// SourceHashes are the hashes of the sources of the planned modules,
+	// by module name.
+	SourceHashes map[string]string `json:"source_hashes"`
+}
+
+// stalePlans detects saved plans that can no longer be applied as they
+// were reviewed, and holds the policy for applying them.
+type stalePlans struct {
+	policy string
+	// reasons are why the plans of each module are stale, by module name,
+	// for the checks that don't depend on the state.
+	reasons map[string]string
+}
+
+// userVariables returns the user variables the plans were made with.
+func (p *savedPlans) userVariables() *UserVariables {
+	return &UserVariables{
+		Values:  p.UserVars,
+		Filters: p.Filters,
+	}
+}
+
+// writeSavedPlans writes the record of saved plans to the session
+// directory.
+func (session *Session) writeSavedPlans(plans *savedPlans) error {
+	data, err := json.MarshalIndent(plans, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(session.path, savedPlansFile), data, 0644)
+}
+
+// readSavedPlans reads the record of saved plans from the session
+// directory.
+func (session *Session) readSavedPlans() (*savedPlans, error) {
+	data, err := os.ReadFile(filepath.Join(session.path, savedPlansFile))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no saved plans in session %v; run plan with --out first", session.id)
+	} else if err != nil {
+		return nil, err
+	}
+
+	plans := &savedPlans{}
+	if err := json.Unmarshal(data, plans); err != nil {
+		return nil, fmt.Errorf("unable to read saved plans: %v", err)
+	}
+
+	return plans, nil
+}
+
+// stalePlans checks whether the saved plans are too old, or whether the
+// sources of the modules changed since they were planned.
+func (c *Project) stalePlans(plans *savedPlans) (*stalePlans, error) {
+	stale := &stalePlans{
+		policy:  c.config.StalePlanPolicy,
+		reasons: map[string]string{},
+	}
+
+	var tooOld string
+	if c.config.StalePlanMaxAge != "" && !plans.PlannedAt.IsZero() {
+		maxAge, err := time.ParseDuration(c.config.StalePlanMaxAge)
+		if err != nil {
+			return nil, err
+		}
+		if age := c.clock.Now().Sub(plans.PlannedAt); age > maxAge {
+			tooOld = fmt.Sprintf("planned %v ago", age.Round(time.Minute))
+		}
+	}
+
+	for _, module := range c.modules(plans.ModuleNames) {
+		name := module.config.Name
+		if tooOld != "" {
+			stale.reasons[name] = tooOld
+			continue
+		}
+
+		expected, ok := plans.SourceHashes[name]
+		if !ok {
+			continue
+		}
+		actual, err := c.lockModule(*module.config)
+		if err != nil {
+			return nil, err
+		}
+		if actual.Hash != expected {
+			stale.reasons[name] = "module source changed"
+		}
+	}
+
+	return stale, nil
+}
+
+// sourceHashes returns the hashes of the sources of the specified modules,
+// to record with saved plans.
+func (c *Project) sourceHashes(moduleNames []string) (map[string]string, error) {
+	hashes := map[string]string{}
+	for _, module := range c.modules(moduleNames) {
+		lock, err := c.lockModule(*module.config)
+		if err != nil {
+			return nil, err
+		}
+		hashes[module.config.Name] = lock.Hash
+	}
+	return hashes, nil
+}
+
+// recordStateSerial records the serial of the state the execution's plan
+// was made against, so that apply can tell if the state changed since.
+// Nothing is recorded if the serial cannot be read, e.g. with old
+// versions of Terraform.
+func (session *Session) recordStateSerial(b *boundExecution, terraform *terraform.Session) {
+	serial, err := terraform.StateSerial()
+	if err != nil {
+		logger.Debug("not recording state serial", logger.Fields{"id": b.ID(), "error": err})
+		return
+	}
+
+	path := filepath.Join(session.path, b.ID(), stateSerialFile)
+	if err := os.WriteFile(path, []byte(strconv.FormatInt(serial, 10)+"\n"), 0644); err != nil {
+		logger.Warn("unable to record state serial", logger.Fields{"id": b.ID(), "error": err})
+	}
+}
+
+// stalePlanReason returns why the saved plan of the execution is stale,
+// or an empty string if it isn't.
+func (session *Session) stalePlanReason(b *boundExecution, terraform *terraform.Session) string {
+	if reason := session.stalePlans.reasons[b.ModuleConfig().Name]; reason != "" {
+		return reason
+	}
+
+	data, err := os.ReadFile(filepath.Join(session.path, b.ID(), stateSerialFile))
+	if err != nil {
+		return ""
+	}
+	planned, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return ""
+	}
+
+	serial, err := terraform.StateSerial()
+	if err != nil {
+		logger.Warn("unable to check state serial", logger.Fields{"id": b.ID(), "error": err})
+		return ""
+	}
+	if serial != planned {
+		return fmt.Sprintf("state changed since it was planned (serial %d, planned against %d)", serial, planned)
+	}
+
+	return ""
+}
+
+// applySavedPlan applies the saved plan of the execution, unless it is
+// stale and the stale plan policy says otherwise. It returns false if the
+// execution should be planned and applied again instead.
+func (session *Session) applySavedPlan(b *boundExecution, terraform *terraform.Session, status *statusQueue) (*Result, bool) {
+	var warnings []string
+
+	if reason := session.stalePlanReason(b, terraform); reason != "" {
+		switch session.stalePlans.policy {
+		case conf.StalePlanReplan:
+			status.send(b.ID(), "Saved plan is stale (%s), planning again...", reason)
+			return nil, false
+		case conf.StalePlanWarn:
+			warnings = append(warnings, fmt.Sprintf("saved plan is stale: %s", reason))
+		default:
+			return &Result{
+				id:          b.ID(),
+				invocations: terraform.Invocations(),
+				err:         fmt.Errorf("saved plan is stale: %s; plan again", reason),
+			}, true
+		}
+	}
+
+	var assessment *risk.Assessment
+	if session.repo.project.config.Risk.Gates() {
+		var err error
+		if assessment, err = session.gateRisk(b, terraform, status); err != nil {
+			return &Result{
+				id:          b.ID(),
+				invocations: terraform.Invocations(),
+				warnings:    warnings,
+				risk:        assessment,
+				err:         err,
+			}, true
+		}
+	}
+
+	status.send(b.ID(), "Applying saved plan...")
+	result, err := terraform.ApplyPlan()
+	return &Result{
+		id:              b.ID(),
+		terraformResult: result,
+		invocations:     terraform.Invocations(),
+		warnings:        warnings,
+		risk:            assessment,
+		err:             err,
+	}, true
+}
@@ -0,0 +1,274 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package scheduler decides when tasks that run at the same time may
+// start: at most a limit of them in total, and of each group they are
+// in, highest priority first. The limits can be changed, and scheduling
+// paused and resumed, while tasks run.
+package scheduler
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// Task describes a task to schedule.
+type Task struct {
+	// Priority orders the tasks that are waiting to start: tasks with a
+	// higher priority start first, and tasks with the same priority in
+	// the order they started waiting.
+	Priority int
+	// Groups are the groups whose limits the task counts against.
+	Groups []string
+}
+
+// waiter is a task that is waiting to start.
+type waiter struct {
+	task Task
+	// ready is closed when the task may start.
+	ready chan struct{}
+}
+
+// Scheduler schedules tasks. It is safe to use from multiple goroutines.
+type Scheduler struct {
+	mu sync.Mutex
+
+	limit   int
+	running int
+
+	groupLimits  map[string]int
+	groupRunning map[string]int
+
+	paused bool
+
+	// waiting is sorted by descending priority, and then by the order
+	// the tasks started waiting.
+	waiting []*waiter
+}
+
+// New returns a scheduler that starts at most limit tasks at the same
+// time. A limit below 1 is taken as 1.
+func New(limit int) *Scheduler {
+	return &Scheduler{
+		limit:        atLeastOne(limit),
+		groupLimits:  map[string]int{},
+		groupRunning: map[string]int{},
+	}
+}
+
+func atLeastOne(limit int) int {
+	if limit < 1 {
+		return 1
+	}
+	return limit
+}
+
+// Limit returns the maximum number of tasks that run at the same time.
+func (s *Scheduler) Limit() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.limit
+}
+
+// Resize changes the maximum number of tasks that run at the same time.
+// If it is lowered below the number of running tasks, they keep running,
+// and no task starts until enough of them have finished.
+func (s *Scheduler) Resize(limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limit = atLeastOne(limit)
+	s.dispatch()
+}
+
+// SetGroupLimit changes the maximum number of tasks in the group that run
+// at the same time. A limit below 1 removes the limit of the group.
+func (s *Scheduler) SetGroupLimit(group string, limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if limit < 1 {
+		delete(s.groupLimits, group)
+	} else {
+		s.groupLimits[group] = limit
+	}
+	s.dispatch()
+}
+
+// Pause stops tasks from starting until Resume is called. Running tasks
+// are not affected.
+func (s *Scheduler) Pause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = true
+}
+
+// Resume lets tasks start again after Pause.
+func (s *Scheduler) Resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = false
+	s.dispatch()
+}
+
+// Paused returns whether tasks are stopped from starting.
+func (s *Scheduler) Paused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paused
+}
+
+// Running returns the number of tasks that are running.
+func (s *Scheduler) Running() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running
+}
+
+// Waiting returns the number of tasks that are waiting to start.
+func (s *Scheduler) Waiting() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.waiting)
+}
+
+// Acquire blocks until the task may start, or ctx is done, in which case
+// the error of ctx is returned. If it succeeds, Release must be called
+// with the same task when it has finished.
+func (s *Scheduler) Acquire(ctx context.Context, task Task) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	w := &waiter{task: task, ready: make(chan struct{})}
+
+	s.mu.Lock()
+	i := sort.Search(len(s.waiting), func(i int) bool {
+		return s.waiting[i].task.Priority < task.Priority
+	})
+	s.waiting = append(s.waiting, nil)
+	copy(s.waiting[i+1:], s.waiting[i:])
+	s.waiting[i] = w
+	s.dispatch()
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		// it started as ctx was done
+		s.finish(task)
+		s.dispatch()
+	default:
+		for i := range s.waiting {
+			if s.waiting[i] == w {
+				s.waiting = append(s.waiting[:i], s.waiting[i+1:]...)
+				break
+			}
+		}
+	}
+
+	return ctx.Err()
+}
+
+// Release lets another task start once the task has finished.
+func (s *Scheduler) Release(task Task) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.finish(task)
+	s.dispatch()
+}
+
+// Run calls fn with the index of each task, as many at the same time as
+// the scheduler allows, and waits for them to finish. Tasks that have not
+// started when ctx is done are skipped.
+func (s *Scheduler) Run(ctx context.Context, tasks []Task, fn func(i int)) {
+	wg := sync.WaitGroup{}
+
+	for i := range tasks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := s.Acquire(ctx, tasks[i]); err != nil {
+				return
+			}
+			defer s.Release(tasks[i])
+			fn(i)
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+// fits returns whether the groups of the task have room for it.
+func (s *Scheduler) fits(task Task) bool {
+	for _, group := range task.Groups {
+		if limit, ok := s.groupLimits[group]; ok && s.groupRunning[group] >= limit {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Scheduler) start(task Task) {
+	s.running++
+	for _, group := range task.Groups {
+		s.groupRunning[group]++
+	}
+}
+
+func (s *Scheduler) finish(task Task) {
+	if s.running == 0 {
+		panic("scheduler: released more tasks than were acquired")
+	}
+	s.running--
+	for _, group := range task.Groups {
+		s.groupRunning[group]--
+		if s.groupRunning[group] == 0 {
+			delete(s.groupRunning, group)
+		}
+	}
+}
+
+// dispatch starts the waiting tasks that may start, in order. Tasks whose
+// groups are full are passed over, so that they don't hold up tasks of
+// other groups. It must be called with mu held.
+func (s *Scheduler) dispatch() {
+	if s.paused {
+		return
+	}
+
+	var waiting []*waiter
+	for i, w := range s.waiting {
+		if s.running >= s.limit {
+			waiting = append(waiting, s.waiting[i:]...)
+			break
+		}
+		if !s.fits(w.task) {
+			waiting = append(waiting, w)
+			continue
+		}
+		s.start(w.task)
+		close(w.ready)
+	}
+	s.waiting = waiting
+}
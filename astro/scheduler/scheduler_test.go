@@ -0,0 +1,215 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduler_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/uber/astro/astro/scheduler"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// acquire acquires the task in the background, once it is waiting, and
+// returns a channel that receives the result of Acquire.
+func acquire(t *testing.T, s *scheduler.Scheduler, ctx context.Context, task scheduler.Task) <-chan error {
+	waiting := s.Waiting()
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Acquire(ctx, task)
+	}()
+	for deadline := time.Now().Add(time.Second); s.Waiting() == waiting && len(done) == 0; {
+		require.True(t, time.Now().Before(deadline), "task did not start waiting")
+		time.Sleep(time.Millisecond)
+	}
+	return done
+}
+
+// started returns whether the acquire of a task has returned.
+func started(done <-chan error) bool {
+	select {
+	case err := <-done:
+		return err == nil
+	case <-time.After(20 * time.Millisecond):
+		return false
+	}
+}
+
+func TestRunLimits(t *testing.T) {
+	s := scheduler.New(3)
+	s.SetGroupLimit("database", 1)
+
+	var tasks []scheduler.Task
+	for i := 0; i < 4; i++ {
+		tasks = append(tasks, scheduler.Task{Groups: []string{"app"}}, scheduler.Task{Groups: []string{"database"}})
+	}
+
+	var mu sync.Mutex
+	running := map[string]int{}
+	maxRunning := map[string]int{}
+	total, maxTotal := 0, 0
+
+	s.Run(context.Background(), tasks, func(i int) {
+		group := tasks[i].Groups[0]
+
+		mu.Lock()
+		running[group]++
+		total++
+		if running[group] > maxRunning[group] {
+			maxRunning[group] = running[group]
+		}
+		if total > maxTotal {
+			maxTotal = total
+		}
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		running[group]--
+		total--
+		mu.Unlock()
+	})
+
+	assert.Equal(t, 3, maxTotal)
+	assert.Equal(t, 1, maxRunning["database"])
+	assert.Equal(t, 0, s.Running())
+}
+
+func TestPriority(t *testing.T) {
+	s := scheduler.New(1)
+	s.Pause()
+
+	var mu sync.Mutex
+	var order []string
+	wg := sync.WaitGroup{}
+	for _, task := range []struct {
+		name     string
+		priority int
+	}{
+		{"low", -1},
+		{"first", 0},
+		{"high", 10},
+		{"second", 0},
+	} {
+		name := task.name
+		done := acquire(t, s, context.Background(), scheduler.Task{Priority: task.priority})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, <-done)
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			s.Release(scheduler.Task{})
+		}()
+	}
+
+	s.Resume()
+	wg.Wait()
+
+	assert.Equal(t, []string{"high", "first", "second", "low"}, order)
+}
+
+func TestFullGroupsDoNotHoldUpOthers(t *testing.T) {
+	s := scheduler.New(2)
+	s.SetGroupLimit("database", 1)
+
+	database := scheduler.Task{Priority: 10, Groups: []string{"database"}}
+	require.NoError(t, s.Acquire(context.Background(), database))
+
+	waitingDatabase := acquire(t, s, context.Background(), database)
+	app := acquire(t, s, context.Background(), scheduler.Task{Groups: []string{"app"}})
+
+	assert.True(t, started(app))
+	assert.False(t, started(waitingDatabase))
+
+	s.Release(database)
+	assert.True(t, started(waitingDatabase))
+}
+
+func TestResize(t *testing.T) {
+	s := scheduler.New(1)
+	require.NoError(t, s.Acquire(context.Background(), scheduler.Task{}))
+
+	done := acquire(t, s, context.Background(), scheduler.Task{})
+	assert.False(t, started(done))
+
+	s.Resize(2)
+	assert.True(t, started(done))
+	assert.Equal(t, 2, s.Limit())
+
+	// running tasks keep running when the limit is lowered
+	s.Resize(1)
+	done = acquire(t, s, context.Background(), scheduler.Task{})
+	s.Release(scheduler.Task{})
+	assert.False(t, started(done))
+	s.Release(scheduler.Task{})
+	assert.True(t, started(done))
+}
+
+func TestGroupLimitRemoved(t *testing.T) {
+	s := scheduler.New(2)
+	s.SetGroupLimit("database", 1)
+
+	database := scheduler.Task{Groups: []string{"database"}}
+	require.NoError(t, s.Acquire(context.Background(), database))
+
+	done := acquire(t, s, context.Background(), database)
+	assert.False(t, started(done))
+
+	s.SetGroupLimit("database", 0)
+	assert.True(t, started(done))
+}
+
+func TestPauseResume(t *testing.T) {
+	s := scheduler.New(1)
+	s.Pause()
+	assert.True(t, s.Paused())
+
+	done := acquire(t, s, context.Background(), scheduler.Task{})
+	assert.False(t, started(done))
+
+	s.Resume()
+	assert.False(t, s.Paused())
+	assert.True(t, started(done))
+	assert.Equal(t, 1, s.Running())
+}
+
+func TestAcquireCancelled(t *testing.T) {
+	s := scheduler.New(1)
+	s.Pause()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := acquire(t, s, ctx, scheduler.Task{})
+	cancel()
+
+	assert.Equal(t, context.Canceled, <-done)
+	assert.Equal(t, 0, s.Waiting())
+
+	// tasks are not started once ctx is done
+	assert.Equal(t, context.Canceled, s.Acquire(ctx, scheduler.Task{}))
+
+	ran := false
+	s.Resume()
+	s.Run(ctx, []scheduler.Task{{}}, func(int) { ran = true })
+	assert.False(t, ran)
+}
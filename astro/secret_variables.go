@@ -0,0 +1,63 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+
+	"github.com/uber/astro/astro/logger"
+)
+
+// secretVariables splits the variables of the execution into those that
+// are passed to Terraform as they are, and the secret ones, which are
+// resolved. Secrets are cached in the session by their reference, so
+// that each is only fetched once however many executions use it.
+func (session *Session) secretVariables(execution *boundExecution) (variables map[string]string, secretValues map[string]string, err error) {
+	variables = map[string]string{}
+	for key, val := range execution.Variables() {
+		variables[key] = val
+	}
+
+	for _, variable := range execution.ModuleConfig().Variables {
+		value, ok := variables[variable.Name]
+		if !variable.Secret || !ok {
+			continue
+		}
+		delete(variables, variable.Name)
+		// optional secrets that are empty are left to Terraform's default
+		if value == "" {
+			continue
+		}
+
+		secret, ok := session.secrets.Load(value)
+		if !ok {
+			logger.Trace.Printf("astro: resolving secret variable %v of %v", variable.Name, execution.ID())
+			resolved, err := session.repo.project.secrets.Resolve(session.ctx, value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("variable %v: %v", variable.Name, err)
+			}
+			secret, _ = session.secrets.LoadOrStore(value, resolved)
+		}
+
+		if secretValues == nil {
+			secretValues = map[string]string{}
+		}
+		secretValues[variable.Name] = secret.(string)
+	}
+
+	return variables, secretValues, nil
+}
@@ -0,0 +1,103 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/uber/astro/astro/secrets"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretVariables(t *testing.T) {
+	t.Parallel()
+
+	config, err := configFromYAML([]byte(`
+session_repo_dir: `+t.TempDir()+`
+terraform:
+  path: fixtures/mock-terraform/success
+modules:
+  - name: app
+    path: .
+    variables:
+      - name: environment
+        values: [dev]
+      - name: db_password
+        secret: true
+      - name: api_token
+        secret: true
+        default: test:default-token
+`), "")
+	require.NoError(t, err)
+
+	resolved := 0
+	c, err := NewProject(WithConfig(*config), WithSecretResolver("test", secrets.ResolverFunc(func(_ context.Context, ref string) (string, error) {
+		if ref == "missing" {
+			return "", errors.New("no such secret")
+		}
+		resolved++
+		return "secret-" + ref, nil
+	})))
+	require.NoError(t, err)
+
+	boundExecutions, err := c.boundExecutions(ExecutionParameters{UserVars: &UserVariables{Values: map[string]string{"db_password": "test:db"}}})
+	require.NoError(t, err)
+	require.Len(t, boundExecutions, 1)
+	execution := boundExecutions[0]
+
+	// secrets are not part of the ID
+	assert.Equal(t, "app-dev", execution.ID())
+
+	session, err := c.sessions.Current()
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		tfConfig, _, err := session.terraformConfig(execution)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"environment": "dev"}, tfConfig.Variables)
+		assert.Equal(t, map[string]string{"db_password": "secret-db", "api_token": "secret-default-token"}, tfConfig.SecretVariables)
+	}
+	// secrets are resolved once per session
+	assert.Equal(t, 2, resolved)
+
+	boundExecutions, err = c.boundExecutions(ExecutionParameters{UserVars: &UserVariables{Values: map[string]string{"db_password": "test:missing"}}})
+	require.NoError(t, err)
+	_, _, err = session.terraformConfig(boundExecutions[0])
+	assert.EqualError(t, err, "variable db_password: unable to resolve secret test:missing: no such secret")
+}
+
+func TestSecretVariablesValidation(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewProjectFromYAML([]byte(`
+terraform:
+  path: fixtures/mock-terraform/success
+modules:
+  - name: app
+    path: .
+    variables:
+      - name: db_password
+        secret: true
+        pattern: "^.{12,}$"
+`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "secret variables cannot have values, allowed_values or pattern")
+}
@@ -0,0 +1,107 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/uber/astro/astro/logger"
+)
+
+// vault resolves path#field to a field of a Vault KV secret, with the
+// vault command line tool.
+type vault struct {
+	path string
+}
+
+// Resolve returns the field of the secret.
+func (v *vault) Resolve(ctx context.Context, ref string) (string, error) {
+	path, field := splitField(ref)
+	if path == "" || field == "" {
+		return "", errors.New("must be vault:path#field")
+	}
+
+	out, err := run(ctx, v.path, "kv", "get", "-field="+field, path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+// awsSecretsManager resolves name to the string of an AWS Secrets Manager
+// secret, or name#key to a key of a secret that is a JSON object, with
+// the aws command line tool.
+type awsSecretsManager struct {
+	path string
+}
+
+// Resolve returns the secret, or the key of it.
+func (a *awsSecretsManager) Resolve(ctx context.Context, ref string) (string, error) {
+	name, key := splitField(ref)
+	if name == "" {
+		return "", errors.New("must be aws-sm:name or aws-sm:name#key")
+	}
+
+	out, err := run(ctx, a.path, "secretsmanager", "get-secret-value", "--secret-id", name, "--query", "SecretString", "--output", "text")
+	if err != nil {
+		return "", err
+	}
+	secret := strings.TrimSuffix(string(out), "\n")
+	if key == "" {
+		return secret, nil
+	}
+
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(secret), &values); err != nil {
+		// the secret is not in the message
+		return "", fmt.Errorf("secret %s is not a JSON object", name)
+	}
+	value, ok := values[key]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no key %s", name, key)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	data, err := json.Marshal(value)
+	return string(data), err
+}
+
+// run runs the command and returns its stdout, which is never logged or
+// included in errors, as it is a secret.
+func run(ctx context.Context, path string, args ...string) ([]byte, error) {
+	logger.Trace.Printf("secrets: running %v %v", path, args)
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s %s: %v: %s", path, strings.Join(args[:2], " "), err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
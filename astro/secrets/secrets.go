@@ -0,0 +1,127 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package secrets resolves references to secrets, e.g.
+// vault:secret/db#password, so that the values of secret variables don't
+// have to be passed in plain text. The Vault and AWS Secrets Manager
+// resolvers shell out to the vault and aws command line tools, so that
+// their usual configuration applies.
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Schemes of the built-in resolvers.
+const (
+	// SchemeEnv resolves env:NAME to the value of an environment variable
+	// of astro.
+	SchemeEnv = "env"
+	// SchemeVault resolves vault:path#field to a field of a Vault KV
+	// secret.
+	SchemeVault = "vault"
+	// SchemeAWSSecretsManager resolves aws-sm:name to an AWS Secrets
+	// Manager secret, or aws-sm:name#key to a key of a JSON secret.
+	SchemeAWSSecretsManager = "aws-sm"
+)
+
+// Resolver returns the secret that a reference refers to. The reference
+// doesn't include the scheme, e.g. secret/db#password.
+type Resolver interface {
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+// ResolverFunc is a function that resolves references, as a Resolver.
+type ResolverFunc func(ctx context.Context, ref string) (string, error)
+
+// Resolve calls the function.
+func (f ResolverFunc) Resolve(ctx context.Context, ref string) (string, error) {
+	return f(ctx, ref)
+}
+
+// Resolvers are resolvers by the scheme of the references they resolve.
+type Resolvers map[string]Resolver
+
+// Defaults returns the built-in resolvers. vaultPath and awsCLIPath are
+// the command line tools they run, which default to vault and aws in the
+// PATH.
+func Defaults(vaultPath, awsCLIPath string) Resolvers {
+	if vaultPath == "" {
+		vaultPath = "vault"
+	}
+	if awsCLIPath == "" {
+		awsCLIPath = "aws"
+	}
+
+	return Resolvers{
+		SchemeEnv:               ResolverFunc(resolveEnv),
+		SchemeVault:             &vault{path: vaultPath},
+		SchemeAWSSecretsManager: &awsSecretsManager{path: awsCLIPath},
+	}
+}
+
+// Resolve returns the secret that value refers to, e.g.
+// vault:secret/db#password. Values that don't start with the scheme of
+// one of the resolvers are the secret themselves, and are returned as
+// they are.
+func (r Resolvers) Resolve(ctx context.Context, value string) (string, error) {
+	scheme, ref, ok := strings.Cut(value, ":")
+	if !ok {
+		return value, nil
+	}
+	resolver, ok := r[scheme]
+	if !ok {
+		return value, nil
+	}
+
+	secret, err := resolver.Resolve(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve secret %s: %v", value, err)
+	}
+	return secret, nil
+}
+
+// Schemes returns the schemes of the resolvers, sorted.
+func (r Resolvers) Schemes() []string {
+	schemes := make([]string, 0, len(r))
+	for scheme := range r {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// resolveEnv returns the value of the environment variable.
+func resolveEnv(_ context.Context, name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+// splitField splits a reference into the path of a secret and the field
+// after the last #, if there is one.
+func splitField(ref string) (path, field string) {
+	if i := strings.LastIndex(ref, "#"); i >= 0 {
+		return ref[:i], ref[i+1:]
+	}
+	return ref, ""
+}
@@ -0,0 +1,154 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package secrets_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/uber/astro/astro/secrets"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCLI installs a command line tool that logs its arguments and runs
+// script, and returns its path and the path of the log.
+func fakeCLI(t *testing.T, name, script string) (path string, callLog string) {
+	binDir := t.TempDir()
+	callLog = filepath.Join(binDir, "calls")
+	path = filepath.Join(binDir, name)
+
+	require.NoError(t, os.WriteFile(path, []byte(`#!/bin/sh
+echo "$@" >> `+callLog+`
+`+script), 0755))
+
+	return path, callLog
+}
+
+// calls returns the commands that the fake CLI ran.
+func calls(t *testing.T, callLog string) []string {
+	data, err := os.ReadFile(callLog)
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestResolvePlainValues(t *testing.T) {
+	resolvers := secrets.Defaults("", "")
+
+	for _, value := range []string{"hunter2", "", "http://example.com", "unknown:ref"} {
+		secret, err := resolvers.Resolve(context.Background(), value)
+		require.NoError(t, err)
+		assert.Equal(t, value, secret)
+	}
+}
+
+func TestResolveEnv(t *testing.T) {
+	t.Setenv("ASTRO_TEST_SECRET", "hunter2")
+	resolvers := secrets.Defaults("", "")
+
+	secret, err := resolvers.Resolve(context.Background(), "env:ASTRO_TEST_SECRET")
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", secret)
+
+	_, err = resolvers.Resolve(context.Background(), "env:ASTRO_TEST_MISSING")
+	assert.EqualError(t, err, "unable to resolve secret env:ASTRO_TEST_MISSING: environment variable ASTRO_TEST_MISSING is not set")
+}
+
+func TestResolveVault(t *testing.T) {
+	vaultPath, callLog := fakeCLI(t, "vault", `
+if [ "$4" = secret/missing ]; then
+	echo "No value found at secret/missing" >&2
+	exit 2
+fi
+echo hunter2
+`)
+	resolvers := secrets.Defaults(vaultPath, "")
+
+	secret, err := resolvers.Resolve(context.Background(), "vault:secret/db#password")
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", secret)
+
+	_, err = resolvers.Resolve(context.Background(), "vault:secret/missing#password")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "No value found at secret/missing")
+
+	_, err = resolvers.Resolve(context.Background(), "vault:secret/db")
+	assert.EqualError(t, err, "unable to resolve secret vault:secret/db: must be vault:path#field")
+
+	assert.Equal(t, []string{
+		"kv get -field=password secret/db",
+		"kv get -field=password secret/missing",
+	}, calls(t, callLog))
+}
+
+func TestResolveAWSSecretsManager(t *testing.T) {
+	awsPath, callLog := fakeCLI(t, "aws", `
+case "$4" in
+db)
+	echo '{"password":"hunter2","port":5432}' ;;
+token)
+	echo abc123 ;;
+*)
+	echo "An error occurred (ResourceNotFoundException) when calling the GetSecretValue operation" >&2
+	exit 255 ;;
+esac
+`)
+	resolvers := secrets.Defaults("", awsPath)
+	ctx := context.Background()
+
+	secret, err := resolvers.Resolve(ctx, "aws-sm:token")
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", secret)
+
+	secret, err = resolvers.Resolve(ctx, "aws-sm:db#password")
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", secret)
+
+	secret, err = resolvers.Resolve(ctx, "aws-sm:db#port")
+	require.NoError(t, err)
+	assert.Equal(t, "5432", secret)
+
+	_, err = resolvers.Resolve(ctx, "aws-sm:db#user")
+	assert.EqualError(t, err, "unable to resolve secret aws-sm:db#user: secret db has no key user")
+
+	// the secret is never in errors
+	_, err = resolvers.Resolve(ctx, "aws-sm:token#key")
+	assert.EqualError(t, err, "unable to resolve secret aws-sm:token#key: secret token is not a JSON object")
+
+	_, err = resolvers.Resolve(ctx, "aws-sm:missing")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ResourceNotFoundException")
+
+	assert.Equal(t, "secretsmanager get-secret-value --secret-id token --query SecretString --output text", calls(t, callLog)[0])
+}
+
+func TestResolverFunc(t *testing.T) {
+	resolvers := secrets.Resolvers{
+		"test": secrets.ResolverFunc(func(_ context.Context, ref string) (string, error) {
+			return strings.ToUpper(ref), nil
+		}),
+	}
+
+	secret, err := resolvers.Resolve(context.Background(), "test:hunter2")
+	require.NoError(t, err)
+	assert.Equal(t, "HUNTER2", secret)
+	assert.Equal(t, []string{"test"}, resolvers.Schemes())
+}
@@ -0,0 +1,312 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing/fstest"
+
+	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/utils"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// sessionArchiveExt is the extension of the archive, in the session repo,
+// that a session directory is compressed into.
+const sessionArchiveExt = ".tar.zst"
+
+// Close ends the current session of the project, which is marked
+// completed, and prunes the plugin cache to its max_size. If
+// archive_sessions is set, or the session was extracted from its archive
+// to apply its plans, the session directory is compressed into an
+// archive, which is read like the directory was. The project can't be
+// used once it is closed.
+func (c *Project) Close() error {
+	session := c.sessions.current
+	if session == nil {
+		return nil
+	}
+	c.sessions.current = nil
+
+	return c.sessions.close(session)
+}
+
+// close ends the session: it is marked completed, the plugin cache is
+// pruned, and the session is archived if it needs to be.
+func (r *SessionRepo) close(session *Session) error {
+	session.stopWatchingSignals()
+	if err := session.markCompleted(); err != nil {
+		logger.Warn("unable to mark session completed", logger.Fields{"session": session.id, "error": err})
+	}
+	if err := session.closePluginCache(); err != nil {
+		logger.Warn("unable to prune plugin cache", logger.Fields{"session": session.id, "error": err})
+	}
+	// the log is closed before the session directory may be archived
+	session.closeLog()
+
+	if !r.project.config.ArchiveSessions && !session.extracted {
+		return nil
+	}
+	return r.archive(session)
+}
+
+// archivePath returns the path of the archive of the session with the ID.
+func (r *SessionRepo) archivePath(id string) string {
+	return filepath.Join(r.path, id+sessionArchiveExt)
+}
+
+// isArchived returns whether the session with the ID is only in the repo
+// as an archive.
+func (r *SessionRepo) isArchived(id string) bool {
+	return !utils.IsDirectory(filepath.Join(r.path, id)) && utils.FileExists(r.archivePath(id))
+}
+
+// exists returns whether the session with the ID is in the repo, as a
+// directory or as an archive.
+func (r *SessionRepo) exists(id string) bool {
+	return utils.IsDirectory(filepath.Join(r.path, id)) || utils.FileExists(r.archivePath(id))
+}
+
+// archive compresses the directory of the session into its archive, and
+// removes the directory. The archive is written to a temporary file
+// first, so that the session is never lost half archived.
+func (r *SessionRepo) archive(session *Session) error {
+	tmp, err := os.CreateTemp(r.path, "."+session.id+"-*"+sessionArchiveExt)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := writeArchive(tmp, session.path); err != nil {
+		tmp.Close()
+		return fmt.Errorf("unable to archive session %v: %v", session.id, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), r.archivePath(session.id)); err != nil {
+		return err
+	}
+
+	logger.Trace.Printf("astro: archived session %v", session.id)
+
+	return os.RemoveAll(session.path)
+}
+
+// extract restores the directory of an archived session, so that commands
+// can run in it again. It is written to a temporary directory first, so
+// that a session is either extracted or not. The archive is kept until
+// the session is archived again.
+func (r *SessionRepo) extract(id string) error {
+	f, err := os.Open(r.archivePath(id))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tmpPath, err := os.MkdirTemp(r.path, "."+id+"-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpPath)
+	if err := os.Chmod(tmpPath, 0755); err != nil {
+		return err
+	}
+
+	err = readArchive(f, func(name string, header *tar.Header, content io.Reader) error {
+		localPath := filepath.Join(tmpPath, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			return os.MkdirAll(localPath, header.FileInfo().Mode().Perm())
+		case tar.TypeSymlink:
+			return os.Symlink(header.Linkname, localPath)
+		case tar.TypeReg:
+			f, err := os.OpenFile(localPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, header.FileInfo().Mode().Perm())
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, content); err != nil {
+				f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+			return os.Chtimes(localPath, header.ModTime, header.ModTime)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to extract session %v: %v", id, err)
+	}
+
+	logger.Trace.Printf("astro: extracted session %v", id)
+
+	return os.Rename(tmpPath, filepath.Join(r.path, id))
+}
+
+// sessionFS returns the files of the session with the ID, from its
+// directory, or from its archive without extracting it. Only the files
+// that make up the history of an archived session are read, i.e. those
+// that historyFiles returns.
+func (r *SessionRepo) sessionFS(id string) (fs.FS, error) {
+	if !r.isArchived(id) {
+		return os.DirFS(filepath.Join(r.path, id)), nil
+	}
+
+	f, err := os.Open(r.archivePath(id))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	files := fstest.MapFS{}
+	err = readArchive(f, func(name string, header *tar.Header, content io.Reader) error {
+		switch {
+		// the directories of the executions, and their logs, even if
+		// they are empty
+		case header.Typeflag == tar.TypeDir && strings.Count(name, "/") <= 1:
+			files[name] = &fstest.MapFile{Mode: fs.ModeDir | 0755, ModTime: header.ModTime}
+		case header.Typeflag == tar.TypeReg && isHistoryFile(name):
+			data, err := io.ReadAll(content)
+			if err != nil {
+				return err
+			}
+			files[name] = &fstest.MapFile{Data: data, Mode: header.FileInfo().Mode(), ModTime: header.ModTime}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read archive of session %v: %v", id, err)
+	}
+
+	return files, nil
+}
+
+// writeArchive writes the files in dir to w, as a tar archive compressed
+// with zstd. Symlinks are archived as links, and other special files are
+// left out.
+func writeArchive(w io.Writer, dir string) error {
+	zw, err := zstd.NewWriter(w)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(zw)
+
+	err = filepath.WalkDir(dir, func(localPath string, entry fs.DirEntry, err error) error {
+		if err != nil || localPath == dir {
+			return err
+		}
+
+		var link string
+		switch {
+		case entry.Type()&fs.ModeSymlink != 0:
+			if link, err = os.Readlink(localPath); err != nil {
+				return err
+			}
+		case !entry.IsDir() && !entry.Type().IsRegular():
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, localPath)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if entry.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(localPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		zw.Close()
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		zw.Close()
+		return err
+	}
+	return zw.Close()
+}
+
+// readArchive calls fn with each entry of the archive read from rd, and
+// its contents, by its name without a trailing slash. Archives with
+// entries outside of the archive directory are refused.
+func readArchive(rd io.Reader, fn func(name string, header *tar.Header, content io.Reader) error) error {
+	zr, err := zstd.NewReader(rd)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	tr := tar.NewReader(zr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := strings.TrimSuffix(header.Name, "/")
+		if name == "" || path.Clean(name) != name || path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("invalid file: %q", header.Name)
+		}
+
+		if err := fn(name, header, tr); err != nil {
+			return fmt.Errorf("%v: %v", name, err)
+		}
+	}
+}
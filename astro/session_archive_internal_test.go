@@ -0,0 +1,135 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newArchiveTestProject returns a project that archives its sessions,
+// with a session that planned app-dev.
+func newArchiveTestProject(t *testing.T) (*Project, *Session) {
+	c := &Project{
+		config: &conf.Project{ArchiveSessions: true},
+		clock:  utils.SystemClock,
+	}
+	sessions, err := NewSessionRepo(c, filepath.Join(t.TempDir(), ".astro"), func() string { return testSessionID })
+	require.NoError(t, err)
+	c.sessions = sessions
+
+	session, err := sessions.Current()
+	require.NoError(t, err)
+
+	modified := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	for file, content := range map[string]string{
+		"git-sha":                               "abc123\n",
+		savedPlansFile:                          `{"executions":["app-dev"]}`,
+		"app-dev/logs/plan.log":                 "Plan: 1 to add",
+		"app-dev/" + terraformBuildFile:         `{"version":"0.12.6"}`,
+		"app-dev/sandbox/app/main.tf":           "resource {}",
+		"app-dev/sandbox/app/app-dev.plan":      "plan",
+		"app-dev/sandbox/app/.terraform/plugin": "binary",
+	} {
+		path := filepath.Join(session.path, filepath.FromSlash(file))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		require.NoError(t, os.Chtimes(path, modified, modified))
+	}
+	require.NoError(t, os.Symlink("plugin", filepath.Join(session.path, "app-dev/sandbox/app/.terraform/link")))
+
+	return c, session
+}
+
+func TestCloseArchivesSession(t *testing.T) {
+	c, session := newArchiveTestProject(t)
+
+	require.NoError(t, c.Close())
+	assert.False(t, utils.FileExists(session.path))
+	assert.True(t, utils.FileExists(filepath.Join(c.sessions.path, testSessionID+sessionArchiveExt)))
+
+	// archived sessions are read without extracting them
+	sessions, err := c.Sessions()
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	info := sessions[0]
+	assert.Equal(t, testSessionID, info.ID)
+	assert.Equal(t, "abc123", info.GitSHA)
+	assert.Equal(t, []string{"app-dev"}, info.SavedPlans)
+	require.Len(t, info.Executions, 1)
+	assert.Equal(t, "0.12.6", info.Executions[0].TerraformVersion)
+	require.Len(t, info.Executions[0].Logs, 1)
+	logInfo := info.Executions[0].Logs[0]
+	assert.Equal(t, "plan.log", logInfo.Name)
+	assert.Equal(t, int64(len("Plan: 1 to add")), logInfo.Size)
+	assert.True(t, logInfo.Modified.Equal(time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)))
+
+	log, err := c.SessionLog(testSessionID, "app-dev", "plan.log")
+	require.NoError(t, err)
+	assert.Equal(t, "Plan: 1 to add", string(log))
+
+	builds, err := c.sessions.readTerraformBuilds(testSessionID)
+	require.NoError(t, err)
+	assert.Equal(t, "0.12.6", builds["app-dev"].Version)
+
+	assert.False(t, utils.FileExists(session.path))
+}
+
+func TestOpenExtractsArchivedSession(t *testing.T) {
+	c, session := newArchiveTestProject(t)
+	require.NoError(t, c.Close())
+
+	// the config no longer archives sessions, but the extracted one is
+	// archived again
+	c.config.ArchiveSessions = false
+	opened, err := c.sessions.Open(testSessionID)
+	require.NoError(t, err)
+	assert.True(t, opened.extracted)
+
+	plan, err := os.ReadFile(filepath.Join(session.path, "app-dev/sandbox/app/app-dev.plan"))
+	require.NoError(t, err)
+	assert.Equal(t, "plan", string(plan))
+	link, err := os.Readlink(filepath.Join(session.path, "app-dev/sandbox/app/.terraform/link"))
+	require.NoError(t, err)
+	assert.Equal(t, "plugin", link)
+
+	// the session is listed once
+	sessions, err := c.Sessions()
+	require.NoError(t, err)
+	assert.Len(t, sessions, 1)
+
+	require.NoError(t, c.Close())
+	assert.False(t, utils.FileExists(session.path))
+	assert.True(t, utils.FileExists(filepath.Join(c.sessions.path, testSessionID+sessionArchiveExt)))
+}
+
+func TestCloseWithoutArchiving(t *testing.T) {
+	c, session := newArchiveTestProject(t)
+	c.config.ArchiveSessions = false
+
+	require.NoError(t, c.Close())
+	assert.True(t, utils.IsDirectory(session.path))
+	assert.False(t, utils.FileExists(filepath.Join(c.sessions.path, testSessionID+sessionArchiveExt)))
+}
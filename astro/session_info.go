@@ -0,0 +1,344 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/uber/astro/astro/exec2"
+	"github.com/uber/astro/astro/terraform"
+	"github.com/uber/astro/astro/utils"
+)
+
+// SessionInfo describes a session in the session repo, for browsing the
+// history of a project.
+type SessionInfo struct {
+	ID string
+	// Started is when the session was created.
+	Started time.Time
+	// Status is whether the process of the session is still running,
+	// ended, or crashed, and Finished is when it ended.
+	Status   SessionStatus
+	Finished time.Time
+	// Command and Args are the command line that ran in the session, or
+	// that last ran in it, with its PID on Host, if they were recorded.
+	Command string
+	Args    []string
+	PID     int
+	Host    string
+	// GitSHA is the commit the session was run from, if it was recorded.
+	GitSHA string
+	// Log is the name of the session log file, if there is one.
+	Log string
+	// SavedPlans is the IDs of the executions whose plans were saved in
+	// the session, and PlannedAt is when they were made.
+	SavedPlans []string
+	PlannedAt  time.Time
+	// Runs is the stats of the commands that ran in the session, in the
+	// order they ran.
+	Runs []RunStats
+	// Executions is the executions that ran in the session, sorted by ID.
+	Executions []ExecutionInfo
+}
+
+// ExecutionInfo describes an execution that ran in a session.
+type ExecutionInfo struct {
+	ID string
+	// TerraformVersion is the version of Terraform the execution ran
+	// with, if it was recorded.
+	TerraformVersion string
+	// Logs is the output of the Terraform commands the execution ran,
+	// sorted by when they were last written to.
+	Logs []LogInfo
+	// Commands is how the Terraform commands the execution ran exited,
+	// in the order they ran. Executions recorded by older versions of
+	// astro have none.
+	Commands []CommandInfo
+	// Crashed is set when Terraform crashed during the execution.
+	Crashed bool
+}
+
+// LogInfo describes a log file in a session.
+type LogInfo struct {
+	Name     string
+	Size     int64
+	Modified time.Time
+}
+
+// CommandInfo describes how a Terraform command of an execution exited.
+// Name is the name of its logs, e.g. plan for plan.log, plan.stdout.log
+// and plan.stderr.log.
+type CommandInfo struct {
+	Name string
+	exec2.Status
+}
+
+// Finished returns when the execution last wrote to a log, which is when
+// its last Terraform command finished.
+func (e ExecutionInfo) Finished() time.Time {
+	var finished time.Time
+	for _, log := range e.Logs {
+		if log.Modified.After(finished) {
+			finished = log.Modified
+		}
+	}
+	return finished
+}
+
+// Sessions returns the sessions in the session repo of the project, most
+// recent first.
+func (c *Project) Sessions() ([]SessionInfo, error) {
+	return c.sessions.list()
+}
+
+// SessionInfo returns the details of a session in the session repo.
+func (c *Project) SessionInfo(id string) (*SessionInfo, error) {
+	return c.sessions.info(id)
+}
+
+// SessionLog returns the contents of a log file of a session. If
+// executionID is empty, it is a log of the session itself.
+func (c *Project) SessionLog(sessionID, executionID, name string) ([]byte, error) {
+	return c.sessions.readLog(sessionID, executionID, name)
+}
+
+// list returns the sessions in the repo, most recent first. Sessions are
+// the directories, or archives, named with a ULID, which excludes e.g.
+// the plugin cache.
+func (r *SessionRepo) list() ([]SessionInfo, error) {
+	if err := r.restoreHistory(""); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(r.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []SessionInfo
+	for _, entry := range entries {
+		id := entry.Name()
+		if !entry.IsDir() {
+			// sessions that were extracted are listed once, by their
+			// directory
+			id = strings.TrimSuffix(id, sessionArchiveExt)
+			if id == entry.Name() || utils.IsDirectory(filepath.Join(r.path, id)) {
+				continue
+			}
+		}
+		if _, err := utils.ULIDTime(id); err != nil {
+			continue
+		}
+		info, err := r.info(id)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *info)
+	}
+
+	// ULIDs sort in the order they were generated
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].ID > sessions[j].ID
+	})
+
+	return sessions, nil
+}
+
+// info returns the details of a session.
+func (r *SessionRepo) info(id string) (*SessionInfo, error) {
+	sessionPath := filepath.Join(r.path, id)
+	if validPathElement(id) {
+		if err := r.restoreHistory(id); err != nil {
+			return nil, err
+		}
+	}
+	if !validPathElement(id) || !r.exists(id) {
+		return nil, fmt.Errorf("session does not exist: %v", id)
+	}
+	if r.isArchived(id) {
+		sessionPath = r.archivePath(id)
+	}
+
+	sessionFS, err := r.sessionFS(id)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &SessionInfo{ID: id}
+
+	if started, err := utils.ULIDTime(id); err == nil {
+		info.Started = started
+	} else if stat, err := os.Stat(sessionPath); err == nil {
+		info.Started = stat.ModTime()
+	}
+
+	metadata, completion, status, err := readSessionStatus(sessionFS)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read status of session %v: %v", id, err)
+	}
+	info.Status = status
+	if metadata != nil {
+		info.Command = metadata.Command
+		info.Args = metadata.Args
+		info.PID = metadata.PID
+		info.Host = metadata.Host
+	}
+	if completion != nil {
+		info.Finished = completion.Finished
+	}
+
+	if sha, err := fs.ReadFile(sessionFS, "git-sha"); err == nil {
+		info.GitSHA = strings.TrimSpace(string(sha))
+	}
+
+	if _, err := fs.Stat(sessionFS, sessionLogFile); err == nil {
+		info.Log = sessionLogFile
+	}
+
+	if data, err := fs.ReadFile(sessionFS, savedPlansFile); err == nil {
+		var plans savedPlans
+		if err := json.Unmarshal(data, &plans); err != nil {
+			return nil, fmt.Errorf("unable to read saved plans of session %v: %v", id, err)
+		}
+		info.SavedPlans = plans.Executions
+		info.PlannedAt = plans.PlannedAt
+	}
+
+	runs, err := readRunStats(sessionFS)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read runs of session %v: %v", id, err)
+	}
+	info.Runs = runs
+
+	entries, err := fs.ReadDir(sessionFS, ".")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		// every execution that ran has a log directory
+		if stat, err := fs.Stat(sessionFS, path.Join(entry.Name(), "logs")); !entry.IsDir() || err != nil || !stat.IsDir() {
+			continue
+		}
+		execution, err := readExecutionInfo(sessionFS, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		info.Executions = append(info.Executions, execution)
+	}
+
+	return info, nil
+}
+
+// readExecutionInfo returns the details of the execution with the ID,
+// from the files of its session.
+func readExecutionInfo(sessionFS fs.FS, id string) (ExecutionInfo, error) {
+	execution := ExecutionInfo{ID: id}
+
+	if _, err := fs.Stat(sessionFS, path.Join(id, terraform.CrashBundleFile)); err == nil {
+		execution.Crashed = true
+	}
+
+	if data, err := fs.ReadFile(sessionFS, path.Join(id, terraformBuildFile)); err == nil {
+		var build terraformBuild
+		if err := json.Unmarshal(data, &build); err == nil {
+			execution.TerraformVersion = build.Version
+		}
+	}
+
+	entries, err := fs.ReadDir(sessionFS, path.Join(id, "logs"))
+	if err != nil {
+		return execution, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if name := strings.TrimSuffix(entry.Name(), terraform.StatusFileSuffix); name != entry.Name() {
+			data, err := fs.ReadFile(sessionFS, path.Join(id, "logs", entry.Name()))
+			if err != nil {
+				return execution, err
+			}
+			status, err := exec2.ReadStatus(data)
+			if err != nil {
+				return execution, fmt.Errorf("unable to read status of %v: %v", entry.Name(), err)
+			}
+			execution.Commands = append(execution.Commands, CommandInfo{Name: name, Status: status})
+			continue
+		}
+		stat, err := entry.Info()
+		if err != nil {
+			return execution, err
+		}
+		execution.Logs = append(execution.Logs, LogInfo{
+			Name:     entry.Name(),
+			Size:     stat.Size(),
+			Modified: stat.ModTime(),
+		})
+	}
+
+	sort.SliceStable(execution.Logs, func(i, j int) bool {
+		return execution.Logs[i].Modified.Before(execution.Logs[j].Modified)
+	})
+	sort.SliceStable(execution.Commands, func(i, j int) bool {
+		return execution.Commands[i].Started.Before(execution.Commands[j].Started)
+	})
+
+	return execution, nil
+}
+
+// readLog returns the contents of a log file of a session, or of an
+// execution in it.
+func (r *SessionRepo) readLog(sessionID, executionID, name string) ([]byte, error) {
+	if !validPathElement(sessionID) || !validPathElement(name) {
+		return nil, fmt.Errorf("invalid log: %v", name)
+	}
+
+	if err := r.restoreHistory(sessionID); err != nil {
+		return nil, err
+	}
+
+	if executionID == "" && name != sessionLogFile {
+		return nil, fmt.Errorf("invalid log: %v", name)
+	}
+	if executionID != "" && !validPathElement(executionID) {
+		return nil, fmt.Errorf("execution does not exist: %v", executionID)
+	}
+
+	sessionFS, err := r.sessionFS(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if executionID == "" {
+		return fs.ReadFile(sessionFS, name)
+	}
+	return fs.ReadFile(sessionFS, path.Join(executionID, "logs", name))
+}
+
+// validPathElement returns whether s names a single entry of a directory,
+// so that it cannot be used to read files outside of the session repo.
+func validPathElement(s string) bool {
+	return s != "" && s != "." && s != ".." && !strings.ContainsAny(s, `/\`)
+}
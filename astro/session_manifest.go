@@ -0,0 +1,211 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// sessionManifestFile is the name of the file in the session
+	// directory that records the parameters of every run of the session,
+	// for auditing.
+	sessionManifestFile = "manifest.json"
+	// sessionManifestKey is the name sessionManifestFile is saved with in
+	// the state backend, where manifestFile lists the files of a session.
+	sessionManifestKey = "session-manifest.json"
+)
+
+// SessionManifest records what ran in a session: the version of astro
+// and the command line, and the parameters and executions of each run.
+type SessionManifest struct {
+	// AstroVersion is the version of astro that last ran in the session,
+	// if it was set with WithVersion.
+	AstroVersion string   `json:"astro_version,omitempty"`
+	Command      string   `json:"command"`
+	Args         []string `json:"args"`
+	// Runs is the runs of the session, in the order they ran.
+	Runs []ManifestRun `json:"runs"`
+}
+
+// ManifestRun records the parameters a command ran with in a session,
+// and how each of its executions did.
+type ManifestRun struct {
+	// Command is the command that ran, e.g. apply.
+	Command  string    `json:"command"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	// UserVars and Filters are the user variables the executions were
+	// bound with.
+	UserVars map[string]string `json:"user_vars"`
+	Filters  map[string]bool   `json:"filters"`
+	// ModuleNames, ExecutionIDs, ExecutionPatterns and Filter select the
+	// executions that ran, if set.
+	ModuleNames       []string `json:"module_names,omitempty"`
+	ExecutionIDs      []string `json:"execution_ids,omitempty"`
+	ExecutionPatterns []string `json:"execution_patterns,omitempty"`
+	Filter            string   `json:"filter,omitempty"`
+	// TerraformParameters and Targets are passed to Terraform.
+	TerraformParameters []string `json:"terraform_parameters,omitempty"`
+	Targets             []string `json:"targets,omitempty"`
+	// Executions is the executions of the run, in the order they
+	// finished.
+	Executions []ManifestExecution `json:"executions"`
+}
+
+// ManifestExecution records an execution of a run.
+type ManifestExecution struct {
+	ID     string `json:"id"`
+	Module string `json:"module"`
+	// TerraformVersion is the version of Terraform the execution ran
+	// with, if it ran Terraform.
+	TerraformVersion string `json:"terraform_version,omitempty"`
+	// Started is when the execution started running Terraform, if it
+	// did, and Finished when its result was reported.
+	Started  time.Time `json:"started,omitempty"`
+	Finished time.Time `json:"finished"`
+	// Result is "ok", "failed" or "skipped".
+	Result string `json:"result"`
+}
+
+// SessionManifest returns the manifest of a session in the session repo.
+// Sessions of older versions of astro have none.
+func (c *Project) SessionManifest(id string) (*SessionManifest, error) {
+	if validPathElement(id) {
+		if err := c.sessions.restoreHistory(id); err != nil {
+			return nil, err
+		}
+	}
+	if !validPathElement(id) || !c.sessions.exists(id) {
+		return nil, fmt.Errorf("session does not exist: %v", id)
+	}
+
+	sessionFS, err := c.sessions.sessionFS(id)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := readSessionManifest(sessionFS)
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("session %v has no manifest", id)
+	}
+
+	return manifest, nil
+}
+
+// newManifestRun returns the record of a run of the command with the
+// parameters.
+func newManifestRun(command string, parameters ExecutionParameters, started time.Time) ManifestRun {
+	run := ManifestRun{
+		Command:             command,
+		Started:             started,
+		ModuleNames:         parameters.ModuleNames,
+		ExecutionIDs:        parameters.ExecutionIDs,
+		ExecutionPatterns:   parameters.ExecutionPatterns,
+		Filter:              parameters.Filter,
+		TerraformParameters: parameters.TerraformParameters,
+		Targets:             parameters.Targets,
+		Executions:          []ManifestExecution{},
+	}
+	if parameters.UserVars != nil {
+		run.UserVars = parameters.UserVars.Values
+		run.Filters = parameters.UserVars.Filters
+	}
+	return run
+}
+
+// manifestExecution returns the record of the execution whose result
+// arrived at finished. It started when it first ran Terraform in the
+// session, unless that was in an earlier run, e.g. the plan of an apply.
+func (session *Session) manifestExecution(result *Result, module string, runStarted, finished time.Time) ManifestExecution {
+	execution := ManifestExecution{
+		ID:       result.id,
+		Module:   module,
+		Finished: finished,
+		Result:   applyStatusOK,
+	}
+	switch {
+	case result.err != nil:
+		execution.Result = applyStatusFailed
+	case result.skipReason != "":
+		execution.Result = applyStatusSkipped
+	}
+
+	if run, ok := session.executionRuns.Load(result.id); ok {
+		execution.Started = run.(*executionRun).started.UTC()
+		if execution.Started.Before(runStarted) {
+			execution.Started = runStarted
+		}
+	}
+
+	if data, err := os.ReadFile(filepath.Join(session.path, result.id, terraformBuildFile)); err == nil {
+		var build terraformBuild
+		if err := json.Unmarshal(data, &build); err == nil {
+			execution.TerraformVersion = build.Version
+		}
+	}
+
+	return execution
+}
+
+// recordManifestRun adds the run to the manifest of the session, along
+// with the version of astro and the command line that ran it.
+func (session *Session) recordManifestRun(run ManifestRun) error {
+	manifest, err := readSessionManifest(os.DirFS(session.path))
+	if err != nil {
+		return err
+	}
+	if manifest == nil {
+		manifest = &SessionManifest{}
+	}
+
+	project := session.repo.project
+	metadata := project.sessionMetadata()
+	manifest.AstroVersion = project.version
+	manifest.Command = metadata.Command
+	manifest.Args = metadata.Args
+	manifest.Runs = append(manifest.Runs, run)
+
+	return writeJSONFile(filepath.Join(session.path, sessionManifestFile), manifest)
+}
+
+// readSessionManifest returns the manifest of the session whose files are
+// in sessionFS, or nil if it has none.
+func readSessionManifest(sessionFS fs.FS) (*SessionManifest, error) {
+	data, err := fs.ReadFile(sessionFS, sessionManifestFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &SessionManifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("unable to read %v: %v", sessionManifestFile, err)
+	}
+
+	return manifest, nil
+}
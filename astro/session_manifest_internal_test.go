@@ -0,0 +1,81 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionManifest(t *testing.T) {
+	now := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	c := newMetadataTestProject(t, now)
+	c.version = "1.2.3"
+
+	session, err := c.sessions.NewSession()
+	require.NoError(t, err)
+
+	parameters := ExecutionParameters{
+		ModuleNames: []string{"app"},
+		UserVars: &UserVariables{
+			Values:  map[string]string{"environment": "dev"},
+			Filters: map[string]bool{"environment": true},
+		},
+		Targets: []string{"aws_instance.web"},
+	}
+
+	results := make(chan *Result, 2)
+	results <- &Result{id: "app-dev"}
+	results <- &Result{id: "app-prod", skipReason: "vetoed"}
+	close(results)
+	testReadResults(c.recordRun(session, "apply", parameters, nil, func() {}, results))
+
+	results = make(chan *Result, 1)
+	results <- &Result{id: "app-dev", err: errors.New("failed")}
+	close(results)
+	testReadResults(c.recordRun(session, "destroy", NoExecutionParameters(), nil, func() {}, results))
+
+	manifest, err := c.SessionManifest(testSessionID)
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.3", manifest.AstroVersion)
+	assert.Equal(t, "astro", manifest.Command)
+	assert.Equal(t, []string{"plan", "--modules", "app"}, manifest.Args)
+	require.Len(t, manifest.Runs, 2)
+
+	apply := manifest.Runs[0]
+	assert.Equal(t, "apply", apply.Command)
+	assert.Equal(t, now, apply.Started)
+	assert.Equal(t, map[string]string{"environment": "dev"}, apply.UserVars)
+	assert.Equal(t, map[string]bool{"environment": true}, apply.Filters)
+	assert.Equal(t, []string{"app"}, apply.ModuleNames)
+	assert.Equal(t, []string{"aws_instance.web"}, apply.Targets)
+	assert.Equal(t, []ManifestExecution{
+		{ID: "app-dev", Module: "app-dev", Finished: now, Result: "ok"},
+		{ID: "app-prod", Module: "app-prod", Finished: now, Result: "skipped"},
+	}, apply.Executions)
+
+	assert.Equal(t, "destroy", manifest.Runs[1].Command)
+	require.Len(t, manifest.Runs[1].Executions, 1)
+	assert.Equal(t, "failed", manifest.Runs[1].Executions[0].Result)
+
+	_, err = c.SessionManifest("..")
+	assert.Error(t, err)
+}
@@ -0,0 +1,200 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/uber/astro/astro/utils"
+)
+
+const (
+	// sessionMetadataFile is the name of the file in the session
+	// directory that describes the process running in the session, or
+	// that last ran in it.
+	sessionMetadataFile = "session.json"
+	// sessionCompletedFile is the name of the file in the session
+	// directory that marks that the process of the session ended.
+	sessionCompletedFile = "completed.json"
+)
+
+// SessionStatus is whether the process of a session is running, ended or
+// crashed.
+type SessionStatus string
+
+const (
+	// SessionInProgress is the status of sessions whose process is still
+	// running.
+	SessionInProgress SessionStatus = "in progress"
+	// SessionCompleted is the status of sessions whose process ended,
+	// whether or not its executions succeeded.
+	SessionCompleted SessionStatus = "completed"
+	// SessionCrashed is the status of sessions whose process stopped
+	// without ending the session, e.g. because it was killed.
+	SessionCrashed SessionStatus = "crashed"
+	// SessionStatusUnknown is the status of sessions created by versions
+	// of astro that didn't record their process, and of sessions that
+	// didn't complete on another host.
+	SessionStatusUnknown SessionStatus = "unknown"
+)
+
+// sessionMetadata describes the process running in a session. It is
+// written before the session directory appears, so that every session has
+// it.
+type sessionMetadata struct {
+	// Command and Args are the command line of the process, e.g. astro
+	// and [plan --modules app].
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+	// Started is when the process started running in the session.
+	Started time.Time `json:"started"`
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+}
+
+// sessionCompletion is written to the session directory when the process
+// of the session ends.
+type sessionCompletion struct {
+	Finished time.Time `json:"finished"`
+}
+
+// sessionMetadata returns the metadata of a session that this process
+// runs in.
+func (c *Project) sessionMetadata() sessionMetadata {
+	command, args := c.command, c.commandArgs
+	if command == "" {
+		command, args = filepath.Base(os.Args[0]), os.Args[1:]
+	}
+	host, _ := os.Hostname()
+
+	return sessionMetadata{
+		Command: command,
+		Args:    args,
+		Started: c.clock.Now().UTC(),
+		PID:     os.Getpid(),
+		Host:    host,
+	}
+}
+
+// create creates the directory of a new session with the ID. It is
+// written to a temporary directory with the session metadata first, so
+// that the session directory never exists without it.
+func (r *SessionRepo) create(id string) (string, error) {
+	sessionPath := filepath.Join(r.path, id)
+	if r.exists(id) {
+		return "", &fs.PathError{Op: "mkdir", Path: sessionPath, Err: fs.ErrExist}
+	}
+
+	tmpPath, err := os.MkdirTemp(r.path, "."+id+"-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmpPath)
+	if err := os.Chmod(tmpPath, 0755); err != nil {
+		return "", err
+	}
+
+	if err := writeJSONFile(filepath.Join(tmpPath, sessionMetadataFile), r.project.sessionMetadata()); err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(tmpPath, sessionPath); err != nil {
+		return "", err
+	}
+
+	return sessionPath, nil
+}
+
+// markRunning records that this process runs in the session, which was
+// opened again, e.g. to apply the plans saved in it.
+func (session *Session) markRunning() error {
+	if err := os.Remove(filepath.Join(session.path, sessionCompletedFile)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return writeJSONFile(filepath.Join(session.path, sessionMetadataFile), session.repo.project.sessionMetadata())
+}
+
+// markCompleted records that the process of the session ended.
+func (session *Session) markCompleted() error {
+	completion := sessionCompletion{Finished: session.repo.project.clock.Now().UTC()}
+	return writeJSONFile(filepath.Join(session.path, sessionCompletedFile), completion)
+}
+
+// readSessionStatus returns the metadata of the session whose files are
+// in sessionFS, if it was recorded, and its status.
+func readSessionStatus(sessionFS fs.FS) (*sessionMetadata, *sessionCompletion, SessionStatus, error) {
+	var metadata *sessionMetadata
+	if data, err := fs.ReadFile(sessionFS, sessionMetadataFile); err == nil {
+		metadata = &sessionMetadata{}
+		if err := json.Unmarshal(data, metadata); err != nil {
+			return nil, nil, "", fmt.Errorf("unable to read %v: %v", sessionMetadataFile, err)
+		}
+	}
+
+	if data, err := fs.ReadFile(sessionFS, sessionCompletedFile); err == nil {
+		completion := &sessionCompletion{}
+		if err := json.Unmarshal(data, completion); err != nil {
+			return nil, nil, "", fmt.Errorf("unable to read %v: %v", sessionCompletedFile, err)
+		}
+		return metadata, completion, SessionCompleted, nil
+	}
+
+	// Whether the process is still running can only be told on the host
+	// it ran on
+	host, _ := os.Hostname()
+	switch {
+	case metadata == nil || metadata.Host != host:
+		return metadata, nil, SessionStatusUnknown, nil
+	case utils.ProcessRunning(metadata.PID):
+		return metadata, nil, SessionInProgress, nil
+	}
+	return metadata, nil, SessionCrashed, nil
+}
+
+// writeJSONFile writes v to the file as JSON. It is written to a temporary
+// file first, so that the file is never half written.
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
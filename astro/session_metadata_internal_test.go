@@ -0,0 +1,150 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/uber/astro/astro/conf"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMetadataTestProject returns a project whose session repo is in a new
+// directory, which runs plan in its sessions at the time.
+func newMetadataTestProject(t *testing.T, now time.Time) *Project {
+	c := &Project{
+		config:      &conf.Project{},
+		clock:       testClock{now: now},
+		command:     "astro",
+		commandArgs: []string{"plan", "--modules", "app"},
+	}
+	sessions, err := NewSessionRepo(c, filepath.Join(t.TempDir(), ".astro"), func() string { return testSessionID })
+	require.NoError(t, err)
+	c.sessions = sessions
+	return c
+}
+
+func TestSessionMetadata(t *testing.T) {
+	now := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	c := newMetadataTestProject(t, now)
+
+	_, err := c.sessions.Current()
+	require.NoError(t, err)
+
+	// the session directory appeared with its metadata
+	entries, err := os.ReadDir(c.sessions.path)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, testSessionID, entries[0].Name())
+
+	info, err := c.SessionInfo(testSessionID)
+	require.NoError(t, err)
+	assert.Equal(t, SessionInProgress, info.Status)
+	assert.Equal(t, "astro", info.Command)
+	assert.Equal(t, []string{"plan", "--modules", "app"}, info.Args)
+	assert.Equal(t, os.Getpid(), info.PID)
+	assert.True(t, info.Finished.IsZero())
+
+	// sessions can't be created twice
+	_, err = c.sessions.NewSession()
+	assert.True(t, os.IsExist(err), "expected the session to exist, got %v", err)
+
+	require.NoError(t, c.Close())
+
+	info, err = c.SessionInfo(testSessionID)
+	require.NoError(t, err)
+	assert.Equal(t, SessionCompleted, info.Status)
+	assert.Equal(t, now, info.Finished)
+
+	// opening it again runs in it again
+	_, err = c.sessions.open(testSessionID)
+	require.NoError(t, err)
+	info, err = c.SessionInfo(testSessionID)
+	require.NoError(t, err)
+	assert.Equal(t, SessionInProgress, info.Status)
+}
+
+func TestSessionOpenEndsCurrent(t *testing.T) {
+	now := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	c := newMetadataTestProject(t, now)
+
+	// e.g. the session Startup hooks ran in
+	current, err := c.sessions.Current()
+	require.NoError(t, err)
+
+	c.sessions.generateID = func() string { return "saved" }
+	saved, err := c.sessions.NewSession()
+	require.NoError(t, err)
+	require.NoError(t, c.sessions.close(saved))
+
+	opened, err := c.sessions.open("saved")
+	require.NoError(t, err)
+	assert.Equal(t, opened, c.sessions.current)
+
+	info, err := c.SessionInfo(current.id)
+	require.NoError(t, err)
+	assert.Equal(t, SessionCompleted, info.Status)
+	assert.Equal(t, now, info.Finished)
+
+	require.NoError(t, c.Close())
+}
+
+func TestSessionStatusCrashedAndUnknown(t *testing.T) {
+	c := newMetadataTestProject(t, time.Now())
+
+	session, err := c.sessions.NewSession()
+	require.NoError(t, err)
+
+	// a process that has exited
+	cmd := exec.Command("true")
+	require.NoError(t, cmd.Run())
+
+	metadata := c.sessionMetadata()
+	metadata.PID = cmd.Process.Pid
+	data, err := json.Marshal(metadata)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(session.path, sessionMetadataFile), data, 0644))
+
+	info, err := c.SessionInfo(testSessionID)
+	require.NoError(t, err)
+	assert.Equal(t, SessionCrashed, info.Status)
+
+	// on another host, it can't be told
+	metadata.Host = "elsewhere"
+	data, err = json.Marshal(metadata)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(session.path, sessionMetadataFile), data, 0644))
+
+	info, err = c.SessionInfo(testSessionID)
+	require.NoError(t, err)
+	assert.Equal(t, SessionStatusUnknown, info.Status)
+
+	// nor for sessions of older versions of astro
+	require.NoError(t, os.Remove(filepath.Join(session.path, sessionMetadataFile)))
+
+	info, err = c.SessionInfo(testSessionID)
+	require.NoError(t, err)
+	assert.Equal(t, SessionStatusUnknown, info.Status)
+	assert.Equal(t, "", info.Command)
+}
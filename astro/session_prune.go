@@ -0,0 +1,120 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/utils"
+)
+
+// SessionPrune is what PruneSessions removed from the session repo.
+type SessionPrune struct {
+	// Removed is the IDs of the sessions that were removed, most recent
+	// first, and Kept the number of sessions left.
+	Removed []string
+	Kept    int
+}
+
+// PruneSessions removes the sessions in the session repo that started
+// more than olderThan ago, if it is set, and all but the keep most recent
+// sessions, if keep is set. Sessions that are in progress, including the
+// current session, are never removed. Only the sessions in the repo are
+// removed: their history in the state backend, if there is one, is kept.
+func (c *Project) PruneSessions(olderThan time.Duration, keep int) (*SessionPrune, error) {
+	return c.sessions.prune(c.clock.Now(), olderThan, keep)
+}
+
+// prune removes the sessions that started more than olderThan before now,
+// and the sessions past the keep most recent ones.
+func (r *SessionRepo) prune(now time.Time, olderThan time.Duration, keep int) (*SessionPrune, error) {
+	ids, err := r.localSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	prune := &SessionPrune{}
+	for i, id := range ids {
+		started, _ := utils.ULIDTime(id)
+		expired := (keep > 0 && i >= keep) || (olderThan > 0 && now.Sub(started) > olderThan)
+		if !expired || r.inProgress(id) {
+			prune.Kept++
+			continue
+		}
+
+		if err := os.RemoveAll(filepath.Join(r.path, id)); err != nil {
+			return prune, err
+		}
+		if err := os.RemoveAll(r.archivePath(id)); err != nil {
+			return prune, err
+		}
+		logger.Trace.Printf("astro: removed session %v", id)
+		prune.Removed = append(prune.Removed, id)
+	}
+
+	return prune, nil
+}
+
+// localSessions returns the IDs of the sessions in the repo, as
+// directories or archives, most recent first. Unlike list, it doesn't
+// restore the sessions in the state backend first.
+func (r *SessionRepo) localSessions() ([]string, error) {
+	entries, err := os.ReadDir(r.path)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var ids []string
+	for _, entry := range entries {
+		id := strings.TrimSuffix(entry.Name(), sessionArchiveExt)
+		if !entry.IsDir() && id == entry.Name() {
+			continue
+		}
+		if _, err := utils.ULIDTime(id); err != nil || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+
+	// ULIDs sort in the order they were generated
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+
+	return ids, nil
+}
+
+// inProgress returns whether the session is the current session, or its
+// process may still be running.
+func (r *SessionRepo) inProgress(id string) bool {
+	if r.current != nil && r.current.id == id {
+		return true
+	}
+
+	sessionFS, err := r.sessionFS(id)
+	if err != nil {
+		// a session that can't be read is kept, to be safe
+		return true
+	}
+	_, _, status, err := readSessionStatus(sessionFS)
+	return err != nil || status == SessionInProgress
+}
@@ -0,0 +1,73 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPruneSessions(t *testing.T) {
+	now := time.Date(2019, 3, 31, 12, 0, 0, 0, time.UTC)
+	c := &Project{config: &conf.Project{}, clock: testClock{now: now}}
+	sessions, err := NewSessionRepo(c, filepath.Join(t.TempDir(), ".astro"), nil)
+	require.NoError(t, err)
+	c.sessions = sessions
+
+	day := 24 * time.Hour
+	newSession := func(age time.Duration) string {
+		id := utils.ULIDAt(now.Add(-age)).String()
+		require.NoError(t, os.MkdirAll(filepath.Join(c.sessions.path, id), 0755))
+		return id
+	}
+	running := newSession(50 * day)
+	old := newSession(40 * day)
+	older := newSession(20 * day)
+	recent := newSession(2 * day)
+	latest := newSession(time.Hour)
+
+	// the process of this session is still running
+	require.NoError(t, writeJSONFile(filepath.Join(c.sessions.path, running, sessionMetadataFile), c.sessionMetadata()))
+
+	// the plugin cache isn't a session
+	require.NoError(t, os.MkdirAll(filepath.Join(c.sessions.path, "plugins"), 0755))
+
+	prune, err := c.PruneSessions(30*day, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{old}, prune.Removed)
+	assert.Equal(t, 4, prune.Kept)
+
+	prune, err = c.PruneSessions(0, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{older}, prune.Removed)
+	assert.Equal(t, 3, prune.Kept)
+
+	for _, id := range []string{running, recent, latest} {
+		assert.True(t, c.sessions.exists(id), "expected session %v to be kept", id)
+	}
+	assert.False(t, c.sessions.exists(old))
+	assert.False(t, c.sessions.exists(older))
+	assert.True(t, utils.IsDirectory(filepath.Join(c.sessions.path, "plugins")))
+}
@@ -21,15 +21,22 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"path"
 	"path/filepath"
+	"sync"
 	"syscall"
 
 	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/terraform"
 	"github.com/uber/astro/astro/utils"
 
+	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/terraform/dag"
 )
 
+// sessionLogFile is the name of the log file in each session directory.
+const sessionLogFile = "astro.log"
+
 // SessionRepo is a parent directory that contains inidividual project
 // sessions.
 type SessionRepo struct {
@@ -65,28 +72,228 @@ type Session struct {
 	id   string
 	path string
 
-	// for OS signal handling
-	signalChan chan os.Signal
+	// ctx is cancelled when astro receives a signal, which interrupts all
+	// running commands.
+	ctx    context.Context
+	cancel context.CancelFunc
+	// signalRuns is the number of runs of the session in progress, which
+	// cancel it when astro receives a signal, and stopSignals stops
+	// watching for signals once they have all finished.
+	signalsMu   sync.Mutex
+	signalRuns  int
+	stopSignals func()
+	// closeLog stops logging to the log file of the session, if there is
+	// one.
+	closeLog func()
+
+	// fromSavedPlans is set when the session was opened to apply the
+	// plans saved in it.
+	fromSavedPlans bool
+	// stalePlans detects saved plans that are stale, when applying saved
+	// plans.
+	stalePlans *stalePlans
+	// approveRisk is set when applies whose risk needs approval are
+	// approved.
+	approveRisk bool
+	// savePlans is set when the plans made in the session are saved to be
+	// applied later.
+	savePlans bool
+	// detectDrift is set when plans are made with -refresh-only.
+	detectDrift bool
+	// estimateCosts is set when the cost of the changes in plans is
+	// estimated.
+	estimateCosts bool
+	// rawOutput is set when the output of Terraform is not parsed.
+	rawOutput bool
+	// pinnedBuilds, if set, are the Terraform binaries that executions
+	// must run with, by execution ID, from pinnedSession.
+	pinnedBuilds  map[string]terraformBuild
+	pinnedSession string
+	// resumed is set when the session was opened to resume an apply,
+	// and resumedApplied is the executions that were applied before.
+	resumed        bool
+	resumedApplied map[string]bool
+	// extracted is set when the session was extracted from its archive,
+	// so that it is archived again once it ends.
+	extracted bool
+	// awsRoles caches the credentials of the AWS roles assumed in the
+	// session, by awsRoleKey, and awsRoleLocks makes executions that
+	// need the same role wait for it to be assumed once.
+	awsRoles     sync.Map
+	awsRoleLocks sync.Map
+	// secrets caches the values of the secret variables resolved in the
+	// session, by their reference.
+	secrets sync.Map
+	// dependencyOutputs caches the outputs of the executions whose outputs
+	// are passed to the executions that depend on them, by execution ID,
+	// and dependencyOutputLocks makes executions that need the same
+	// outputs wait for them to be read once.
+	dependencyOutputs     sync.Map
+	dependencyOutputLocks sync.Map
+	// executionRuns is the run of each execution, by ID, which times it
+	// against its warn_after and kill_after.
+	executionRuns sync.Map
 }
 
 // NewSession creates a new session in the repository.
 func (r *SessionRepo) NewSession() (*Session, error) {
 	id := r.generateID()
 
+	sessionPath, err := r.create(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.newSession(id, sessionPath), nil
+}
+
+// Open opens an existing session in the repository, to apply the plans
+// that were saved in it. It becomes the current session.
+func (r *SessionRepo) Open(id string) (*Session, error) {
+	session, err := r.open(id)
+	if err != nil {
+		return nil, err
+	}
+
+	session.fromSavedPlans = true
+
+	return session, nil
+}
+
+// open opens an existing session in the repository, which becomes the
+// current session.
+func (r *SessionRepo) open(id string) (*Session, error) {
 	sessionPath := filepath.Join(r.path, id)
-	if err := os.Mkdir(sessionPath, 0755); err != nil {
+	if !validPathElement(id) || !r.exists(id) {
+		return nil, fmt.Errorf("session does not exist: %v", id)
+	}
+
+	// The session it replaces, e.g. the one Startup hooks ran in, ends
+	if r.current != nil {
+		current := r.current
+		r.current = nil
+		if err := r.close(current); err != nil {
+			return nil, err
+		}
+	}
+
+	extracted := r.isArchived(id)
+	if extracted {
+		if err := r.extract(id); err != nil {
+			return nil, err
+		}
+	}
+
+	session := r.newSession(id, sessionPath)
+	session.extracted = extracted
+	if err := session.markRunning(); err != nil {
+		session.closeLog()
 		return nil, err
 	}
 
-	signalChan := make(chan os.Signal, 1)
-	signal.Notify(signalChan, syscall.SIGTERM, syscall.SIGINT)
+	r.current = session
+
+	return session, nil
+}
+
+// newSession returns a session for the directory, which is cancelled when
+// astro receives a signal while it runs something. See watchSignals.
+func (r *SessionRepo) newSession(id, sessionPath string) *Session {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	closeLog := func() {}
+	if r.project.sessionLogFormat != "" {
+		closeLog = r.openLog(sessionPath)
+	}
+	logger.Debug("using session", logger.Fields{"session": id, "path": sessionPath})
 
 	return &Session{
-		id:         id,
-		path:       sessionPath,
-		repo:       r,
-		signalChan: signalChan,
-	}, nil
+		id:       id,
+		path:     sessionPath,
+		repo:     r,
+		ctx:      ctx,
+		cancel:   cancel,
+		closeLog: closeLog,
+	}
+}
+
+// watchSignals cancels the session when astro receives a signal, until the
+// returned function is called, which can be called more than once. Every
+// run of the session watches for signals while it runs, so that no signal
+// handler is left behind once they have all finished.
+func (session *Session) watchSignals() func() {
+	session.signalsMu.Lock()
+	defer session.signalsMu.Unlock()
+
+	if session.signalRuns == 0 {
+		signalChan := make(chan os.Signal, 1)
+		signal.Notify(signalChan, syscall.SIGTERM, syscall.SIGINT)
+
+		stopped := make(chan struct{})
+		go func() {
+			select {
+			case sig := <-signalChan:
+				fmt.Printf("\nReceived signal: %s, cancelling all operations...\n", sig)
+				session.cancel()
+			case <-stopped:
+			}
+		}()
+
+		session.stopSignals = func() {
+			signal.Stop(signalChan)
+			close(stopped)
+		}
+	}
+	session.signalRuns++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			session.signalsMu.Lock()
+			defer session.signalsMu.Unlock()
+
+			// the session may have been closed first
+			if session.signalRuns == 0 {
+				return
+			}
+			session.signalRuns--
+			if session.signalRuns == 0 {
+				session.stopSignals()
+			}
+		})
+	}
+}
+
+// stopWatchingSignals stops cancelling the session when astro receives a
+// signal, even if runs of the session are still in progress.
+func (session *Session) stopWatchingSignals() {
+	session.signalsMu.Lock()
+	defer session.signalsMu.Unlock()
+
+	if session.signalRuns > 0 {
+		session.signalRuns = 0
+		session.stopSignals()
+	}
+}
+
+// openLog starts logging to a log file in the session directory. It
+// returns a function that stops logging to it and closes it, which can be
+// called more than once.
+func (r *SessionRepo) openLog(sessionPath string) func() {
+	f, err := os.OpenFile(filepath.Join(sessionPath, sessionLogFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		logger.Warn("unable to open session log file", logger.Fields{"error": err})
+		return func() {}
+	}
+
+	remove := logger.AddSink(f, logger.TraceLevel, r.project.sessionLogFormat)
+	var closeOnce sync.Once
+	return func() {
+		closeOnce.Do(func() {
+			remove()
+			f.Close()
+		})
+	}
 }
 
 // Current returns the last session created, or creates one if it's the
@@ -106,67 +313,208 @@ func (r *SessionRepo) Current() (*Session, error) {
 	return session, nil
 }
 
-func (session *Session) apply(boundExecutions []*boundExecution) (<-chan string, <-chan *Result, error) {
-	logger.Trace.Println("astro session: running apply without graph")
+// recordGitSHA saves the SHA of the commit the session was run from.
+func (session *Session) recordGitSHA(sha string) error {
+	return os.WriteFile(filepath.Join(session.path, "git-sha"), []byte(sha+"\n"), 0644)
+}
 
-	numberOfExecutions := len(boundExecutions)
-	// Needs to be big enough to buffer log lines from below for tests that
-	// don't consume from the channel.
-	status := make(chan string, numberOfExecutions*10)
-	results := make(chan *Result, numberOfExecutions)
+func (session *Session) applyWithGraph(boundExecutions []*boundExecution, limiter *executionLimiter) (<-chan string, <-chan *Result, error) {
+	logger.Debug("running apply", logger.Fields{"executions": len(boundExecutions), "graph": true})
+
+	// Convert unboundExecutions to executionSet
+	executions := make(executionSet, len(boundExecutions))
+	for i, e := range boundExecutions {
+		executions[i] = e
+	}
+
+	// Generate dep graph
+	graph, err := executions.selectionGraph()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	numberOfExecutions := len(executions)
+	status := newStatusQueue()
+	results := newResults(numberOfExecutions)
+
+	stopWatching := session.watchSignals()
+	// Walk the graph and execute
+	go func() {
+		defer stopWatching()
+		defer close(results) // signals the end of all executions
+		defer status.close()
+
+		err := graph.Walk(func(vertex dag.Vertex) error {
+			// skip if we've reached the root
+			if _, ok := vertex.(graphNodeRoot); ok {
+				return nil
+			}
 
-	logger.Trace.Printf("astro: %d executions to apply\n", numberOfExecutions)
+			b := vertex.(*boundExecution)
+
+			if session.alreadyApplied(b) {
+				results <- &Result{
+					id:         b.ID(),
+					skipReason: alreadyAppliedReason,
+				}
+				return nil
+			}
+
+			// don't start new executions once cancelled
+			if err := session.ctx.Err(); err != nil {
+				results <- &Result{
+					id:  b.ID(),
+					err: fmt.Errorf("not applied: %v", err),
+				}
+				return err
+			}
+
+			// Blocked executions don't take a slot, and the executions
+			// that depend on them are not applied.
+			if err := session.checkPreconditions(b, status); err != nil {
+				results <- &Result{
+					id:  b.ID(),
+					err: err,
+				}
+				return err
+			}
+
+			if err := limiter.acquire(session.ctx, b); err != nil {
+				results <- &Result{
+					id:  b.ID(),
+					err: fmt.Errorf("not applied: %v", err),
+				}
+				return err
+			}
+			defer limiter.release(b)
 
-	var fns []func()
-	for _, e := range boundExecutions {
-		b := e // save for use inside the loop
-		fns = append(fns, func() {
 			terraform, err := session.newTerraformSession(b)
 			if err != nil {
 				results <- &Result{
 					id:  b.ID(),
 					err: err,
 				}
-				return
+				return err
+			}
+
+			skipReason, err := session.runPreModuleRunHooks(b, status)
+			if err != nil {
+				results <- &Result{
+					id:  b.ID(),
+					err: err,
+				}
+				return err
+			}
+			if skipReason != "" {
+				// Executions that depend on this one still run.
+				results <- &Result{
+					id:         b.ID(),
+					skipReason: skipReason,
+				}
+				return nil
 			}
 
-			status <- fmt.Sprintf("[%s] Initializing...", b.ID())
-			if result, err := terraform.Init(); err != nil {
+			if session.fromSavedPlans {
+				if result, ok := session.applySavedPlan(b, terraform, status); ok {
+					results <- result
+					return result.err
+				}
+				if terraform, err = session.replanTerraformSession(b); err != nil {
+					results <- &Result{
+						id:  b.ID(),
+						err: err,
+					}
+					return err
+				}
+			}
+
+			status.send(b.ID(), "Initializing...")
+			if result, err := session.initialize(b, terraform, status); err != nil {
 				results <- &Result{
 					id:              b.ID(),
 					terraformResult: result,
+					invocations:     terraform.Invocations(),
 					err:             err,
 				}
-				return
+				return err
 			}
 
-			status <- fmt.Sprintf("[%s] Applying...", b.ID())
-			result, err := terraform.Apply()
+			result := session.applyExecution(b, terraform, status)
+			if result.err == nil {
+				session.recordDependencyOutputs(b, terraform)
+				// the outputs were read after the result was made
+				result.invocations = terraform.Invocations()
+			}
+			results <- result
+
+			// This will cause any executions that depend on this one
+			// to be skipped.
+			return result.err
+		})
+		if err != nil {
+			return
+		}
+	}()
+
+	return status.ch, results, nil
+}
+
+func (session *Session) destroy(boundExecutions []*boundExecution, limiter *executionLimiter) (<-chan string, <-chan *Result, error) {
+	numberOfExecutions := len(boundExecutions)
+	status := newStatusQueue()
+	results := newResults(numberOfExecutions)
+
+	logger.Debug("running destroy", logger.Fields{"executions": numberOfExecutions, "graph": false})
+
+	execute := func(b *boundExecution) {
+		terraform, err := session.newTerraformSession(b)
+		if err != nil {
+			results <- &Result{
+				id:  b.ID(),
+				err: err,
+			}
+			return
+		}
+
+		status.send(b.ID(), "Initializing...")
+		if result, err := session.initialize(b, terraform, status); err != nil {
 			results <- &Result{
 				id:              b.ID(),
 				terraformResult: result,
+				invocations:     terraform.Invocations(),
 				err:             err,
 			}
-		})
-	}
+			return
+		}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	go func() {
-		sig := <-session.signalChan
-		fmt.Printf("\nReceived signal: %s, cancelling all operations...\n", sig)
-		cancel()
-	}()
+		status.send(b.ID(), "Destroying...")
+		result, err := session.retry(b, status, terraform.Destroy)
+		results <- &Result{
+			id:              b.ID(),
+			terraformResult: result,
+			invocations:     terraform.Invocations(),
+			err:             err,
+		}
+	}
 
+	stopWatching := session.watchSignals()
 	go func() {
-		defer close(results) // signals the end of all executions
-		utils.Parallel(ctx, 10, fns...)
+		defer stopWatching()
+		limiter.run(session.ctx, boundExecutions, execute, func(b *boundExecution, err error) {
+			results <- &Result{
+				id:  b.ID(),
+				err: fmt.Errorf("not destroyed: %v", err),
+			}
+		})
+		status.close()
+		close(results) // signals the end of all executions
 	}()
 
-	return status, results, nil
+	return status.ch, results, nil
 }
 
-func (session *Session) applyWithGraph(boundExecutions []*boundExecution) (<-chan string, <-chan *Result, error) {
-	logger.Trace.Println("astro session: running apply with graph")
+func (session *Session) destroyWithGraph(boundExecutions []*boundExecution, limiter *executionLimiter) (<-chan string, <-chan *Result, error) {
+	logger.Debug("running destroy", logger.Fields{"executions": len(boundExecutions), "graph": true})
 
 	// Convert unboundExecutions to executionSet
 	executions := make(executionSet, len(boundExecutions))
@@ -174,21 +522,23 @@ func (session *Session) applyWithGraph(boundExecutions []*boundExecution) (<-cha
 		executions[i] = e
 	}
 
-	// Generate dep graph
-	graph, err := executions.graph()
+	// Generate reversed dep graph, so that executions are destroyed
+	// before their dependencies
+	graph, err := executions.reverseGraph()
 	if err != nil {
 		return nil, nil, err
 	}
 
 	numberOfExecutions := len(executions)
-	// Needs to be big enough to buffer log lines from below for tests that
-	// don't consume from the channel.
-	status := make(chan string, numberOfExecutions*10)
-	results := make(chan *Result, numberOfExecutions)
+	status := newStatusQueue()
+	results := newResults(numberOfExecutions)
 
+	stopWatching := session.watchSignals()
 	// Walk the graph and execute
 	go func() {
-		defer close(results)
+		defer stopWatching()
+		defer close(results) // signals the end of all executions
+		defer status.close()
 
 		err := graph.Walk(func(vertex dag.Vertex) error {
 			// skip if we've reached the root
@@ -197,6 +547,25 @@ func (session *Session) applyWithGraph(boundExecutions []*boundExecution) (<-cha
 			}
 
 			b := vertex.(*boundExecution)
+
+			// don't start new executions once cancelled
+			if err := session.ctx.Err(); err != nil {
+				results <- &Result{
+					id:  b.ID(),
+					err: fmt.Errorf("not destroyed: %v", err),
+				}
+				return err
+			}
+
+			if err := limiter.acquire(session.ctx, b); err != nil {
+				results <- &Result{
+					id:  b.ID(),
+					err: fmt.Errorf("not destroyed: %v", err),
+				}
+				return err
+			}
+			defer limiter.release(b)
+
 			terraform, err := session.newTerraformSession(b)
 			if err != nil {
 				results <- &Result{
@@ -206,38 +575,46 @@ func (session *Session) applyWithGraph(boundExecutions []*boundExecution) (<-cha
 				return err
 			}
 
-			for _, hook := range b.ModuleConfig().Hooks.PreModuleRun {
-				status <- fmt.Sprintf("[%s] Running PreModuleRun hook...", b.ID())
-				if err := runCommandkAndSetEnvironment(session.path, hook); err != nil {
-					results <- &Result{
-						id:  b.ID(),
-						err: fmt.Errorf("error running PreModuleRun hook: %v", err),
-					}
-					return err
+			skipReason, err := session.runPreModuleRunHooks(b, status)
+			if err != nil {
+				results <- &Result{
+					id:  b.ID(),
+					err: err,
+				}
+				return err
+			}
+			if skipReason != "" {
+				// Executions that depend on this one still run.
+				results <- &Result{
+					id:         b.ID(),
+					skipReason: skipReason,
 				}
+				return nil
 			}
 
-			status <- fmt.Sprintf("[%s] Initializing...", b.ID())
-			if result, err := terraform.Init(); err != nil {
+			status.send(b.ID(), "Initializing...")
+			if result, err := session.initialize(b, terraform, status); err != nil {
 				results <- &Result{
 					id:              b.ID(),
 					terraformResult: result,
+					invocations:     terraform.Invocations(),
 					err:             err,
 				}
 				return err
 			}
 
-			status <- fmt.Sprintf("[%s] Applying...", b.ID())
+			status.send(b.ID(), "Destroying...")
 
-			result, err := terraform.Apply()
+			result, err := session.retry(b, status, terraform.Destroy)
 			results <- &Result{
 				id:              b.ID(),
 				terraformResult: result,
+				invocations:     terraform.Invocations(),
 				err:             err,
 			}
 
-			// This will cause any executions that depend on this one
-			// to be skipped.
+			// This will cause any dependencies of this execution to be
+			// skipped.
 			return err
 		})
 		if err != nil {
@@ -245,89 +622,256 @@ func (session *Session) applyWithGraph(boundExecutions []*boundExecution) (<-cha
 		}
 	}()
 
-	return status, results, nil
+	return status.ch, results, nil
 }
 
-func (session *Session) plan(boundExecutions []*boundExecution, detach bool) (<-chan string, <-chan *Result, error) {
-	logger.Trace.Println("astro session: running plan")
-
+func (session *Session) plan(boundExecutions []*boundExecution, limiter *executionLimiter, detach bool) (<-chan string, <-chan *Result, error) {
 	numberOfExecutions := len(boundExecutions)
-	// Needs to be big enough to buffer log lines from below for tests that
-	// don't consume from the channel.
-	status := make(chan string, numberOfExecutions*10)
-	results := make(chan *Result, numberOfExecutions)
-
-	logger.Trace.Printf("astro: %d executions to plan\n", numberOfExecutions)
-
-	// Create plan functions
-	var fns []func()
-	for _, e := range boundExecutions {
-		b := e // save for use inside the loop
-		fns = append(fns, func() {
-			terraform, err := session.newTerraformSession(b)
-			if err != nil {
-				results <- &Result{
-					id:  b.ID(),
-					err: err,
-				}
-				return
+	status := newStatusQueue()
+	results := newResults(numberOfExecutions)
+
+	logger.Debug("running plan", logger.Fields{"executions": numberOfExecutions, "graph": false})
+
+	execute := func(b *boundExecution) {
+		results <- session.planExecution(b, detach, status)
+	}
+
+	stopWatching := session.watchSignals()
+	// Run plans in parallel
+	go func() {
+		defer stopWatching()
+		limiter.run(session.ctx, boundExecutions, execute, func(b *boundExecution, err error) {
+			results <- &Result{
+				id:  b.ID(),
+				err: fmt.Errorf("not planned: %v", err),
 			}
+		})
+		status.close()
+		close(results) // signals the end of all executions
+	}()
 
-			for _, hook := range e.ModuleConfig().Hooks.PreModuleRun {
-				status <- fmt.Sprintf("[%s] Running PreModuleRun hook...", b.ID())
-				if err := runCommandkAndSetEnvironment(session.path, hook); err != nil {
-					results <- &Result{
-						id:  b.ID(),
-						err: fmt.Errorf("error running PreModuleRun hook: %v", err),
-					}
-					return
-				}
+	return status.ch, results, nil
+}
+
+func (session *Session) planWithGraph(boundExecutions []*boundExecution, limiter *executionLimiter, detach bool) (<-chan string, <-chan *Result, error) {
+	logger.Debug("running plan", logger.Fields{"executions": len(boundExecutions), "graph": true})
+
+	// Convert unboundExecutions to executionSet
+	executions := make(executionSet, len(boundExecutions))
+	for i, e := range boundExecutions {
+		executions[i] = e
+	}
+
+	// Generate dep graph
+	graph, err := executions.selectionGraph()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	numberOfExecutions := len(executions)
+	status := newStatusQueue()
+	results := newResults(numberOfExecutions)
+
+	stopWatching := session.watchSignals()
+	// Walk the graph and execute
+	go func() {
+		defer stopWatching()
+		defer close(results) // signals the end of all executions
+		defer status.close()
+
+		err := graph.Walk(func(vertex dag.Vertex) error {
+			// skip if we've reached the root
+			if _, ok := vertex.(graphNodeRoot); ok {
+				return nil
 			}
 
-			status <- fmt.Sprintf("[%s] Initializing...", b.ID())
-			if result, err := terraform.Init(); err != nil {
+			b := vertex.(*boundExecution)
+
+			// don't start new executions once cancelled
+			if err := session.ctx.Err(); err != nil {
 				results <- &Result{
-					id:              b.ID(),
-					terraformResult: result,
-					err:             err,
+					id:  b.ID(),
+					err: fmt.Errorf("not planned: %v", err),
 				}
-				return
+				return err
 			}
 
-			if detach {
-				status <- fmt.Sprintf("[%s] Disconnecting remote state...", b.ID())
-				if result, err := terraform.Detach(); err != nil {
-					results <- &Result{
-						id:              b.ID(),
-						terraformResult: result,
-						err:             err,
-					}
-					return
+			if err := limiter.acquire(session.ctx, b); err != nil {
+				results <- &Result{
+					id:  b.ID(),
+					err: fmt.Errorf("not planned: %v", err),
 				}
+				return err
 			}
+			defer limiter.release(b)
 
-			status <- fmt.Sprintf("[%s] Planning...", b.ID())
-			result, err := terraform.Plan()
-			results <- &Result{
+			result := session.planExecution(b, detach, status)
+			results <- result
+
+			// This will cause any executions that depend on this one
+			// to be skipped.
+			return result.err
+		})
+		if err != nil {
+			return
+		}
+	}()
+
+	return status.ch, results, nil
+}
+
+// planExecution initializes and plans a single execution.
+func (session *Session) planExecution(b *boundExecution, detach bool, status *statusQueue) *Result {
+	if err := session.checkPreconditions(b, status); err != nil {
+		return &Result{
+			id:  b.ID(),
+			err: err,
+		}
+	}
+
+	terraform, err := session.newTerraformSession(b)
+	if err != nil {
+		return &Result{
+			id:  b.ID(),
+			err: err,
+		}
+	}
+
+	skipReason, err := session.runPreModuleRunHooks(b, status)
+	if err != nil {
+		return &Result{
+			id:  b.ID(),
+			err: err,
+		}
+	}
+	if skipReason != "" {
+		return &Result{
+			id:         b.ID(),
+			skipReason: skipReason,
+		}
+	}
+
+	status.send(b.ID(), "Initializing...")
+	if result, err := session.initialize(b, terraform, status); err != nil {
+		return &Result{
+			id:              b.ID(),
+			terraformResult: result,
+			invocations:     terraform.Invocations(),
+			err:             err,
+		}
+	}
+
+	if detach {
+		status.send(b.ID(), "Disconnecting remote state...")
+		if result, err := terraform.Detach(); err != nil {
+			return &Result{
 				id:              b.ID(),
 				terraformResult: result,
+				invocations:     terraform.Invocations(),
 				err:             err,
 			}
-		})
+		}
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	go func() {
-		sig := <-session.signalChan
-		fmt.Printf("\nReceived signal: %s, cancelling all operations...\n", sig)
-		cancel()
-	}()
+	if pattern := b.ModuleConfig().Workspaces; pattern != "" {
+		return session.planWorkspaces(terraform, b, pattern, status)
+	}
 
-	// Run plans in parallel
-	go func() {
-		defer close(results) // signals the end of all executions
-		utils.Parallel(ctx, 10, fns...)
-	}()
+	status.send(b.ID(), "Planning...")
+	result, err := session.retry(b, status, terraform.Plan)
+
+	planResult := &Result{
+		id:              b.ID(),
+		terraformResult: result,
+		err:             err,
+	}
+	if err == nil {
+		session.checkPlan(b, terraform, planResult, status)
+	}
+
+	if planResult.err == nil && session.savePlans {
+		session.recordStateSerial(b, terraform)
+	}
+	planResult.invocations = terraform.Invocations()
+	return planResult
+}
+
+// checkPlan checks the plan the execution just made against the policies,
+// and estimates its cost and assesses its risk if configured to, recording
+// the outcome in result.
+func (session *Session) checkPlan(b *boundExecution, terraform *terraform.Session, result *Result, status *statusQueue) {
+	planJSON := session.planJSON(b, terraform)
+
+	result.warnings, result.err = session.checkPolicies(b, terraform, planJSON, status)
+	if result.err != nil {
+		return
+	}
+
+	// Plans whose cost cannot be estimated are still good
+	if session.estimateCosts {
+		var costErr error
+		if result.cost, costErr = session.estimateCost(b, planJSON, status); costErr != nil {
+			result.warnings = append(result.warnings, costErr.Error())
+		}
+	}
+
+	// Plans whose risk cannot be assessed are still good, until they are
+	// applied
+	if session.repo.project.config.Risk != nil {
+		var riskErr error
+		if result.risk, riskErr = session.assessRisk(b, planJSON, status); riskErr != nil {
+			result.warnings = append(result.warnings, riskErr.Error())
+		}
+	}
+}
+
+// planWorkspaces plans every existing workspace of the execution that
+// matches pattern. It returns a result with a sub-result for each
+// workspace.
+func (session *Session) planWorkspaces(terraform *terraform.Session, b *boundExecution, pattern string, status *statusQueue) *Result {
+	status.send(b.ID(), "Listing workspaces...")
+	workspaces, listResult, err := terraform.WorkspaceList()
+	if err != nil {
+		return &Result{
+			id:              b.ID(),
+			terraformResult: listResult,
+			invocations:     terraform.Invocations(),
+			err:             err,
+		}
+	}
+
+	parent := &Result{id: b.ID()}
+
+	for _, workspace := range workspaces {
+		if matched, _ := path.Match(pattern, workspace); !matched {
+			continue
+		}
+
+		sub := &Result{id: fmt.Sprintf("%s/%s", b.ID(), workspace)}
+
+		status.send(b.ID(), "Planning workspace %s...", workspace)
+		if result, err := terraform.WorkspaceSelect(workspace); err != nil {
+			sub.terraformResult, sub.err = result, err
+		} else {
+			sub.terraformResult, sub.err = session.retry(b, status, terraform.Plan)
+		}
+
+		if sub.err != nil {
+			parent.err = multierror.Append(parent.err, fmt.Errorf("workspace %s: plan failed", workspace))
+		} else {
+			session.checkPlan(b, terraform, sub, status)
+			if sub.err != nil {
+				parent.err = multierror.Append(parent.err, fmt.Errorf("workspace %s: %w", workspace, sub.err))
+			}
+		}
+		parent.subResults = append(parent.subResults, sub)
+	}
+
+	if parent.subResults == nil {
+		parent.err = fmt.Errorf("no workspaces match %q", pattern)
+	}
+
+	parent.invocations = terraform.Invocations()
 
-	return status, results, nil
+	return parent
 }
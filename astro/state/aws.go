@@ -0,0 +1,107 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package state
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/uber/astro/astro/logger"
+)
+
+// awsCLI runs the aws command line tool.
+type awsCLI struct {
+	path   string
+	region string
+}
+
+// awsError is the failure of an aws command.
+type awsError struct {
+	command string
+	err     error
+	stderr  string
+}
+
+// Error is the error message, so this satisfies the error interface.
+func (e *awsError) Error() string {
+	return fmt.Sprintf("%s: %v: %s", e.command, e.err, e.stderr)
+}
+
+// isAWSError returns whether err is the failure of an aws command whose
+// output mentions any of the error codes.
+func isAWSError(err error, codes ...string) bool {
+	awsErr, ok := err.(*awsError)
+	if !ok {
+		return false
+	}
+	for _, code := range codes {
+		if strings.Contains(awsErr.stderr, code) {
+			return true
+		}
+	}
+	return false
+}
+
+// run runs the aws command and returns its stdout.
+func (a *awsCLI) run(ctx context.Context, args ...string) ([]byte, error) {
+	if a.region != "" {
+		args = append(args, "--region", a.region)
+	}
+
+	logger.Trace.Printf("state: running %v %v", a.path, args)
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	cmd := exec.CommandContext(ctx, a.path, args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, &awsError{
+			command: "aws " + strings.Join(args[:2], " "),
+			err:     err,
+			stderr:  strings.TrimSpace(stderr.String()),
+		}
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// withTempFile calls fn with the path of a temporary file that contains
+// data, for arguments that aws reads from a file.
+func withTempFile(data []byte, fn func(path string) error) error {
+	f, err := os.CreateTemp("", "astro-state-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return fn(f.Name())
+}
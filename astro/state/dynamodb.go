@@ -0,0 +1,172 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// maxDynamoDBValue is the largest value that fits in an item, which
+// DynamoDB limits to 400 KB including its key.
+const maxDynamoDBValue = 390 * 1024
+
+// dynamoDB stores each value in an item of a table, whose partition key
+// is the string "key" and whose value is the binary "data". Create uses a
+// condition expression, so it is atomic.
+type dynamoDB struct {
+	table string
+	aws   *awsCLI
+}
+
+// dynamoDBValue is an attribute value, as the aws command reads and
+// writes it. Binary values are base64 encoded, which encoding/json does
+// for []byte.
+type dynamoDBValue struct {
+	S *string `json:",omitempty"`
+	B []byte  `json:",omitempty"`
+}
+
+// dynamoDBItem is an item of the table.
+type dynamoDBItem struct {
+	Key  dynamoDBValue  `json:"key"`
+	Data *dynamoDBValue `json:"data,omitempty"`
+}
+
+// Name describes the backend, for messages.
+func (d *dynamoDB) Name() string {
+	return fmt.Sprintf("DynamoDB table %s", d.table)
+}
+
+// itemKey returns the key of the item of the key, as JSON.
+func itemKey(key string) string {
+	b, _ := json.Marshal(dynamoDBItem{Key: dynamoDBValue{S: &key}})
+	return string(b)
+}
+
+// Get returns the value of the key, or ErrNotExist.
+func (d *dynamoDB) Get(ctx context.Context, key string) ([]byte, error) {
+	out, err := d.aws.run(ctx, "dynamodb", "get-item", "--table-name", d.table, "--key", itemKey(key), "--consistent-read", "--output", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Item *dynamoDBItem
+	}
+	if err := json.Unmarshal(out, &response); err != nil {
+		return nil, fmt.Errorf("unable to parse item: %v", err)
+	}
+	if response.Item == nil {
+		return nil, ErrNotExist
+	}
+	if response.Item.Data == nil {
+		return []byte{}, nil
+	}
+
+	return response.Item.Data.B, nil
+}
+
+// Put sets the value of the key.
+func (d *dynamoDB) Put(ctx context.Context, key string, data []byte) error {
+	return d.putItem(ctx, key, data, nil)
+}
+
+// Create sets the value of the key only if it has no item.
+func (d *dynamoDB) Create(ctx context.Context, key string, data []byte) error {
+	err := d.putItem(ctx, key, data, map[string]interface{}{
+		"ConditionExpression":      "attribute_not_exists(#k)",
+		"ExpressionAttributeNames": map[string]string{"#k": "key"},
+	})
+	if isAWSError(err, "ConditionalCheckFailedException") {
+		return ErrExist
+	}
+	return err
+}
+
+// putItem writes the item of the key, with the extra parameters of the
+// request. The request is passed in a file, as values can be larger than
+// an argument can be.
+func (d *dynamoDB) putItem(ctx context.Context, key string, data []byte, params map[string]interface{}) error {
+	if len(data) > maxDynamoDBValue {
+		return fmt.Errorf("value of %s is too large for DynamoDB: %d bytes", key, len(data))
+	}
+
+	item := dynamoDBItem{Key: dynamoDBValue{S: &key}}
+	if len(data) > 0 {
+		item.Data = &dynamoDBValue{B: data}
+	}
+
+	input := map[string]interface{}{
+		"TableName": d.table,
+		"Item":      item,
+	}
+	for name, value := range params {
+		input[name] = value
+	}
+
+	b, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+
+	return withTempFile(b, func(path string) error {
+		_, err := d.aws.run(ctx, "dynamodb", "put-item", "--cli-input-json", "file://"+path)
+		return err
+	})
+}
+
+// Delete removes the item of the key.
+func (d *dynamoDB) Delete(ctx context.Context, key string) error {
+	_, err := d.aws.run(ctx, "dynamodb", "delete-item", "--table-name", d.table, "--key", itemKey(key))
+	return err
+}
+
+// List returns the keys that start with prefix, sorted. This scans the
+// table, which the aws command does page by page.
+func (d *dynamoDB) List(ctx context.Context, prefix string) ([]string, error) {
+	args := []string{"dynamodb", "scan", "--table-name", d.table, "--consistent-read",
+		"--projection-expression", "#k", "--expression-attribute-names", `{"#k":"key"}`, "--output", "json"}
+	if prefix != "" {
+		values, _ := json.Marshal(map[string]dynamoDBValue{":prefix": {S: &prefix}})
+		args = append(args, "--filter-expression", "begins_with(#k, :prefix)", "--expression-attribute-values", string(values))
+	}
+
+	out, err := d.aws.run(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Items []dynamoDBItem
+	}
+	if err := json.Unmarshal(out, &response); err != nil {
+		return nil, fmt.Errorf("unable to parse items: %v", err)
+	}
+
+	var keys []string
+	for _, item := range response.Items {
+		if item.Key.S != nil {
+			keys = append(keys, *item.Key.S)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys, nil
+}
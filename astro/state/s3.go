@@ -0,0 +1,122 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// s3 stores each value in an object under a prefix of a bucket. Create
+// uses a conditional write, so it is atomic.
+type s3 struct {
+	bucket string
+	prefix string
+	aws    *awsCLI
+}
+
+// newS3 returns a backend for the s3://bucket/prefix URL.
+func newS3(rawURL string, aws *awsCLI) (*s3, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := strings.Trim(u.Path, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	return &s3{bucket: u.Host, prefix: prefix, aws: aws}, nil
+}
+
+// Name describes the backend, for messages.
+func (s *s3) Name() string {
+	return fmt.Sprintf("s3://%s/%s", s.bucket, s.prefix)
+}
+
+// Get returns the value of the key, or ErrNotExist.
+func (s *s3) Get(ctx context.Context, key string) ([]byte, error) {
+	f, err := os.CreateTemp("", "astro-state-*")
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	_, err = s.aws.run(ctx, "s3api", "get-object", "--bucket", s.bucket, "--key", s.prefix+key, f.Name())
+	if isAWSError(err, "NoSuchKey") {
+		return nil, ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return os.ReadFile(f.Name())
+}
+
+// Put sets the value of the key.
+func (s *s3) Put(ctx context.Context, key string, data []byte) error {
+	return withTempFile(data, func(path string) error {
+		_, err := s.aws.run(ctx, "s3api", "put-object", "--bucket", s.bucket, "--key", s.prefix+key, "--body", path)
+		return err
+	})
+}
+
+// Create sets the value of the key only if the object doesn't exist.
+func (s *s3) Create(ctx context.Context, key string, data []byte) error {
+	return withTempFile(data, func(path string) error {
+		_, err := s.aws.run(ctx, "s3api", "put-object", "--bucket", s.bucket, "--key", s.prefix+key, "--body", path, "--if-none-match", "*")
+		if isAWSError(err, "PreconditionFailed", "ConditionalRequestConflict") {
+			return ErrExist
+		}
+		return err
+	})
+}
+
+// Delete removes the object of the key.
+func (s *s3) Delete(ctx context.Context, key string) error {
+	_, err := s.aws.run(ctx, "s3api", "delete-object", "--bucket", s.bucket, "--key", s.prefix+key)
+	return err
+}
+
+// List returns the keys that start with prefix, sorted. The aws command
+// fetches every page of the listing.
+func (s *s3) List(ctx context.Context, prefix string) ([]string, error) {
+	out, err := s.aws.run(ctx, "s3api", "list-objects-v2", "--bucket", s.bucket, "--prefix", s.prefix+prefix, "--query", "Contents[].Key", "--output", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []string
+	if err := json.Unmarshal(out, &objects); err != nil {
+		return nil, fmt.Errorf("unable to parse object list: %v", err)
+	}
+
+	keys := make([]string, 0, len(objects))
+	for _, object := range objects {
+		keys = append(keys, strings.TrimPrefix(object, s.prefix))
+	}
+	sort.Strings(keys)
+
+	return keys, nil
+}
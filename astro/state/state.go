@@ -0,0 +1,137 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package state stores astro's own metadata, e.g. the history of sessions
+// and the lock that stops runs from overlapping, in a backend that
+// outlives the machine astro runs on. The S3 and DynamoDB backends shell
+// out to the aws command line tool, so that its usual credential
+// configuration applies.
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/uber/astro/astro/conf"
+)
+
+var (
+	// ErrNotExist is returned when a key has no value.
+	ErrNotExist = errors.New("does not exist")
+	// ErrExist is returned by Create when a key already has a value.
+	ErrExist = errors.New("already exists")
+)
+
+// Backend stores values by key. Keys are slash separated paths, e.g.
+// sessions/01E2Q5HXW3TGNWAJ9T3V0MB4T4/astro.log.
+type Backend interface {
+	// Name describes the backend, for messages.
+	Name() string
+	// Get returns the value of the key, or ErrNotExist.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Put sets the value of the key.
+	Put(ctx context.Context, key string, data []byte) error
+	// Create sets the value of the key only if it has none, or returns
+	// ErrExist. It is atomic, so that it can be used for locking.
+	Create(ctx context.Context, key string, data []byte) error
+	// Delete removes the key. Removing a key without a value is not an
+	// error.
+	Delete(ctx context.Context, key string) error
+	// List returns the keys that start with prefix, sorted.
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// New returns the backend for the configuration. awsCLIPath is the aws
+// command line tool that the S3 and DynamoDB backends run.
+func New(config conf.State, awsCLIPath string) (Backend, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	aws := &awsCLI{path: awsCLIPath, region: config.Region}
+	if aws.path == "" {
+		aws.path = "aws"
+	}
+
+	switch config.Backend {
+	case conf.StateBackendFilesystem:
+		return NewFilesystem(config.Path), nil
+	case conf.StateBackendS3:
+		return newS3(config.URL, aws)
+	case conf.StateBackendDynamoDB:
+		return &dynamoDB{table: config.Table, aws: aws}, nil
+	}
+
+	// Validate rejects any other backend
+	return nil, fmt.Errorf("unsupported backend: %q", config.Backend)
+}
+
+// LockInfo describes the holder of a lock.
+type LockInfo struct {
+	// Owner is who holds the lock, e.g. user@host.
+	Owner string `json:"owner"`
+	// Command is the astro command that holds the lock, e.g. apply.
+	Command string `json:"command"`
+	// Session is the ID of the session the command runs in.
+	Session string `json:"session"`
+	// Created is when the lock was taken.
+	Created time.Time `json:"created"`
+}
+
+// LockedError is returned when a lock is held by another run.
+type LockedError struct {
+	Key  string
+	Info LockInfo
+}
+
+// Error is the error message, so this satisfies the error interface.
+func (e *LockedError) Error() string {
+	if e.Info.Command == "" {
+		return fmt.Sprintf("%s is locked", e.Key)
+	}
+	return fmt.Sprintf("%s is locked by %s of session %s, run by %s at %s",
+		e.Key, e.Info.Command, e.Info.Session, e.Info.Owner, e.Info.Created.Format(time.RFC3339))
+}
+
+// Lock takes the lock named key in the backend, or returns a *LockedError
+// describing its holder.
+func Lock(ctx context.Context, backend Backend, key string, info LockInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	err = backend.Create(ctx, key, data)
+	if !errors.Is(err, ErrExist) {
+		return err
+	}
+
+	lockedErr := &LockedError{Key: key}
+	// the holder is only for the message, so a lock that can't be read
+	// is still locked
+	if data, err := backend.Get(ctx, key); err == nil {
+		json.Unmarshal(data, &lockedErr.Info)
+	}
+	return lockedErr
+}
+
+// Unlock releases the lock named key in the backend, whoever holds it.
+func Unlock(ctx context.Context, backend Backend, key string) error {
+	return backend.Delete(ctx, key)
+}
@@ -0,0 +1,218 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package state_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilesystem(t *testing.T) {
+	ctx := context.Background()
+	backend := state.NewFilesystem(filepath.Join(t.TempDir(), "state"))
+
+	_, err := backend.Get(ctx, "sessions/1/git-sha")
+	assert.Equal(t, state.ErrNotExist, err)
+
+	keys, err := backend.List(ctx, "")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	require.NoError(t, backend.Put(ctx, "sessions/1/git-sha", []byte("abc123")))
+	require.NoError(t, backend.Put(ctx, "sessions/1/app/logs/plan.log", []byte("plan")))
+	require.NoError(t, backend.Put(ctx, "sessions/2/git-sha", []byte("def456")))
+
+	data, err := backend.Get(ctx, "sessions/1/git-sha")
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", string(data))
+
+	keys, err = backend.List(ctx, "sessions/1/")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sessions/1/app/logs/plan.log", "sessions/1/git-sha"}, keys)
+
+	require.NoError(t, backend.Create(ctx, "lock", []byte("1")))
+	assert.Equal(t, state.ErrExist, backend.Create(ctx, "lock", []byte("2")))
+	require.NoError(t, backend.Delete(ctx, "lock"))
+	require.NoError(t, backend.Delete(ctx, "lock"))
+
+	for _, key := range []string{"", "../outside", "sessions/../lock", "/lock"} {
+		assert.Error(t, backend.Put(ctx, key, nil), key)
+	}
+}
+
+func TestLock(t *testing.T) {
+	ctx := context.Background()
+	backend := state.NewFilesystem(t.TempDir())
+
+	info := state.LockInfo{
+		Owner:   "ci@runner-1",
+		Command: "apply",
+		Session: "01E2Q5HXW3TGNWAJ9T3V0MB4T4",
+		Created: time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+	require.NoError(t, state.Lock(ctx, backend, "lock", info))
+
+	err := state.Lock(ctx, backend, "lock", state.LockInfo{Owner: "ci@runner-2"})
+	lockedErr, ok := err.(*state.LockedError)
+	require.True(t, ok)
+	assert.Equal(t, info, lockedErr.Info)
+	assert.EqualError(t, err, "lock is locked by apply of session 01E2Q5HXW3TGNWAJ9T3V0MB4T4, run by ci@runner-1 at 2019-03-01T12:00:00Z")
+
+	require.NoError(t, state.Unlock(ctx, backend, "lock"))
+	require.NoError(t, state.Lock(ctx, backend, "lock", info))
+}
+
+// fakeAWS installs an aws CLI that logs its arguments and runs script,
+// and returns the path of the log.
+func fakeAWS(t *testing.T, script string) (awsPath string, callLog string) {
+	binDir := t.TempDir()
+	callLog = filepath.Join(binDir, "calls")
+	awsPath = filepath.Join(binDir, "aws")
+
+	require.NoError(t, os.WriteFile(awsPath, []byte(`#!/bin/sh
+echo "$@" >> `+callLog+`
+`+script), 0755))
+
+	return awsPath, callLog
+}
+
+// calls returns the commands that the fake aws CLI ran.
+func calls(t *testing.T, callLog string) []string {
+	data, err := os.ReadFile(callLog)
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestS3(t *testing.T) {
+	ctx := context.Background()
+	awsPath, callLog := fakeAWS(t, `
+case "$2" in
+get-object)
+	if [ "$6" = astro/missing ]; then
+		echo "An error occurred (NoSuchKey) when calling the GetObject operation" >&2
+		exit 255
+	fi
+	echo -n abc123 > "$7" ;;
+put-object)
+	if [ "$9" = --if-none-match ]; then
+		echo "An error occurred (PreconditionFailed) when calling the PutObject operation" >&2
+		exit 255
+	fi ;;
+list-objects-v2)
+	echo '["astro/sessions/2/git-sha", "astro/sessions/1/git-sha"]' ;;
+esac
+`)
+
+	backend, err := state.New(conf.State{Backend: conf.StateBackendS3, URL: "s3://bucket/astro", Region: "us-west-2"}, awsPath)
+	require.NoError(t, err)
+	assert.Equal(t, "s3://bucket/astro/", backend.Name())
+
+	data, err := backend.Get(ctx, "sessions/1/git-sha")
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", string(data))
+
+	_, err = backend.Get(ctx, "missing")
+	assert.Equal(t, state.ErrNotExist, err)
+
+	require.NoError(t, backend.Put(ctx, "sessions/1/git-sha", []byte("abc123")))
+	assert.Equal(t, state.ErrExist, backend.Create(ctx, "lock", []byte("{}")))
+	require.NoError(t, backend.Delete(ctx, "lock"))
+
+	keys, err := backend.List(ctx, "sessions/")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sessions/1/git-sha", "sessions/2/git-sha"}, keys)
+
+	commands := calls(t, callLog)
+	require.Len(t, commands, 6)
+	assert.Regexp(t, `^s3api get-object --bucket bucket --key astro/sessions/1/git-sha \S+ --region us-west-2$`, commands[0])
+	assert.Regexp(t, `^s3api put-object --bucket bucket --key astro/sessions/1/git-sha --body \S+ --region us-west-2$`, commands[2])
+	assert.Regexp(t, `^s3api put-object --bucket bucket --key astro/lock --body \S+ --if-none-match \* --region us-west-2$`, commands[3])
+	assert.Equal(t, "s3api delete-object --bucket bucket --key astro/lock --region us-west-2", commands[4])
+	assert.Equal(t, "s3api list-objects-v2 --bucket bucket --prefix astro/sessions/ --query Contents[].Key --output json --region us-west-2", commands[5])
+}
+
+func TestDynamoDB(t *testing.T) {
+	ctx := context.Background()
+	awsPath, callLog := fakeAWS(t, `
+case "$2" in
+get-item)
+	case "$6" in
+	*missing*) echo '{}' ;;
+	*) echo '{"Item": {"key": {"S": "lock"}, "data": {"B": "eyJvd25lciI6ImNpQHJ1bm5lci0xIn0="}}}' ;;
+	esac ;;
+put-item)
+	cat "${4#file://}" >> `+"`dirname $0`"+`/inputs
+	echo >> `+"`dirname $0`"+`/inputs
+	if grep -q ConditionExpression "${4#file://}"; then
+		echo "An error occurred (ConditionalCheckFailedException) when calling the PutItem operation" >&2
+		exit 255
+	fi ;;
+scan)
+	echo '{"Items": [{"key": {"S": "sessions/2/git-sha"}}, {"key": {"S": "sessions/1/git-sha"}}]}' ;;
+esac
+`)
+
+	backend, err := state.New(conf.State{Backend: conf.StateBackendDynamoDB, Table: "astro"}, awsPath)
+	require.NoError(t, err)
+
+	data, err := backend.Get(ctx, "lock")
+	require.NoError(t, err)
+	assert.Equal(t, `{"owner":"ci@runner-1"}`, string(data))
+
+	_, err = backend.Get(ctx, "missing")
+	assert.Equal(t, state.ErrNotExist, err)
+
+	require.NoError(t, backend.Put(ctx, "sessions/1/git-sha", []byte("abc123")))
+	assert.Equal(t, state.ErrExist, backend.Create(ctx, "lock", []byte("{}")))
+
+	keys, err := backend.List(ctx, "sessions/")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sessions/1/git-sha", "sessions/2/git-sha"}, keys)
+
+	assert.Error(t, backend.Put(ctx, "large", make([]byte, 400*1024)))
+
+	commands := calls(t, callLog)
+	require.Len(t, commands, 5)
+	assert.Equal(t, `dynamodb get-item --table-name astro --key {"key":{"S":"lock"}} --consistent-read --output json`, commands[0])
+	assert.Equal(t, `dynamodb scan --table-name astro --consistent-read --projection-expression #k --expression-attribute-names {"#k":"key"} --output json `+
+		`--filter-expression begins_with(#k, :prefix) --expression-attribute-values {":prefix":{"S":"sessions/"}}`, commands[4])
+
+	inputs, err := os.ReadFile(filepath.Join(filepath.Dir(awsPath), "inputs"))
+	require.NoError(t, err)
+	assert.Equal(t,
+		`{"Item":{"key":{"S":"sessions/1/git-sha"},"data":{"B":"YWJjMTIz"}},"TableName":"astro"}`+"\n"+
+			`{"ConditionExpression":"attribute_not_exists(#k)","ExpressionAttributeNames":{"#k":"key"},"Item":{"key":{"S":"lock"},"data":{"B":"e30="}},"TableName":"astro"}`+"\n",
+		string(inputs))
+}
+
+func TestNewInvalid(t *testing.T) {
+	_, err := state.New(conf.State{Backend: "consul"}, "")
+	assert.EqualError(t, err, `unsupported backend "consul"; must be filesystem, s3 or dynamodb`)
+
+	_, err = state.New(conf.State{Backend: conf.StateBackendS3, URL: "https://bucket"}, "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,272 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/uber/astro/astro/utils"
+)
+
+// maxErrorSignatureLength is the length error signatures are cut to.
+const maxErrorSignatureLength = 120
+
+var (
+	// reTerraformError matches the line of Terraform's output that says
+	// what went wrong.
+	reTerraformError = regexp.MustCompile(`(?m)^\s*Error:\s*(.+)$`)
+	// reErrorDetails matches the parts of an error that vary from one
+	// occurrence of it to the next: quoted values, IDs and numbers.
+	reErrorDetails = regexp.MustCompile(`"[^"]*"|'[^']*'|\b(?:[a-z]+-)?[0-9a-f]{8,}\b|\d+`)
+	// reANSI matches the color codes in Terraform's output.
+	reANSI = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+)
+
+// Stats summarizes the runs in the session history of a project, so that
+// the modules that fail most often can be found.
+type Stats struct {
+	// Since is when the earliest run that counts started; it is zero if
+	// all of the history counts.
+	Since time.Time
+	// Sessions and Runs are the number of sessions and of runs in them
+	// that were counted.
+	Sessions int
+	Runs     int
+	// Modules is the stats of each module that ran, by name.
+	Modules []ModuleStats
+	// Errors is the errors that executions failed with, most frequent
+	// first.
+	Errors []ErrorStats
+}
+
+// ModuleStats is how the executions of a module did across runs.
+type ModuleStats struct {
+	Name string
+	// Executions is the number of times an execution of the module ran
+	// or was skipped, of which Failed failed and Skipped were skipped.
+	Executions int
+	Failed     int
+	Skipped    int
+	// Flips is the number of times an execution of the module failed
+	// after it succeeded in its previous run, or the other way around.
+	Flips int
+	// Reruns is the number of executions that had a previous run, which
+	// could have flipped.
+	Reruns int
+	// TotalDuration is how long the Terraform commands of the executions
+	// took, over Timed of them.
+	TotalDuration time.Duration
+	Timed         int
+}
+
+// FailureRate returns the fraction of the executions of the module that
+// ran which failed.
+func (m ModuleStats) FailureRate() float64 {
+	ran := m.Executions - m.Skipped
+	if ran == 0 {
+		return 0
+	}
+	return float64(m.Failed) / float64(ran)
+}
+
+// Flakiness returns the fraction of the reruns of executions of the
+// module whose outcome differed from their previous run.
+func (m ModuleStats) Flakiness() float64 {
+	if m.Reruns == 0 {
+		return 0
+	}
+	return float64(m.Flips) / float64(m.Reruns)
+}
+
+// AverageDuration returns how long the Terraform commands of the module
+// took on average, or 0 if none were timed.
+func (m ModuleStats) AverageDuration() time.Duration {
+	if m.Timed == 0 {
+		return 0
+	}
+	return m.TotalDuration / time.Duration(m.Timed)
+}
+
+// ErrorStats is how often executions failed with errors that have the
+// same signature.
+type ErrorStats struct {
+	Signature string
+	Count     int
+	// Modules is the modules whose executions failed with the error,
+	// sorted.
+	Modules []string
+}
+
+// Flakiest returns the modules whose executions flipped between failing
+// and succeeding at least once, flakiest first.
+func (s *Stats) Flakiest() []ModuleStats {
+	var flaky []ModuleStats
+	for _, module := range s.Modules {
+		if module.Flips > 0 {
+			flaky = append(flaky, module)
+		}
+	}
+	sort.SliceStable(flaky, func(i, j int) bool {
+		if flaky[i].Flakiness() != flaky[j].Flakiness() {
+			return flaky[i].Flakiness() > flaky[j].Flakiness()
+		}
+		return flaky[i].Flips > flaky[j].Flips
+	})
+	return flaky
+}
+
+// Stats returns the stats of the runs in the session history that started
+// within since of now, or of all of them if since is 0.
+func (c *Project) Stats(since time.Duration) (*Stats, error) {
+	sessions, err := c.Sessions()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &Stats{}
+	if since > 0 {
+		stats.Since = c.clock.Now().Add(-since).UTC()
+	}
+
+	var runs []RunStats
+	for _, session := range sessions {
+		counted := false
+		for _, run := range session.Runs {
+			if run.Started.Before(stats.Since) {
+				continue
+			}
+			runs = append(runs, run)
+			counted = true
+		}
+		if counted {
+			stats.Sessions++
+		}
+	}
+	stats.Runs = len(runs)
+
+	// Flips are counted in the order the runs happened
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].Started.Before(runs[j].Started)
+	})
+
+	modules := map[string]*ModuleStats{}
+	errorsBySignature := map[string]*ErrorStats{}
+	lastFailed := map[string]bool{}
+	for _, run := range runs {
+		for _, result := range run.Results {
+			module, ok := modules[result.Module]
+			if !ok {
+				module = &ModuleStats{Name: result.Module}
+				modules[result.Module] = module
+			}
+
+			module.Executions++
+			if result.Skipped {
+				module.Skipped++
+				continue
+			}
+			if result.Duration > 0 {
+				module.TotalDuration += result.Duration
+				module.Timed++
+			}
+
+			failed := result.Error != ""
+			if previous, ok := lastFailed[result.ID]; ok {
+				module.Reruns++
+				if previous != failed {
+					module.Flips++
+				}
+			}
+			lastFailed[result.ID] = failed
+
+			if !failed {
+				continue
+			}
+			module.Failed++
+
+			signature := errorSignature(result.Error)
+			errorStats, ok := errorsBySignature[signature]
+			if !ok {
+				errorStats = &ErrorStats{Signature: signature}
+				errorsBySignature[signature] = errorStats
+			}
+			errorStats.Count++
+			if !utils.StringSliceContains(errorStats.Modules, result.Module) {
+				errorStats.Modules = append(errorStats.Modules, result.Module)
+			}
+		}
+	}
+
+	for _, module := range modules {
+		stats.Modules = append(stats.Modules, *module)
+	}
+	sort.Slice(stats.Modules, func(i, j int) bool {
+		return stats.Modules[i].Name < stats.Modules[j].Name
+	})
+
+	for _, errorStats := range errorsBySignature {
+		sort.Strings(errorStats.Modules)
+		stats.Errors = append(stats.Errors, *errorStats)
+	}
+	sort.Slice(stats.Errors, func(i, j int) bool {
+		if stats.Errors[i].Count != stats.Errors[j].Count {
+			return stats.Errors[i].Count > stats.Errors[j].Count
+		}
+		return stats.Errors[i].Signature < stats.Errors[j].Signature
+	})
+
+	return stats, nil
+}
+
+// errorSignature returns what the error is about, without the details
+// that vary between occurrences of it, so that the same failure is
+// counted once however many resources or runs it happened to. It is the
+// first error Terraform reported, or the first line of the error.
+func errorSignature(err string) string {
+	err = reANSI.ReplaceAllString(err, "")
+
+	signature := ""
+	if match := reTerraformError.FindStringSubmatch(err); match != nil {
+		signature = match[1]
+	} else {
+		for _, line := range strings.Split(err, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				signature = line
+				break
+			}
+		}
+	}
+
+	signature = reErrorDetails.ReplaceAllStringFunc(signature, func(detail string) string {
+		switch {
+		case detail[0] == '"' || detail[0] == '\'':
+			return detail[:1] + "*" + detail[:1]
+		case strings.Trim(detail, "0123456789") == "":
+			return "N"
+		}
+		return "*"
+	})
+	signature = strings.TrimSpace(signature)
+
+	if len(signature) > maxErrorSignatureLength {
+		signature = signature[:maxErrorSignatureLength] + "..."
+	}
+	return signature
+}
@@ -0,0 +1,131 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestRuns writes a session to the session repo of the project that
+// started at the time, in which the runs were recorded.
+func writeTestRuns(t *testing.T, c *Project, started time.Time, runs ...RunStats) {
+	sessionPath := filepath.Join(c.sessions.path, utils.ULIDAt(started).String())
+	require.NoError(t, os.MkdirAll(sessionPath, 0755))
+	data, err := json.Marshal(runs)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(sessionPath, runsFile), data, 0644))
+}
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2019, 3, 31, 12, 0, 0, 0, time.UTC)
+	c := &Project{config: &conf.Project{}, clock: testClock{now: now}}
+	sessions, err := NewSessionRepo(c, filepath.Join(t.TempDir(), ".astro"), nil)
+	require.NoError(t, err)
+	c.sessions = sessions
+
+	run := func(started time.Time, results ...ExecutionRunStats) RunStats {
+		return RunStats{Command: "plan", Started: started, Results: results}
+	}
+	failed := func(id, module, err string) ExecutionRunStats {
+		return ExecutionRunStats{ID: id, Module: module, Error: err, Duration: time.Minute}
+	}
+	succeeded := func(id, module string, duration time.Duration) ExecutionRunStats {
+		return ExecutionRunStats{ID: id, Module: module, Duration: duration}
+	}
+
+	// too old to count
+	old := now.Add(-40 * 24 * time.Hour)
+	writeTestRuns(t, c, old, run(old, failed("app-dev", "app", "Error: old")))
+
+	first := now.Add(-3 * 24 * time.Hour)
+	writeTestRuns(t, c, first,
+		run(first,
+			succeeded("app-dev", "app", 10*time.Second),
+			failed("network-dev", "network", "exit status 1\nError: creating VPC (vpc-0123456789abcdef0): LimitExceeded: 5 VPCs\n"),
+		),
+		run(first.Add(time.Hour),
+			failed("app-dev", "app", "Error: timeout while waiting for state \"available\" after 10m"),
+			failed("network-dev", "network", "Error: creating VPC (vpc-0fedcba9876543210): LimitExceeded: 6 VPCs"),
+		),
+	)
+
+	second := now.Add(-24 * time.Hour)
+	writeTestRuns(t, c, second,
+		run(second,
+			succeeded("app-dev", "app", 20*time.Second),
+			ExecutionRunStats{ID: "network-dev", Module: "network", Skipped: true},
+		),
+	)
+
+	stats, err := c.Stats(30 * 24 * time.Hour)
+	require.NoError(t, err)
+
+	assert.Equal(t, now.Add(-30*24*time.Hour), stats.Since)
+	assert.Equal(t, 2, stats.Sessions)
+	assert.Equal(t, 3, stats.Runs)
+
+	require.Len(t, stats.Modules, 2)
+	app, network := stats.Modules[0], stats.Modules[1]
+	assert.Equal(t, "app", app.Name)
+	assert.Equal(t, 3, app.Executions)
+	assert.Equal(t, 1, app.Failed)
+	assert.InDelta(t, 1.0/3, app.FailureRate(), 0.001)
+	assert.Equal(t, 2, app.Flips)
+	assert.Equal(t, 1.0, app.Flakiness())
+	assert.Equal(t, (10*time.Second+time.Minute+20*time.Second)/3, app.AverageDuration())
+
+	assert.Equal(t, "network", network.Name)
+	assert.Equal(t, 3, network.Executions)
+	assert.Equal(t, 1, network.Skipped)
+	assert.Equal(t, 1.0, network.FailureRate())
+	assert.Equal(t, 0, network.Flips)
+
+	flakiest := stats.Flakiest()
+	require.Len(t, flakiest, 1)
+	assert.Equal(t, "app", flakiest[0].Name)
+
+	require.Len(t, stats.Errors, 2)
+	assert.Equal(t, ErrorStats{Signature: "creating VPC (*): LimitExceeded: N VPCs", Count: 2, Modules: []string{"network"}}, stats.Errors[0])
+	assert.Equal(t, ErrorStats{Signature: `timeout while waiting for state "*" after Nm`, Count: 1, Modules: []string{"app"}}, stats.Errors[1])
+
+	// all of the history
+	stats, err = c.Stats(0)
+	require.NoError(t, err)
+	assert.True(t, stats.Since.IsZero())
+	assert.Equal(t, 3, stats.Sessions)
+	assert.Equal(t, 4, stats.Runs)
+}
+
+func TestErrorSignature(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "no such file", errorSignature("\n  no such file\nexit status 1"))
+	assert.Equal(t, "Invalid reference", errorSignature("\x1b[31mError: \x1b[0mInvalid reference\n\n  on main.tf line 3"))
+	assert.Equal(t, "module '*' has N errors", errorSignature("module 'app' has 3 errors"))
+}
@@ -0,0 +1,64 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/uber/astro/astro/logger"
+)
+
+// statusQueueSize is the number of status updates that are buffered for
+// the consumer before further updates are dropped.
+const statusQueueSize = 100
+
+// statusQueue is a bounded queue of status updates. Sending to it never
+// blocks: if the consumer of the channel is not keeping up, or isn't
+// reading from it at all, updates are dropped rather than stalling the
+// executions that produce them.
+type statusQueue struct {
+	ch      chan string
+	dropped uint64
+}
+
+func newStatusQueue() *statusQueue {
+	return &statusQueue{
+		ch: make(chan string, statusQueueSize),
+	}
+}
+
+// send queues a status update for the execution with the specified ID,
+// or drops it if the queue is full.
+func (q *statusQueue) send(id string, format string, args ...interface{}) {
+	update := fmt.Sprintf("[%s] %s", id, fmt.Sprintf(format, args...))
+
+	select {
+	case q.ch <- update:
+	default:
+		atomic.AddUint64(&q.dropped, 1)
+	}
+}
+
+// close closes the channel, which signals the consumer that there will be
+// no more updates. It must only be called once all producers are done.
+func (q *statusQueue) close() {
+	if dropped := atomic.LoadUint64(&q.dropped); dropped > 0 {
+		logger.Debug("dropped status updates as the queue was full", logger.Fields{"dropped": dropped})
+	}
+	close(q.ch)
+}
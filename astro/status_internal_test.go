@@ -0,0 +1,72 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusQueueDropsWhenFull(t *testing.T) {
+	q := newStatusQueue()
+
+	// Nothing is consuming from the channel, so this would block if
+	// sending wasn't fail-safe.
+	for i := 0; i < statusQueueSize+5; i++ {
+		q.send("app", "update %d", i)
+	}
+	q.close()
+
+	var updates []string
+	for update := range q.ch {
+		updates = append(updates, update)
+	}
+
+	assert.Len(t, updates, statusQueueSize)
+	assert.Equal(t, "[app] update 0", updates[0])
+	assert.Equal(t, uint64(5), q.dropped)
+}
+
+func TestCollect(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-plan-success/astro.yaml")
+	require.NoError(t, err)
+
+	c.config.TerraformDefaults.Path = absolutePath("fixtures/mock-terraform/success")
+
+	status, results, err := c.Plan(PlanExecutionParameters{
+		ExecutionParameters: ExecutionParameters{
+			UserVars: &UserVariables{
+				Values: map[string]string{
+					"aws_region": "east1",
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	collected := Collect(status, results)
+	require.Len(t, collected, 12)
+
+	for _, result := range collected {
+		assert.NoError(t, result.Err(), fmt.Sprintf("execution %s failed", result.ID()))
+	}
+}
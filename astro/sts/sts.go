@@ -0,0 +1,196 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package sts assumes AWS IAM roles with the AWS CLI, for the temporary
+// credentials that Terraform runs with, and looks up the identity that
+// credentials belong to.
+package sts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/exec2"
+	"github.com/uber/astro/astro/logger"
+)
+
+// killTimeout is how long the AWS CLI has to exit after it is interrupted
+// before it is killed.
+const killTimeout = 10 * time.Second
+
+// expiryWindow is how long before they expire credentials stop being
+// reused, so that they don't expire while Terraform runs.
+const expiryWindow = 5 * time.Minute
+
+// Credentials are the temporary credentials of an assumed role.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      time.Time
+}
+
+// Environment returns the environment variables that make Terraform use
+// the credentials.
+func (c *Credentials) Environment() map[string]string {
+	return map[string]string{
+		"AWS_ACCESS_KEY_ID":     c.AccessKeyID,
+		"AWS_SECRET_ACCESS_KEY": c.SecretAccessKey,
+		"AWS_SESSION_TOKEN":     c.SessionToken,
+	}
+}
+
+// Valid returns whether the credentials can still be used at now.
+func (c *Credentials) Valid(now time.Time) bool {
+	return now.Add(expiryWindow).Before(c.Expiration)
+}
+
+// assumeRoleOutput is the part of the output of `aws sts assume-role` that
+// astro uses.
+type assumeRoleOutput struct {
+	Credentials *struct {
+		AccessKeyID     string `json:"AccessKeyId"`
+		SecretAccessKey string `json:"SecretAccessKey"`
+		SessionToken    string `json:"SessionToken"`
+		Expiration      string `json:"Expiration"`
+	} `json:"Credentials"`
+}
+
+// AssumeRole assumes the role with the AWS CLI at cliPath, or aws in the
+// PATH if it's empty. env is the environment the CLI runs with, e.g. with
+// the profile that is allowed to assume the role.
+func AssumeRole(ctx context.Context, cliPath string, env []string, role conf.AWSCredentials, sessionName string) (*Credentials, error) {
+	args := []string{"sts", "assume-role",
+		"--role-arn", role.RoleARN,
+		"--role-session-name", sessionName,
+		"--output", "json",
+	}
+	if role.ExternalID != "" {
+		args = append(args, "--external-id", role.ExternalID)
+	}
+	if duration := role.Duration(); duration > 0 {
+		args = append(args, "--duration-seconds", strconv.Itoa(int(duration.Seconds())))
+	}
+
+	stdout, err := run(ctx, cliPath, env, args)
+	if err != nil {
+		return nil, fmt.Errorf("unable to assume role %v: %v", role.RoleARN, err)
+	}
+
+	credentials, err := parseAssumeRole(stdout)
+	if err != nil {
+		return nil, fmt.Errorf("unable to assume role %v: %v", role.RoleARN, err)
+	}
+
+	return credentials, nil
+}
+
+// parseAssumeRole returns the credentials in the output of `aws sts
+// assume-role`.
+func parseAssumeRole(data []byte) (*Credentials, error) {
+	var output assumeRoleOutput
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, err
+	}
+	if output.Credentials == nil || output.Credentials.AccessKeyID == "" {
+		return nil, errors.New("no credentials in the output of the aws CLI")
+	}
+
+	expiration, err := time.Parse(time.RFC3339, output.Credentials.Expiration)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expiration: %v", err)
+	}
+
+	return &Credentials{
+		AccessKeyID:     output.Credentials.AccessKeyID,
+		SecretAccessKey: output.Credentials.SecretAccessKey,
+		SessionToken:    output.Credentials.SessionToken,
+		Expiration:      expiration,
+	}, nil
+}
+
+// Identity is the AWS identity that credentials belong to.
+type Identity struct {
+	UserID  string `json:"UserId"`
+	Account string `json:"Account"`
+	ARN     string `json:"Arn"`
+}
+
+// CallerIdentity returns the identity that the AWS CLI at cliPath, or aws
+// in the PATH if it's empty, runs as with the environment env.
+func CallerIdentity(ctx context.Context, cliPath string, env []string) (*Identity, error) {
+	stdout, err := run(ctx, cliPath, env, []string{"sts", "get-caller-identity", "--output", "json"})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get the AWS caller identity: %v", err)
+	}
+
+	identity, err := parseCallerIdentity(stdout)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get the AWS caller identity: %v", err)
+	}
+
+	return identity, nil
+}
+
+// parseCallerIdentity returns the identity in the output of `aws sts
+// get-caller-identity`.
+func parseCallerIdentity(data []byte) (*Identity, error) {
+	var identity Identity
+	if err := json.Unmarshal(data, &identity); err != nil {
+		return nil, err
+	}
+	if identity.ARN == "" {
+		return nil, errors.New("no identity in the output of the aws CLI")
+	}
+	return &identity, nil
+}
+
+// run runs the AWS CLI at cliPath, or aws in the PATH if it's empty, and
+// returns its stdout. If it fails, the error is what it printed to stderr.
+func run(ctx context.Context, cliPath string, env []string, args []string) ([]byte, error) {
+	if cliPath == "" {
+		path, err := exec.LookPath("aws")
+		if err != nil {
+			return nil, errors.New("unable to find the aws CLI: install it, or set aws_cli_path in the config")
+		}
+		cliPath = path
+	}
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+	cmd := exec.Command(cliPath, args...)
+	cmd.Env = env
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	logger.Trace.Printf("sts: running %v", cmd.Args)
+	if err := exec2.RunContext(ctx, cmd, killTimeout); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, errors.New(message)
+		}
+		return nil, err
+	}
+
+	return stdout.Bytes(), nil
+}
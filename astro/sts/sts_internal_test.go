@@ -0,0 +1,68 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAssumeRole(t *testing.T) {
+	credentials, err := parseAssumeRole([]byte(`{
+		"Credentials": {
+			"AccessKeyId": "ASIAEXAMPLE",
+			"SecretAccessKey": "secret",
+			"SessionToken": "token",
+			"Expiration": "2019-11-01T20:26:47+00:00"
+		},
+		"AssumedRoleUser": {"Arn": "arn:aws:sts::123456789012:assumed-role/deploy/astro"}
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"AWS_ACCESS_KEY_ID":     "ASIAEXAMPLE",
+		"AWS_SECRET_ACCESS_KEY": "secret",
+		"AWS_SESSION_TOKEN":     "token",
+	}, credentials.Environment())
+
+	expiration := time.Date(2019, 11, 1, 20, 26, 47, 0, time.UTC)
+	assert.True(t, credentials.Expiration.Equal(expiration))
+	assert.True(t, credentials.Valid(expiration.Add(-time.Hour)))
+	assert.False(t, credentials.Valid(expiration.Add(-time.Minute)))
+
+	_, err = parseAssumeRole([]byte(`{}`))
+	assert.Error(t, err)
+}
+
+func TestParseCallerIdentity(t *testing.T) {
+	identity, err := parseCallerIdentity([]byte(`{
+		"UserId": "AROAEXAMPLE:astro",
+		"Account": "123456789012",
+		"Arn": "arn:aws:sts::123456789012:assumed-role/deploy/astro"
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, &Identity{
+		UserID:  "AROAEXAMPLE:astro",
+		Account: "123456789012",
+		ARN:     "arn:aws:sts::123456789012:assumed-role/deploy/astro",
+	}, identity)
+
+	_, err = parseCallerIdentity([]byte(`{}`))
+	assert.Error(t, err)
+}
@@ -72,6 +72,19 @@ func replaceAllVars(s string, data interface{}) (string, error) {
 	return result, assertAllVarsReplaced(result)
 }
 
+// replaceAllVarsInStrings replaces the placeholders in each of the
+// strings in place, returning an error if not all variables were replaced.
+func replaceAllVarsInStrings(data interface{}, values ...*string) error {
+	for _, val := range values {
+		replacedValue, err := replaceAllVars(*val, data)
+		if err != nil {
+			return err
+		}
+		*val = replacedValue
+	}
+	return nil
+}
+
 func replaceVarsInMapValues(inputMap map[string]string, data interface{}) (map[string]string, error) {
 	outputMap := make(map[string]string)
 	for key, val := range inputMap {
@@ -87,7 +100,13 @@ func replaceVarsInMapValues(inputMap map[string]string, data interface{}) (map[s
 // replaceVars takes a string as a template, executes the template against the
 // data provided and returns the result as a string.
 func replaceVars(s string, data interface{}) (string, error) {
-	t := template.New("")
+	return replaceVarsWithFuncs(s, data, nil)
+}
+
+// replaceVarsWithFuncs is the same as replaceVars, with the functions in
+// funcs available to the template.
+func replaceVarsWithFuncs(s string, data interface{}, funcs template.FuncMap) (string, error) {
+	t := template.New("").Funcs(funcs)
 	if _, err := t.Parse(s); err != nil {
 		return "", err
 	}
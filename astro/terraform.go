@@ -20,61 +20,232 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/uber/astro/astro/logger"
 	"github.com/uber/astro/astro/terraform"
+	"github.com/uber/astro/astro/utils"
+
+	"github.com/burl/go-version"
 )
 
-// newTerraformSession returns a new Terraform session.
+// newTerraformSession returns a new Terraform session. If the session was
+// opened to apply saved plans, the Terraform session the plan was saved in
+// is opened instead.
 func (session *Session) newTerraformSession(execution *boundExecution) (*terraform.Session, error) {
 	terraformSessionDir := filepath.Join(session.path, execution.ID())
 
+	config, build, err := session.terraformConfig(execution)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := session.executionRun(execution.ID()).ctx
+
+	// Saved plans already have the outputs of the dependencies in them
+	if session.fromSavedPlans {
+		return terraform.OpenTerraformSession(ctx, execution.ID(), terraformSessionDir, config)
+	}
+
+	if err := session.wireDependencyOutputs(execution, &config); err != nil {
+		return nil, err
+	}
+
+	// Executions that ran before an apply was resumed run again in their
+	// sandbox, with the providers and local state they left there.
+	if session.resumed && utils.IsDirectory(filepath.Join(terraformSessionDir, "sandbox")) {
+		return terraform.OpenTerraformSession(ctx, execution.ID(), terraformSessionDir, config)
+	}
+
+	terraformSession, err := terraform.NewTerraformSession(ctx, execution.ID(), terraformSessionDir, config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := session.recordTerraformBuild(execution, build); err != nil {
+		return nil, err
+	}
+
+	return terraformSession, nil
+}
+
+// initialize runs terraform init for the execution. Downloading the
+// providers can take a while on a cold start, so the providers it installs
+// are reported in the status updates as it goes.
+func (session *Session) initialize(b *boundExecution, terraformSession *terraform.Session, status *statusQueue) (terraform.Result, error) {
+	terraformSession.SetProviderProgress(func(provider terraform.ProviderInstall) {
+		if provider.Origin == terraform.ProviderDownloading {
+			status.send(b.ID(), "Downloading provider %s v%s...", provider.Name, provider.Version)
+		}
+	})
+
+	result, err := session.retry(b, status, terraformSession.Init)
+	if err != nil {
+		return result, err
+	}
+
+	var providers []string
+	for _, provider := range terraformSession.Providers() {
+		providers = append(providers, provider.String())
+	}
+	if len(providers) > 0 {
+		status.send(b.ID(), "Providers: %s", strings.Join(providers, ", "))
+	}
+
+	return result, nil
+}
+
+// replanTerraformSession returns a new Terraform session for an execution
+// whose saved plan is stale, so that it is planned and applied again from
+// the current module source.
+func (session *Session) replanTerraformSession(execution *boundExecution) (*terraform.Session, error) {
+	config, _, err := session.terraformConfig(execution)
+	if err != nil {
+		return nil, err
+	}
+	if err := session.wireDependencyOutputs(execution, &config); err != nil {
+		return nil, err
+	}
+
+	return terraform.NewTerraformSession(session.executionRun(execution.ID()).ctx, execution.ID(), filepath.Join(session.path, execution.ID(), "replan"), config)
+}
+
+// terraformConfig returns the Terraform configuration for the execution,
+// and the Terraform binary it runs with.
+func (session *Session) terraformConfig(execution *boundExecution) (terraform.Config, terraformBuild, error) {
+	project := session.repo.project
 	moduleConfig := execution.ModuleConfig()
 
-	config := terraform.Config{
-		Name:                moduleConfig.Name,
-		BasePath:            moduleConfig.TerraformCodeRoot,
-		ModulePath:          moduleConfig.Path,
-		Remote:              moduleConfig.Remote,
-		Variables:           execution.Variables(),
-		TerraformParameters: execution.TerraformParameters(),
+	variables, secretVariables, err := session.secretVariables(execution)
+	if err != nil {
+		return terraform.Config{}, terraformBuild{}, err
+	}
+
+	env, err := renderOutputsInMapValues(moduleConfig.Environment(), session.outputResolver(execution))
+	if err != nil {
+		return terraform.Config{}, terraformBuild{}, fmt.Errorf("unable to render env: %v", err)
 	}
 
+	if moduleConfig.Credentials.AWS != nil {
+		credentials, err := session.assumeAWSRole(*moduleConfig.Credentials.AWS, env)
+		if err != nil {
+			return terraform.Config{}, terraformBuild{}, err
+		}
+		env = mergeMaps(env, credentials.Environment())
+	}
+
+	config := project.newTerraformConfig(execution, variables, secretVariables, env)
+	config.RefreshOnly = session.detectDrift
+	config.RawOutput = session.rawOutput
+
 	// Fetch the right Terraform version
 	terraformVersion := moduleConfig.Terraform.Version
 
-	if terraformVersion != nil {
-		terraformPath, err := session.repo.project.terraformVersions.Get(terraformVersion.String())
+	if moduleConfig.Terraform.Path == "" && terraformVersion != nil {
+		versions := project.terraformVersions.ForFlavor(moduleConfig.Terraform.TVMFlavor())
+		terraformPath, err := versions.Get(terraformVersion.String())
 		if err != nil {
-			return nil, fmt.Errorf("unable to activate Terraform %v: %v", terraformVersion.String(), err)
+			return terraform.Config{}, terraformBuild{}, fmt.Errorf("unable to activate %v %v: %v", versions.Flavor().Name(), terraformVersion.String(), err)
 		}
 
 		config.TerraformPath = terraformPath
 	}
 
-	// In Terraform 0.9.x and later, the backend configuration must be
-	// in the Terraform code itself.
-	if terraform.VersionMatches(terraformVersion, ">= 0.9") {
-		config.Remote.Backend = ""
+	var build terraformBuild
+	if session.pinnedBuilds != nil {
+		// Use the binary the execution ran with in the pinned session
+		pinned, pinnedVersion, err := project.pinnedBuild(execution, session.pinnedBuilds, session.pinnedSession)
+		if err != nil {
+			return terraform.Config{}, terraformBuild{}, err
+		}
+		build = pinned
+		config.TerraformPath = pinned.Path
+		if pinnedVersion != nil {
+			terraformVersion = pinnedVersion
+		}
+	} else {
+		hash, err := project.terraformBinaryHash(config.TerraformPath)
+		if err != nil {
+			return terraform.Config{}, terraformBuild{}, fmt.Errorf("unable to hash Terraform binary: %v", err)
+		}
+		build = terraformBuild{
+			Path: config.TerraformPath,
+			Hash: hash,
+		}
+		if terraformVersion != nil {
+			build.Version = terraformVersion.String()
+		}
 	}
 
 	// Create a shared plugin directory
-	if terraform.VersionMatches(terraformVersion, ">= 0.10") {
-		if _, exists := os.LookupEnv("TF_PLUGIN_CACHE_DIR"); !exists {
-			pluginDir := filepath.Join(session.repo.path, "plugins")
-			logger.Trace.Printf("astro: creating shared plugin directory: %v", pluginDir)
-
-			if err := os.MkdirAll(pluginDir, 0755); err != nil {
-				return nil, err
-			}
-			config.SharedPluginDir = pluginDir
+	var pluginDir string
+	if usesSharedPluginDir(terraformVersion) {
+		pluginDir, err = session.pluginCacheDir()
+		if err != nil {
+			return terraform.Config{}, terraformBuild{}, err
+		}
+		logger.Trace.Printf("astro: creating shared plugin directory: %v", pluginDir)
+
+		if err := os.MkdirAll(pluginDir, 0755); err != nil {
+			return terraform.Config{}, terraformBuild{}, err
 		}
 	}
 
-	// If an override path has been specified, use that instead
-	if moduleConfig.Terraform.Path != "" {
-		config.TerraformPath = moduleConfig.Terraform.Path
+	setTerraformVersion(&config, terraformVersion, pluginDir)
+
+	return config, build, nil
+}
+
+// newTerraformConfig returns the Terraform configuration for the execution
+// with the variables and env it runs with, which have to be resolved
+// first. It has no side effects, so dry runs use it too: the Terraform
+// binary is its path, or else where tvm installs it, which may not have
+// been downloaded yet. See setTerraformVersion.
+func (c *Project) newTerraformConfig(execution *boundExecution, variables, secretVariables, env map[string]string) terraform.Config {
+	moduleConfig := execution.ModuleConfig()
+
+	return terraform.Config{
+		Name:                moduleConfig.Name,
+		BasePath:            moduleConfig.TerraformCodeRoot,
+		ModulePath:          moduleConfig.Path,
+		Remote:              moduleConfig.Remote,
+		Env:                 env,
+		Variables:           variables,
+		SecretVariables:     secretVariables,
+		VarFiles:            moduleConfig.VarFiles,
+		TerraformParameters: execution.TerraformParameters(),
+		Targets:             execution.Targets(),
+		MaxOutputInMemory:   c.maxOutputInMemory(),
+		OutputWriter:        c.terraformOutput,
+		DisableInput:        moduleConfig.DisableInput,
+		Lockfile:            moduleConfig.Terraform.Lockfile,
+		LockTimeout:         c.lockTimeout(moduleConfig),
+		Suppressions:        c.config.Suppressions,
+		Clock:               c.clock,
+		// If an override path has been specified, it is used instead,
+		// which also may not be Terraform, e.g. OpenTofu
+		TerraformPath: c.terraformPath(moduleConfig.Terraform),
 	}
+}
 
-	return terraform.NewTerraformSession(execution.ID(), terraformSessionDir, config)
+// usesSharedPluginDir returns whether executions that run with the
+// Terraform version share a plugin directory, which Terraform 0.10 and
+// later supports, unless TF_PLUGIN_CACHE_DIR is already set.
+func usesSharedPluginDir(terraformVersion *version.Version) bool {
+	if !terraform.VersionMatches(terraformVersion, ">= 0.10") {
+		return false
+	}
+	_, exists := os.LookupEnv("TF_PLUGIN_CACHE_DIR")
+	return !exists
+}
+
+// setTerraformVersion adapts the configuration to the Terraform version it
+// runs with, and sets the shared plugin directory, if it uses one.
+func setTerraformVersion(config *terraform.Config, terraformVersion *version.Version, pluginDir string) {
+	// In Terraform 0.9.x and later, the backend configuration must be
+	// in the Terraform code itself.
+	if terraform.VersionMatches(terraformVersion, ">= 0.9") {
+		config.Remote.Backend = ""
+	}
+	config.SharedPluginDir = pluginDir
 }
@@ -18,9 +18,13 @@ package terraform
 
 import (
 	"errors"
+	"io"
+	"sort"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/utils"
 )
 
 // Config is the Terraform configuration required to initialize and run
@@ -34,10 +38,45 @@ type Config struct {
 	ModulePath string
 	// Remote is the Terraform remote configuration for this module.
 	Remote conf.Remote
+	// Env is a map of additional environment variables to set when running
+	// Terraform.
+	Env map[string]string
 	// Variables is a map of the variable values for execution.
 	Variables map[string]string
+	// SecretVariables is a map of the values of the secret variables of
+	// the execution. They are passed in the environment of Terraform, as
+	// TF_VAR_name, rather than on its command line, and are masked in
+	// the commands that are recorded.
+	SecretVariables map[string]string
+	// VarFiles is a list of Terraform variable files to pass to plan,
+	// apply and destroy, relative to the module path.
+	VarFiles []string
 	// TerraformParameters is a list of additional Terraform command-line parameters
 	TerraformParameters []string
+	// Targets is a list of resource addresses to pass as -target to plan,
+	// apply and destroy
+	Targets []string
+	// Lockfile is the mode of the dependency lock file, passed to init as
+	// -lockfile, e.g. "readonly"
+	Lockfile string
+	// LockTimeout is how long plan, apply and destroy wait for the lock
+	// of the state, if another run holds it, passed as -lock-timeout.
+	// They fail right away if it is 0.
+	LockTimeout time.Duration
+	// RefreshOnly makes plan only compare the state with the real
+	// infrastructure, to detect drift, with -refresh-only. Before
+	// Terraform 0.15.4, which added it, a regular plan is made instead.
+	RefreshOnly bool
+	// RawOutput disables parsing the text output of Terraform: the changes
+	// of plans are their full output, and suppressions are not applied.
+	// Change counts then only come from the machine-readable plan of
+	// Terraform 1.0 and later.
+	RawOutput bool
+	// Suppressions hide known-noisy changes from the changes of plans.
+	Suppressions []conf.Suppression
+	// DisableInput passes -input=false to plan and apply, so that Terraform
+	// fails instead of prompting for missing variables.
+	DisableInput bool
 
 	// TerraformPath is the path to the Terraform binary
 	TerraformPath string
@@ -45,6 +84,20 @@ type Config struct {
 	// SharedPluginDir is the path to a directory that should contain shared
 	// plugins.
 	SharedPluginDir string
+
+	// MaxOutputInMemory is the number of bytes of the stdout and of the
+	// stderr of each Terraform command that are kept in memory. Larger
+	// output is moved to a file in the log directory of the session. If
+	// zero, all output is kept in memory.
+	MaxOutputInMemory int
+
+	// OutputWriter, if set, receives the output of Terraform commands as
+	// they run. Each line is prefixed with the execution ID.
+	OutputWriter io.Writer
+
+	// Clock times Terraform commands and timestamps crash bundles.
+	// Defaults to the system clock.
+	Clock utils.Clock
 }
 
 // Validate validates the Terraform configuration is valid.
@@ -60,3 +113,14 @@ func (config Config) Validate() (errs error) {
 	}
 	return errs
 }
+
+// sortedKeys returns the keys of the map, sorted, so that the arguments
+// of commands are always in the same order.
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,188 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/uber/astro/astro/exec2"
+	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/utils"
+)
+
+// CrashBundleFile is the name of the archive, in the session directory of
+// the execution, that collects what is needed to report a Terraform crash.
+const CrashBundleFile = "crash-bundle.tgz"
+
+// matches the output of Terraform, or of a provider, panicking
+var reCrash = regexp.MustCompile(`(?m)^panic: |TERRAFORM CRASH|Terraform crashed!|plugin exited unexpectedly|The plugin encountered an error, and failed to respond`)
+
+// crashMetadata describes the command that crashed, and where it ran.
+type crashMetadata struct {
+	ID               string     `json:"id"`
+	Command          Invocation `json:"command"`
+	ExitCode         int        `json:"exit_code"`
+	TerraformVersion string     `json:"terraform_version"`
+	OS               string     `json:"os"`
+	Arch             string     `json:"arch"`
+	Workspace        string     `json:"workspace,omitempty"`
+	SandboxDir       string     `json:"sandbox_dir"`
+	ModuleDir        string     `json:"module_dir"`
+	Time             time.Time  `json:"time"`
+}
+
+// run runs a Terraform command. If Terraform crashes, it collects the
+// crash log, the output of the command and details about the environment
+// into a crash bundle, and returns an error that points to it. If the
+// state is locked, it returns a *StateLockedError.
+func (s *Session) run(process *exec2.Process) error {
+	err := process.Run()
+	if err == nil || !s.crashed(process) {
+		return explainStateLock(err, process.Stderr().String())
+	}
+
+	bundle, bundleErr := s.writeCrashBundle(process)
+	if bundleErr != nil {
+		logger.Warn("unable to write crash bundle", logger.Fields{"id": s.id, "error": bundleErr})
+		return fmt.Errorf("%v; Terraform crashed", err)
+	}
+
+	logger.Error("terraform crashed", logger.Fields{"id": s.id, "bundle": bundle})
+	return fmt.Errorf("%v; Terraform crashed, details to include in a bug report are in %v", err, bundle)
+}
+
+// crashed returns whether a command that failed crashed, instead of
+// reporting an error.
+func (s *Session) crashed(process *exec2.Process) bool {
+	return utils.FileExists(filepath.Join(s.moduleDir, "crash.log")) || reCrash.MatchString(process.Stderr().String())
+}
+
+// writeCrashBundle writes the crash bundle for a command that crashed and
+// returns its path.
+func (s *Session) writeCrashBundle(process *exec2.Process) (string, error) {
+	metadata := crashMetadata{
+		ID:         s.id,
+		ExitCode:   process.ExitCode(),
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		Workspace:  s.workspace,
+		SandboxDir: s.sandboxDir,
+		ModuleDir:  s.moduleDir,
+		Time:       s.now(),
+	}
+	if len(s.invocations) > 0 {
+		metadata.Command = s.invocations[len(s.invocations)-1]
+	}
+	if s.versionCachedValue != nil {
+		metadata.TerraformVersion = s.versionCachedValue.String()
+	}
+
+	metadataJSON, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	files := map[string][]byte{
+		"metadata.json": metadataJSON,
+		"stdout.txt":    process.Stdout().Bytes(),
+		"stderr.txt":    process.Stderr().Bytes(),
+	}
+
+	// crash.log is written by Terraform before 0.15, and the dependency
+	// lock file records the provider versions since 0.14
+	for _, name := range []string{"crash.log", ".terraform.lock.hcl"} {
+		if data, err := os.ReadFile(filepath.Join(s.moduleDir, name)); err == nil {
+			files[name] = data
+		}
+	}
+
+	// the logs of every command run in the session so far
+	logs, err := os.ReadDir(s.logDir)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range logs {
+		if entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.logDir, entry.Name()))
+		if err != nil {
+			return "", err
+		}
+		files[filepath.Join("logs", entry.Name())] = data
+	}
+
+	path := filepath.Join(s.baseDir, CrashBundleFile)
+	if err := writeTarGz(path, files, metadata.Time); err != nil {
+		return "", err
+	}
+
+	// so that a later failure isn't mistaken for a crash
+	os.Remove(filepath.Join(s.moduleDir, "crash.log"))
+
+	return filepath.Abs(path)
+}
+
+// writeTarGz writes a gzipped tar archive with the files, by name, to
+// path, with modTime as the modification time of every file.
+func writeTarGz(path string, files map[string][]byte, modTime time.Time) error {
+	var buf bytes.Buffer
+
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		data := files[name]
+		header := &tar.Header{
+			Name:    name,
+			Mode:    0644,
+			Size:    int64(len(data)),
+			ModTime: modTime,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
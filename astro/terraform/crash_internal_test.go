@@ -0,0 +1,116 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/astro/astro/utils"
+)
+
+// testCrashSession returns a session in a temporary directory that runs
+// script as Terraform.
+func testCrashSession(t *testing.T, script string) *Session {
+	baseDir := t.TempDir()
+	for _, dir := range []string{"logs", "sandbox"} {
+		require.NoError(t, os.Mkdir(filepath.Join(baseDir, dir), 0755))
+	}
+
+	terraformPath := filepath.Join(baseDir, "terraform")
+	require.NoError(t, os.WriteFile(terraformPath, []byte("#!/bin/sh\n"+script), 0755))
+
+	return &Session{
+		ctx:        context.Background(),
+		id:         "app",
+		config:     &Config{TerraformPath: terraformPath},
+		baseDir:    baseDir,
+		logDir:     filepath.Join(baseDir, "logs"),
+		sandboxDir: filepath.Join(baseDir, "sandbox"),
+		moduleDir:  filepath.Join(baseDir, "sandbox"),
+	}
+}
+
+// testReadTarGz returns the names of the files in a gzipped tar archive.
+func testReadTarGz(t *testing.T, path string) []string {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+
+	var names []string
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return names
+		}
+		require.NoError(t, err)
+		names = append(names, header.Name)
+	}
+}
+
+func TestRunCrash(t *testing.T) {
+	s := testCrashSession(t, `echo "crash details" > crash.log
+echo "panic: runtime error: invalid memory address" >&2
+exit 11
+`)
+
+	process, err := s.terraformCommand([]string{"apply"}, []int{0})
+	require.NoError(t, err)
+
+	err = s.run(process)
+	require.Error(t, err)
+
+	bundle := filepath.Join(s.baseDir, CrashBundleFile)
+	assert.Contains(t, err.Error(), bundle)
+	assert.ElementsMatch(t, []string{
+		"crash.log",
+		"logs/apply.log",
+		"logs/apply.status.json",
+		"logs/apply.stderr.log",
+		"logs/apply.stdout.log",
+		"logs/commands.log",
+		"metadata.json",
+		"stderr.txt",
+		"stdout.txt",
+	}, testReadTarGz(t, bundle))
+	assert.False(t, utils.FileExists(filepath.Join(s.moduleDir, "crash.log")))
+}
+
+func TestRunErrorIsNotCrash(t *testing.T) {
+	s := testCrashSession(t, `echo "Error: Unsupported argument" >&2
+exit 1
+`)
+
+	process, err := s.terraformCommand([]string{"apply"}, []int{0})
+	require.NoError(t, err)
+
+	err = s.run(process)
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "crashed")
+	assert.False(t, utils.FileExists(filepath.Join(s.baseDir, CrashBundleFile)))
+}
@@ -0,0 +1,85 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"path/filepath"
+
+	"github.com/burl/go-version"
+)
+
+// DryRunCommand is what a dry run does after initializing the module.
+type DryRunCommand int
+
+// Commands of dry runs.
+const (
+	// DryRunPlan plans, saving the plan.
+	DryRunPlan DryRunCommand = iota
+	// DryRunApply applies without planning first.
+	DryRunApply
+	// DryRunPlanAndApply plans, saving the plan, and applies the plan.
+	DryRunPlanAndApply
+)
+
+// latestVersion is the Terraform version dry runs assume when the version
+// isn't known, as it can only be detected by running Terraform.
+var latestVersion = version.Must(version.NewVersion("1.0.0"))
+
+// DryRun returns the Terraform commands that initializing the module with
+// the configuration, and then running the command, would run, in order,
+// without running them. id is the ID of the Terraform session they would
+// run in, which names the plan, and dir the module directory, which is a
+// copy of the module in the sandbox of the session in real runs.
+// Terraform 1.0 and later is assumed if terraformVersion is nil. Commands
+// that only run depending on the outcome of others, e.g. show to count
+// the changes of plans that have some, are left out.
+func DryRun(id, dir string, config Config, terraformVersion *version.Version, command DryRunCommand) ([]Invocation, error) {
+	if terraformVersion == nil {
+		terraformVersion = latestVersion
+	}
+
+	var argsList [][]string
+
+	if config.initializesBackend(terraformVersion) {
+		args, err := config.initArgs(terraformVersion)
+		if err != nil {
+			return nil, err
+		}
+		argsList = append(argsList, args)
+	}
+	argsList = append(argsList, []string{"get"})
+
+	planFile := planFileName(id)
+	refreshOnly := config.refreshesOnly(terraformVersion)
+	switch command {
+	case DryRunPlan:
+		argsList = append(argsList, config.planArgs(planFile, refreshOnly))
+	case DryRunApply:
+		argsList = append(argsList, config.applyArgs(terraformVersion))
+	case DryRunPlanAndApply:
+		argsList = append(argsList,
+			config.planArgs(planFile, refreshOnly),
+			config.applyPlanArgs(planFile),
+		)
+	}
+
+	invocations := make([]Invocation, 0, len(argsList))
+	for _, args := range argsList {
+		invocations = append(invocations, config.invocation(config.TerraformPath, args, filepath.Clean(dir)))
+	}
+	return invocations, nil
+}
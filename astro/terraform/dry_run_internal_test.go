@@ -0,0 +1,65 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"testing"
+
+	"github.com/uber/astro/astro/conf"
+
+	"github.com/burl/go-version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRun(t *testing.T) {
+	config := Config{
+		TerraformPath:   "/usr/bin/terraform",
+		Variables:       map[string]string{"region": "us-east-1", "environment": "dev"},
+		SecretVariables: map[string]string{"password": "hunter2"},
+		Remote:          conf.Remote{BackendConfig: map[string]string{"key": "app/dev"}},
+		DisableInput:    true,
+	}
+
+	invocations, err := DryRun("app-dev", "/code/app", config, nil, DryRunPlanAndApply)
+	require.NoError(t, err)
+
+	var commands [][]string
+	for _, invocation := range invocations {
+		commands = append(commands, invocation.Args)
+		assert.Equal(t, "/code/app", invocation.WorkingDir)
+		// secrets are never shown
+		assert.Equal(t, maskedValue, invocation.Env["TF_VAR_password"])
+	}
+	assert.Equal(t, [][]string{
+		{"/usr/bin/terraform", "init", "-backend-config=key=app/dev", "-input=false"},
+		{"/usr/bin/terraform", "get"},
+		{"/usr/bin/terraform", "plan", "-detailed-exitcode", "-out=app-dev.plan", "-input=false", "-var", "environment=dev", "-var", "region=us-east-1"},
+		{"/usr/bin/terraform", "apply", "-input=false", "app-dev.plan"},
+	}, commands)
+}
+
+func TestDryRunLegacy(t *testing.T) {
+	config := Config{TerraformPath: "terraform"}
+
+	invocations, err := DryRun("app", "/code/app", config, version.Must(version.NewVersion("0.8.8")), DryRunApply)
+	require.NoError(t, err)
+	require.Len(t, invocations, 2)
+	// there is no backend to initialize, and apply asks for approval
+	assert.Equal(t, []string{"terraform", "get"}, invocations[0].Args)
+	assert.Equal(t, []string{"terraform", "apply"}, invocations[1].Args)
+}
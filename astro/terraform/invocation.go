@@ -0,0 +1,115 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maskedValue replaces the values of environment variables that look like
+// they contain secrets.
+const maskedValue = "********"
+
+// matches the names of environment variables that are likely to contain
+// secrets, e.g. AWS_SECRET_ACCESS_KEY
+var reSecretEnvName = regexp.MustCompile(`(?i)secret|token|passw|key|credential|auth`)
+
+// Invocation records how a Terraform command was run, so that it can be
+// reproduced by hand.
+type Invocation struct {
+	// Args is the command and its arguments.
+	Args []string `json:"args"`
+	// Env is the environment variables that were set in addition to
+	// astro's own environment. Values that look like secrets are masked.
+	Env map[string]string `json:"env,omitempty"`
+	// WorkingDir is the directory the command was run in.
+	WorkingDir string `json:"working_dir"`
+}
+
+// newInvocation returns an invocation for the command, masking the values
+// of env that look like secrets.
+func newInvocation(cmd string, args []string, env map[string]string, workingDir string) Invocation {
+	masked := map[string]string{}
+	for key, val := range env {
+		if reSecretEnvName.MatchString(key) {
+			val = maskedValue
+		}
+		masked[key] = val
+	}
+
+	return Invocation{
+		Args:       append([]string{cmd}, args...),
+		Env:        masked,
+		WorkingDir: workingDir,
+	}
+}
+
+// String returns a shell command line that reproduces the invocation.
+func (i Invocation) String() string {
+	var keys []string
+	for key := range i.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := []string{"cd", shellQuote(i.WorkingDir), "&&"}
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%s", key, shellQuote(i.Env[key])))
+	}
+	for _, arg := range i.Args {
+		parts = append(parts, shellQuote(arg))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// matches strings that don't need quoting in a shell
+var reShellSafe = regexp.MustCompile(`^[a-zA-Z0-9_@%+=:,./-]+$`)
+
+// shellQuote quotes s for use in a shell command line, if necessary.
+func shellQuote(s string) string {
+	if reShellSafe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
+
+// recordInvocation adds the invocation to the session, and appends it to
+// the commands.log file in the session's log directory.
+func (s *Session) recordInvocation(invocation Invocation) error {
+	s.invocations = append(s.invocations, invocation)
+
+	f, err := os.OpenFile(filepath.Join(s.logDir, "commands.log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintln(f, invocation.String())
+	return err
+}
+
+// Invocations returns all of the Terraform commands that were run in this
+// session, in order.
+func (s *Session) Invocations() []Invocation {
+	return s.invocations
+}
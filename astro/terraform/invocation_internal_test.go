@@ -0,0 +1,51 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewInvocationMasksSecrets(t *testing.T) {
+	invocation := newInvocation("terraform", []string{"plan"}, map[string]string{
+		"AWS_PROFILE":           "dev",
+		"AWS_SECRET_ACCESS_KEY": "hunter2",
+		"GITHUB_TOKEN":          "abc",
+	}, "/tmp/sandbox")
+
+	assert.Equal(t, Invocation{
+		Args: []string{"terraform", "plan"},
+		Env: map[string]string{
+			"AWS_PROFILE":           "dev",
+			"AWS_SECRET_ACCESS_KEY": maskedValue,
+			"GITHUB_TOKEN":          maskedValue,
+		},
+		WorkingDir: "/tmp/sandbox",
+	}, invocation)
+}
+
+func TestInvocationString(t *testing.T) {
+	invocation := Invocation{
+		Args:       []string{"terraform", "plan", "-var", "name=it's here"},
+		Env:        map[string]string{"B": "2", "A": "1"},
+		WorkingDir: "/tmp/my sandbox",
+	}
+
+	assert.Equal(t, `cd '/tmp/my sandbox' && A=1 B=2 terraform plan -var 'name=it'"'"'s here'`, invocation.String())
+}
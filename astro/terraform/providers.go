@@ -0,0 +1,140 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// How the providers of a module were installed by terraform init.
+const (
+	// ProviderDownloading is reported while a provider is downloaded.
+	ProviderDownloading = "downloading"
+	// ProviderDownloaded is a provider that was downloaded.
+	ProviderDownloaded = "downloaded"
+	// ProviderCached is a provider from the shared plugin cache.
+	ProviderCached = "cached"
+	// ProviderReused is a provider that was already installed.
+	ProviderReused = "reused"
+)
+
+var (
+	reProviderInstalling = regexp.MustCompile(`^- Installing (\S+) v(\S+)\.\.\.$`)
+	reProviderInstalled  = regexp.MustCompile(`^- Installed (\S+) v(\S+)`)
+	reProviderCached     = regexp.MustCompile(`^- Using (\S+) v(\S+) from the shared cache directory`)
+	reProviderReused     = regexp.MustCompile(`^- Using previously-installed (\S+) v(\S+)`)
+	// Before Terraform 0.13, e.g. `- Downloading plugin for provider "aws"
+	// (hashicorp/aws) 2.70.0...`, or without the source before 0.12.
+	reProviderDownloadingLegacy = regexp.MustCompile(`^- Downloading plugin for provider "([^"]+)" (?:\(([^)]+)\) (\S+?)|\((\S+?)\))\.\.\.$`)
+)
+
+// ProviderInstall is a provider that terraform init installed, or is
+// installing.
+type ProviderInstall struct {
+	// Name is the provider, e.g. "hashicorp/aws", or "aws" before
+	// Terraform 0.12.
+	Name    string
+	Version string
+	// Origin is how the provider was installed, e.g. ProviderDownloaded.
+	Origin string
+	// Host is the registry the provider is from, e.g.
+	// "registry.terraform.io", if it is known.
+	Host string
+	// Size is the size of the provider binary in bytes, if it was found.
+	Size int64
+}
+
+// String returns a description of the provider install, e.g.
+// "hashicorp/aws v5.31.0 (downloaded from registry.terraform.io, 120.3 MB)".
+func (p ProviderInstall) String() string {
+	details := p.Origin
+	if p.Host != "" && p.Origin != ProviderReused {
+		details += " from " + p.Host
+	}
+	if p.Size > 0 {
+		details += fmt.Sprintf(", %.1f MB", float64(p.Size)/1e6)
+	}
+	return fmt.Sprintf("%s v%s (%s)", p.Name, p.Version, details)
+}
+
+// parseProviderLine returns the provider installs that a line of the
+// output of terraform init reports, if any.
+func parseProviderLine(line string) []ProviderInstall {
+	line = strings.TrimSpace(line)
+
+	for _, pattern := range []struct {
+		re     *regexp.Regexp
+		origin string
+	}{
+		{reProviderInstalling, ProviderDownloading},
+		{reProviderInstalled, ProviderDownloaded},
+		{reProviderCached, ProviderCached},
+		{reProviderReused, ProviderReused},
+	} {
+		if m := pattern.re.FindStringSubmatch(line); m != nil {
+			return []ProviderInstall{{Name: m[1], Version: m[2], Origin: pattern.origin}}
+		}
+	}
+
+	// Older versions only report the download
+	if m := reProviderDownloadingLegacy.FindStringSubmatch(line); m != nil {
+		name, version := m[1], m[4]
+		if m[2] != "" {
+			name, version = m[2], m[3]
+		}
+		return []ProviderInstall{
+			{Name: name, Version: version, Origin: ProviderDownloading},
+			{Name: name, Version: version, Origin: ProviderDownloaded},
+		}
+	}
+
+	return nil
+}
+
+// locateProvider fills in the host and the size of the installed provider,
+// from where terraform init put it in the module directory.
+func locateProvider(moduleDir string, provider *ProviderInstall) {
+	patterns := []string{
+		// Terraform 0.13 and later: .terraform/providers/HOST/NAMESPACE/TYPE/VERSION/OS_ARCH/
+		filepath.Join(moduleDir, ".terraform", "providers", "*", provider.Name, provider.Version, "*", "terraform-provider-*"),
+	}
+	if name := provider.Name[strings.LastIndex(provider.Name, "/")+1:]; name != "" {
+		// Before 0.13: .terraform/plugins/OS_ARCH/terraform-provider-TYPE_vVERSION_x4
+		patterns = append(patterns, filepath.Join(moduleDir, ".terraform", "plugins", "*", fmt.Sprintf("terraform-provider-%s_v%s*", name, provider.Version)))
+	}
+
+	for i, pattern := range patterns {
+		matches, _ := filepath.Glob(pattern)
+		if len(matches) == 0 {
+			continue
+		}
+		if info, err := os.Stat(matches[0]); err == nil {
+			provider.Size = info.Size()
+		}
+		if i == 0 {
+			rel, err := filepath.Rel(filepath.Join(moduleDir, ".terraform", "providers"), matches[0])
+			if err == nil {
+				provider.Host = strings.Split(filepath.ToSlash(rel), "/")[0]
+			}
+		}
+		return
+	}
+}
@@ -17,6 +17,11 @@
 package terraform
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -29,6 +34,7 @@ type Result interface {
 	Runtime() string
 	Stdout() string
 	Stderr() string
+	OutputPath() string
 }
 
 // terraformResult is returned by the Plan/Apply commands.
@@ -52,11 +58,30 @@ func (r *terraformResult) Stderr() string {
 	return r.process.Stderr().String()
 }
 
+// OutputPath returns the path to the log of this execution, which has
+// all of its stdout and stderr, however large they are.
+func (r *terraformResult) OutputPath() string {
+	return r.process.LogFile()
+}
+
 // PlanResult is the terraformResult of a Terraform plan.
 type PlanResult struct {
 	*terraformResult
 
 	changes string
+
+	// counts, if set, are the change counts from the machine-readable
+	// plan
+	counts *ChangeCounts
+
+	// raw is set if the output of the plan was not parsed, in which case
+	// there are no counts unless they came from the machine-readable plan
+	raw bool
+
+	// suppressed is the changes to the resources whose changes were all
+	// suppressed, and allSuppressed is set if every change was.
+	suppressed    ChangeCounts
+	allSuppressed bool
 }
 
 // Changes returns the changes for this plan.
@@ -64,7 +89,178 @@ func (r *PlanResult) Changes() string {
 	return strings.TrimSpace(r.changes)
 }
 
-// HasChanges returns whether this plan had changes or not.
+// ChangeCounts returns the number of resources this plan will add,
+// change and destroy. Suppressed changes are not counted.
+func (r *PlanResult) ChangeCounts() ChangeCounts {
+	if r.counts != nil {
+		return r.counts.minus(r.suppressed)
+	}
+	if r.raw {
+		return ChangeCounts{}
+	}
+	return parseChangeCounts(r.process.Stdout().String()).minus(r.suppressed)
+}
+
+// ResourceChanges returns the resources this plan will add, change or
+// destroy, in the order they are in the plan. Suppressed changes are not
+// included, and there are none if the output of the plan was not parsed.
+func (r *PlanResult) ResourceChanges() []ResourceChange {
+	if r.raw {
+		return nil
+	}
+
+	blocks, _ := splitPlanBlocks(r.changes)
+
+	var changes []ResourceChange
+	for _, block := range blocks {
+		if block.address == "" {
+			continue
+		}
+		if action := block.counts.action(); action != "" {
+			changes = append(changes, ResourceChange{Address: block.address, Action: action})
+		}
+	}
+	return changes
+}
+
+// SuppressedCounts returns the changes to the resources whose changes
+// were all suppressed by the suppressions of the configuration.
+func (r *PlanResult) SuppressedCounts() ChangeCounts {
+	return r.suppressed
+}
+
+// HasChanges returns whether this plan had changes or not. A plan whose
+// changes were all suppressed has none.
 func (r *PlanResult) HasChanges() bool {
-	return r.process.ExitCode() == 2
+	return r.process.ExitCode() == 2 && !r.allSuppressed
+}
+
+// ChangeCounts is the number of resources that a plan will add, change
+// and destroy.
+type ChangeCounts struct {
+	Add     int `json:"add"`
+	Change  int `json:"change"`
+	Destroy int `json:"destroy"`
+}
+
+// ResourceChange is a change that a plan makes to a resource.
+type ResourceChange struct {
+	// Address is the address of the resource, e.g. aws_instance.web.
+	Address string `json:"address"`
+	// Action is "create", "update", "delete" or "replace".
+	Action string `json:"action"`
+}
+
+// action returns the action of the change to a resource that counts for
+// c in the summary of a plan, or "" if it is not counted, e.g. because
+// the resource is only read.
+func (c ChangeCounts) action() string {
+	switch {
+	case c.Add > 0 && c.Destroy > 0:
+		return "replace"
+	case c.Add > 0:
+		return "create"
+	case c.Change > 0:
+		return "update"
+	case c.Destroy > 0:
+		return "delete"
+	}
+	return ""
+}
+
+// Total returns the total number of resource changes.
+func (c ChangeCounts) Total() int {
+	return c.Add + c.Change + c.Destroy
+}
+
+// String returns the counts in the form "+1 ~2 -0".
+func (c ChangeCounts) String() string {
+	return fmt.Sprintf("+%d ~%d -%d", c.Add, c.Change, c.Destroy)
+}
+
+// Plus returns the sum of c and other.
+func (c ChangeCounts) Plus(other ChangeCounts) ChangeCounts {
+	return ChangeCounts{
+		Add:     c.Add + other.Add,
+		Change:  c.Change + other.Change,
+		Destroy: c.Destroy + other.Destroy,
+	}
+}
+
+// minus returns c without other, with no count below zero.
+func (c ChangeCounts) minus(other ChangeCounts) ChangeCounts {
+	nonNegative := func(n int) int {
+		if n < 0 {
+			return 0
+		}
+		return n
+	}
+	return ChangeCounts{
+		Add:     nonNegative(c.Add - other.Add),
+		Change:  nonNegative(c.Change - other.Change),
+		Destroy: nonNegative(c.Destroy - other.Destroy),
+	}
+}
+
+// matches the summary line at the end of a plan, e.g.
+// Plan: 1 to add, 2 to change, 0 to destroy.
+// Terraform may colorize the "Plan:" prefix.
+var rePlanSummary = regexp.MustCompile(`Plan:(?:\x1b\[[0-9;]*m)*\s+(\d+) to add, (\d+) to change, (\d+) to destroy`)
+
+// parseChangeCounts reads the change counts from the summary line of
+// Terraform plan output. If there is no summary line, e.g. because there
+// are no changes, all counts are zero.
+func parseChangeCounts(output string) ChangeCounts {
+	match := rePlanSummary.FindStringSubmatch(output)
+	if match == nil {
+		return ChangeCounts{}
+	}
+
+	// errors are impossible here as the regexp only matches digits
+	add, _ := strconv.Atoi(match[1])
+	change, _ := strconv.Atoi(match[2])
+	destroy, _ := strconv.Atoi(match[3])
+
+	return ChangeCounts{Add: add, Change: change, Destroy: destroy}
+}
+
+// planJSON is the part of the machine-readable plan, printed by
+// `terraform show -json`, that describes the resource changes.
+type planJSON struct {
+	FormatVersion   string `json:"format_version"`
+	ResourceChanges []struct {
+		Address string `json:"address"`
+		Change  struct {
+			Actions []string `json:"actions"`
+		} `json:"change"`
+	} `json:"resource_changes"`
+}
+
+// parsePlanJSON returns the change counts of a machine-readable plan. Like
+// in the summary line, a resource that is replaced counts as both added
+// and destroyed.
+func parsePlanJSON(data []byte) (ChangeCounts, error) {
+	var plan planJSON
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return ChangeCounts{}, err
+	}
+	if plan.FormatVersion == "" {
+		return ChangeCounts{}, errors.New("missing format_version")
+	}
+
+	var counts ChangeCounts
+	for _, resource := range plan.ResourceChanges {
+		for _, action := range resource.Change.Actions {
+			switch action {
+			case "create":
+				counts.Add++
+			case "update":
+				counts.Change++
+			case "delete":
+				counts.Destroy++
+			}
+		}
+	}
+
+	return counts, nil
 }
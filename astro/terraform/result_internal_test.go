@@ -0,0 +1,173 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseChangeCounts(t *testing.T) {
+	tt := []struct {
+		output   string
+		expected ChangeCounts
+	}{
+		{"No changes. Infrastructure is up-to-date.", ChangeCounts{}},
+		{"\nPlan: 1 to add, 0 to change, 0 to destroy.\n", ChangeCounts{Add: 1}},
+		{"Plan: 3 to add, 12 to change, 2 to destroy.", ChangeCounts{Add: 3, Change: 12, Destroy: 2}},
+		// colorized output
+		{"\x1b[0m\x1b[1mPlan:\x1b[0m 0 to add, 1 to change, 0 to destroy.", ChangeCounts{Change: 1}},
+	}
+
+	for _, test := range tt {
+		assert.Equal(t, test.expected, parseChangeCounts(test.output))
+	}
+}
+
+func TestParsePlanJSON(t *testing.T) {
+	counts, err := parsePlanJSON([]byte(`{
+		"format_version": "1.1",
+		"resource_changes": [
+			{"address": "aws_instance.new", "change": {"actions": ["create"]}},
+			{"address": "aws_instance.updated", "change": {"actions": ["update"]}},
+			{"address": "aws_instance.replaced", "change": {"actions": ["delete", "create"]}},
+			{"address": "aws_instance.same", "change": {"actions": ["no-op"]}},
+			{"address": "data.aws_ami.ubuntu", "change": {"actions": ["read"]}},
+			{"address": "aws_instance.old", "change": {"actions": ["delete"]}}
+		]
+	}`))
+	assert.NoError(t, err)
+	assert.Equal(t, ChangeCounts{Add: 2, Change: 1, Destroy: 2}, counts)
+
+	_, err = parsePlanJSON([]byte(`{"resource_changes": []}`))
+	assert.Error(t, err)
+}
+
+func TestResourceChanges(t *testing.T) {
+	result := &PlanResult{changes: `
+  # aws_instance.new will be created
+  + resource "aws_instance" "new" {
+      + ami = "ami-123"
+    }
+
+  # aws_instance.updated will be updated in-place
+  ~ resource "aws_instance" "updated" {
+      ~ instance_type = "t2.micro" -> "t2.small"
+    }
+
+  # aws_instance.replaced must be replaced
+-/+ resource "aws_instance" "replaced" {
+      ~ ami = "ami-123" -> "ami-456" # forces replacement
+    }
+
+  # data.aws_ami.ubuntu will be read during apply
+ <= data "aws_ami" "ubuntu" {
+    }
+
+  # aws_instance.old will be destroyed
+  - resource "aws_instance" "old" {
+      - ami = "ami-123" -> null
+    }
+
+Plan: 2 to add, 1 to change, 2 to destroy.`}
+
+	assert.Equal(t, []ResourceChange{
+		{Address: "aws_instance.new", Action: "create"},
+		{Address: "aws_instance.updated", Action: "update"},
+		{Address: "aws_instance.replaced", Action: "replace"},
+		{Address: "aws_instance.old", Action: "delete"},
+	}, result.ResourceChanges())
+
+	legacy := &PlanResult{changes: `
++ null_resource.foo
+    id: <computed>
+
+-/+ aws_instance.web (new resource required)
+    ami: "ami-123" => "ami-456" (forces new resource)
+`}
+	assert.Equal(t, []ResourceChange{
+		{Address: "null_resource.foo", Action: "create"},
+		{Address: "aws_instance.web", Action: "replace"},
+	}, legacy.ResourceChanges())
+
+	raw := &PlanResult{changes: result.changes, raw: true}
+	assert.Empty(t, raw.ResourceChanges())
+}
+
+func TestPlanActions(t *testing.T) {
+	tt := []struct {
+		output   string
+		expected string
+	}{
+		// Terraform 0.12
+		{
+			"Terraform will perform the following actions:\n  # null_resource.a will be created\n\nPlan: 1 to add, 0 to change, 0 to destroy.\n\n" + strings.Repeat("-", 72) + "\n",
+			"\n  # null_resource.a will be created\n\nPlan: 1 to add, 0 to change, 0 to destroy.\n\n",
+		},
+		// Terraform 1.x
+		{
+			"Terraform will perform the following actions:\n  # null_resource.a will be created\n\nPlan: 1 to add, 0 to change, 0 to destroy.\n\n" + strings.Repeat("─", 77) + "\n\nSaved the plan to: a.plan\n",
+			"\n  # null_resource.a will be created\n\nPlan: 1 to add, 0 to change, 0 to destroy.\n\n",
+		},
+		// OpenTofu
+		{
+			"OpenTofu will perform the following actions:\n  # null_resource.a will be created\n\nPlan: 1 to add, 0 to change, 0 to destroy.\n\n" + strings.Repeat("─", 77) + "\n",
+			"\n  # null_resource.a will be created\n\nPlan: 1 to add, 0 to change, 0 to destroy.\n\n",
+		},
+		// Terraform 1.x, only changing outputs
+		{
+			"Changes to Outputs:\n  + name = \"a\"\n\n" + strings.Repeat("─", 77) + "\n",
+			"Changes to Outputs:\n  + name = \"a\"\n\n",
+		},
+	}
+
+	for _, test := range tt {
+		match := rePlanActions.FindStringSubmatch(test.output)
+		if assert.NotNil(t, match) {
+			assert.Equal(t, test.expected, match[1]+match[2])
+		}
+	}
+}
+
+func TestDriftChanges(t *testing.T) {
+	output := `
+Note: Objects have changed outside of Terraform
+
+Terraform detected the following changes made outside of Terraform since the
+last "terraform apply" which may have affected this plan:
+
+  # aws_instance.web has changed
+  ~ resource "aws_instance" "web" {
+      ~ instance_type = "t2.micro" -> "t2.large"
+    }
+
+This is a refresh-only plan, so Terraform will not take any actions to undo
+these. If you were expecting these changes then you can apply this plan to
+record the updated values in the Terraform state without changing any real
+infrastructure.
+` + strings.Repeat("─", 77) + "\n"
+
+	match := reDriftChanges.FindStringSubmatch(output)
+	if assert.NotNil(t, match) {
+		assert.Equal(t, `# aws_instance.web has changed
+  ~ resource "aws_instance" "web" {
+      ~ instance_type = "t2.micro" -> "t2.large"
+    }`, strings.TrimSpace(match[1]))
+	}
+}
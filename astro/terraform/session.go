@@ -17,11 +17,15 @@
 package terraform
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"time"
 
 	"github.com/burl/go-version"
 	"github.com/uber/astro/astro/exec2"
@@ -36,6 +40,7 @@ import (
 // `terraform init` and `terraform get` as necessary before planning or
 // applying.
 type Session struct {
+	ctx    context.Context
 	id     string
 	config *Config
 
@@ -44,13 +49,25 @@ type Session struct {
 	moduleDir  string
 	sandboxDir string
 
+	// workspace is the selected Terraform workspace, if one was selected
+	workspace string
+
+	// invocations is the Terraform commands that were run
+	invocations []Invocation
+
+	// providers is the providers the last init installed, and
+	// providerProgress is called as it installs them
+	providers        []ProviderInstall
+	providerProgress func(ProviderInstall)
+
 	versionCachedValue *version.Version
 }
 
 // NewTerraformSession creates a new Terraform session in the specified
 // directory. It will return an error if a previous Terraform session
-// was already created here.
-func NewTerraformSession(id, baseDir string, config Config) (*Session, error) {
+// was already created here. Terraform commands are interrupted when ctx is
+// done.
+func NewTerraformSession(ctx context.Context, id, baseDir string, config Config) (*Session, error) {
 	if err := config.Validate(); err != nil {
 		return nil, err
 	}
@@ -78,7 +95,7 @@ func NewTerraformSession(id, baseDir string, config Config) (*Session, error) {
 
 	// Copy the Terraform code tree into the sandbox
 	logger.Trace.Printf("terraform: copying tree from %v to %v", config.BasePath, sandboxDir)
-	if err := cloneTree(config.BasePath, sandboxDir); err != nil {
+	if err := cloneTree(ctx, config.BasePath, sandboxDir); err != nil {
 		return nil, fmt.Errorf("unable to clone tree from %v to %v: %v", config.BasePath, sandboxDir, err)
 	}
 
@@ -88,6 +105,45 @@ func NewTerraformSession(id, baseDir string, config Config) (*Session, error) {
 	}
 
 	return &Session{
+		ctx:        ctx,
+		id:         id,
+		config:     &config,
+		baseDir:    baseDir,
+		sandboxDir: sandboxDir,
+		moduleDir:  moduleDir,
+		logDir:     logDir,
+	}, nil
+}
+
+// OpenTerraformSession opens a Terraform session that was previously
+// created in the specified directory, e.g. to apply a plan that was saved
+// in it. Terraform commands are interrupted when ctx is done.
+func OpenTerraformSession(ctx context.Context, id, baseDir string, config Config) (*Session, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	logDir, err := filepath.Abs(filepath.Join(baseDir, "logs"))
+	if err != nil {
+		return nil, err
+	}
+
+	sandboxDir, err := filepath.Abs(filepath.Join(baseDir, "sandbox"))
+	if err != nil {
+		return nil, err
+	}
+
+	if !utils.IsDirectory(sandboxDir) {
+		return nil, fmt.Errorf("cannot open session: no session exists at %v", baseDir)
+	}
+
+	moduleDir, err := filepath.Abs(filepath.Join(sandboxDir, config.ModulePath))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Session{
+		ctx:        ctx,
 		id:         id,
 		config:     &config,
 		baseDir:    baseDir,
@@ -97,29 +153,132 @@ func NewTerraformSession(id, baseDir string, config Config) (*Session, error) {
 	}, nil
 }
 
-// command returns an exec2.Process ready to be executed.
+// matches Terraform prompting for the value of a variable, e.g.
+//
+//	var.region
+//	  The AWS region
+//
+//	  Enter a value:
+var reVariablePrompt = regexp.MustCompile(`(?m)^var\.(\S+)\n(?:.*\n)*?\s*Enter a value:`)
+
+// explainPrompt converts the error returned when Terraform was killed for
+// prompting for a variable into an error that says which variable it was.
+func explainPrompt(err error) error {
+	var promptErr *exec2.PromptError
+	if errors.As(err, &promptErr) && len(promptErr.Submatches) > 1 {
+		return fmt.Errorf("terraform prompted for a value for variable %q, which was not provided; set it in the module configuration or pass it with -var", promptErr.Submatches[1])
+	}
+	return err
+}
+
+// terraformLocale is the locale Terraform and the programs it runs, e.g.
+// providers and provisioners, are run with.
+const terraformLocale = "C.UTF-8"
+
+// The output of each command is logged to <command>.log in the log
+// directory of the session, and its stdout and stderr separately to files
+// with these suffixes, e.g. plan.stdout.log. How it exited is written to
+// <command>.status.json.
+const (
+	StdoutLogSuffix  = ".stdout.log"
+	StderrLogSuffix  = ".stderr.log"
+	StatusFileSuffix = ".status.json"
+)
+
+// command returns an exec2.Process ready to be executed. Its output is
+// logged to files named after logfileName.
 func (s *Session) command(logfileName string, cmd string, args []string, expectedSuccessCodes []int) (*exec2.Process, error) {
 	env := os.Environ()
 
-	if s.config.SharedPluginDir != "" {
-		env = append(env, fmt.Sprintf("TF_PLUGIN_CACHE_DIR=%s", s.config.SharedPluginDir))
+	envDelta := s.config.environment()
+	for key, val := range envDelta {
+		env = append(env, fmt.Sprintf("%s=%s", key, val))
+	}
+
+	for name, val := range s.config.SecretVariables {
+		env = append(env, fmt.Sprintf("TF_VAR_%s=%s", name, val))
+	}
+
+	invocation := s.config.invocation(cmd, args, s.moduleDir)
+
+	logger.Debug("running terraform", logger.Fields{"id": s.id, "command": invocation.String()})
+	if err := s.recordInvocation(invocation); err != nil {
+		return nil, err
+	}
+
+	var outputWriter io.Writer
+	if s.config.OutputWriter != nil {
+		outputWriter = utils.NewPrefixWriter(s.config.OutputWriter, fmt.Sprintf("[%s] ", s.id))
 	}
 
 	return exec2.NewProcess(exec2.Cmd{
 		Command:               cmd,
+		Context:               s.ctx,
 		Args:                  args,
 		Env:                   env,
-		CombinedOutputLogFile: filepath.Join(s.logDir, fmt.Sprintf("%s.log", logfileName)),
+		CombinedOutputLogFile: filepath.Join(s.logDir, logfileName+".log"),
+		StdoutLogFile:         filepath.Join(s.logDir, logfileName+StdoutLogSuffix),
+		StderrLogFile:         filepath.Join(s.logDir, logfileName+StderrLogSuffix),
+		StatusFile:            filepath.Join(s.logDir, logfileName+StatusFileSuffix),
 		ExpectedSuccessCodes:  expectedSuccessCodes,
+		MaxOutputInMemory:     s.config.MaxOutputInMemory,
+		OutputWriter:          outputWriter,
+		PromptPattern:         reVariablePrompt,
+		SpillDir:              s.logDir,
 		WorkingDir:            s.moduleDir,
+		Clock:                 s.config.Clock,
 	}), nil
 }
 
+// environment returns the environment variables that commands run with
+// in addition to astro's own environment, except for the secret
+// variables. The locale is set so that the output that is parsed is never
+// translated; the module environment can still override it.
+func (config *Config) environment() map[string]string {
+	env := map[string]string{
+		"LANG":   terraformLocale,
+		"LC_ALL": terraformLocale,
+	}
+
+	if config.SharedPluginDir != "" {
+		env["TF_PLUGIN_CACHE_DIR"] = config.SharedPluginDir
+	}
+
+	for key, val := range config.Env {
+		env[key] = val
+	}
+
+	return env
+}
+
+// invocation returns how the command is run in dir, with the values of
+// its environment that look like secrets, and of the secret variables,
+// masked.
+func (config *Config) invocation(cmd string, args []string, dir string) Invocation {
+	invocation := newInvocation(cmd, args, config.environment(), dir)
+	for name := range config.SecretVariables {
+		invocation.Env["TF_VAR_"+name] = maskedValue
+	}
+	return invocation
+}
+
 func (s *Session) terraformCommand(args []string, expectedSuccessCodes []int) (*exec2.Process, error) {
 	if len(args) < 1 {
 		return nil, errors.New("missing args")
 	}
-	return s.command(args[0], s.config.TerraformPath, args, expectedSuccessCodes)
+	logfileName := args[0]
+	if s.workspace != "" {
+		logfileName = fmt.Sprintf("%s-%s", logfileName, s.workspace)
+	}
+	return s.command(logfileName, s.config.TerraformPath, args, expectedSuccessCodes)
+}
+
+// now returns the current time according to the clock of the session.
+func (s *Session) now() time.Time {
+	if s.config.Clock == nil {
+		return time.Now()
+	}
+	return s.config.Clock.Now()
 }
 
 // SetTerraformPath sets the path to Terraform.
@@ -129,7 +288,7 @@ func (s *Session) SetTerraformPath(path string) {
 
 // cloneTree copies the files in existingPath to newPath recursively,
 // using hard links.
-func cloneTree(existingPath string, newPath string) error {
+func cloneTree(ctx context.Context, existingPath string, newPath string) error {
 	existingPathDeref, err := filepath.EvalSymlinks(existingPath)
 	if err != nil {
 		return err
@@ -140,7 +299,7 @@ func cloneTree(existingPath string, newPath string) error {
 		return err
 	}
 
-	find := exec.Command("find", ".",
+	find := exec.CommandContext(ctx, "find", ".",
 		"!", "-path", "*/.terraform/*",
 		"!", "-name", ".terraform",
 		"!", "-path", "*/.astro/*",
@@ -148,7 +307,7 @@ func cloneTree(existingPath string, newPath string) error {
 		"!", "-name", "terraform.tfstate*",
 	)
 	find.Dir = existingPathDeref
-	cpio := exec.Command("cpio", "-pl", newPathDeref)
+	cpio := exec.CommandContext(ctx, "cpio", "-pl", newPathDeref)
 	cpio.Dir = existingPathDeref
 
 	cpio.Stdin, err = find.StdoutPipe()
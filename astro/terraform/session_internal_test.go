@@ -0,0 +1,69 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/uber/astro/astro/exec2"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplainVariablePrompt(t *testing.T) {
+	output := "Refreshing state...\nvar.region\n  The AWS region\n\n  Enter a value: "
+
+	submatches := reVariablePrompt.FindStringSubmatch(output)
+	require.NotNil(t, submatches)
+
+	err := explainPrompt(&exec2.PromptError{Submatches: submatches})
+	assert.Contains(t, err.Error(), `variable "region"`)
+
+	assert.Nil(t, reVariablePrompt.FindStringSubmatch("var.region is not set\n"))
+	assert.NoError(t, explainPrompt(nil))
+}
+
+func TestCommandPassesSecretVariablesInEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	s := &Session{
+		ctx: context.Background(),
+		id:  "app",
+		config: &Config{
+			Variables:       map[string]string{"region": "us-east-1"},
+			SecretVariables: map[string]string{"password": "hunter2"},
+		},
+		logDir:    dir,
+		moduleDir: dir,
+	}
+
+	process, err := s.command("secret", "sh", []string{"-c", "echo $TF_VAR_password"}, []int{0})
+	require.NoError(t, err)
+	require.NoError(t, process.Run())
+	assert.Equal(t, "hunter2", strings.TrimSpace(process.Stdout().String()))
+
+	// the secret is never recorded
+	require.Len(t, s.Invocations(), 1)
+	assert.Equal(t, maskedValue, s.Invocations()[0].Env["TF_VAR_password"])
+	commands, err := os.ReadFile(filepath.Join(dir, "commands.log"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(commands), "hunter2")
+}
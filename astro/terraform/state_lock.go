@@ -0,0 +1,101 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// matches the error of Terraform when the state is locked by another run
+var reStateLocked = regexp.MustCompile(`Error acquiring the state lock|Error locking state`)
+
+// matches a field of the "Lock Info" that Terraform prints when the state
+// is locked, e.g. "  Who:       jane@laptop"
+var reLockInfoField = regexp.MustCompile(`(?m)^\s*(ID|Operation|Who|Created):[ \t]*(.*?)\s*$`)
+
+// StateLockedError is returned when a Terraform command fails because
+// the lock of the state is held by another run, of astro or Terraform,
+// for longer than the lock timeout.
+type StateLockedError struct {
+	// Err is the error of the command.
+	Err error
+	// ID, Who, Operation and Created describe the lock, as reported by
+	// Terraform. They are empty if Terraform didn't report them.
+	ID        string
+	Who       string
+	Operation string
+	Created   string
+}
+
+// Error is the error message, so this satisfies the error interface.
+func (e *StateLockedError) Error() string {
+	return fmt.Sprintf("%v; %s", e.Err, e.Holder())
+}
+
+// Unwrap returns the error of the command.
+func (e *StateLockedError) Unwrap() error {
+	return e.Err
+}
+
+// Holder describes who holds the lock of the state, e.g. "the state is
+// locked by jane@laptop (OperationTypeApply since 2019-03-01 12:00:00)".
+func (e *StateLockedError) Holder() string {
+	if e.Who == "" {
+		return "the state is locked by another run"
+	}
+
+	var details []string
+	if e.Operation != "" {
+		details = append(details, e.Operation)
+	}
+	if e.Created != "" {
+		details = append(details, "since "+e.Created)
+	}
+	if e.ID != "" {
+		details = append(details, "lock ID "+e.ID)
+	}
+	if len(details) == 0 {
+		return "the state is locked by " + e.Who
+	}
+	return fmt.Sprintf("the state is locked by %s (%s)", e.Who, strings.Join(details, ", "))
+}
+
+// explainStateLock returns a *StateLockedError for an error of a command
+// whose output, stderr, says the state is locked. Other errors are
+// returned as they are.
+func explainStateLock(err error, stderr string) error {
+	if err == nil || !reStateLocked.MatchString(stderr) {
+		return err
+	}
+
+	lockErr := &StateLockedError{Err: err}
+	for _, match := range reLockInfoField.FindAllStringSubmatch(stderr, -1) {
+		switch match[1] {
+		case "ID":
+			lockErr.ID = match[2]
+		case "Who":
+			lockErr.Who = match[2]
+		case "Operation":
+			lockErr.Operation = match[2]
+		case "Created":
+			lockErr.Created = match[2]
+		}
+	}
+	return lockErr
+}
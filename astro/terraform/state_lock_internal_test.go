@@ -0,0 +1,64 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplainStateLock(t *testing.T) {
+	err := errors.New("exit status 1")
+
+	// Terraform 0.12 and later
+	stderr := `
+Error: Error acquiring the state lock
+
+Error message: ConditionalCheckFailedException: The conditional request failed
+Lock Info:
+  ID:        7d1f0b3e-4c1a-2b3c-9d8e-0f1a2b3c4d5e
+  Path:      bucket/app/terraform.tfstate
+  Operation: OperationTypeApply
+  Who:       jane@laptop
+  Version:   1.5.0
+  Created:   2019-03-01 12:00:00.000000 +0000 UTC
+  Info:
+`
+	var lockErr *StateLockedError
+	require.True(t, errors.As(explainStateLock(err, stderr), &lockErr))
+	assert.Equal(t, &StateLockedError{
+		Err:       err,
+		ID:        "7d1f0b3e-4c1a-2b3c-9d8e-0f1a2b3c4d5e",
+		Who:       "jane@laptop",
+		Operation: "OperationTypeApply",
+		Created:   "2019-03-01 12:00:00.000000 +0000 UTC",
+	}, lockErr)
+	assert.EqualError(t, lockErr, "exit status 1; the state is locked by jane@laptop (OperationTypeApply, since 2019-03-01 12:00:00.000000 +0000 UTC, lock ID 7d1f0b3e-4c1a-2b3c-9d8e-0f1a2b3c4d5e)")
+	assert.Equal(t, err, errors.Unwrap(lockErr))
+
+	// Terraform 0.11, without the lock info
+	lockErr = nil
+	require.True(t, errors.As(explainStateLock(err, "Error locking state: Error acquiring the state lock: resource temporarily unavailable"), &lockErr))
+	assert.EqualError(t, lockErr, "exit status 1; the state is locked by another run")
+
+	// other errors
+	assert.Equal(t, err, explainStateLock(err, "Error: Unsupported argument"))
+	assert.NoError(t, explainStateLock(nil, stderr))
+}
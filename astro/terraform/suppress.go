@@ -0,0 +1,340 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/uber/astro/astro/conf"
+)
+
+// matches the comment that introduces the changes to a resource in
+// Terraform 0.12 and later, e.g. "# aws_instance.web will be updated
+// in-place", or "# aws_instance.web has changed" in refresh-only plans.
+var reResourceComment = regexp.MustCompile(`^\s*# (\S+) .*?(will be|must be|has changed|has been deleted)`)
+
+// matches the line that introduces the changes to a resource in Terraform
+// 0.11 and earlier, e.g. "-/+ aws_instance.web (new resource required)".
+var reLegacyResource = regexp.MustCompile(`^\s*(\+|-|~|-/\+|<=) (\S+)(?: \(.*\))?$`)
+
+// matches a changed attribute in Terraform 0.11 and earlier, e.g.
+// `    tags.Name: "a" => "b"`.
+var reLegacyAttribute = regexp.MustCompile(`^\s+([^\s:]+):\s`)
+
+// matches the symbol that starts a changed line in Terraform 0.12 and later.
+var reChangeSymbol = regexp.MustCompile(`^(-/\+|\+/-|<=|[~+-]) `)
+
+// matches the start of a heredoc string, e.g. "<<-EOT".
+var reHeredoc = regexp.MustCompile(`<<-?(\w+)$`)
+
+// matches the escape sequences Terraform colorizes its output with.
+var reColor = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// suppressionRule is a compiled conf.Suppression.
+type suppressionRule struct {
+	pattern   *regexp.Regexp
+	attribute []string
+	resource  string
+}
+
+// compileSuppressions compiles the suppressions of the configuration.
+func compileSuppressions(suppressions []conf.Suppression) ([]suppressionRule, error) {
+	var rules []suppressionRule
+	for _, suppression := range suppressions {
+		rule := suppressionRule{resource: suppression.Resource}
+		if suppression.Pattern != "" {
+			pattern, err := regexp.Compile(suppression.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid suppression pattern: %v", err)
+			}
+			rule.pattern = pattern
+		} else {
+			rule.attribute = strings.Split(suppression.Attribute, ".")
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// matches returns whether the rule suppresses a changed line of a plan.
+// address is the resource the line belongs to, which is empty if it
+// doesn't belong to one, e.g. for changes to outputs, and attribute is
+// the path of the attribute it changes.
+func (rule suppressionRule) matches(address string, attribute []string, line string) bool {
+	if rule.resource != "" {
+		// Validate ensures the glob is valid
+		if matched, _ := path.Match(rule.resource, address); !matched || address == "" {
+			return false
+		}
+	}
+	if rule.pattern != nil {
+		return rule.pattern.MatchString(line)
+	}
+	if address == "" || len(attribute) < len(rule.attribute) {
+		return false
+	}
+	for i, element := range rule.attribute {
+		if matched, _ := path.Match(element, attribute[i]); !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// planBlock is a part of the changes of a plan: the changes to a single
+// resource, or other lines, such as the changes to outputs.
+type planBlock struct {
+	// address is the address of the resource, if the block is one.
+	address string
+	// counts is what the changes to the resource count for in the
+	// summary of the plan.
+	counts ChangeCounts
+	// header is set if the first line starts the block, e.g. the comment
+	// before a resource, and summary if it is the summary of the plan.
+	header, summary bool
+	// lines is the output of the block, and plain the same without
+	// colors.
+	lines, plain []string
+	// changed is the number of changed lines, and kept the number of
+	// them that were not suppressed.
+	changed, kept int
+}
+
+// suppressChanges removes the lines matched by the rules from the changes
+// of a plan. Resources that are left without changed lines are removed
+// entirely, and their changes are returned separately. hasChanges is
+// whether there are changes left.
+func suppressChanges(changes string, rules []suppressionRule) (filtered string, suppressed ChangeCounts, hasChanges bool) {
+	blocks, legacy := splitPlanBlocks(changes)
+
+	for _, block := range blocks {
+		block.suppress(rules, legacy)
+	}
+
+	var lines []string
+	for _, block := range blocks {
+		if block.changed > 0 && block.kept == 0 {
+			suppressed = suppressed.Plus(block.counts)
+		}
+	}
+	for _, block := range blocks {
+		switch {
+		case block.changed > 0 && block.kept == 0:
+			continue
+		case block.summary && suppressed.Total() > 0:
+			// the summary must not count the suppressed changes
+			counts := parseChangeCounts(block.plain[0]).minus(suppressed)
+			indent := block.plain[0][:len(block.plain[0])-len(strings.TrimLeft(block.plain[0], " "))]
+			lines = append(lines, fmt.Sprintf("%sPlan: %d to add, %d to change, %d to destroy.", indent, counts.Add, counts.Change, counts.Destroy))
+			lines = append(lines, block.lines[1:]...)
+			continue
+		case block.kept > 0 || block.address != "":
+			hasChanges = true
+		}
+		lines = append(lines, block.lines...)
+	}
+
+	return strings.Join(lines, "\n"), suppressed, hasChanges
+}
+
+// splitPlanBlocks splits the changes of a plan into blocks, and returns
+// whether they are in the format of Terraform 0.11 and earlier, which
+// doesn't introduce resources with a comment.
+func splitPlanBlocks(changes string) (blocks []*planBlock, legacy bool) {
+	lines := strings.Split(changes, "\n")
+	plain := make([]string, len(lines))
+	legacy = true
+	for i, line := range lines {
+		plain[i] = reColor.ReplaceAllString(line, "")
+		if reResourceComment.MatchString(plain[i]) {
+			legacy = false
+		}
+	}
+
+	block := &planBlock{}
+	blocks = []*planBlock{block}
+	for i, line := range lines {
+		var next *planBlock
+		if match := reResourceComment.FindStringSubmatch(plain[i]); match != nil && !legacy {
+			next = &planBlock{address: match[1], counts: commentCounts(plain[i])}
+		} else if match := reLegacyResource.FindStringSubmatch(plain[i]); match != nil && legacy {
+			next = &planBlock{address: match[2], counts: symbolCounts(match[1])}
+		} else if strings.TrimSpace(plain[i]) == "Changes to Outputs:" {
+			next = &planBlock{}
+		} else if rePlanSummary.MatchString(plain[i]) {
+			next = &planBlock{summary: true}
+		}
+		if next != nil {
+			next.header = true
+			block = next
+			blocks = append(blocks, block)
+		}
+		block.lines = append(block.lines, line)
+		block.plain = append(block.plain, plain[i])
+	}
+
+	return blocks, legacy
+}
+
+// suppress removes the changed lines matched by the rules from the block.
+// In Terraform 0.12 and later, lines that open a nested block, such as
+// `~ tags = {`, add to the path of the attributes in it, and suppressing
+// them suppresses everything up to the line that closes the block.
+func (b *planBlock) suppress(rules []suppressionRule, legacy bool) {
+	var lines, plain, stack []string
+	suppressedDepth := -1
+	heredoc := ""
+
+	for i, line := range b.lines {
+		if i == 0 && b.header {
+			lines = append(lines, line)
+			plain = append(plain, b.plain[i])
+			continue
+		}
+
+		text := strings.TrimSpace(b.plain[i])
+		symbol := reChangeSymbol.FindString(text)
+		rest := strings.TrimPrefix(text, symbol)
+		changed := symbol != ""
+		var key string
+		var opens, closes bool
+
+		switch {
+		case legacy:
+			if match := reLegacyAttribute.FindStringSubmatch(b.plain[i]); match != nil {
+				key = match[1]
+				changed = true
+			}
+		case heredoc != "":
+			if text == heredoc {
+				closes = true
+				heredoc = ""
+			}
+		case strings.HasPrefix(rest, "}") || strings.HasPrefix(rest, "]") || strings.HasPrefix(rest, ")"):
+			closes = true
+		default:
+			key = attributeKey(rest)
+			if match := reHeredoc.FindStringSubmatch(rest); match != nil {
+				opens = true
+				heredoc = match[1]
+			} else if strings.HasSuffix(rest, "{") || strings.HasSuffix(rest, "[") || strings.HasSuffix(rest, "(") {
+				opens = true
+			}
+		}
+
+		suppressed := suppressedDepth >= 0
+		if !suppressed && changed {
+			attribute := append(append([]string{}, stack...), strings.Split(key, ".")...)
+			attribute = nonEmpty(attribute)
+			for _, rule := range rules {
+				if rule.matches(b.address, attribute, text) {
+					suppressed = true
+					break
+				}
+			}
+		}
+
+		if opens {
+			if suppressed && suppressedDepth < 0 {
+				suppressedDepth = len(stack)
+			}
+			stack = append(stack, key)
+		}
+		if closes && len(stack) > 0 {
+			stack = stack[:len(stack)-1]
+			if suppressedDepth == len(stack) {
+				suppressedDepth = -1
+			}
+		}
+
+		if changed && !opens && !closes {
+			b.changed++
+			if !suppressed {
+				b.kept++
+			}
+		}
+		if !suppressed {
+			lines = append(lines, line)
+			plain = append(plain, b.plain[i])
+		}
+	}
+
+	b.lines, b.plain = lines, plain
+}
+
+// attributeKey returns the name of the attribute or nested block that a
+// line of a plan changes, e.g. "tags" for `tags = {`, or "" if it has
+// none, e.g. for the line that opens a resource or an element of a list.
+func attributeKey(line string) string {
+	if strings.HasPrefix(line, `resource "`) || strings.HasPrefix(line, `data "`) {
+		return ""
+	}
+	if i := strings.Index(line, " = "); i >= 0 {
+		return strings.Trim(strings.TrimSpace(line[:i]), `"`)
+	}
+	if fields := strings.Fields(line); len(fields) == 2 && fields[1] == "{" {
+		return fields[0]
+	}
+	return ""
+}
+
+// commentCounts returns what the changes to a resource count for in the
+// summary of a plan, from the comment that introduces them.
+func commentCounts(comment string) ChangeCounts {
+	switch {
+	case strings.Contains(comment, "replaced"):
+		return ChangeCounts{Add: 1, Destroy: 1}
+	case strings.Contains(comment, "will be created"):
+		return ChangeCounts{Add: 1}
+	case strings.Contains(comment, "will be destroyed"), strings.Contains(comment, "has been deleted"):
+		return ChangeCounts{Destroy: 1}
+	case strings.Contains(comment, "will be updated"), strings.Contains(comment, "has changed"):
+		return ChangeCounts{Change: 1}
+	}
+	return ChangeCounts{}
+}
+
+// symbolCounts returns what the changes to a resource count for in the
+// summary of a plan, from the symbol of its action in Terraform 0.11 and
+// earlier.
+func symbolCounts(symbol string) ChangeCounts {
+	switch symbol {
+	case "-/+":
+		return ChangeCounts{Add: 1, Destroy: 1}
+	case "+":
+		return ChangeCounts{Add: 1}
+	case "-":
+		return ChangeCounts{Destroy: 1}
+	case "~":
+		return ChangeCounts{Change: 1}
+	}
+	return ChangeCounts{}
+}
+
+// nonEmpty returns the elements of s that are not empty.
+func nonEmpty(s []string) []string {
+	var ret []string
+	for _, e := range s {
+		if e != "" {
+			ret = append(ret, e)
+		}
+	}
+	return ret
+}
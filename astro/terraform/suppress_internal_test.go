@@ -0,0 +1,161 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"testing"
+
+	"github.com/uber/astro/astro/conf"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testNoisyPlan = `
+  # aws_instance.web will be updated in-place
+  ~ resource "aws_instance" "web" {
+        id            = "i-123"
+      ~ instance_type = "t2.micro" -> "t2.small"
+      ~ tags          = {
+          ~ "LastModified" = "2019-01-01" -> "2019-06-01"
+            # (1 unchanged element hidden)
+        }
+    }
+
+  # aws_s3_bucket.logs will be updated in-place
+  ~ resource "aws_s3_bucket" "logs" {
+        id   = "logs"
+      ~ tags = {
+          ~ "LastModified" = "2019-01-01" -> "2019-06-01"
+        }
+      ~ policy = <<-EOT
+          - old
+          + new
+        EOT
+    }
+
+Plan: 0 to add, 2 to change, 0 to destroy.
+`
+
+func TestSuppressChanges(t *testing.T) {
+	tt := []struct {
+		name         string
+		changes      string
+		suppressions []conf.Suppression
+		expected     string
+		suppressed   ChangeCounts
+		hasChanges   bool
+	}{
+		{
+			name:    "attribute",
+			changes: testNoisyPlan,
+			suppressions: []conf.Suppression{
+				{Attribute: "tags.LastModified"},
+				{Attribute: "policy", Resource: "aws_s3_bucket.*"},
+			},
+			expected: `
+  # aws_instance.web will be updated in-place
+  ~ resource "aws_instance" "web" {
+        id            = "i-123"
+      ~ instance_type = "t2.micro" -> "t2.small"
+      ~ tags          = {
+            # (1 unchanged element hidden)
+        }
+    }
+
+Plan: 0 to add, 1 to change, 0 to destroy.
+`,
+			suppressed: ChangeCounts{Change: 1},
+			hasChanges: true,
+		},
+		{
+			name:    "pattern of a nested block",
+			changes: testNoisyPlan,
+			suppressions: []conf.Suppression{
+				{Pattern: `tags\s+= \{`},
+				{Pattern: `instance_type`},
+			},
+			expected: `
+  # aws_s3_bucket.logs will be updated in-place
+  ~ resource "aws_s3_bucket" "logs" {
+        id   = "logs"
+      ~ policy = <<-EOT
+          - old
+          + new
+        EOT
+    }
+
+Plan: 0 to add, 1 to change, 0 to destroy.
+`,
+			suppressed: ChangeCounts{Change: 1},
+			hasChanges: true,
+		},
+		{
+			name:    "attribute glob not matching",
+			changes: testNoisyPlan,
+			suppressions: []conf.Suppression{
+				{Attribute: "tags.*", Resource: "aws_autoscaling_group.*"},
+			},
+			expected:   testNoisyPlan,
+			hasChanges: true,
+		},
+		{
+			name: "refresh-only",
+			changes: `
+  # aws_instance.web has changed
+  ~ resource "aws_instance" "web" {
+        id   = "i-123"
+      ~ tags = {
+          + "LastModified" = "2019-06-01"
+        }
+    }
+`,
+			suppressions: []conf.Suppression{{Attribute: "tags.*"}},
+			expected:     "",
+			suppressed:   ChangeCounts{Change: 1},
+			hasChanges:   false,
+		},
+		{
+			name: "Terraform 0.11",
+			changes: `~ aws_instance.web
+    tags.%:            "1" => "2"
+    tags.LastModified: "" => "2019-06-01"
+
+-/+ aws_instance.db (new resource required)
+    ami: "ami-1" => "ami-2" (forces new resource)
+`,
+			suppressions: []conf.Suppression{{Attribute: "tags"}},
+			expected: `-/+ aws_instance.db (new resource required)
+    ami: "ami-1" => "ami-2" (forces new resource)
+`,
+			suppressed: ChangeCounts{Change: 1},
+			hasChanges: true,
+		},
+	}
+
+	for _, test := range tt {
+		t.Run(test.name, func(t *testing.T) {
+			rules, err := compileSuppressions(test.suppressions)
+			require.NoError(t, err)
+
+			changes, suppressed, hasChanges := suppressChanges(test.changes, rules)
+			assert.Equal(t, test.expected, changes)
+			assert.Equal(t, test.suppressed, suppressed)
+			assert.Equal(t, test.hasChanges, hasChanges)
+		})
+	}
+}
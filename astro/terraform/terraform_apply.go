@@ -18,6 +18,11 @@ package terraform
 
 import (
 	"fmt"
+	"path/filepath"
+
+	"github.com/uber/astro/astro/utils"
+
+	"github.com/burl/go-version"
 )
 
 // Apply runs a `terraform apply`
@@ -33,26 +38,87 @@ func (s *Session) Apply() (Result, error) {
 		return nil, err
 	}
 
-	args := []string{"apply"}
+	args := s.config.applyArgs(terraformVersion)
 
-	if VersionMatches(terraformVersion, ">= 0.11") {
-		args = append(args, "-auto-approve")
+	process, err := s.terraformCommand(args, []int{0})
+	if err != nil {
+		return nil, err
 	}
 
-	for key, val := range s.config.Variables {
-		args = append(args, "-var", fmt.Sprintf("%s=%s", key, val))
+	err = s.run(process)
+
+	return &terraformResult{
+		process: process,
+	}, explainPrompt(err)
+}
+
+// ApplyPlan runs a `terraform apply` of the plan saved by a previous call
+// to Plan in this session, so that exactly the planned changes are
+// applied.
+func (s *Session) ApplyPlan() (Result, error) {
+	if !utils.FileExists(filepath.Join(s.moduleDir, s.planFile())) {
+		return nil, fmt.Errorf("no saved plan found in %v", s.moduleDir)
 	}
 
-	args = append(args, s.config.TerraformParameters...)
+	args := s.config.applyPlanArgs(s.planFile())
 
 	process, err := s.terraformCommand(args, []int{0})
 	if err != nil {
 		return nil, err
 	}
 
-	err = process.Run()
+	err = s.run(process)
 
 	return &terraformResult{
 		process: process,
-	}, err
+	}, explainPrompt(err)
+}
+
+// applyArgs returns the arguments of the apply command of the Terraform
+// version, which plans and applies in one go.
+func (config *Config) applyArgs(terraformVersion *version.Version) []string {
+	args := []string{"apply"}
+
+	if VersionMatches(terraformVersion, ">= 0.11") {
+		args = append(args, "-auto-approve")
+	}
+
+	if config.DisableInput {
+		args = append(args, "-input=false")
+	}
+
+	if config.LockTimeout > 0 {
+		args = append(args, fmt.Sprintf("-lock-timeout=%s", config.LockTimeout))
+	}
+
+	for _, varFile := range config.VarFiles {
+		args = append(args, fmt.Sprintf("-var-file=%s", varFile))
+	}
+
+	for _, key := range sortedKeys(config.Variables) {
+		args = append(args, "-var", fmt.Sprintf("%s=%s", key, config.Variables[key]))
+	}
+
+	for _, target := range config.Targets {
+		args = append(args, fmt.Sprintf("-target=%s", target))
+	}
+
+	return append(args, config.TerraformParameters...)
+}
+
+// applyPlanArgs returns the arguments of the apply command that applies
+// the plan saved to planFile.
+func (config *Config) applyPlanArgs(planFile string) []string {
+	args := []string{"apply"}
+
+	if config.DisableInput {
+		args = append(args, "-input=false")
+	}
+
+	if config.LockTimeout > 0 {
+		args = append(args, fmt.Sprintf("-lock-timeout=%s", config.LockTimeout))
+	}
+
+	args = append(args, config.TerraformParameters...)
+	return append(args, planFile)
 }
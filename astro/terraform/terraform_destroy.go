@@ -0,0 +1,78 @@
+/*
+ *  Copyright (c) 2018 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"fmt"
+)
+
+// Destroy runs a `terraform destroy`
+func (s *Session) Destroy() (Result, error) {
+	if !s.Initialized() {
+		if result, err := s.Init(); err != nil {
+			return result, err
+		}
+	}
+
+	terraformVersion, err := s.versionCached()
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"destroy"}
+
+	// -force was replaced with -auto-approve, which older versions don't
+	// support for destroy.
+	if VersionMatches(terraformVersion, ">= 0.12") {
+		args = append(args, "-auto-approve")
+	} else {
+		args = append(args, "-force")
+	}
+
+	if s.config.DisableInput {
+		args = append(args, "-input=false")
+	}
+
+	if s.config.LockTimeout > 0 {
+		args = append(args, fmt.Sprintf("-lock-timeout=%s", s.config.LockTimeout))
+	}
+
+	for _, varFile := range s.config.VarFiles {
+		args = append(args, fmt.Sprintf("-var-file=%s", varFile))
+	}
+
+	for key, val := range s.config.Variables {
+		args = append(args, "-var", fmt.Sprintf("%s=%s", key, val))
+	}
+
+	for _, target := range s.config.Targets {
+		args = append(args, fmt.Sprintf("-target=%s", target))
+	}
+
+	args = append(args, s.config.TerraformParameters...)
+
+	process, err := s.terraformCommand(args, []int{0})
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.run(process)
+
+	return &terraformResult{
+		process: process,
+	}, explainPrompt(err)
+}
@@ -23,7 +23,7 @@ func (s *Session) Get() (Result, error) {
 		return nil, err
 	}
 
-	err = process.Run()
+	err = s.run(process)
 
 	return &terraformResult{
 		process: process,
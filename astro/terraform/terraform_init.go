@@ -23,32 +23,42 @@ import (
 
 	"github.com/uber/astro/astro/logger"
 	"github.com/uber/astro/astro/utils"
+
+	"github.com/burl/go-version"
 )
 
-func (s *Session) terraformInitArgsLegacy() ([]string, error) {
+func (config *Config) terraformInitArgsLegacy() ([]string, error) {
 	args := []string{"remote", "config"}
 
-	if s.config.Remote.Backend != "" {
-		args = append(args, "-backend", s.config.Remote.Backend)
+	if config.Remote.Backend != "" {
+		args = append(args, "-backend", config.Remote.Backend)
 	}
 
-	for key, val := range s.config.Remote.BackendConfig {
-		args = append(args, fmt.Sprintf("-backend-config=%s=%s", key, val))
+	for _, key := range sortedKeys(config.Remote.BackendConfig) {
+		args = append(args, fmt.Sprintf("-backend-config=%s=%s", key, config.Remote.BackendConfig[key]))
 	}
 
 	return args, nil
 }
 
-func (s *Session) terraformInitArgsModern() ([]string, error) {
+func (config *Config) terraformInitArgsModern(terraformVersion *version.Version) ([]string, error) {
 	args := []string{"init"}
 
-	if s.config.Remote.Backend != "" {
+	if config.Remote.Backend != "" {
 		return nil, errors.New("backend configuration was specified but is not compatible with Terraform 0.9.x and later")
 	}
 
+	// The dependency lock file is only honoured in Terraform 1.0 and later
+	if config.Lockfile != "" {
+		if !VersionMatches(terraformVersion, ">= 1.0") {
+			return nil, fmt.Errorf("lockfile requires Terraform 1.0 or later, not %v", terraformVersion)
+		}
+		args = append(args, fmt.Sprintf("-lockfile=%s", config.Lockfile))
+	}
+
 	// Backend config parameters are permitted, however
-	for key, val := range s.config.Remote.BackendConfig {
-		args = append(args, fmt.Sprintf("-backend-config=%s=%s", key, val))
+	for _, key := range sortedKeys(config.Remote.BackendConfig) {
+		args = append(args, fmt.Sprintf("-backend-config=%s=%s", key, config.Remote.BackendConfig[key]))
 	}
 
 	// Input is a new option that means Terraform will return an
@@ -59,6 +69,21 @@ func (s *Session) terraformInitArgsModern() ([]string, error) {
 	return args, nil
 }
 
+// initializesBackend returns whether init runs before get. Before
+// Terraform 0.9, it only configures the remote state, if there is one.
+func (config *Config) initializesBackend(terraformVersion *version.Version) bool {
+	return VersionMatches(terraformVersion, ">= 0.9") || config.Remote.Backend != ""
+}
+
+// initArgs returns the arguments of the init command of the Terraform
+// version.
+func (config *Config) initArgs(terraformVersion *version.Version) ([]string, error) {
+	if VersionMatches(terraformVersion, "< 0.9") {
+		return config.terraformInitArgsLegacy()
+	}
+	return config.terraformInitArgsModern(terraformVersion)
+}
+
 // Init initializes a Terraform module. This needs to happen before other
 // commands like "plan" and "apply" can be called. See:
 // https://www.terraform.io/docs/commands/init.html
@@ -72,22 +97,13 @@ func (s *Session) Init() (Result, error) {
 
 	// If we're on 0.8.x and lower and there is no backend config, we
 	// can skip straight to the `terraform get`. No init required.
-	if VersionMatches(terraformVersion, "< 0.9") && s.config.Remote.Backend == "" {
+	if !s.config.initializesBackend(terraformVersion) {
 		return s.Get()
 	}
 
-	var args []string
-
-	if VersionMatches(terraformVersion, "< 0.9") {
-		args, err = s.terraformInitArgsLegacy()
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		args, err = s.terraformInitArgsModern()
-		if err != nil {
-			return nil, err
-		}
+	args, err := s.config.initArgs(terraformVersion)
+	if err != nil {
+		return nil, err
 	}
 
 	process, err := s.terraformCommand(args, []int{0})
@@ -95,7 +111,12 @@ func (s *Session) Init() (Result, error) {
 		return nil, err
 	}
 
-	if err := process.Run(); err != nil {
+	s.providers = nil
+	process.WatchLines(s.watchProviders)
+
+	err = s.run(process)
+	s.logProviders()
+	if err != nil {
 		logger.Trace.Printf("terraform: init failed: %v\n", err)
 		return &terraformResult{
 			process: process,
@@ -105,6 +126,48 @@ func (s *Session) Init() (Result, error) {
 	return s.Get()
 }
 
+// SetProviderProgress makes init call fn for each provider it installs,
+// as it installs it, e.g. to show which provider a slow init is
+// downloading.
+func (s *Session) SetProviderProgress(fn func(ProviderInstall)) {
+	s.providerProgress = fn
+}
+
+// Providers returns the providers that the last init installed.
+func (s *Session) Providers() []ProviderInstall {
+	return s.providers
+}
+
+// watchProviders records the providers that a line of the output of init
+// reports.
+func (s *Session) watchProviders(line string) {
+	for _, provider := range parseProviderLine(line) {
+		if provider.Origin != ProviderDownloading {
+			s.providers = append(s.providers, provider)
+		}
+		if s.providerProgress != nil {
+			s.providerProgress(provider)
+		}
+	}
+}
+
+// logProviders logs the providers that init installed, once it's done and
+// their binaries are in place.
+func (s *Session) logProviders() {
+	for i := range s.providers {
+		provider := &s.providers[i]
+		locateProvider(s.moduleDir, provider)
+		logger.Info("installed provider", logger.Fields{
+			"id":       s.id,
+			"provider": provider.Name,
+			"version":  provider.Version,
+			"origin":   provider.Origin,
+			"host":     provider.Host,
+			"size":     provider.Size,
+		})
+	}
+}
+
 // Initialized returns whether or not `terraform init` has been run.
 func (s *Session) Initialized() bool {
 	terraformSpecialDir := filepath.Join(s.moduleDir, ".terraform")
@@ -0,0 +1,95 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"testing"
+
+	"github.com/burl/go-version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInitArgsLockfile(t *testing.T) {
+	s := &Session{config: &Config{Lockfile: "readonly"}}
+
+	args, err := s.config.terraformInitArgsModern(version.Must(version.NewVersion("1.3.7")))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"init", "-lockfile=readonly", "-input=false"}, args)
+
+	_, err = s.config.terraformInitArgsModern(version.Must(version.NewVersion("0.14.11")))
+	assert.EqualError(t, err, "lockfile requires Terraform 1.0 or later, not 0.14.11")
+}
+
+func TestParseProviderLine(t *testing.T) {
+	for line, expected := range map[string][]ProviderInstall{
+		`- Installing hashicorp/aws v5.31.0...`: {
+			{Name: "hashicorp/aws", Version: "5.31.0", Origin: ProviderDownloading},
+		},
+		`- Installed hashicorp/aws v5.31.0 (signed by HashiCorp)`: {
+			{Name: "hashicorp/aws", Version: "5.31.0", Origin: ProviderDownloaded},
+		},
+		`- Using hashicorp/aws v5.31.0 from the shared cache directory`: {
+			{Name: "hashicorp/aws", Version: "5.31.0", Origin: ProviderCached},
+		},
+		`- Using previously-installed hashicorp/null v3.2.1`: {
+			{Name: "hashicorp/null", Version: "3.2.1", Origin: ProviderReused},
+		},
+		`- Downloading plugin for provider "aws" (hashicorp/aws) 2.70.0...`: {
+			{Name: "hashicorp/aws", Version: "2.70.0", Origin: ProviderDownloading},
+			{Name: "hashicorp/aws", Version: "2.70.0", Origin: ProviderDownloaded},
+		},
+		`- Downloading plugin for provider "aws" (1.60.0)...`: {
+			{Name: "aws", Version: "1.60.0", Origin: ProviderDownloading},
+			{Name: "aws", Version: "1.60.0", Origin: ProviderDownloaded},
+		},
+		`- Finding hashicorp/aws versions matching "~> 5.0"...`: nil,
+		`Terraform has been successfully initialized!`:          nil,
+	} {
+		assert.Equal(t, expected, parseProviderLine(line), line)
+	}
+}
+
+func TestInitProviders(t *testing.T) {
+	s := testCrashSession(t, `if [ "$1" = "init" ]; then
+    dir=.terraform/providers/registry.terraform.io/hashicorp/null/3.2.1/linux_amd64
+    mkdir -p $dir
+    printf '12345' > $dir/terraform-provider-null_v3.2.1_x5
+    echo "Initializing provider plugins..."
+    echo "- Using previously-installed hashicorp/aws v5.31.0"
+    echo "- Installing hashicorp/null v3.2.1..."
+    echo "- Installed hashicorp/null v3.2.1 (signed by HashiCorp)"
+fi
+`)
+	s.versionCachedValue = version.Must(version.NewVersion("1.3.7"))
+
+	var progress []ProviderInstall
+	s.SetProviderProgress(func(provider ProviderInstall) {
+		progress = append(progress, provider)
+	})
+
+	_, err := s.Init()
+	require.NoError(t, err)
+
+	require.Len(t, progress, 3)
+	assert.Equal(t, ProviderDownloading, progress[1].Origin)
+	assert.Equal(t, []ProviderInstall{
+		{Name: "hashicorp/aws", Version: "5.31.0", Origin: ProviderReused},
+		{Name: "hashicorp/null", Version: "3.2.1", Origin: ProviderDownloaded, Host: "registry.terraform.io", Size: 5},
+	}, s.Providers())
+	assert.Equal(t, "hashicorp/aws v5.31.0 (reused)", s.Providers()[0].String())
+}
@@ -0,0 +1,78 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Output is an output of a module, as printed by `terraform output -json`.
+type Output struct {
+	Sensitive bool `json:"sensitive"`
+	// Type is the type of the value: a string before Terraform 0.12, and
+	// a type constraint after.
+	Type  json.RawMessage `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Output runs `terraform output -json` and returns the outputs of the
+// module, by name. Sensitive values are included.
+func (s *Session) Output() (map[string]Output, Result, error) {
+	process, err := s.terraformCommand([]string{"output", "-json"}, []int{0, 1})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := &terraformResult{
+		process: process,
+	}
+
+	if err := process.Run(); err != nil {
+		return nil, result, err
+	}
+
+	// Before Terraform 0.12, output exits with 1 when there are no
+	// outputs, even with -json.
+	if process.ExitCode() == 1 {
+		if strings.Contains(process.Stderr().String(), "no outputs defined") {
+			return map[string]Output{}, result, nil
+		}
+		return nil, result, fmt.Errorf("terraform output failed: %s", strings.TrimSpace(process.Stderr().String()))
+	}
+
+	outputs, err := parseOutputs(process.Stdout().String())
+	if err != nil {
+		return nil, result, err
+	}
+
+	return outputs, result, nil
+}
+
+// parseOutputs parses the output of `terraform output -json`.
+func parseOutputs(output string) (map[string]Output, error) {
+	outputs := map[string]Output{}
+	if strings.TrimSpace(output) == "" {
+		return outputs, nil
+	}
+
+	if err := json.Unmarshal([]byte(output), &outputs); err != nil {
+		return nil, fmt.Errorf("unable to parse outputs: %v", err)
+	}
+
+	return outputs, nil
+}
@@ -0,0 +1,44 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package terraform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOutputs(t *testing.T) {
+	outputs, err := parseOutputs(`{
+  "vpc_id": {"sensitive": false, "type": "string", "value": "vpc-123"},
+  "subnets": {"sensitive": false, "type": ["list", "string"], "value": ["a", "b"]},
+  "password": {"sensitive": true, "type": "string", "value": "hunter2"}
+}`)
+	require.NoError(t, err)
+
+	assert.Len(t, outputs, 3)
+	assert.JSONEq(t, `"vpc-123"`, string(outputs["vpc_id"].Value))
+	assert.JSONEq(t, `["list", "string"]`, string(outputs["subnets"].Type))
+	assert.True(t, outputs["password"].Sensitive)
+
+	outputs, err = parseOutputs("\n")
+	require.NoError(t, err)
+	assert.Empty(t, outputs)
+
+	_, err = parseOutputs("Terraform v0.8.8")
+	assert.Error(t, err)
+}
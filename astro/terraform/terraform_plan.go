@@ -18,9 +18,57 @@ package terraform
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"regexp"
+
+	"github.com/burl/go-version"
 )
 
+// planFile is the name of the file that plans are saved to, relative to
+// the module directory.
+func (s *Session) planFile() string {
+	return planFileName(s.id)
+}
+
+// planFileName returns the name of the file the plan of the session with
+// the ID is saved to, in the module directory.
+func planFileName(id string) string {
+	return fmt.Sprintf("%s.plan", id)
+}
+
+// planArgs returns the arguments of the plan command, which saves the plan
+// to planFile.
+func (config *Config) planArgs(planFile string, refreshOnly bool) []string {
+	args := []string{"plan", "-detailed-exitcode", fmt.Sprintf("-out=%s", planFile)}
+
+	if refreshOnly {
+		args = append(args, "-refresh-only")
+	}
+
+	if config.DisableInput {
+		args = append(args, "-input=false")
+	}
+
+	if config.LockTimeout > 0 {
+		args = append(args, fmt.Sprintf("-lock-timeout=%s", config.LockTimeout))
+	}
+
+	for _, varFile := range config.VarFiles {
+		args = append(args, fmt.Sprintf("-var-file=%s", varFile))
+	}
+
+	for _, key := range sortedKeys(config.Variables) {
+		args = append(args, "-var", fmt.Sprintf("%s=%s", key, config.Variables[key]))
+	}
+
+	for _, target := range config.Targets {
+		args = append(args, fmt.Sprintf("-target=%s", target))
+	}
+
+	return append(args, config.TerraformParameters...)
+}
+
 // Plan runs a `terraform plan`
 func (s *Session) Plan() (Result, error) {
 	if !s.Initialized() {
@@ -29,26 +77,25 @@ func (s *Session) Plan() (Result, error) {
 		}
 	}
 
-	args := []string{"plan", "-detailed-exitcode", fmt.Sprintf("-out=%s.plan", s.id)}
-
-	for key, val := range s.config.Variables {
-		args = append(args, "-var", fmt.Sprintf("%s=%s", key, val))
+	refreshOnly, err := s.refreshOnly()
+	if err != nil {
+		return nil, err
 	}
-
-	args = append(args, s.config.TerraformParameters...)
+	args := s.config.planArgs(s.planFile(), refreshOnly)
 
 	process, err := s.terraformCommand(args, []int{0, 2})
 	if err != nil {
 		return nil, err
 	}
 
-	if err := process.Run(); err != nil {
+	if err := s.run(process); err != nil {
 		return &terraformResult{
 			process: process,
-		}, err
+		}, explainPrompt(err)
 	}
 
 	var changes string
+	var counts *ChangeCounts
 
 	// With -detailed-exitcode, plans that return exit code 2 mean there
 	// are changes (so there's no error).
@@ -59,28 +106,107 @@ func (s *Session) Plan() (Result, error) {
 			return nil, err
 		}
 		if VersionMatches(terraformVersion, "<0.12") {
-			result, err := s.Show(fmt.Sprintf("%s.plan", s.id))
+			result, err := s.Show(s.planFile())
 			if err != nil {
 				return result, err
 			}
 			changes = result.Stdout()
+		} else if s.config.RawOutput {
+			changes = process.Stdout().String()
+		} else if refreshOnly {
+			if match := reDriftChanges.FindStringSubmatch(process.Stdout().String()); match != nil {
+				changes = match[1]
+			} else {
+				return &terraformResult{
+					process: process,
+				}, fmt.Errorf("unable to parse terraform plan output")
+			}
 		} else {
 			rawPlanOutput := process.Stdout().String()
-			var re = regexp.MustCompile(`(?s)Terraform will perform the following actions:(.*)-{72}`)
-			if match := re.FindStringSubmatch(rawPlanOutput); len(match) == 2 {
-				changes = match[1]
+			if match := rePlanActions.FindStringSubmatch(rawPlanOutput); match != nil {
+				changes = match[1] + match[2]
 			} else {
 				return &terraformResult{
 					process: process,
 				}, fmt.Errorf("unable to parse terraform plan output")
 			}
 		}
+
+		// Terraform 1.0 and later describe the plan in a machine-readable
+		// format, which is more reliable than the summary line. Plans
+		// that only refresh have no resource changes to count.
+		if VersionMatches(terraformVersion, ">= 1.0") && !refreshOnly {
+			result, err := s.ShowJSON(s.planFile())
+			if err != nil {
+				return result, err
+			}
+			planCounts, err := parsePlanJSON([]byte(result.Stdout()))
+			if err != nil {
+				return result, fmt.Errorf("unable to parse terraform plan: %v", err)
+			}
+			counts = &planCounts
+		}
 	}
 
-	return &PlanResult{
+	result := &PlanResult{
 		terraformResult: &terraformResult{
 			process: process,
 		},
 		changes: changes,
-	}, nil
+		counts:  counts,
+		raw:     s.config.RawOutput,
+	}
+
+	if process.ExitCode() == 2 && len(s.config.Suppressions) > 0 && !s.config.RawOutput {
+		rules, err := compileSuppressions(s.config.Suppressions)
+		if err != nil {
+			return result, err
+		}
+		var hasChanges bool
+		result.changes, result.suppressed, hasChanges = suppressChanges(changes, rules)
+		result.allSuppressed = !hasChanges
+	}
+
+	return result, nil
 }
+
+// DiscardPlan deletes the plan made by Plan, so that it cannot be
+// applied.
+func (s *Session) DiscardPlan() error {
+	err := os.Remove(filepath.Join(s.moduleDir, s.planFile()))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// refreshOnly returns whether plans are made with -refresh-only, which
+// requires Terraform 0.15.4 or later.
+func (s *Session) refreshOnly() (bool, error) {
+	if !s.config.RefreshOnly {
+		return false, nil
+	}
+
+	terraformVersion, err := s.versionCached()
+	if err != nil {
+		return false, err
+	}
+
+	return s.config.refreshesOnly(terraformVersion), nil
+}
+
+// refreshesOnly returns whether plans are made with -refresh-only with the
+// Terraform version.
+func (config *Config) refreshesOnly(terraformVersion *version.Version) bool {
+	return config.RefreshOnly && VersionMatches(terraformVersion, ">= 0.15.4")
+}
+
+// matches the changes in the output of a plan, which end with a line of
+// dashes, or of box-drawing characters in Terraform 0.15 and later. Plans
+// that only change outputs have no actions. OpenTofu prints its own name
+// instead of Terraform's.
+var rePlanActions = regexp.MustCompile(`(?s)(?:(?:Terraform|OpenTofu) will perform the following actions:|(Changes to Outputs:))(.*?)(?:-{72}|─{72})`)
+
+// matches the changes made outside of Terraform in the output of a plan
+// made with -refresh-only. Terraform wraps the line that introduces them.
+var reDriftChanges = regexp.MustCompile(`(?s)(?:Terraform|OpenTofu) detected the following changes made outside of\s+(?:Terraform|OpenTofu).*?:\n(.*?)(?:This is a refresh-only plan|-{72}|─{72})`)
@@ -16,6 +16,8 @@
 
 package terraform
 
+import "fmt"
+
 // Show runs a `terraform show`.
 func (s *Session) Show(planFile string) (Result, error) {
 	args := []string{"show"}
@@ -32,3 +34,38 @@ func (s *Session) Show(planFile string) (Result, error) {
 		process: process,
 	}, err
 }
+
+// ShowJSON runs a `terraform show -json` of a plan file, which prints
+// the plan in a machine-readable format. It requires Terraform 0.12 or
+// later.
+func (s *Session) ShowJSON(planFile string) (Result, error) {
+	process, err := s.terraformCommand([]string{"show", "-json", planFile}, []int{0})
+	if err != nil {
+		return nil, err
+	}
+
+	err = process.Run()
+
+	return &terraformResult{
+		process: process,
+	}, err
+}
+
+// PlanJSON returns the plan made by Plan in the machine-readable format
+// of `terraform show -json`. It requires Terraform 0.12 or later.
+func (s *Session) PlanJSON() ([]byte, Result, error) {
+	terraformVersion, err := s.versionCached()
+	if err != nil {
+		return nil, nil, err
+	}
+	if !VersionMatches(terraformVersion, ">= 0.12") {
+		return nil, nil, fmt.Errorf("Terraform %v cannot show plans as JSON; it requires 0.12 or later", terraformVersion)
+	}
+
+	result, err := s.ShowJSON(s.planFile())
+	if err != nil {
+		return nil, result, err
+	}
+
+	return []byte(result.Stdout()), result, nil
+}
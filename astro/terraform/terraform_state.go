@@ -0,0 +1,65 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// StateSerial runs `terraform state pull` and returns the serial of the
+// state, which Terraform increments every time it writes the state. It is
+// 0 if there is no state yet.
+func (s *Session) StateSerial() (int64, error) {
+	terraformVersion, err := s.versionCached()
+	if err != nil {
+		return 0, err
+	}
+
+	if !VersionMatches(terraformVersion, ">= 0.9") {
+		return 0, fmt.Errorf("reading the state serial requires Terraform 0.9 or later")
+	}
+
+	process, err := s.terraformCommand([]string{"state", "pull"}, []int{0})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := process.Run(); err != nil {
+		return 0, err
+	}
+
+	return parseStateSerial(process.Stdout().String())
+}
+
+// parseStateSerial returns the serial of the state output by `terraform
+// state pull`.
+func parseStateSerial(output string) (int64, error) {
+	if strings.TrimSpace(output) == "" {
+		return 0, nil
+	}
+
+	var state struct {
+		Serial int64 `json:"serial"`
+	}
+	if err := json.Unmarshal([]byte(output), &state); err != nil {
+		return 0, fmt.Errorf("unable to parse state: %v", err)
+	}
+
+	return state.Serial, nil
+}
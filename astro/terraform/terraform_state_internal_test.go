@@ -0,0 +1,38 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStateSerial(t *testing.T) {
+	serial, err := parseStateSerial(`{"version": 4, "serial": 42, "lineage": "abc"}`)
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), serial)
+
+	// there is no output when there is no state yet
+	serial, err = parseStateSerial("\n")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), serial)
+
+	_, err = parseStateSerial("Terraform v0.8.8")
+	assert.Error(t, err)
+}
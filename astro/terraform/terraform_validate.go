@@ -0,0 +1,79 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"fmt"
+
+	"github.com/uber/astro/astro/logger"
+)
+
+// Validate runs `terraform validate`, which checks the module is valid
+// without accessing the remote state or the providers' APIs. The module
+// is initialized without its backend first, so that no credentials are
+// needed. See: https://www.terraform.io/docs/commands/validate.html
+func (s *Session) Validate() (Result, error) {
+	logger.Trace.Printf("terraform: validating module in directory: %v\n", s.moduleDir)
+
+	terraformVersion, err := s.versionCached()
+	if err != nil {
+		return nil, err
+	}
+
+	var initArgs []string
+	if VersionMatches(terraformVersion, "< 0.9") {
+		initArgs = []string{"get"}
+	} else {
+		initArgs = []string{"init", "-backend=false", "-input=false"}
+		if s.config.Lockfile != "" && VersionMatches(terraformVersion, ">= 1.0") {
+			initArgs = append(initArgs, fmt.Sprintf("-lockfile=%s", s.config.Lockfile))
+		}
+	}
+
+	process, err := s.terraformCommand(initArgs, []int{0})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.run(process); err != nil {
+		return &terraformResult{
+			process: process,
+		}, err
+	}
+
+	args := []string{"validate"}
+
+	// Before 0.12, validate also checks that the variables are set
+	if VersionMatches(terraformVersion, "< 0.12") {
+		for _, varFile := range s.config.VarFiles {
+			args = append(args, fmt.Sprintf("-var-file=%s", varFile))
+		}
+		for key, val := range s.config.Variables {
+			args = append(args, "-var", fmt.Sprintf("%s=%s", key, val))
+		}
+	}
+
+	process, err = s.terraformCommand(args, []int{0})
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.run(process)
+
+	return &terraformResult{
+		process: process,
+	}, err
+}
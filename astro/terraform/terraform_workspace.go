@@ -0,0 +1,75 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package terraform
+
+import (
+	"bufio"
+	"strings"
+)
+
+// WorkspaceList runs `terraform workspace list` and returns the names of
+// the workspaces.
+func (s *Session) WorkspaceList() ([]string, Result, error) {
+	process, err := s.terraformCommand([]string{"workspace", "list"}, []int{0})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := &terraformResult{
+		process: process,
+	}
+
+	if err := process.Run(); err != nil {
+		return nil, result, err
+	}
+
+	return parseWorkspaceList(process.Stdout().String()), result, nil
+}
+
+// WorkspaceSelect runs `terraform workspace select` to switch to the
+// specified workspace. Subsequent commands are logged to separate files
+// for each workspace.
+func (s *Session) WorkspaceSelect(name string) (Result, error) {
+	s.workspace = name
+
+	process, err := s.terraformCommand([]string{"workspace", "select", name}, []int{0})
+	if err != nil {
+		return nil, err
+	}
+
+	err = process.Run()
+
+	return &terraformResult{
+		process: process,
+	}, err
+}
+
+// parseWorkspaceList parses the output of `terraform workspace list`, in
+// which the current workspace is marked with an asterisk.
+func parseWorkspaceList(output string) []string {
+	var workspaces []string
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		name := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "*"))
+		if name != "" {
+			workspaces = append(workspaces, name)
+		}
+	}
+
+	return workspaces
+}
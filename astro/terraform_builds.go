@@ -0,0 +1,163 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/uber/astro/astro/utils"
+
+	"github.com/burl/go-version"
+)
+
+// terraformBuildFile is the name of the file, in the session directory of
+// an execution, that records the Terraform binary the execution ran with.
+const terraformBuildFile = "terraform-build.json"
+
+// terraformBuild identifies the Terraform binary an execution ran with.
+type terraformBuild struct {
+	Version string `json:"version"`
+	Path    string `json:"path"`
+	// Hash is the hash of the binary, e.g. "sha256:5e5b...".
+	Hash string `json:"hash"`
+}
+
+// terraformBinaryHash returns the hash of the Terraform binary at path.
+// Hashes are cached, as every execution usually runs the same binary.
+func (c *Project) terraformBinaryHash(path string) (string, error) {
+	if hash, ok := c.terraformHashes.Load(path); ok {
+		return hash.(string), nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	hash := fmt.Sprintf("sha256:%x", h.Sum(nil))
+	c.terraformHashes.Store(path, hash)
+
+	return hash, nil
+}
+
+// readTerraformBuilds returns the Terraform binaries the executions of a
+// session ran with, by execution ID.
+func (r *SessionRepo) readTerraformBuilds(id string) (map[string]terraformBuild, error) {
+	if validPathElement(id) {
+		if err := r.restoreHistory(id); err != nil {
+			return nil, err
+		}
+	}
+	if !validPathElement(id) || !r.exists(id) {
+		return nil, fmt.Errorf("session does not exist: %v", id)
+	}
+
+	sessionFS, err := r.sessionFS(id)
+	if err != nil {
+		return nil, err
+	}
+	files, err := fs.Glob(sessionFS, path.Join("*", terraformBuildFile))
+	if err != nil {
+		return nil, err
+	}
+	if files == nil {
+		return nil, fmt.Errorf("session %v has no record of the Terraform binaries it ran", id)
+	}
+
+	builds := map[string]terraformBuild{}
+	for _, file := range files {
+		data, err := fs.ReadFile(sessionFS, file)
+		if err != nil {
+			return nil, err
+		}
+		var build terraformBuild
+		if err := json.Unmarshal(data, &build); err != nil {
+			return nil, fmt.Errorf("unable to read %v: %v", file, err)
+		}
+		builds[path.Dir(file)] = build
+	}
+
+	return builds, nil
+}
+
+// recordTerraformBuild records the Terraform binary the execution runs
+// with in its session directory.
+func (session *Session) recordTerraformBuild(execution *boundExecution, build terraformBuild) error {
+	data, err := json.MarshalIndent(build, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(session.path, execution.ID(), terraformBuildFile), data, 0644)
+}
+
+// pinnedBuild returns the Terraform binary the execution ran with in
+// pinnedSession, whose binaries are builds, and its version, and checks
+// that it hasn't changed since. If it has been removed or changed, the
+// binary of the same version installed by tvm is used instead, if it is
+// identical. The version is nil if it wasn't recorded.
+func (c *Project) pinnedBuild(execution *boundExecution, builds map[string]terraformBuild, pinnedSession string) (terraformBuild, *version.Version, error) {
+	build, ok := builds[execution.ID()]
+	if !ok {
+		return terraformBuild{}, nil, fmt.Errorf("%v did not run in session %v, so there is no Terraform binary to use", execution.ID(), pinnedSession)
+	}
+
+	// The version isn't known for binaries set with terraform.path, so
+	// those are only identified by their hash
+	paths := []string{build.Path}
+	var v *version.Version
+	if build.Version != "" {
+		parsed, err := version.NewVersion(build.Version)
+		if err != nil {
+			return terraformBuild{}, nil, fmt.Errorf("invalid Terraform version recorded in session %v: %v", pinnedSession, err)
+		}
+		v = parsed
+
+		moduleConfig := execution.ModuleConfig()
+		versions := c.terraformVersions.ForFlavor(moduleConfig.Terraform.TVMFlavor())
+		paths = append(paths, versions.Path(build.Version))
+	}
+
+	for _, path := range paths {
+		if !utils.FileExists(path) {
+			continue
+		}
+		if hash, err := c.terraformBinaryHash(path); err == nil && hash == build.Hash {
+			build.Path = path
+			return build, v, nil
+		}
+	}
+
+	binary := "Terraform binary"
+	if build.Version != "" {
+		binary = fmt.Sprintf("Terraform %v binary", build.Version)
+	}
+	return terraformBuild{}, nil, fmt.Errorf("the %s that %v ran with in session %v (%v) has been removed or changed", binary, execution.ID(), pinnedSession, build.Hash)
+}
@@ -18,6 +18,8 @@ package astro
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/uber/astro/astro/tvm"
@@ -155,3 +157,42 @@ func TestSharedPluginCachePreservesExisting(t *testing.T) {
 		"test": nil,
 	}, testResultErrs(testReadResults(resultChan)))
 }
+
+func TestAssumeAWSRole(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewProjectFromConfigFile("fixtures/test-aws-role/astro.yaml")
+	require.NoError(t, err)
+
+	// The mock AWS CLI logs its calls to the file in the module env
+	awsLog := filepath.Join(t.TempDir(), "aws.log")
+	for i := range c.config.Modules {
+		c.config.Modules[i].Env = map[string]string{"MOCK_AWS_LOG": awsLog}
+	}
+
+	session, err := c.sessions.NewSession()
+	require.NoError(t, err)
+
+	env := map[string]map[string]string{}
+	for _, e := range c.executions(NoExecutionParameters()) {
+		b, err := e.(*unboundExecution).bind(nil)
+		require.NoError(t, err)
+		config, _, err := session.terraformConfig(b)
+		require.NoError(t, err)
+		env[b.ID()] = config.Env
+	}
+
+	assert.Equal(t, "ASIA-deploy", env["app-dev"]["AWS_ACCESS_KEY_ID"])
+	assert.Equal(t, "secret-deploy", env["app-prod"]["AWS_SECRET_ACCESS_KEY"])
+	assert.Equal(t, "token-dev-db", env["db-dev"]["AWS_SESSION_TOKEN"])
+	assert.Equal(t, "ASIA-prod-db", env["db-prod"]["AWS_ACCESS_KEY_ID"])
+
+	// Each role is only assumed once in the session
+	data, err := os.ReadFile(awsLog)
+	require.NoError(t, err)
+	calls := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, calls, 3)
+	sessionName := "--role-session-name astro-" + session.id
+	assert.Contains(t, calls, "sts assume-role --role-arn arn:aws:iam::123456789012:role/deploy "+sessionName+" --output json --external-id astro --duration-seconds 7200")
+	assert.Contains(t, calls, "sts assume-role --role-arn arn:aws:iam::123456789012:role/prod-db "+sessionName+" --output json")
+}
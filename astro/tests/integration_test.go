@@ -36,7 +36,7 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-var noChangesRegexp = `(?s)foo: \x1b\[32mOK\x1b\[0m\x1b\[37m No changes\x1b\[0m\x1b\[37m \(\d{0,2}s\)\x1b\[0m\nDone\n`
+var noChangesRegexp = `(?s)foo: OK No changes \(\d{0,2}s\)\nDone\n`
 
 // getSessionDirs returns a list of the sessions inside a session repository.
 // This excludes other directories that might have been created in there, e.g.
@@ -168,7 +168,7 @@ func TestProjectApplyChangesSuccess(t *testing.T) {
 			require.NoError(t, err)
 
 			result := RunTest(t, []string{"apply"}, "fixtures/apply-changes-success", v)
-			assert.Contains(t, result.Stdout.String(), "foo: [32mOK")
+			assert.Contains(t, result.Stdout.String(), "foo: OK")
 			assert.Empty(t, result.Stderr.String())
 			assert.Equal(t, 0, result.ExitCode)
 		})
@@ -178,7 +178,7 @@ func TestProjectApplyChangesSuccess(t *testing.T) {
 func TestProjectPlanSuccessNoChanges(t *testing.T) {
 	for _, v := range terraformVersionsToTest {
 		t.Run(v, func(t *testing.T) {
-			result := RunTest(t, []string{"plan", "--trace"}, "fixtures/plan-success-nochanges", v)
+			result := RunTest(t, []string{"plan", "-vvv"}, "fixtures/plan-success-nochanges", v)
 			assert.Regexp(t, noChangesRegexp, result.Stdout.String())
 			assert.Equal(t, 0, result.ExitCode)
 		})
@@ -188,8 +188,8 @@ func TestProjectPlanSuccessNoChanges(t *testing.T) {
 func TestProjectPlanSuccessChanges(t *testing.T) {
 	for _, v := range terraformVersionsToTest {
 		t.Run(v, func(t *testing.T) {
-			result := RunTest(t, []string{"plan"}, "fixtures/plan-success-changes", v)
-			assert.Contains(t, result.Stdout.String(), "foo: [32mOK[0m[33m Changes[0m[37m")
+			result := RunTest(t, []string{"plan", "--color=always"}, "fixtures/plan-success-changes", v)
+			assert.Contains(t, result.Stdout.String(), "foo: [32mOK[0m[33m Changes: +1 ~0 -0[0m[37m")
 			addedResourceRe := `\+.*null_resource.foo`
 			if stringVersionMatches(v, ">=0.12") {
 				addedResourceRe = `null_resource.foo.*will be created`
@@ -204,7 +204,7 @@ func TestProjectPlanError(t *testing.T) {
 	for _, v := range terraformVersionsToTest {
 		t.Run(v, func(t *testing.T) {
 			result := RunTest(t, []string{"plan"}, "fixtures/plan-error", v)
-			assert.Contains(t, result.Stderr.String(), "foo: [31mERROR")
+			assert.Contains(t, result.Stderr.String(), "foo: ERROR")
 			errorMessage := "Error parsing"
 			if stringVersionMatches(v, ">=0.12") {
 				errorMessage = "Argument or block definition required"
@@ -0,0 +1,172 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/terraform"
+)
+
+// TimeoutWarning is passed to the function given to WithTimeoutWarnings
+// when an execution has been running for longer than its warn_after.
+type TimeoutWarning struct {
+	// ID is the ID of the execution.
+	ID string
+	// Session is the ID of the session it runs in.
+	Session string
+	// WarnAfter is how long it has been running, and KillAfter, if it is
+	// set, is when it will be interrupted.
+	WarnAfter time.Duration
+	KillAfter time.Duration
+}
+
+// Message describes the warning, for status updates and notifications.
+func (w TimeoutWarning) Message() string {
+	message := fmt.Sprintf("still running after %v; it may be stuck", w.WarnAfter)
+	if w.KillAfter > 0 {
+		message += fmt.Sprintf(", and will be killed after %v", w.KillAfter)
+	}
+	return message
+}
+
+// executionRun is the run of an execution in a session. Its context is
+// cancelled when it runs for longer than kill_after, which interrupts its
+// Terraform commands.
+type executionRun struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	started time.Time
+
+	mu     sync.Mutex
+	warned bool
+	killed bool
+}
+
+// executionRun returns the run of the execution, which starts the first
+// time this is called for it.
+func (session *Session) executionRun(id string) *executionRun {
+	if run, ok := session.executionRuns.Load(id); ok {
+		return run.(*executionRun)
+	}
+
+	ctx, cancel := context.WithCancel(session.ctx)
+	run, loaded := session.executionRuns.LoadOrStore(id, &executionRun{
+		ctx:     ctx,
+		cancel:  cancel,
+		started: session.repo.project.clock.Now(),
+	})
+	if loaded {
+		cancel()
+	}
+
+	return run.(*executionRun)
+}
+
+// watch runs a Terraform command of the execution, warning if the
+// execution runs for longer than its warn_after, and interrupting the
+// command if it runs for longer than its kill_after. Time spent between
+// commands counts, but the timers only fire while a command runs, so that
+// an execution that has finished is never reported.
+func (session *Session) watch(b *boundExecution, status *statusQueue, command func() (terraform.Result, error)) (terraform.Result, error) {
+	terraformConfig := b.ModuleConfig().Terraform
+	warnAfter, killAfter := terraformConfig.Timeouts()
+	if warnAfter == 0 && killAfter == 0 {
+		return command()
+	}
+
+	run := session.executionRun(b.ID())
+	elapsed := session.repo.project.clock.Now().Sub(run.started)
+
+	done := make(chan struct{})
+	watched := make(chan struct{})
+
+	go func() {
+		defer close(watched)
+
+		var warn, kill <-chan time.Time
+		run.mu.Lock()
+		if warnAfter > 0 && !run.warned {
+			warn = time.After(warnAfter - elapsed)
+		}
+		run.mu.Unlock()
+		if killAfter > 0 {
+			kill = time.After(killAfter - elapsed)
+		}
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-warn:
+				warn = nil
+				session.warnTimeout(b, status, run, warnAfter, killAfter)
+			case <-kill:
+				logger.Warn("killing execution that ran for longer than kill_after", logger.Fields{"id": b.ID(), "kill_after": killAfter})
+				status.send(b.ID(), "Killing after %v...", killAfter)
+				run.mu.Lock()
+				run.killed = true
+				run.mu.Unlock()
+				run.cancel()
+				return
+			}
+		}
+	}()
+
+	result, err := command()
+	close(done)
+	<-watched
+
+	run.mu.Lock()
+	killed := run.killed
+	run.mu.Unlock()
+	if killed && err != nil {
+		err = fmt.Errorf("timed out after %v: %v", killAfter, err)
+	}
+
+	return result, err
+}
+
+// warnTimeout reports that the execution has been running for longer
+// than warnAfter, once.
+func (session *Session) warnTimeout(b *boundExecution, status *statusQueue, run *executionRun, warnAfter, killAfter time.Duration) {
+	run.mu.Lock()
+	warned := run.warned
+	run.warned = true
+	run.mu.Unlock()
+	if warned {
+		return
+	}
+
+	warning := TimeoutWarning{
+		ID:        b.ID(),
+		Session:   session.id,
+		WarnAfter: warnAfter,
+		KillAfter: killAfter,
+	}
+
+	logger.Warn("execution is running for longer than warn_after", logger.Fields{"id": b.ID(), "warn_after": warnAfter})
+	status.send(b.ID(), "Still running after %v; it may be stuck", warnAfter)
+
+	if warn := session.repo.project.timeoutWarnings; warn != nil {
+		warn(warning)
+	}
+}
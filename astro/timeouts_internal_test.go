@@ -0,0 +1,154 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/terraform"
+	"github.com/uber/astro/astro/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTimeoutTestSession returns a session of a project that records its
+// timeout warnings, and an execution with the timeouts.
+func newTimeoutTestSession(t *testing.T, warnAfter, killAfter string) (*Session, *boundExecution, func() []TimeoutWarning) {
+	var mu sync.Mutex
+	var warnings []TimeoutWarning
+
+	c := &Project{
+		config: &conf.Project{},
+		clock:  utils.SystemClock,
+		timeoutWarnings: func(warning TimeoutWarning) {
+			mu.Lock()
+			defer mu.Unlock()
+			warnings = append(warnings, warning)
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	session := &Session{
+		id:   "01E2Q5HXW3TGNWAJ9T3V0MB4T4",
+		repo: &SessionRepo{project: c},
+		ctx:  ctx,
+	}
+
+	b := bindModules(t, conf.Module{
+		Name:      "app",
+		Terraform: conf.Terraform{WarnAfter: warnAfter, KillAfter: killAfter},
+	})[0]
+
+	return session, b, func() []TimeoutWarning {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]TimeoutWarning{}, warnings...)
+	}
+}
+
+// sleepCommand returns a Terraform command that runs for d, or until the
+// execution is interrupted.
+func sleepCommand(session *Session, b *boundExecution, d time.Duration) func() (terraform.Result, error) {
+	return func() (terraform.Result, error) {
+		select {
+		case <-time.After(d):
+			return nil, nil
+		case <-session.executionRun(b.ID()).ctx.Done():
+			return nil, errors.New("interrupted")
+		}
+	}
+}
+
+func TestWatchWarns(t *testing.T) {
+	session, b, warnings := newTimeoutTestSession(t, "20ms", "")
+	status := newStatusQueue()
+
+	_, err := session.watch(b, status, sleepCommand(session, b, 100*time.Millisecond))
+	require.NoError(t, err)
+
+	require.Len(t, warnings(), 1)
+	assert.Equal(t, TimeoutWarning{
+		ID:        "app",
+		Session:   "01E2Q5HXW3TGNWAJ9T3V0MB4T4",
+		WarnAfter: 20 * time.Millisecond,
+	}, warnings()[0])
+	assert.Equal(t, "[app] Still running after 20ms; it may be stuck", <-status.ch)
+
+	// the execution is only reported once
+	_, err = session.watch(b, status, sleepCommand(session, b, 50*time.Millisecond))
+	require.NoError(t, err)
+	assert.Len(t, warnings(), 1)
+}
+
+func TestWatchFastCommand(t *testing.T) {
+	session, b, warnings := newTimeoutTestSession(t, "1s", "2s")
+
+	_, err := session.watch(b, newStatusQueue(), sleepCommand(session, b, 0))
+	require.NoError(t, err)
+	assert.Empty(t, warnings())
+
+	// finished executions are never reported
+	time.Sleep(10 * time.Millisecond)
+	assert.Empty(t, warnings())
+}
+
+func TestWatchKills(t *testing.T) {
+	session, b, warnings := newTimeoutTestSession(t, "10ms", "50ms")
+	status := newStatusQueue()
+
+	started := time.Now()
+	_, err := session.watch(b, status, sleepCommand(session, b, time.Minute))
+	assert.EqualError(t, err, "timed out after 50ms: interrupted")
+	assert.True(t, time.Since(started) < time.Minute)
+	assert.Len(t, warnings(), 1)
+	assert.Equal(t, "still running after 10ms; it may be stuck, and will be killed after 50ms", warnings()[0].Message())
+
+	// the rest of the session runs on
+	assert.NoError(t, session.ctx.Err())
+}
+
+func TestTerraformTimeoutsValidate(t *testing.T) {
+	version := &conf.Terraform{}
+	require.Error(t, version.Validate(), "version is not set")
+
+	for _, tc := range []struct {
+		warnAfter, killAfter string
+		err                  string
+	}{
+		{"20m", "1h", ""},
+		{"", "1h", ""},
+		{"soon", "", "invalid warn_after"},
+		{"-1m", "", "warn_after must be positive"},
+		{"1h", "20m", "warn_after (1h0m0s) must be shorter than kill_after (20m0s)"},
+	} {
+		terraformConfig := conf.Terraform{WarnAfter: tc.warnAfter, KillAfter: tc.killAfter}
+		err := terraformConfig.Validate()
+		// the version is not set, which is always an error here
+		require.Error(t, err)
+		if tc.err == "" {
+			assert.NotContains(t, err.Error(), "_after")
+		} else {
+			assert.Contains(t, err.Error(), tc.err)
+		}
+	}
+}
@@ -19,6 +19,8 @@ package cmd
 import (
 	"fmt"
 	"log"
+	"path/filepath"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -32,6 +34,9 @@ const defaultInstallPath = "/usr/local/bin/terraform"
 
 var (
 	installPath string
+	mirror      string
+	timeout     time.Duration
+	retries     int
 )
 
 // installCmd represents the install command
@@ -40,14 +45,37 @@ var installCmd = &cobra.Command{
 	Short: "Download and link the specified version of Terraform",
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		tvm, err := tvm.NewVersionRepoForCurrentSystem(repoPath)
+		repo, err := versionRepo()
 		if err != nil {
 			log.Fatal(err)
 		}
 
+		// The flags override the environment
+		downloads, err := tvm.DownloadConfigFromEnv()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if cmd.Flags().Changed("mirror") {
+			downloads.Mirror = mirror
+		}
+		if cmd.Flags().Changed("timeout") {
+			downloads.Timeout = timeout
+		}
+		if cmd.Flags().Changed("retries") {
+			downloads.Retries = retries
+		}
+		repo = repo.WithDownloadConfig(downloads)
+
 		version := args[0]
 
-		if err := tvm.Link(version, viper.GetString("installPath"), true); err != nil {
+		// The binary of other flavors is linked next to where Terraform
+		// would be, under its own name
+		linkPath := viper.GetString("installPath")
+		if linkPath == defaultInstallPath {
+			linkPath = filepath.Join(filepath.Dir(defaultInstallPath), repo.Flavor().Binary())
+		}
+
+		if err := repo.Link(version, linkPath, true); err != nil {
 			log.Fatal(err)
 		}
 	},
@@ -59,6 +87,10 @@ func init() {
 		fmt.Sprintf("path to link Terraform binary to (default: %s )", defaultInstallPath),
 	)
 
+	installCmd.Flags().StringVar(&mirror, "mirror", "", "base URL of a mirror of the release hosts to download from (default $TVM_MIRROR)")
+	installCmd.Flags().DurationVar(&timeout, "timeout", 0, "how long each attempt to download a file may take (default $TVM_DOWNLOAD_TIMEOUT, or 10m)")
+	installCmd.Flags().IntVar(&retries, "retries", 0, "how many times to retry a download that failed (default $TVM_DOWNLOAD_RETRIES, or 3)")
+
 	err := viper.BindPFlag("path", installCmd.PersistentFlags().Lookup("path"))
 	if err != nil {
 		return
@@ -25,15 +25,13 @@ import (
 
 	"github.com/burl/go-version"
 	"github.com/spf13/cobra"
-
-	"github.com/uber/astro/astro/tvm"
 )
 
 var listCmd = &cobra.Command{
 	Use:   "ls",
 	Short: "List locally downloaded versions of Terraform",
 	Run: func(cmd *cobra.Command, args []string) {
-		tvm, err := tvm.NewVersionRepoForCurrentSystem(repoPath)
+		tvm, err := versionRepo()
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -51,7 +49,7 @@ var listCmd = &cobra.Command{
 		}
 
 		// Get the path to the current Terraform binary, according to $PATH
-		terraformPath, _ := exec.LookPath("terraform")
+		terraformPath, _ := exec.LookPath(tvm.Flavor().Binary())
 
 		// Get path that Terraform binary links to
 		terraformLinkPath, _ := os.Readlink(terraformPath)
@@ -23,9 +23,14 @@ import (
 
 	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
+
+	"github.com/uber/astro/astro/tvm"
 )
 
-var repoPath string
+var (
+	repoPath   string
+	flavorName string
+)
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -47,6 +52,21 @@ func Execute() {
 func init() {
 	cobra.OnInitialize(setDefaultRepoPath)
 	rootCmd.PersistentFlags().StringVar(&repoPath, "repo", "", "path to store versions (default is $HOME/.tvm)")
+	rootCmd.PersistentFlags().StringVar(&flavorName, "flavor", "", "flavor of Terraform to manage: terraform or opentofu (default is terraform)")
+}
+
+// versionRepo returns the version repository for the current system and
+// the flavor from the flags.
+func versionRepo() (*tvm.VersionRepo, error) {
+	flavor, err := tvm.ParseFlavor(flavorName)
+	if err != nil {
+		return nil, err
+	}
+	repo, err := tvm.NewVersionRepoForCurrentSystem(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	return repo.ForFlavor(flavor), nil
 }
 
 func setDefaultRepoPath() {
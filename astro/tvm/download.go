@@ -0,0 +1,267 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tvm
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultDownloadTimeout is how long each attempt to download a file
+	// may take by default.
+	defaultDownloadTimeout = 10 * time.Minute
+	// defaultDownloadRetries is how many times a download is retried by
+	// default, after defaultDownloadBackoff at first.
+	defaultDownloadRetries = 3
+	defaultDownloadBackoff = time.Second
+)
+
+// DownloadConfig configures how tvm downloads releases, their checksums
+// and the indexes of releases. Proxies are configured with the
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+type DownloadConfig struct {
+	// Mirror, if set, is the base URL of a server that serves the same
+	// paths as the hosts that releases are downloaded from, e.g. an
+	// artifact server in an air-gapped environment. With a mirror of
+	// https://artifacts.example.com/tvm, the release at
+	// https://releases.hashicorp.com/terraform/0.12.29/... is downloaded
+	// from https://artifacts.example.com/tvm/terraform/0.12.29/...
+	Mirror string
+	// Timeout is how long each attempt to download a file may take.
+	Timeout time.Duration
+	// Retries is how many times a download that failed is tried again,
+	// and Backoff how long to wait before the first retry. The wait
+	// doubles for each retry after that.
+	Retries int
+	Backoff time.Duration
+	// OpenTofuPublicKey, if set, is the armored public key that OpenTofu
+	// signs the checksums of its releases with. tvm doesn't ship it, so
+	// without it only the checksums of OpenTofu releases are checked.
+	OpenTofuPublicKey string
+}
+
+// DefaultDownloadConfig returns the config that releases are downloaded
+// with unless the environment changes it.
+func DefaultDownloadConfig() DownloadConfig {
+	return DownloadConfig{
+		Timeout: defaultDownloadTimeout,
+		Retries: defaultDownloadRetries,
+		Backoff: defaultDownloadBackoff,
+	}
+}
+
+// DownloadConfigFromEnv returns the default download config, changed by
+// the TVM_MIRROR, TVM_DOWNLOAD_TIMEOUT and TVM_DOWNLOAD_RETRIES
+// environment variables, and TVM_OPENTOFU_PUBLIC_KEY, the path to the
+// armored public key of OpenTofu.
+func DownloadConfigFromEnv() (DownloadConfig, error) {
+	config := DefaultDownloadConfig()
+
+	if mirror := os.Getenv("TVM_MIRROR"); mirror != "" {
+		u, err := url.Parse(mirror)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return config, fmt.Errorf("TVM_MIRROR: %q is not an http or https URL", mirror)
+		}
+		config.Mirror = mirror
+	}
+
+	if timeout := os.Getenv("TVM_DOWNLOAD_TIMEOUT"); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil || d <= 0 {
+			return config, fmt.Errorf("TVM_DOWNLOAD_TIMEOUT: %q is not a positive duration", timeout)
+		}
+		config.Timeout = d
+	}
+
+	if retries := os.Getenv("TVM_DOWNLOAD_RETRIES"); retries != "" {
+		n, err := strconv.Atoi(retries)
+		if err != nil || n < 0 {
+			return config, fmt.Errorf("TVM_DOWNLOAD_RETRIES: %q is not a number of retries", retries)
+		}
+		config.Retries = n
+	}
+
+	if keyPath := os.Getenv("TVM_OPENTOFU_PUBLIC_KEY"); keyPath != "" {
+		key, err := os.ReadFile(keyPath)
+		if err != nil {
+			return config, fmt.Errorf("TVM_OPENTOFU_PUBLIC_KEY: %v", err)
+		}
+		config.OpenTofuPublicKey = string(key)
+	}
+
+	return config, nil
+}
+
+// mirrored returns the URL to download rawURL from, which is on the
+// mirror if there is one.
+func (d DownloadConfig) mirrored(rawURL string) string {
+	if d.Mirror == "" {
+		return rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return strings.TrimSuffix(d.Mirror, "/") + u.RequestURI()
+}
+
+// client returns the HTTP client that files are downloaded with.
+func (d DownloadConfig) client() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	return &http.Client{Transport: transport, Timeout: d.Timeout}
+}
+
+// statusError is returned when a server responds to a download with an
+// unexpected status.
+type statusError struct {
+	status string
+	code   int
+}
+
+func (e *statusError) Error() string {
+	return e.status
+}
+
+// retryable returns whether the request may succeed if it is made again.
+func (e *statusError) retryable() bool {
+	return e.code == http.StatusTooManyRequests || e.code >= 500
+}
+
+// retry calls attempt until it succeeds, it fails in a way that retrying
+// won't fix, or the retries run out, waiting longer after each failure.
+func (d DownloadConfig) retry(attempt func() error) error {
+	backoff := d.Backoff
+	for i := 0; ; i++ {
+		err := attempt()
+		var statusErr *statusError
+		if err == nil || i >= d.Retries || (errors.As(err, &statusErr) && !statusErr.retryable()) {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// downloadFile downloads the file at the URL to path. When an attempt
+// fails part way, the next one resumes from where it stopped, if the
+// server supports it.
+func (d DownloadConfig) downloadFile(fileURL string, path string) error {
+	client := d.client()
+	err := d.retry(func() error {
+		return resumeDownload(client, fileURL, path)
+	})
+	if err != nil {
+		return fmt.Errorf("unable to download %s: %v", fileURL, err)
+	}
+	return nil
+}
+
+// resumeDownload downloads the rest of the file at the URL to path, which
+// has the part that was downloaded before, if any.
+func resumeDownload(client *http.Client, fileURL string, path string) error {
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	offset, err := out.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, fileURL, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return withoutURL(err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusPartialContent:
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// all of it was downloaded already
+		return nil
+	case resp.StatusCode == http.StatusOK:
+		// the server sent the whole file, not the rest of it
+		if err := out.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := out.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	default:
+		return &statusError{status: resp.Status, code: resp.StatusCode}
+	}
+
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// fetch returns the body of the URL.
+func (d DownloadConfig) fetch(fileURL string) ([]byte, error) {
+	client := d.client()
+
+	var body []byte
+	err := d.retry(func() error {
+		resp, err := client.Get(fileURL)
+		if err != nil {
+			return withoutURL(err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return &statusError{status: resp.Status, code: resp.StatusCode}
+		}
+
+		body, err = io.ReadAll(resp.Body)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fileURL, err)
+	}
+
+	return body, nil
+}
+
+// withoutURL returns the cause of an error of the HTTP client, which
+// repeats the URL that errors about downloads already name.
+func withoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
@@ -0,0 +1,162 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tvm
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testDownloadConfig retries quickly.
+var testDownloadConfig = DownloadConfig{Timeout: 10 * time.Second, Retries: 2, Backoff: time.Millisecond}
+
+func TestDownloadRetries(t *testing.T) {
+	t.Parallel()
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("release"))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "terraform.zip")
+	require.NoError(t, testDownloadConfig.downloadFile(server.URL, path))
+	assert.Equal(t, int32(3), requests)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "release", string(content))
+
+	// the retries run out
+	atomic.StoreInt32(&requests, -10)
+	err = testDownloadConfig.downloadFile(server.URL, filepath.Join(t.TempDir(), "terraform.zip"))
+	assert.EqualError(t, err, "unable to download "+server.URL+": 503 Service Unavailable")
+	assert.Equal(t, int32(-7), requests)
+}
+
+func TestDownloadDoesNotRetryMissingFiles(t *testing.T) {
+	t.Parallel()
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	_, err := testDownloadConfig.fetch(server.URL + "/SHA256SUMS")
+	assert.EqualError(t, err, server.URL+"/SHA256SUMS: 404 Not Found")
+	assert.Equal(t, int32(1), requests)
+}
+
+func TestDownloadResumes(t *testing.T) {
+	t.Parallel()
+
+	release := []byte("0123456789")
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		if len(ranges) == 1 {
+			// the connection drops half way
+			w.Header().Set("Content-Length", "10")
+			w.Write(release[:4])
+			return
+		}
+		http.ServeContent(w, r, "terraform.zip", time.Time{}, bytesReadSeeker(release))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "terraform.zip")
+	require.NoError(t, testDownloadConfig.downloadFile(server.URL, path))
+	assert.Equal(t, []string{"", "bytes=4-"}, ranges)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, release, content)
+}
+
+func TestDownloadMirror(t *testing.T) {
+	t.Parallel()
+
+	config := DownloadConfig{Mirror: "https://artifacts.example.com/tvm/"}
+	assert.Equal(t,
+		"https://artifacts.example.com/tvm/terraform/0.12.29/terraform_0.12.29_linux_amd64.zip",
+		config.mirrored("https://releases.hashicorp.com/terraform/0.12.29/terraform_0.12.29_linux_amd64.zip"),
+	)
+	assert.Equal(t,
+		"https://artifacts.example.com/tvm/tofu/api.json",
+		config.mirrored("https://get.opentofu.org/tofu/api.json"),
+	)
+
+	repo, err := NewVersionRepo(t.TempDir(), "amd64", "linux")
+	require.NoError(t, err)
+	repo = repo.ForFlavor(OpenTofu).WithDownloadConfig(config)
+	assert.Equal(t,
+		"https://artifacts.example.com/tvm/opentofu/opentofu/releases/download/v1.6.2/tofu_1.6.2_linux_amd64.zip",
+		repo.DownloadURL("1.6.2"),
+	)
+}
+
+func TestDownloadConfigFromEnv(t *testing.T) {
+	t.Setenv("TVM_MIRROR", "https://artifacts.example.com/tvm")
+	t.Setenv("TVM_DOWNLOAD_TIMEOUT", "30s")
+	t.Setenv("TVM_DOWNLOAD_RETRIES", "0")
+	keyPath := filepath.Join(t.TempDir(), "opentofu.asc")
+	require.NoError(t, os.WriteFile(keyPath, []byte("key"), 0644))
+	t.Setenv("TVM_OPENTOFU_PUBLIC_KEY", keyPath)
+
+	config, err := DownloadConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, DownloadConfig{
+		Mirror:            "https://artifacts.example.com/tvm",
+		Timeout:           30 * time.Second,
+		Retries:           0,
+		Backoff:           defaultDownloadBackoff,
+		OpenTofuPublicKey: "key",
+	}, config)
+
+	for variable, value := range map[string]string{
+		"TVM_MIRROR":              "artifacts.example.com",
+		"TVM_DOWNLOAD_TIMEOUT":    "-1s",
+		"TVM_DOWNLOAD_RETRIES":    "many",
+		"TVM_OPENTOFU_PUBLIC_KEY": "/nonexistent/opentofu.asc",
+	} {
+		t.Run(variable, func(t *testing.T) {
+			t.Setenv(variable, value)
+			_, err := DownloadConfigFromEnv()
+			assert.Contains(t, err.Error(), variable)
+		})
+	}
+}
+
+// bytesReadSeeker returns a ReadSeeker of b.
+func bytesReadSeeker(b []byte) *bytes.Reader {
+	return bytes.NewReader(b)
+}
@@ -0,0 +1,128 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tvm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Flavor is a distribution of Terraform that tvm installs: Terraform
+// itself, or a fork that is compatible with it.
+type Flavor string
+
+const (
+	// Terraform is Terraform from HashiCorp. This is the default.
+	Terraform Flavor = "terraform"
+	// OpenTofu is the OpenTofu fork of Terraform, whose binary is tofu.
+	OpenTofu Flavor = "opentofu"
+)
+
+// openTofuZipFileDownloadURL is the path to download OpenTofu zip files
+// from its GitHub releases.
+var openTofuZipFileDownloadURL = "https://github.com/opentofu/opentofu/releases/download/v%s/tofu_%s_%s_%s.zip"
+
+// openTofuReleasesIndexURL is the URL of the index of all OpenTofu
+// releases.
+var openTofuReleasesIndexURL = "https://get.opentofu.org/tofu/api.json"
+
+// ParseFlavor returns the flavor with the name, e.g. "opentofu". An empty
+// name is Terraform.
+func ParseFlavor(name string) (Flavor, error) {
+	switch Flavor(name) {
+	case "", Terraform:
+		return Terraform, nil
+	case OpenTofu:
+		return OpenTofu, nil
+	}
+
+	var names []string
+	for _, flavor := range []Flavor{Terraform, OpenTofu} {
+		names = append(names, string(flavor))
+	}
+	sort.Strings(names)
+	return "", fmt.Errorf("unknown flavor %q; must be one of %s", name, strings.Join(names, ", "))
+}
+
+// Name returns the name of the flavor for humans, e.g. "OpenTofu".
+func (f Flavor) Name() string {
+	if f == OpenTofu {
+		return "OpenTofu"
+	}
+	return "Terraform"
+}
+
+// Binary returns the name of the binary of the flavor.
+func (f Flavor) Binary() string {
+	if f == OpenTofu {
+		return "tofu"
+	}
+	return terraformBinaryFile
+}
+
+// zipFileDownloadURL returns the URL of the zip file of the version for
+// the platform and architecture.
+func (f Flavor) zipFileDownloadURL(version, platform, arch string) string {
+	if f == OpenTofu {
+		return fmt.Sprintf(openTofuZipFileDownloadURL, version, version, platform, arch)
+	}
+	return fmt.Sprintf(terraformZipFileDownloadURL, version, version, platform, arch)
+}
+
+// releasesIndexURL returns the URL of the index of all releases.
+func (f Flavor) releasesIndexURL() string {
+	if f == OpenTofu {
+		return openTofuReleasesIndexURL
+	}
+	return terraformReleasesIndexURL
+}
+
+// parseReleasesIndex returns the versions in the index of all releases.
+func (f Flavor) parseReleasesIndex(r io.Reader) ([]string, error) {
+	var versions []string
+
+	if f == OpenTofu {
+		// e.g. {"versions": [{"id": "1.6.0"}, ...]}
+		var index struct {
+			Versions []struct {
+				ID string `json:"id"`
+			} `json:"versions"`
+		}
+		if err := json.NewDecoder(r).Decode(&index); err != nil {
+			return nil, err
+		}
+		for _, v := range index.Versions {
+			versions = append(versions, v.ID)
+		}
+		return versions, nil
+	}
+
+	// e.g. {"versions": {"0.12.29": {...}, ...}}
+	var index struct {
+		Versions map[string]interface{} `json:"versions"`
+	}
+	if err := json.NewDecoder(r).Decode(&index); err != nil {
+		return nil, err
+	}
+	for v := range index.Versions {
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
@@ -0,0 +1,100 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tvm
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestConcurrentDownloadsShareBinary gets the same version from repos
+// that share a directory but not their locks, like separate processes.
+func TestConcurrentDownloadsShareBinary(t *testing.T) {
+	signer, publicKey := newTestSigner(t)
+	release := newTestRelease(t, signer)
+	release.serve(t, publicKey)
+
+	repoPath := t.TempDir()
+
+	var wg sync.WaitGroup
+	paths := make([]string, 5)
+	errs := make([]error, 5)
+	for i := range paths {
+		repo, err := NewVersionRepo(repoPath, "amd64", "linux")
+		require.NoError(t, err)
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			paths[i], errs[i] = repo.Get("0.12.29")
+		}(i)
+	}
+	wg.Wait()
+
+	repo, err := NewVersionRepo(repoPath, "amd64", "linux")
+	require.NoError(t, err)
+	for i := range paths {
+		require.NoError(t, errs[i])
+		assert.Equal(t, repo.Path("0.12.29"), paths[i])
+	}
+	assert.Equal(t, int32(1), release.downloads)
+
+	// only the version and its lock file are left
+	entries, err := os.ReadDir(filepath.Join(repoPath, "linux", "amd64"))
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.Equal(t, []string{"0.12.29", "0.12.29.lock"}, names)
+
+	installed, err := repo.List()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"0.12.29": repo.Path("0.12.29")}, installed)
+}
+
+func TestLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "1.5.7.lock")
+
+	unlock, err := lockFile(path)
+	require.NoError(t, err)
+
+	locked := make(chan struct{})
+	go func() {
+		unlockAgain, err := lockFile(path)
+		assert.NoError(t, err)
+		close(locked)
+		if err == nil {
+			assert.NoError(t, unlockAgain())
+		}
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("the lock was taken twice")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	require.NoError(t, unlock())
+	<-locked
+}
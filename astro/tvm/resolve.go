@@ -0,0 +1,173 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tvm
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/burl/go-version"
+)
+
+// terraformReleasesIndexURL is the URL of the index of all Terraform
+// releases on the Hashicorp website.
+var terraformReleasesIndexURL = "https://releases.hashicorp.com/terraform/index.json"
+
+// LatestInstalled returns the highest version in the repository that
+// matches the constraint, e.g. ">= 0.11, < 0.12". An empty constraint
+// matches any version. If no version matches, an empty string is returned.
+func (r *VersionRepo) LatestInstalled(constraint string) (string, error) {
+	installed, err := r.List()
+	if err != nil {
+		// the repository doesn't have any versions for this platform yet
+		return "", nil
+	}
+
+	var versions []string
+	for v := range installed {
+		versions = append(versions, v)
+	}
+
+	return latestMatching(versions, constraint)
+}
+
+// LatestAvailable returns the highest released version of Terraform that
+// matches the constraint. Pre-releases are ignored. An empty constraint
+// matches any version.
+func LatestAvailable(constraint string) (string, error) {
+	return Terraform.LatestAvailable(constraint)
+}
+
+// LatestAvailable returns the highest released version of the flavor that
+// matches the constraint, like LatestAvailable. The index of releases is
+// downloaded with the config from the environment.
+func (f Flavor) LatestAvailable(constraint string) (string, error) {
+	downloads, err := DownloadConfigFromEnv()
+	if err != nil {
+		return "", err
+	}
+	return f.latestAvailable(downloads, constraint)
+}
+
+// LatestAvailable returns the highest released version of the flavor of
+// the repository that matches the constraint, like LatestAvailable, from
+// its mirror if it has one.
+func (r *VersionRepo) LatestAvailable(constraint string) (string, error) {
+	return r.flavor.latestAvailable(r.downloads, constraint)
+}
+
+// latestAvailable returns the highest released version of the flavor that
+// matches the constraint, downloading the index of releases with the
+// config.
+func (f Flavor) latestAvailable(downloads DownloadConfig, constraint string) (string, error) {
+	versions, err := f.available(downloads, constraint)
+	if err != nil {
+		return "", err
+	}
+	if len(versions) == 0 {
+		return "", fmt.Errorf("no %s release matches %q", f.Name(), constraint)
+	}
+
+	return versions[0], nil
+}
+
+// Available returns the released versions of the flavor of the repository
+// that match the constraint, from the highest to the lowest. Pre-releases
+// are ignored. An empty constraint matches any version.
+func (r *VersionRepo) Available(constraint string) ([]string, error) {
+	return r.flavor.available(r.downloads, constraint)
+}
+
+// available returns the released versions of the flavor that match the
+// constraint, from the highest to the lowest, downloading the index of
+// releases with the config.
+func (f Flavor) available(downloads DownloadConfig, constraint string) ([]string, error) {
+	index, err := downloads.fetch(downloads.mirrored(f.releasesIndexURL()))
+	if err != nil {
+		return nil, fmt.Errorf("unable to list %s releases: %v", f.Name(), err)
+	}
+
+	versions, err := f.parseReleasesIndex(bytes.NewReader(index))
+	if err != nil {
+		return nil, fmt.Errorf("unable to list %s releases: %v", f.Name(), err)
+	}
+
+	return matching(versions, constraint)
+}
+
+// latestMatching returns the highest of versions that matches the
+// constraint, ignoring pre-releases and invalid versions.
+func latestMatching(versions []string, constraint string) (string, error) {
+	matches, err := matching(versions, constraint)
+	if err != nil || len(matches) == 0 {
+		return "", err
+	}
+	return matches[0], nil
+}
+
+// SortVersions returns the versions sorted from the highest to the
+// lowest. Invalid versions are left out.
+func SortVersions(versions []string) []string {
+	var parsed version.Collection
+	for _, v := range versions {
+		if p, err := version.NewVersion(v); err == nil {
+			parsed = append(parsed, p)
+		}
+	}
+
+	sort.Sort(sort.Reverse(parsed))
+
+	sorted := make([]string, 0, len(parsed))
+	for _, v := range parsed {
+		sorted = append(sorted, v.String())
+	}
+	return sorted
+}
+
+// matching returns the versions that match the constraint, from the
+// highest to the lowest, ignoring pre-releases and invalid versions.
+func matching(versions []string, constraint string) ([]string, error) {
+	var constraints version.Constraints
+	if constraint != "" {
+		var err error
+		if constraints, err = version.NewConstraint(constraint); err != nil {
+			return nil, err
+		}
+	}
+
+	var matches version.Collection
+	for _, v := range versions {
+		parsed, err := version.NewVersion(v)
+		if err != nil || parsed.Prerelease() != "" {
+			continue
+		}
+		if constraints != nil && !constraints.Check(parsed) {
+			continue
+		}
+		matches = append(matches, parsed)
+	}
+
+	sort.Sort(sort.Reverse(matches))
+
+	var sorted []string
+	for _, v := range matches {
+		sorted = append(sorted, v.String())
+	}
+
+	return sorted, nil
+}
@@ -0,0 +1,97 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tvm
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatestMatching(t *testing.T) {
+	versions := []string{"0.11.7", "0.11.14", "0.12.0-beta1", "0.12.29", "1.0.0", "invalid"}
+
+	tt := []struct {
+		constraint string
+		expected   string
+	}{
+		{"", "1.0.0"},
+		{"~> 0.11.0", "0.11.14"},
+		{">= 0.12, < 1.0", "0.12.29"},
+		{"0.11.7", "0.11.7"},
+		{"> 2.0", ""},
+	}
+
+	for _, test := range tt {
+		latest, err := latestMatching(versions, test.constraint)
+		require.NoError(t, err)
+		assert.Equal(t, test.expected, latest, test.constraint)
+	}
+
+	_, err := latestMatching(versions, "not a constraint")
+	assert.Error(t, err)
+
+	matches, err := matching(versions, ">= 0.11.10")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.0.0", "0.12.29", "0.11.14"}, matches)
+}
+
+func TestSortVersions(t *testing.T) {
+	assert.Equal(t, []string{"1.10.0", "1.6.0", "1.5.7"}, SortVersions([]string{"1.5.7", "1.10.0", "invalid", "1.6.0"}))
+}
+
+func TestLatestAvailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"name": "terraform", "versions": {"0.11.14": {}, "0.12.29": {}, "0.13.0-rc1": {}}}`)
+	}))
+	defer server.Close()
+
+	defer func(url string) { terraformReleasesIndexURL = url }(terraformReleasesIndexURL)
+	terraformReleasesIndexURL = server.URL
+
+	latest, err := LatestAvailable("")
+	require.NoError(t, err)
+	assert.Equal(t, "0.12.29", latest)
+
+	latest, err = LatestAvailable("< 0.12")
+	require.NoError(t, err)
+	assert.Equal(t, "0.11.14", latest)
+
+	_, err = LatestAvailable(">= 0.13")
+	assert.Error(t, err)
+}
+
+func TestLatestAvailableOpenTofu(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"versions": [{"id": "1.7.0-beta1"}, {"id": "1.6.2"}, {"id": "1.6.0"}]}`)
+	}))
+	defer server.Close()
+
+	defer func(url string) { openTofuReleasesIndexURL = url }(openTofuReleasesIndexURL)
+	openTofuReleasesIndexURL = server.URL
+
+	latest, err := OpenTofu.LatestAvailable("")
+	require.NoError(t, err)
+	assert.Equal(t, "1.6.2", latest)
+
+	_, err = OpenTofu.LatestAvailable(">= 1.7")
+	assert.EqualError(t, err, `no OpenTofu release matches ">= 1.7"`)
+}
@@ -4,44 +4,11 @@ import (
 	"archive/zip"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 )
 
-// downloadFile will download the specified file to the specified path.
-func downloadFile(url string, path string) error {
-	out, err := os.Create(path)
-	if err != nil {
-		return err
-	}
-	defer func(out *os.File) {
-		err := out.Close()
-		if err != nil {
-
-		}
-	}(out)
-
-	resp, err := http.Get(url)
-	if err != nil {
-		return err
-	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-
-		}
-	}(resp.Body)
-
-	_, err = io.Copy(out, resp.Body)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
 func processFileContents(f *zip.File, destDir string) error {
 	fh, err := f.Open()
 	if err != nil {
@@ -76,8 +43,9 @@ func processFileContents(f *zip.File, destDir string) error {
 		}
 
 		_, err = io.Copy(out, fh)
-
-		err = out.Close()
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
 		if err != nil {
 			return err
 		}
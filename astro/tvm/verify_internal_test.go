@@ -0,0 +1,300 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tvm
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/uber/astro/astro/utils"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testRelease is a release of Terraform, or of another flavor, served by
+// a test server, signed with a key that is trusted instead of the
+// flavor's while it runs.
+type testRelease struct {
+	flavor    Flavor
+	zip       []byte
+	checksums []byte
+	signature []byte
+	// downloads counts the downloads of the zip file.
+	downloads int32
+}
+
+// newTestSigner returns a new key, and its armored public key.
+func newTestSigner(t *testing.T) (*openpgp.Entity, string) {
+	entity, err := openpgp.NewEntity("tvm test", "", "tvm@example.com", nil)
+	require.NoError(t, err)
+
+	var publicKey bytes.Buffer
+	w, err := armor.Encode(&publicKey, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.Serialize(w))
+	require.NoError(t, w.Close())
+
+	return entity, publicKey.String()
+}
+
+// newTestRelease returns a release of Terraform 0.12.29 for linux/amd64,
+// whose checksums are signed by signer.
+func newTestRelease(t *testing.T, signer *openpgp.Entity) *testRelease {
+	return newTestFlavorRelease(t, Terraform, signer)
+}
+
+// newTestFlavorRelease returns a release of version 0.12.29 of the flavor
+// for linux/amd64, whose checksums are signed by signer.
+func newTestFlavorRelease(t *testing.T, flavor Flavor, signer *openpgp.Entity) *testRelease {
+	var zipFile bytes.Buffer
+	zw := zip.NewWriter(&zipFile)
+	f, err := zw.Create(flavor.Binary())
+	require.NoError(t, err)
+	_, err = f.Write([]byte("#!/bin/sh\necho Terraform v0.12.29\n"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	release := &testRelease{flavor: flavor, zip: zipFile.Bytes()}
+	release.checksums = []byte(fmt.Sprintf("%x  %s_0.12.29_darwin_amd64.zip\n%x  %s_0.12.29_linux_amd64.zip\n", sha256.Sum256(nil), flavor.Binary(), sha256.Sum256(release.zip), flavor.Binary()))
+
+	release.signature = signChecksums(t, signer, release.checksums)
+
+	return release
+}
+
+// signChecksums returns the detached signature of the checksums by signer.
+func signChecksums(t *testing.T, signer *openpgp.Entity, checksums []byte) []byte {
+	var signature bytes.Buffer
+	require.NoError(t, openpgp.DetachSign(&signature, signer, bytes.NewReader(checksums), nil))
+	return signature.Bytes()
+}
+
+// serve serves the release, and points tvm at it until the test finishes.
+// For Terraform, publicKey is trusted instead of HashiCorp's key too.
+func (release *testRelease) serve(t *testing.T, publicKey string) {
+	prefix := release.flavor.Binary()
+	signatureFile := prefix + "_0.12.29_SHA256SUMS.sig"
+	if release.flavor == OpenTofu {
+		signatureFile = prefix + "_0.12.29_SHA256SUMS.gpgsig"
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + prefix + "_0.12.29_linux_amd64.zip":
+			atomic.AddInt32(&release.downloads, 1)
+			w.Write(release.zip)
+		case "/" + prefix + "_0.12.29_SHA256SUMS":
+			w.Write(release.checksums)
+		case "/" + signatureFile:
+			if release.signature == nil {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write(release.signature)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	urls := []*string{
+		&terraformZipFileDownloadURL, &terraformChecksumsURL, &terraformChecksumsSignatureURL, &hashicorpPublicKey,
+		&openTofuZipFileDownloadURL, &openTofuChecksumsURL, &openTofuChecksumsSignatureURL,
+	}
+	saved := make([]string, len(urls))
+	for i, url := range urls {
+		saved[i] = *url
+	}
+	t.Cleanup(func() {
+		server.Close()
+		for i, url := range urls {
+			*url = saved[i]
+		}
+	})
+
+	terraformZipFileDownloadURL = server.URL + "/%.0s" + "terraform_%s_%s_%s.zip"
+	terraformChecksumsURL = server.URL + "/%.0s" + "terraform_%s_SHA256SUMS"
+	terraformChecksumsSignatureURL = server.URL + "/%.0s" + "terraform_%s_SHA256SUMS.sig"
+	hashicorpPublicKey = publicKey
+	openTofuZipFileDownloadURL = server.URL + "/%.0s" + "tofu_%s_%s_%s.zip"
+	openTofuChecksumsURL = server.URL + "/%.0s" + "tofu_%s_SHA256SUMS"
+	openTofuChecksumsSignatureURL = server.URL + "/%.0s" + "tofu_%s_SHA256SUMS.gpgsig"
+}
+
+func TestVerifyDownload(t *testing.T) {
+	signer, publicKey := newTestSigner(t)
+	release := newTestRelease(t, signer)
+	release.serve(t, publicKey)
+
+	repo, err := NewVersionRepo(t.TempDir(), "amd64", "linux")
+	require.NoError(t, err)
+
+	path, err := repo.Get("0.12.29")
+	require.NoError(t, err)
+	assert.Equal(t, repo.Path("0.12.29"), path)
+	assert.True(t, utils.FileExists(path))
+}
+
+func TestVerifyDownloadFailsClosed(t *testing.T) {
+	signer, publicKey := newTestSigner(t)
+	other, _ := newTestSigner(t)
+
+	tests := []struct {
+		name     string
+		tamper   func(release *testRelease)
+		expected string
+	}{
+		{
+			name:     "tampered zip",
+			tamper:   func(release *testRelease) { release.zip = append(release.zip, 0) },
+			expected: "checksum of terraform_0.12.29_linux_amd64.zip is",
+		},
+		{
+			name: "tampered checksums",
+			tamper: func(release *testRelease) {
+				release.checksums = bytes.Replace(release.checksums, []byte("darwin"), []byte("freebsd"), 1)
+			},
+			expected: "invalid signature of checksums",
+		},
+		{
+			name:     "signed by another key",
+			tamper:   func(release *testRelease) { release.signature = signChecksums(t, other, release.checksums) },
+			expected: "invalid signature of checksums",
+		},
+		{
+			name:     "missing signature",
+			tamper:   func(release *testRelease) { release.signature = nil },
+			expected: "unable to download signature of checksums",
+		},
+		{
+			name: "missing from checksums",
+			tamper: func(release *testRelease) {
+				release.checksums = bytes.Replace(release.checksums, []byte("linux"), []byte("windows"), 1)
+				release.signature = signChecksums(t, signer, release.checksums)
+			},
+			expected: "terraform_0.12.29_linux_amd64.zip is not in the checksums",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release := newTestRelease(t, signer)
+			tt.tamper(release)
+			release.serve(t, publicKey)
+
+			repo, err := NewVersionRepo(t.TempDir(), "amd64", "linux")
+			require.NoError(t, err)
+
+			_, err = repo.Get("0.12.29")
+			require.Error(t, err)
+			var verificationErr *VerificationError
+			require.True(t, errors.As(err, &verificationErr), "expected a *VerificationError, got %v", err)
+			assert.Contains(t, err.Error(), "unable to verify Terraform 0.12.29, not installing it: "+tt.expected)
+			assert.False(t, utils.FileExists(repo.Path("0.12.29")))
+		})
+	}
+}
+
+func TestVerifyOpenTofuDownload(t *testing.T) {
+	signer, publicKey := newTestSigner(t)
+	other, otherPublicKey := newTestSigner(t)
+
+	tests := []struct {
+		name      string
+		publicKey string
+		tamper    func(release *testRelease)
+		expected  string
+	}{
+		{
+			name:      "signed",
+			publicKey: publicKey,
+		},
+		{
+			name:      "armored signature",
+			publicKey: publicKey,
+			tamper: func(release *testRelease) {
+				var signature bytes.Buffer
+				require.NoError(t, openpgp.ArmoredDetachSign(&signature, signer, bytes.NewReader(release.checksums), nil))
+				release.signature = signature.Bytes()
+			},
+		},
+		{
+			// without the key, only the checksums are checked
+			name:   "no key",
+			tamper: func(release *testRelease) { release.signature = nil },
+		},
+		{
+			name:      "signed by another key",
+			publicKey: publicKey,
+			tamper:    func(release *testRelease) { release.signature = signChecksums(t, other, release.checksums) },
+			expected:  "invalid signature of checksums",
+		},
+		{
+			name:      "another key",
+			publicKey: otherPublicKey,
+			expected:  "invalid signature of checksums",
+		},
+		{
+			name:      "missing signature",
+			publicKey: publicKey,
+			tamper:    func(release *testRelease) { release.signature = nil },
+			expected:  "unable to download signature of checksums",
+		},
+		{
+			name:     "tampered zip",
+			tamper:   func(release *testRelease) { release.zip = append(release.zip, 0) },
+			expected: "checksum of tofu_0.12.29_linux_amd64.zip is",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release := newTestFlavorRelease(t, OpenTofu, signer)
+			if tt.tamper != nil {
+				tt.tamper(release)
+			}
+			release.serve(t, "")
+
+			repo, err := NewVersionRepo(t.TempDir(), "amd64", "linux")
+			require.NoError(t, err)
+			config := DefaultDownloadConfig()
+			config.OpenTofuPublicKey = tt.publicKey
+			repo = repo.ForFlavor(OpenTofu).WithDownloadConfig(config)
+
+			_, err = repo.Get("0.12.29")
+			if tt.expected == "" {
+				require.NoError(t, err)
+				assert.True(t, utils.FileExists(repo.Path("0.12.29")))
+				return
+			}
+			require.Error(t, err)
+			var verificationErr *VerificationError
+			require.True(t, errors.As(err, &verificationErr), "expected a *VerificationError, got %v", err)
+			assert.Contains(t, err.Error(), "unable to verify OpenTofu 0.12.29, not installing it: "+tt.expected)
+			assert.False(t, utils.FileExists(repo.Path("0.12.29")))
+		})
+	}
+}
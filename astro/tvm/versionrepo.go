@@ -15,11 +15,11 @@
  */
 
 // Package tvm stands for Terraform version manager. It will
-// automatically download and manage multiple Terraform binaries.
+// automatically download and manage multiple Terraform binaries, and
+// binaries of OpenTofu.
 package tvm
 
 import (
-	"errors"
 	"fmt"
 	"os"
 	"path"
@@ -28,6 +28,7 @@ import (
 	"runtime"
 	"sync"
 
+	"github.com/uber/astro/astro/logger"
 	"github.com/uber/astro/astro/utils"
 
 	"github.com/mitchellh/go-homedir"
@@ -50,9 +51,12 @@ type VersionRepo struct {
 	repoPath string
 	arch     string
 	platform string
+	flavor   Flavor
+	// downloads configures how releases are downloaded.
+	downloads DownloadConfig
 
 	// locks is a map of mutexes. There is one mutex created on demand for
-	// every Terraform version requested from tvm. The mutex prevents tvm from
+	// every Terraform version requested from tvm, by directory. The mutex prevents tvm from
 	// downloading the same version of Terraform multiple times. If multiple
 	// threads request the same version of Terraform, only one of them will
 	// trigger the download and the rest will block until the download is
@@ -77,14 +81,44 @@ func NewVersionRepo(repoPath string, arch string, platform string) (*VersionRepo
 		return nil, err
 	}
 
+	downloads, err := DownloadConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
 	return &VersionRepo{
-		locks:    &sync.Map{},
-		repoPath: repoPath,
-		arch:     arch,
-		platform: platform,
+		locks:     &sync.Map{},
+		repoPath:  repoPath,
+		arch:      arch,
+		platform:  platform,
+		flavor:    Terraform,
+		downloads: downloads,
 	}, nil
 }
 
+// ForFlavor returns a VersionRepo for the binaries of the flavor, in the
+// same directory. Terraform binaries are at the root of the directory,
+// and those of other flavors in a directory named after the flavor.
+func (r *VersionRepo) ForFlavor(flavor Flavor) *VersionRepo {
+	flavored := *r
+	flavored.flavor = flavor
+	return &flavored
+}
+
+// WithDownloadConfig returns a VersionRepo for the same binaries, that
+// downloads releases with the config instead of the one from the
+// environment.
+func (r *VersionRepo) WithDownloadConfig(config DownloadConfig) *VersionRepo {
+	configured := *r
+	configured.downloads = config
+	return &configured
+}
+
+// Flavor returns the flavor of the binaries in the repository.
+func (r *VersionRepo) Flavor() Flavor {
+	return r.flavor
+}
+
 // NewVersionRepoForCurrentSystem returns a new VersionRepo instance
 // with platform and architecture information retrieve from the current
 // system.
@@ -95,17 +129,22 @@ func NewVersionRepoForCurrentSystem(repoPath string) (*VersionRepo, error) {
 // dir returns the directory in the repository that contains the
 // specified version.
 func (r *VersionRepo) dir(version string) string {
+	if r.flavor != Terraform {
+		return filepath.Join(r.repoPath, string(r.flavor), r.platform, r.arch, version)
+	}
 	return filepath.Join(r.repoPath, r.platform, r.arch, version)
 }
 
-// download gets the Terraform binary from the Terraform website. It
-// returns the path to the downloaded file or an error if there was a
-// problem.
+// download gets the binary from the releases of the flavor, e.g. the
+// Terraform website. It returns the path to the downloaded file or an
+// error if there was a problem.
 func (r *VersionRepo) download(version string) (string, error) {
-	url := fmt.Sprintf(terraformZipFileDownloadURL, version, version, r.platform, r.arch)
+	url := r.DownloadURL(version)
 
-	// Temporary directory for downloading Terraform and extracting the zip file
-	tmpDir, err := os.MkdirTemp("", "terraform")
+	// Temporary directory for downloading Terraform and extracting the zip
+	// file, in the repository so that the binary is moved into place
+	// atomically. Its name doesn't look like a version, so it isn't listed.
+	tmpDir, err := os.MkdirTemp(filepath.Dir(r.dir(version)), ".download-")
 	if err != nil {
 		return "", err
 	}
@@ -119,7 +158,12 @@ func (r *VersionRepo) download(version string) (string, error) {
 	zipFilePath := path.Join(tmpDir, "terraform.zip")
 
 	// Download Terraform zip file
-	if err := downloadFile(url, zipFilePath); err != nil {
+	if err := r.downloads.downloadFile(url, zipFilePath); err != nil {
+		return "", err
+	}
+
+	// Check it is the release that was published before unzipping it
+	if err := r.verify(version, url, zipFilePath); err != nil {
 		return "", err
 	}
 
@@ -128,11 +172,11 @@ func (r *VersionRepo) download(version string) (string, error) {
 		return "", err
 	}
 
-	terraformBinaryPath := path.Join(tmpDir, "terraform")
+	terraformBinaryPath := path.Join(tmpDir, r.flavor.Binary())
 
 	// Check the binary is there
 	if !utils.FileExists(terraformBinaryPath) {
-		return "", errors.New("terraform binary missing from zip file")
+		return "", fmt.Errorf("%s binary missing from zip file", r.flavor.Binary())
 	}
 
 	targetDir := r.dir(version)
@@ -143,13 +187,25 @@ func (r *VersionRepo) download(version string) (string, error) {
 	}
 
 	// Move binary to repo path
-	if err := os.Rename(terraformBinaryPath, path.Join(targetDir, "terraform")); err != nil {
+	if err := os.Rename(terraformBinaryPath, r.terraformPath(version)); err != nil {
 		return "", err
 	}
 
 	return r.terraformPath(version), nil
 }
 
+// DownloadURL returns the URL that the specified version is downloaded
+// from, which is on the mirror if there is one.
+func (r *VersionRepo) DownloadURL(version string) string {
+	return r.downloads.mirrored(r.flavor.zipFileDownloadURL(version, r.platform, r.arch))
+}
+
+// Path returns the path that the binary for the specified version is
+// installed to. The binary may not exist yet.
+func (r *VersionRepo) Path(version string) string {
+	return r.terraformPath(version)
+}
+
 // exists returns whether the binary for the specified version
 // exists.
 func (r *VersionRepo) exists(version string) bool {
@@ -160,7 +216,7 @@ func (r *VersionRepo) exists(version string) bool {
 // prevent multiple threads from downloading the same version of Terraform at
 // the same time.
 func (r *VersionRepo) getLock(version string) *sync.Mutex {
-	v, _ := r.locks.LoadOrStore(version, &sync.Mutex{})
+	v, _ := r.locks.LoadOrStore(r.dir(version), &sync.Mutex{})
 	return v.(*sync.Mutex)
 }
 
@@ -168,20 +224,58 @@ func (r *VersionRepo) getLock(version string) *sync.Mutex {
 // that version. If the binary doesn't exist, it will be downloaded from
 // the Terraform website automatically.
 func (r *VersionRepo) Get(version string) (string, error) {
-	lock := r.getLock(version)
+	// Binaries are moved into place once they are complete, so one that
+	// exists can be used right away.
+	terraformPath := r.terraformPath(version)
+	if utils.FileExists(terraformPath) {
+		return terraformPath, nil
+	}
 
-	// Lock() here will block and wait if another thread is currently
+	// This will block and wait if another thread or process is currently
 	// downloading Terraform.
-	lock.Lock()
-	defer lock.Unlock()
+	unlock, err := r.lockVersion(version)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
 
-	terraformPath := r.terraformPath(version)
+	// The binary exists if it was downloaded while waiting
 	if !utils.FileExists(terraformPath) {
 		return r.download(version)
 	}
 	return terraformPath, nil
 }
 
+// lockVersion takes the lock of the specified version in this process,
+// and the lock file of the version in the repository, so that other
+// processes sharing the repository, e.g. parallel CI jobs, don't download
+// or remove the same version at the same time. It blocks until both are
+// free, and returns a function that releases them.
+func (r *VersionRepo) lockVersion(version string) (unlock func(), err error) {
+	lock := r.getLock(version)
+	lock.Lock()
+
+	if err := os.MkdirAll(filepath.Dir(r.dir(version)), os.ModePerm); err != nil {
+		lock.Unlock()
+		return nil, err
+	}
+
+	// The lock files are left in place: a process waiting for a lock
+	// file that was removed would hold a lock that others can't see.
+	unlockFile, err := lockFile(r.dir(version) + ".lock")
+	if err != nil {
+		lock.Unlock()
+		return nil, fmt.Errorf("unable to lock %s %s: %v", r.flavor.Name(), version, err)
+	}
+
+	return func() {
+		if err := unlockFile(); err != nil {
+			logger.Trace.Printf("tvm: unable to unlock %s %s: %v", r.flavor.Name(), version, err)
+		}
+		lock.Unlock()
+	}, nil
+}
+
 // Link symlinks the version binary into the targetPath. It will
 // download the binary if the version does not exist in the repository.
 func (r *VersionRepo) Link(version string, targetPath string, overwrite bool) error {
@@ -234,8 +328,29 @@ func (r *VersionRepo) List() (map[string]string, error) {
 	return dirs, nil
 }
 
+// Remove deletes the downloaded binary of the specified version from the
+// repository. It is an error if the version was not downloaded.
+func (r *VersionRepo) Remove(version string) error {
+	// The version must name a directory of the repository, not a path
+	// elsewhere
+	if !versionDirectoryFormat.MatchString(version) || filepath.Base(version) != version {
+		return fmt.Errorf("%s %s is not installed", r.flavor.Name(), version)
+	}
+
+	unlock, err := r.lockVersion(version)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if _, err := os.Stat(r.dir(version)); os.IsNotExist(err) {
+		return fmt.Errorf("%s %s is not installed", r.flavor.Name(), version)
+	}
+
+	return os.RemoveAll(r.dir(version))
+}
+
 // terraformPath returns the path to the Terraform binary file with the
-// specified version.
+// specified version, e.g. tofu for OpenTofu.
 func (r *VersionRepo) terraformPath(version string) string {
-	return filepath.Join(r.dir(version), terraformBinaryFile)
+	return filepath.Join(r.dir(version), r.flavor.Binary())
 }
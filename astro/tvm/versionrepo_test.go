@@ -18,6 +18,7 @@ package tvm_test
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/uber/astro/astro/tvm"
@@ -97,3 +98,67 @@ func TestDownloadDifferentRepo(t *testing.T) {
 		})
 	}
 }
+
+func TestFlavorPaths(t *testing.T) {
+	tmpdir := t.TempDir()
+
+	versions, err := tvm.NewVersionRepo(tmpdir, "amd64", "linux")
+	require.NoError(t, err)
+	assert.Equal(t, tvm.Terraform, versions.Flavor())
+	assert.Equal(t, filepath.Join(tmpdir, "linux", "amd64", "1.5.7", "terraform"), versions.Path("1.5.7"))
+	assert.Equal(t, "https://releases.hashicorp.com/terraform/1.5.7/terraform_1.5.7_linux_amd64.zip", versions.DownloadURL("1.5.7"))
+
+	tofu := versions.ForFlavor(tvm.OpenTofu)
+	assert.Equal(t, tvm.OpenTofu, tofu.Flavor())
+	assert.Equal(t, filepath.Join(tmpdir, "opentofu", "linux", "amd64", "1.6.0", "tofu"), tofu.Path("1.6.0"))
+	assert.Equal(t, "https://github.com/opentofu/opentofu/releases/download/v1.6.0/tofu_1.6.0_linux_amd64.zip", tofu.DownloadURL("1.6.0"))
+
+	// the versions of each flavor are listed separately
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpdir, "linux", "amd64", "1.5.7"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpdir, "opentofu", "linux", "amd64", "1.6.0"), 0755))
+
+	installed, err := versions.List()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"1.5.7": versions.Path("1.5.7")}, installed)
+
+	installed, err = tofu.List()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"1.6.0": tofu.Path("1.6.0")}, installed)
+}
+
+func TestParseFlavor(t *testing.T) {
+	for name, expected := range map[string]tvm.Flavor{
+		"":          tvm.Terraform,
+		"terraform": tvm.Terraform,
+		"opentofu":  tvm.OpenTofu,
+	} {
+		flavor, err := tvm.ParseFlavor(name)
+		require.NoError(t, err)
+		assert.Equal(t, expected, flavor, name)
+	}
+
+	_, err := tvm.ParseFlavor("tofu")
+	assert.EqualError(t, err, `unknown flavor "tofu"; must be one of opentofu, terraform`)
+}
+
+func TestRemove(t *testing.T) {
+	tmpdir := t.TempDir()
+
+	versions, err := tvm.NewVersionRepo(tmpdir, "amd64", "linux")
+	require.NoError(t, err)
+
+	for _, version := range []string{"1.5.7", "1.6.0"} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(versions.Path(version)), 0755))
+		require.NoError(t, os.WriteFile(versions.Path(version), nil, 0755))
+	}
+
+	require.NoError(t, versions.Remove("1.5.7"))
+
+	installed, err := versions.List()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"1.6.0": versions.Path("1.6.0")}, installed)
+
+	assert.EqualError(t, versions.Remove("1.5.7"), "Terraform 1.5.7 is not installed")
+	assert.Error(t, versions.Remove("../amd64/1.6.0"))
+	assert.Error(t, versions.ForFlavor(tvm.OpenTofu).Remove("1.6.0"))
+}
@@ -0,0 +1,84 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package upload uploads files to cloud storage and creates links that can
+// be used to read them. It shells out to the aws and gcloud command line
+// tools, so that their usual credential configuration applies.
+package upload
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/uber/astro/astro/logger"
+)
+
+// Upload copies the file at path to destURL, which must be an s3:// or
+// gs:// URL of the object to create. It returns a presigned URL that can be
+// used to read the object until expiry has passed.
+func Upload(ctx context.Context, path string, destURL string, expiry time.Duration) (string, error) {
+	u, err := url.Parse(destURL)
+	if err != nil {
+		return "", err
+	}
+
+	var copyArgs, signArgs []string
+
+	switch u.Scheme {
+	case "s3":
+		copyArgs = []string{"aws", "s3", "cp", "--quiet", path, destURL}
+		signArgs = []string{"aws", "s3", "presign", destURL, "--expires-in", fmt.Sprintf("%d", int(expiry.Seconds()))}
+	case "gs":
+		copyArgs = []string{"gcloud", "storage", "cp", path, destURL}
+		signArgs = []string{"gcloud", "storage", "sign-url", destURL, "--duration", expiry.String(), "--format", "value(signed_url)"}
+	default:
+		return "", fmt.Errorf("unsupported url scheme: %q", u.Scheme)
+	}
+
+	if _, err := run(ctx, copyArgs...); err != nil {
+		return "", err
+	}
+
+	signedURL, err := run(ctx, signArgs...)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(signedURL), nil
+}
+
+// run runs the command and returns its stdout.
+func run(ctx context.Context, args ...string) (string, error) {
+	logger.Trace.Printf("upload: running %v", args)
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s: %v: %s", strings.Join(args[:3], " "), err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
@@ -0,0 +1,55 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package upload_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/uber/astro/astro/upload"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadS3(t *testing.T) {
+	binDir := t.TempDir()
+	callLog := filepath.Join(binDir, "calls")
+
+	// fake aws CLI that logs its arguments
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "aws"), []byte(`#!/bin/sh
+echo "$@" >> `+callLog+`
+if [ "$2" = presign ]; then echo "https://example.com/signed"; fi
+`), 0755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	link, err := upload.Upload(context.Background(), "/tmp/report.html", "s3://bucket/reports/1.html", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/signed", link)
+
+	calls, err := os.ReadFile(callLog)
+	require.NoError(t, err)
+	assert.Equal(t, "s3 cp --quiet /tmp/report.html s3://bucket/reports/1.html\ns3 presign s3://bucket/reports/1.html --expires-in 3600\n", string(calls))
+}
+
+func TestUploadUnsupportedScheme(t *testing.T) {
+	_, err := upload.Upload(context.Background(), "/tmp/report.html", "ftp://example.com/1.html", time.Hour)
+	assert.Error(t, err)
+}
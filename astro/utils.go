@@ -52,3 +52,16 @@ func filterMaps(a, b map[string]string) bool {
 	}
 	return true
 }
+
+// mergeMaps returns a new map with the values of b applied on top of the
+// values of a.
+func mergeMaps(a, b map[string]string) map[string]string {
+	result := make(map[string]string)
+	for key, val := range a {
+		result[key] = val
+	}
+	for key, val := range b {
+		result[key] = val
+	}
+	return result
+}
@@ -1,5 +1,7 @@
+//go:build !windows
+
 /*
- *  Copyright (c) 2018 Uber Technologies, Inc.
+ *  Copyright (c) 2019 Uber Technologies, Inc.
  *
  *     Licensed under the Apache License, Version 2.0 (the "License");
  * you may not use this file except in compliance with the License.
@@ -17,29 +19,18 @@
 package utils
 
 import (
-	"context"
-	"sync"
-
-	"golang.org/x/sync/semaphore"
+	"errors"
+	"syscall"
 )
 
-// Parallel runs at most maxConcurrent functions in parallel.
-func Parallel(ctx context.Context, maxConcurrent int, fns ...func()) {
-	wg := sync.WaitGroup{}
-	sem := semaphore.NewWeighted(int64(maxConcurrent))
-
-	for _, fn := range fns {
-		// https://medium.com/@deckarep/gos-extended-concurrency-semaphores-part-1-5eeabfa351ce
-		if err := sem.Acquire(ctx, 1); err != nil {
-			break
-		}
-		wg.Add(1)
-		go func(fn func()) {
-			defer wg.Done()
-			fn()
-			sem.Release(1)
-		}(fn)
+// ProcessRunning returns whether a process with the PID is running on
+// this host.
+func ProcessRunning(pid int) bool {
+	if pid <= 0 {
+		return false
 	}
-
-	wg.Wait()
+	// signal 0 checks that the process exists without signalling it; a
+	// process of another user can't be signalled, but exists
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
 }
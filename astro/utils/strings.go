@@ -25,3 +25,56 @@ func StringSliceContains(slice []string, value string) bool {
 	}
 	return false
 }
+
+// maxSuggestionDistance is the maximum edit distance between a string and
+// the candidates returned by ClosestMatches.
+const maxSuggestionDistance = 2
+
+// ClosestMatches returns the candidates that are within a small edit
+// distance of s, e.g. to suggest what the user meant when they made a
+// typo. The closest candidates are returned first.
+func ClosestMatches(s string, candidates []string) (matches []string) {
+	for distance := 1; distance <= maxSuggestionDistance && distance < len(s); distance++ {
+		for _, candidate := range candidates {
+			if editDistance(s, candidate) == distance && !StringSliceContains(matches, candidate) {
+				matches = append(matches, candidate)
+			}
+		}
+	}
+	return matches
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	previous := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current := make([]int, len(rb)+1)
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+
+	return previous[len(rb)]
+}
+
+func minInt(values ...int) int {
+	result := values[0]
+	for _, v := range values[1:] {
+		if v < result {
+			result = v
+		}
+	}
+	return result
+}
@@ -0,0 +1,35 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/uber/astro/astro/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClosestMatches(t *testing.T) {
+	candidates := []string{"dev", "staging", "prod", "mgmt"}
+
+	assert.Equal(t, []string{"prod"}, utils.ClosestMatches("pord", candidates))
+	assert.Equal(t, []string{"staging"}, utils.ClosestMatches("stagin", candidates))
+	assert.Equal(t, []string{"dev"}, utils.ClosestMatches("deb", candidates))
+	assert.Nil(t, utils.ClosestMatches("production", candidates))
+	assert.Nil(t, utils.ClosestMatches("x", candidates))
+}
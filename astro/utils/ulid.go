@@ -17,7 +17,7 @@
 package utils
 
 import (
-	"math/rand"
+	"crypto/rand"
 	"time"
 
 	"github.com/oklog/ulid"
@@ -26,12 +26,28 @@ import (
 // ULID returns a new Universally Unique Lexicographically Sortable Identifier
 // based on the current time.
 func ULID() ulid.ULID {
-	t := time.Now()
-	entropy := rand.New(rand.NewSource(t.UnixNano()))
-	return ulid.MustNew(ulid.Timestamp(t), entropy)
+	return ULIDAt(time.Now())
+}
+
+// ULIDAt returns a new ULID for the time t. Its random part comes from
+// crypto/rand, so that ULIDs generated concurrently, or for the same time
+// by a fixed clock, are unique.
+func ULIDAt(t time.Time) ulid.ULID {
+	return ulid.MustNew(ulid.Timestamp(t), rand.Reader)
 }
 
 // ULIDString returns a ULID as a string.
 func ULIDString() string {
 	return ULID().String()
 }
+
+// ULIDTime returns the time encoded in a ULID string, or an error if it
+// is not a ULID.
+func ULIDTime(s string) (time.Time, error) {
+	id, err := ulid.Parse(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	ms := int64(id.Time())
+	return time.Unix(ms/1000, (ms%1000)*int64(time.Millisecond)), nil
+}
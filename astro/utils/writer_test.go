@@ -0,0 +1,47 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/uber/astro/astro/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := utils.NewPrefixWriter(&out, "[foo] ")
+
+	_, err := w.Write([]byte("hello\nwor"))
+	require.NoError(t, err)
+	assert.Equal(t, "[foo] hello\n", out.String())
+
+	_, err = w.Write([]byte("ld\npartial"))
+	require.NoError(t, err)
+	assert.Equal(t, "[foo] hello\n[foo] world\n", out.String())
+
+	require.NoError(t, w.Flush())
+	assert.Equal(t, "[foo] hello\n[foo] world\n[foo] partial\n", out.String())
+
+	// flushing again is a no-op
+	require.NoError(t, w.Flush())
+	assert.Equal(t, "[foo] hello\n[foo] world\n[foo] partial\n", out.String())
+}
@@ -0,0 +1,99 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"fmt"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
+)
+
+// Validate runs `terraform validate` for every execution matching the
+// parameters, in parallel. It doesn't touch the state: modules are
+// initialized without their backend, and without the credentials
+// configured for them, so that it can run where neither is available,
+// e.g. in CI before changes are merged. The returned channels behave in
+// the same way as for Plan.
+func (c *Project) Validate(parameters ExecutionParameters) (<-chan string, <-chan *Result, error) {
+	logger.Debug("starting validate", logger.Fields{"modules": parameters.ModuleNames})
+
+	if parameters.Frozen {
+		if err := c.VerifyLock(parameters.ModuleNames); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	boundExecutions, err := c.boundExecutions(parameters)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	session, err := c.sessions.Current()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	status, results := session.validate(boundExecutions, c.executionLimiter(parameters, 0))
+
+	return status, reportAliases(boundExecutions, addGuidance(boundExecutions, results)), nil
+}
+
+func (session *Session) validate(boundExecutions []*boundExecution, limiter *executionLimiter) (<-chan string, <-chan *Result) {
+	status := newStatusQueue()
+	results := newResults(len(boundExecutions))
+
+	logger.Debug("running validate", logger.Fields{"executions": len(boundExecutions)})
+
+	execute := func(b *boundExecution) {
+		// validating needs no credentials, so roles are not assumed
+		b.moduleConf.Credentials = conf.Credentials{}
+
+		terraform, err := session.newTerraformSession(b)
+		if err != nil {
+			results <- &Result{
+				id:  b.ID(),
+				err: err,
+			}
+			return
+		}
+
+		status.send(b.ID(), "Validating...")
+		result, err := terraform.Validate()
+		results <- &Result{
+			id:              b.ID(),
+			terraformResult: result,
+			invocations:     terraform.Invocations(),
+			err:             err,
+		}
+	}
+
+	stopWatching := session.watchSignals()
+	go func() {
+		defer stopWatching()
+		limiter.run(session.ctx, boundExecutions, execute, func(b *boundExecution, err error) {
+			results <- &Result{
+				id:  b.ID(),
+				err: fmt.Errorf("not validated: %v", err),
+			}
+		})
+		status.close()
+		close(results) // signals the end of all executions
+	}()
+
+	return status.ch, results
+}
@@ -0,0 +1,263 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/uber/astro/astro/conf"
+	"github.com/uber/astro/astro/logger"
+	"github.com/uber/astro/astro/utils"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// WatchChange is a change to the sources of some of the modules of a
+// project, or to the other files that are watched.
+type WatchChange struct {
+	// Modules are the names of the modules whose sources changed,
+	// sorted.
+	Modules []string
+	// Files are the other watched files that changed, e.g. the config
+	// file, sorted.
+	Files []string
+}
+
+// Watch watches the sources of the modules, all of them if moduleNames is
+// nil, and files, and sends what changed on the returned channel once no
+// more changes were made for debounce, so that saving several files at
+// once makes a single change. The sources of a module are its directory,
+// and the directories of the local modules it uses. Watching stops, and
+// the channel is closed, when ctx is done.
+func (c *Project) Watch(ctx context.Context, moduleNames []string, files []string, debounce time.Duration) (<-chan WatchChange, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	sessionPath, err := filepath.Abs(c.sessions.path)
+	if err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	w := &projectWatcher{
+		watcher:     watcher,
+		sourceDirs:  map[string][]string{},
+		files:       map[string]bool{},
+		sessionPath: sessionPath,
+	}
+
+	for _, module := range c.modules(moduleNames) {
+		dirs, err := c.sourceDirs(*module.config)
+		if err != nil {
+			watcher.Close()
+			return nil, err
+		}
+		for _, dir := range dirs {
+			w.sourceDirs[dir] = append(w.sourceDirs[dir], module.config.Name)
+			if err := w.addTree(dir); err != nil {
+				watcher.Close()
+				return nil, err
+			}
+		}
+	}
+
+	// Editors often replace files rather than write to them, so their
+	// directories are watched
+	for _, file := range files {
+		path, err := filepath.Abs(file)
+		if err != nil {
+			watcher.Close()
+			return nil, err
+		}
+		w.files[path] = true
+		if err := watcher.Add(filepath.Dir(path)); err != nil {
+			watcher.Close()
+			return nil, err
+		}
+	}
+
+	changes := make(chan WatchChange)
+	go w.run(ctx, debounce, changes)
+
+	return changes, nil
+}
+
+// sourceDirs returns the directories of the sources of the module: its own
+// directory, and those of the local modules it uses, sorted.
+func (c *Project) sourceDirs(moduleConfig conf.Module) ([]string, error) {
+	visited := map[string]bool{}
+	modulePath := filepath.Join(moduleConfig.TerraformCodeRoot, moduleConfig.Path)
+	if err := c.hashModuleDir(io.Discard, modulePath, visited, map[string]bool{}); err != nil {
+		return nil, err
+	}
+
+	var dirs []string
+	for dir := range visited {
+		path, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, err
+		}
+		dirs = append(dirs, path)
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+// projectWatcher watches the sources of modules and other files.
+type projectWatcher struct {
+	watcher *fsnotify.Watcher
+	// sourceDirs are the names of the modules whose sources are in each
+	// directory, by its absolute path.
+	sourceDirs map[string][]string
+	// files are the absolute paths of the other files that are watched.
+	files map[string]bool
+	// sessionPath is the session repo, which is never watched, as
+	// Terraform runs in copies of the modules in it.
+	sessionPath string
+}
+
+// addTree watches dir and all its subdirectories, except those of
+// Terraform, astro and git.
+func (w *projectWatcher) addTree(dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if w.ignored(path) {
+			return filepath.SkipDir
+		}
+		return w.watcher.Add(path)
+	})
+}
+
+// ignored returns whether changes to the path are ignored.
+func (w *projectWatcher) ignored(path string) bool {
+	if w.sessionPath != "" && (path == w.sessionPath || strings.HasPrefix(path, w.sessionPath+string(filepath.Separator))) {
+		return true
+	}
+	for _, part := range strings.Split(path, string(filepath.Separator)) {
+		if part == ".terraform" || part == ".astro" || part == ".git" {
+			return true
+		}
+	}
+	return false
+}
+
+// modules returns the names of the modules whose sources the path is in.
+func (w *projectWatcher) modules(path string) []string {
+	var modules []string
+	for dir, names := range w.sourceDirs {
+		if path == dir || strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			modules = append(modules, names...)
+		}
+	}
+	return modules
+}
+
+// run sends the changes that were made once they settle, until ctx is
+// done.
+func (w *projectWatcher) run(ctx context.Context, debounce time.Duration, changes chan<- WatchChange) {
+	defer close(changes)
+	defer w.watcher.Close()
+
+	modules := map[string]bool{}
+	files := map[string]bool{}
+
+	// The timer only runs while there are changes to send
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod || w.ignored(event.Name) {
+				continue
+			}
+
+			changed := false
+			if w.files[event.Name] {
+				files[event.Name] = true
+				changed = true
+			}
+			for _, name := range w.modules(event.Name) {
+				modules[name] = true
+				changed = true
+			}
+			if !changed {
+				continue
+			}
+			logger.Trace.Printf("astro: watched file changed: %v", event)
+
+			// New directories of modules are watched too
+			if event.Op&fsnotify.Create != 0 && utils.IsDirectory(event.Name) {
+				if err := w.addTree(event.Name); err != nil {
+					logger.Warn("unable to watch directory", logger.Fields{"path": event.Name, "error": err})
+				}
+			}
+
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(debounce)
+
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			logger.Warn("error watching files", logger.Fields{"error": err})
+
+		case <-timer.C:
+			change := WatchChange{}
+			for name := range modules {
+				change.Modules = append(change.Modules, name)
+			}
+			for path := range files {
+				change.Files = append(change.Files, path)
+			}
+			sort.Strings(change.Modules)
+			sort.Strings(change.Files)
+			modules = map[string]bool{}
+			files = map[string]bool{}
+
+			select {
+			case changes <- change:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
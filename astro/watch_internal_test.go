@@ -0,0 +1,108 @@
+/*
+ *  Copyright (c) 2019 Uber Technologies, Inc.
+ *
+ *     Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package astro
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newWatchTestProject returns a project with the modules app and db, where
+// app uses the local module vpc.
+func newWatchTestProject(t *testing.T) (*Project, string) {
+	dir := t.TempDir()
+	for file, content := range map[string]string{
+		"app/main.tf":         "module \"vpc\" {\n  source = \"../modules/vpc\"\n}\n",
+		"db/main.tf":          "# db\n",
+		"modules/vpc/main.tf": "# vpc\n",
+		"astro.yaml": "terraform:\n  path: " + absolutePath("fixtures/mock-terraform/success") + "\n" +
+			"modules:\n  - name: app\n    path: app\n  - name: db\n    path: db\n",
+	} {
+		path := filepath.Join(dir, file)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	c, err := NewProjectFromConfigFile(filepath.Join(dir, "astro.yaml"))
+	require.NoError(t, err)
+	return c, dir
+}
+
+// testNextChange returns the next change that is sent, or fails.
+func testNextChange(t *testing.T, changes <-chan WatchChange) WatchChange {
+	select {
+	case change := <-changes:
+		return change
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change was sent")
+	}
+	return WatchChange{}
+}
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+
+	c, dir := newWatchTestProject(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := c.Watch(ctx, nil, []string{filepath.Join(dir, "astro.yaml")}, 50*time.Millisecond)
+	require.NoError(t, err)
+
+	// changes to the local modules a module uses are changes to it, and
+	// changes that are made together are sent together
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "modules/vpc/main.tf"), []byte("# vpc 2\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "db/variables.tf"), []byte("# db\n"), 0644))
+	assert.Equal(t, WatchChange{Modules: []string{"app", "db"}}, testNextChange(t, changes))
+
+	// new directories are watched
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "db/files"), 0755))
+	testNextChange(t, changes)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "db/files/policy.json"), []byte("{}"), 0644))
+	assert.Equal(t, WatchChange{Modules: []string{"db"}}, testNextChange(t, changes))
+
+	// Terraform files are ignored
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "app/.terraform"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "astro.yaml"), []byte("modules: []\n"), 0644))
+	assert.Equal(t, WatchChange{Files: []string{filepath.Join(dir, "astro.yaml")}}, testNextChange(t, changes))
+
+	cancel()
+	_, ok := <-changes
+	assert.False(t, ok)
+}
+
+func TestWatchModules(t *testing.T) {
+	t.Parallel()
+
+	c, dir := newWatchTestProject(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := c.Watch(ctx, []string{"db"}, nil, 50*time.Millisecond)
+	require.NoError(t, err)
+
+	// app is not watched
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app/main.tf"), []byte("# app\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "db/main.tf"), []byte("# db 2\n"), 0644))
+	assert.Equal(t, WatchChange{Modules: []string{"db"}}, testNextChange(t, changes))
+}